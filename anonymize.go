@@ -0,0 +1,270 @@
+package jpack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultAnonymizeBatchSize = 500
+
+// Scrubber rewrites a sensitive value for Anonymize. It is not called for
+// unset or nil values.
+type Scrubber func(value any) (any, error)
+
+// AnonymizeRules select how Anonymize rewrites fields.
+type AnonymizeRules struct {
+	// Scrubbers are keyed by "<schema>.<field>", which may also name fields
+	// not marked Sensitive, or by the category of sensitive fields.
+	Scrubbers map[string]Scrubber
+	// Default scrubs sensitive fields no scrubber matches. Without it such
+	// fields fail Anonymize before anything is written.
+	Default Scrubber
+	// BatchSize is the number of documents rewritten with one bulk write.
+	BatchSize int
+}
+
+// scrubber returns the scrubber for a field, if any.
+func (r AnonymizeRules) scrubber(field JField) (Scrubber, bool) {
+	if scrub, ok := r.Scrubbers[field.Schema().Name()+"."+field.Name()]; ok {
+		return scrub, true
+	}
+	category, sensitive := SensitiveCategory(field)
+	if !sensitive {
+		return nil, false
+	}
+	if scrub, ok := r.Scrubbers[category]; ok && category != "" {
+		return scrub, true
+	}
+	return r.Default, r.Default != nil
+}
+
+// anonymizePlan lists the fields of a schema Anonymize rewrites, with their
+// scrubbers.
+type anonymizePlan struct {
+	schema    JSchema
+	fields    []JField
+	scrubbers []Scrubber
+}
+
+func planAnonymize(schema JSchema, rules AnonymizeRules) (anonymizePlan, error) {
+	plan := anonymizePlan{schema: schema}
+	for _, field := range schema.Fields() {
+		scrub, ok := rules.scrubber(field)
+		if !ok {
+			if _, sensitive := SensitiveCategory(field); sensitive {
+				return plan, fmt.Errorf("jpack: no scrubber for sensitive field %s.%s", schema.Name(), field.Name())
+			}
+			continue
+		}
+		plan.fields = append(plan.fields, field)
+		plan.scrubbers = append(plan.scrubbers, scrub)
+	}
+	return plan, nil
+}
+
+// scrub returns the update rewriting the planned fields of a stored
+// document, or nil when it has none of them. Values found under an alias are
+// moved to the current name, so the original isn't left behind.
+func (p anonymizePlan) scrub(ctx context.Context, doc bson.M) (bson.M, error) {
+	record := NewMongoRecord(p.schema)
+	if err := record.loadDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+	unset := bson.M{}
+	for i, field := range p.fields {
+		value, ok := record.Value(field)
+		if !ok || value == nil {
+			continue
+		}
+		scrubbed, err := p.scrubbers[i](value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name(), err)
+		}
+		values[field.Name()] = scrubbed
+		for _, alias := range FieldAliases(field) {
+			if _, ok := doc[alias]; ok {
+				unset[alias] = ""
+			}
+		}
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	set, err := record.convertToBSON(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+	delete(set, defaultMongoPK)
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// Anonymize rewrites the sensitive fields of every stored record of the
+// schemas, and the fields named in the rules, for producing QA copies of
+// production data. Run it against the copy: the documents are changed in
+// place, without hooks, outbox entries or webhooks, and immutable fields are
+// rewritten too. It returns the number of documents rewritten per schema.
+func Anonymize(ctx context.Context, schemas []JSchema, rules AnonymizeRules) (map[string]int, error) {
+	if IsReadOnly(ctx) {
+		return nil, ErrReadOnly
+	}
+
+	// Every schema is checked before anything is written
+	plans := make([]anonymizePlan, 0, len(schemas))
+	for _, schema := range schemas {
+		if _, ok := schema.(*ViewSchema); ok {
+			return nil, ErrViewReadOnly
+		}
+		plan, err := planAnonymize(schema, rules)
+		if err != nil {
+			return nil, err
+		}
+		if len(plan.fields) > 0 {
+			plans = append(plans, plan)
+		}
+	}
+
+	batchSize := rules.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultAnonymizeBatchSize
+	}
+
+	counts := make(map[string]int)
+	for _, plan := range plans {
+		count, err := plan.run(ctx, batchSize)
+		counts[plan.schema.Name()] = count
+		if err != nil {
+			return counts, fmt.Errorf("jpack: anonymizing %s: %w", plan.schema.Name(), err)
+		}
+	}
+	return counts, nil
+}
+
+func (p anonymizePlan) run(ctx context.Context, batchSize int) (int, error) {
+	coll := collection(ctx, p.schema)
+
+	projection := bson.M{}
+	for _, field := range p.fields {
+		for _, key := range storageKeys(field) {
+			projection[key] = 1
+		}
+		for _, alias := range FieldAliases(field) {
+			projection[alias] = 1
+		}
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{}, options.Find().SetProjection(projection))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	var models []mongo.WriteModel
+	write := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+		start := time.Now()
+		_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "anonymize", Count: int64(len(models)), Err: err}, start)
+		if err == nil {
+			count += len(models)
+		}
+		models = models[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return count, err
+		}
+		id := doc[defaultMongoPK]
+
+		update, err := p.scrub(ctx, doc)
+		if err != nil {
+			return count, fmt.Errorf("%v: %w", id, err)
+		}
+		if update == nil {
+			continue
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{defaultMongoPK: id}).SetUpdate(update))
+
+		if len(models) >= batchSize {
+			if err := write(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	return count, write()
+}
+
+// HashWith replaces string values with their hex HMAC-SHA256 under key, so
+// equal values stay equal, e.g. to keep joins on emails working, without
+// being reversible by whoever lacks the key.
+func HashWith(key string) Scrubber {
+	return func(value any) (any, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("hash needs a string value, got %T", value)
+		}
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	}
+}
+
+// Replace replaces every value with value, e.g. Replace(nil) or
+// Replace("redacted").
+func Replace(value any) Scrubber {
+	return func(any) (any, error) {
+		return value, nil
+	}
+}
+
+var (
+	fakeFirstNames = []string{"Alex", "Sam", "Robin", "Jordan", "Taylor", "Casey", "Morgan", "Jamie", "Riley", "Avery"}
+	fakeLastNames  = []string{"Smith", "Garcia", "Chen", "Novak", "Okafor", "Silva", "Larsen", "Kowalski", "Haddad", "Ito"}
+)
+
+// FakeName replaces values with made-up full names. The same value always
+// gets the same name.
+func FakeName() Scrubber {
+	return func(value any) (any, error) {
+		n := fakeSeed(value)
+		return fakeFirstNames[n%uint64(len(fakeFirstNames))] + " " + fakeLastNames[n/uint64(len(fakeFirstNames))%uint64(len(fakeLastNames))], nil
+	}
+}
+
+// FakeEmail replaces values with addresses at example.com. The same value
+// always gets the same address, so unique indexes still hold.
+func FakeEmail() Scrubber {
+	return func(value any) (any, error) {
+		return fmt.Sprintf("user-%016x@example.com", fakeSeed(value)), nil
+	}
+}
+
+// fakeSeed derives a stable number from a value.
+func fakeSeed(value any) uint64 {
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	return binary.BigEndian.Uint64(sum[:8])
+}
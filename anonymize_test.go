@@ -0,0 +1,96 @@
+package jpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestAnonymize(t *testing.T) {
+	schema := NewSchema("test_anonymize").
+		Field("id", &String{}).
+		Field("name", &String{}, Sensitive("name")).
+		Field("email", &String{}, Sensitive("email"), FieldAlias("mail")).
+		Field("phone", &String{}, Sensitive("")).
+		Field("city", &String{}).
+		Build()
+
+	rules := AnonymizeRules{
+		Scrubbers: map[string]Scrubber{
+			"name":                 FakeName(),
+			"email":                FakeEmail(),
+			"test_anonymize.phone": Replace("000"),
+			"test_anonymize.city":  Replace("Springfield"),
+		},
+	}
+
+	t.Run("sensitive fields", func(t *testing.T) {
+		category, ok := SensitiveCategory(mustField(t, schema, "email"))
+		assert.True(t, ok)
+		assert.Equal(t, "email", category)
+		_, ok = SensitiveCategory(mustField(t, schema, "city"))
+		assert.False(t, ok)
+	})
+
+	t.Run("scrub", func(t *testing.T) {
+		plan, err := planAnonymize(schema, rules)
+		assert.NoError(t, err)
+		assert.Len(t, plan.fields, 4)
+
+		update, err := plan.scrub(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Ada Lovelace", "mail": "ada@example.org", "phone": nil})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"mail": ""}, update["$unset"], "the aliased original is removed")
+		set := update["$set"].(bson.M)
+		assert.NotContains(t, set, "_id")
+		assert.NotEqual(t, "Ada Lovelace", set["name"])
+		assert.Regexp(t, `^user-[0-9a-f]{16}@example\.com$`, set["email"])
+		assert.NotContains(t, set, "phone", "nulls are kept")
+		assert.NotContains(t, set, "city", "missing values are kept")
+
+		again, err := plan.scrub(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Ada Lovelace"})
+		assert.NoError(t, err)
+		assert.Equal(t, set["name"], again["$set"].(bson.M)["name"], "fakes are deterministic")
+		assert.NotContains(t, again, "$unset")
+
+		update, err = plan.scrub(t.Context(), bson.M{"_id": bson.NewObjectID()})
+		assert.NoError(t, err)
+		assert.Nil(t, update)
+	})
+
+	t.Run("sensitive fields need a scrubber", func(t *testing.T) {
+		_, err := Anonymize(offlineContext(t), []JSchema{schema}, AnonymizeRules{Scrubbers: map[string]Scrubber{"name": FakeName()}})
+		assert.ErrorContains(t, err, "no scrubber for sensitive field test_anonymize.email")
+
+		plan, err := planAnonymize(schema, AnonymizeRules{Default: Replace(nil)})
+		assert.NoError(t, err)
+		assert.Len(t, plan.fields, 3)
+	})
+
+	t.Run("scrubber errors name the field", func(t *testing.T) {
+		plan, err := planAnonymize(schema, AnonymizeRules{Default: func(any) (any, error) { return nil, errors.New("boom") }})
+		assert.NoError(t, err)
+		_, err = plan.scrub(t.Context(), bson.M{"_id": bson.NewObjectID(), "email": "ada@example.org"})
+		assert.ErrorContains(t, err, "email: boom")
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		_, err := Anonymize(WithReadOnly(offlineContext(t)), []JSchema{schema}, rules)
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
+
+func TestHashWith(t *testing.T) {
+	hash := HashWith("salt")
+	a, err := hash("ada@example.org")
+	assert.NoError(t, err)
+	b, _ := hash("ada@example.org")
+	c, _ := HashWith("pepper")("ada@example.org")
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, a, c)
+	assert.Len(t, a, 64)
+
+	_, err = hash(42)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package jpack
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// avroBlockSize is the number of records per Avro container block.
+const avroBlockSize = 1000
+
+// AvroSchema returns the Avro record schema, as JSON, used by WriteAvro.
+// Every column is nullable; composite fields are flattened into a column per
+// part and datetimes are timestamp-micros.
+func AvroSchema(schema JSchema) (string, error) {
+	columns, err := exportColumns(schema)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(avroSchema(schema, columns))
+	return string(data), err
+}
+
+func avroSchema(schema JSchema, columns []exportColumn) map[string]any {
+	fields := make([]any, 0, len(columns))
+	for _, column := range columns {
+		var avroType any
+		switch column.kind {
+		case exportString:
+			avroType = "string"
+		case exportLong:
+			avroType = "long"
+		case exportBoolean:
+			avroType = "boolean"
+		case exportTimestamp:
+			avroType = map[string]any{"type": "long", "logicalType": "timestamp-micros"}
+		}
+
+		fields = append(fields, map[string]any{
+			"name":    column.name,
+			"type":    []any{"null", avroType},
+			"default": nil,
+		})
+	}
+
+	return map[string]any{
+		"type":   "record",
+		"name":   schema.Name(),
+		"fields": fields,
+	}
+}
+
+// ExportAvro runs the query and writes its results as an Avro object
+// container file.
+func ExportAvro(w io.Writer, q Query) error {
+	records, err := q.Execute()
+	if err != nil {
+		return err
+	}
+	return WriteAvro(w, q.Schema(), records)
+}
+
+// WriteAvro writes records of the schema as an uncompressed Avro object
+// container file with the schema returned by AvroSchema.
+func WriteAvro(w io.Writer, schema JSchema, records []JRecord) error {
+	columns, err := exportColumns(schema)
+	if err != nil {
+		return err
+	}
+
+	schemaJSON, err := json.Marshal(avroSchema(schema, columns))
+	if err != nil {
+		return err
+	}
+
+	var sync [16]byte
+	if _, err := rand.Read(sync[:]); err != nil {
+		return err
+	}
+
+	// Header: magic, metadata map, sync marker
+	header := []byte{'O', 'b', 'j', 1}
+	header = binary.AppendVarint(header, 2)
+	header = appendAvroBytes(header, []byte("avro.schema"))
+	header = appendAvroBytes(header, schemaJSON)
+	header = appendAvroBytes(header, []byte("avro.codec"))
+	header = appendAvroBytes(header, []byte("null"))
+	header = binary.AppendVarint(header, 0)
+	header = append(header, sync[:]...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for start := 0; start < len(records); start += avroBlockSize {
+		end := min(start+avroBlockSize, len(records))
+
+		var data []byte
+		for _, record := range records[start:end] {
+			row, err := exportRow(record, columns)
+			if err != nil {
+				return err
+			}
+			data = appendAvroRow(data, columns, row)
+		}
+
+		block := binary.AppendVarint(nil, int64(end-start))
+		block = binary.AppendVarint(block, int64(len(data)))
+		block = append(block, data...)
+		block = append(block, sync[:]...)
+		if _, err := w.Write(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// appendAvroRow encodes a row in Avro binary encoding. Avro longs are zigzag
+// varints, which is what binary.AppendVarint writes.
+func appendAvroRow(buf []byte, columns []exportColumn, row []any) []byte {
+	for i, column := range columns {
+		if row[i] == nil {
+			buf = binary.AppendVarint(buf, 0) // union branch "null"
+			continue
+		}
+		buf = binary.AppendVarint(buf, 1)
+
+		switch column.kind {
+		case exportString:
+			buf = appendAvroBytes(buf, []byte(row[i].(string)))
+		case exportLong:
+			buf = binary.AppendVarint(buf, int64(row[i].(int)))
+		case exportBoolean:
+			if row[i].(bool) {
+				buf = append(buf, 1)
+			} else {
+				buf = append(buf, 0)
+			}
+		case exportTimestamp:
+			buf = binary.AppendVarint(buf, row[i].(time.Time).UnixMicro())
+		}
+	}
+	return buf
+}
+
+func appendAvroBytes(buf []byte, data []byte) []byte {
+	buf = binary.AppendVarint(buf, int64(len(data)))
+	return append(buf, data...)
+}
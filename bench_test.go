@@ -0,0 +1,246 @@
+package jpack
+
+// Benchmarks for the hot paths of reading and writing records. Run them on
+// the base revision and on the change, then compare with benchstat:
+//
+//	go test -run '^$' -bench . -benchmem -count 10 > old.txt
+//	go test -run '^$' -bench . -benchmem -count 10 > new.txt
+//	benchstat old.txt new.txt
+//
+// Sub-benchmarks are named key=value so benchstat can group by them.
+// BenchmarkEagerLoading seeds a local MongoDB and is skipped without one.
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var benchSizes = []int{8, 64}
+
+// benchSchema returns a schema with an id and n alternating string and
+// number fields.
+func benchSchema(name string, n int) JSchema {
+	builder := NewSchema(name).Field("id", &String{})
+	for i := range n {
+		if i%2 == 0 {
+			builder.Field("s"+strconv.Itoa(i), &String{})
+		} else {
+			builder.Field("n"+strconv.Itoa(i), &Number{})
+		}
+	}
+	return builder.Build()
+}
+
+// benchValue returns the i-th value of a field of benchSchema.
+func benchValue(field JField, i int) any {
+	if _, ok := field.Type().(*Number); ok {
+		return int64(i)
+	}
+	return "value-" + strconv.Itoa(i)
+}
+
+// benchDocument returns a stored document of schema.
+func benchDocument(schema JSchema) bson.M {
+	doc := bson.M{defaultMongoPK: bson.NewObjectID()}
+	for i, field := range schema.Fields() {
+		if field.Name() != "id" {
+			doc[field.Name()] = benchValue(field, i)
+		}
+	}
+	return doc
+}
+
+func BenchmarkRecordHydration(b *testing.B) {
+	for _, n := range benchSizes {
+		schema := benchSchema("bench_hydration", n)
+		doc := benchDocument(schema)
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("source=bson.M/fields=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := RecordFromBSON(schema, doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("source=raw/fields=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(raw)))
+			for b.Loop() {
+				var decoded bson.M
+				if err := bson.Unmarshal(raw, &decoded); err != nil {
+					b.Fatal(err)
+				}
+				record, err := RecordFromBSON(schema, decoded)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, field := range schema.Fields() {
+					record.Value(field)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkResolveFilter(b *testing.B) {
+	schema := benchSchema("bench_filter", 8)
+	fields := schema.Fields()
+
+	for _, depth := range []int{1, 4, 16} {
+		filter := Eq(fields[1], "value")
+		for i := 1; i < depth; i++ {
+			field := fields[1+i%(len(fields)-1)]
+			switch i % 3 {
+			case 0:
+				filter = filter.And(Gte(field, i))
+			case 1:
+				filter = filter.Or(In(field, []any{i, i + 1}))
+			default:
+				filter = filter.And(Between(field, i, i*2).Not())
+			}
+		}
+
+		b.Run(fmt.Sprintf("depth=%d", depth), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if ResolveFilter(filter) == nil {
+					b.Fatal("filter resolved to nil")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBulkSetValue(b *testing.B) {
+	for _, n := range benchSizes {
+		schema := benchSchema("bench_set_value", n)
+		fields := schema.Fields()[1:]
+
+		b.Run(fmt.Sprintf("fields=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				record := NewMongoRecord(schema)
+				for i, field := range fields {
+					if err := record.SetValue(field, benchValue(field, i)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("fields=%d/to=bson", n), func(b *testing.B) {
+			ctx := context.Background()
+			b.ReportAllocs()
+			for b.Loop() {
+				record := NewMongoRecord(schema)
+				for i, field := range fields {
+					if err := record.SetValue(field, benchValue(field, i)); err != nil {
+						b.Fatal(err)
+					}
+				}
+				if _, err := record.ToBSON(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEagerLoading(b *testing.B) {
+	ctx := benchMongoContext(b)
+
+	authors := NewSchema("bench_authors").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Build()
+	posts := NewSchema("bench_posts").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Ref("author", authors).
+		Build()
+	author := mustField(b, posts, "author")
+
+	for _, n := range []int{10, 100} {
+		seedEagerLoading(b, ctx, authors, posts, n)
+
+		b.Run(fmt.Sprintf("records=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				records, err := NewMongoQuery(ctx, posts).
+					With(author.(JRef), func(_ JSchema, q Query) Query { return q }).
+					Execute()
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(records) != n {
+					b.Fatalf("loaded %d records, want %d", len(records), n)
+				}
+			}
+		})
+	}
+}
+
+// benchMongoContext connects to a local MongoDB, skipping the benchmark when
+// none is reachable.
+func benchMongoContext(b *testing.B) context.Context {
+	b.Helper()
+	client, err := mongo.Connect(options.Client().
+		ApplyURI("mongodb://localhost:27017").
+		SetServerSelectionTimeout(time.Second))
+	if err != nil {
+		b.Skipf("mongodb unavailable: %v", err)
+	}
+	b.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	if err := client.Ping(context.Background(), nil); err != nil {
+		b.Skipf("mongodb unavailable: %v", err)
+	}
+
+	db := client.Database("jpack_bench")
+	if err := db.Drop(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+	return WithDatabase(context.Background(), db)
+}
+
+// seedEagerLoading replaces the stored posts and authors with n posts, each
+// by its own author.
+func seedEagerLoading(b *testing.B, ctx context.Context, authors, posts JSchema, n int) {
+	b.Helper()
+	for _, schema := range []JSchema{authors, posts} {
+		if err := collection(ctx, schema).Drop(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	name := mustField(b, authors, "name")
+	title := mustField(b, posts, "title")
+	author := mustField(b, posts, "author")
+	for i := range n {
+		a := NewMongoRecord(authors)
+		a.SetValue(name, "author-"+strconv.Itoa(i))
+		if err := a.Save(ctx); err != nil {
+			b.Fatal(err)
+		}
+
+		p := NewMongoRecord(posts)
+		p.SetValue(title, "post-"+strconv.Itoa(i))
+		p.SetValue(author, a)
+		if err := p.Save(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// BoundedFieldType is implemented by numeric field types that only accept
+// values within a range. The bounds are exported to OpenAPI and schema docs,
+// so UIs get the constraints for free.
+type BoundedFieldType interface {
+	JFieldType
+
+	// Bounds returns the inclusive range of accepted values.
+	Bounds() (min, max int)
+}
+
+// FieldBounds returns the range of accepted values of a bounded field type,
+// also when it is wrapped.
+func FieldBounds(fType JFieldType) (min, max int, ok bool) {
+	for {
+		if bounded, ok := fType.(BoundedFieldType); ok {
+			min, max = bounded.Bounds()
+			return min, max, true
+		}
+		w, ok := fType.(interface{ Unwrap() JFieldType })
+		if !ok {
+			return 0, 0, false
+		}
+		fType = w.Unwrap()
+	}
+}
+
+// validateBounds validates a value as a Number within the bounds of t.
+func validateBounds(t BoundedFieldType, n *Number, value any) error {
+	if err := n.Validate(value); err != nil {
+		return err
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		return nil
+	}
+	v, err := convertToInt(reflectValue)
+	if err != nil {
+		return err
+	}
+	if min, max := t.Bounds(); v < min || v > max {
+		return fmt.Errorf("value %d is not between %d and %d", v, min, max)
+	}
+	return nil
+}
+
+// Percent is a Number field type for whole percentages from 0 to 100.
+type Percent struct {
+	Number
+}
+
+var _ BoundedFieldType = &Percent{}
+
+// Bounds implements BoundedFieldType.
+func (p *Percent) Bounds() (min, max int) {
+	return 0, 100
+}
+
+// Validate implements JFieldType.
+func (p *Percent) Validate(value any) error {
+	return validateBounds(p, &p.Number, value)
+}
+
+// SetValue implements JFieldType.
+func (p *Percent) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	if err := p.Validate(value); err != nil {
+		return err
+	}
+	return p.Number.SetValue(ctx, field, value, row)
+}
+
+// Unwrap returns the Number the type is stored as.
+func (p *Percent) Unwrap() JFieldType {
+	return &p.Number
+}
+
+// TypeName reports the registry name of the type.
+func (p *Percent) TypeName() string {
+	return "percent"
+}
+
+// Example returns an example value for generated documentation.
+func (p *Percent) Example() any {
+	return 42
+}
+
+const (
+	defaultRatingMin = 1
+	defaultRatingMax = 5
+)
+
+// Rating is a Number field type for ratings from Min to Max, 1 to 5 when
+// both are zero.
+type Rating struct {
+	Number
+	Min int
+	Max int
+}
+
+var _ BoundedFieldType = &Rating{}
+
+// Bounds implements BoundedFieldType.
+func (r *Rating) Bounds() (min, max int) {
+	if r.Min == 0 && r.Max == 0 {
+		return defaultRatingMin, defaultRatingMax
+	}
+	return r.Min, r.Max
+}
+
+// Validate implements JFieldType.
+func (r *Rating) Validate(value any) error {
+	return validateBounds(r, &r.Number, value)
+}
+
+// SetValue implements JFieldType.
+func (r *Rating) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	if err := r.Validate(value); err != nil {
+		return err
+	}
+	return r.Number.SetValue(ctx, field, value, row)
+}
+
+// Unwrap returns the Number the type is stored as.
+func (r *Rating) Unwrap() JFieldType {
+	return &r.Number
+}
+
+// TypeName reports the registry name of the type.
+func (r *Rating) TypeName() string {
+	return "rating"
+}
+
+// Example returns an example value for generated documentation.
+func (r *Rating) Example() any {
+	_, max := r.Bounds()
+	return max
+}
@@ -0,0 +1,69 @@
+package jpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedTypes(t *testing.T) {
+	schema := NewSchema("test_bounded").
+		Field("id", &String{}).
+		Field("discount", &Percent{}).
+		Field("stars", &Rating{}).
+		Field("score", &Rating{Min: 0, Max: 10}).
+		Build()
+	discount, stars, score := mustField(t, schema, "discount"), mustField(t, schema, "stars"), mustField(t, schema, "score")
+
+	t.Run("values outside the bounds are rejected", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(discount, 100))
+		assert.NoError(t, record.SetValue(discount, "0"))
+		assert.EqualError(t, record.SetValue(discount, 101), "value 101 is not between 0 and 100")
+		assert.Error(t, record.SetValue(discount, -1))
+		assert.Error(t, record.SetValue(discount, 12.5), "whole numbers only")
+		assert.NoError(t, record.SetValue(discount, nil))
+
+		assert.NoError(t, record.SetValue(stars, 5))
+		assert.EqualError(t, record.SetValue(stars, 0), "value 0 is not between 1 and 5")
+		assert.NoError(t, record.SetValue(score, 0))
+		assert.Error(t, record.SetValue(score, 11))
+
+		row := map[string]any{}
+		assert.Error(t, stars.Type().SetValue(t.Context(), stars, 6, row))
+		assert.NoError(t, stars.Type().SetValue(t.Context(), stars, int32(4), row))
+		assert.Equal(t, 4, row["stars"])
+	})
+
+	t.Run("bounds are exported", func(t *testing.T) {
+		min, max, ok := FieldBounds(Wrap(&Rating{}))
+		assert.True(t, ok)
+		assert.Equal(t, []int{1, 5}, []int{min, max})
+		_, _, ok = FieldBounds(&Number{})
+		assert.False(t, ok)
+
+		property := openAPIField(t.Context(), score)
+		assert.Equal(t, map[string]any{"type": "integer", "format": "int64", "minimum": 0, "maximum": 10}, property)
+		assert.Contains(t, describeField(t.Context(), discount), "between 0 and 100")
+		assert.Equal(t, 5, exampleValue(t.Context(), stars.Type()))
+
+		kind, err := exportKindOf(discount.Type())
+		assert.NoError(t, err)
+		assert.Equal(t, exportLong, kind)
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		fType, err := NewFieldType("rating", map[string]any{"min": 1, "max": 10})
+		assert.NoError(t, err)
+		assert.Equal(t, &Rating{Min: 1, Max: 10}, fType)
+		assert.Equal(t, "rating", FieldTypeName(fType))
+		assert.Equal(t, "percent", FieldTypeName(&Percent{}))
+	})
+
+	t.Run("docs", func(t *testing.T) {
+		var b strings.Builder
+		assert.NoError(t, GenerateDocs(t.Context(), &b, DocMarkdown, schema))
+		assert.Contains(t, b.String(), "between 1 and 5")
+	})
+}
@@ -0,0 +1,56 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRecordBSON(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ToBSON merges stored and pending values", func(t *testing.T) {
+		id := bson.NewObjectID()
+		record, err := RecordFromBSON(userSchema, bson.M{"_id": id, "first_name": "John", "age": int32(30)})
+		assert.NoError(t, err)
+
+		assert.NoError(t, record.SetValue(mustField(t, userSchema, "age"), 31))
+
+		doc, err := record.ToBSON(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"_id": id, "first_name": "John", "age": 31}, doc)
+		assert.Equal(t, []string{"age"}, record.DirtyKeys(), "ToBSON does not save")
+	})
+
+	t.Run("new records have no _id", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(mustField(t, userSchema, "email"), "john@example.com"))
+
+		doc, err := record.ToBSON(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"email": "john@example.com"}, doc)
+	})
+
+	t.Run("RecordFromBSON exposes the id as a string", func(t *testing.T) {
+		id := bson.NewObjectID()
+		record, err := RecordFromBSON(userSchema, bson.M{"_id": id})
+		assert.NoError(t, err)
+		assert.False(t, record.IsNew())
+
+		got, ok := record.Value(mustField(t, userSchema, "id"))
+		assert.True(t, ok)
+		assert.Equal(t, id.Hex(), got)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		doc := bson.M{"_id": bson.NewObjectID(), "first_name": "Jane", "last_name": "Doe", "age": 40}
+		record, err := RecordFromBSON(userSchema, doc)
+		assert.NoError(t, err)
+
+		got, err := record.ToBSON(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, doc, got)
+	})
+}
@@ -0,0 +1,71 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// defaultMainQueryShare is the share of a query's remaining deadline its main
+// find gets when refs are eager loaded, when Config.MainQueryShare isn't set.
+const defaultMainQueryShare = 0.8
+
+// StageTimeoutError is returned when a stage of a query, its main find or the
+// eager load of a ref, runs out of its share of the context's deadline. It
+// matches context.DeadlineExceeded.
+type StageTimeoutError struct {
+	Schema string
+	// Stage is "find" or "ref <name>".
+	Stage string
+	// Budget is the time the stage was given.
+	Budget time.Duration
+	Err    error
+}
+
+func (e *StageTimeoutError) Error() string {
+	return fmt.Sprintf("jpack: %s of %s exceeded its %s budget: %v", e.Stage, e.Schema, e.Budget.Round(time.Millisecond), e.Err)
+}
+
+func (e *StageTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// mainQueryShare returns the share of the deadline of a query's main find.
+func mainQueryShare(ctx context.Context) float64 {
+	share := ConfigFrom(ctx).MainQueryShare
+	if share <= 0 || share > 1 {
+		return defaultMainQueryShare
+	}
+	return share
+}
+
+// stageBudget bounds a stage by the share of the context's remaining
+// deadline. Without a deadline the stage isn't bounded and its budget is 0.
+func stageBudget(ctx context.Context, share float64) (context.Context, context.CancelFunc, time.Duration) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}, 0
+	}
+	budget := time.Duration(float64(time.Until(deadline)) * share)
+	stageCtx, cancel := context.WithTimeout(ctx, budget)
+	return stageCtx, cancel, budget
+}
+
+// stageError names the stage of a query that ran out of its budget.
+func stageError(schema JSchema, stage string, budget time.Duration, err error) error {
+	if budget <= 0 || !(errors.Is(err, context.DeadlineExceeded) || mongo.IsTimeout(err)) {
+		return err
+	}
+	var stageErr *StageTimeoutError
+	if errors.As(err, &stageErr) {
+		// Already named by a nested query
+		return err
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return &StageTimeoutError{Schema: schema.Name(), Stage: stage, Budget: budget, Err: err}
+}
@@ -0,0 +1,66 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageBudget(t *testing.T) {
+	t.Run("stages get a share of the remaining deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		stageCtx, cancelStage, budget := stageBudget(ctx, 0.8)
+		defer cancelStage()
+		assert.InDelta(t, 800*time.Millisecond, budget, float64(50*time.Millisecond))
+		deadline, ok := stageCtx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(budget), deadline, 50*time.Millisecond)
+	})
+
+	t.Run("without a deadline stages aren't bounded", func(t *testing.T) {
+		ctx, cancel, budget := stageBudget(context.Background(), 0.8)
+		defer cancel()
+		assert.Zero(t, budget)
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+
+	t.Run("the share is configurable", func(t *testing.T) {
+		assert.Equal(t, 0.8, mainQueryShare(context.Background()))
+		assert.Equal(t, 0.5, mainQueryShare(WithConfig(context.Background(), Config{MainQueryShare: 0.5})))
+	})
+
+	t.Run("timeouts name their stage", func(t *testing.T) {
+		err := stageError(userSchema, "ref manager", 200*time.Millisecond, context.DeadlineExceeded)
+		var stageErr *StageTimeoutError
+		assert.ErrorAs(t, err, &stageErr)
+		assert.Equal(t, "ref manager", stageErr.Stage)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.EqualError(t, err, "jpack: ref manager of test_user exceeded its 200ms budget: context deadline exceeded")
+
+		assert.Same(t, err, stageError(userSchema, "find", time.Second, err), "nested stages keep their name")
+
+		other := errors.New("boom")
+		assert.Same(t, other, stageError(userSchema, "find", time.Second, other))
+	})
+
+	t.Run("the main find leaves time to the refs", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(offlineContext(t), 100*time.Millisecond)
+		defer cancel()
+
+		posts := NewSchema("test_budget_posts").Field("id", &String{}).Ref("author", userSchema).Build()
+		author := mustField(t, posts, "author").(JRef)
+		_, err := NewQuery(ctx, posts).With(author, func(_ JSchema, q Query) Query { return q }).Execute()
+		var stageErr *StageTimeoutError
+		if assert.ErrorAs(t, err, &stageErr) {
+			assert.Equal(t, "find", stageErr.Stage)
+			assert.Less(t, stageErr.Budget, 85*time.Millisecond)
+		}
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,117 @@
+package jpack
+
+import (
+	"maps"
+	"slices"
+)
+
+// Capabilities describes what the backend of a query or store supports, so
+// generic code can branch instead of failing at run time.
+type Capabilities struct {
+	// Transactions reports support for writes of several records that
+	// commit together, e.g. UnitOfWork.
+	Transactions bool
+
+	// Regex reports whether LIKE and NOT LIKE filters, which take regular
+	// expressions, run.
+	Regex bool
+
+	// Aggregation reports support for aggregations such as BucketBy.
+	Aggregation bool
+
+	// MaxBatchSize is the most writes sent to the backend at once; 1 means
+	// every write is sent on its own.
+	MaxBatchSize int
+
+	// Operators lists the filter operators the backend runs, sorted.
+	Operators []string
+}
+
+// SupportsOperator reports whether filters with the operator run.
+func (c Capabilities) SupportsOperator(operator string) bool {
+	_, found := slices.BinarySearch(c.Operators, operator)
+	return found
+}
+
+// SupportsFilter reports whether every operator of a filter tree runs.
+func (c Capabilities) SupportsFilter(filter Filter) bool {
+	if filter == nil {
+		return true
+	}
+	if !c.SupportsOperator(filter.Operator()) {
+		return false
+	}
+	return c.SupportsFilter(filter.Left()) && c.SupportsFilter(filter.Right())
+}
+
+// intersect returns the capabilities supported by both c and other.
+func (c Capabilities) intersect(other Capabilities) Capabilities {
+	return Capabilities{
+		Transactions: c.Transactions && other.Transactions,
+		Regex:        c.Regex && other.Regex,
+		Aggregation:  c.Aggregation && other.Aggregation,
+		MaxBatchSize: min(c.MaxBatchSize, other.MaxBatchSize),
+		Operators: slices.DeleteFunc(slices.Clone(c.Operators), func(operator string) bool {
+			return !other.SupportsOperator(operator)
+		}),
+	}
+}
+
+// mongoMaxWriteBatchSize is the most writes MongoDB accepts in one bulk write.
+const mongoMaxWriteBatchSize = 100_000
+
+// MongoCapabilities returns the capabilities of MongoDB stores. Transactions
+// need a replica set or a sharded cluster.
+func MongoCapabilities() Capabilities {
+	return Capabilities{
+		Transactions: true,
+		Regex:        true,
+		Aggregation:  true,
+		MaxBatchSize: mongoMaxWriteBatchSize,
+		Operators:    resolvableOperators(filterResolvers),
+	}
+}
+
+// Capabilities returns the capabilities of SQLite stores. SQLite has no
+// regular expressions of its own, so LIKE filters only run after
+// RegisterSQLiteRegexpFilters.
+func (s *SQLiteStore) Capabilities() Capabilities {
+	operators := resolvableOperators(sqlFilterResolvers)
+	return Capabilities{
+		Transactions: true,
+		Regex:        slices.Contains(operators, "LIKE") && slices.Contains(operators, "NOT LIKE"),
+		MaxBatchSize: 1,
+		Operators:    operators,
+	}
+}
+
+// resolvableOperators returns the sorted operators of a resolver registry,
+// with the logical operators every backend runs.
+func resolvableOperators[R any](resolvers map[string]R) []string {
+	operators := append(slices.Collect(maps.Keys(resolvers)), "AND", "OR", "NOT")
+	slices.Sort(operators)
+	return slices.Compact(operators)
+}
+
+// Capabilities implements Query
+func (q *mongoQuery) Capabilities() Capabilities {
+	return MongoCapabilities()
+}
+
+// Capabilities implements Query
+func (q *sqliteQuery) Capabilities() Capabilities {
+	return q.store.Capabilities()
+}
+
+// Capabilities implements Query. A union supports what all of its queries
+// support.
+func (u *unionQuery) Capabilities() Capabilities {
+	if len(u.queries) == 0 {
+		return Capabilities{}
+	}
+	capabilities := u.queries[0].Capabilities()
+	for _, q := range u.queries[1:] {
+		capabilities = capabilities.intersect(q.Capabilities())
+	}
+	return capabilities
+}
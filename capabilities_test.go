@@ -0,0 +1,55 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	schema := NewSchema("test_capabilities").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Build()
+	name := mustField(t, schema, "name")
+
+	t.Run("MongoDB", func(t *testing.T) {
+		capabilities := NewQuery(offlineContext(t), schema).Capabilities()
+		assert.True(t, capabilities.Transactions)
+		assert.True(t, capabilities.Regex)
+		assert.True(t, capabilities.Aggregation)
+		assert.Equal(t, 100_000, capabilities.MaxBatchSize)
+		for _, operator := range []string{"=", "LIKE", "IN SUBNET", "MONEY BETWEEN", "AND", "NOT"} {
+			assert.True(t, capabilities.SupportsOperator(operator), operator)
+		}
+	})
+
+	t.Run("SQLite", func(t *testing.T) {
+		ctx := WithSQLite(context.Background(), NewSQLiteStore(nil))
+		capabilities := NewQuery(ctx, schema).Capabilities()
+		assert.True(t, capabilities.Transactions)
+		assert.False(t, capabilities.Regex)
+		assert.False(t, capabilities.Aggregation)
+		assert.Equal(t, 1, capabilities.MaxBatchSize)
+		assert.True(t, capabilities.SupportsOperator("BETWEEN"))
+		assert.False(t, capabilities.SupportsOperator("LIKE"))
+		assert.False(t, capabilities.SupportsOperator("IN SUBNET"))
+	})
+
+	t.Run("SupportsFilter checks the whole tree", func(t *testing.T) {
+		capabilities := NewSQLiteStore(nil).Capabilities()
+		assert.True(t, capabilities.SupportsFilter(nil))
+		assert.True(t, capabilities.SupportsFilter(Eq(name, "Ada").Or(Exists(name)).Not()))
+		assert.False(t, capabilities.SupportsFilter(Eq(name, "Ada").And(Like(name, "^A"))))
+	})
+
+	t.Run("unions support what all their queries support", func(t *testing.T) {
+		mongo := NewQuery(offlineContext(t), schema)
+		sqlite := NewQuery(WithSQLite(context.Background(), NewSQLiteStore(nil)), schema)
+
+		capabilities := Union(mongo, sqlite).Capabilities()
+		assert.Equal(t, NewSQLiteStore(nil).Capabilities(), capabilities)
+		assert.Equal(t, Capabilities{}, Union().Capabilities())
+	})
+}
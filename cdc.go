@@ -0,0 +1,268 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ChangeMessage is a change event encoded for a message broker such as Kafka.
+// Key is the record id so a partitioned topic keeps each record's events in
+// order. A nil Value is a tombstone.
+type ChangeMessage struct {
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// ChangeEncoder encodes change events into message values.
+type ChangeEncoder interface {
+	Encode(event ChangeEvent) ([]byte, error)
+}
+
+// ChangePublisher publishes messages to a topic, e.g. a thin wrapper around a
+// Kafka producer. Publish must return only once the message is acknowledged.
+type ChangePublisher interface {
+	Publish(ctx context.Context, topic string, message ChangeMessage) error
+}
+
+// CheckpointStore persists the resume token of a CDC runner.
+type CheckpointStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load(ctx context.Context, name string) (bson.Raw, error)
+	Save(ctx context.Context, name string, token bson.Raw) error
+}
+
+// CDCRunner tails the change streams of the schemas and publishes every change
+// as a schema-tagged message. Events are published one at a time in stream
+// order and the resume token is checkpointed after each acknowledged publish,
+// so delivery is at-least-once: after a restart, the last event may be
+// published again.
+type CDCRunner struct {
+	// Name identifies the runner's checkpoint.
+	Name      string
+	Schemas   []JSchema
+	Publisher ChangePublisher
+
+	// Encoder defaults to JSONChangeEncoder.
+	Encoder ChangeEncoder
+	// Checkpoints defaults to a MongoCheckpointStore.
+	Checkpoints CheckpointStore
+	// Topic defaults to "jpack.<schema name>".
+	Topic func(schema JSchema) string
+}
+
+// Run publishes changes until ctx is done or publishing fails. Restarting a
+// failed runner resumes from the last checkpoint.
+func (r *CDCRunner) Run(ctx context.Context) error {
+	if r.Name == "" || r.Publisher == nil {
+		return errors.New("jpack: CDC runner needs a name and a publisher")
+	}
+
+	token, err := r.checkpoints().Load(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+
+	return WatchChanges(ctx, r.Schemas, token, func(event ChangeEvent) error {
+		return r.handle(ctx, event)
+	})
+}
+
+// handle publishes an event and checkpoints it.
+func (r *CDCRunner) handle(ctx context.Context, event ChangeEvent) error {
+	message := ChangeMessage{
+		Key: []byte(event.ID),
+		Headers: map[string]string{
+			"jpack-schema":    event.Schema.Name(),
+			"jpack-operation": string(event.Operation),
+		},
+	}
+
+	if event.Record != nil || event.Operation == ChangeDelete {
+		value, err := r.encoder().Encode(event)
+		if err != nil {
+			return err
+		}
+		message.Value = value
+	}
+
+	if err := r.Publisher.Publish(ctx, r.topic(event.Schema), message); err != nil {
+		return err
+	}
+
+	if event.ResumeToken == nil {
+		return nil
+	}
+	return r.checkpoints().Save(ctx, r.Name, event.ResumeToken)
+}
+
+func (r *CDCRunner) encoder() ChangeEncoder {
+	if r.Encoder == nil {
+		return JSONChangeEncoder{}
+	}
+	return r.Encoder
+}
+
+func (r *CDCRunner) checkpoints() CheckpointStore {
+	if r.Checkpoints == nil {
+		return &MongoCheckpointStore{}
+	}
+	return r.Checkpoints
+}
+
+func (r *CDCRunner) topic(schema JSchema) string {
+	if r.Topic == nil {
+		return "jpack." + schema.Name()
+	}
+	return r.Topic(schema)
+}
+
+// JSONChangeEncoder encodes events as JSON envelopes:
+// {"schema", "operation", "id", "time", "record"}.
+type JSONChangeEncoder struct{}
+
+// Encode implements ChangeEncoder.
+func (JSONChangeEncoder) Encode(event ChangeEvent) ([]byte, error) {
+	envelope := map[string]any{
+		"schema":    event.Schema.Name(),
+		"operation": event.Operation,
+		"id":        event.ID,
+		"time":      event.Time,
+		"record":    nil,
+	}
+
+	if event.Record != nil {
+		values, err := recordValues(event.Record)
+		if err != nil {
+			return nil, err
+		}
+		envelope["record"] = values
+	}
+
+	return json.Marshal(envelope)
+}
+
+// AvroChangeEncoder encodes records as Avro binary datums with the schema from
+// AvroSchema. Deletes are tombstones. Frame can wrap the datum, e.g. with a
+// schema registry id.
+type AvroChangeEncoder struct {
+	Frame func(schema JSchema, datum []byte) ([]byte, error)
+}
+
+// Encode implements ChangeEncoder.
+func (e AvroChangeEncoder) Encode(event ChangeEvent) ([]byte, error) {
+	if event.Record == nil {
+		return nil, nil
+	}
+
+	columns, err := exportColumns(event.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := exportRow(event.Record, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	datum := appendAvroRow(nil, columns, row)
+	if e.Frame != nil {
+		return e.Frame(event.Schema, datum)
+	}
+	return datum, nil
+}
+
+// ProtoChangeEncoder encodes records with MarshalProto. Deletes are tombstones.
+type ProtoChangeEncoder struct{}
+
+// Encode implements ChangeEncoder.
+func (ProtoChangeEncoder) Encode(event ChangeEvent) ([]byte, error) {
+	if event.Record == nil {
+		return nil, nil
+	}
+	return MarshalProto(event.Record)
+}
+
+const defaultCheckpointCollection = "jpack_cdc_checkpoints"
+
+// MongoCheckpointStore keeps checkpoints in a collection of the context's
+// database, "jpack_cdc_checkpoints" unless Collection is set.
+type MongoCheckpointStore struct {
+	Collection string
+}
+
+type checkpointDocument struct {
+	Name      string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func (s *MongoCheckpointStore) collection(ctx context.Context) *mongo.Collection {
+	name := s.Collection
+	if name == "" {
+		name = defaultCheckpointCollection
+	}
+	return MustConn(ctx).Collection(name)
+}
+
+// Load implements CheckpointStore.
+func (s *MongoCheckpointStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	var doc checkpointDocument
+	err := s.collection(ctx).FindOne(ctx, bson.M{"_id": name}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+// Save implements CheckpointStore.
+func (s *MongoCheckpointStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	doc := checkpointDocument{Name: name, Token: token, UpdatedAt: time.Now().UTC()}
+	_, err := s.collection(ctx).ReplaceOne(ctx, bson.M{"_id": name}, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory, for tests and runners
+// that may replay from the current time after a restart.
+type MemoryCheckpointStore struct {
+	mu     sync.Mutex
+	tokens map[string]bson.Raw
+}
+
+// Load implements CheckpointStore.
+func (s *MemoryCheckpointStore) Load(ctx context.Context, name string) (bson.Raw, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokens[name], nil
+}
+
+// Save implements CheckpointStore.
+func (s *MemoryCheckpointStore) Save(ctx context.Context, name string, token bson.Raw) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = make(map[string]bson.Raw)
+	}
+	s.tokens[name] = token
+	return nil
+}
+
+var (
+	_ CheckpointStore = &MongoCheckpointStore{}
+	_ CheckpointStore = &MemoryCheckpointStore{}
+	_ ChangeEncoder   = JSONChangeEncoder{}
+	_ ChangeEncoder   = AvroChangeEncoder{}
+	_ ChangeEncoder   = ProtoChangeEncoder{}
+)
@@ -0,0 +1,125 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type recordingPublisher struct {
+	topics   []string
+	messages []ChangeMessage
+	err      error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, message ChangeMessage) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.topics = append(p.topics, topic)
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func TestNewChangeEvent(t *testing.T) {
+	assert := assert.New(t)
+
+	id := bson.NewObjectID()
+	var doc changeDocument
+	doc.OperationType = "update"
+	doc.NS.Coll = userSchema.Name()
+	doc.DocumentKey.ID = id
+	doc.FullDocument = bson.M{"_id": id, "first_name": "Ada"}
+	doc.ClusterTime = bson.Timestamp{T: 1700000000}
+
+	event, err := newChangeEvent(userSchema, doc)
+	assert.NoError(err)
+	assert.Equal(ChangeUpdate, event.Operation)
+	assert.Equal(id.Hex(), event.ID)
+	assert.Equal(int64(1700000000), event.Time.Unix())
+
+	name, ok := event.Record.Value(mustField(t, userSchema, "first_name"))
+	assert.True(ok)
+	assert.Equal("Ada", name)
+
+	doc.OperationType = "delete"
+	event, err = newChangeEvent(userSchema, doc)
+	assert.NoError(err)
+	assert.Nil(event.Record)
+}
+
+func TestCDCRunner_handle(t *testing.T) {
+	ctx := context.Background()
+	id := bson.NewObjectID()
+
+	record, err := RecordFromBSON(userSchema, bson.M{"_id": id, "first_name": "Ada", "age": int32(36)})
+	assert.NoError(t, err)
+
+	token, err := bson.Marshal(bson.M{"_data": "1"})
+	assert.NoError(t, err)
+
+	upsert := ChangeEvent{
+		Schema:      userSchema,
+		Operation:   ChangeInsert,
+		ID:          id.Hex(),
+		Record:      record,
+		ResumeToken: token,
+	}
+
+	t.Run("publishes JSON and checkpoints", func(t *testing.T) {
+		assert := assert.New(t)
+
+		publisher := &recordingPublisher{}
+		checkpoints := &MemoryCheckpointStore{}
+		runner := &CDCRunner{Name: "test", Publisher: publisher, Checkpoints: checkpoints}
+
+		assert.NoError(runner.handle(ctx, upsert))
+		assert.Equal([]string{"jpack.test_user"}, publisher.topics)
+
+		message := publisher.messages[0]
+		assert.Equal(id.Hex(), string(message.Key))
+		assert.Equal("test_user", message.Headers["jpack-schema"])
+		assert.Equal("insert", message.Headers["jpack-operation"])
+
+		var envelope map[string]any
+		assert.NoError(json.Unmarshal(message.Value, &envelope))
+		assert.Equal("insert", envelope["operation"])
+		assert.Equal(map[string]any{"id": id.Hex(), "first_name": "Ada", "age": float64(36)}, envelope["record"])
+
+		saved, err := checkpoints.Load(ctx, "test")
+		assert.NoError(err)
+		assert.Equal(bson.Raw(token), saved)
+	})
+
+	t.Run("binary encoders send tombstones for deletes", func(t *testing.T) {
+		assert := assert.New(t)
+
+		publisher := &recordingPublisher{}
+		runner := &CDCRunner{Name: "test", Publisher: publisher, Encoder: ProtoChangeEncoder{}, Checkpoints: &MemoryCheckpointStore{}}
+
+		assert.NoError(runner.handle(ctx, ChangeEvent{Schema: userSchema, Operation: ChangeDelete, ID: id.Hex()}))
+		assert.Nil(publisher.messages[0].Value)
+		assert.Equal("delete", publisher.messages[0].Headers["jpack-operation"])
+	})
+
+	t.Run("failed publishes are not checkpointed", func(t *testing.T) {
+		assert := assert.New(t)
+
+		checkpoints := &MemoryCheckpointStore{}
+		runner := &CDCRunner{
+			Name:        "test",
+			Publisher:   &recordingPublisher{err: errors.New("broker unavailable")},
+			Encoder:     AvroChangeEncoder{},
+			Checkpoints: checkpoints,
+		}
+
+		assert.Error(runner.handle(ctx, upsert))
+		saved, err := checkpoints.Load(ctx, "test")
+		assert.NoError(err)
+		assert.Nil(saved)
+	})
+}
@@ -0,0 +1,141 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// ChangeOperation is the kind of write a ChangeEvent describes.
+type ChangeOperation string
+
+const (
+	ChangeInsert  ChangeOperation = "insert"
+	ChangeUpdate  ChangeOperation = "update"
+	ChangeReplace ChangeOperation = "replace"
+	ChangeDelete  ChangeOperation = "delete"
+)
+
+// ChangeEvent is a write to a record observed on a change stream.
+type ChangeEvent struct {
+	Schema    JSchema
+	Operation ChangeOperation
+	ID        string
+	// Record is the record after the change, nil for deletes and for updates
+	// of records deleted before they could be looked up.
+	Record JRecord
+	Time   time.Time
+	// ResumeToken resumes a stream right after this event.
+	ResumeToken bson.Raw
+}
+
+// changeDocument is the subset of a change stream document jpack reads.
+type changeDocument struct {
+	OperationType string `bson:"operationType"`
+	NS            struct {
+		Coll string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey struct {
+		ID any `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument bson.M         `bson:"fullDocument"`
+	ClusterTime  bson.Timestamp `bson:"clusterTime"`
+}
+
+// WatchChanges tails the change stream of the schemas' collections and calls
+// handle for every insert, update, replace and delete, in order. Updates carry
+// the current record. A nil resumeToken starts at the current time. It returns
+// handle's first error, or nil once ctx is done. The schemas must share a
+// store.
+func WatchChanges(ctx context.Context, schemas []JSchema, resumeToken bson.Raw, handle func(ChangeEvent) error) error {
+	bySchema := make(map[string]JSchema, len(schemas))
+	names := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		if StoreOf(schema) != StoreOf(schemas[0]) {
+			return fmt.Errorf("jpack: can't watch %s and %s, they are in different stores", schemas[0].Name(), schema.Name())
+		}
+		name := collectionName(ctx, schema)
+		bySchema[name] = schema
+		names = append(names, name)
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"ns.coll":       bson.M{"$in": names},
+		"operationType": bson.M{"$in": bson.A{ChangeInsert, ChangeUpdate, ChangeReplace, ChangeDelete}},
+	}}}}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	var db *mongo.Database
+	if len(schemas) > 0 {
+		db = mustDatabaseFor(ctx, schemas[0])
+	} else {
+		db = MustConn(ctx)
+	}
+	stream, err := db.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.WithoutCancel(ctx))
+
+	for stream.Next(ctx) {
+		var doc changeDocument
+		if err := stream.Decode(&doc); err != nil {
+			return err
+		}
+
+		schema, ok := bySchema[doc.NS.Coll]
+		if !ok {
+			continue
+		}
+
+		event, err := newChangeEvent(schema, doc)
+		if err != nil {
+			return err
+		}
+		event.ResumeToken = stream.ResumeToken()
+
+		if err := handle(event); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return stream.Err()
+}
+
+// newChangeEvent converts a change stream document of the schema's collection.
+func newChangeEvent(schema JSchema, doc changeDocument) (ChangeEvent, error) {
+	event := ChangeEvent{
+		Schema:    schema,
+		Operation: ChangeOperation(doc.OperationType),
+		Time:      time.Unix(int64(doc.ClusterTime.T), 0).UTC(),
+	}
+
+	switch id := doc.DocumentKey.ID.(type) {
+	case bson.ObjectID:
+		event.ID = id.Hex()
+	case nil:
+	default:
+		event.ID = fmt.Sprint(id)
+	}
+
+	if doc.FullDocument != nil && event.Operation != ChangeDelete {
+		record, err := RecordFromBSON(schema, doc.FullDocument)
+		if err != nil {
+			return ChangeEvent{}, err
+		}
+		event.Record = record
+	}
+
+	return event, nil
+}
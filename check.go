@@ -0,0 +1,439 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const checkBatchSize = 500
+
+// CheckIssueKind is the kind of constraint a stored document violates.
+type CheckIssueKind string
+
+const (
+	// IssueTypeMismatch is a value the field type can't scan, e.g. a string
+	// stored for a Number, or a ref that isn't an ObjectID hex string.
+	IssueTypeMismatch CheckIssueKind = "type_mismatch"
+	// IssueDanglingRef is a ref to a record that doesn't exist.
+	IssueDanglingRef CheckIssueKind = "dangling_ref"
+	// IssueInvalidOption is an Options value its service doesn't offer.
+	IssueInvalidOption CheckIssueKind = "invalid_option"
+	// IssueMissingRequired is a Required field without a value.
+	IssueMissingRequired CheckIssueKind = "missing_required"
+)
+
+// CheckIssue is a field of a stored document that violates the schema.
+type CheckIssue struct {
+	Schema string
+	// ID is the primary key of the document.
+	ID    string
+	Field string
+	Kind  CheckIssueKind
+	// Value is the stored value.
+	Value any
+	Err   error
+	// Fixed reports whether an auto-fix strategy rewrote the value.
+	Fixed bool
+}
+
+func (i CheckIssue) String() string {
+	s := fmt.Sprintf("%s %s: %s: %s", i.Schema, i.ID, i.Field, i.Kind)
+	if i.Err != nil {
+		s += ": " + i.Err.Error()
+	}
+	if i.Fixed {
+		s += " (fixed)"
+	}
+	return s
+}
+
+// CheckReport is the outcome of Check.
+type CheckReport struct {
+	// Scanned is the number of documents checked per schema.
+	Scanned map[string]int
+	Issues  []CheckIssue
+}
+
+// OK reports whether no issues were found.
+func (r *CheckReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Count returns the number of issues of a kind.
+func (r *CheckReport) Count(kind CheckIssueKind) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Fixed returns the number of issues that were fixed.
+func (r *CheckReport) Fixed() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Fixed {
+			n++
+		}
+	}
+	return n
+}
+
+// FixStrategy is how AutoFix rewrites an offending value.
+type FixStrategy int
+
+const (
+	// FixUnset removes the value. Required fields are left alone.
+	FixUnset FixStrategy = iota + 1
+	// FixNull replaces the value with null. Required fields are left alone.
+	FixNull
+	// FixDefault replaces the value with the field's default. Fields without
+	// a client-side default are left alone.
+	FixDefault
+)
+
+// CheckOption configures Check.
+type CheckOption func(*checkOptions)
+
+type checkOptions struct {
+	fixes map[CheckIssueKind]FixStrategy
+}
+
+// AutoFix makes Check rewrite the values with issues of the given kinds, or
+// of every kind when none are given.
+func AutoFix(strategy FixStrategy, kinds ...CheckIssueKind) CheckOption {
+	if len(kinds) == 0 {
+		kinds = []CheckIssueKind{IssueTypeMismatch, IssueDanglingRef, IssueInvalidOption, IssueMissingRequired}
+	}
+	return func(o *checkOptions) {
+		for _, kind := range kinds {
+			o.fixes[kind] = strategy
+		}
+	}
+}
+
+// checker checks documents in batches. The lookups of referenced records
+// and the writes of fixes can be replaced in tests.
+type checker struct {
+	fixes  map[CheckIssueKind]FixStrategy
+	exists func(ctx context.Context, schema JSchema, ids []string) (map[string]bool, error)
+	write  func(ctx context.Context, schema JSchema, models []mongo.WriteModel) error
+}
+
+func newChecker(opts []CheckOption) *checker {
+	o := &checkOptions{fixes: make(map[CheckIssueKind]FixStrategy)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &checker{fixes: o.fixes, exists: existingIDs, write: bulkUpdate}
+}
+
+// Check scans the stored documents of the schemas for values that violate
+// their current constraints: values of the wrong type, refs to missing
+// records, options the service doesn't offer and missing required values.
+// With AutoFix, the offending values are rewritten in place, without hooks.
+// It returns an error, with the report so far, when reading or fixing fails.
+func Check(ctx context.Context, schemas []JSchema, opts ...CheckOption) (*CheckReport, error) {
+	c := newChecker(opts)
+	report := &CheckReport{Scanned: make(map[string]int)}
+
+	if len(c.fixes) > 0 {
+		if IsReadOnly(ctx) {
+			return report, ErrReadOnly
+		}
+		for _, schema := range schemas {
+			if _, ok := schema.(*ViewSchema); ok {
+				return report, ErrViewReadOnly
+			}
+			if len(SerializersOf(schema)) > 0 {
+				return report, fmt.Errorf("jpack: can't fix %s: its documents are stored through serializers", schema.Name())
+			}
+		}
+	}
+
+	for _, schema := range schemas {
+		if err := c.checkCollection(ctx, schema, report); err != nil {
+			return report, fmt.Errorf("jpack: checking %s: %w", schema.Name(), err)
+		}
+	}
+	return report, nil
+}
+
+func (c *checker) checkCollection(ctx context.Context, schema JSchema, report *CheckReport) error {
+	cursor, err := collection(ctx, schema).Find(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var batch []bson.M
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		batch = append(batch, doc)
+		if len(batch) >= checkBatchSize {
+			if err := c.checkBatch(ctx, schema, batch, report); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return c.checkBatch(ctx, schema, batch, report)
+}
+
+// checkBatch checks a batch of stored documents of schema, and fixes them.
+func (c *checker) checkBatch(ctx context.Context, schema JSchema, docs []bson.M, report *CheckReport) error {
+	report.Scanned[schema.Name()] += len(docs)
+	first := len(report.Issues)
+
+	// Refs are checked with one lookup per referenced schema
+	type refCheck struct {
+		issue CheckIssue
+		doc   bson.M
+	}
+	refs := make(map[JSchema][]refCheck)
+	var refSchemas []JSchema
+	var issueDocs []bson.M
+
+	for _, raw := range docs {
+		doc, err := decodeDocument(ctx, schema, raw)
+		if err != nil {
+			return err
+		}
+		id := documentID(raw)
+
+		for _, field := range schema.Fields() {
+			issue, value := checkField(ctx, field, doc)
+			issue.Schema, issue.ID, issue.Field = schema.Name(), id, field.Name()
+			if issue.Kind != "" {
+				report.Issues = append(report.Issues, issue)
+				issueDocs = append(issueDocs, raw)
+				continue
+			}
+
+			ref, ok := field.(JRef)
+			if ok && value != nil && ref.RelSchema() != nil {
+				issue.Value = value
+				if _, ok := refs[ref.RelSchema()]; !ok {
+					refSchemas = append(refSchemas, ref.RelSchema())
+				}
+				refs[ref.RelSchema()] = append(refs[ref.RelSchema()], refCheck{issue: issue, doc: raw})
+			}
+		}
+	}
+
+	for _, refSchema := range refSchemas {
+		checks := refs[refSchema]
+		ids := make([]string, 0, len(checks))
+		for _, check := range checks {
+			ids = append(ids, check.issue.Value.(string))
+		}
+		found, err := c.exists(ctx, refSchema, ids)
+		if err != nil {
+			return err
+		}
+		for _, check := range checks {
+			if found[check.issue.Value.(string)] {
+				continue
+			}
+			check.issue.Kind = IssueDanglingRef
+			check.issue.Err = fmt.Errorf("%s %v does not exist", refSchema.Name(), check.issue.Value)
+			report.Issues = append(report.Issues, check.issue)
+			issueDocs = append(issueDocs, check.doc)
+		}
+	}
+
+	return c.fix(ctx, schema, report.Issues[first:], issueDocs)
+}
+
+// checkField returns the issue of a field of a decoded document, if any, and
+// its scanned value.
+func checkField(ctx context.Context, field JField, doc bson.M) (CheckIssue, any) {
+	stored, _ := RowValue(field, doc)
+	issue := CheckIssue{Value: stored}
+
+	value, err := scanField(ctx, field, doc)
+	if err != nil {
+		issue.Kind, issue.Err = IssueTypeMismatch, err
+		return issue, nil
+	}
+	if value == nil {
+		if IsRequired(field) {
+			issue.Kind = IssueMissingRequired
+		}
+		return issue, nil
+	}
+
+	switch t := baseType(field.Type()).(type) {
+	case *Ref:
+		if _, err := bson.ObjectIDFromHex(value.(string)); err != nil {
+			issue.Kind, issue.Err = IssueTypeMismatch, fmt.Errorf("value is not a valid ObjectID hex string")
+		}
+	case *Options:
+		if err := t.Validate(value); err != nil {
+			issue.Kind, issue.Err = IssueInvalidOption, err
+		}
+	}
+	return issue, value
+}
+
+// fix writes the fixes of the issues found in a batch, and marks them fixed.
+// docs holds the stored document of every issue.
+func (c *checker) fix(ctx context.Context, schema JSchema, issues []CheckIssue, docs []bson.M) error {
+	if len(c.fixes) == 0 || len(issues) == 0 {
+		return nil
+	}
+
+	type documentFix struct {
+		doc    bson.M
+		set    bson.M
+		unset  bson.M
+		issues []int
+	}
+	var order []string
+	fixes := make(map[string]*documentFix)
+
+	for i, issue := range issues {
+		field, _ := schema.Field(issue.Field)
+		set, unset, ok := c.fixField(ctx, field, issue.Kind, docs[i])
+		if !ok {
+			continue
+		}
+
+		f, ok := fixes[issue.ID]
+		if !ok {
+			f = &documentFix{doc: docs[i], set: bson.M{}, unset: bson.M{}}
+			fixes[issue.ID] = f
+			order = append(order, issue.ID)
+		}
+		for key, value := range set {
+			f.set[key] = value
+		}
+		for key := range unset {
+			f.unset[key] = ""
+		}
+		f.issues = append(f.issues, i)
+	}
+	if len(order) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(order))
+	for _, id := range order {
+		f := fixes[id]
+		update := bson.M{}
+		if len(f.set) > 0 {
+			update["$set"] = f.set
+		}
+		if len(f.unset) > 0 {
+			update["$unset"] = f.unset
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{defaultMongoPK: f.doc[defaultMongoPK]}).SetUpdate(update))
+	}
+	if err := c.write(ctx, schema, models); err != nil {
+		return err
+	}
+
+	for _, id := range order {
+		for _, i := range fixes[id].issues {
+			issues[i].Fixed = true
+		}
+	}
+	return nil
+}
+
+// fixField returns the update fixing a field of a stored document with the
+// strategy for the issue kind, if it applies.
+func (c *checker) fixField(ctx context.Context, field JField, kind CheckIssueKind, doc bson.M) (set, unset bson.M, ok bool) {
+	strategy, ok := c.fixes[kind]
+	if !ok {
+		return nil, nil, false
+	}
+
+	// Values stored under an alias are removed, the value moves to the current name
+	unset = bson.M{}
+	for _, alias := range FieldAliases(field) {
+		if _, stored := doc[alias]; stored {
+			unset[alias] = ""
+		}
+	}
+
+	set = bson.M{}
+	switch strategy {
+	case FixUnset, FixNull:
+		if IsRequired(field) {
+			return nil, nil, false
+		}
+		for _, key := range storageKeys(field) {
+			if strategy == FixNull {
+				set[key] = nil
+			} else if _, stored := doc[key]; stored {
+				unset[key] = ""
+			}
+		}
+	case FixDefault:
+		defaultValue := field.Default()
+		if defaultValue == nil || IsServerDefault(defaultValue) {
+			return nil, nil, false
+		}
+		record := NewMongoRecord(field.Schema())
+		converted, err := record.convertToBSON(ctx, map[string]any{field.Name(): resolveDefault(ctx, defaultValue)})
+		if err != nil {
+			return nil, nil, false
+		}
+		for key, value := range converted {
+			set[key] = value
+		}
+	default:
+		return nil, nil, false
+	}
+	return set, unset, true
+}
+
+// documentID returns the primary key of a stored document as a string.
+func documentID(doc bson.M) string {
+	if id, ok := doc[defaultMongoPK].(bson.ObjectID); ok {
+		return id.Hex()
+	}
+	return fmt.Sprint(doc[defaultMongoPK])
+}
+
+// existingIDs returns which of the primary keys have a stored document.
+func existingIDs(ctx context.Context, schema JSchema, ids []string) (map[string]bool, error) {
+	cursor, err := collection(ctx, schema).Find(ctx, idsFilter(ids), options.Find().SetProjection(bson.M{defaultMongoPK: 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	found := make(map[string]bool, len(ids))
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		found[documentID(doc)] = true
+	}
+	return found, cursor.Err()
+}
+
+// bulkUpdate writes update models to the collection of a schema.
+func bulkUpdate(ctx context.Context, schema JSchema, models []mongo.WriteModel) error {
+	coll := collection(ctx, schema)
+	start := time.Now()
+	_, err := coll.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "check", Count: int64(len(models)), Err: err}, start)
+	return err
+}
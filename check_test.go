@@ -0,0 +1,100 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestCheck(t *testing.T) {
+	authors := NewSchema("test_check_author").Field("id", &String{}).Build()
+	status := NewOptions(NewInMemoryOptionService([]Option{{UniqueName: "draft"}, {UniqueName: "published"}}))
+	schema := NewSchema("test_check").
+		Field("id", &String{}).
+		Field("title", &String{}, Required()).
+		Field("pages", &Number{}, FieldAlias("page_count")).
+		FieldWithDefault("status", status, "draft").
+		Ref("author", authors).
+		Build()
+
+	existing := bson.NewObjectID().Hex()
+	missing := bson.NewObjectID().Hex()
+	ids := []bson.ObjectID{bson.NewObjectID(), bson.NewObjectID(), bson.NewObjectID()}
+	docs := []bson.M{
+		{"_id": ids[0], "title": "Ok", "pages": int32(10), "status": "draft", "author": existing},
+		{"_id": ids[1], "title": 42, "page_count": "many", "status": "archived", "author": missing},
+		{"_id": ids[2], "author": "not-an-id"},
+	}
+
+	// newChecker records the lookups of refs and the fixes written
+	newChecker := func(opts ...CheckOption) (*checker, *[]mongo.WriteModel) {
+		var written []mongo.WriteModel
+		c := newChecker(opts)
+		c.exists = func(ctx context.Context, refSchema JSchema, ids []string) (map[string]bool, error) {
+			assert.Equal(t, authors, refSchema)
+			assert.Equal(t, []string{existing, missing}, ids)
+			return map[string]bool{existing: true}, nil
+		}
+		c.write = func(ctx context.Context, _ JSchema, models []mongo.WriteModel) error {
+			written = append(written, models...)
+			return nil
+		}
+		return c, &written
+	}
+
+	t.Run("report", func(t *testing.T) {
+		c, written := newChecker()
+		report := &CheckReport{Scanned: make(map[string]int)}
+		assert.NoError(t, c.checkBatch(t.Context(), schema, docs, report))
+		assert.Empty(t, *written)
+
+		assert.False(t, report.OK())
+		assert.Equal(t, 3, report.Scanned["test_check"])
+		var got []string
+		for _, issue := range report.Issues {
+			got = append(got, issue.ID+" "+issue.Field+" "+string(issue.Kind))
+		}
+		assert.Equal(t, []string{
+			ids[1].Hex() + " title type_mismatch",
+			ids[1].Hex() + " pages type_mismatch",
+			ids[1].Hex() + " status invalid_option",
+			ids[2].Hex() + " title missing_required",
+			ids[2].Hex() + " author type_mismatch",
+			ids[1].Hex() + " author dangling_ref",
+		}, got)
+		assert.Equal(t, "many", report.Issues[1].Value, "values under an alias are checked")
+		assert.Equal(t, 3, report.Count(IssueTypeMismatch))
+		assert.Equal(t, 1, report.Count(IssueDanglingRef))
+		assert.Contains(t, report.Issues[5].String(), "test_check_author "+missing+" does not exist")
+	})
+
+	t.Run("auto-fix", func(t *testing.T) {
+		c, written := newChecker(AutoFix(FixUnset), AutoFix(FixDefault, IssueInvalidOption))
+		report := &CheckReport{Scanned: make(map[string]int)}
+		assert.NoError(t, c.checkBatch(t.Context(), schema, docs, report))
+
+		updates := make(map[any]any)
+		for _, model := range *written {
+			update := model.(*mongo.UpdateOneModel)
+			updates[update.Filter.(bson.M)["_id"]] = update.Update
+		}
+		assert.Equal(t, map[any]any{
+			ids[1]: bson.M{"$set": bson.M{"status": "draft"}, "$unset": bson.M{"page_count": "", "author": ""}},
+			ids[2]: bson.M{"$unset": bson.M{"author": ""}},
+		}, updates)
+
+		// Required fields aren't unset
+		assert.Equal(t, 4, report.Fixed())
+		for _, issue := range report.Issues {
+			assert.Equal(t, issue.Field != "title", issue.Fixed, issue.String())
+		}
+	})
+
+	t.Run("fixes need a writable context", func(t *testing.T) {
+		_, err := Check(WithReadOnly(offlineContext(t)), []JSchema{schema}, AutoFix(FixNull))
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+}
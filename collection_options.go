@@ -0,0 +1,189 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CappedOptions makes a schema's collection a fixed-size capped collection,
+// which keeps documents in insertion order and drops the oldest ones once it
+// is full, e.g. for recent activity logs.
+type CappedOptions struct {
+	// SizeBytes is the maximum size of the collection; required.
+	SizeBytes int64
+	// MaxDocuments optionally caps the number of documents as well.
+	MaxDocuments int64
+}
+
+// TimeSeriesGranularity is the expected interval between measurements of the
+// same series.
+type TimeSeriesGranularity string
+
+const (
+	GranularitySeconds TimeSeriesGranularity = "seconds"
+	GranularityMinutes TimeSeriesGranularity = "minutes"
+	GranularityHours   TimeSeriesGranularity = "hours"
+)
+
+// TimeSeriesOptions makes a schema's collection a native time-series
+// collection, which stores measurements in compressed time buckets, e.g. for
+// metrics and events.
+type TimeSeriesOptions struct {
+	// TimeField names the datetime field holding the time of a measurement;
+	// required.
+	TimeField string
+	// MetaField optionally names the field identifying the series, e.g. a
+	// host or sensor id. Measurements are bucketed per meta value.
+	MetaField   string
+	Granularity TimeSeriesGranularity
+	// ExpireAfter optionally deletes measurements older than the duration.
+	ExpireAfter time.Duration
+}
+
+// Capped stores the schema in a capped collection, created by
+// CreateCollectionStep.
+func (s *SchemaBuilder) Capped(opts CappedOptions) *SchemaBuilder {
+	s.schema.capped = &opts
+	return s
+}
+
+// TimeSeries stores the schema in a time-series collection, created by
+// CreateCollectionStep.
+func (s *SchemaBuilder) TimeSeries(opts TimeSeriesOptions) *SchemaBuilder {
+	s.schema.timeSeries = &opts
+	return s
+}
+
+// CappedOf returns the capped collection options of a schema, or of the base
+// schema of a view.
+func CappedOf(schema JSchema) (CappedOptions, bool) {
+	if s, ok := storageSchema(schema).(interface{ Capped() (CappedOptions, bool) }); ok {
+		return s.Capped()
+	}
+	return CappedOptions{}, false
+}
+
+// TimeSeriesOf returns the time-series collection options of a schema, or of
+// the base schema of a view.
+func TimeSeriesOf(schema JSchema) (TimeSeriesOptions, bool) {
+	if s, ok := storageSchema(schema).(interface {
+		TimeSeries() (TimeSeriesOptions, bool)
+	}); ok {
+		return s.TimeSeries()
+	}
+	return TimeSeriesOptions{}, false
+}
+
+// CreateCollectionStep creates the capped or time-series collection of a
+// schema. MongoDB creates plain collections on first write, but these must
+// exist before it.
+type CreateCollectionStep struct {
+	Schema JSchema
+}
+
+// Description implements MigrationStep.
+func (s CreateCollectionStep) Description() string {
+	_, kind, _ := s.options()
+	return fmt.Sprintf("create %s collection %s", kind, storageSchema(s.Schema).Name())
+}
+
+// Apply implements MigrationStep. A collection that exists with the same kind
+// is left as is; an existing collection of another kind must be converted by
+// hand.
+func (s CreateCollectionStep) Apply(ctx context.Context) error {
+	opts, kind, err := s.options()
+	if err != nil {
+		return err
+	}
+
+	db := mustDatabaseFor(ctx, s.Schema)
+	name := collectionName(ctx, storageSchema(s.Schema))
+	specs, err := db.ListCollectionSpecifications(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if len(specs) > 0 {
+		if collectionKind(specs[0]) != kind {
+			return fmt.Errorf("jpack: collection %s exists and is not %s", name, kind)
+		}
+		return nil
+	}
+
+	return db.CreateCollection(ctx, name, opts)
+}
+
+// options validates the schema's collection options and converts them.
+func (s CreateCollectionStep) options() (*options.CreateCollectionOptionsBuilder, string, error) {
+	capped, isCapped := CappedOf(s.Schema)
+	ts, isTimeSeries := TimeSeriesOf(s.Schema)
+
+	switch {
+	case isCapped && isTimeSeries:
+		return nil, "", errors.New("jpack: a collection cannot be both capped and time-series")
+
+	case isCapped:
+		if capped.SizeBytes <= 0 {
+			return nil, "capped", errors.New("jpack: capped collections need a size")
+		}
+		opts := options.CreateCollection().SetCapped(true).SetSizeInBytes(capped.SizeBytes)
+		if capped.MaxDocuments > 0 {
+			opts.SetMaxDocuments(capped.MaxDocuments)
+		}
+		return opts, "capped", nil
+
+	case isTimeSeries:
+		field, ok := s.Schema.Field(ts.TimeField)
+		if !ok {
+			return nil, "time-series", fmt.Errorf("jpack: unknown time field %q", ts.TimeField)
+		}
+		if _, ok := baseType(field.Type()).(*DateTime); !ok {
+			return nil, "time-series", fmt.Errorf("jpack: time field %s is not a datetime", ts.TimeField)
+		}
+
+		tsOpts := options.TimeSeries().SetTimeField(ts.TimeField)
+		if ts.MetaField != "" {
+			if _, ok := s.Schema.Field(ts.MetaField); !ok {
+				return nil, "time-series", fmt.Errorf("jpack: unknown meta field %q", ts.MetaField)
+			}
+			tsOpts.SetMetaField(ts.MetaField)
+		}
+		if ts.Granularity != "" {
+			tsOpts.SetGranularity(string(ts.Granularity))
+		}
+		opts := options.CreateCollection().SetTimeSeriesOptions(tsOpts)
+		if ts.ExpireAfter > 0 {
+			opts.SetExpireAfterSeconds(int64(ts.ExpireAfter / time.Second))
+		}
+		return opts, "time-series", nil
+	}
+
+	return nil, "", fmt.Errorf("jpack: %s has no collection options", s.Schema.Name())
+}
+
+// collectionKind returns "capped", "time-series" or "plain".
+func collectionKind(spec mongo.CollectionSpecification) string {
+	if spec.Type == "timeseries" {
+		return "time-series"
+	}
+	if capped, ok := spec.Options.Lookup("capped").BooleanOK(); ok && capped {
+		return "capped"
+	}
+	return "plain"
+}
+
+// collectionOptionsChanged reports whether the capped or time-series options
+// differ between two versions of a schema.
+func collectionOptionsChanged(old, new JSchema) bool {
+	oldCapped, _ := CappedOf(old)
+	newCapped, _ := CappedOf(new)
+	oldTS, _ := TimeSeriesOf(old)
+	newTS, _ := TimeSeriesOf(new)
+	return !reflect.DeepEqual(oldCapped, newCapped) || !reflect.DeepEqual(oldTS, newTS)
+}
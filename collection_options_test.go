@@ -0,0 +1,100 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func metricsSchema() JSchema {
+	return NewSchema("metrics").
+		Field("id", &String{}).
+		Field("at", &DateTime{}).
+		Field("host", &String{}).
+		Field("latency", &Number{}).
+		TimeSeries(TimeSeriesOptions{TimeField: "at", MetaField: "host", Granularity: GranularityMinutes, ExpireAfter: 30 * 24 * time.Hour}).
+		Build()
+}
+
+func TestCreateCollectionStep(t *testing.T) {
+	t.Run("time-series", func(t *testing.T) {
+		step := CreateCollectionStep{Schema: metricsSchema()}
+		assert.Equal(t, "create time-series collection metrics", step.Description())
+
+		builder, kind, err := step.options()
+		assert.NoError(t, err)
+		assert.Equal(t, "time-series", kind)
+
+		opts := &options.CreateCollectionOptions{}
+		for _, set := range builder.List() {
+			assert.NoError(t, set(opts))
+		}
+		assert.Equal(t, int64(30*24*60*60), *opts.ExpireAfterSeconds)
+
+		ts := &options.TimeSeriesOptions{}
+		for _, set := range opts.TimeSeriesOptions.List() {
+			assert.NoError(t, set(ts))
+		}
+		assert.Equal(t, "at", ts.TimeField)
+		assert.Equal(t, "host", *ts.MetaField)
+		assert.Equal(t, "minutes", *ts.Granularity)
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		schema := NewSchema("activity").Field("id", &String{}).Capped(CappedOptions{SizeBytes: 1 << 20, MaxDocuments: 1000}).Build()
+		step := CreateCollectionStep{Schema: schema}
+		assert.Equal(t, "create capped collection activity", step.Description())
+
+		builder, _, err := step.options()
+		assert.NoError(t, err)
+		opts := &options.CreateCollectionOptions{}
+		for _, set := range builder.List() {
+			assert.NoError(t, set(opts))
+		}
+		assert.True(t, *opts.Capped)
+		assert.Equal(t, int64(1<<20), *opts.SizeInBytes)
+		assert.Equal(t, int64(1000), *opts.MaxDocuments)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		for name, schema := range map[string]JSchema{
+			"no size":           NewSchema("a").Capped(CappedOptions{}).Build(),
+			"unknown time":      NewSchema("a").TimeSeries(TimeSeriesOptions{TimeField: "at"}).Build(),
+			"non-datetime time": NewSchema("a").Field("at", &String{}).TimeSeries(TimeSeriesOptions{TimeField: "at"}).Build(),
+			"unknown meta":      NewSchema("a").Field("at", &DateTime{}).TimeSeries(TimeSeriesOptions{TimeField: "at", MetaField: "host"}).Build(),
+			"both":              NewSchema("a").Field("at", &DateTime{}).Capped(CappedOptions{SizeBytes: 1}).TimeSeries(TimeSeriesOptions{TimeField: "at"}).Build(),
+			"none":              NewSchema("a").Build(),
+		} {
+			_, _, err := CreateCollectionStep{Schema: schema}.options()
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("existing collections", func(t *testing.T) {
+		capped, _ := bson.Marshal(bson.M{"capped": true, "size": 1024})
+		assert.Equal(t, "capped", collectionKind(mongo.CollectionSpecification{Type: "collection", Options: capped}))
+		assert.Equal(t, "time-series", collectionKind(mongo.CollectionSpecification{Type: "timeseries"}))
+		empty, _ := bson.Marshal(bson.M{})
+		assert.Equal(t, "plain", collectionKind(mongo.CollectionSpecification{Type: "collection", Options: empty}))
+	})
+}
+
+func TestGenerateMigrationsCreatesCollection(t *testing.T) {
+	plain := NewSchema("metrics").Field("id", &String{}).Field("at", &DateTime{}).Field("host", &String{}).Field("latency", &Number{}).Build()
+	metrics := metricsSchema()
+
+	migrations := GenerateMigrations("2024-07-metrics", plain, metrics, time.Hour)
+	assert.Equal(t, []Migration{{ID: "2024-07-metrics", Steps: []MigrationStep{CreateCollectionStep{Schema: metrics}}}}, migrations)
+
+	assert.Nil(t, GenerateMigrations("noop", metrics, metricsSchema(), time.Hour))
+
+	// Views share the collection options of their base schema
+	view := NewView("metrics_by_host", metrics).Fields("host").Build()
+	ts, ok := TimeSeriesOf(view)
+	assert.True(t, ok)
+	assert.Equal(t, "at", ts.TimeField)
+}
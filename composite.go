@@ -0,0 +1,171 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// CompositeFieldType is implemented by field types whose value is stored under
+// several row keys instead of the field name, e.g. Money stored as an amount
+// and a currency column.
+type CompositeFieldType interface {
+	JFieldType
+
+	// StorageKeys returns the row keys the field reads from and writes to.
+	StorageKeys(field JField) []string
+}
+
+// storageKeys returns the row keys backing the field.
+func storageKeys(field JField) []string {
+	if composite, ok := field.Type().(CompositeFieldType); ok {
+		return composite.StorageKeys(field)
+	}
+	return []string{field.Name()}
+}
+
+// CompositePart is a single component of a Composite field.
+type CompositePart struct {
+	Name string
+	Type JFieldType
+}
+
+// Composite is a value object spanning multiple row keys. Its value is a
+// map[string]any keyed by part name; each part is stored under
+// "<field>_<part>" using the part's own field type.
+type Composite struct {
+	Parts []CompositePart
+}
+
+// NewComposite creates a new Composite field type from the given parts
+func NewComposite(parts ...CompositePart) *Composite {
+	return &Composite{
+		Parts: parts,
+	}
+}
+
+// StorageKeys implements CompositeFieldType.
+func (c *Composite) StorageKeys(field JField) []string {
+	keys := make([]string, 0, len(c.Parts))
+	for _, part := range c.Parts {
+		keys = append(keys, c.partKey(field, part))
+	}
+	return keys
+}
+
+// Scan implements JFieldType.
+func (c *Composite) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	result := make(map[string]any, len(c.Parts))
+	found := false
+
+	for _, part := range c.Parts {
+		v, err := part.Type.Scan(ctx, c.partField(field, part), row)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", part.Name, err)
+		}
+
+		if v != nil {
+			found = true
+		}
+		result[part.Name] = v
+	}
+
+	if !found {
+		return nil, nil // No part has a value, return nil
+	}
+
+	return result, nil
+}
+
+// SetValue implements JFieldType.
+func (c *Composite) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, clear every part
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		for _, key := range c.StorageKeys(field) {
+			row[key] = nil
+		}
+		return nil
+	}
+
+	parts, err := c.toMap(value)
+	if err != nil {
+		return err
+	}
+
+	for _, part := range c.Parts {
+		if err := part.Type.SetValue(ctx, c.partField(field, part), parts[part.Name], row); err != nil {
+			return fmt.Errorf("%s: %w", part.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate implements JFieldType.
+func (c *Composite) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	parts, err := c.toMap(value)
+	if err != nil {
+		return err
+	}
+
+	for name := range parts {
+		if _, ok := c.part(name); !ok {
+			return fmt.Errorf("unknown composite part %q", name)
+		}
+	}
+
+	for _, part := range c.Parts {
+		v, ok := parts[part.Name]
+		if !ok || v == nil {
+			continue
+		}
+
+		if err := part.Type.Validate(v); err != nil {
+			return fmt.Errorf("%s: %w", part.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Composite) toMap(value any) (map[string]any, error) {
+	switch v := value.(type) {
+	case map[string]any:
+		return v, nil
+	case *map[string]any:
+		return *v, nil
+	default:
+		return nil, errors.New("composite value must be a map[string]any")
+	}
+}
+
+func (c *Composite) part(name string) (CompositePart, bool) {
+	for _, part := range c.Parts {
+		if part.Name == name {
+			return part, true
+		}
+	}
+	return CompositePart{}, false
+}
+
+func (c *Composite) partKey(field JField, part CompositePart) string {
+	return field.Name() + "_" + part.Name
+}
+
+// partField returns a field that lets a part's type read and write its own row key.
+func (c *Composite) partField(field JField, part CompositePart) JField {
+	return &fieldImpl{
+		name:   c.partKey(field, part),
+		fType:  part.Type,
+		schema: field.Schema(),
+	}
+}
+
+var _ CompositeFieldType = &Composite{}
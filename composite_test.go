@@ -0,0 +1,81 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMoneyType() *Composite {
+	return NewComposite(
+		CompositePart{Name: "amount", Type: &Number{}},
+		CompositePart{Name: "currency", Type: &String{}},
+	)
+}
+
+func TestComposite(t *testing.T) {
+	ctx := context.Background()
+	schema := NewSchema("test_product").
+		Field("id", &String{}).
+		Field("price", newMoneyType()).
+		Build()
+	price := mustField(t, schema, "price")
+
+	t.Run("storage keys", func(t *testing.T) {
+		assert.Equal(t, []string{"price_amount", "price_currency"}, newMoneyType().StorageKeys(price))
+	})
+
+	t.Run("SetValue writes every part", func(t *testing.T) {
+		row := map[string]any{}
+		err := newMoneyType().SetValue(ctx, price, map[string]any{"amount": "1250", "currency": "EUR"}, row)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"price_amount": 1250, "price_currency": "EUR"}, row)
+	})
+
+	t.Run("SetValue nil clears every part", func(t *testing.T) {
+		row := map[string]any{"price_amount": 1, "price_currency": "EUR"}
+		assert.NoError(t, newMoneyType().SetValue(ctx, price, nil, row))
+		assert.Equal(t, map[string]any{"price_amount": nil, "price_currency": nil}, row)
+	})
+
+	t.Run("Scan reads every part", func(t *testing.T) {
+		got, err := newMoneyType().Scan(ctx, price, map[string]any{"price_amount": int32(1250), "price_currency": "EUR"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]any{"amount": 1250, "currency": "EUR"}, got)
+	})
+
+	t.Run("Scan missing parts returns nil", func(t *testing.T) {
+		got, err := newMoneyType().Scan(ctx, price, map[string]any{})
+		assert.NoError(t, err)
+		assert.Nil(t, got)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		money := newMoneyType()
+		assert.NoError(t, money.Validate(map[string]any{"amount": 10}))
+		assert.NoError(t, money.Validate(nil))
+		assert.Error(t, money.Validate(map[string]any{"amount": "ten"}))
+		assert.Error(t, money.Validate(map[string]any{"cents": 10}))
+		assert.Error(t, money.Validate("10 EUR"))
+	})
+
+	t.Run("record round trip", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(price, map[string]any{"amount": 10, "currency": "USD"}))
+		assert.Equal(t, []string{"price"}, record.DirtyKeys())
+
+		doc, err := record.convertToBSON(ctx, record.record)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, doc["price_amount"])
+		assert.Equal(t, "USD", doc["price_currency"])
+
+		loaded := NewMongoRecord(schema)
+		loaded.originalRecord = map[string]any{"price_amount": int64(10), "price_currency": "USD"}
+
+		got, ok := loaded.Value(price)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]any{"amount": 10, "currency": "USD"}, got)
+		assert.Equal(t, []JField{price}, loaded.Fields())
+	})
+}
@@ -0,0 +1,139 @@
+package jpack
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// configKey is the context key holding a Config set with WithConfig.
+	configKey key = "jpack.config"
+	// loggerKey is the context key holding a logger set with WithLogger.
+	loggerKey key = "jpack.logger"
+)
+
+// Config gathers the deployment-wide settings of jpack. The zero value keeps
+// the defaults. Set it for the whole process with SetConfig, or for the
+// operations of a context with WithConfig.
+type Config struct {
+	// Conversion is the conversion policy of schemas that don't set their
+	// own. Values are converted without a context, so it only applies
+	// through SetConfig.
+	Conversion ConversionPolicy
+
+	// Logger receives jpack's log output. nil uses zerolog's global logger.
+	Logger *zerolog.Logger
+
+	// OperationTimeout bounds each database operation of record writes,
+	// deletes and queries. Zero leaves them to the context's deadline.
+	OperationTimeout time.Duration
+
+	// CollectionName maps schema names to collection names, e.g. to add a
+	// prefix per deployment. nil uses the schema name.
+	CollectionName func(schema string) string
+
+	// PKField names the field holding the record id. Empty accepts "id" and
+	// "_id". Schemas are inspected without a context, so it only applies
+	// through SetConfig.
+	PKField string
+
+	// LogQueries logs every database operation like LogQueryTap, in
+	// addition to the query taps.
+	LogQueries bool
+
+	// ValidationConcurrency bounds the record checks of a record, e.g.
+	// DependentOptions lookups, that Save and Validate run at once. Zero
+	// uses 8; 1 runs them one after another.
+	ValidationConcurrency int
+
+	// MainQueryShare is the share of the context's remaining deadline a
+	// query's main find gets when it eager loads refs; the refs split the
+	// rest. Zero uses 0.8.
+	MainQueryShare float64
+}
+
+var (
+	configMu sync.RWMutex
+	config   Config
+)
+
+// SetConfig sets the package-wide configuration, including the package-wide
+// conversion policy.
+func SetConfig(cfg Config) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	config = cfg
+	SetConversionPolicy(cfg.Conversion)
+}
+
+// DefaultConfig returns the package-wide configuration.
+func DefaultConfig() Config {
+	configMu.RLock()
+	cfg := config
+	configMu.RUnlock()
+
+	cfg.Conversion = DefaultConversionPolicy()
+	return cfg
+}
+
+// WithConfig returns a context whose operations use cfg instead of the
+// package-wide configuration, e.g. a tenant's collection prefix or a tighter
+// timeout for a request.
+func WithConfig(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, configKey, cfg)
+}
+
+// ConfigFrom returns the configuration of the context, falling back to the
+// package-wide one.
+func ConfigFrom(ctx context.Context) Config {
+	if cfg, ok := ctx.Value(configKey).(Config); ok {
+		return cfg
+	}
+	return DefaultConfig()
+}
+
+// pkFieldName returns the configured name of the id field, if any.
+func pkFieldName() string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return config.PKField
+}
+
+// WithLogger returns a context whose jpack log output goes to l, e.g. a
+// logger carrying the request id. It takes precedence over Config.Logger.
+func WithLogger(ctx context.Context, l *zerolog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// LoggerFrom returns the logger jpack writes to in the context: the one set
+// with WithLogger, the configured Logger or zerolog's global logger.
+func LoggerFrom(ctx context.Context) *zerolog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*zerolog.Logger); ok && l != nil {
+		return l
+	}
+	if l := ConfigFrom(ctx).Logger; l != nil {
+		return l
+	}
+	return &log.Logger
+}
+
+// operationContext bounds a database operation by the configured timeout.
+func operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := ConfigFrom(ctx).OperationTimeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func() {}
+}
+
+// collectionName returns the name of the schema's collection.
+func collectionName(ctx context.Context, schema JSchema) string {
+	if name := ConfigFrom(ctx).CollectionName; name != nil {
+		return name(schema.Name())
+	}
+	return schema.Name()
+}
@@ -0,0 +1,89 @@
+package jpack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestConfig(t *testing.T) {
+	defer SetConfig(Config{})
+
+	t.Run("package-wide", func(t *testing.T) {
+		SetConfig(Config{Conversion: ConversionPolicy{Mode: Strict}, PKField: "uuid"})
+		defer SetConfig(Config{})
+
+		assert.Equal(t, Strict, DefaultConversionPolicy().Mode)
+		assert.Equal(t, "uuid", ConfigFrom(t.Context()).PKField)
+
+		schema := NewSchema("test_config_pk").Field("id", &String{}).Field("uuid", &String{}).Build()
+		pk, ok := PK(schema)
+		assert.True(t, ok)
+		assert.Equal(t, "uuid", pk.Name())
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		assert.Equal(t, Lenient, DefaultConversionPolicy().Mode)
+		pk, ok := PK(userSchema)
+		assert.True(t, ok)
+		assert.Equal(t, "id", pk.Name())
+
+		opCtx, cancel := operationContext(t.Context())
+		defer cancel()
+		_, hasDeadline := opCtx.Deadline()
+		assert.False(t, hasDeadline)
+	})
+
+	t.Run("per context", func(t *testing.T) {
+		ctx := WithConfig(offlineContext(t), Config{
+			CollectionName:   func(schema string) string { return "tenant_a_" + schema },
+			OperationTimeout: time.Second,
+		})
+
+		assert.Equal(t, "tenant_a_"+userSchema.Name(), collection(ctx, userSchema).Name())
+		assert.Equal(t, userSchema.Name(), collection(offlineContext(t), userSchema).Name())
+
+		opCtx, cancel := operationContext(ctx)
+		defer cancel()
+		deadline, ok := opCtx.Deadline()
+		assert.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Second), deadline, 100*time.Millisecond)
+	})
+
+	t.Run("query logging", func(t *testing.T) {
+		var out bytes.Buffer
+		l := zerolog.New(&out).Level(zerolog.DebugLevel)
+		ctx := WithConfig(t.Context(), Config{Logger: &l, LogQueries: true})
+
+		tapQuery(ctx, QueryOperation{Collection: "users", Operation: "find", Filter: bson.M{}}, time.Now())
+		assert.Equal(t, 1, strings.Count(out.String(), `"message":"jpack: query"`))
+		assert.Contains(t, out.String(), `"collection":"users"`)
+
+		out.Reset()
+		tapQuery(WithConfig(t.Context(), Config{Logger: &l}), QueryOperation{Collection: "users"}, time.Now())
+		assert.Empty(t, out.String())
+	})
+}
+
+func TestContextAccessors(t *testing.T) {
+	_, ok := DatabaseFrom(t.Context())
+	assert.False(t, ok)
+	assert.Panics(t, func() { MustConn(t.Context()) })
+
+	db := MustConn(offlineContext(t))
+	got, ok := DatabaseFrom(WithDatabase(t.Context(), db))
+	assert.True(t, ok)
+	assert.Same(t, db, got)
+
+	var configured, scoped bytes.Buffer
+	configuredLogger, scopedLogger := zerolog.New(&configured), zerolog.New(&scoped)
+	ctx := WithConfig(t.Context(), Config{Logger: &configuredLogger})
+	assert.Same(t, &configuredLogger, LoggerFrom(ctx))
+	assert.Same(t, &scopedLogger, LoggerFrom(WithLogger(ctx, &scopedLogger)))
+	assert.NotNil(t, LoggerFrom(t.Context()))
+}
@@ -0,0 +1,375 @@
+// Package fieldtypes hosts long-tail jpack field types: hex colors, semantic
+// versions, country codes, language tags and JSON pointers. Their values are
+// strings, validated strictly and stored in canonical form.
+//
+// Importing the package registers the types by name for config-driven
+// schemas:
+//
+//	import _ "github.com/kabi175/jpack/contrib/fieldtypes"
+//
+//	fType, err := jpack.NewFieldType("country_code", nil)
+package fieldtypes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/kabi175/jpack"
+	"golang.org/x/text/language"
+)
+
+func init() {
+	jpack.RegisterFieldType("color", func(config map[string]any) jpack.JFieldType { return &Color{} })
+	jpack.RegisterFieldType("semver", func(config map[string]any) jpack.JFieldType { return &SemVer{} })
+	jpack.RegisterFieldType("country_code", func(config map[string]any) jpack.JFieldType { return &CountryCode{} })
+	jpack.RegisterFieldType("language_tag", func(config map[string]any) jpack.JFieldType { return &LanguageTag{} })
+	jpack.RegisterFieldType("json_pointer", func(config map[string]any) jpack.JFieldType { return &JSONPointer{} })
+}
+
+// canonicalizer validates a string value and returns its canonical form.
+type canonicalizer func(s string) (string, error)
+
+// scan reads a string value of a field.
+func scan(field jpack.JField, row map[string]any) (any, error) {
+	v, ok := jpack.RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("value is not a string")
+	}
+	return s, nil
+}
+
+// setValue stores the canonical form of a string value.
+func setValue(field jpack.JField, value any, row map[string]any, canonical canonicalizer) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	s, err := validate(value, canonical)
+	if err != nil {
+		return err
+	}
+	row[field.Name()] = s
+	return nil
+}
+
+// normalize returns the canonical form of valid string values, and other
+// values unchanged for validation to reject.
+func normalize(value any, canonical canonicalizer) (any, error) {
+	if s, ok := value.(string); ok {
+		if c, err := canonical(s); err == nil {
+			return c, nil
+		}
+	}
+	return value, nil
+}
+
+// validate returns the canonical form of a string value.
+func validate(value any, canonical canonicalizer) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New("value is not a string")
+	}
+	return canonical(s)
+}
+
+// Color is a hex color such as "#1e90ff". Short forms and an alpha channel
+// are accepted; values are stored lowercase with two digits per channel, so
+// "#ABC" is stored as "#aabbcc".
+type Color struct{}
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3,4}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+func canonicalColor(s string) (string, error) {
+	if !hexColor.MatchString(s) {
+		return "", fmt.Errorf("%q is not a hex color", s)
+	}
+	s = strings.ToLower(s)
+	if len(s) > 5 {
+		return s, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('#')
+	for _, c := range s[1:] {
+		b.WriteRune(c)
+		b.WriteRune(c)
+	}
+	return b.String(), nil
+}
+
+// Scan implements jpack.JFieldType.
+func (c *Color) Scan(ctx context.Context, field jpack.JField, row map[string]any) (any, error) {
+	return scan(field, row)
+}
+
+// SetValue implements jpack.JFieldType.
+func (c *Color) SetValue(ctx context.Context, field jpack.JField, value any, row map[string]any) error {
+	return setValue(field, value, row, canonicalColor)
+}
+
+// Validate implements jpack.JFieldType.
+func (c *Color) Validate(value any) error {
+	if value == nil {
+		return nil
+	}
+	_, err := validate(value, canonicalColor)
+	return err
+}
+
+// Normalize converts values to their canonical form, so records hold it
+// before they are saved.
+func (c *Color) Normalize(value any) (any, error) {
+	return normalize(value, canonicalColor)
+}
+
+// TypeName reports the registry name of the type.
+func (c *Color) TypeName() string {
+	return "color"
+}
+
+// Example returns an example value for generated documentation.
+func (c *Color) Example() any {
+	return "#1e90ff"
+}
+
+// OpenAPISchema implements jpack.OpenAPIFieldType.
+func (c *Color) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "pattern": hexColor.String()}
+}
+
+// SemVer is a semantic version such as "1.4.0-rc.1+build.5", as specified by
+// semver.org. A leading "v" is rejected.
+type SemVer struct{}
+
+// semVer is the pattern recommended by semver.org.
+var semVer = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+func canonicalSemVer(s string) (string, error) {
+	if !semVer.MatchString(s) {
+		return "", fmt.Errorf("%q is not a semantic version", s)
+	}
+	return s, nil
+}
+
+// Scan implements jpack.JFieldType.
+func (v *SemVer) Scan(ctx context.Context, field jpack.JField, row map[string]any) (any, error) {
+	return scan(field, row)
+}
+
+// SetValue implements jpack.JFieldType.
+func (v *SemVer) SetValue(ctx context.Context, field jpack.JField, value any, row map[string]any) error {
+	return setValue(field, value, row, canonicalSemVer)
+}
+
+// Validate implements jpack.JFieldType.
+func (v *SemVer) Validate(value any) error {
+	if value == nil {
+		return nil
+	}
+	_, err := validate(value, canonicalSemVer)
+	return err
+}
+
+// Normalize converts values to their canonical form, so records hold it
+// before they are saved.
+func (v *SemVer) Normalize(value any) (any, error) {
+	return normalize(value, canonicalSemVer)
+}
+
+// TypeName reports the registry name of the type.
+func (v *SemVer) TypeName() string {
+	return "semver"
+}
+
+// Example returns an example value for generated documentation.
+func (v *SemVer) Example() any {
+	return "1.4.0"
+}
+
+// OpenAPISchema implements jpack.OpenAPIFieldType.
+func (v *SemVer) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "pattern": semVer.String()}
+}
+
+// CountryCode is an ISO 3166-1 alpha-2 country code such as "DE", stored
+// uppercase. Regions that aren't countries, e.g. "EU", are rejected.
+type CountryCode struct{}
+
+func canonicalCountryCode(s string) (string, error) {
+	region, err := language.ParseRegion(s)
+	if err != nil || len(s) != 2 || !region.IsCountry() {
+		return "", fmt.Errorf("%q is not an ISO 3166-1 alpha-2 country code", s)
+	}
+	return region.String(), nil
+}
+
+// Scan implements jpack.JFieldType.
+func (c *CountryCode) Scan(ctx context.Context, field jpack.JField, row map[string]any) (any, error) {
+	return scan(field, row)
+}
+
+// SetValue implements jpack.JFieldType.
+func (c *CountryCode) SetValue(ctx context.Context, field jpack.JField, value any, row map[string]any) error {
+	return setValue(field, value, row, canonicalCountryCode)
+}
+
+// Validate implements jpack.JFieldType.
+func (c *CountryCode) Validate(value any) error {
+	if value == nil {
+		return nil
+	}
+	_, err := validate(value, canonicalCountryCode)
+	return err
+}
+
+// Normalize converts values to their canonical form, so records hold it
+// before they are saved.
+func (c *CountryCode) Normalize(value any) (any, error) {
+	return normalize(value, canonicalCountryCode)
+}
+
+// TypeName reports the registry name of the type.
+func (c *CountryCode) TypeName() string {
+	return "country_code"
+}
+
+// Example returns an example value for generated documentation.
+func (c *CountryCode) Example() any {
+	return "DE"
+}
+
+// OpenAPISchema implements jpack.OpenAPIFieldType.
+func (c *CountryCode) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "pattern": "^[A-Z]{2}$"}
+}
+
+// LanguageTag is a BCP 47 language tag such as "en-US" or "zh-Hant-TW",
+// stored in its canonical form, e.g. "en-US" for "en_us".
+type LanguageTag struct{}
+
+func canonicalLanguageTag(s string) (string, error) {
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", fmt.Errorf("%q is not a BCP 47 language tag", s)
+	}
+	return tag.String(), nil
+}
+
+// Scan implements jpack.JFieldType.
+func (l *LanguageTag) Scan(ctx context.Context, field jpack.JField, row map[string]any) (any, error) {
+	return scan(field, row)
+}
+
+// SetValue implements jpack.JFieldType.
+func (l *LanguageTag) SetValue(ctx context.Context, field jpack.JField, value any, row map[string]any) error {
+	return setValue(field, value, row, canonicalLanguageTag)
+}
+
+// Validate implements jpack.JFieldType.
+func (l *LanguageTag) Validate(value any) error {
+	if value == nil {
+		return nil
+	}
+	_, err := validate(value, canonicalLanguageTag)
+	return err
+}
+
+// Normalize converts values to their canonical form, so records hold it
+// before they are saved.
+func (l *LanguageTag) Normalize(value any) (any, error) {
+	return normalize(value, canonicalLanguageTag)
+}
+
+// TypeName reports the registry name of the type.
+func (l *LanguageTag) TypeName() string {
+	return "language_tag"
+}
+
+// Example returns an example value for generated documentation.
+func (l *LanguageTag) Example() any {
+	return "en-US"
+}
+
+// OpenAPISchema implements jpack.OpenAPIFieldType.
+func (l *LanguageTag) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+// JSONPointer is an RFC 6901 JSON pointer such as "/items/0/name". The empty
+// pointer refers to the whole document.
+type JSONPointer struct{}
+
+func canonicalJSONPointer(s string) (string, error) {
+	if s != "" && !strings.HasPrefix(s, "/") {
+		return "", fmt.Errorf("%q is not a JSON pointer: it must start with /", s)
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] == '~' && (i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1')) {
+			return "", fmt.Errorf("%q is not a JSON pointer: ~ must be escaped as ~0", s)
+		}
+	}
+	return s, nil
+}
+
+// Scan implements jpack.JFieldType.
+func (p *JSONPointer) Scan(ctx context.Context, field jpack.JField, row map[string]any) (any, error) {
+	return scan(field, row)
+}
+
+// SetValue implements jpack.JFieldType.
+func (p *JSONPointer) SetValue(ctx context.Context, field jpack.JField, value any, row map[string]any) error {
+	return setValue(field, value, row, canonicalJSONPointer)
+}
+
+// Validate implements jpack.JFieldType.
+func (p *JSONPointer) Validate(value any) error {
+	if value == nil {
+		return nil
+	}
+	_, err := validate(value, canonicalJSONPointer)
+	return err
+}
+
+// Normalize converts values to their canonical form, so records hold it
+// before they are saved.
+func (p *JSONPointer) Normalize(value any) (any, error) {
+	return normalize(value, canonicalJSONPointer)
+}
+
+// TypeName reports the registry name of the type.
+func (p *JSONPointer) TypeName() string {
+	return "json_pointer"
+}
+
+// Example returns an example value for generated documentation.
+func (p *JSONPointer) Example() any {
+	return "/items/0/name"
+}
+
+// OpenAPISchema implements jpack.OpenAPIFieldType.
+func (p *JSONPointer) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "format": "json-pointer"}
+}
+
+var (
+	_ jpack.OpenAPIFieldType = &Color{}
+	_ jpack.OpenAPIFieldType = &SemVer{}
+	_ jpack.OpenAPIFieldType = &CountryCode{}
+	_ jpack.OpenAPIFieldType = &LanguageTag{}
+	_ jpack.OpenAPIFieldType = &JSONPointer{}
+)
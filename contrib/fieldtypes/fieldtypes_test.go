@@ -0,0 +1,78 @@
+package fieldtypes
+
+import (
+	"testing"
+
+	"github.com/kabi175/jpack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalForms(t *testing.T) {
+	tests := []struct {
+		fType jpack.JFieldType
+		value string
+		want  string
+	}{
+		{&Color{}, "#ABC", "#aabbcc"},
+		{&Color{}, "#abcd", "#aabbccdd"},
+		{&Color{}, "#1E90FF", "#1e90ff"},
+		{&SemVer{}, "1.4.0-rc.1+build.5", "1.4.0-rc.1+build.5"},
+		{&CountryCode{}, "de", "DE"},
+		{&LanguageTag{}, "en_us", "en-US"},
+		{&LanguageTag{}, "zh-hant-tw", "zh-Hant-TW"},
+		{&JSONPointer{}, "", ""},
+		{&JSONPointer{}, "/a~1b/0", "/a~1b/0"},
+	}
+	schema := jpack.NewSchema("test_contrib").Field("value", &Color{}).Build()
+	field, _ := schema.Field("value")
+
+	for _, tt := range tests {
+		row := map[string]any{}
+		assert.NoError(t, tt.fType.SetValue(t.Context(), field, tt.value, row), tt.value)
+		assert.Equal(t, tt.want, row["value"], tt.value)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	tests := []struct {
+		fType jpack.JFieldType
+		value any
+	}{
+		{&Color{}, "#abcde"},
+		{&Color{}, "abc"},
+		{&SemVer{}, "v1.2.3"},
+		{&SemVer{}, "1.02.3"},
+		{&CountryCode{}, "EU"},
+		{&CountryCode{}, "DEU"},
+		{&LanguageTag{}, "not a tag"},
+		{&JSONPointer{}, "a/b"},
+		{&JSONPointer{}, "/a~2"},
+		{&JSONPointer{}, 42},
+	}
+	for _, tt := range tests {
+		assert.Error(t, tt.fType.Validate(tt.value), "%v", tt.value)
+	}
+	assert.NoError(t, (&Color{}).Validate(nil))
+}
+
+func TestRegistered(t *testing.T) {
+	for _, name := range []string{"color", "semver", "country_code", "language_tag", "json_pointer"} {
+		fType, err := jpack.NewFieldType(name, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, name, jpack.FieldTypeName(fType))
+	}
+}
+
+func TestRecordNormalizes(t *testing.T) {
+	schema := jpack.NewSchema("test_contrib").
+		Field("id", &jpack.String{}).
+		Field("country", &CountryCode{}).
+		Build()
+	country, _ := schema.Field("country")
+
+	record := jpack.NewMongoRecord(schema)
+	assert.NoError(t, record.SetValue(country, "fr"))
+	value, _ := record.Value(country)
+	assert.Equal(t, "FR", value)
+	assert.Error(t, record.SetValue(country, "XX"))
+}
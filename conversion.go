@@ -0,0 +1,108 @@
+package jpack
+
+import (
+	"errors"
+	"sync"
+)
+
+// ConversionMode selects how field types coerce values passed to SetValue.
+type ConversionMode int
+
+const (
+	// Lenient accepts every representation a field type knows how to convert,
+	// e.g. "yes" for a Boolean or "42" for a Number. This is the default.
+	Lenient ConversionMode = iota
+
+	// Strict only accepts values that are already of the field's native type.
+	Strict
+
+	// Custom runs the policy's Convert function before the field type validates the value.
+	Custom
+)
+
+// ConversionPolicy controls how input values are coerced before they are stored in a record.
+type ConversionPolicy struct {
+	Mode ConversionMode
+
+	// Convert is used in Custom mode. It receives the target field and the raw
+	// input and returns the value handed to the field type.
+	Convert func(field JField, value any) (any, error)
+}
+
+// StrictFieldType is implemented by field types that accept a narrower set of
+// inputs when the Strict conversion mode is active.
+type StrictFieldType interface {
+	ValidateStrict(value any) error
+}
+
+var (
+	conversionPolicyMu sync.RWMutex
+	conversionPolicy   = ConversionPolicy{Mode: Lenient}
+)
+
+// SetConversionPolicy sets the package-wide conversion policy used by schemas
+// that don't define their own.
+func SetConversionPolicy(policy ConversionPolicy) {
+	conversionPolicyMu.Lock()
+	defer conversionPolicyMu.Unlock()
+
+	conversionPolicy = policy
+}
+
+// DefaultConversionPolicy returns the package-wide conversion policy.
+func DefaultConversionPolicy() ConversionPolicy {
+	conversionPolicyMu.RLock()
+	defer conversionPolicyMu.RUnlock()
+
+	return conversionPolicy
+}
+
+// ConversionPolicyOf returns the conversion policy in effect for the schema,
+// falling back to the package-wide policy.
+func ConversionPolicyOf(schema JSchema) ConversionPolicy {
+	if s, ok := schema.(interface {
+		ConversionPolicy() (ConversionPolicy, bool)
+	}); ok {
+		if policy, ok := s.ConversionPolicy(); ok {
+			return policy
+		}
+	}
+
+	return DefaultConversionPolicy()
+}
+
+// coerceInput applies the conversion policy of the field's schema to value and
+// validates the result against the field type.
+func coerceInput(field JField, value any) (any, error) {
+	policy := ConversionPolicyOf(field.Schema())
+
+	switch policy.Mode {
+	case Strict:
+		if strict, ok := field.Type().(StrictFieldType); ok {
+			if err := strict.ValidateStrict(value); err != nil {
+				return nil, err
+			}
+		}
+	case Custom:
+		if policy.Convert == nil {
+			return nil, errors.New("custom conversion policy has no Convert function")
+		}
+
+		converted, err := policy.Convert(field, value)
+		if err != nil {
+			return nil, err
+		}
+		value = converted
+	}
+
+	value, err := normalizeInput(field, value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := field.Type().Validate(value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,93 @@
+package jpack
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConversionPolicy(t *testing.T) {
+	t.Run("lenient is the default", func(t *testing.T) {
+		schema := NewSchema("test_lenient").
+			Field("active", &Boolean{}).
+			Field("age", &Number{}).
+			Build()
+
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(mustField(t, schema, "active"), "yes"))
+		assert.NoError(t, record.SetValue(mustField(t, schema, "age"), "42"))
+	})
+
+	t.Run("strict schema rejects coercible inputs", func(t *testing.T) {
+		schema := NewSchema("test_strict").
+			ConversionPolicy(ConversionPolicy{Mode: Strict}).
+			Field("active", &Boolean{}).
+			Field("age", &Number{}).
+			Field("name", &String{}).
+			Field("born", &DateTime{}).
+			Build()
+
+		record := NewMongoRecord(schema)
+		assert.Error(t, record.SetValue(mustField(t, schema, "active"), "yes"))
+		assert.Error(t, record.SetValue(mustField(t, schema, "age"), "42"))
+		assert.Error(t, record.SetValue(mustField(t, schema, "name"), 42))
+		assert.Error(t, record.SetValue(mustField(t, schema, "born"), "2024-12-25T10:00:00Z"))
+
+		active := true
+		assert.NoError(t, record.SetValue(mustField(t, schema, "active"), &active))
+		assert.NoError(t, record.SetValue(mustField(t, schema, "age"), int64(42)))
+		assert.NoError(t, record.SetValue(mustField(t, schema, "name"), "John"))
+		assert.NoError(t, record.SetValue(mustField(t, schema, "born"), time.Now()))
+	})
+
+	t.Run("custom policy converts before validation", func(t *testing.T) {
+		schema := NewSchema("test_custom").
+			ConversionPolicy(ConversionPolicy{
+				Mode: Custom,
+				Convert: func(field JField, value any) (any, error) {
+					if s, ok := value.(string); ok {
+						return strings.TrimSpace(s), nil
+					}
+					return value, nil
+				},
+			}).
+			Field("name", &String{}).
+			Build()
+
+		record := NewMongoRecord(schema)
+		field := mustField(t, schema, "name")
+		assert.NoError(t, record.SetValue(field, "  John "))
+
+		got, _ := record.Value(field)
+		assert.Equal(t, "John", got)
+	})
+
+	t.Run("custom policy errors are returned", func(t *testing.T) {
+		schema := NewSchema("test_custom_err").
+			ConversionPolicy(ConversionPolicy{
+				Mode: Custom,
+				Convert: func(field JField, value any) (any, error) {
+					return nil, errors.New("rejected")
+				},
+			}).
+			Field("name", &String{}).
+			Build()
+
+		record := NewMongoRecord(schema)
+		assert.EqualError(t, record.SetValue(mustField(t, schema, "name"), "John"), "rejected")
+	})
+
+	t.Run("package-wide policy applies to schemas without their own", func(t *testing.T) {
+		SetConversionPolicy(ConversionPolicy{Mode: Strict})
+		defer SetConversionPolicy(ConversionPolicy{Mode: Lenient})
+
+		schema := NewSchema("test_global").Field("active", &Boolean{}).Build()
+
+		record := NewMongoRecord(schema)
+		assert.Error(t, record.SetValue(mustField(t, schema, "active"), "yes"))
+		assert.NoError(t, record.SetValue(mustField(t, schema, "active"), true))
+	})
+}
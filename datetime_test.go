@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 func TestDateTime_Validate(t *testing.T) {
@@ -39,11 +40,17 @@ func TestDateTime_Validate(t *testing.T) {
 	})
 
 	t.Run("Invalid type", func(t *testing.T) {
-		err := dt.Validate(123)
+		err := dt.Validate(true)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "datetime type")
 	})
 
+	t.Run("Fractional epoch", func(t *testing.T) {
+		err := dt.Validate(1735120800.5)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "epoch")
+	})
+
 	t.Run("Pointer to time.Time", func(t *testing.T) {
 		now := time.Now()
 		err := dt.Validate(&now)
@@ -127,7 +134,7 @@ func TestDateTime_Scan(t *testing.T) {
 	})
 
 	t.Run("Invalid type", func(t *testing.T) {
-		row := map[string]any{"created_at": 123}
+		row := map[string]any{"created_at": []int{123}}
 
 		value, err := dt.Scan(ctx, field, row)
 		assert.Error(t, err)
@@ -211,7 +218,7 @@ func TestDateTime_SetValue(t *testing.T) {
 	t.Run("Set invalid type", func(t *testing.T) {
 		row := make(map[string]any)
 
-		err := dt.SetValue(ctx, field, 123, row)
+		err := dt.SetValue(ctx, field, true, row)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "datetime type")
 	})
@@ -269,4 +276,85 @@ func TestDateTime_GMTZoneHandling(t *testing.T) {
 	})
 }
 
+func TestDateTime_ExternalFormats(t *testing.T) {
+	ctx := context.Background()
+	field := &mockField{name: "created_at", fieldType: &DateTime{}}
+	expected := time.Date(2024, 12, 25, 10, 0, 0, 0, time.UTC)
+
+	t.Run("bson.DateTime from database", func(t *testing.T) {
+		dt := &DateTime{}
+		row := map[string]any{"created_at": bson.NewDateTimeFromTime(expected)}
+
+		value, err := dt.Scan(ctx, field, row)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+	})
+
+	t.Run("Epoch seconds from database", func(t *testing.T) {
+		dt := &DateTime{}
+		row := map[string]any{"created_at": expected.Unix()}
+
+		value, err := dt.Scan(ctx, field, row)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+	})
+
+	t.Run("Epoch millis from database", func(t *testing.T) {
+		dt := &DateTime{}
+		row := map[string]any{"created_at": expected.UnixMilli()}
+
+		value, err := dt.Scan(ctx, field, row)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+	})
+
+	t.Run("Epochs of every numeric type", func(t *testing.T) {
+		dt := &DateTime{}
+		millis := time.Date(2024, 12, 25, 10, 0, 0, 123_000_000, time.UTC)
+		tests := []struct {
+			name  string
+			epoch any
+			want  time.Time
+		}{
+			{"int", int(expected.Unix()), expected},
+			{"int32", int32(expected.Unix()), expected},
+			{"int64", expected.Unix(), expected},
+			{"int64 millis", millis.UnixMilli(), millis},
+			{"int millis", int(millis.UnixMilli()), millis},
+			{"uint32", uint32(expected.Unix()), expected},
+			{"uint64 millis", uint64(millis.UnixMilli()), millis},
+			{"float64", float64(expected.Unix()), expected},
+			{"float64 millis", float64(millis.UnixMilli()), millis},
+			{"int8", int8(-1), time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC)},
+			{"int16", int16(3600), time.Date(1970, 1, 1, 1, 0, 0, 0, time.UTC)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				value, err := dt.Scan(ctx, field, map[string]any{"created_at": tt.epoch})
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, value)
+			})
+		}
+	})
+
+	t.Run("Extra layout", func(t *testing.T) {
+		dt := &DateTime{Layouts: []string{time.DateTime}}
+		row := map[string]any{"created_at": "2024-12-25 10:00:00"}
+
+		value, err := dt.Scan(ctx, field, row)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+		assert.NoError(t, dt.Validate("2024-12-25 10:00:00"))
+	})
+
+	t.Run("Set bson.DateTime value", func(t *testing.T) {
+		dt := &DateTime{}
+		row := make(map[string]any)
+
+		err := dt.SetValue(ctx, field, bson.NewDateTimeFromTime(expected), row)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, row["created_at"])
+	})
+}
+
 // Use existing mockField from field_types_test.go
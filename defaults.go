@@ -0,0 +1,36 @@
+package jpack
+
+import (
+	"context"
+)
+
+// DefaultFunc computes a field's default value when a new record is saved,
+// e.g. the ID of the user making the request.
+type DefaultFunc func(ctx context.Context) any
+
+type serverNow struct{}
+
+// ServerNow returns a default value that is filled in with the database
+// server's current time when a new record is inserted.
+func ServerNow() any {
+	return serverNow{}
+}
+
+// IsServerDefault reports whether a field's default is computed by the database.
+func IsServerDefault(defaultValue any) bool {
+	_, ok := defaultValue.(serverNow)
+	return ok
+}
+
+// resolveDefault evaluates a client-side default value. Server-side defaults
+// are returned unchanged and must be checked with IsServerDefault.
+func resolveDefault(ctx context.Context, defaultValue any) any {
+	switch fn := defaultValue.(type) {
+	case DefaultFunc:
+		return fn(ctx)
+	case func(context.Context) any:
+		return fn(ctx)
+	default:
+		return defaultValue
+	}
+}
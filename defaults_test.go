@@ -0,0 +1,68 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxUserKey struct{}
+
+func TestDefaults(t *testing.T) {
+	schema := NewSchema("test_defaults").
+		Field("id", &String{}).
+		FieldWithDefault("status", &String{}, "draft").
+		FieldWithDefault("created_by", &String{}, func(ctx context.Context) any {
+			return ctx.Value(ctxUserKey{})
+		}).
+		FieldWithDefault("priority", &Number{}, DefaultFunc(func(ctx context.Context) any { return 3 })).
+		FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+		Build()
+
+	ctx := context.WithValue(context.Background(), ctxUserKey{}, "user-1")
+
+	t.Run("unset fields get their defaults", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+
+		serverDefaults, err := record.applyDefaults(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []JField{mustField(t, schema, "created_at")}, serverDefaults)
+
+		assert.Equal(t, "draft", record.record["status"])
+		assert.Equal(t, "user-1", record.record["created_by"])
+		assert.Equal(t, 3, record.record["priority"])
+		assert.NotContains(t, record.record, "created_at")
+	})
+
+	t.Run("explicit values are kept", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(mustField(t, schema, "status"), "published"))
+
+		_, err := record.applyDefaults(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "published", record.record["status"])
+	})
+
+	t.Run("nil results are skipped", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+
+		_, err := record.applyDefaults(context.Background())
+		assert.NoError(t, err)
+		assert.NotContains(t, record.record, "created_by")
+	})
+
+	t.Run("invalid defaults fail", func(t *testing.T) {
+		badSchema := NewSchema("test_bad_defaults").
+			FieldWithDefault("age", &Number{}, func(ctx context.Context) any { return "old" }).
+			Build()
+
+		_, err := NewMongoRecord(badSchema).applyDefaults(ctx)
+		assert.Error(t, err)
+	})
+
+	t.Run("IsServerDefault", func(t *testing.T) {
+		assert.True(t, IsServerDefault(ServerNow()))
+		assert.False(t, IsServerDefault("now"))
+	})
+}
@@ -0,0 +1,201 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// RecordFieldType is implemented by field types whose values can only be
+// validated against the rest of the record. Save calls ValidateRecord for
+// every such field after the schema's policies. The checks of a record's
+// fields run concurrently, so ValidateRecord must only read the record.
+type RecordFieldType interface {
+	ValidateRecord(ctx context.Context, field JField, record JRecord) error
+}
+
+// validateRecordFields runs the record checks of the fields checked in the
+// scenarios, and returns the failure of the first field that fails.
+func validateRecordFields(ctx context.Context, record JRecord, scenarios ...Scenario) error {
+	if errs := runRecordChecks(ctx, record, scenarios); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// DependentOptionService provides the options available for a value of the
+// field a DependentOptions field depends on.
+type DependentOptionService interface {
+	GetDependentOptions(ctx context.Context, parent string) ([]Option, error)
+}
+
+// DependentOptionsMap is a static DependentOptionService keyed by the parent
+// value, e.g. the states of each country.
+type DependentOptionsMap map[string][]Option
+
+// GetDependentOptions implements DependentOptionService.
+func (m DependentOptionsMap) GetDependentOptions(ctx context.Context, parent string) ([]Option, error) {
+	return m[parent], nil
+}
+
+// DependentOptions is an enum field type whose allowed values depend on the
+// value of another field of the record, e.g. a state that must belong to the
+// selected country. SetValue only checks that the value is a string; it is
+// checked against the options of the parent value when the record is saved.
+type DependentOptions struct {
+	service   DependentOptionService
+	dependsOn string
+}
+
+// NewDependentOptions creates a DependentOptions field type whose options are
+// looked up by the value of the dependsOnField field.
+func NewDependentOptions(service DependentOptionService, dependsOnField string) *DependentOptions {
+	return &DependentOptions{
+		service:   service,
+		dependsOn: dependsOnField,
+	}
+}
+
+// DependsOn returns the name of the field the options depend on.
+func (d *DependentOptions) DependsOn() string {
+	return d.dependsOn
+}
+
+// Scan implements JFieldType.
+func (d *DependentOptions) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, errors.New("options field must be a string")
+	}
+	return s, nil
+}
+
+// SetValue implements JFieldType.
+func (d *DependentOptions) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("options field must be a string")
+	}
+
+	row[field.Name()] = s
+	return nil
+}
+
+// Validate implements JFieldType. Membership is checked by ValidateRecord,
+// once the parent value is known.
+func (d *DependentOptions) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() == reflect.Pointer {
+		if reflectValue.IsNil() {
+			return nil
+		}
+		reflectValue = reflectValue.Elem()
+	}
+
+	if reflectValue.Kind() != reflect.String {
+		return errors.New("options field must be a string")
+	}
+	return nil
+}
+
+// ValidateRecord implements RecordFieldType. Stored records are only checked
+// again when the field or its parent changed.
+func (d *DependentOptions) ValidateRecord(ctx context.Context, field JField, record JRecord) error {
+	if !record.IsNew() {
+		dirty := record.DirtyKeys()
+		if !slices.Contains(dirty, field.Name()) && !slices.Contains(dirty, d.dependsOn) {
+			return nil
+		}
+	}
+
+	value, err := d.stringValue(record, field)
+	if err != nil || value == "" {
+		return err
+	}
+
+	parentField, ok := record.Schema().Field(d.dependsOn)
+	if !ok {
+		return fmt.Errorf("depends on unknown field %s", d.dependsOn)
+	}
+	parent, err := d.stringValue(record, parentField)
+	if err != nil {
+		return fmt.Errorf("%s: %w", d.dependsOn, err)
+	}
+	if parent == "" {
+		return fmt.Errorf("requires a value for %s", d.dependsOn)
+	}
+
+	options, err := d.service.GetDependentOptions(ctx, parent)
+	if err != nil {
+		return errors.Join(errors.New("failed to get available options"), err)
+	}
+
+	for _, option := range options {
+		if option.UniqueName == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("value is not in the list of available options for %s %q", d.dependsOn, parent)
+}
+
+func (d *DependentOptions) stringValue(record JRecord, field JField) (string, error) {
+	value, ok := record.Value(field)
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	value, err := normalizeValue(field, value)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", errors.New("value must be a string")
+	}
+	return s, nil
+}
+
+// GetDependentOptions returns the options available for a parent value.
+func (d *DependentOptions) GetDependentOptions(ctx context.Context, parent string) ([]Option, error) {
+	return d.service.GetDependentOptions(ctx, parent)
+}
+
+// TypeName implements the FieldTypeName override.
+func (d *DependentOptions) TypeName() string {
+	return "dependent_options"
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (d *DependentOptions) OpenAPISchema() map[string]any {
+	return map[string]any{
+		"type":        "string",
+		"description": "One of the options for the value of " + d.dependsOn,
+	}
+}
+
+var (
+	_ JFieldType             = &DependentOptions{}
+	_ RecordFieldType        = &DependentOptions{}
+	_ OpenAPIFieldType       = &DependentOptions{}
+	_ DependentOptionService = DependentOptionsMap{}
+)
@@ -0,0 +1,68 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestDependentOptions(t *testing.T) {
+	ctx := context.Background()
+	states := DependentOptionsMap{
+		"us": {{UniqueName: "ca", DisplayName: "California"}, {UniqueName: "ny", DisplayName: "New York"}},
+		"au": {{UniqueName: "nsw", DisplayName: "New South Wales"}},
+	}
+	schema := NewSchema("addresses").
+		Field("id", &String{}).
+		Field("country", &String{}).
+		Field("state", NewDependentOptions(states, "country")).
+		Build()
+	country, _ := schema.Field("country")
+	state, _ := schema.Field("state")
+
+	t.Run("validates against the parent value", func(t *testing.T) {
+		assert := assert.New(t)
+
+		record := NewMongoRecord(schema)
+		assert.NoError(record.SetValue(country, "us"))
+		assert.NoError(record.SetValue(state, "ca"))
+		assert.NoError(validateRecordFields(ctx, record))
+
+		assert.NoError(record.SetValue(country, "au"))
+		assert.ErrorContains(validateRecordFields(ctx, record), `state: value is not in the list of available options for country "au"`)
+	})
+
+	t.Run("requires the parent value", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(state, "ca"))
+		assert.ErrorContains(t, validateRecordFields(ctx, record), "requires a value for country")
+
+		empty := NewMongoRecord(schema)
+		assert.NoError(t, validateRecordFields(ctx, empty))
+	})
+
+	t.Run("rejects non-strings on set", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.Error(t, record.SetValue(state, 42))
+	})
+
+	t.Run("rechecks stored records only when changed", func(t *testing.T) {
+		assert := assert.New(t)
+
+		// Stored before the state was removed from the list
+		record, err := RecordFromBSON(schema, bson.M{"_id": bson.NewObjectID(), "country": "us", "state": "wa"})
+		assert.NoError(err)
+		assert.NoError(validateRecordFields(ctx, record))
+
+		assert.NoError(record.SetValue(country, "au"))
+		assert.ErrorContains(validateRecordFields(ctx, record), "not in the list")
+	})
+
+	t.Run("documents the dependency", func(t *testing.T) {
+		assert.Equal(t, "dependent_options", FieldTypeName(state.Type()))
+		properties := ToOpenAPI(schema)["addresses"].(map[string]any)["properties"].(map[string]any)
+		assert.Equal(t, "string", properties["state"].(map[string]any)["type"])
+	})
+}
@@ -0,0 +1,281 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// DocFormat selects the output of GenerateDocs.
+type DocFormat int
+
+const (
+	DocMarkdown DocFormat = iota
+	DocHTML
+)
+
+type schemaDoc struct {
+	Name    string
+	Fields  []fieldDoc
+	Edges   []edgeDoc
+	Example string
+}
+
+type fieldDoc struct {
+	Name    string
+	Type    string
+	Details []string
+}
+
+type edgeDoc struct {
+	Name   string
+	Schema string
+	Field  string
+}
+
+// GenerateDocs writes a data dictionary for the schemas, or for every
+// registered schema when none are given: fields with their types and
+// constraints, refs, edges and an example document.
+func GenerateDocs(ctx context.Context, w io.Writer, format DocFormat, schemas ...JSchema) error {
+	if len(schemas) == 0 {
+		schemas = RegisteredSchemas()
+	}
+
+	docs := make([]schemaDoc, 0, len(schemas))
+	for _, schema := range schemas {
+		doc, err := describeSchema(ctx, schema)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+
+	switch format {
+	case DocMarkdown:
+		return writeMarkdownDocs(w, docs)
+	case DocHTML:
+		return htmlDocsTemplate.Execute(w, docs)
+	default:
+		return errors.New("jpack: unknown documentation format")
+	}
+}
+
+func describeSchema(ctx context.Context, schema JSchema) (schemaDoc, error) {
+	doc := schemaDoc{Name: schema.Name()}
+
+	for _, field := range schema.Fields() {
+		doc.Fields = append(doc.Fields, fieldDoc{
+			Name:    field.Name(),
+			Type:    FieldTypeName(field.Type()),
+			Details: describeField(ctx, field),
+		})
+	}
+
+	for _, edge := range schema.Edge() {
+		e := edgeDoc{Name: edge.Name()}
+		if edge.Schema() != nil {
+			e.Schema = edge.Schema().Name()
+		}
+		if edge.Ref() != nil {
+			e.Field = edge.Ref().Name()
+		}
+		doc.Edges = append(doc.Edges, e)
+	}
+
+	example, err := json.MarshalIndent(exampleDocument(ctx, schema), "", "  ")
+	if err != nil {
+		return schemaDoc{}, err
+	}
+	doc.Example = string(example)
+
+	return doc, nil
+}
+
+// describeDefault describes a field default, or returns "" for none.
+func describeDefault(defaultValue any) string {
+	switch v := defaultValue.(type) {
+	case nil:
+		return ""
+	case serverNow:
+		return "server time on insert"
+	case DefaultFunc, func(context.Context) any:
+		return "computed at write time"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// describeField lists the constraints and relations of a field in prose.
+func describeField(ctx context.Context, field JField) []string {
+	var details []string
+
+	if pk, ok := PK(field.Schema()); ok && pk.Name() == field.Name() {
+		details = append(details, "primary key")
+	}
+
+	if ref, ok := field.(JRef); ok && ref.RelSchema() != nil {
+		details = append(details, "references "+ref.RelSchema().Name())
+	}
+
+	if IsRequired(field) {
+		details = append(details, "required")
+	}
+
+	if IsUnique(field) {
+		details = append(details, "unique")
+	}
+
+	if IsImmutable(field) {
+		details = append(details, "immutable")
+	}
+
+	if category, ok := SensitiveCategory(field); ok {
+		details = append(details, strings.TrimSuffix("sensitive "+category, " "))
+	}
+
+	if min, max, ok := FieldBounds(field.Type()); ok {
+		details = append(details, fmt.Sprintf("between %d and %d", min, max))
+	}
+
+	if defaultValue := describeDefault(field.Default()); defaultValue != "" {
+		details = append(details, "default: "+defaultValue)
+	}
+
+	if aliases := FieldAliases(field); len(aliases) > 0 {
+		details = append(details, "previously: "+strings.Join(aliases, ", "))
+	}
+
+	switch t := baseType(field.Type()).(type) {
+	case *Composite:
+		keys := t.StorageKeys(field)
+		details = append(details, "stored as: "+strings.Join(keys, ", "))
+	case *Options:
+		if options, err := t.GetAllOptions(ctx); err == nil {
+			names := make([]string, 0, len(options))
+			for _, option := range options {
+				names = append(names, option.UniqueName)
+			}
+			details = append(details, "one of: "+strings.Join(names, ", "))
+		}
+	case *DependentOptions:
+		details = append(details, "depends on: "+t.DependsOn())
+	case *DateTime:
+		if len(t.Layouts) > 0 {
+			details = append(details, "also accepts: "+strings.Join(t.Layouts, ", "))
+		}
+	}
+
+	return details
+}
+
+// exampleDocument returns a document with an example value for every field.
+func exampleDocument(ctx context.Context, schema JSchema) map[string]any {
+	doc := make(map[string]any, len(schema.Fields()))
+	for _, field := range schema.Fields() {
+		doc[field.Name()] = exampleValue(ctx, field.Type())
+	}
+	return doc
+}
+
+// exampleValue returns a representative value for a field type. Custom types
+// can provide one by implementing Example() any.
+func exampleValue(ctx context.Context, fType JFieldType) any {
+	if t, ok := fType.(interface{ Example() any }); ok {
+		return t.Example()
+	}
+
+	switch t := baseType(fType).(type) {
+	case interface{ Example() any }:
+		return t.Example()
+	case *String, *Text:
+		return "text"
+	case *Number:
+		return 42
+	case *Boolean:
+		return true
+	case *DateTime:
+		return "2024-01-02T15:04:05Z"
+	case *Ref:
+		return "507f1f77bcf86cd799439011"
+	case *Options:
+		if options, err := t.GetAllOptions(ctx); err == nil && len(options) > 0 {
+			return options[0].UniqueName
+		}
+		return nil
+	case *Composite:
+		parts := make(map[string]any, len(t.Parts))
+		for _, part := range t.Parts {
+			parts[part.Name] = exampleValue(ctx, part.Type)
+		}
+		return parts
+	default:
+		return nil
+	}
+}
+
+func writeMarkdownDocs(w io.Writer, docs []schemaDoc) error {
+	var b strings.Builder
+
+	b.WriteString("# Data Dictionary\n")
+	for _, doc := range docs {
+		fmt.Fprintf(&b, "\n## %s\n\n", doc.Name)
+
+		b.WriteString("| Field | Type | Details |\n")
+		b.WriteString("|-------|------|---------|\n")
+		for _, field := range doc.Fields {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", field.Name, field.Type, escapeMarkdownCell(strings.Join(field.Details, "; ")))
+		}
+
+		if len(doc.Edges) > 0 {
+			b.WriteString("\n### Edges\n\n")
+			for _, edge := range doc.Edges {
+				fmt.Fprintf(&b, "- `%s` → %s (via `%s`)\n", edge.Name, edge.Schema, edge.Field)
+			}
+		}
+
+		fmt.Fprintf(&b, "\n### Example\n\n```json\n%s\n```\n", doc.Example)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var htmlDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Data Dictionary</title></head>
+<body>
+<h1>Data Dictionary</h1>
+{{- range .}}
+<section id="{{.Name}}">
+<h2>{{.Name}}</h2>
+<table>
+<thead><tr><th>Field</th><th>Type</th><th>Details</th></tr></thead>
+<tbody>
+{{- range .Fields}}
+<tr><td><code>{{.Name}}</code></td><td>{{.Type}}</td><td>{{range $i, $d := .Details}}{{if $i}}; {{end}}{{$d}}{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- if .Edges}}
+<h3>Edges</h3>
+<ul>
+{{- range .Edges}}
+<li><code>{{.Name}}</code> → <a href="#{{.Schema}}">{{.Schema}}</a> (via <code>{{.Field}}</code>)</li>
+{{- end}}
+</ul>
+{{- end}}
+<h3>Example</h3>
+<pre><code>{{.Example}}</code></pre>
+</section>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,68 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateDocs(t *testing.T) {
+	userSchema := NewSchema("docs_user").
+		Field("id", &String{}).
+		Field("email", &String{}, Immutable(), FieldAlias("mail")).
+		FieldWithDefault("active", &Boolean{}, true).
+		Build()
+
+	postSchema := NewSchema("docs_post").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+		Ref("author", userSchema).
+		Build()
+
+	t.Run("markdown", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var buf bytes.Buffer
+		assert.NoError(GenerateDocs(context.Background(), &buf, DocMarkdown, userSchema, postSchema))
+
+		out := buf.String()
+		assert.Contains(out, "## docs_user")
+		assert.Contains(out, "| `email` | string | immutable; previously: mail |")
+		assert.Contains(out, "| `active` | boolean | default: true |")
+		assert.Contains(out, "| `author` | ref | references docs_user |")
+		assert.Contains(out, "default: server time on insert")
+		assert.Contains(out, `"title": "text"`)
+	})
+
+	t.Run("html escapes content", func(t *testing.T) {
+		assert := assert.New(t)
+
+		schema := NewSchema("<script>").Field("id", &String{}).Build()
+
+		var buf bytes.Buffer
+		assert.NoError(GenerateDocs(context.Background(), &buf, DocHTML, schema))
+		assert.Contains(buf.String(), "&lt;script&gt;")
+		assert.NotContains(buf.String(), "<h2><script>")
+	})
+
+	t.Run("defaults to registered schemas", func(t *testing.T) {
+		assert := assert.New(t)
+
+		RegisterSchema(userSchema)
+		schema, ok := GetSchema("docs_user")
+		assert.True(ok)
+		assert.Equal(userSchema, schema)
+
+		var buf bytes.Buffer
+		assert.NoError(GenerateDocs(context.Background(), &buf, DocMarkdown))
+		assert.Contains(buf.String(), "## docs_user")
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, GenerateDocs(context.Background(), &buf, DocFormat(99), userSchema))
+	})
+}
@@ -0,0 +1,522 @@
+package jpack
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const dataKeysCollection = "jpack_data_keys"
+
+var (
+	// keyringKey is the context key holding the Keyring set with WithKeyring.
+	keyringKey key = "jpack.keyring"
+
+	// ErrNoKeyring is returned when encrypted values are written or read
+	// without a Keyring in the context.
+	ErrNoKeyring = errors.New("jpack: no keyring in context")
+	// ErrDataKeyDestroyed is returned when decrypting a value whose data key
+	// was destroyed with DestroyDataKey.
+	ErrDataKeyDestroyed = errors.New("jpack: data key destroyed")
+)
+
+// MasterKeyProvider wraps and unwraps data keys with a master key that never
+// leaves it, e.g. a KMS key.
+type MasterKeyProvider interface {
+	WrapKey(ctx context.Context, key []byte) ([]byte, error)
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// LocalMasterKey is a MasterKeyProvider holding a 32-byte AES key in memory,
+// for development and tests.
+type LocalMasterKey []byte
+
+// WrapKey implements MasterKeyProvider.
+func (k LocalMasterKey) WrapKey(ctx context.Context, key []byte) ([]byte, error) {
+	return seal(k, key, nil)
+}
+
+// UnwrapKey implements MasterKeyProvider.
+func (k LocalMasterKey) UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error) {
+	return open(k, wrapped, nil)
+}
+
+var _ MasterKeyProvider = LocalMasterKey(nil)
+
+// WrappedDataKey is a data key as stored, wrapped by the master key.
+type WrappedDataKey struct {
+	ID      string
+	Wrapped []byte
+}
+
+// DataKeyStore stores the wrapped data key of each tenant.
+type DataKeyStore interface {
+	// Get returns the key of the tenant, or nil when it has none.
+	Get(ctx context.Context, tenant string) (*WrappedDataKey, error)
+	// Create stores the key unless the tenant has one, and returns the
+	// tenant's key.
+	Create(ctx context.Context, tenant string, key WrappedDataKey) (*WrappedDataKey, error)
+	// Delete removes the key of the tenant.
+	Delete(ctx context.Context, tenant string) error
+}
+
+// Keyring encrypts values with a data key per tenant, wrapped by a master key
+// (envelope encryption). Destroying a tenant's data key with DestroyDataKey
+// makes all of its encrypted values unreadable (crypto-shredding), without
+// finding and deleting them.
+type Keyring struct {
+	Master MasterKeyProvider
+	// Keys stores the wrapped data keys; nil uses the jpack_data_keys
+	// collection of the context's database.
+	Keys DataKeyStore
+
+	mu    sync.Mutex
+	cache map[string]dataKey
+}
+
+// dataKey is an unwrapped data key.
+type dataKey struct {
+	id  string
+	key []byte
+}
+
+// NewKeyring creates a keyring storing its data keys in the jpack_data_keys
+// collection.
+func NewKeyring(master MasterKeyProvider) *Keyring {
+	return &Keyring{Master: master}
+}
+
+// WithKeyring returns a context whose Encrypted fields use keyring.
+func WithKeyring(ctx context.Context, keyring *Keyring) context.Context {
+	return context.WithValue(ctx, keyringKey, keyring)
+}
+
+// KeyringFrom returns the keyring of the context, if any.
+func KeyringFrom(ctx context.Context) (*Keyring, bool) {
+	keyring, ok := ctx.Value(keyringKey).(*Keyring)
+	return keyring, ok && keyring != nil
+}
+
+func (k *Keyring) store() DataKeyStore {
+	if k.Keys != nil {
+		return k.Keys
+	}
+	return mongoDataKeys{}
+}
+
+// dataKey returns the data key of the tenant, creating one when create is
+// set and the tenant has none.
+func (k *Keyring) dataKey(ctx context.Context, tenant string, create bool) (dataKey, error) {
+	k.mu.Lock()
+	cached, ok := k.cache[tenant]
+	k.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	wrapped, err := k.store().Get(ctx, tenant)
+	if err != nil {
+		return dataKey{}, err
+	}
+	if wrapped == nil {
+		if !create {
+			return dataKey{}, ErrDataKeyDestroyed
+		}
+		if wrapped, err = k.createDataKey(ctx, tenant); err != nil {
+			return dataKey{}, err
+		}
+	}
+
+	key, err := k.Master.UnwrapKey(ctx, wrapped.Wrapped)
+	if err != nil {
+		return dataKey{}, fmt.Errorf("jpack: unwrapping data key of tenant %q: %w", tenant, err)
+	}
+
+	unwrapped := dataKey{id: wrapped.ID, key: key}
+	k.mu.Lock()
+	if k.cache == nil {
+		k.cache = make(map[string]dataKey)
+	}
+	k.cache[tenant] = unwrapped
+	k.mu.Unlock()
+	return unwrapped, nil
+}
+
+// createDataKey stores a new data key for the tenant, or returns the one
+// another writer created first.
+func (k *Keyring) createDataKey(ctx context.Context, tenant string) (*WrappedDataKey, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	wrapped, err := k.Master.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("jpack: wrapping data key of tenant %q: %w", tenant, err)
+	}
+	return k.store().Create(ctx, tenant, WrappedDataKey{ID: bson.NewObjectID().Hex(), Wrapped: wrapped})
+}
+
+// DestroyDataKey deletes the data key of the tenant, so its encrypted values
+// can't be decrypted anymore. Values written for the tenant afterwards get a
+// new key. Other processes drop the destroyed key from their cache only when
+// they restart or call ForgetDataKeys.
+func (k *Keyring) DestroyDataKey(ctx context.Context, tenant string) error {
+	if err := k.store().Delete(ctx, tenant); err != nil {
+		return err
+	}
+	k.ForgetDataKeys()
+	return nil
+}
+
+// ForgetDataKeys drops the unwrapped data keys cached by the keyring.
+func (k *Keyring) ForgetDataKeys() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.cache = nil
+}
+
+// mongoDataKeys is the DataKeyStore of the jpack_data_keys collection.
+type mongoDataKeys struct{}
+
+type dataKeyDocument struct {
+	Tenant  string `bson:"_id"`
+	ID      string `bson:"key_id"`
+	Wrapped []byte `bson:"wrapped"`
+}
+
+// Get implements DataKeyStore.
+func (mongoDataKeys) Get(ctx context.Context, tenant string) (*WrappedDataKey, error) {
+	var doc dataKeyDocument
+	err := MustConn(ctx).Collection(dataKeysCollection).FindOne(ctx, bson.M{defaultMongoPK: tenant}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &WrappedDataKey{ID: doc.ID, Wrapped: doc.Wrapped}, nil
+}
+
+// Create implements DataKeyStore.
+func (s mongoDataKeys) Create(ctx context.Context, tenant string, key WrappedDataKey) (*WrappedDataKey, error) {
+	// Only inserts; a key created concurrently is kept
+	_, err := MustConn(ctx).Collection(dataKeysCollection).UpdateOne(ctx,
+		bson.M{defaultMongoPK: tenant},
+		bson.M{"$setOnInsert": bson.M{"key_id": key.ID, "wrapped": key.Wrapped}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	return s.Get(ctx, tenant)
+}
+
+// Delete implements DataKeyStore.
+func (mongoDataKeys) Delete(ctx context.Context, tenant string) error {
+	_, err := MustConn(ctx).Collection(dataKeysCollection).DeleteOne(ctx, bson.M{defaultMongoPK: tenant})
+	return err
+}
+
+// MemoryDataKeys is a DataKeyStore kept in memory, for tests and single
+// process tools.
+type MemoryDataKeys struct {
+	mu   sync.Mutex
+	keys map[string]WrappedDataKey
+}
+
+// Get implements DataKeyStore.
+func (s *MemoryDataKeys) Get(ctx context.Context, tenant string) (*WrappedDataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[tenant]
+	if !ok {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+// Create implements DataKeyStore.
+func (s *MemoryDataKeys) Create(ctx context.Context, tenant string, key WrappedDataKey) (*WrappedDataKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.keys[tenant]; ok {
+		return &existing, nil
+	}
+	if s.keys == nil {
+		s.keys = make(map[string]WrappedDataKey)
+	}
+	s.keys[tenant] = key
+	return &key, nil
+}
+
+// Delete implements DataKeyStore.
+func (s *MemoryDataKeys) Delete(ctx context.Context, tenant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, tenant)
+	return nil
+}
+
+var (
+	_ DataKeyStore = mongoDataKeys{}
+	_ DataKeyStore = &MemoryDataKeys{}
+)
+
+// Encrypted is a field type storing the values of its inner type encrypted
+// with the data key of the context's tenant, or of the "" tenant without
+// one. Values are decrypted when records are loaded within the context of
+// the Keyring; values whose data key was destroyed load as nil. Encrypted
+// values can't be filtered or sorted on in queries.
+type Encrypted struct {
+	Inner JFieldType
+}
+
+// Encrypt decorates inner with encryption.
+func Encrypt(inner JFieldType) *Encrypted {
+	return &Encrypted{Inner: inner}
+}
+
+var _ JFieldType = &Encrypted{}
+
+// Unwrap returns the inner field type.
+func (e *Encrypted) Unwrap() JFieldType {
+	return e.Inner
+}
+
+// TypeName reports the registry name of the inner type.
+func (e *Encrypted) TypeName() string {
+	return FieldTypeName(e.Inner)
+}
+
+// Scan implements JFieldType.
+func (e *Encrypted) Scan(ctx context.Context, field JField, row map[string]any) (any, error) {
+	value, ok := RowValue(field, row)
+	if !ok || value == nil {
+		return nil, nil
+	}
+	if envelope, ok := encryptedEnvelope(value); ok {
+		var err error
+		if value, err = decryptValue(ctx, field, envelope); err != nil {
+			return nil, err
+		}
+	}
+	return e.Inner.Scan(ctx, field, map[string]any{field.Name(): value})
+}
+
+// SetValue implements JFieldType.
+func (e *Encrypted) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	if _, ok := encryptedEnvelope(value); ok {
+		// Already encrypted, e.g. a value that couldn't be decrypted
+		row[field.Name()] = value
+		return nil
+	}
+
+	plain := map[string]any{}
+	if err := e.Inner.SetValue(ctx, field, value, plain); err != nil {
+		return err
+	}
+	stored, ok := plain[field.Name()]
+	if !ok || stored == nil {
+		row[field.Name()] = stored
+		return nil
+	}
+
+	envelope, err := encryptValue(ctx, field, stored)
+	if err != nil {
+		return err
+	}
+	row[field.Name()] = envelope
+	return nil
+}
+
+// Validate implements JFieldType.
+func (e *Encrypted) Validate(value any) error {
+	if _, ok := encryptedEnvelope(value); ok {
+		return nil
+	}
+	return e.Inner.Validate(value)
+}
+
+// encryptedEnvelope returns the stored form of an encrypted value.
+func encryptedEnvelope(value any) (bson.M, bool) {
+	var doc bson.M
+	switch v := value.(type) {
+	case bson.M:
+		doc = v
+	case map[string]any:
+		doc = v
+	case bson.D:
+		doc = make(bson.M, len(v))
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+	default:
+		return nil, false
+	}
+	if _, ok := doc["ciphertext"]; !ok {
+		return nil, false
+	}
+	return doc, true
+}
+
+// encryptValue encrypts a stored value with the data key of the context's
+// tenant. The field is bound to the ciphertext, so it can't be copied into
+// another field.
+func encryptValue(ctx context.Context, field JField, value any) (bson.M, error) {
+	keyring, ok := KeyringFrom(ctx)
+	if !ok {
+		return nil, ErrNoKeyring
+	}
+	tenant, _ := TenantFrom(ctx)
+	key, err := keyring.dataKey(ctx, tenant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(key.key, plain, encryptionAAD(field, key.id))
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"tenant": tenant, "key_id": key.id, "ciphertext": bson.Binary{Data: ciphertext}}, nil
+}
+
+// decryptValue returns the stored value of an encrypted envelope.
+func decryptValue(ctx context.Context, field JField, envelope bson.M) (any, error) {
+	keyring, ok := KeyringFrom(ctx)
+	if !ok {
+		return nil, ErrNoKeyring
+	}
+	tenant, _ := envelope["tenant"].(string)
+	keyID, _ := envelope["key_id"].(string)
+
+	var ciphertext []byte
+	switch c := envelope["ciphertext"].(type) {
+	case bson.Binary:
+		ciphertext = c.Data
+	case []byte:
+		ciphertext = c
+	default:
+		return nil, errors.New("value is not an encrypted value")
+	}
+
+	key, err := keyring.dataKey(ctx, tenant, false)
+	if err != nil {
+		return nil, err
+	}
+	if key.id != keyID {
+		// The key the value was written with was replaced after being destroyed
+		return nil, ErrDataKeyDestroyed
+	}
+
+	plain, err := open(key.key, ciphertext, encryptionAAD(field, keyID))
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		V any `bson:"v"`
+	}
+	if err := bson.Unmarshal(plain, &doc); err != nil {
+		return nil, err
+	}
+	return doc.V, nil
+}
+
+func encryptionAAD(field JField, keyID string) []byte {
+	return []byte(field.Schema().Name() + "." + field.Name() + ":" + keyID)
+}
+
+// seal encrypts plain with AES-GCM, prefixing the random nonce.
+func seal(key, plain, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+// open decrypts the output of seal.
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("jpack: ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted reports whether a field type is, or wraps, an Encrypted type.
+func isEncrypted(fType JFieldType) bool {
+	for {
+		if _, ok := fType.(*Encrypted); ok {
+			return true
+		}
+		w, ok := fType.(interface{ Unwrap() JFieldType })
+		if !ok {
+			return false
+		}
+		fType = w.Unwrap()
+	}
+}
+
+// decryptFields replaces the encrypted values of a loaded document with
+// their plain values. Values whose data key was destroyed become nil.
+func (m *mongoRecord) decryptFields(ctx context.Context) error {
+	for _, field := range m.Schema().Fields() {
+		if !isEncrypted(field.Type()) {
+			continue
+		}
+		envelope, ok := encryptedEnvelope(m.originalRecord[field.Name()])
+		if !ok {
+			continue
+		}
+		if _, ok := KeyringFrom(ctx); !ok {
+			// Left encrypted; Scan fails with ErrNoKeyring
+			continue
+		}
+
+		start := time.Now()
+		value, err := decryptValue(ctx, field, envelope)
+		if profile, ok := HydrationProfileFrom(ctx); ok {
+			profile.observeScan(field, time.Since(start))
+		}
+		if errors.Is(err, ErrDataKeyDestroyed) {
+			value, err = nil, nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name(), err)
+		}
+		m.originalRecord[field.Name()] = value
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEncrypted(t *testing.T) {
+	patients := NewSchema("test_encrypted_patients").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("diagnosis", Encrypt(&String{})).
+		Field("notes", Encrypt(&Text{})).
+		Build()
+	name, diagnosis, notes := mustField(t, patients, "name"), mustField(t, patients, "diagnosis"), mustField(t, patients, "notes")
+
+	keyring := &Keyring{Master: LocalMasterKey(bytes.Repeat([]byte{7}, 32)), Keys: &MemoryDataKeys{}}
+	base := WithKeyring(offlineContext(t), keyring)
+	acme, globex := WithTenant(base, "acme"), WithTenant(base, "globex")
+
+	// stored writes a record within ctx and returns its stored document
+	stored := func(t *testing.T, ctx context.Context) bson.M {
+		record := NewMongoRecord(patients)
+		assert.NoError(t, record.SetValue(name, "Ada"))
+		assert.NoError(t, record.SetValue(diagnosis, "flu"))
+		assert.NoError(t, record.SetValue(notes, "rest and fluids"))
+		w, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		doc := w.document
+		doc["_id"] = bson.NewObjectID()
+		return doc
+	}
+	load := func(t *testing.T, ctx context.Context, doc bson.M) JRecord {
+		record := NewMongoRecord(patients)
+		assert.NoError(t, record.loadDocument(ctx, doc))
+		return record
+	}
+
+	t.Run("values round-trip encrypted", func(t *testing.T) {
+		doc := stored(t, acme)
+		assert.Equal(t, "Ada", doc["name"])
+		envelope, ok := doc["diagnosis"].(bson.M)
+		assert.True(t, ok)
+		assert.Equal(t, "acme", envelope["tenant"])
+		assert.NotContains(t, string(envelope["ciphertext"].(bson.Binary).Data), "flu")
+
+		record := load(t, acme, doc)
+		value, _ := record.String(diagnosis)
+		assert.Equal(t, "flu", value)
+		value, _ = record.String(notes)
+		assert.Equal(t, "rest and fluids", value)
+	})
+
+	t.Run("each tenant has its own data key", func(t *testing.T) {
+		stored(t, acme)
+		stored(t, globex)
+		a, _ := keyring.Keys.Get(acme, "acme")
+		g, _ := keyring.Keys.Get(globex, "globex")
+		assert.NotEqual(t, a.ID, g.ID)
+		assert.NotEqual(t, a.Wrapped, g.Wrapped)
+	})
+
+	t.Run("values are bound to their field", func(t *testing.T) {
+		doc := stored(t, acme)
+		doc["notes"] = doc["diagnosis"]
+		record := NewMongoRecord(patients)
+		assert.Error(t, record.loadDocument(acme, doc))
+	})
+
+	t.Run("writing needs a keyring", func(t *testing.T) {
+		record := NewMongoRecord(patients)
+		assert.NoError(t, record.SetValue(diagnosis, "flu"))
+		_, err := record.prepareSave(offlineContext(t), &saveOptions{})
+		assert.ErrorIs(t, err, ErrNoKeyring)
+	})
+
+	t.Run("destroying the data key shreds the tenant's values", func(t *testing.T) {
+		acmeDoc, globexDoc := stored(t, acme), stored(t, globex)
+		assert.NoError(t, keyring.DestroyDataKey(acme, "acme"))
+
+		record := load(t, acme, acmeDoc)
+		_, ok := record.String(diagnosis)
+		assert.False(t, ok)
+		value, _ := record.String(name)
+		assert.Equal(t, "Ada", value)
+
+		value, _ = load(t, globex, globexDoc).String(diagnosis)
+		assert.Equal(t, "flu", value)
+
+		// New writes get a new key, which doesn't decrypt the shredded values
+		fresh := stored(t, acme)
+		value, _ = load(t, acme, fresh).String(diagnosis)
+		assert.Equal(t, "flu", value)
+		_, ok = load(t, acme, acmeDoc).String(diagnosis)
+		assert.False(t, ok)
+	})
+}
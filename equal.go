@@ -0,0 +1,40 @@
+package jpack
+
+import "reflect"
+
+// EqualFieldType is implemented by field types whose values can be equal
+// without being identical, e.g. the same instant in two time zones. Dirty
+// detection compares values with Equal, so setting an equal value doesn't
+// mark the field dirty and Save doesn't write it.
+type EqualFieldType interface {
+	Equal(a, b any) bool
+}
+
+// valuesEqual reports whether two values of a field are equal, using the
+// Equal method of its type or of the type it wraps.
+func valuesEqual(field JField, a, b any) bool {
+	fType := field.Type()
+	for fType != nil {
+		if eq, ok := fType.(EqualFieldType); ok {
+			return eq.Equal(a, b)
+		}
+		w, ok := fType.(interface{ Unwrap() JFieldType })
+		if !ok {
+			break
+		}
+		fType = w.Unwrap()
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isDirty reports whether the value of key differs from the stored one.
+func (m *mongoRecord) isDirty(key string) bool {
+	original, exists := m.originalRecord[key]
+	if !exists {
+		return true
+	}
+	if field, ok := m.Schema().Field(key); ok {
+		return !valuesEqual(field, m.record[key], original)
+	}
+	return !reflect.DeepEqual(m.record[key], original)
+}
@@ -0,0 +1,103 @@
+package jpack
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestFieldTypeEqual(t *testing.T) {
+	t.Run("DateTime compares instants", func(t *testing.T) {
+		dt := &DateTime{}
+		instant := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		tokyo := instant.In(time.FixedZone("JST", 9*60*60))
+
+		assert.True(t, dt.Equal(instant, tokyo))
+		assert.True(t, dt.Equal(bson.NewDateTimeFromTime(instant), &tokyo))
+		assert.True(t, dt.Equal(instant, "2024-03-01T21:00:00+09:00"))
+		assert.True(t, dt.Equal(instant, instant.Add(time.Microsecond)), "stored with millisecond precision")
+		assert.False(t, dt.Equal(instant, instant.Add(time.Millisecond)))
+		assert.False(t, dt.Equal(instant, nil))
+		assert.True(t, dt.Equal(nil, (*time.Time)(nil)))
+	})
+
+	t.Run("Ref compares ids", func(t *testing.T) {
+		ref := &Ref{}
+		id := bson.NewObjectID()
+		user := NewMongoRecord(userSchema)
+		pk, _ := PK(userSchema)
+		assert.NoError(t, user.SetValue(pk, id.Hex()))
+
+		assert.True(t, ref.Equal(id.Hex(), strings.ToUpper(id.Hex())))
+		assert.True(t, ref.Equal(user, id.Hex()))
+		assert.True(t, ref.Equal(id, id.Hex()))
+		assert.False(t, ref.Equal(id.Hex(), bson.NewObjectID().Hex()))
+		assert.False(t, ref.Equal(id.Hex(), nil))
+	})
+
+	t.Run("Ref lowercases ids", func(t *testing.T) {
+		value, err := (&Ref{}).Normalize("65F1A2B3C4D5E6F7A8B9C0D1")
+		assert.NoError(t, err)
+		assert.Equal(t, "65f1a2b3c4d5e6f7a8b9c0d1", value)
+	})
+}
+
+func TestDirtyDetection(t *testing.T) {
+	schema := NewSchema("test_dirty").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("at", &DateTime{}).
+		Build()
+	name, at := mustField(t, schema, "name"), mustField(t, schema, "at")
+	instant := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	load := func(t *testing.T) *mongoRecord {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Ada", "at": bson.NewDateTimeFromTime(instant)}))
+		return record
+	}
+
+	t.Run("equal values aren't dirty", func(t *testing.T) {
+		record := load(t)
+		assert.NoError(t, record.SetValue(at, instant.In(time.FixedZone("EST", -5*60*60))))
+		assert.NoError(t, record.SetValue(name, "Ada"))
+		assert.Empty(t, record.DirtyKeys())
+		assert.False(t, record.IsModified())
+	})
+
+	t.Run("no-op saves don't write", func(t *testing.T) {
+		record := load(t)
+		assert.NoError(t, record.SetValue(at, instant.Local()))
+
+		w, err := record.prepareSave(offlineContext(t), &saveOptions{})
+		assert.NoError(t, err)
+		assert.Nil(t, w)
+
+		// offlineContext has no server to write to
+		assert.NoError(t, record.Save(offlineContext(t)))
+	})
+
+	t.Run("unchanged fields aren't written", func(t *testing.T) {
+		record := load(t)
+		assert.NoError(t, record.SetValue(at, instant.Local()))
+		assert.NoError(t, record.SetValue(name, "Grace"))
+
+		w, err := record.prepareSave(offlineContext(t), &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$set": bson.M{"name": "Grace"}}, w.document)
+	})
+
+	t.Run("observers skip equal values", func(t *testing.T) {
+		record := load(t)
+		var changes int
+		record.OnChange(func(field JField, old, new any) { changes++ })
+
+		assert.NoError(t, record.SetValue(at, instant.Local()))
+		assert.Equal(t, 0, changes)
+		assert.NoError(t, record.SetValue(at, instant.Add(time.Hour)))
+		assert.Equal(t, 1, changes)
+	})
+}
@@ -0,0 +1,146 @@
+package jpack
+
+import (
+	"fmt"
+	"time"
+)
+
+// exportKind is the storage type of an exported column.
+type exportKind int
+
+const (
+	exportString exportKind = iota
+	exportLong
+	exportBoolean
+	exportTimestamp
+)
+
+// exportColumn is a flat column of an export file. Composite fields are split
+// into one column per part, named like their storage keys.
+type exportColumn struct {
+	name  string
+	kind  exportKind
+	field JField
+	part  string
+}
+
+// exportColumns returns the flat columns for a schema.
+func exportColumns(schema JSchema) ([]exportColumn, error) {
+	var columns []exportColumn
+	for _, field := range schema.Fields() {
+		composite, ok := field.Type().(*Composite)
+		if !ok {
+			kind, err := exportKindOf(field.Type())
+			if err != nil {
+				return nil, fmt.Errorf("jpack: %s.%s: %w", schema.Name(), field.Name(), err)
+			}
+			columns = append(columns, exportColumn{name: field.Name(), kind: kind, field: field})
+			continue
+		}
+
+		for _, part := range composite.Parts {
+			kind, err := exportKindOf(part.Type)
+			if err != nil {
+				return nil, fmt.Errorf("jpack: %s.%s.%s: %w", schema.Name(), field.Name(), part.Name, err)
+			}
+			columns = append(columns, exportColumn{
+				name:  composite.partKey(field, part),
+				kind:  kind,
+				field: field,
+				part:  part.Name,
+			})
+		}
+	}
+
+	return columns, nil
+}
+
+func exportKindOf(fType JFieldType) (exportKind, error) {
+	switch baseType(fType).(type) {
+	case *String, *Text, *Options, *DependentOptions, *Ref:
+		return exportString, nil
+	case *Number:
+		return exportLong, nil
+	case *Boolean:
+		return exportBoolean, nil
+	case *DateTime:
+		return exportTimestamp, nil
+	default:
+		return 0, fmt.Errorf("field type %s cannot be exported", FieldTypeName(fType))
+	}
+}
+
+// exportRow returns the values of a record in column order; nil marks a null.
+// Values are normalized through their field types first.
+func exportRow(record JRecord, columns []exportColumn) ([]any, error) {
+	normalized := make(map[string]any)
+	row := make([]any, len(columns))
+
+	for i, column := range columns {
+		name := column.field.Name()
+
+		value, ok := normalized[name]
+		if !ok {
+			raw, exists := record.Value(column.field)
+			if exists {
+				var err error
+				if value, err = normalizeValue(column.field, raw); err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+			}
+			normalized[name] = value
+		}
+
+		if column.part != "" {
+			parts, _ := value.(map[string]any)
+			value = parts[column.part]
+		}
+
+		if value != nil {
+			if err := checkExportValue(column, value); err != nil {
+				return nil, err
+			}
+		}
+		row[i] = value
+	}
+
+	return row, nil
+}
+
+func checkExportValue(column exportColumn, value any) error {
+	var ok bool
+	switch column.kind {
+	case exportString:
+		_, ok = value.(string)
+	case exportLong:
+		_, ok = value.(int)
+	case exportBoolean:
+		_, ok = value.(bool)
+	case exportTimestamp:
+		_, ok = value.(time.Time)
+	}
+
+	if !ok {
+		return fmt.Errorf("%s: unexpected value of type %T", column.name, value)
+	}
+	return nil
+}
+
+// exportRows converts the records to rows for the schema's columns.
+func exportRows(schema JSchema, records []JRecord) ([]exportColumn, [][]any, error) {
+	columns, err := exportColumns(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows := make([][]any, 0, len(records))
+	for _, record := range records {
+		row, err := exportRow(record, columns)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = append(rows, row)
+	}
+
+	return columns, rows, nil
+}
@@ -0,0 +1,164 @@
+package jpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func exportFixture(t *testing.T) (JSchema, []JRecord) {
+	t.Helper()
+
+	money := NewComposite(
+		CompositePart{Name: "amount", Type: &Number{}},
+		CompositePart{Name: "currency", Type: &String{}},
+	)
+	schema := NewSchema("export_order").
+		Field("id", &String{}).
+		Field("total", money).
+		Field("paid", &Boolean{}).
+		Field("placed_at", &DateTime{}).
+		Build()
+
+	first, err := RecordFromBSON(schema, bson.M{
+		"_id":            bson.NewObjectID(),
+		"total_amount":   int64(1250),
+		"total_currency": "EUR",
+		"paid":           true,
+		"placed_at":      time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	})
+	assert.NoError(t, err)
+
+	second := NewMongoRecord(schema)
+	assert.NoError(t, second.SetValue(mustField(t, schema, "paid"), false))
+
+	return schema, []JRecord{first, second}
+}
+
+func TestAvroSchema(t *testing.T) {
+	schema, _ := exportFixture(t)
+
+	got, err := AvroSchema(schema)
+	assert.NoError(t, err)
+
+	var parsed map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(got), &parsed))
+	assert.Equal(t, "export_order", parsed["name"])
+
+	var names []string
+	for _, field := range parsed["fields"].([]any) {
+		names = append(names, field.(map[string]any)["name"].(string))
+	}
+	assert.Equal(t, []string{"id", "total_amount", "total_currency", "paid", "placed_at"}, names)
+}
+
+func TestWriteAvro(t *testing.T) {
+	assert := assert.New(t)
+	schema, records := exportFixture(t)
+
+	var buf bytes.Buffer
+	assert.NoError(WriteAvro(&buf, schema, records))
+
+	data := buf.Bytes()
+	assert.Equal([]byte{'O', 'b', 'j', 1}, data[:4])
+	data = data[4:]
+
+	readLong := func() int64 {
+		v, n := binary.Varint(data)
+		data = data[n:]
+		return v
+	}
+	readBytes := func() []byte {
+		length := readLong()
+		b := data[:length]
+		data = data[length:]
+		return b
+	}
+
+	meta := map[string]string{}
+	for count := readLong(); count > 0; count = readLong() {
+		for range count {
+			key := string(readBytes())
+			meta[key] = string(readBytes())
+		}
+	}
+	assert.Equal("null", meta["avro.codec"])
+	assert.Contains(meta["avro.schema"], "timestamp-micros")
+
+	sync := data[:16]
+	data = data[16:]
+
+	assert.Equal(int64(2), readLong())
+	readLong() // block size
+
+	// First record: every column set
+	assert.Equal(int64(1), readLong())
+	assert.Len(readBytes(), 24)
+	assert.Equal(int64(1), readLong())
+	assert.Equal(int64(1250), readLong())
+	assert.Equal(int64(1), readLong())
+	assert.Equal("EUR", string(readBytes()))
+	assert.Equal(int64(1), readLong())
+	assert.Equal(byte(1), data[0])
+	data = data[1:]
+	assert.Equal(int64(1), readLong())
+	assert.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).UnixMicro(), readLong())
+
+	// Second record: only paid is set
+	for range 3 {
+		assert.Equal(int64(0), readLong())
+	}
+	assert.Equal(int64(1), readLong())
+	assert.Equal(byte(0), data[0])
+	data = data[1:]
+	assert.Equal(int64(0), readLong())
+
+	assert.Equal(sync, data)
+}
+
+func TestWriteParquet(t *testing.T) {
+	assert := assert.New(t)
+	schema, records := exportFixture(t)
+
+	var buf bytes.Buffer
+	assert.NoError(WriteParquet(&buf, schema, records))
+
+	data := buf.Bytes()
+	assert.Equal("PAR1", string(data[:4]))
+	assert.Equal("PAR1", string(data[len(data)-4:]))
+
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	for _, name := range []string{"export_order", "total_amount", "total_currency", "paid", "placed_at", "jpack"} {
+		assert.Contains(string(footer), name)
+	}
+	assert.Contains(string(data), "\x03\x00\x00\x00EUR")
+}
+
+func TestParquetLevels(t *testing.T) {
+	assert.Equal(t, []byte{0x04, 1, 0x02, 0, 0x06, 1}, parquetLevels([]bool{true, true, false, true, true, true}))
+}
+
+func TestThriftCompact(t *testing.T) {
+	tc := newThriftCompact()
+	tc.i32(1, 1)
+	tc.binary(4, "a")
+	tc.beginStruct(20)
+	tc.i64(1, -1)
+	tc.endStruct()
+	tc.endStruct()
+
+	assert.Equal(t, []byte{
+		0x15, 0x02, // field 1, i32, zigzag(1)
+		0x38, 0x01, 'a', // field 4 (delta 3), binary
+		0x0c, 0x28, // field 20 (long form), struct
+		0x16, 0x01, // field 1, i64, zigzag(-1)
+		0x00, // end nested struct
+		0x00, // end top-level struct
+	}, tc.buf)
+}
@@ -0,0 +1,207 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FieldOption configures a field added through the SchemaBuilder.
+type FieldOption func(*fieldImpl)
+
+// Immutable marks a field that can be set when a record is created but not
+// changed afterwards, e.g. created_by, tenant_id or external IDs.
+func Immutable() FieldOption {
+	return func(f *fieldImpl) {
+		f.immutable = true
+	}
+}
+
+// IsImmutable reports whether the field rejects updates after creation.
+func IsImmutable(field JField) bool {
+	f, ok := field.(interface{ Immutable() bool })
+	return ok && f.Immutable()
+}
+
+// ErrImmutableField is matched by every ImmutableFieldError.
+var ErrImmutableField = errors.New("field is immutable")
+
+// ImmutableFieldError is returned when an immutable field of an existing record is changed.
+type ImmutableFieldError struct {
+	Schema string
+	Field  string
+}
+
+func (e *ImmutableFieldError) Error() string {
+	return fmt.Sprintf("jpack: field %s.%s is immutable and cannot be changed after creation", e.Schema, e.Field)
+}
+
+// Is implements errors.Is support for ErrImmutableField.
+func (e *ImmutableFieldError) Is(target error) bool {
+	return target == ErrImmutableField
+}
+
+// Required marks a field that every record must have a value for. Save
+// rejects inserts without a value or default, and updates that clear it.
+func Required() FieldOption {
+	return func(f *fieldImpl) {
+		f.required = true
+	}
+}
+
+// IsRequired reports whether the field must have a value.
+func IsRequired(field JField) bool {
+	f, ok := field.(interface{ Required() bool })
+	return ok && f.Required()
+}
+
+// ErrRequiredField is matched by every RequiredFieldError.
+var ErrRequiredField = errors.New("field is required")
+
+// RequiredFieldError is returned when a record is saved without a value for a
+// required field.
+type RequiredFieldError struct {
+	Schema string
+	Field  string
+}
+
+func (e *RequiredFieldError) Error() string {
+	return fmt.Sprintf("jpack: field %s.%s is required", e.Schema, e.Field)
+}
+
+// Is implements errors.Is support for ErrRequiredField.
+func (e *RequiredFieldError) Is(target error) bool {
+	return target == ErrRequiredField
+}
+
+// Default sets the value filled in when a new record is saved without one.
+// The value may be a static value, a DefaultFunc (or func(context.Context)
+// any) evaluated at write time, or ServerNow().
+func Default(value any) FieldOption {
+	return func(f *fieldImpl) {
+		f.defaultValue = value
+	}
+}
+
+// Unique marks a field whose values must not repeat across records. Generated
+// migrations create a unique index for it.
+func Unique() FieldOption {
+	return func(f *fieldImpl) {
+		f.unique = true
+	}
+}
+
+// IsUnique reports whether the field's values must not repeat.
+func IsUnique(field JField) bool {
+	f, ok := field.(interface{ Unique() bool })
+	return ok && f.Unique()
+}
+
+// Meta attaches application metadata to a field, e.g. a UI label or widget.
+// jpack doesn't interpret it.
+func Meta(key string, value any) FieldOption {
+	return func(f *fieldImpl) {
+		if f.meta == nil {
+			f.meta = make(map[string]any)
+		}
+		f.meta[key] = value
+	}
+}
+
+// FieldMeta returns the metadata attached to a field with Meta.
+func FieldMeta(field JField) map[string]any {
+	if f, ok := field.(interface{ Meta() map[string]any }); ok {
+		return f.Meta()
+	}
+	return nil
+}
+
+// FieldAlias lists previous names of a field. Values stored under an alias are
+// read as the field's value, and are rewritten under the current name the next
+// time the record is saved.
+func FieldAlias(names ...string) FieldOption {
+	return func(f *fieldImpl) {
+		f.aliases = append(f.aliases, names...)
+	}
+}
+
+// FieldAliases returns the previous names of a field.
+func FieldAliases(field JField) []string {
+	if f, ok := field.(interface{ Aliases() []string }); ok {
+		return f.Aliases()
+	}
+	return nil
+}
+
+// Sensitive marks a field holding personal or secret data, e.g. emails or
+// phone numbers, which Anonymize rewrites. The category, e.g. "email",
+// selects the scrubber of the rules; it may be empty.
+func Sensitive(category string) FieldOption {
+	return func(f *fieldImpl) {
+		f.sensitive = true
+		f.sensitiveCategory = category
+	}
+}
+
+// SensitiveCategory returns the category of a sensitive field, and whether
+// the field is sensitive.
+func SensitiveCategory(field JField) (string, bool) {
+	if f, ok := field.(interface{ Sensitive() (string, bool) }); ok {
+		return f.Sensitive()
+	}
+	return "", false
+}
+
+// WithScanFallback tolerates legacy shapes of stored values, e.g. dates
+// stored as "DD/MM/YYYY", without a custom field type: when the field type
+// fails to scan a stored value, fallback is called with the raw value and
+// returns the scanned value instead. Its error is returned as the scan error.
+func WithScanFallback(fallback func(raw any) (any, error)) FieldOption {
+	return func(f *fieldImpl) {
+		f.scanFallback = fallback
+	}
+}
+
+// scanField scans the value of a field from a row with its type, falling
+// back to the field's scan fallback when the type fails. The scan is timed
+// into the context's HydrationProfile, if any.
+func scanField(ctx context.Context, field JField, row map[string]any) (any, error) {
+	if profile, ok := HydrationProfileFrom(ctx); ok {
+		start := time.Now()
+		defer func() { profile.observeScan(field, time.Since(start)) }()
+	}
+
+	value, err := field.Type().Scan(ctx, field, row)
+	if err == nil {
+		return value, nil
+	}
+
+	f, ok := field.(interface {
+		ScanFallback() func(raw any) (any, error)
+	})
+	if !ok || f.ScanFallback() == nil {
+		return nil, err
+	}
+	raw, ok := RowValue(field, row)
+	if !ok {
+		return nil, err
+	}
+	return f.ScanFallback()(raw)
+}
+
+// RowValue returns the value stored for the field in a row, falling back to
+// the field's aliases when the current name is absent.
+func RowValue(field JField, row map[string]any) (any, bool) {
+	if v, ok := row[field.Name()]; ok {
+		return v, true
+	}
+
+	for _, alias := range FieldAliases(field) {
+		if v, ok := row[alias]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
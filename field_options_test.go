@@ -0,0 +1,203 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestImmutableField(t *testing.T) {
+	schema := NewSchema("test_immutable").
+		Field("id", &String{}).
+		Field("tenant_id", &String{}, Immutable()).
+		Field("name", &String{}).
+		Build()
+	tenant := mustField(t, schema, "tenant_id")
+
+	t.Run("field option is applied", func(t *testing.T) {
+		assert.True(t, IsImmutable(tenant))
+		assert.False(t, IsImmutable(mustField(t, schema, "name")))
+	})
+
+	t.Run("new records can set immutable fields", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(tenant, "acme"))
+		assert.NoError(t, record.SetValue(tenant, "globex"))
+	})
+
+	t.Run("existing records reject changes", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		record.originalRecord = map[string]any{"id": "1", "tenant_id": "acme"}
+
+		err := record.SetValue(tenant, "globex")
+		assert.Error(t, err)
+		assert.True(t, errors.Is(err, ErrImmutableField))
+
+		var immutableErr *ImmutableFieldError
+		assert.True(t, errors.As(err, &immutableErr))
+		assert.Equal(t, "test_immutable", immutableErr.Schema)
+		assert.Equal(t, "tenant_id", immutableErr.Field)
+
+		assert.NoError(t, record.SetValue(tenant, "acme"), "setting the same value is not a change")
+		assert.NoError(t, record.SetValue(mustField(t, schema, "name"), "Acme Inc"))
+	})
+
+	t.Run("ref fields accept options", func(t *testing.T) {
+		postSchema := NewSchema("test_immutable_post").
+			Field("id", &String{}).
+			Ref("author", schema, Immutable()).
+			Build()
+
+		assert.True(t, IsImmutable(mustField(t, postSchema, "author")))
+	})
+}
+
+func TestRequiredField(t *testing.T) {
+	schema := NewSchema("test_required").
+		Field("id", &String{}).
+		Field("email", &String{}, Required()).
+		FieldWithDefault("status", &String{}, "active", Required()).
+		Field("name", &String{}).
+		Build()
+	email := mustField(t, schema, "email")
+
+	t.Run("field option is applied", func(t *testing.T) {
+		assert.True(t, IsRequired(email))
+		assert.False(t, IsRequired(mustField(t, schema, "name")))
+	})
+
+	t.Run("inserts need a value or default", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		record.SetValue(mustField(t, schema, "name"), "Jane")
+
+		err := record.Save(offlineContext(t))
+		assert.True(t, errors.Is(err, ErrRequiredField))
+
+		var requiredErr *RequiredFieldError
+		assert.True(t, errors.As(err, &requiredErr))
+		assert.Equal(t, "test_required", requiredErr.Schema)
+		assert.Equal(t, "email", requiredErr.Field)
+	})
+
+	t.Run("updates cannot clear the value", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		record.originalRecord = map[string]any{"id": bson.NewObjectID().Hex(), "email": "jane@example.com", "status": "active"}
+		assert.NoError(t, record.SetValue(email, nil))
+
+		err := record.Save(offlineContext(t))
+		assert.True(t, errors.Is(err, ErrRequiredField))
+	})
+}
+
+func TestFieldAlias(t *testing.T) {
+	schema := NewSchema("test_alias").
+		Field("id", &String{}).
+		Field("full_name", &String{}, FieldAlias("name", "fullname")).
+		Field("active", &Boolean{}, FieldAlias("enabled")).
+		Build()
+	fullName := mustField(t, schema, "full_name")
+
+	t.Run("RowValue falls back to aliases", func(t *testing.T) {
+		v, ok := RowValue(fullName, map[string]any{"fullname": "John"})
+		assert.True(t, ok)
+		assert.Equal(t, "John", v)
+
+		v, ok = RowValue(fullName, map[string]any{"full_name": "Jane", "name": "John"})
+		assert.True(t, ok)
+		assert.Equal(t, "Jane", v, "the current name wins over aliases")
+
+		_, ok = RowValue(fullName, map[string]any{})
+		assert.False(t, ok)
+	})
+
+	t.Run("Scan reads aliased keys", func(t *testing.T) {
+		got, err := fullName.Type().Scan(context.Background(), fullName, map[string]any{"name": "John"})
+		assert.NoError(t, err)
+		assert.Equal(t, "John", got)
+
+		active := mustField(t, schema, "active")
+		got, err = active.Type().Scan(context.Background(), active, map[string]any{"enabled": true})
+		assert.NoError(t, err)
+		assert.Equal(t, true, got)
+	})
+
+	t.Run("loaded documents expose and track aliased values", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(context.Background(), bson.M{"_id": bson.NewObjectID(), "name": "John"}))
+
+		got, ok := record.Value(fullName)
+		assert.True(t, ok)
+		assert.Equal(t, "John", got)
+		assert.NotContains(t, record.originalRecord, "name")
+		assert.Equal(t, map[string]string{"name": "full_name"}, record.renamedKeys)
+		assert.Empty(t, record.DirtyKeys())
+	})
+}
+
+func TestScanFallback(t *testing.T) {
+	legacyDate := func(raw any) (any, error) {
+		s, ok := raw.(string)
+		if !ok {
+			return nil, errors.New("not a legacy date")
+		}
+		return time.Parse("02/01/2006", s)
+	}
+	schema := NewSchema("test_scan_fallback").
+		Field("id", &String{}).
+		Field("born", &DateTime{}, WithScanFallback(legacyDate), FieldAlias("birthday")).
+		Field("died", &DateTime{}).
+		Build()
+	born, died := mustField(t, schema, "born"), mustField(t, schema, "died")
+
+	t.Run("legacy values scan through the fallback", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "birthday": "10/12/1815", "died": "27/11/1852"}))
+
+		got, ok := record.Time(born)
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC), got)
+
+		_, err := record.ScannedValue(t.Context(), died)
+		assert.Error(t, err, "fields without a fallback still fail")
+	})
+
+	t.Run("values the type reads skip the fallback", func(t *testing.T) {
+		stored := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+		got, err := scanField(t.Context(), born, map[string]any{"born": stored})
+		assert.NoError(t, err)
+		assert.Equal(t, stored, got)
+
+		_, err = scanField(t.Context(), born, map[string]any{"born": true})
+		assert.EqualError(t, err, "not a legacy date")
+	})
+
+	t.Run("Check tolerates legacy values", func(t *testing.T) {
+		issue, _ := checkField(t.Context(), born, bson.M{"born": "10/12/1815"})
+		assert.Empty(t, issue.Kind)
+	})
+}
+
+func TestFieldOptions(t *testing.T) {
+	schema := NewSchema("test_field_options").
+		Field("id", &String{}).
+		Field("email", &String{}, Required(), Unique(), Meta("label", "E-mail"), Meta("widget", "email")).
+		Field("status", &String{}, Default("active")).
+		FieldWithDefault("role", &String{}, "member", Default("admin")).
+		Field("name", &String{}).
+		Build()
+	email, name := mustField(t, schema, "email"), mustField(t, schema, "name")
+
+	assert.True(t, IsRequired(email))
+	assert.True(t, IsUnique(email))
+	assert.False(t, IsUnique(name))
+	assert.Equal(t, map[string]any{"label": "E-mail", "widget": "email"}, FieldMeta(email))
+	assert.Nil(t, FieldMeta(name))
+
+	assert.Equal(t, "active", mustField(t, schema, "status").Default())
+	assert.Equal(t, "admin", mustField(t, schema, "role").Default(), "options override the FieldWithDefault value")
+	assert.Nil(t, name.Default())
+}
@@ -0,0 +1,207 @@
+package jpack
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// FieldTypeFactory creates a field type from its configuration, e.g. the
+// options of a field in a schema loaded from a config file.
+type FieldTypeFactory func(config map[string]any) JFieldType
+
+var (
+	fieldTypesMu sync.RWMutex
+	fieldTypes   = make(map[string]FieldTypeFactory)
+)
+
+// RegisterFieldType registers a factory for a named field type so it can be
+// referenced by name. Registering an existing name replaces its factory.
+func RegisterFieldType(name string, factory FieldTypeFactory) {
+	fieldTypesMu.Lock()
+	defer fieldTypesMu.Unlock()
+
+	fieldTypes[name] = factory
+}
+
+// GetFieldType retrieves the factory registered under name.
+func GetFieldType(name string) (FieldTypeFactory, bool) {
+	fieldTypesMu.RLock()
+	defer fieldTypesMu.RUnlock()
+
+	factory, exists := fieldTypes[name]
+	return factory, exists
+}
+
+// NewFieldType creates a field type by its registered name.
+func NewFieldType(name string, config map[string]any) (JFieldType, error) {
+	factory, ok := GetFieldType(name)
+	if !ok {
+		return nil, fmt.Errorf("jpack: unknown field type %q", name)
+	}
+
+	fType := factory(config)
+	if fType == nil {
+		return nil, fmt.Errorf("jpack: field type %q could not be created from config", name)
+	}
+
+	return fType, nil
+}
+
+// RegisteredFieldTypes returns the names of all registered field types in sorted order.
+func RegisteredFieldTypes() []string {
+	fieldTypesMu.RLock()
+	defer fieldTypesMu.RUnlock()
+
+	names := make([]string, 0, len(fieldTypes))
+	for name := range fieldTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FieldTypeName returns the registry name of a field type. Custom types can
+// report their name by implementing TypeName() string; unknown types fall back
+// to their Go type name.
+func FieldTypeName(fType JFieldType) string {
+	switch t := fType.(type) {
+	case interface{ TypeName() string }:
+		return t.TypeName()
+	case *String:
+		return "string"
+	case *Text:
+		return "text"
+	case *Number:
+		return "number"
+	case *Boolean:
+		return "boolean"
+	case *DateTime:
+		return "datetime"
+	case *Ref:
+		return "ref"
+	case *Options:
+		return "options"
+	case *Composite:
+		return "composite"
+	case *File:
+		return "file"
+	}
+
+	return reflect.TypeOf(fType).String()
+}
+
+// Register the built-in field types
+func init() {
+	RegisterFieldType("string", func(config map[string]any) JFieldType {
+		return &String{}
+	})
+
+	RegisterFieldType("number", func(config map[string]any) JFieldType {
+		return &Number{}
+	})
+
+	RegisterFieldType("boolean", func(config map[string]any) JFieldType {
+		return &Boolean{}
+	})
+
+	// "compression" is "gzip" or "zstd", "compress_above" and "max_size" are
+	// sizes in bytes
+	RegisterFieldType("text", func(config map[string]any) JFieldType {
+		text := &Text{}
+		if compression, ok := config["compression"].(string); ok {
+			text.Compression = Compression(compression)
+		}
+		if size, ok := toFloat(config["compress_above"]); ok {
+			text.CompressAbove = int(size)
+		}
+		if size, ok := toFloat(config["max_size"]); ok {
+			text.MaxSize = int(size)
+		}
+		return text
+	})
+
+	RegisterFieldType("ref", func(config map[string]any) JFieldType {
+		return &Ref{}
+	})
+
+	// "layouts" lists extra accepted time layouts
+	RegisterFieldType("datetime", func(config map[string]any) JFieldType {
+		return &DateTime{Layouts: configStrings(config["layouts"])}
+	})
+
+	// "locales" lists the accepted locales, "fallback" the locales read when
+	// the context's locales have no value
+	RegisterFieldType("i18n_string", func(config map[string]any) JFieldType {
+		return &I18nString{Locales: configStrings(config["locales"]), Fallback: configStrings(config["fallback"])}
+	})
+
+	// "service" is an OptionService, or "options" a static []Option list
+	RegisterFieldType("options", func(config map[string]any) JFieldType {
+		if service, ok := config["service"].(OptionService); ok {
+			return NewOptions(service)
+		}
+		if options, ok := config["options"].([]Option); ok {
+			return NewOptions(NewInMemoryOptionService(options))
+		}
+		return nil
+	})
+
+	RegisterFieldType("percent", func(config map[string]any) JFieldType {
+		return &Percent{}
+	})
+
+	// "min" and "max" are the inclusive bounds, 1 and 5 by default
+	RegisterFieldType("rating", func(config map[string]any) JFieldType {
+		rating := &Rating{}
+		if min, ok := toFloat(config["min"]); ok {
+			rating.Min = int(min)
+		}
+		if max, ok := toFloat(config["max"]); ok {
+			rating.Max = int(max)
+		}
+		return rating
+	})
+
+	// "currencies" restricts the accepted ISO 4217 codes
+	RegisterFieldType("money", func(config map[string]any) JFieldType {
+		return &Money{Currencies: configStrings(config["currencies"])}
+	})
+
+	// "binary" stores addresses as 16 bytes
+	RegisterFieldType("ip_address", func(config map[string]any) JFieldType {
+		binary, _ := config["binary"].(bool)
+		return &IPAddress{Binary: binary}
+	})
+
+	RegisterFieldType("cidr", func(config map[string]any) JFieldType {
+		return &CIDR{}
+	})
+
+	// "store" is the BlobStore holding the content
+	RegisterFieldType("file", func(config map[string]any) JFieldType {
+		if store, ok := config["store"].(BlobStore); ok {
+			return NewFile(store)
+		}
+		return nil
+	})
+}
+
+// configStrings reads a list of strings from a field type config.
+func configStrings(value any) []string {
+	switch values := value.(type) {
+	case []string:
+		return values
+	case []any:
+		var strs []string
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type colorType struct {
+	String
+	palette []string
+}
+
+func TestFieldTypeRegistry(t *testing.T) {
+	t.Run("built-in types are registered", func(t *testing.T) {
+		for _, name := range []string{"string", "number", "boolean", "datetime", "ref"} {
+			fType, err := NewFieldType(name, nil)
+			assert.NoError(t, err, "field type %s should be registered", name)
+			assert.NotNil(t, fType)
+		}
+	})
+
+	t.Run("datetime layouts from config", func(t *testing.T) {
+		fType, err := NewFieldType("datetime", map[string]any{"layouts": []any{"2006-01-02"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"2006-01-02"}, fType.(*DateTime).Layouts)
+		assert.NoError(t, fType.Validate("2024-12-25"))
+	})
+
+	t.Run("options from static list", func(t *testing.T) {
+		fType, err := NewFieldType("options", map[string]any{
+			"options": []Option{{UniqueName: "red", DisplayName: "Red"}},
+		})
+		assert.NoError(t, err)
+
+		name, err := fType.(*Options).GetDisplayName(context.Background(), "red")
+		assert.NoError(t, err)
+		assert.Equal(t, "Red", name)
+	})
+
+	t.Run("options without a source fails", func(t *testing.T) {
+		_, err := NewFieldType("options", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("custom type", func(t *testing.T) {
+		RegisterFieldType("test_color", func(config map[string]any) JFieldType {
+			palette, _ := config["palette"].([]string)
+			return &colorType{palette: palette}
+		})
+		defer func() {
+			fieldTypesMu.Lock()
+			delete(fieldTypes, "test_color")
+			fieldTypesMu.Unlock()
+		}()
+
+		assert.Contains(t, RegisteredFieldTypes(), "test_color")
+
+		fType, err := NewFieldType("test_color", map[string]any{"palette": []string{"red"}})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"red"}, fType.(*colorType).palette)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := NewFieldType("does_not_exist", nil)
+		assert.Error(t, err)
+	})
+}
@@ -2,8 +2,11 @@ package jpack
 
 import (
 	"context"
+	"math"
 	"reflect"
 	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type mockField struct {
@@ -79,6 +82,83 @@ func TestNumber_Scan(t *testing.T) {
 			wantValue: num,
 			wantErr:   false,
 		},
+		{
+			name: "Int64 above 32 bits",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": int64(1) << 40},
+			},
+			wantValue: 1 << 40,
+			wantErr:   false,
+		},
+		{
+			name: "Int64 string above 32 bits",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": "4294967296"},
+			},
+			wantValue: 4294967296,
+			wantErr:   false,
+		},
+		{
+			name: "Int32 from BSON",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": int32(7)},
+			},
+			wantValue: 7,
+			wantErr:   false,
+		},
+		{
+			name: "Whole float",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": float64(12)},
+			},
+			wantValue: 12,
+			wantErr:   false,
+		},
+		{
+			name: "Fractional float is rejected",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": 12.5},
+			},
+			wantValue: nil,
+			wantErr:   true,
+		},
+		{
+			name: "Decimal128 whole number",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": mustDecimal128(t, "9007199254740993")},
+			},
+			wantValue: 9007199254740993,
+			wantErr:   false,
+		},
+		{
+			name: "Decimal128 fraction is rejected",
+			n:    &Number{},
+			args: args{
+				ctx:   context.Background(),
+				field: &mockField{name: "testField", fieldType: &Number{}},
+				row:   map[string]any{"testField": mustDecimal128(t, "1.5")},
+			},
+			wantValue: nil,
+			wantErr:   true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -88,6 +168,9 @@ func TestNumber_Scan(t *testing.T) {
 				t.Errorf("Number.Scan() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 			if !reflect.DeepEqual(gotValue, tt.wantValue) {
 				t.Errorf("Number.Scan() = %v, want %v", gotValue, tt.wantValue)
 			}
@@ -95,3 +178,56 @@ func TestNumber_Scan(t *testing.T) {
 	}
 
 }
+
+func mustDecimal128(t *testing.T, s string) bson.Decimal128 {
+	t.Helper()
+	d, err := bson.ParseDecimal128(s)
+	if err != nil {
+		t.Fatalf("failed to parse decimal128 %q: %v", s, err)
+	}
+	return d
+}
+
+func TestNumber_Validate(t *testing.T) {
+	var nilInt *int
+	num := int(42)
+
+	tests := []struct {
+		name    string
+		value   any
+		wantErr bool
+	}{
+		{name: "Nil", value: nil},
+		{name: "Nil pointer", value: nilInt},
+		{name: "Int", value: 42},
+		{name: "Int pointer", value: &num},
+		{name: "Int32", value: int32(7)},
+		{name: "Integer string", value: "42"},
+		{name: "Uint", value: uint(42)},
+		{name: "Uint64 within int64", value: uint64(math.MaxInt64)},
+		{name: "Uint64 overflow", value: uint64(math.MaxInt64) + 1, wantErr: true},
+		{name: "Whole float", value: float64(12)},
+		{name: "Whole float32", value: float32(3)},
+		{name: "Fractional float", value: 12.5, wantErr: true},
+		{name: "Infinite float", value: math.Inf(1), wantErr: true},
+		{name: "Float overflow", value: float64(math.MaxInt64), wantErr: true},
+		{name: "Decimal128 whole number", value: mustDecimal128(t, "9007199254740993")},
+		{name: "Decimal128 fraction", value: mustDecimal128(t, "1.5"), wantErr: true},
+		{name: "Non-numeric string", value: "abc", wantErr: true},
+		{name: "Bool", value: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &Number{}
+			err := n.Validate(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Number.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			_, convErr := convertToInt(reflect.ValueOf(tt.value))
+			if tt.value != nil && (convErr != nil) != (err != nil) {
+				t.Errorf("Number.Validate() error = %v, convertToInt error = %v", err, convErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package jpack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// BlobStore stores file contents for File fields.
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader, info FileInfo) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ErrBlobNotFound is returned by BlobStore.Get for unknown keys.
+var ErrBlobNotFound = errors.New("jpack: blob not found")
+
+// FileInfo is the metadata a File field stores in the record. The content
+// lives in the field's BlobStore under Key.
+type FileInfo struct {
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+func (f FileInfo) toMap() map[string]any {
+	return map[string]any{
+		"key":          f.Key,
+		"name":         f.Name,
+		"content_type": f.ContentType,
+		"size":         f.Size,
+		"sha256":       f.SHA256,
+		"uploaded_at":  f.UploadedAt,
+	}
+}
+
+// File is an attachment field type. Its value is a FileInfo; the content is
+// uploaded with UploadFile and read with OpenFile.
+type File struct {
+	Store BlobStore
+}
+
+// NewFile creates a File field type storing content in store.
+func NewFile(store BlobStore) *File {
+	return &File{Store: store}
+}
+
+// Scan implements JFieldType.
+func (f *File) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	return toFileInfo(v)
+}
+
+// SetValue implements JFieldType.
+func (f *File) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	info, err := toFileInfo(value)
+	if err != nil {
+		return err
+	}
+
+	row[field.Name()] = info.toMap()
+	return nil
+}
+
+// Validate implements JFieldType.
+func (f *File) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	_, err := toFileInfo(value)
+	return err
+}
+
+// toFileInfo converts a FileInfo or its stored document form.
+func toFileInfo(value any) (FileInfo, error) {
+	var doc map[string]any
+	switch v := value.(type) {
+	case FileInfo:
+		return v, v.validate()
+	case *FileInfo:
+		return *v, v.validate()
+	case map[string]any:
+		doc = v
+	case bson.M:
+		doc = v
+	case bson.D:
+		doc = make(map[string]any, len(v))
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+	default:
+		return FileInfo{}, errors.New("value is not a FileInfo")
+	}
+
+	var info FileInfo
+	info.Key, _ = doc["key"].(string)
+	info.Name, _ = doc["name"].(string)
+	info.ContentType, _ = doc["content_type"].(string)
+	info.SHA256, _ = doc["sha256"].(string)
+
+	if size, ok := doc["size"]; ok && size != nil {
+		n, err := convertToInt(reflect.ValueOf(size))
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("size: %w", err)
+		}
+		info.Size = int64(n)
+	}
+
+	if uploadedAt, ok := doc["uploaded_at"]; ok && uploadedAt != nil {
+		t, err := (&DateTime{}).convertToTime(uploadedAt)
+		if err != nil {
+			return FileInfo{}, fmt.Errorf("uploaded_at: %w", err)
+		}
+		info.UploadedAt = t.UTC()
+	}
+
+	return info, info.validate()
+}
+
+func (f FileInfo) validate() error {
+	if f.Key == "" {
+		return errors.New("file has no key")
+	}
+	return nil
+}
+
+// Example returns an example value for generated documentation.
+func (f *File) Example() any {
+	return FileInfo{
+		Key:         "507f1f77bcf86cd799439011",
+		Name:        "report.pdf",
+		ContentType: "application/pdf",
+		Size:        1024,
+		UploadedAt:  time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (f *File) OpenAPISchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"key":          map[string]any{"type": "string"},
+			"name":         map[string]any{"type": "string"},
+			"content_type": map[string]any{"type": "string"},
+			"size":         map[string]any{"type": "integer", "format": "int64"},
+			"sha256":       map[string]any{"type": "string"},
+			"uploaded_at":  map[string]any{"type": "string", "format": "date-time"},
+		},
+	}
+}
+
+func fileType(field JField) (*File, error) {
+	f, ok := field.Type().(*File)
+	if !ok || f.Store == nil {
+		return nil, fmt.Errorf("jpack: field %s is not a File field with a store", field.Name())
+	}
+	return f, nil
+}
+
+// UploadFile streams content to the field's BlobStore and sets the field of
+// the record to the new FileInfo. The record still has to be saved. A file it
+// replaces is not deleted; use DeleteFile once the record is saved.
+func UploadFile(ctx context.Context, record JRecord, field JField, name, contentType string, content io.Reader) (FileInfo, error) {
+	f, err := fileType(field)
+	if err != nil {
+		return FileInfo{}, err
+	}
+
+	info := FileInfo{
+		Key:         record.Schema().Name() + "/" + field.Name() + "/" + bson.NewObjectID().Hex(),
+		Name:        name,
+		ContentType: contentType,
+		UploadedAt:  time.Now().UTC(),
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(content, hash)}
+	if err := f.Store.Put(ctx, info.Key, counter, info); err != nil {
+		return FileInfo{}, err
+	}
+	info.Size = counter.n
+	info.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err := record.SetValue(field, info); err != nil {
+		return FileInfo{}, errors.Join(err, f.Store.Delete(ctx, info.Key))
+	}
+	return info, nil
+}
+
+// OpenFile opens the content of the record's file. The caller must close it.
+func OpenFile(ctx context.Context, record JRecord, field JField) (io.ReadCloser, FileInfo, error) {
+	f, err := fileType(field)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	value, ok := record.Value(field)
+	if !ok || value == nil {
+		return nil, FileInfo{}, ErrBlobNotFound
+	}
+
+	info, err := toFileInfo(value)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+
+	content, err := f.Store.Get(ctx, info.Key)
+	if err != nil {
+		return nil, FileInfo{}, err
+	}
+	return content, info, nil
+}
+
+// DeleteFile deletes a file's content from the field's BlobStore.
+func DeleteFile(ctx context.Context, field JField, info FileInfo) error {
+	f, err := fileType(field)
+	if err != nil {
+		return err
+	}
+	return f.Store.Delete(ctx, info.Key)
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// GridFSBlobStore stores files in a GridFS bucket of the context's database.
+type GridFSBlobStore struct {
+	// Bucket name, defaults to "fs".
+	Bucket string
+}
+
+func (s *GridFSBlobStore) bucket(ctx context.Context) *mongo.GridFSBucket {
+	opts := options.GridFSBucket()
+	if s.Bucket != "" {
+		opts.SetName(s.Bucket)
+	}
+	return MustConn(ctx).GridFSBucket(opts)
+}
+
+// Put implements BlobStore.
+func (s *GridFSBlobStore) Put(ctx context.Context, key string, content io.Reader, info FileInfo) error {
+	opts := options.GridFSUpload().SetMetadata(bson.M{"content_type": info.ContentType})
+	return s.bucket(ctx).UploadFromStreamWithID(ctx, key, info.Name, content, opts)
+}
+
+// Get implements BlobStore.
+func (s *GridFSBlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	stream, err := s.bucket(ctx).OpenDownloadStream(ctx, key)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return nil, ErrBlobNotFound
+	}
+	return stream, err
+}
+
+// Delete implements BlobStore.
+func (s *GridFSBlobStore) Delete(ctx context.Context, key string) error {
+	err := s.bucket(ctx).Delete(ctx, key)
+	if errors.Is(err, mongo.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
+
+var (
+	_ JFieldType       = &File{}
+	_ OpenAPIFieldType = &File{}
+	_ BlobStore        = &GridFSBlobStore{}
+)
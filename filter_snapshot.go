@@ -0,0 +1,146 @@
+package jpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ResolveFilterForTest resolves filter like ResolveFilter and renders it as
+// indented JSON with map keys sorted, so the output is stable and can be
+// compared against a snapshot. bson.D keeps its order, ObjectIDs render as
+// {"$oid": ...}, times as {"$date": ...} in UTC and regexes as
+// {"$regex": ..., "$options": ...}. A nil filter renders as "null".
+func ResolveFilterForTest(filter Filter) (string, error) {
+	var resolved any
+	if filter != nil {
+		if m := ResolveFilter(filter); m != nil {
+			resolved = m
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, resolved); err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func writeCanonicalJSON(buf *bytes.Buffer, value any) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+		return nil
+	case bson.D:
+		buf.WriteByte('{')
+		for i, e := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalKey(buf, e.Key, e.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case bson.E:
+		return writeCanonicalJSON(buf, bson.D{v})
+	case bson.ObjectID:
+		return writeCanonicalJSON(buf, bson.D{{Key: "$oid", Value: v.Hex()}})
+	case time.Time:
+		return writeCanonicalJSON(buf, bson.D{{Key: "$date", Value: v.UTC().Format(time.RFC3339Nano)}})
+	case bson.DateTime:
+		return writeCanonicalJSON(buf, v.Time())
+	case bson.Regex:
+		return writeCanonicalJSON(buf, bson.D{{Key: "$regex", Value: v.Pattern}, {Key: "$options", Value: v.Options}})
+	case []byte, json.Marshaler:
+		return writeJSONValue(buf, v)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeCanonicalJSON(buf, rv.Elem().Interface())
+
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("jpack: cannot render map with %s keys", rv.Type().Key())
+		}
+		if rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+
+		keys := make([]string, 0, rv.Len())
+		for _, key := range rv.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		slices.Sort(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			elem := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+			if err := writeCanonicalKey(buf, key, elem.Interface()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+
+		buf.WriteByte('[')
+		for i := range rv.Len() {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buf, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	return writeJSONValue(buf, value)
+}
+
+func writeCanonicalKey(buf *bytes.Buffer, key string, value any) error {
+	if err := writeJSONValue(buf, key); err != nil {
+		return err
+	}
+	buf.WriteByte(':')
+	return writeCanonicalJSON(buf, value)
+}
+
+func writeJSONValue(buf *bytes.Buffer, value any) error {
+	var data bytes.Buffer
+	enc := json.NewEncoder(&data)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return fmt.Errorf("jpack: cannot render %T: %w", value, err)
+	}
+	buf.Write(bytes.TrimSuffix(data.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,74 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestResolveFilterForTest(t *testing.T) {
+	name := mustField(t, userSchema, "first_name")
+	age := mustField(t, userSchema, "age")
+
+	t.Run("map keys are sorted", func(t *testing.T) {
+		filter := Between(age, 18, 65).And(Like(name, "^J&"))
+
+		for range 20 {
+			got, err := ResolveFilterForTest(filter)
+			assert.NoError(t, err)
+			assert.Equal(t, `{
+  "$and": [
+    {
+      "age": {
+        "$gte": 18,
+        "$lte": 65
+      }
+    },
+    {
+      "first_name": {
+        "$regex": "^J&"
+      }
+    }
+  ]
+}`, got)
+		}
+	})
+
+	t.Run("bson values render as extended JSON", func(t *testing.T) {
+		id, err := bson.ObjectIDFromHex("65f000000000000000000001")
+		assert.NoError(t, err)
+		at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+		got, err := ResolveFilterForTest(In(name, []any{id, at, bson.D{{Key: "z", Value: 1}, {Key: "a", Value: 2}}}))
+		assert.NoError(t, err)
+		assert.Equal(t, `{
+  "first_name": {
+    "$in": [
+      {
+        "$oid": "65f000000000000000000001"
+      },
+      {
+        "$date": "2024-03-01T11:00:00Z"
+      },
+      {
+        "z": 1,
+        "a": 2
+      }
+    ]
+  }
+}`, got)
+	})
+
+	t.Run("nil filter", func(t *testing.T) {
+		got, err := ResolveFilterForTest(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "null", got)
+	})
+
+	t.Run("unsupported values fail", func(t *testing.T) {
+		_, err := ResolveFilterForTest(Eq(name, map[int]string{1: "a"}))
+		assert.Error(t, err)
+	})
+}
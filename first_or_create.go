@@ -0,0 +1,160 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// FirstOrCreate implements Query. The lookup and the insert are one upsert,
+// so concurrent callers agree on a single record as long as a unique index
+// covers the equality conditions; without one MongoDB may still insert
+// duplicates under contention. The inserted document holds the equality
+// conditions of the filter, including policy filters, and the defaults;
+// schema defaults apply as on Save, with server-time defaults taken from the
+// local clock.
+func (q *mongoQuery) FirstOrCreate(ctx context.Context, defaults map[JField]any) (JRecord, bool, error) {
+	if IsReadOnly(ctx) {
+		return nil, false, ErrReadOnly
+	}
+	if q.err != nil {
+		return nil, false, q.err
+	}
+	if err := checkOutbox(ctx, q.schema); err != nil {
+		return nil, false, err
+	}
+
+	filter := q.filter()
+	insert, err := q.insertDocument(ctx, defaults)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Values fixed by the filter win, and _id keeps $setOnInsert non-empty
+	fixed := equalityKeys(filter)
+	for _, key := range fixed {
+		for field := range insert {
+			if field == key || strings.HasPrefix(field, key+".") || strings.HasPrefix(key, field+".") {
+				delete(insert, field)
+			}
+		}
+	}
+	if _, ok := insert[defaultMongoPK]; !ok && !slices.Contains(fixed, defaultMongoPK) {
+		insert[defaultMongoPK] = bson.NewObjectID()
+	}
+	update := bson.M{"$setOnInsert": insert}
+
+	start := time.Now()
+	res, err := q.collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		tapQuery(ctx, writeOperation(q.collection, "upsert", filter, update, 0, err), start)
+		return nil, false, err
+	}
+	tapQuery(ctx, writeOperation(q.collection, "upsert", filter, update, res.MatchedCount+res.UpsertedCount, nil), start)
+	created := res.UpsertedID != nil
+
+	lookup := filter
+	if created {
+		lookup = bson.M{defaultMongoPK: res.UpsertedID}
+	}
+	opts := options.FindOne()
+	if len(q.orderBy) > 0 {
+		opts.SetSort(q.orderBy)
+	}
+
+	start = time.Now()
+	var doc bson.M
+	err = q.collection.FindOne(ctx, lookup, opts).Decode(&doc)
+	tapQuery(ctx, q.operation("findOne", lookup, false, 1, err), start)
+	if err != nil {
+		return nil, false, err
+	}
+
+	record := NewMongoRecord(q.schema)
+	if err := record.loadDocument(ctx, maps.Clone(doc)); err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		if err := writeOutbox(ctx, record, ChangeInsert); err != nil {
+			return nil, false, err
+		}
+		record.afterWrite(ctx, ChangeInsert)
+	}
+
+	return q.track(record), created, nil
+}
+
+// insertDocument converts the defaults and the schema defaults to the
+// document inserted by FirstOrCreate.
+func (q *mongoQuery) insertDocument(ctx context.Context, defaults map[JField]any) (bson.M, error) {
+	record := NewMongoRecord(q.schema)
+	for field, value := range defaults {
+		if err := record.SetValue(field, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+
+	serverDefaults, err := record.applyDefaults(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	for _, field := range serverDefaults {
+		record.record[field.Name()] = now
+	}
+
+	return record.convertToBSON(ctx, record.record)
+}
+
+// equalityKeys returns the keys a filter matches by plain equality, which an
+// upsert copies into the inserted document.
+func equalityKeys(filter bson.M) []string {
+	var keys []string
+	for key, value := range filter {
+		if key == "$and" {
+			if clauses, ok := value.([]bson.M); ok {
+				for _, clause := range clauses {
+					keys = append(keys, equalityKeys(clause)...)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "$") || isOperatorDocument(value) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func isOperatorDocument(value any) bool {
+	doc, ok := value.(bson.M)
+	if !ok {
+		return false
+	}
+	for key := range doc {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOrInsert returns the record of the schema whose fields equal key, e.g.
+// a natural key like an email address, inserting it with the key and the
+// defaults when there is none. It reports whether the record was inserted.
+// See Query.FirstOrCreate.
+func GetOrInsert(ctx context.Context, schema JSchema, key map[JField]any, defaults map[JField]any) (JRecord, bool, error) {
+	q := NewMongoQuery(ctx, schema)
+	for field, value := range key {
+		q = q.Where(Eq(field, value))
+	}
+	return q.FirstOrCreate(ctx, defaults)
+}
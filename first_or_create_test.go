@@ -0,0 +1,25 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEqualityKeys(t *testing.T) {
+	keys := equalityKeys(bson.M{"$and": []bson.M{
+		{"email": "ada@example.com"},
+		{"age": bson.M{"$gte": 18}},
+		{"address.city": "London"},
+		{"$or": []bson.M{{"a": 1}}},
+	}})
+	assert.ElementsMatch(t, []string{"email", "address.city"}, keys)
+}
+
+func TestFirstOrCreateReadOnly(t *testing.T) {
+	ctx := context.WithValue(offlineContext(t), ReadOnly, true)
+	_, _, err := NewMongoQuery(ctx, userSchema).FirstOrCreate(ctx, nil)
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
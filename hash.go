@@ -0,0 +1,120 @@
+package jpack
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Hash implements JRecord.
+func (m *mongoRecord) Hash() (string, error) {
+	return hashRecord(m)
+}
+
+// hashRecord returns a stable SHA-256 over the record's field values. Values
+// are normalized through their field types first, so "42" and 42 in a Number
+// field hash the same, and keys are written in sorted order. The primary key
+// is left out so copies of the same content hash equally.
+func hashRecord(record JRecord) (string, error) {
+	pkField, hasPK := PK(record.Schema())
+
+	row := make(map[string]any)
+	for _, field := range record.Schema().Fields() {
+		if hasPK && field.Name() == pkField.Name() {
+			continue
+		}
+
+		value, ok := record.Value(field)
+		if !ok {
+			continue
+		}
+
+		if err := field.Type().SetValue(context.Background(), field, value, row); err != nil {
+			return "", fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+
+	h := sha256.New()
+	writeCanonical(h, row)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeCanonical writes a type-tagged, order-independent encoding of value.
+func writeCanonical(w io.Writer, value any) {
+	switch v := value.(type) {
+	case nil:
+		io.WriteString(w, "n;")
+		return
+	case string:
+		fmt.Fprintf(w, "s%d:%s;", len(v), v)
+		return
+	case bool:
+		fmt.Fprintf(w, "b%t;", v)
+		return
+	case time.Time:
+		fmt.Fprintf(w, "t%s;", v.UTC().Format(time.RFC3339Nano))
+		return
+	case bson.DateTime:
+		writeCanonical(w, v.Time())
+		return
+	case bson.ObjectID:
+		fmt.Fprintf(w, "o%s;", v.Hex())
+		return
+	case bson.Decimal128:
+		fmt.Fprintf(w, "d%s;", v.String())
+		return
+	case JRecord:
+		if id, ok := recordID(v); ok {
+			writeCanonical(w, id)
+			return
+		}
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		fmt.Fprintf(w, "i%d;", reflectValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fmt.Fprintf(w, "i%d;", reflectValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		fmt.Fprintf(w, "f%s;", strconv.FormatFloat(reflectValue.Float(), 'g', -1, 64))
+	case reflect.Pointer:
+		if reflectValue.IsNil() {
+			writeCanonical(w, nil)
+			return
+		}
+		writeCanonical(w, reflectValue.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		fmt.Fprintf(w, "a%d[", reflectValue.Len())
+		for i := 0; i < reflectValue.Len(); i++ {
+			writeCanonical(w, reflectValue.Index(i).Interface())
+		}
+		io.WriteString(w, "]")
+	case reflect.Map:
+		keys := make([]string, 0, reflectValue.Len())
+		values := make(map[string]any, reflectValue.Len())
+		for _, key := range reflectValue.MapKeys() {
+			k := fmt.Sprint(key.Interface())
+			keys = append(keys, k)
+			values[k] = reflectValue.MapIndex(key).Interface()
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(w, "m%d{", len(keys))
+		for _, k := range keys {
+			writeCanonical(w, k)
+			writeCanonical(w, values[k])
+		}
+		io.WriteString(w, "}")
+	default:
+		fmt.Fprintf(w, "x%T:%v;", value, value)
+	}
+}
@@ -0,0 +1,76 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRecordHash(t *testing.T) {
+	schema := NewSchema("test_hash").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("age", &Number{}).
+		Field("born", &DateTime{}).
+		Field("price", newMoneyType()).
+		Build()
+
+	build := func(values map[string]any) JRecord {
+		record := NewMongoRecord(schema)
+		for name, value := range values {
+			assert.NoError(t, record.SetValue(mustField(t, schema, name), value))
+		}
+		return record
+	}
+
+	hash := func(record JRecord) string {
+		h, err := record.Hash()
+		assert.NoError(t, err)
+		return h
+	}
+
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	base := hash(build(map[string]any{
+		"name":  "John",
+		"age":   42,
+		"born":  born,
+		"price": map[string]any{"amount": 10, "currency": "EUR"},
+	}))
+
+	t.Run("stable across equivalent representations", func(t *testing.T) {
+		assert.Equal(t, base, hash(build(map[string]any{
+			"price": map[string]any{"currency": "EUR", "amount": int64(10)},
+			"born":  born.In(time.FixedZone("IST", 19800)),
+			"age":   "42",
+			"name":  "John",
+		})))
+	})
+
+	t.Run("stored and pending values hash the same", func(t *testing.T) {
+		stored, err := RecordFromBSON(schema, bson.M{
+			"_id":            bson.NewObjectID(),
+			"name":           "John",
+			"age":            int32(42),
+			"born":           bson.NewDateTimeFromTime(born),
+			"price_amount":   int64(10),
+			"price_currency": "EUR",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, base, hash(stored), "the primary key is excluded")
+	})
+
+	t.Run("changes alter the hash", func(t *testing.T) {
+		assert.NotEqual(t, base, hash(build(map[string]any{
+			"name":  "John",
+			"age":   43,
+			"born":  born,
+			"price": map[string]any{"amount": 10, "currency": "EUR"},
+		})))
+	})
+
+	t.Run("unset and nil differ", func(t *testing.T) {
+		assert.NotEqual(t, hash(build(map[string]any{})), hash(build(map[string]any{"name": nil})))
+	})
+}
@@ -0,0 +1,106 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPOptionClient is an OptionService reading options owned by another
+// service from a REST endpoint that returns a JSON array of options:
+//
+//	[{"uniqueName": "active", "displayName": "Active"}]
+//
+// Responses are cached locally and revalidated with If-None-Match, so an
+// unchanged list costs a 304 without a body.
+type HTTPOptionClient struct {
+	URL    string
+	Client *http.Client
+	// Header is added to every request, e.g. for authorization.
+	Header http.Header
+	// MaxAge serves the cached options without revalidating them for this
+	// long; zero revalidates on every call.
+	MaxAge time.Duration
+
+	mu        sync.Mutex
+	options   []Option
+	etag      string
+	fetchedAt time.Time
+	now       func() time.Time
+}
+
+// HTTPOptionService creates an OptionService reading options from url. A nil
+// client uses http.DefaultClient.
+func HTTPOptionService(url string, client *http.Client) *HTTPOptionClient {
+	return &HTTPOptionClient{URL: url, Client: client}
+}
+
+// GetOptions implements OptionService.
+func (c *HTTPOptionClient) GetOptions(ctx context.Context) ([]Option, error) {
+	c.mu.Lock()
+	options, etag, fetchedAt := c.options, c.etag, c.fetchedAt
+	c.mu.Unlock()
+
+	if options != nil && c.MaxAge > 0 && c.clock().Sub(fetchedAt) < c.MaxAge {
+		return copyOptions(options), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Accept", "application/json")
+	if options != nil && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && options != nil:
+		// Cached options are still current
+	case res.StatusCode == http.StatusOK:
+		options = nil
+		if err := json.NewDecoder(res.Body).Decode(&options); err != nil {
+			return nil, fmt.Errorf("jpack: invalid options from %s: %w", c.URL, err)
+		}
+		if options == nil {
+			options = []Option{}
+		}
+		etag = res.Header.Get("ETag")
+	default:
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, fmt.Errorf("jpack: option service returned %d: %s", res.StatusCode, data)
+	}
+
+	c.mu.Lock()
+	c.options, c.etag, c.fetchedAt = options, etag, c.clock()
+	c.mu.Unlock()
+
+	return copyOptions(options), nil
+}
+
+func (c *HTTPOptionClient) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+var _ OptionService = &HTTPOptionClient{}
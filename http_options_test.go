@@ -0,0 +1,75 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPOptionService(t *testing.T) {
+	ctx := context.Background()
+	options := []Option{{UniqueName: "active", DisplayName: "Active"}, {UniqueName: "inactive", DisplayName: "Inactive"}}
+
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode(options)
+	}))
+	defer server.Close()
+
+	t.Run("revalidates with etag", func(t *testing.T) {
+		assert := assert.New(t)
+
+		svc := HTTPOptionService(server.URL, server.Client())
+		svc.Header = http.Header{"Authorization": {"Bearer token"}}
+
+		for range 3 {
+			got, err := svc.GetOptions(ctx)
+			assert.NoError(err)
+			assert.Equal(options, got)
+		}
+		assert.Equal(3, requests)
+		assert.Equal(2, notModified)
+
+		assert.NoError(NewOptions(svc).Validate("active"))
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		requests = 0
+		svc := HTTPOptionService(server.URL, server.Client())
+		svc.Header = http.Header{"Authorization": {"Bearer token"}}
+		svc.MaxAge = time.Minute
+		now := time.Now()
+		svc.now = func() time.Time { return now }
+
+		_, _ = svc.GetOptions(ctx)
+		_, _ = svc.GetOptions(ctx)
+		assert.Equal(t, 1, requests)
+
+		now = now.Add(time.Minute)
+		_, _ = svc.GetOptions(ctx)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}))
+		defer failing.Close()
+
+		_, err := HTTPOptionService(failing.URL, nil).GetOptions(ctx)
+		assert.ErrorContains(t, err, "503")
+	})
+}
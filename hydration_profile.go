@@ -0,0 +1,165 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hydrationProfileKey is the context key holding the HydrationProfile set
+// with WithHydrationProfile.
+var hydrationProfileKey key = "jpack.hydrationprofile"
+
+// HydrationProfile collects how long records take to load from stored
+// documents, and how the time splits over the Scan calls of their fields, to
+// find the field types that dominate query latency, e.g. Options backed by a
+// remote service. Collecting costs two clock reads per scan, so it is only
+// done for contexts carrying a profile.
+type HydrationProfile struct {
+	mu      sync.Mutex
+	records int
+	load    time.Duration
+	fields  map[string]*FieldTiming
+}
+
+// FieldTiming is the time spent scanning one field.
+type FieldTiming struct {
+	Schema string
+	Field  string
+	// Type is the registry name of the field type.
+	Type  string
+	Scans int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Mean returns the average time of a scan.
+func (t FieldTiming) Mean() time.Duration {
+	if t.Scans == 0 {
+		return 0
+	}
+	return t.Total / time.Duration(t.Scans)
+}
+
+// TypeTiming is the time spent scanning the fields of one field type.
+type TypeTiming struct {
+	Type  string
+	Scans int
+	Total time.Duration
+}
+
+// NewHydrationProfile creates an empty profile.
+func NewHydrationProfile() *HydrationProfile {
+	return &HydrationProfile{fields: make(map[string]*FieldTiming)}
+}
+
+// WithHydrationProfile returns a context whose loaded records and field scans
+// are timed into profile.
+func WithHydrationProfile(ctx context.Context, profile *HydrationProfile) context.Context {
+	return context.WithValue(ctx, hydrationProfileKey, profile)
+}
+
+// HydrationProfileFrom returns the profile of the context, if any.
+func HydrationProfileFrom(ctx context.Context) (*HydrationProfile, bool) {
+	profile, ok := ctx.Value(hydrationProfileKey).(*HydrationProfile)
+	return profile, ok && profile != nil
+}
+
+// observeRecord adds the load of a record.
+func (p *HydrationProfile) observeRecord(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records++
+	p.load += d
+}
+
+// observeScan adds a scan of the field.
+func (p *HydrationProfile) observeScan(field JField, d time.Duration) {
+	key := field.Schema().Name() + "." + field.Name()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.fields[key]
+	if !ok {
+		t = &FieldTiming{Schema: field.Schema().Name(), Field: field.Name(), Type: FieldTypeName(field.Type())}
+		p.fields[key] = t
+	}
+	t.Scans++
+	t.Total += d
+	t.Max = max(t.Max, d)
+}
+
+// Records returns the number of records loaded and the time it took.
+func (p *HydrationProfile) Records() (int, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.records, p.load
+}
+
+// Fields returns the timings of the scanned fields, slowest in total first.
+// Scans include values read from loaded records later, e.g. by
+// ScannedValue.
+func (p *HydrationProfile) Fields() []FieldTiming {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	timings := make([]FieldTiming, 0, len(p.fields))
+	for _, t := range p.fields {
+		timings = append(timings, *t)
+	}
+	slices.SortFunc(timings, func(a, b FieldTiming) int {
+		if a.Total != b.Total {
+			return int(b.Total - a.Total)
+		}
+		return strings.Compare(a.Schema+"."+a.Field, b.Schema+"."+b.Field)
+	})
+	return timings
+}
+
+// Types returns the scan timings summed by field type, slowest first.
+func (p *HydrationProfile) Types() []TypeTiming {
+	var timings []TypeTiming
+	for _, f := range p.Fields() {
+		i := slices.IndexFunc(timings, func(t TypeTiming) bool { return t.Type == f.Type })
+		if i < 0 {
+			timings = append(timings, TypeTiming{Type: f.Type})
+			i = len(timings) - 1
+		}
+		timings[i].Scans += f.Scans
+		timings[i].Total += f.Total
+	}
+	slices.SortStableFunc(timings, func(a, b TypeTiming) int { return int(b.Total - a.Total) })
+	return timings
+}
+
+// Reset drops the collected timings.
+func (p *HydrationProfile) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.records, p.load = 0, 0
+	clear(p.fields)
+}
+
+// String formats the profile as a report of the field types and fields.
+func (p *HydrationProfile) String() string {
+	records, load := p.Records()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d records loaded in %s\n", records, load)
+	b.WriteString("by type:\n")
+	for _, t := range p.Types() {
+		fmt.Fprintf(&b, "  %-16s %6d scans  %s\n", t.Type, t.Scans, t.Total)
+	}
+	b.WriteString("by field:\n")
+	for _, f := range p.Fields() {
+		fmt.Fprintf(&b, "  %-32s %6d scans  %s total  %s mean  %s max\n", f.Schema+"."+f.Field, f.Scans, f.Total, f.Mean(), f.Max)
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// slowScan is a field type whose values take a while to scan, like options
+// looked up in a remote service.
+type slowScan struct {
+	String
+}
+
+func (s *slowScan) TypeName() string { return "slow" }
+
+func (s *slowScan) Scan(ctx context.Context, field JField, row map[string]any) (any, error) {
+	time.Sleep(5 * time.Millisecond)
+	return s.String.Scan(ctx, field, row)
+}
+
+func TestHydrationProfile(t *testing.T) {
+	schema := NewSchema("test_hydration").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("body", &Text{}).
+		Field("status", &slowScan{}).
+		Build()
+	status := mustField(t, schema, "status")
+	name := mustField(t, schema, "name")
+
+	load := func(ctx context.Context) JRecord {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(ctx, bson.M{"_id": bson.NewObjectID(), "name": "Ada", "body": "notes", "status": "open"}))
+		for _, field := range []JField{name, status} {
+			_, err := record.ScannedValue(ctx, field)
+			assert.NoError(t, err)
+		}
+		return record
+	}
+
+	t.Run("records and scans are timed", func(t *testing.T) {
+		profile := NewHydrationProfile()
+		ctx := WithHydrationProfile(t.Context(), profile)
+		load(ctx)
+		load(ctx)
+
+		records, took := profile.Records()
+		assert.Equal(t, 2, records)
+		assert.Positive(t, took)
+
+		fields := profile.Fields()
+		assert.Len(t, fields, 3)
+		assert.Equal(t, "status", fields[0].Field)
+		assert.Equal(t, "slow", fields[0].Type)
+		assert.Equal(t, 2, fields[0].Scans)
+		assert.GreaterOrEqual(t, fields[0].Total, 10*time.Millisecond)
+		assert.GreaterOrEqual(t, fields[0].Max, 5*time.Millisecond)
+		assert.GreaterOrEqual(t, fields[0].Mean(), 5*time.Millisecond)
+
+		types := profile.Types()
+		assert.Equal(t, "slow", types[0].Type)
+		assert.Equal(t, 2, types[0].Scans)
+		assert.Contains(t, profile.String(), "test_hydration.status")
+
+		profile.Reset()
+		records, _ = profile.Records()
+		assert.Zero(t, records)
+		assert.Empty(t, profile.Fields())
+	})
+
+	t.Run("nothing is collected without a profile", func(t *testing.T) {
+		_, ok := HydrationProfileFrom(t.Context())
+		assert.False(t, ok)
+		_, ok = HydrationProfileFrom(WithHydrationProfile(t.Context(), nil))
+		assert.False(t, ok)
+		load(t.Context())
+	})
+}
@@ -0,0 +1,198 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// LocaleKey is the context key holding the preferred locales of a request.
+//
+// Deprecated: use WithLocale and LocalesFrom.
+var LocaleKey key = "jpack.locale"
+
+// WithLocale returns a context whose I18nString values are read in the first
+// of the locales that has a value, e.g. WithLocale(ctx, "de-AT", "en").
+func WithLocale(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, LocaleKey, locales)
+}
+
+// LocalesFrom returns the preferred locales stored in the context, if any.
+func LocalesFrom(ctx context.Context) []string {
+	locales, _ := ctx.Value(LocaleKey).([]string)
+	return locales
+}
+
+// I18nString is a field type for multi-language content. Its value is a map
+// of locale to string, stored as a subdocument, so single translations can
+// be filtered on as "<field>.<locale>". Scan returns the string of the
+// context's locale.
+type I18nString struct {
+	// Locales lists the accepted locales; empty accepts any.
+	Locales []string
+	// Fallback lists the locales read, in order, when none of the context's
+	// locales has a value.
+	Fallback []string
+}
+
+var _ OpenAPIFieldType = &I18nString{}
+
+// Scan implements JFieldType. It returns the translation for the first of
+// the context's locales that has one, trying a locale's language after the
+// locale itself, e.g. "de" after "de-AT", then the Fallback locales. It
+// returns nil when none of them has a value.
+func (s *I18nString) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	translations, err := toTranslations(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if text, ok := s.Resolve(ctx, translations); ok {
+		return text, nil
+	}
+	return nil, nil
+}
+
+// Resolve returns the translation for the context's locales and the
+// fallback chain.
+func (s *I18nString) Resolve(ctx context.Context, translations map[string]string) (string, bool) {
+	for _, locale := range LocalesFrom(ctx) {
+		if text, ok := translations[locale]; ok {
+			return text, true
+		}
+		if language, _, found := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-"); found {
+			if text, ok := translations[language]; ok {
+				return text, true
+			}
+		}
+	}
+	for _, locale := range s.Fallback {
+		if text, ok := translations[locale]; ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// SetValue implements JFieldType. The value replaces every translation.
+func (s *I18nString) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	if err := s.Validate(value); err != nil {
+		return err
+	}
+
+	translations, _ := toTranslations(value)
+	row[field.Name()] = translations
+	return nil
+}
+
+// Validate implements JFieldType.
+func (s *I18nString) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	translations, err := toTranslations(value)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Locales) == 0 {
+		return nil
+	}
+	for locale := range translations {
+		if !slices.Contains(s.Locales, locale) {
+			return fmt.Errorf("locale %q is not one of %s", locale, strings.Join(s.Locales, ", "))
+		}
+	}
+	return nil
+}
+
+// TypeName reports the registry name of the type.
+func (s *I18nString) TypeName() string {
+	return "i18n_string"
+}
+
+// Example returns an example value for generated documentation.
+func (s *I18nString) Example() any {
+	locale := "en"
+	if len(s.Locales) > 0 {
+		locale = s.Locales[0]
+	}
+	return map[string]string{locale: "text"}
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (s *I18nString) OpenAPISchema() map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "string"},
+	}
+	if len(s.Locales) > 0 {
+		schema["propertyNames"] = map[string]any{"enum": s.Locales}
+	}
+	return schema
+}
+
+// Translations returns every translation of an I18nString field of the
+// record.
+func Translations(record JRecord, field JField) (map[string]string, error) {
+	if _, ok := baseType(field.Type()).(*I18nString); !ok {
+		return nil, fmt.Errorf("jpack: field %s is not an I18nString", field.Name())
+	}
+
+	value, ok := record.Value(field)
+	if !ok || value == nil {
+		return nil, nil
+	}
+	return toTranslations(value)
+}
+
+// toTranslations converts a map of locale to string or its stored document
+// form.
+func toTranslations(value any) (map[string]string, error) {
+	var doc map[string]any
+	switch v := value.(type) {
+	case map[string]string:
+		return maps.Clone(v), nil
+	case map[string]any:
+		doc = v
+	case bson.M:
+		doc = v
+	case bson.D:
+		doc = make(map[string]any, len(v))
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+	default:
+		return nil, errors.New("value is not a map of locale to string")
+	}
+
+	translations := make(map[string]string, len(doc))
+	for locale, text := range doc {
+		s, ok := text.(string)
+		if !ok {
+			return nil, fmt.Errorf("translation for locale %q is not a string", locale)
+		}
+		translations[locale] = s
+	}
+	return translations, nil
+}
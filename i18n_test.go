@@ -0,0 +1,78 @@
+package jpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestI18nString(t *testing.T) {
+	i18n := &I18nString{Locales: []string{"en", "de", "de-CH", "fr"}, Fallback: []string{"en"}}
+	schema := NewSchema("test_i18n").
+		Field("id", &String{}).
+		Field("title", i18n).
+		Build()
+	title := mustField(t, schema, "title")
+
+	load := func(t *testing.T, translations bson.D) *mongoRecord {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "title": translations}))
+		return record
+	}
+
+	t.Run("Scan reads the context's locale", func(t *testing.T) {
+		record := load(t, bson.D{{Key: "en", Value: "Colour"}, {Key: "de", Value: "Farbe"}, {Key: "de-CH", Value: "Farb"}})
+
+		for locales, want := range map[string]any{
+			"de-CH":    "Farb",
+			"de-AT":    "Farbe",
+			"de_AT":    "Farbe",
+			"fr":       "Colour",
+			"":         "Colour",
+			"fr,de-CH": "Farb",
+		} {
+			ctx := t.Context()
+			if locales != "" {
+				ctx = WithLocale(ctx, strings.Split(locales, ",")...)
+			}
+			got, err := title.Type().Scan(ctx, title, record.originalRecord)
+			assert.NoError(t, err)
+			assert.Equal(t, want, got, locales)
+		}
+
+		record = load(t, bson.D{{Key: "fr", Value: "Couleur"}})
+		got, err := title.Type().Scan(WithLocale(t.Context(), "de"), title, record.originalRecord)
+		assert.NoError(t, err)
+		assert.Nil(t, got, "no translation in the chain")
+	})
+
+	t.Run("SetValue replaces the translations", func(t *testing.T) {
+		record := load(t, bson.D{{Key: "en", Value: "Colour"}})
+		assert.NoError(t, record.SetValue(title, map[string]any{"en": "Color", "fr": "Couleur"}))
+
+		translations, err := Translations(record, title)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"en": "Color", "fr": "Couleur"}, translations)
+
+		row := map[string]any{}
+		assert.NoError(t, i18n.SetValue(t.Context(), title, map[string]string{"de": "Farbe"}, row))
+		assert.Equal(t, map[string]string{"de": "Farbe"}, row["title"])
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, i18n.Validate(nil))
+		assert.EqualError(t, i18n.Validate(map[string]string{"es": "Color"}), `locale "es" is not one of en, de, de-CH, fr`)
+		assert.Error(t, i18n.Validate(map[string]any{"en": 42}))
+		assert.Error(t, i18n.Validate("Colour"))
+		assert.NoError(t, (&I18nString{}).Validate(map[string]string{"es": "Color"}), "any locale without Locales")
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		fType, err := NewFieldType("i18n_string", map[string]any{"locales": []any{"en", "de"}, "fallback": []string{"en"}})
+		assert.NoError(t, err)
+		assert.Equal(t, &I18nString{Locales: []string{"en", "de"}, Fallback: []string{"en"}}, fType)
+		assert.Equal(t, "i18n_string", FieldTypeName(fType))
+	})
+}
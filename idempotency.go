@@ -0,0 +1,64 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// idempotencyKeyField holds the idempotency key a document was inserted
+// with. It is hidden from records like treeIDKey.
+const idempotencyKeyField = "_idempotency_key"
+
+// WithIdempotencyKey makes the insert of a new record retry-safe, e.g. for
+// retried HTTP POSTs: the key is stored on the document, and when a document
+// was already inserted with the key, Save loads that document into the
+// record and returns nil instead of inserting a duplicate. It needs the
+// unique index created by IdempotencyIndexStep. Saves of stored records
+// ignore the key, and with the key Saves aren't batched by a RequestBatcher.
+func WithIdempotencyKey(key string) SaveOption {
+	return func(o *saveOptions) {
+		o.idempotencyKey = key
+	}
+}
+
+// IdempotencyIndexStep returns the migration step creating the unique index
+// WithIdempotencyKey relies on. The index is sparse, so documents inserted
+// without a key don't collide.
+func IdempotencyIndexStep(schema JSchema) MigrationStep {
+	return CreateIndexStep{Schema: schema, Keys: []string{idempotencyKeyField}, Unique: true, Sparse: true}
+}
+
+// loadIdempotent replaces the state of the record with the document inserted
+// with the key. It reports false when there is none, e.g. because a
+// duplicate key error came from another unique index.
+func (m *mongoRecord) loadIdempotent(ctx context.Context, coll *mongo.Collection, key string) (bool, error) {
+	filter := bson.M{idempotencyKeyField: key}
+	start := time.Now()
+	var doc bson.M
+	err := coll.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "findOne", Filter: filter}, start)
+		return false, nil
+	}
+	tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "findOne", Filter: filter, Count: 1, Err: err}, start)
+	if err != nil {
+		return false, err
+	}
+
+	stored := NewMongoRecord(m.schema)
+	if err := stored.loadDocument(ctx, doc); err != nil {
+		return false, err
+	}
+	m.originalRecord = stored.originalRecord
+	m.record = stored.record
+	m.renamedKeys = stored.renamedKeys
+	m.unknownKeys = stored.unknownKeys
+	m.lineage = stored.lineage
+	m.observers = nil
+	m.invalidateScanned()
+	return true, nil
+}
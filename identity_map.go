@@ -0,0 +1,105 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// IdentityMapKey is the context key holding the request's IdentityMap.
+//
+// Deprecated: use WithIdentityMap and IdentityMapFrom.
+var IdentityMapKey key = "jpack.identitymap"
+
+// IdentityMap makes every query within a request return the same JRecord
+// instance for the same primary key, so copies of a record can't diverge.
+type IdentityMap struct {
+	mu      sync.Mutex
+	records map[string]JRecord
+}
+
+// WithIdentityMap returns a context carrying a new, empty identity map.
+func WithIdentityMap(ctx context.Context) context.Context {
+	return context.WithValue(ctx, IdentityMapKey, &IdentityMap{
+		records: make(map[string]JRecord),
+	})
+}
+
+// IdentityMapFrom returns the identity map stored in the context, if any.
+func IdentityMapFrom(ctx context.Context) (*IdentityMap, bool) {
+	identityMap, ok := ctx.Value(IdentityMapKey).(*IdentityMap)
+	return identityMap, ok && identityMap != nil
+}
+
+// Get returns the record tracked for the schema and primary key.
+func (im *IdentityMap) Get(schema JSchema, id string) (JRecord, bool) {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	record, ok := im.records[identityKey(schema, id)]
+	return record, ok
+}
+
+// Track returns the record already tracked under the same primary key, or
+// starts tracking record and returns it. Records without a primary key value
+// are returned as is.
+func (im *IdentityMap) Track(record JRecord) JRecord {
+	id, ok := recordID(record)
+	if !ok {
+		return record
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	key := identityKey(record.Schema(), id)
+	if existing, ok := im.records[key]; ok {
+		return existing
+	}
+
+	im.records[key] = record
+	return record
+}
+
+// Forget stops tracking a record.
+func (im *IdentityMap) Forget(record JRecord) {
+	id, ok := recordID(record)
+	if !ok {
+		return
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	delete(im.records, identityKey(record.Schema(), id))
+}
+
+// Clear stops tracking all records.
+func (im *IdentityMap) Clear() {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	clear(im.records)
+}
+
+func identityKey(schema JSchema, id string) string {
+	return schema.Name() + "/" + id
+}
+
+// recordID returns the primary key value of a record as a string.
+func recordID(record JRecord) (string, bool) {
+	pkField, ok := PK(record.Schema())
+	if !ok {
+		return "", false
+	}
+
+	id, ok := record.Value(pkField)
+	if !ok || id == nil {
+		return "", false
+	}
+
+	if s, ok := id.(string); ok {
+		return s, s != ""
+	}
+	return fmt.Sprint(id), true
+}
@@ -0,0 +1,60 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIdentityMap(t *testing.T) {
+	ctx := WithIdentityMap(context.Background())
+	identityMap, ok := IdentityMapFrom(ctx)
+	assert.True(t, ok)
+
+	loaded := func(id bson.ObjectID) *mongoRecord {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.loadDocument(ctx, bson.M{"_id": id, "first_name": "John"}))
+		return record
+	}
+
+	t.Run("same primary key yields the same instance", func(t *testing.T) {
+		id := bson.NewObjectID()
+		first := identityMap.Track(loaded(id))
+		second := identityMap.Track(loaded(id))
+		assert.Same(t, first, second)
+
+		got, ok := identityMap.Get(userSchema, id.Hex())
+		assert.True(t, ok)
+		assert.Same(t, first, got)
+	})
+
+	t.Run("different primary keys are kept apart", func(t *testing.T) {
+		first := identityMap.Track(loaded(bson.NewObjectID()))
+		second := identityMap.Track(loaded(bson.NewObjectID()))
+		assert.NotSame(t, first, second)
+	})
+
+	t.Run("records without primary key are not tracked", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.Same(t, record, identityMap.Track(record))
+		assert.NotSame(t, record, identityMap.Track(NewMongoRecord(userSchema)))
+	})
+
+	t.Run("forget and clear", func(t *testing.T) {
+		id := bson.NewObjectID()
+		first := identityMap.Track(loaded(id))
+		identityMap.Forget(first)
+		assert.NotSame(t, first, identityMap.Track(loaded(id)))
+
+		identityMap.Clear()
+		_, ok := identityMap.Get(userSchema, id.Hex())
+		assert.False(t, ok)
+	})
+
+	t.Run("no identity map in context", func(t *testing.T) {
+		_, ok := IdentityMapFrom(context.Background())
+		assert.False(t, ok)
+	})
+}
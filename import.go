@@ -0,0 +1,346 @@
+package jpack
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const defaultImportBatchSize = 500
+
+// ImportFormat is the format of the rows read by an Importer.
+type ImportFormat int
+
+const (
+	// ImportJSON reads a JSON array of objects or JSON Lines, one object per
+	// line. Objects are applied like SetValuesFromJSON.
+	ImportJSON ImportFormat = iota
+
+	// ImportCSV reads CSV with a header row naming the fields. Composite
+	// fields have one column per part, named like their storage keys. Empty
+	// cells leave the field unset.
+	ImportCSV
+)
+
+// ImportRowError is the failure of a single imported row.
+type ImportRowError struct {
+	// Row is the 1-based number of the row, not counting a CSV header.
+	Row int
+	Err error
+}
+
+func (e ImportRowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+func (e ImportRowError) Unwrap() error {
+	return e.Err
+}
+
+// ImportReport sums up an import, also while it runs.
+type ImportReport struct {
+	// Rows is the number of rows read, including skipped ones.
+	Rows     int
+	Imported int
+	// Skipped counts the rows up to the ResumeFrom checkpoint.
+	Skipped int
+	Errors  []ImportRowError
+	// Checkpoint is the last row that was imported or failed. Passing it as
+	// ResumeFrom continues an interrupted import after it.
+	Checkpoint int
+}
+
+// Failed returns the number of rows that failed.
+func (r *ImportReport) Failed() int {
+	return len(r.Errors)
+}
+
+// Importer streams records of a schema from a reader, validates them and
+// saves them in batches. Rows that fail are collected into the report and
+// the import goes on, so a bulk onboarding isn't stopped by a few bad rows.
+type Importer struct {
+	Schema JSchema
+	Format ImportFormat
+	// BatchSize is the number of records written with one bulk write per
+	// collection.
+	BatchSize int
+	// UnknownKeys decides whether keys that are not schema fields fail their
+	// row or are ignored. A CSV header with an unknown column fails the
+	// import under RejectUnknownKeys.
+	UnknownKeys UnknownKeyPolicy
+	// ResumeFrom skips the rows up to a checkpoint of an earlier report.
+	ResumeFrom int
+	// OnProgress is called with the report after every batch is written.
+	OnProgress func(ImportReport)
+
+	flush func(ctx context.Context, batcher *RequestBatcher) error
+}
+
+// NewImporter creates an importer for rows of schema in the given format.
+func NewImporter(schema JSchema, format ImportFormat) *Importer {
+	return &Importer{
+		Schema:    schema,
+		Format:    format,
+		BatchSize: defaultImportBatchSize,
+		flush: func(ctx context.Context, batcher *RequestBatcher) error {
+			return batcher.Flush(ctx)
+		},
+	}
+}
+
+// importRow is a row read but not yet written.
+type importRow struct {
+	number int
+	record *mongoRecord
+}
+
+// Run imports the rows of r. It returns an error, with the report so far,
+// when the input can't be read any further or ctx is done; failed rows are
+// only reported.
+func (i *Importer) Run(ctx context.Context, r io.Reader) (*ImportReport, error) {
+	report := &ImportReport{Checkpoint: i.ResumeFrom}
+
+	var next func() (*mongoRecord, error)
+	switch i.Format {
+	case ImportJSON:
+		next = i.jsonRows(ctx, r)
+	case ImportCSV:
+		var err error
+		if next, err = i.csvRows(r); err != nil {
+			return report, err
+		}
+	default:
+		return report, errors.New("jpack: unknown import format")
+	}
+
+	batchSize := i.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	batcher := NewRequestBatcher()
+	batchCtx := WithRequestBatcher(ctx, batcher)
+	var batch []importRow
+
+	for {
+		if err := ctx.Err(); err != nil {
+			batcher.Discard()
+			return report, err
+		}
+
+		record, err := next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *ImportRowError
+		if err != nil && !errors.As(err, &rowErr) {
+			batcher.Discard()
+			return report, err
+		}
+
+		report.Rows++
+		row := report.Rows
+		if row <= i.ResumeFrom {
+			report.Skipped++
+			continue
+		}
+
+		if rowErr != nil {
+			rowErr.Row = row
+			report.Errors = append(report.Errors, *rowErr)
+			if len(batch) == 0 {
+				report.Checkpoint = row
+			}
+			continue
+		}
+
+		if err := record.Save(batchCtx); err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: row, Err: err})
+			if len(batch) == 0 {
+				report.Checkpoint = row
+			}
+			continue
+		}
+		batch = append(batch, importRow{number: row, record: record})
+
+		if len(batch) >= batchSize {
+			i.write(ctx, batcher, batch, row, report)
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		i.write(ctx, batcher, batch, report.Rows, report)
+	}
+	return report, nil
+}
+
+// write flushes a batch and records its outcome. Rows the flush didn't write
+// fail with its error.
+func (i *Importer) write(ctx context.Context, batcher *RequestBatcher, batch []importRow, last int, report *ImportReport) {
+	err := i.flush(ctx, batcher)
+	for _, row := range batch {
+		if err != nil && row.record.IsNew() {
+			report.Errors = append(report.Errors, ImportRowError{Row: row.number, Err: err})
+		} else {
+			report.Imported++
+		}
+	}
+	report.Checkpoint = last
+
+	if i.OnProgress != nil {
+		i.OnProgress(*report)
+	}
+}
+
+// jsonRows returns a reader of records from a JSON array or JSON Lines.
+func (i *Importer) jsonRows(ctx context.Context, r io.Reader) func() (*mongoRecord, error) {
+	buffered := bufio.NewReader(r)
+	dec := json.NewDecoder(buffered)
+	started, inArray := false, false
+
+	return func() (*mongoRecord, error) {
+		if !started {
+			started = true
+			first, err := firstNonSpace(buffered)
+			if err == io.EOF {
+				return nil, io.EOF
+			}
+			if err != nil {
+				return nil, fmt.Errorf("jpack: reading import: %w", err)
+			}
+			if first == '[' {
+				inArray = true
+				if _, err := dec.Token(); err != nil {
+					return nil, fmt.Errorf("jpack: reading import: %w", err)
+				}
+			}
+		}
+
+		if inArray && !dec.More() {
+			return nil, io.EOF
+		}
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF && !inArray {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, fmt.Errorf("jpack: reading import: %w", err)
+		}
+
+		record := NewMongoRecord(i.Schema)
+		if err := record.SetValuesFromJSON(ctx, raw, i.UnknownKeys); err != nil {
+			return nil, &ImportRowError{Err: err}
+		}
+		return record, nil
+	}
+}
+
+// firstNonSpace peeks at the first byte of r that isn't white space.
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		r.Discard(1)
+	}
+}
+
+// csvRows returns a reader of records from CSV with a header row.
+func (i *Importer) csvRows(r io.Reader) (func() (*mongoRecord, error), error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return func() (*mongoRecord, error) { return nil, io.EOF }, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jpack: reading import header: %w", err)
+	}
+
+	// Map every column to its field, and composite parts to their part name
+	type csvColumn struct {
+		field JField
+		part  string
+	}
+	columns := make([]*csvColumn, len(header))
+	for n, name := range header {
+		name = strings.TrimSpace(name)
+		if field, ok := i.Schema.Field(name); ok {
+			columns[n] = &csvColumn{field: field}
+			continue
+		}
+		for _, field := range i.Schema.Fields() {
+			composite, ok := field.Type().(*Composite)
+			if !ok {
+				continue
+			}
+			for _, part := range composite.Parts {
+				if composite.partKey(field, part) == name {
+					columns[n] = &csvColumn{field: field, part: part.Name}
+				}
+			}
+		}
+		if columns[n] == nil && i.UnknownKeys == RejectUnknownKeys {
+			return nil, fmt.Errorf("jpack: import column %s is not a field of %s", name, i.Schema.Name())
+		}
+	}
+
+	return func() (*mongoRecord, error) {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &ImportRowError{Err: err}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jpack: reading import: %w", err)
+		}
+
+		values := make(map[JField]any)
+		for n, cell := range cells {
+			if n >= len(columns) || columns[n] == nil || cell == "" {
+				continue
+			}
+			column := columns[n]
+			if column.part == "" {
+				values[column.field] = cell
+				continue
+			}
+			parts, _ := values[column.field].(map[string]any)
+			if parts == nil {
+				parts = make(map[string]any)
+				values[column.field] = parts
+			}
+			parts[column.part] = cell
+		}
+
+		record := NewMongoRecord(i.Schema)
+		var errs []error
+		for _, field := range i.Schema.Fields() {
+			value, ok := values[field]
+			if !ok {
+				continue
+			}
+			if err := record.SetValue(field, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field.Name(), err))
+			}
+		}
+		if len(errs) > 0 {
+			return nil, &ImportRowError{Err: errors.Join(errs...)}
+		}
+		return record, nil
+	}, nil
+}
@@ -0,0 +1,120 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImporter(t *testing.T) {
+	schema := NewSchema("test_import").
+		Field("id", &String{}).
+		Field("name", &String{}, Required()).
+		Field("age", &Number{}).
+		Field("price", &Composite{Parts: []CompositePart{{Name: "amount", Type: &Number{}}, {Name: "currency", Type: &String{}}}}).
+		Build()
+
+	// newImporter records the names written by each flush
+	newImporter := func(format ImportFormat, flushes *[][]string) *Importer {
+		importer := NewImporter(schema, format)
+		importer.flush = func(ctx context.Context, batcher *RequestBatcher) error {
+			var names []string
+			for _, entry := range batcher.entries {
+				names = append(names, entry.write.document["name"].(string))
+			}
+			*flushes = append(*flushes, names)
+			batcher.Discard()
+			return nil
+		}
+		return importer
+	}
+
+	t.Run("json lines", func(t *testing.T) {
+		var flushes [][]string
+		importer := newImporter(ImportJSON, &flushes)
+		importer.BatchSize = 2
+		var progress []int
+		importer.OnProgress = func(r ImportReport) { progress = append(progress, r.Checkpoint) }
+
+		input := `{"name": "Ada", "age": 36}
+{"name": "Grace", "age": "old"}
+{"age": 40}
+{"name": "Linus"}
+{"name": "Ken", "shoe_size": 44}
+`
+		report, err := importer.Run(offlineContext(t), strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"Ada", "Linus"}}, flushes)
+		assert.Equal(t, 5, report.Rows)
+		assert.Equal(t, 2, report.Imported)
+		assert.Equal(t, 5, report.Checkpoint)
+		assert.Equal(t, []int{4}, progress)
+
+		assert.Equal(t, 3, report.Failed())
+		assert.Equal(t, 2, report.Errors[0].Row)
+		assert.Equal(t, 3, report.Errors[1].Row)
+		assert.ErrorIs(t, report.Errors[1], ErrRequiredField)
+		assert.ErrorContains(t, report.Errors[2], "row 5: shoe_size: unknown field")
+	})
+
+	t.Run("json array and resume", func(t *testing.T) {
+		var flushes [][]string
+		importer := newImporter(ImportJSON, &flushes)
+		importer.ResumeFrom = 2
+
+		report, err := importer.Run(offlineContext(t), strings.NewReader(` [{"name": "Ada"}, {"name": "Grace"}, {"name": "Linus"}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"Linus"}}, flushes)
+		assert.Equal(t, 2, report.Skipped)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, 3, report.Checkpoint)
+	})
+
+	t.Run("malformed json stops the import", func(t *testing.T) {
+		var flushes [][]string
+		importer := newImporter(ImportJSON, &flushes)
+		report, err := importer.Run(offlineContext(t), strings.NewReader(`{"name": "Ada"}
+{"name": `))
+		assert.ErrorContains(t, err, "reading import")
+		assert.Empty(t, flushes, "pending rows are not written")
+		assert.Equal(t, 1, report.Rows)
+		assert.Zero(t, report.Checkpoint)
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		var flushes [][]string
+		importer := newImporter(ImportCSV, &flushes)
+		importer.UnknownKeys = IgnoreUnknownKeys
+
+		input := "name,age,price_amount,price_currency,notes\nAda,36,10,EUR,x\nGrace,,,,\nLinus,abc,,,\n"
+		report, err := importer.Run(offlineContext(t), strings.NewReader(input))
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{{"Ada", "Grace"}}, flushes)
+		assert.Equal(t, 2, report.Imported)
+		assert.Len(t, report.Errors, 1)
+		assert.Equal(t, 3, report.Errors[0].Row)
+
+		importer.UnknownKeys = RejectUnknownKeys
+		_, err = importer.Run(offlineContext(t), strings.NewReader(input))
+		assert.ErrorContains(t, err, "notes is not a field")
+	})
+
+	t.Run("rows a failed flush didn't write fail", func(t *testing.T) {
+		importer := NewImporter(schema, ImportJSON)
+		failure := errors.New("bulk write failed")
+		importer.flush = func(ctx context.Context, batcher *RequestBatcher) error {
+			batcher.Discard()
+			return failure
+		}
+
+		report, err := importer.Run(offlineContext(t), strings.NewReader(`{"name": "Ada"} {"name": "Grace"}`))
+		assert.NoError(t, err)
+		assert.Zero(t, report.Imported)
+		assert.Len(t, report.Errors, 2)
+		assert.ErrorIs(t, report.Errors[1], failure)
+		assert.Equal(t, 2, report.Checkpoint)
+	})
+}
@@ -0,0 +1,320 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// maxAdvisorShapes bounds the query shapes an IndexAdvisor keeps.
+const maxAdvisorShapes = 1000
+
+// rangeOperators are the filter operators an index is scanned by range for;
+// other operators, such as $eq and $in, match by equality.
+var rangeOperators = []string{"$gt", "$gte", "$lt", "$lte", "$ne", "$nin", "$regex", "$exists", "$not"}
+
+// QueryShape is the fields an operation filters and sorts by, regardless of
+// the values it compares them with.
+type QueryShape struct {
+	Collection string
+	// Equality holds the fields matched by value, sorted.
+	Equality []string
+	// Sort holds the sorted fields, in sort order.
+	Sort []string
+	// Range holds the fields scanned by range, sorted.
+	Range []string
+}
+
+// Keys returns the keys of an index serving the shape, ordered equality,
+// sort, range.
+func (s QueryShape) Keys() []string {
+	var keys []string
+	for _, key := range slices.Concat(s.Equality, s.Sort, s.Range) {
+		if !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// String formats the shape.
+func (s QueryShape) String() string {
+	return fmt.Sprintf("%s eq%v sort%v range%v", s.Collection, s.Equality, s.Sort, s.Range)
+}
+
+// IndexSuggestion is an index missing for a query shape.
+type IndexSuggestion struct {
+	Shape QueryShape
+	// Keys are the suggested index keys, all ascending.
+	Keys []string
+	// Queries counts the operations of the shape, and Duration their time.
+	Queries  int
+	Duration time.Duration
+}
+
+// Model returns the index to create.
+func (s IndexSuggestion) Model() mongo.IndexModel {
+	keys := bson.D{}
+	for _, key := range s.Keys {
+		keys = append(keys, bson.E{Key: key, Value: 1})
+	}
+	return mongo.IndexModel{Keys: keys}
+}
+
+// String formats the suggestion.
+func (s IndexSuggestion) String() string {
+	return fmt.Sprintf("index %v on %s: %d queries in %s", s.Keys, s.Shape.Collection, s.Queries, s.Duration)
+}
+
+// shapeStats is what an IndexAdvisor knows of a query shape.
+type shapeStats struct {
+	shape    QueryShape
+	queries  int
+	duration time.Duration
+	// filter is the last filter of the shape, replayed by Warmup.
+	filter bson.M
+	sort   bson.D
+}
+
+// IndexAdvisor is a QueryTap collecting the shapes of the filters and sorts
+// operations run, to suggest the indexes missing for them. Install it with
+// SetQueryTap, or WithQueryTap for the operations of a context.
+type IndexAdvisor struct {
+	// MinQueries is the number of operations of a shape before an index is
+	// suggested for it, defaults to 1.
+	MinQueries int
+
+	mu     sync.Mutex
+	shapes map[string]*shapeStats
+}
+
+var _ QueryTap = &IndexAdvisor{}
+
+// Tap implements QueryTap.
+func (a *IndexAdvisor) Tap(ctx context.Context, op QueryOperation) {
+	if op.Operation == "insert" || op.Err != nil {
+		return
+	}
+	sort, _ := op.Options["sort"].(bson.D)
+	shape := shapeOf(op.Collection, op.Filter, sort)
+	if len(shape.Keys()) == 0 {
+		return
+	}
+	key := shape.String()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.shapes == nil {
+		a.shapes = make(map[string]*shapeStats)
+	}
+	stats, ok := a.shapes[key]
+	if !ok {
+		if len(a.shapes) >= maxAdvisorShapes {
+			return
+		}
+		stats = &shapeStats{shape: shape}
+		a.shapes[key] = stats
+	}
+	stats.queries++
+	stats.duration += op.Duration
+	stats.filter, stats.sort = op.Filter, sort
+}
+
+// shapeOf returns the shape of a filter and sort. The branches of $or and
+// $nor are merged, so the shape asks for one index serving them all.
+func shapeOf(collection string, filter bson.M, sort bson.D) QueryShape {
+	shape := QueryShape{Collection: collection}
+	addFilterFields(&shape, filter)
+	for _, e := range sort {
+		if e.Key != defaultMongoPK {
+			shape.Sort = append(shape.Sort, e.Key)
+		}
+	}
+
+	shape.Equality = slices.Compact(slices.Sorted(slices.Values(shape.Equality)))
+	shape.Range = slices.Compact(slices.Sorted(slices.Values(shape.Range)))
+	// A field matched by value doesn't need a range scan
+	shape.Range = slices.DeleteFunc(shape.Range, func(field string) bool { return slices.Contains(shape.Equality, field) })
+	return shape
+}
+
+// addFilterFields adds the fields of a filter to the shape.
+func addFilterFields(shape *QueryShape, filter bson.M) {
+	for key, value := range filter {
+		switch {
+		case key == "$and" || key == "$or" || key == "$nor":
+			for _, branch := range filterBranches(value) {
+				addFilterFields(shape, branch)
+			}
+		case strings.HasPrefix(key, "$") || key == defaultMongoPK:
+			// Expressions and text search don't map to index keys, and _id is
+			// always indexed
+		case isRangeCondition(value):
+			shape.Range = append(shape.Range, key)
+		default:
+			shape.Equality = append(shape.Equality, key)
+		}
+	}
+}
+
+// filterBranches returns the filters of a logical operator.
+func filterBranches(value any) []bson.M {
+	switch branches := value.(type) {
+	case []bson.M:
+		return branches
+	case []any:
+		var filters []bson.M
+		for _, branch := range branches {
+			if filter, ok := branch.(bson.M); ok {
+				filters = append(filters, filter)
+			}
+		}
+		return filters
+	}
+	return nil
+}
+
+// isRangeCondition reports whether a field condition uses a range operator.
+func isRangeCondition(value any) bool {
+	condition, ok := value.(bson.M)
+	if !ok {
+		return false
+	}
+	for operator := range condition {
+		if slices.Contains(rangeOperators, operator) {
+			return true
+		}
+	}
+	return false
+}
+
+// Shapes returns the query shapes seen, most frequent first.
+func (a *IndexAdvisor) Shapes() []QueryShape {
+	var shapes []QueryShape
+	for _, stats := range a.stats() {
+		shapes = append(shapes, stats.shape)
+	}
+	return shapes
+}
+
+// stats returns copies of the shape statistics, most frequent first.
+func (a *IndexAdvisor) stats() []shapeStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := make([]shapeStats, 0, len(a.shapes))
+	for _, key := range slices.Sorted(maps.Keys(a.shapes)) {
+		stats = append(stats, *a.shapes[key])
+	}
+	slices.SortStableFunc(stats, func(a, b shapeStats) int { return b.queries - a.queries })
+	return stats
+}
+
+// Reset forgets the shapes seen.
+func (a *IndexAdvisor) Reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	clear(a.shapes)
+}
+
+// Suggest returns the indexes missing for the shapes seen, given the keys of
+// the existing indexes by collection. A shape counts as served by an index
+// leading with one of its fields. Suggestions for the same keys are merged,
+// most queried first.
+func (a *IndexAdvisor) Suggest(existing map[string][][]string) []IndexSuggestion {
+	minQueries := max(a.MinQueries, 1)
+
+	var suggestions []IndexSuggestion
+	for _, stats := range a.stats() {
+		if stats.queries < minQueries || servedBy(stats.shape, existing[stats.shape.Collection]) {
+			continue
+		}
+
+		keys := stats.shape.Keys()
+		i := slices.IndexFunc(suggestions, func(s IndexSuggestion) bool {
+			return s.Shape.Collection == stats.shape.Collection && slices.Equal(s.Keys, keys)
+		})
+		if i < 0 {
+			suggestions = append(suggestions, IndexSuggestion{Shape: stats.shape, Keys: keys})
+			i = len(suggestions) - 1
+		}
+		suggestions[i].Queries += stats.queries
+		suggestions[i].Duration += stats.duration
+	}
+	slices.SortStableFunc(suggestions, func(a, b IndexSuggestion) int { return b.Queries - a.Queries })
+	return suggestions
+}
+
+// servedBy reports whether one of the indexes leads with a field of the
+// shape.
+func servedBy(shape QueryShape, indexes [][]string) bool {
+	keys := shape.Keys()
+	for _, index := range indexes {
+		if len(index) > 0 && slices.Contains(keys, index[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Advise lists the indexes of the collections seen in db and suggests the
+// missing ones.
+func (a *IndexAdvisor) Advise(ctx context.Context, db *mongo.Database) ([]IndexSuggestion, error) {
+	existing := make(map[string][][]string)
+	for _, stats := range a.stats() {
+		name := stats.shape.Collection
+		if _, ok := existing[name]; ok {
+			continue
+		}
+
+		specs, err := db.Collection(name).Indexes().ListSpecifications(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("jpack: indexes of %s: %w", name, err)
+		}
+		existing[name] = [][]string{}
+		for _, spec := range specs {
+			var keys bson.D
+			if err := bson.Unmarshal(spec.KeysDocument, &keys); err != nil {
+				return nil, err
+			}
+			index := make([]string, 0, len(keys))
+			for _, e := range keys {
+				index = append(index, e.Key)
+			}
+			existing[name] = append(existing[name], index)
+		}
+	}
+	return a.Suggest(existing), nil
+}
+
+// Warmup replays the last filter and sort of the n most frequent shapes
+// against db, fetching at most one document each, so MongoDB has cached
+// their plans before traffic arrives, e.g. after a deploy or a restart.
+func (a *IndexAdvisor) Warmup(ctx context.Context, db *mongo.Database, n int) error {
+	for i, stats := range a.stats() {
+		if i >= n {
+			break
+		}
+
+		opts := options.FindOne()
+		if len(stats.sort) > 0 {
+			opts.SetSort(stats.sort)
+		}
+		err := db.Collection(stats.shape.Collection).FindOne(ctx, stats.filter, opts).Err()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("jpack: warmup of %s: %w", stats.shape, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIndexAdvisor(t *testing.T) {
+	email := mustField(t, userSchema, "email")
+	age := mustField(t, userSchema, "age")
+	lastName := mustField(t, userSchema, "last_name")
+	ctx := context.Background()
+
+	find := func(filter Filter, sort bson.D) QueryOperation {
+		op := QueryOperation{Collection: "test_user", Operation: "find", Filter: ResolveFilter(filter), Duration: time.Millisecond}
+		if sort != nil {
+			op.Options = bson.M{"sort": sort}
+		}
+		return op
+	}
+
+	t.Run("shapes order keys equality, sort, range", func(t *testing.T) {
+		shape := shapeOf("test_user", ResolveFilter(Eq(lastName, "Doe").And(Gt(age, 18)).Or(Eq(email, "a@b.c"))), bson.D{{Key: "age", Value: 1}, {Key: "_id", Value: 1}})
+		assert.Equal(t, []string{"email", "last_name"}, shape.Equality)
+		assert.Equal(t, []string{"age"}, shape.Sort)
+		assert.Equal(t, []string{"age"}, shape.Range)
+		assert.Equal(t, []string{"email", "last_name", "age"}, shape.Keys())
+	})
+
+	t.Run("suggests indexes for shapes without one", func(t *testing.T) {
+		advisor := &IndexAdvisor{MinQueries: 2}
+		for range 3 {
+			advisor.Tap(ctx, find(Eq(lastName, "Doe").And(Gte(age, 18)), nil))
+		}
+		advisor.Tap(ctx, find(Eq(lastName, "Roe").And(Gte(age, 30)), nil))
+		advisor.Tap(ctx, find(Eq(email, "a@b.c"), nil))
+		advisor.Tap(ctx, find(Eq(email, "a@b.c"), nil))
+		advisor.Tap(ctx, find(Lt(age, 10), bson.D{{Key: "first_name", Value: 1}}))
+		advisor.Tap(ctx, QueryOperation{Collection: "test_user", Operation: "find", Filter: bson.M{"email": "x"}, Err: errors.New("boom")})
+		advisor.Tap(ctx, QueryOperation{Collection: "test_user", Operation: "insert", Document: bson.M{"email": "x"}})
+		advisor.Tap(ctx, QueryOperation{Collection: "test_user", Operation: "find", Filter: bson.M{"_id": bson.NewObjectID()}, Duration: time.Millisecond})
+
+		assert.Len(t, advisor.Shapes(), 3)
+
+		suggestions := advisor.Suggest(map[string][][]string{"test_user": {{"_id"}, {"email"}}})
+		assert.Len(t, suggestions, 1, "email is indexed and the age query ran once")
+		assert.Equal(t, []string{"last_name", "age"}, suggestions[0].Keys)
+		assert.Equal(t, 4, suggestions[0].Queries)
+		assert.Equal(t, 4*time.Millisecond, suggestions[0].Duration)
+		assert.Equal(t, bson.D{{Key: "last_name", Value: 1}, {Key: "age", Value: 1}}, suggestions[0].Model().Keys)
+		assert.Contains(t, suggestions[0].String(), "index [last_name age] on test_user: 4 queries")
+
+		assert.Empty(t, advisor.Suggest(map[string][][]string{"test_user": {{"last_name", "first_name"}, {"email"}}}))
+
+		advisor.Reset()
+		assert.Empty(t, advisor.Shapes())
+	})
+}
@@ -2,7 +2,12 @@ package jpack
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
 	"sync"
+	"time"
 )
 
 // InMemoryOptionService provides an in-memory implementation of OptionService
@@ -147,3 +152,114 @@ func (i *InMemoryOptionService) HasDisplayName(displayName string) bool {
 	}
 	return false
 }
+
+// ReplaceAll atomically replaces every option. Later duplicates of a
+// uniqueName are dropped, as with AddOption.
+func (i *InMemoryOptionService) ReplaceAll(options []Option) {
+	seen := make(map[string]bool, len(options))
+	result := make([]Option, 0, len(options))
+	for _, option := range options {
+		if seen[option.UniqueName] {
+			continue
+		}
+		seen[option.UniqueName] = true
+		result = append(result, option)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.options = result
+}
+
+// LoadFromJSON replaces every option with a JSON array read from r:
+//
+//	[{"uniqueName": "active", "displayName": "Active"}]
+//
+// The options are left unchanged if r is not a valid array.
+func (i *InMemoryOptionService) LoadFromJSON(r io.Reader) error {
+	var options []Option
+	if err := json.NewDecoder(r).Decode(&options); err != nil {
+		return fmt.Errorf("jpack: invalid options JSON: %w", err)
+	}
+	for _, option := range options {
+		if option.UniqueName == "" {
+			return fmt.Errorf("jpack: invalid options JSON: option %q has no uniqueName", option.DisplayName)
+		}
+	}
+
+	i.ReplaceAll(options)
+	return nil
+}
+
+// LoadFromJSONFile replaces every option with the JSON array in a file.
+func (i *InMemoryOptionService) LoadFromJSONFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return i.LoadFromJSON(f)
+}
+
+// WatchJSONFile loads the options from a JSON file and reloads them whenever
+// the file's modification time changes, checking every interval until ctx is
+// done. Failed reloads are logged and keep the previous options, so a bad
+// edit never empties the enum. Only the initial load returns an error.
+func (i *InMemoryOptionService) WatchJSONFile(ctx context.Context, path string, interval time.Duration) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := i.LoadFromJSONFile(path); err != nil {
+		return err
+	}
+	modTime := stat.ModTime()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			LoggerFrom(ctx).Error().Err(err).Str("path", path).Msg("jpack: failed to check options file")
+			continue
+		}
+		if stat.ModTime().Equal(modTime) {
+			continue
+		}
+
+		if err := i.LoadFromJSONFile(path); err != nil {
+			LoggerFrom(ctx).Error().Err(err).Str("path", path).Msg("jpack: failed to reload options file")
+			continue
+		}
+		modTime = stat.ModTime()
+	}
+}
+
+// SearchOptions implements OptionSearchService, matching unique and display
+// names case-insensitively.
+func (i *InMemoryOptionService) SearchOptions(ctx context.Context, query string, limit, offset int) ([]Option, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	matches := pageOptions(matchOptions(i.options, query), limit, offset)
+
+	// Return a copy of the options to prevent external modification
+	return copyOptions(matches), nil
+}
+
+// LookupOption implements OptionSearchService.
+func (i *InMemoryOptionService) LookupOption(ctx context.Context, uniqueName string) (Option, bool, error) {
+	option, found := i.GetOptionByUniqueName(uniqueName)
+	return option, found, nil
+}
+
+var _ OptionSearchService = &InMemoryOptionService{}
@@ -3,6 +3,9 @@ package jpack
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -417,3 +420,98 @@ func TestInMemoryOptionService_IntegrationWithOptionsFieldType(t *testing.T) {
 		assert.Equal(t, "Completed", displayName)
 	})
 }
+
+func TestInMemoryOptionService_SearchOptions(t *testing.T) {
+	ctx := context.Background()
+	service := NewInMemoryOptionService([]Option{
+		{UniqueName: "berlin", DisplayName: "Berlin"},
+		{UniqueName: "bern", DisplayName: "Bern"},
+		{UniqueName: "paris", DisplayName: "Paris"},
+		{UniqueName: "sydney", DisplayName: "Sydney"},
+	})
+
+	t.Run("Matches names case-insensitively", func(t *testing.T) {
+		options, err := service.SearchOptions(ctx, "BER", 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []Option{{UniqueName: "berlin", DisplayName: "Berlin"}, {UniqueName: "bern", DisplayName: "Bern"}}, options)
+	})
+
+	t.Run("Pages results", func(t *testing.T) {
+		options, err := service.SearchOptions(ctx, "", 2, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, []Option{{UniqueName: "bern", DisplayName: "Bern"}, {UniqueName: "paris", DisplayName: "Paris"}}, options)
+
+		options, err = service.SearchOptions(ctx, "", 2, 10)
+		assert.NoError(t, err)
+		assert.Empty(t, options)
+	})
+
+	t.Run("Looks up options", func(t *testing.T) {
+		option, found, err := service.LookupOption(ctx, "paris")
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "Paris", option.DisplayName)
+
+		_, found, err = service.LookupOption(ctx, "london")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+}
+
+func TestInMemoryOptionService_Load(t *testing.T) {
+	t.Run("ReplaceAll drops duplicates", func(t *testing.T) {
+		service := NewInMemoryOptionService([]Option{{UniqueName: "old", DisplayName: "Old"}})
+		service.ReplaceAll([]Option{
+			{UniqueName: "active", DisplayName: "Active"},
+			{UniqueName: "active", DisplayName: "Duplicate"},
+			{UniqueName: "inactive", DisplayName: "Inactive"},
+		})
+
+		options, _ := service.GetOptions(context.Background())
+		assert.Equal(t, []Option{{UniqueName: "active", DisplayName: "Active"}, {UniqueName: "inactive", DisplayName: "Inactive"}}, options)
+	})
+
+	t.Run("LoadFromJSON", func(t *testing.T) {
+		service := NewInMemoryOptionService([]Option{{UniqueName: "old", DisplayName: "Old"}})
+
+		err := service.LoadFromJSON(strings.NewReader(`[{"uniqueName": "active", "displayName": "Active"}]`))
+		assert.NoError(t, err)
+		assert.False(t, service.HasOption("old"))
+		assert.True(t, service.HasOption("active"))
+
+		assert.Error(t, service.LoadFromJSON(strings.NewReader(`{"active": "Active"}`)))
+		assert.Error(t, service.LoadFromJSON(strings.NewReader(`[{"displayName": "Nameless"}]`)))
+		assert.True(t, service.HasOption("active"))
+	})
+
+	t.Run("WatchJSONFile reloads changes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "statuses.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`[{"uniqueName": "active", "displayName": "Active"}]`), 0o644))
+
+		service := NewInMemoryOptionService(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- service.WatchJSONFile(ctx, path, time.Millisecond) }()
+
+		assert.Eventually(t, func() bool { return service.HasOption("active") }, time.Second, time.Millisecond)
+
+		// A broken edit keeps the previous options
+		assert.NoError(t, os.WriteFile(path, []byte(`[{`), 0o644))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Second)))
+		time.Sleep(20 * time.Millisecond)
+		assert.True(t, service.HasOption("active"))
+
+		assert.NoError(t, os.WriteFile(path, []byte(`[{"uniqueName": "pending", "displayName": "Pending"}]`), 0o644))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(2*time.Second)))
+		assert.Eventually(t, func() bool { return service.HasOption("pending") }, time.Second, time.Millisecond)
+		assert.False(t, service.HasOption("active"))
+
+		cancel()
+		assert.NoError(t, <-done)
+	})
+
+	t.Run("WatchJSONFile fails on a missing file", func(t *testing.T) {
+		service := NewInMemoryOptionService(nil)
+		assert.Error(t, service.WatchJSONFile(context.Background(), filepath.Join(t.TempDir(), "missing.json"), time.Second))
+	})
+}
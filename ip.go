@@ -0,0 +1,280 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// IPAddress is a field type for IPv4 and IPv6 addresses. Scan returns a
+// netip.Addr. Addresses are stored as canonical strings, e.g. "2001:db8::1",
+// or with Binary as 16 bytes, which InSubnet can filter on for IPv6 too.
+type IPAddress struct {
+	// Binary stores addresses as 16 bytes, IPv4 addresses IPv4-mapped, so
+	// they sort numerically.
+	Binary bool
+}
+
+var _ OpenAPIFieldType = &IPAddress{}
+
+// Scan implements JFieldType.
+func (t *IPAddress) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	if binary, ok := v.(bson.Binary); ok {
+		addr, ok := netip.AddrFromSlice(binary.Data)
+		if !ok {
+			return nil, errors.New("value is not a binary IP address")
+		}
+		return addr.Unmap(), nil
+	}
+	return toAddr(v)
+}
+
+// SetValue implements JFieldType.
+func (t *IPAddress) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	addr, err := toAddr(value)
+	if err != nil {
+		return err
+	}
+
+	if t.Binary {
+		row[field.Name()] = addrBinary(addr)
+	} else {
+		row[field.Name()] = addr.String()
+	}
+	return nil
+}
+
+// Validate implements JFieldType.
+func (t *IPAddress) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	_, err := toAddr(value)
+	return err
+}
+
+// TypeName reports the registry name of the type.
+func (t *IPAddress) TypeName() string {
+	return "ip_address"
+}
+
+// Example returns an example value for generated documentation.
+func (t *IPAddress) Example() any {
+	return "192.0.2.1"
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (t *IPAddress) OpenAPISchema() map[string]any {
+	return map[string]any{
+		"type":  "string",
+		"anyOf": []any{map[string]any{"format": "ipv4"}, map[string]any{"format": "ipv6"}},
+	}
+}
+
+// toAddr converts a netip.Addr, net.IP or address string.
+func toAddr(value any) (netip.Addr, error) {
+	switch v := value.(type) {
+	case netip.Addr:
+		if v.IsValid() {
+			return v.Unmap(), nil
+		}
+	case *netip.Addr:
+		return toAddr(*v)
+	case net.IP:
+		if addr, ok := netip.AddrFromSlice(v); ok {
+			return addr.Unmap(), nil
+		}
+	case string:
+		addr, err := netip.ParseAddr(strings.TrimSpace(v))
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("value is not a valid IP address: %w", err)
+		}
+		return addr.Unmap(), nil
+	}
+	return netip.Addr{}, errors.New("value is not a valid IP address")
+}
+
+// addrBinary returns the 16-byte form of an address.
+func addrBinary(addr netip.Addr) bson.Binary {
+	bytes := addr.As16()
+	return bson.Binary{Subtype: bson.TypeBinaryGeneric, Data: bytes[:]}
+}
+
+// CIDR is a field type for IP prefixes such as "10.0.0.0/8". Scan returns a
+// netip.Prefix. Prefixes are stored as canonical strings with the host bits
+// cleared.
+type CIDR struct{}
+
+var _ OpenAPIFieldType = &CIDR{}
+
+// Scan implements JFieldType.
+func (t *CIDR) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	return toPrefix(v)
+}
+
+// SetValue implements JFieldType.
+func (t *CIDR) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	prefix, err := toPrefix(value)
+	if err != nil {
+		return err
+	}
+	row[field.Name()] = prefix.String()
+	return nil
+}
+
+// Validate implements JFieldType.
+func (t *CIDR) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	_, err := toPrefix(value)
+	return err
+}
+
+// TypeName reports the registry name of the type.
+func (t *CIDR) TypeName() string {
+	return "cidr"
+}
+
+// Example returns an example value for generated documentation.
+func (t *CIDR) Example() any {
+	return "192.0.2.0/24"
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (t *CIDR) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "format": "cidr"}
+}
+
+// toPrefix converts a netip.Prefix, *net.IPNet or CIDR string, with the host
+// bits cleared.
+func toPrefix(value any) (netip.Prefix, error) {
+	var prefix netip.Prefix
+	switch v := value.(type) {
+	case netip.Prefix:
+		prefix = v
+	case *netip.Prefix:
+		prefix = *v
+	case *net.IPNet:
+		addr, ok := netip.AddrFromSlice(v.IP)
+		ones, bits := v.Mask.Size()
+		if !ok || bits == 0 {
+			return netip.Prefix{}, errors.New("value is not a valid CIDR prefix")
+		}
+		prefix = netip.PrefixFrom(addr.Unmap(), ones-(bits-addr.Unmap().BitLen()))
+	case string:
+		var err error
+		if prefix, err = netip.ParsePrefix(strings.TrimSpace(v)); err != nil {
+			return netip.Prefix{}, fmt.Errorf("value is not a valid CIDR prefix: %w", err)
+		}
+	default:
+		return netip.Prefix{}, errors.New("value is not a valid CIDR prefix")
+	}
+
+	if !prefix.IsValid() {
+		return netip.Prefix{}, errors.New("value is not a valid CIDR prefix")
+	}
+	return prefix.Masked(), nil
+}
+
+// InSubnet matches IPAddress fields within a prefix, given as a CIDR string
+// or netip.Prefix. Addresses stored as strings can only be matched against
+// IPv4 prefixes; other values match nothing.
+var InSubnet Comparator = NewComparator("IN SUBNET")
+
+func init() {
+	RegisterFilterResolver("IN SUBNET", func(filter Filter) bson.M {
+		field := filter.Field()
+		if field == nil {
+			return nil
+		}
+		prefix, err := toPrefix(filter.Value())
+		if err != nil {
+			return matchNothing
+		}
+
+		if t, ok := baseType(field.Type()).(*IPAddress); ok && t.Binary {
+			return bson.M{field.Name(): bson.M{
+				"$gte": addrBinary(prefix.Addr()),
+				"$lte": addrBinary(lastAddr(prefix)),
+			}}
+		}
+
+		if !prefix.Addr().Is4() {
+			return matchNothing
+		}
+		return bson.M{field.Name(): bson.M{"$regex": ipv4PrefixPattern(prefix)}}
+	})
+}
+
+// lastAddr returns the last address of a masked prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// ipv4PrefixPattern returns a regular expression matching the canonical
+// strings of the IPv4 addresses within a masked prefix.
+func ipv4PrefixPattern(prefix netip.Prefix) string {
+	octets := prefix.Addr().As4()
+	full, partial := prefix.Bits()/8, prefix.Bits()%8
+
+	var parts []string
+	for i := 0; i < 4; i++ {
+		switch {
+		case i < full:
+			parts = append(parts, strconv.Itoa(int(octets[i])))
+		case i == full && partial > 0:
+			// The octet is any value sharing the prefix's leading bits
+			first := int(octets[i])
+			var values []string
+			for v := first; v < first+1<<(8-partial); v++ {
+				values = append(values, strconv.Itoa(v))
+			}
+			parts = append(parts, "(?:"+strings.Join(values, "|")+")")
+		default:
+			parts = append(parts, `\d{1,3}`)
+		}
+	}
+	return "^" + strings.Join(parts, regexp.QuoteMeta(".")) + "$"
+}
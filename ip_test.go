@@ -0,0 +1,93 @@
+package jpack
+
+import (
+	"net"
+	"net/netip"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIPAddress(t *testing.T) {
+	schema := NewSchema("test_ip").
+		Field("id", &String{}).
+		Field("client", &IPAddress{}).
+		Field("peer", &IPAddress{Binary: true}).
+		Field("network", &CIDR{}).
+		Build()
+	client, peer, network := mustField(t, schema, "client"), mustField(t, schema, "peer"), mustField(t, schema, "network")
+
+	t.Run("stored canonically", func(t *testing.T) {
+		row := map[string]any{}
+		assert.NoError(t, client.Type().SetValue(t.Context(), client, " 2001:DB8:0:0::1 ", row))
+		assert.Equal(t, "2001:db8::1", row["client"])
+		assert.NoError(t, client.Type().SetValue(t.Context(), client, net.ParseIP("192.0.2.1"), row))
+		assert.Equal(t, "192.0.2.1", row["client"], "16-byte IPv4 is unmapped")
+
+		assert.NoError(t, peer.Type().SetValue(t.Context(), peer, "192.0.2.1", row))
+		assert.Equal(t, bson.Binary{Data: []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 192, 0, 2, 1}}, row["peer"])
+		got, err := peer.Type().Scan(t.Context(), peer, row)
+		assert.NoError(t, err)
+		assert.Equal(t, netip.MustParseAddr("192.0.2.1"), got)
+
+		assert.NoError(t, network.Type().SetValue(t.Context(), network, "10.1.2.3/8", row))
+		assert.Equal(t, "10.0.0.0/8", row["network"], "host bits are cleared")
+		_, ipNet, _ := net.ParseCIDR("2001:db8::/32")
+		assert.NoError(t, network.Type().SetValue(t.Context(), network, ipNet, row))
+		assert.Equal(t, "2001:db8::/32", row["network"])
+		got, err = network.Type().Scan(t.Context(), network, row)
+		assert.NoError(t, err)
+		assert.Equal(t, netip.MustParsePrefix("2001:db8::/32"), got)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.Error(t, client.Type().Validate("300.1.1.1"))
+		assert.Error(t, client.Type().Validate(42))
+		assert.Error(t, client.Type().Validate(netip.Addr{}))
+		assert.NoError(t, client.Type().Validate(nil))
+		assert.Error(t, network.Type().Validate("10.0.0.0"))
+		assert.Error(t, network.Type().Validate("10.0.0.0/33"))
+
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(client, "::1"))
+		assert.Error(t, record.SetValue(client, "localhost"))
+	})
+
+	t.Run("InSubnet on binary addresses", func(t *testing.T) {
+		filter := ResolveFilter(InSubnet(peer, "2001:db8::/112"))
+		first := bson.Binary{Data: netip.MustParseAddr("2001:db8::").AsSlice()}
+		last := bson.Binary{Data: netip.MustParseAddr("2001:db8::ffff").AsSlice()}
+		assert.Equal(t, bson.M{"peer": bson.M{"$gte": first, "$lte": last}}, filter)
+
+		filter = ResolveFilter(InSubnet(peer, netip.MustParsePrefix("10.0.0.0/8")))
+		assert.Equal(t, addrBinary(netip.MustParseAddr("10.255.255.255")), filter["peer"].(bson.M)["$lte"])
+	})
+
+	t.Run("InSubnet on IPv4 strings", func(t *testing.T) {
+		for cidr, cases := range map[string]map[string]bool{
+			"10.0.0.0/8":     {"10.1.2.3": true, "11.0.0.1": false, "110.0.0.1": false},
+			"172.16.0.0/12":  {"172.16.0.1": true, "172.31.255.255": true, "172.32.0.1": false, "172.15.0.1": false},
+			"192.168.1.0/24": {"192.168.1.77": true, "192.168.10.1": false},
+			"192.0.2.1/32":   {"192.0.2.1": true, "192.0.2.10": false},
+			"0.0.0.0/0":      {"8.8.8.8": true, "::1": false},
+		} {
+			filter := ResolveFilter(InSubnet(client, cidr))
+			pattern := regexp.MustCompile(filter["client"].(bson.M)["$regex"].(string))
+			for ip, want := range cases {
+				assert.Equal(t, want, pattern.MatchString(ip), "%s in %s", ip, cidr)
+			}
+		}
+
+		assert.Equal(t, matchNothing, ResolveFilter(InSubnet(client, "2001:db8::/32")), "IPv6 needs binary storage")
+		assert.Equal(t, matchNothing, ResolveFilter(InSubnet(client, "not a cidr")))
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		fType, err := NewFieldType("ip_address", map[string]any{"binary": true})
+		assert.NoError(t, err)
+		assert.Equal(t, &IPAddress{Binary: true}, fType)
+		assert.Equal(t, "cidr", FieldTypeName(&CIDR{}))
+	})
+}
@@ -0,0 +1,372 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Job statuses.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+const (
+	defaultJobMaxAttempts = 5
+	defaultJobVisibility  = 5 * time.Minute
+	defaultJobPoll        = time.Second
+	maxJobBackoff         = time.Hour
+)
+
+// JobsSchema is the schema of the jobs collection shared by all queues.
+var JobsSchema = NewSchema("jpack_jobs").
+	Field("id", &String{}).
+	Field("queue", &String{}).
+	Field("payload", &String{}).
+	Field("status", &String{}).
+	Field("attempts", &Number{}).
+	Field("max_attempts", &Number{}).
+	Field("run_at", &DateTime{}).
+	Field("locked_until", &DateTime{}).
+	Field("lock", &String{}).
+	Field("last_error", &String{}).
+	FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+	Build()
+
+// Job is a claimed job.
+type Job struct {
+	Record   JRecord
+	ID       string
+	Queue    string
+	Payload  json.RawMessage
+	Attempts int
+
+	maxAttempts int
+	lock        string
+}
+
+// Decode unmarshals the job's JSON payload into v.
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// JobOption configures an enqueued job.
+type JobOption func(record JRecord) error
+
+// RunAt delays a job until t.
+func RunAt(t time.Time) JobOption {
+	return func(record JRecord) error {
+		return record.SetValue(jobField("run_at"), t)
+	}
+}
+
+// MaxAttempts sets how often a job is tried before it is marked failed.
+func MaxAttempts(n int) JobOption {
+	return func(record JRecord) error {
+		return record.SetValue(jobField("max_attempts"), n)
+	}
+}
+
+func jobField(name string) JField {
+	field, _ := JobsSchema.Field(name)
+	return field
+}
+
+// JobQueue is a named queue of jobs stored in the jobs collection.
+type JobQueue struct {
+	Name string
+	// Backoff returns the delay before retrying a job that failed for the
+	// attempt-th time; defaults to 2^attempt seconds, capped at an hour.
+	Backoff func(attempt int) time.Duration
+}
+
+// NewJobQueue creates a queue with the default backoff.
+func NewJobQueue(name string) *JobQueue {
+	return &JobQueue{Name: name}
+}
+
+// Enqueue adds a job with a JSON encoded payload.
+func (q *JobQueue) Enqueue(ctx context.Context, payload any, opts ...JobOption) (JRecord, error) {
+	record, err := q.newJob(payload, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := record.Save(ctx); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (q *JobQueue) newJob(payload any, opts ...JobOption) (JRecord, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	record := NewMongoRecord(JobsSchema)
+	values := map[string]any{
+		"queue":        q.Name,
+		"payload":      string(data),
+		"status":       JobPending,
+		"attempts":     0,
+		"max_attempts": defaultJobMaxAttempts,
+		"run_at":       time.Now().UTC(),
+	}
+	for name, value := range values {
+		if err := record.SetValue(jobField(name), value); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, opt := range opts {
+		if err := opt(record); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// Claim atomically takes the next due job and hides it from other workers
+// for the visibility timeout. A job whose timeout expires, e.g. because its
+// worker crashed, can be claimed again while it has attempts left; one that
+// expired on its last attempt is marked failed. It returns nil when no job
+// is due.
+func (q *JobQueue) Claim(ctx context.Context, visibility time.Duration) (*Job, error) {
+	now := time.Now().UTC()
+	lock := bson.NewObjectID().Hex()
+
+	exhausted, failed := q.exhaustedJobs(now)
+	if _, err := collection(ctx, JobsSchema).UpdateMany(ctx, exhausted, failed); err != nil {
+		return nil, err
+	}
+
+	filter := q.dueJobs(now)
+	update := bson.M{
+		"$set": bson.M{"status": JobRunning, "locked_until": now.Add(visibility), "lock": lock},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var doc bson.M
+	err := collection(ctx, JobsSchema).FindOneAndUpdate(ctx, filter, update, opts).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := RecordFromBSON(JobsSchema, doc)
+	if err != nil {
+		return nil, err
+	}
+	return jobFromRecord(record)
+}
+
+// dueJobs matches the queue's jobs that can be claimed at now: pending jobs
+// whose run_at passed, and running jobs whose visibility timeout expired
+// with attempts left.
+func (q *JobQueue) dueJobs(now time.Time) bson.M {
+	return bson.M{
+		"queue": q.Name,
+		"$or": bson.A{
+			bson.M{"status": JobPending, "run_at": bson.M{"$lte": now}},
+			bson.M{
+				"status":       JobRunning,
+				"locked_until": bson.M{"$lte": now},
+				"$expr":        bson.M{"$lt": bson.A{"$attempts", "$max_attempts"}},
+			},
+		},
+	}
+}
+
+// exhaustedJobs returns the filter and update marking failed the queue's
+// running jobs whose visibility timeout expired on their last attempt.
+func (q *JobQueue) exhaustedJobs(now time.Time) (filter, update bson.M) {
+	filter = bson.M{
+		"queue":        q.Name,
+		"status":       JobRunning,
+		"locked_until": bson.M{"$lte": now},
+		"$expr":        bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}},
+	}
+	update = bson.M{
+		"$set":   bson.M{"status": JobFailed, "last_error": "jpack: job timed out on its last attempt"},
+		"$unset": bson.M{"lock": "", "locked_until": ""},
+	}
+	return filter, update
+}
+
+func jobFromRecord(record JRecord) (*Job, error) {
+	values := make(map[string]any)
+	for _, name := range []string{"queue", "payload", "attempts", "max_attempts", "lock"} {
+		field := jobField(name)
+		value, ok := record.Value(field)
+		if !ok {
+			continue
+		}
+
+		value, err := normalizeValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		values[name] = value
+	}
+
+	job := &Job{Record: record}
+	job.ID, _ = recordID(record)
+	job.Queue, _ = values["queue"].(string)
+	job.Attempts, _ = values["attempts"].(int)
+	job.maxAttempts, _ = values["max_attempts"].(int)
+	job.lock, _ = values["lock"].(string)
+	if payload, ok := values["payload"].(string); ok {
+		job.Payload = json.RawMessage(payload)
+	}
+	return job, nil
+}
+
+// Complete marks a claimed job done.
+func (q *JobQueue) Complete(ctx context.Context, job *Job) error {
+	return q.release(ctx, job, bson.M{"status": JobDone})
+}
+
+// Fail records a failed attempt. The job is retried after the backoff, or
+// marked failed once it ran out of attempts.
+func (q *JobQueue) Fail(ctx context.Context, job *Job, cause error) error {
+	set := bson.M{"last_error": cause.Error()}
+	if job.Attempts >= job.maxAttempts {
+		set["status"] = JobFailed
+	} else {
+		set["status"] = JobPending
+		set["run_at"] = time.Now().UTC().Add(q.backoff(job.Attempts))
+	}
+	return q.release(ctx, job, set)
+}
+
+// release updates a job if this worker still holds its claim.
+func (q *JobQueue) release(ctx context.Context, job *Job, set bson.M) error {
+	objID, err := bson.ObjectIDFromHex(job.ID)
+	if err != nil {
+		return err
+	}
+
+	res, err := collection(ctx, JobsSchema).UpdateOne(ctx,
+		bson.M{defaultMongoPK: objID, "lock": job.lock},
+		bson.M{"$set": set, "$unset": bson.M{"lock": "", "locked_until": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("jpack: job claim expired and was taken by another worker")
+	}
+	return nil
+}
+
+func (q *JobQueue) backoff(attempt int) time.Duration {
+	if q.Backoff != nil {
+		return q.Backoff(attempt)
+	}
+	if attempt >= 12 {
+		return maxJobBackoff
+	}
+	return min(time.Duration(1<<attempt)*time.Second, maxJobBackoff)
+}
+
+// Worker runs jobs of a queue.
+type Worker struct {
+	Queue   *JobQueue
+	Handler func(ctx context.Context, job *Job) error
+
+	// Concurrency is the number of jobs run at once, defaults to 1.
+	Concurrency int
+	// Visibility must exceed the handler's run time, defaults to 5 minutes.
+	Visibility time.Duration
+	// PollInterval is the wait after finding no due job, defaults to 1 second.
+	PollInterval time.Duration
+}
+
+// Run claims and handles jobs until ctx is done, then waits for running jobs.
+func (w *Worker) Run(ctx context.Context) error {
+	concurrency := max(w.Concurrency, 1)
+	visibility := w.Visibility
+	if visibility <= 0 {
+		visibility = defaultJobVisibility
+	}
+	poll := w.PollInterval
+	if poll <= 0 {
+		poll = defaultJobPoll
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+
+		job, err := w.Queue.Claim(ctx, visibility)
+		if err != nil || job == nil {
+			<-slots
+			if err != nil && ctx.Err() == nil {
+				LoggerFrom(ctx).Error().Err(err).Str("queue", w.Queue.Name).Msg("jpack: failed to claim job")
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(poll):
+			}
+			continue
+		}
+
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			defer func() { <-slots }()
+			w.handle(ctx, job)
+		}()
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, job *Job) {
+	// Finish bookkeeping for jobs that were running when ctx was cancelled
+	store := context.WithoutCancel(ctx)
+
+	err := w.run(ctx, job)
+	if err == nil {
+		err = w.Queue.Complete(store, job)
+	} else {
+		err = w.Queue.Fail(store, job, err)
+	}
+
+	if err != nil {
+		LoggerFrom(ctx).Error().Err(err).Str("queue", w.Queue.Name).Str("job", job.ID).Msg("jpack: failed to update job")
+	}
+}
+
+// run calls the handler, turning panics into errors so the job is retried.
+func (w *Worker) run(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("jpack: job panicked: %v", r)
+		}
+	}()
+	return w.Handler(ctx, job)
+}
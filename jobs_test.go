@@ -0,0 +1,97 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestJobQueue(t *testing.T) {
+	queue := NewJobQueue("emails")
+
+	t.Run("new jobs", func(t *testing.T) {
+		assert := assert.New(t)
+
+		runAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		record, err := queue.newJob(map[string]string{"to": "ada@example.com"}, RunAt(runAt), MaxAttempts(3))
+		assert.NoError(err)
+
+		for name, want := range map[string]any{
+			"queue":        "emails",
+			"payload":      `{"to":"ada@example.com"}`,
+			"status":       JobPending,
+			"max_attempts": 3,
+			"run_at":       runAt,
+		} {
+			got, ok := record.Value(jobField(name))
+			assert.True(ok, name)
+			assert.Equal(want, got, name)
+		}
+	})
+
+	t.Run("claimed documents", func(t *testing.T) {
+		assert := assert.New(t)
+
+		id := bson.NewObjectID()
+		record, err := RecordFromBSON(JobsSchema, bson.M{
+			"_id":          id,
+			"queue":        "emails",
+			"payload":      `{"to":"ada@example.com"}`,
+			"attempts":     int32(2),
+			"max_attempts": int32(5),
+			"lock":         "l1",
+		})
+		assert.NoError(err)
+
+		job, err := jobFromRecord(record)
+		assert.NoError(err)
+		assert.Equal(id.Hex(), job.ID)
+		assert.Equal(2, job.Attempts)
+		assert.Equal(5, job.maxAttempts)
+		assert.Equal("l1", job.lock)
+
+		var payload struct{ To string }
+		assert.NoError(job.Decode(&payload))
+		assert.Equal("ada@example.com", payload.To)
+	})
+
+	t.Run("expired claims are retried while attempts are left", func(t *testing.T) {
+		now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		retried := bson.M{"$lt": bson.A{"$attempts", "$max_attempts"}}
+		exhausted := bson.M{"$gte": bson.A{"$attempts", "$max_attempts"}}
+
+		due := queue.dueJobs(now)
+		assert.Equal(t, "emails", due["queue"])
+		running := due["$or"].(bson.A)[1].(bson.M)
+		assert.Equal(t, JobRunning, running["status"])
+		assert.Equal(t, bson.M{"$lte": now}, running["locked_until"])
+		assert.Equal(t, retried, running["$expr"])
+
+		filter, update := queue.exhaustedJobs(now)
+		assert.Equal(t, "emails", filter["queue"])
+		assert.Equal(t, JobRunning, filter["status"])
+		assert.Equal(t, bson.M{"$lte": now}, filter["locked_until"])
+		assert.Equal(t, exhausted, filter["$expr"])
+		assert.Equal(t, JobFailed, update["$set"].(bson.M)["status"])
+		assert.Equal(t, bson.M{"lock": "", "locked_until": ""}, update["$unset"])
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		assert.Equal(t, 2*time.Second, queue.backoff(1))
+		assert.Equal(t, 8*time.Second, queue.backoff(3))
+		assert.Equal(t, time.Hour, queue.backoff(40))
+
+		custom := &JobQueue{Name: "x", Backoff: func(int) time.Duration { return time.Minute }}
+		assert.Equal(t, time.Minute, custom.backoff(1))
+	})
+
+	t.Run("handler panics become errors", func(t *testing.T) {
+		worker := &Worker{Queue: queue, Handler: func(ctx context.Context, job *Job) error {
+			panic("boom")
+		}}
+		assert.ErrorContains(t, worker.run(context.Background(), &Job{}), "boom")
+	})
+}
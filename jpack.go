@@ -2,8 +2,9 @@ package jpack
 
 import (
 	"context"
+	"time"
 
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type JRecord interface {
@@ -12,14 +13,75 @@ type JRecord interface {
 	Value(JField) (any, bool)
 	SetValue(field JField, value any) error
 
+	// ScannedValue returns the value of a field converted by its type's Scan,
+	// e.g. a DateTime as time.Time. Results are memoized per record until the
+	// field is set again.
+	ScannedValue(ctx context.Context, field JField) (any, error)
+
+	// String, Int, Time and Bool return the scanned value of a field as the
+	// given type. They return the zero value and false when the field is
+	// unset, nil, fails to scan or scans to another type.
+	String(field JField) (string, bool)
+	Int(field JField) (int, bool)
+	Time(field JField) (time.Time, bool)
+	Bool(field JField) (bool, bool)
+
+	// OnChange registers fn to be called after SetValue changes a field's
+	// value. Observers are dropped once the record is saved.
+	OnChange(fn func(field JField, old, new any))
+
+	// IsSet reports whether the field has a value, which may be nil. Fields
+	// that were never set, or cleared with Unset, are not set.
+	IsSet(field JField) bool
+
+	// Snapshot captures the record's unsaved changes, and RollbackTo reverts
+	// the record to them, e.g. to undo the tentative edits of a wizard step
+	// without reading the record again. RollbackTo returns ErrStaleSnapshot
+	// for snapshots of another record or taken before the record was saved.
+	// OnChange observers are not called.
+	Snapshot() Snapshot
+	RollbackTo(snapshot Snapshot) error
+
+	// Unset clears a field. Unlike SetValue(field, nil), which stores null,
+	// Save removes an unset field from the stored document.
+	Unset(field JField) error
+
+	// SetValuesFromJSON applies a partial JSON object to the record. Only the
+	// provided keys are set, each is validated by its field type, and the
+	// record is left unchanged if any key fails.
+	SetValuesFromJSON(ctx context.Context, data []byte, policy UnknownKeyPolicy) error
+
 	Fields() []JField
 
 	IsModified() bool
 	IsNew() bool
 	DirtyKeys() []string
 
-	Save(ctx context.Context) error
-	Validate() error
+	// UnknownKeys returns the sorted keys of the stored document that are not
+	// declared in the schema, whatever its UnknownFieldPolicy.
+	UnknownKeys() []string
+
+	// Hash returns a stable content hash of the record's field values,
+	// excluding the primary key.
+	Hash() (string, error)
+
+	// ToBSON returns the record's storage representation without saving it.
+	ToBSON(ctx context.Context) (bson.M, error)
+
+	Save(ctx context.Context, opts ...SaveOption) error
+
+	// SaveWithRetry saves the record unless the stored document changed since
+	// it was loaded. On a conflict the document is read again and onConflict
+	// returns the record to save instead: the remote record with the local
+	// changes merged in, the local record to apply its changes on top of the
+	// stored document, or an error to give up. It returns
+	// ErrPreconditionFailed when the last of the attempts still conflicts, or
+	// when onConflict is nil, and ErrNotFound when the document was deleted.
+	SaveWithRetry(ctx context.Context, attempts int, onConflict func(local, remote JRecord) (JRecord, error)) error
+	Delete(ctx context.Context) error
+	// Validate checks the record's required values and record checks in
+	// the scenarios, without saving it.
+	Validate(ctx context.Context, scenarios ...Scenario) error
 }
 
 type Filter interface {
@@ -37,14 +99,16 @@ type Filter interface {
 
 // NewQuery creates a new query for the given schema and context
 // This is a convenience function that returns the appropriate query implementation
-// based on the context (MongoDB connection)
+// based on the context: MongoDB when there is a database for the schema, else
+// the context's SQLite store
 func NewQuery(ctx context.Context, schema JSchema) Query {
-	// Check if MongoDB connection is available in context
-	if _, ok := ctx.Value(Conn).(*mongo.Database); ok {
+	// Check if a MongoDB database is available for the schema
+	if _, ok := databaseFor(ctx, schema); ok {
 		return NewMongoQuery(ctx, schema)
 	}
+	if _, ok := SQLiteFrom(ctx); ok {
+		return NewSQLiteQuery(ctx, schema)
+	}
 
-	// For now, only MongoDB is supported
-	// In the future, this could support other databases
 	panic("jpack: no supported database connection found in context")
 }
@@ -0,0 +1,425 @@
+// Package jpacktest provides a conformance suite for jpack backends, so a new
+// backend can prove it behaves like the MongoDB one and regressions in either
+// are caught by the same cases.
+package jpacktest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/kabi175/jpack"
+)
+
+// Backend is a store under test.
+type Backend struct {
+	// Context carries the store, e.g. jpack.WithDatabase or jpack.WithSQLite.
+	Context context.Context
+
+	// NewRecord returns a new record of the schema stored by the backend.
+	NewRecord func(schema jpack.JSchema) jpack.JRecord
+
+	// Query returns a query of the schema. It defaults to jpack.NewQuery.
+	Query func(ctx context.Context, schema jpack.JSchema) jpack.Query
+
+	// FindByID reads the record of the schema with the primary key. It
+	// defaults to jpack.FindByID.
+	FindByID func(ctx context.Context, schema jpack.JSchema, id string) (jpack.JRecord, error)
+}
+
+// Factory returns a backend whose stores hold no records of the suite's
+// schemas. It is called once per case so the cases don't see each other's
+// records; it may skip the test when the backend is unavailable, and should
+// register its cleanup with t.Cleanup.
+type Factory func(t *testing.T, schemas ...jpack.JSchema) Backend
+
+var (
+	// Authors is the schema of the suite's referenced records.
+	Authors = jpack.NewSchema("jpacktest_authors").
+		Field("id", &jpack.String{}).
+		Field("name", &jpack.String{}, jpack.Required()).
+		Build()
+
+	// Books is the schema of the suite's records, referencing Authors.
+	Books = jpack.NewSchema("jpacktest_books").
+		Field("id", &jpack.String{}).
+		Field("title", &jpack.String{}, jpack.Required()).
+		Field("pages", &jpack.Number{}).
+		Field("isbn", &jpack.String{}).
+		Ref("author", Authors).
+		Build()
+)
+
+// RunBackendSuite runs the conformance cases against the backends made by
+// factory: CRUD, filters, ordering, pagination, references and error
+// semantics. Filters the backend's Capabilities don't support are skipped.
+func RunBackendSuite(t *testing.T, factory Factory) {
+	t.Helper()
+
+	cases := []struct {
+		name string
+		run  func(t *testing.T, s *suite)
+	}{
+		{"CRUD", testCRUD},
+		{"Filters", testFilters},
+		{"Ordering", testOrdering},
+		{"Pagination", testPagination},
+		{"Iteration", testIteration},
+		{"References", testReferences},
+		{"FirstOrCreate", testFirstOrCreate},
+		{"Errors", testErrors},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			backend := factory(t, Authors, Books)
+			if backend.Query == nil {
+				backend.Query = jpack.NewQuery
+			}
+			if backend.FindByID == nil {
+				backend.FindByID = jpack.FindByID
+			}
+			c.run(t, &suite{Backend: backend})
+		})
+	}
+}
+
+// suite is the backend of a case with helpers over the suite's schemas.
+type suite struct {
+	Backend
+}
+
+// field returns the field of the schema, failing the test when it is missing.
+func field(t *testing.T, schema jpack.JSchema, name string) jpack.JField {
+	t.Helper()
+	f, ok := schema.Field(name)
+	if !ok {
+		t.Fatalf("jpacktest: %s has no field %s", schema.Name(), name)
+	}
+	return f
+}
+
+// create saves a new record of the schema with the values.
+func (s *suite) create(t *testing.T, schema jpack.JSchema, values map[string]any) jpack.JRecord {
+	t.Helper()
+	record := s.NewRecord(schema)
+	for name, value := range values {
+		if err := record.SetValue(field(t, schema, name), value); err != nil {
+			t.Fatalf("setting %s: %v", name, err)
+		}
+	}
+	if err := record.Save(s.Context); err != nil {
+		t.Fatalf("saving %s: %v", schema.Name(), err)
+	}
+	return record
+}
+
+// seedBooks saves books with 100, 200 and 300 pages titled A, B and C; only
+// A and B have an ISBN.
+func (s *suite) seedBooks(t *testing.T) {
+	t.Helper()
+	s.create(t, Books, map[string]any{"title": "B", "pages": 200, "isbn": "isbn-b"})
+	s.create(t, Books, map[string]any{"title": "C", "pages": 300})
+	s.create(t, Books, map[string]any{"title": "A", "pages": 100, "isbn": "isbn-a"})
+}
+
+// id returns the primary key of a saved record.
+func id(t *testing.T, record jpack.JRecord) string {
+	t.Helper()
+	pk, _ := jpack.PK(record.Schema())
+	value, _ := record.String(pk)
+	if value == "" {
+		t.Fatalf("jpacktest: saved %s record has no primary key", record.Schema().Name())
+	}
+	return value
+}
+
+// titles returns the titles of the books matched by q.
+func titles(t *testing.T, q jpack.Query) []string {
+	t.Helper()
+	records, err := q.Execute()
+	if err != nil {
+		t.Fatalf("executing query: %v", err)
+	}
+	title := field(t, Books, "title")
+	result := make([]string, 0, len(records))
+	for _, record := range records {
+		value, _ := record.String(title)
+		result = append(result, value)
+	}
+	return result
+}
+
+func testCRUD(t *testing.T, s *suite) {
+	name := field(t, Authors, "name")
+
+	author := s.create(t, Authors, map[string]any{"name": "Ada"})
+	if author.IsNew() || author.IsModified() {
+		t.Fatalf("saved record: IsNew = %v, IsModified = %v, want false", author.IsNew(), author.IsModified())
+	}
+	authorID := id(t, author)
+
+	read := func() jpack.JRecord {
+		t.Helper()
+		record, err := s.FindByID(s.Context, Authors, authorID)
+		if err != nil {
+			t.Fatalf("reading by primary key: %v", err)
+		}
+		return record
+	}
+	if got, _ := read().String(name); got != "Ada" {
+		t.Errorf("read name = %q, want %q", got, "Ada")
+	}
+
+	stored := read()
+	if err := stored.SetValue(name, "Grace"); err != nil {
+		t.Fatal(err)
+	}
+	if err := stored.Save(s.Context); err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+	if got := id(t, stored); got != authorID {
+		t.Errorf("updated primary key = %q, want %q", got, authorID)
+	}
+	if got, _ := read().String(name); got != "Grace" {
+		t.Errorf("updated name = %q, want %q", got, "Grace")
+	}
+
+	if err := stored.Delete(s.Context); err != nil {
+		t.Fatalf("deleting: %v", err)
+	}
+	_, err := s.FindByID(s.Context, Authors, authorID)
+	if !errors.Is(err, jpack.ErrNotFound) {
+		t.Errorf("reading a deleted record: err = %v, want ErrNotFound", err)
+	}
+}
+
+func testFilters(t *testing.T, s *suite) {
+	title, pages, isbn := field(t, Books, "title"), field(t, Books, "pages"), field(t, Books, "isbn")
+	s.seedBooks(t)
+
+	tests := []struct {
+		name   string
+		filter jpack.Filter
+		want   []string
+	}{
+		{"=", jpack.Eq(title, "B"), []string{"B"}},
+		{"!=", jpack.Ne(title, "B"), []string{"A", "C"}},
+		{"<", jpack.Lt(pages, 200), []string{"A"}},
+		{"<=", jpack.Lte(pages, 200), []string{"A", "B"}},
+		{">", jpack.Gt(pages, 200), []string{"C"}},
+		{">=", jpack.Gte(pages, 200), []string{"B", "C"}},
+		{"IN", jpack.In(title, []any{"A", "C", "Z"}), []string{"A", "C"}},
+		{"NOT IN", jpack.NotIn(title, []any{"A", "C"}), []string{"B"}},
+		{"BETWEEN", jpack.Between(pages, 150, 300), []string{"B", "C"}},
+		{"NOT BETWEEN", jpack.NotBetween(pages, 150, 300), []string{"A"}},
+		{"EXISTS", jpack.Exists(isbn), []string{"A", "B"}},
+		{"NOT EXISTS", jpack.NotExists(isbn), []string{"C"}},
+		{"LIKE", jpack.Like(title, "^[AB]$"), []string{"A", "B"}},
+		{"NOT LIKE", jpack.NotLike(title, "^[AB]$"), []string{"C"}},
+		{"AND", jpack.Gt(pages, 100).And(jpack.Lt(pages, 300)), []string{"B"}},
+		{"OR", jpack.Eq(title, "A").Or(jpack.Eq(title, "C")), []string{"A", "C"}},
+		{"NOT", jpack.Eq(title, "A").Not(), []string{"B", "C"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := s.Query(s.Context, Books)
+			if !q.Capabilities().SupportsFilter(tt.filter) {
+				t.Skipf("backend doesn't support %s", tt.name)
+			}
+			got := titles(t, q.Where(tt.filter))
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testOrdering(t *testing.T, s *suite) {
+	title, pages := field(t, Books, "title"), field(t, Books, "pages")
+	s.seedBooks(t)
+
+	if got := titles(t, s.Query(s.Context, Books).OrderBy(pages)); !slices.Equal(got, []string{"A", "B", "C"}) {
+		t.Errorf("ordered by pages = %v, want [A B C]", got)
+	}
+	if got := titles(t, s.Query(s.Context, Books).OrderBy(title)); !slices.Equal(got, []string{"A", "B", "C"}) {
+		t.Errorf("ordered by title = %v, want [A B C]", got)
+	}
+}
+
+func testPagination(t *testing.T, s *suite) {
+	pages := field(t, Books, "pages")
+	s.seedBooks(t)
+
+	q := func() jpack.Query { return s.Query(s.Context, Books).OrderBy(pages) }
+	if got := titles(t, q().Limit(2)); !slices.Equal(got, []string{"A", "B"}) {
+		t.Errorf("first page = %v, want [A B]", got)
+	}
+	if got := titles(t, q().Limit(2).Offset(2)); !slices.Equal(got, []string{"C"}) {
+		t.Errorf("second page = %v, want [C]", got)
+	}
+	if got := titles(t, q().Offset(3)); len(got) != 0 {
+		t.Errorf("past the last page = %v, want none", got)
+	}
+
+	count, err := s.Query(s.Context, Books).Where(jpack.Gt(pages, 100)).Count()
+	if err != nil {
+		t.Fatalf("counting: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+}
+
+func testIteration(t *testing.T, s *suite) {
+	title, pages := field(t, Books, "title"), field(t, Books, "pages")
+	s.seedBooks(t)
+
+	var got []string
+	for record, err := range s.Query(s.Context, Books).OrderBy(pages).All(s.Context) {
+		if err != nil {
+			t.Fatalf("iterating: %v", err)
+		}
+		value, _ := record.String(title)
+		got = append(got, value)
+		if len(got) == 2 {
+			break
+		}
+	}
+	if !slices.Equal(got, []string{"A", "B"}) {
+		t.Errorf("iterated = %v, want [A B]", got)
+	}
+}
+
+func testReferences(t *testing.T, s *suite) {
+	name, title := field(t, Authors, "name"), field(t, Books, "title")
+	author := field(t, Books, "author").(jpack.JRef)
+
+	ada := s.create(t, Authors, map[string]any{"name": "Ada"})
+	s.create(t, Authors, map[string]any{"name": "Grace"})
+	s.create(t, Books, map[string]any{"title": "Notes", "author": id(t, ada)})
+	s.create(t, Books, map[string]any{"title": "Anonymous"})
+
+	records, err := s.Query(s.Context, Books).
+		With(author, func(_ jpack.JSchema, q jpack.Query) jpack.Query { return q }).
+		OrderBy(title).
+		Execute()
+	if err != nil {
+		t.Fatalf("executing query: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+
+	if value, _ := records[0].Value(author); value != nil {
+		t.Errorf("reference of a book without author = %v, want nil", value)
+	}
+	value, _ := records[1].Value(author)
+	referenced, ok := value.(jpack.JRecord)
+	if !ok {
+		t.Fatalf("reference = %T, want a loaded record", value)
+	}
+	if got, _ := referenced.String(name); got != "Ada" {
+		t.Errorf("referenced name = %q, want %q", got, "Ada")
+	}
+}
+
+func testFirstOrCreate(t *testing.T, s *suite) {
+	name := field(t, Authors, "name")
+
+	q := func() jpack.Query { return s.Query(s.Context, Authors).Where(jpack.Eq(name, "Ursula")) }
+	created, inserted, err := q().FirstOrCreate(s.Context, nil)
+	if err != nil || !inserted {
+		t.Fatalf("first call: inserted = %v, err = %v, want an insert", inserted, err)
+	}
+	found, inserted, err := q().FirstOrCreate(s.Context, nil)
+	if err != nil || inserted {
+		t.Fatalf("second call: inserted = %v, err = %v, want the existing record", inserted, err)
+	}
+	if id(t, found) != id(t, created) {
+		t.Errorf("second call found %s, want %s", id(t, found), id(t, created))
+	}
+	if count, _ := s.Query(s.Context, Authors).Count(); count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+}
+
+func testErrors(t *testing.T, s *suite) {
+	name := field(t, Authors, "name")
+
+	t.Run("First without a match is ErrNotFound", func(t *testing.T) {
+		_, err := s.Query(s.Context, Authors).Where(jpack.Eq(name, "nobody")).First()
+		if !errors.Is(err, jpack.ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("FindByID with a missing id is ErrNotFound", func(t *testing.T) {
+		_, err := s.FindByID(s.Context, Authors, "000000000000000000000000")
+		if !errors.Is(err, jpack.ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("FirstOrNil without a match is nil", func(t *testing.T) {
+		record, err := s.Query(s.Context, Authors).Where(jpack.Eq(name, "nobody")).FirstOrNil()
+		if err != nil || record != nil {
+			t.Errorf("record = %v, err = %v, want neither", record, err)
+		}
+	})
+
+	t.Run("missing required fields are a RequiredFieldError", func(t *testing.T) {
+		err := s.NewRecord(Authors).Save(s.Context)
+		var required *jpack.RequiredFieldError
+		if !errors.As(err, &required) || !errors.Is(err, jpack.ErrRequiredField) {
+			t.Fatalf("err = %v, want a RequiredFieldError", err)
+		}
+		if required.Field != "name" {
+			t.Errorf("required field = %q, want %q", required.Field, "name")
+		}
+	})
+
+	t.Run("IfMatch fails on new and changed records", func(t *testing.T) {
+		record := s.NewRecord(Authors)
+		if err := record.SetValue(name, "Ada"); err != nil {
+			t.Fatal(err)
+		}
+		if err := record.Save(s.Context, jpack.IfMatch(`"stale"`)); !errors.Is(err, jpack.ErrPreconditionFailed) {
+			t.Errorf("new record: err = %v, want ErrPreconditionFailed", err)
+		}
+
+		record = s.create(t, Authors, map[string]any{"name": "Ada"})
+		etag, err := jpack.ETag(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale, err := s.FindByID(s.Context, Authors, id(t, record))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := record.SetValue(name, "Grace"); err != nil {
+			t.Fatal(err)
+		}
+		if err := record.Save(s.Context, jpack.IfMatch(etag)); err != nil {
+			t.Fatalf("matching ETag: %v", err)
+		}
+		if err := stale.SetValue(name, "Hedy"); err != nil {
+			t.Fatal(err)
+		}
+		if err := stale.Save(s.Context, jpack.IfMatch(etag)); !errors.Is(err, jpack.ErrPreconditionFailed) {
+			t.Errorf("changed record: err = %v, want ErrPreconditionFailed", err)
+		}
+	})
+
+	t.Run("read-only stores reject writes with ErrReadOnly", func(t *testing.T) {
+		record := s.NewRecord(Authors)
+		if err := record.SetValue(name, "Ada"); err != nil {
+			t.Fatal(err)
+		}
+		if err := record.Save(jpack.WithReadOnly(s.Context)); !errors.Is(err, jpack.ErrReadOnly) {
+			t.Errorf("err = %v, want ErrReadOnly", err)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package jpacktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kabi175/jpack"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// TestMongoBackend runs the suite against a local MongoDB, skipping it when
+// none is reachable.
+func TestMongoBackend(t *testing.T) {
+	client, err := mongo.Connect(options.Client().
+		ApplyURI("mongodb://localhost:27017").
+		SetServerSelectionTimeout(time.Second))
+	if err != nil {
+		t.Skipf("mongodb unavailable: %v", err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	if err := client.Ping(context.Background(), nil); err != nil {
+		t.Skipf("mongodb unavailable: %v", err)
+	}
+
+	RunBackendSuite(t, func(t *testing.T, schemas ...jpack.JSchema) Backend {
+		db := client.Database("jpacktest")
+		for _, schema := range schemas {
+			if err := db.Collection(schema.Name()).Drop(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return Backend{
+			Context: jpack.WithDatabase(context.Background(), db),
+			NewRecord: func(schema jpack.JSchema) jpack.JRecord {
+				return jpack.NewMongoRecord(schema)
+			},
+		}
+	})
+}
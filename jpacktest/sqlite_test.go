@@ -0,0 +1,36 @@
+package jpacktest
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/kabi175/jpack"
+	_ "modernc.org/sqlite"
+)
+
+// TestSQLiteBackend runs the suite through database/sql on SQLite, with the
+// cgo-free driver of modernc.org/sqlite.
+func TestSQLiteBackend(t *testing.T) {
+	RunBackendSuite(t, func(t *testing.T, schemas ...jpack.JSchema) Backend {
+		path := filepath.Join(t.TempDir(), "jpack.db")
+		db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		store := jpack.NewSQLiteStore(db)
+		ctx := jpack.WithSQLite(context.Background(), store)
+		if err := store.Migrate(ctx, schemas...); err != nil {
+			t.Fatal(err)
+		}
+		return Backend{
+			Context: ctx,
+			NewRecord: func(schema jpack.JSchema) jpack.JRecord {
+				return jpack.NewSQLiteRecord(schema)
+			},
+		}
+	})
+}
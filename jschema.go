@@ -2,6 +2,7 @@ package jpack
 
 import (
 	"context"
+	"slices"
 )
 
 type JFieldType interface {
@@ -44,10 +45,37 @@ type JSchema interface {
 	AddEdge(edge JEdge) JSchema
 
 	Validate(JRecord) error
+
+	// NewRecord creates a record of the schema stored by the backend the
+	// context selects, with its defaults applied.
+	NewRecord(ctx context.Context) (JRecord, error)
+}
+
+// VersionedSchema is implemented by schemas that count their changes, so
+// caches derived from a schema can tell when it changed.
+type VersionedSchema interface {
+	JSchema
+
+	// Version is bumped on every change of the fields or edges.
+	Version() uint64
+}
+
+// SchemaVersion returns the version of a schema, or 0 when it isn't
+// versioned.
+func SchemaVersion(schema JSchema) uint64 {
+	if s, ok := schema.(VersionedSchema); ok {
+		return s.Version()
+	}
+	return 0
 }
 
 type JPolicy interface {
+	// IsValid checks a record before it is written.
 	IsValid(ctx context.Context, record JRecord) error
+
+	// QueryFilter returns a filter that every query on the schema must match,
+	// e.g. restricting rows to the current tenant. It may return nil.
+	QueryFilter(ctx context.Context, schema JSchema) Filter
 }
 
 type SchemaBuilder struct {
@@ -70,24 +98,30 @@ func (s *SchemaBuilder) appendFieldIfNotPresent(field JField) {
 	s.fields = append(s.fields, field)
 }
 
-func (s *SchemaBuilder) FieldWithDefault(name string, fType JFieldType, defaultValue any) *SchemaBuilder {
+// FieldWithDefault adds a field whose value is filled in when a new record is
+// saved without it. It is a shorthand for Field with the Default option.
+func (s *SchemaBuilder) FieldWithDefault(name string, fType JFieldType, defaultValue any, opts ...FieldOption) *SchemaBuilder {
+	return s.Field(name, fType, append([]FieldOption{Default(defaultValue)}, opts...)...)
+}
 
+// Field adds a field configured by options such as Required, Unique,
+// Immutable, Default or Meta.
+func (s *SchemaBuilder) Field(name string, fType JFieldType, opts ...FieldOption) *SchemaBuilder {
 	field := &fieldImpl{
-		name:         name,
-		fType:        fType,
-		schema:       s.schema,
-		defaultValue: defaultValue,
+		name:   name,
+		fType:  fType,
+		schema: s.schema,
+	}
+
+	for _, opt := range opts {
+		opt(field)
 	}
 
 	s.appendFieldIfNotPresent(field)
 	return s
 }
 
-func (s *SchemaBuilder) Field(name string, fType JFieldType) *SchemaBuilder {
-	return s.FieldWithDefault(name, fType, nil)
-}
-
-func (s *SchemaBuilder) Ref(name string, schema JSchema) *SchemaBuilder {
+func (s *SchemaBuilder) Ref(name string, schema JSchema, opts ...FieldOption) *SchemaBuilder {
 	field := &refImpl{
 		fieldImpl: fieldImpl{
 			name:   name,
@@ -97,6 +131,10 @@ func (s *SchemaBuilder) Ref(name string, schema JSchema) *SchemaBuilder {
 		relSchema: schema,
 	}
 
+	for _, opt := range opts {
+		opt(&field.fieldImpl)
+	}
+
 	s.appendFieldIfNotPresent(field)
 	return s
 }
@@ -119,11 +157,51 @@ func (s *SchemaBuilder) Edge(name string, schema JSchema, ref JRef) *SchemaBuild
 	return s
 }
 
+// ConversionPolicy overrides the package-wide conversion policy for this schema.
+func (s *SchemaBuilder) ConversionPolicy(policy ConversionPolicy) *SchemaBuilder {
+	s.schema.conversionPolicy = &policy
+	return s
+}
+
+// Policy attaches an access policy to the schema. Policies validate records on
+// Save and add their query filters to every query.
+func (s *SchemaBuilder) Policy(policy JPolicy) *SchemaBuilder {
+	s.schema.policies = append(s.schema.policies, policy)
+	return s
+}
+
+// Serializer registers a hook that transforms documents when they are written
+// to or read from storage. Hooks run in registration order on write and in
+// reverse order on read.
+func (s *SchemaBuilder) Serializer(serializer RecordSerializer) *SchemaBuilder {
+	s.schema.serializers = append(s.schema.serializers, serializer)
+	return s
+}
+
+// Build returns the schema. It panics with a *FrozenSchemaError when the
+// schema was frozen; see TryBuild.
 func (s *SchemaBuilder) Build() JSchema {
-	s.schema.fields = s.fields
-	s.schema.edges = s.edges
+	schema, err := s.TryBuild()
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// TryBuild returns the schema, or a *FrozenSchemaError when the schema was
+// frozen and the builder can't change it anymore.
+func (s *SchemaBuilder) TryBuild() (JSchema, error) {
+	s.schema.mu.Lock()
+	defer s.schema.mu.Unlock()
+
+	if err := s.schema.checkMutable("rebuild"); err != nil {
+		return nil, err
+	}
+	s.schema.fields = slices.Clip(s.fields)
+	s.schema.edges = slices.Clip(s.edges)
+	s.schema.version++
 
-	return s.schema
+	return s.schema, nil
 }
 
 func NewSchema(name string) *SchemaBuilder {
@@ -0,0 +1,128 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+)
+
+// UnknownKeyPolicy decides what happens to JSON keys that don't match a schema field.
+type UnknownKeyPolicy int
+
+const (
+	// RejectUnknownKeys fails the whole update when a key is not a schema field.
+	RejectUnknownKeys UnknownKeyPolicy = iota
+
+	// IgnoreUnknownKeys silently drops keys that are not schema fields.
+	IgnoreUnknownKeys
+)
+
+// SetValuesFromJSON implements JRecord.
+func (m *mongoRecord) SetValuesFromJSON(ctx context.Context, data []byte, policy UnknownKeyPolicy) error {
+	values, err := decodeJSONObject(data)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Apply every key against a snapshot so a failing key leaves the record untouched
+	snapshot := maps.Clone(m.record)
+
+	// Observers only hear about the changes once every key was applied
+	type change struct {
+		field    JField
+		old, new any
+	}
+	var changes []change
+	observers := m.observers
+	if len(observers) > 0 {
+		m.observers = []func(JField, any, any){func(field JField, old, new any) {
+			changes = append(changes, change{field, old, new})
+		}}
+	}
+
+	var errs []error
+	for _, key := range keys {
+		field, ok := m.Schema().Field(key)
+		if !ok {
+			if policy == RejectUnknownKeys {
+				errs = append(errs, fmt.Errorf("%s: unknown field", key))
+			}
+			continue
+		}
+
+		if err := m.SetValue(field, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	m.observers = observers
+	if len(errs) > 0 {
+		m.record = snapshot
+		return errors.Join(errs...)
+	}
+
+	for _, c := range changes {
+		for _, fn := range observers {
+			fn(c.field, c.old, c.new)
+		}
+	}
+	return nil
+}
+
+// decodeJSONObject decodes a JSON object, keeping integers exact instead of
+// turning every number into a float64.
+func decodeJSONObject(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return nil, errors.Join(errors.New("invalid JSON object"), err)
+	}
+
+	if values == nil {
+		return nil, errors.New("invalid JSON object")
+	}
+
+	for key, value := range values {
+		values[key] = normalizeJSONValue(value)
+	}
+
+	return values, nil
+}
+
+// normalizeJSONValue converts json.Number values to int64 or float64.
+func normalizeJSONValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeJSONValue(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeJSONValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
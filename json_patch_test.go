@@ -0,0 +1,71 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetValuesFromJSON(t *testing.T) {
+	ctx := context.Background()
+	schema := NewSchema("test_patch").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("age", &Number{}).
+		Field("active", &Boolean{}).
+		Build()
+
+	loaded := func() *mongoRecord {
+		record := NewMongoRecord(schema)
+		record.originalRecord = map[string]any{"id": "1", "name": "John", "age": int64(30), "active": true}
+		return record
+	}
+
+	t.Run("only provided keys become dirty", func(t *testing.T) {
+		record := loaded()
+		err := record.SetValuesFromJSON(ctx, []byte(`{"age": 31, "name": "John"}`), RejectUnknownKeys)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"age"}, record.DirtyKeys())
+
+		age, _ := record.Value(mustField(t, schema, "age"))
+		assert.Equal(t, int64(31), age)
+	})
+
+	t.Run("null clears a field", func(t *testing.T) {
+		record := loaded()
+		assert.NoError(t, record.SetValuesFromJSON(ctx, []byte(`{"age": null}`), RejectUnknownKeys))
+
+		age, ok := record.Value(mustField(t, schema, "age"))
+		assert.True(t, ok)
+		assert.Nil(t, age)
+	})
+
+	t.Run("unknown keys are rejected", func(t *testing.T) {
+		record := loaded()
+		err := record.SetValuesFromJSON(ctx, []byte(`{"age": 31, "role": "admin"}`), RejectUnknownKeys)
+		assert.ErrorContains(t, err, "role: unknown field")
+		assert.Empty(t, record.DirtyKeys(), "a failed patch must not change the record")
+	})
+
+	t.Run("unknown keys are ignored", func(t *testing.T) {
+		record := loaded()
+		err := record.SetValuesFromJSON(ctx, []byte(`{"age": 31, "role": "admin"}`), IgnoreUnknownKeys)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"age"}, record.DirtyKeys())
+	})
+
+	t.Run("validation errors are reported per field", func(t *testing.T) {
+		record := loaded()
+		err := record.SetValuesFromJSON(ctx, []byte(`{"age": 1.5, "active": "maybe", "name": "Jane"}`), RejectUnknownKeys)
+		assert.ErrorContains(t, err, "age:")
+		assert.ErrorContains(t, err, "active:")
+		assert.Empty(t, record.DirtyKeys())
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		record := loaded()
+		assert.Error(t, record.SetValuesFromJSON(ctx, []byte(`[1, 2]`), RejectUnknownKeys))
+		assert.Error(t, record.SetValuesFromJSON(ctx, []byte(`null`), RejectUnknownKeys))
+	})
+}
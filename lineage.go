@@ -0,0 +1,183 @@
+package jpack
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// lineageKey holds the Lineage of each field of a document written to a
+// store with lineage enabled. It is hidden from records like treeIDKey.
+const lineageKey = "_lineage"
+
+var (
+	// requestIDKey is the context key holding the id set with WithRequestID.
+	requestIDKey key = "jpack.requestid"
+	// actorKey is the context key holding the actor set with WithActor.
+	actorKey key = "jpack.actor"
+)
+
+// WithRequestID returns a context whose writes are stamped with the request
+// id in their lineage.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestIDFrom returns the request id of the context, if any.
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok && id != ""
+}
+
+// WithActor returns a context whose writes are stamped with the actor, e.g.
+// the authenticated user, in their lineage.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFrom returns the actor of the context, if any.
+func ActorFrom(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey).(string)
+	return actor, ok && actor != ""
+}
+
+// Lineage is what last wrote a field of a stored document.
+type Lineage struct {
+	Service   string          `bson:"service,omitempty"`
+	RequestID string          `bson:"request_id,omitempty"`
+	Actor     string          `bson:"actor,omitempty"`
+	Operation ChangeOperation `bson:"operation"`
+	At        time.Time       `bson:"at"`
+}
+
+// LineageOptions configures the lineage stamped on writes to a store.
+type LineageOptions struct {
+	// Service names the writing service, e.g. "billing-api".
+	Service string
+}
+
+var (
+	lineageMu     sync.RWMutex
+	lineageStores = map[string]LineageOptions{}
+)
+
+// EnableLineage stamps every Save to the named store with the Lineage of the
+// fields it writes, in a reserved sub-document of the stored document.
+// Schemas without a store of their own belong to DefaultStore. Deletes and
+// bulk maintenance such as Cleanup or Anonymize aren't stamped, and SQLite
+// stores don't keep lineage.
+func EnableLineage(store string, opts LineageOptions) {
+	lineageMu.Lock()
+	defer lineageMu.Unlock()
+
+	lineageStores[store] = opts
+}
+
+// DisableLineage stops stamping writes to the named store. Stamped lineage
+// stays in the stored documents.
+func DisableLineage(store string) {
+	lineageMu.Lock()
+	defer lineageMu.Unlock()
+
+	delete(lineageStores, store)
+}
+
+// lineageOptions returns the lineage options of the schema's store.
+func lineageOptions(schema JSchema) (LineageOptions, bool) {
+	store := StoreOf(schema)
+	if store == "" {
+		store = DefaultStore
+	}
+
+	lineageMu.RLock()
+	defer lineageMu.RUnlock()
+
+	opts, ok := lineageStores[store]
+	return opts, ok
+}
+
+// newLineage returns the lineage of a write made within ctx.
+func newLineage(ctx context.Context, opts LineageOptions, op ChangeOperation) Lineage {
+	lineage := Lineage{Service: opts.Service, Operation: op, At: time.Now().UTC().Truncate(time.Millisecond)}
+	lineage.RequestID, _ = RequestIDFrom(ctx)
+	lineage.Actor, _ = ActorFrom(ctx)
+	return lineage
+}
+
+// LineageOf returns what last wrote the field of a stored record, if its
+// store had lineage enabled then.
+func LineageOf(record JRecord, field JField) (Lineage, bool) {
+	m, ok := record.(*mongoRecord)
+	if !ok {
+		return Lineage{}, false
+	}
+	lineage, ok := m.lineage[field.Name()]
+	return lineage, ok
+}
+
+// stampLineage adds the lineage of the named fields, which a write sets or
+// removes, to its document. The write keeps it for the record to take over
+// once written.
+func (m *mongoRecord) stampLineage(ctx context.Context, w *pendingWrite, names []string) {
+	opts, ok := lineageOptions(m.schema)
+	if !ok || len(names) == 0 {
+		return
+	}
+
+	lineage := newLineage(ctx, opts, w.op)
+	w.lineage = make(map[string]Lineage, len(names))
+	for _, name := range names {
+		w.lineage[name] = lineage
+	}
+
+	switch w.operation {
+	case "insert":
+		w.document[lineageKey] = w.lineage
+	case "upsert":
+		setOnInsert, _ := w.document["$setOnInsert"].(bson.M)
+		if setOnInsert == nil {
+			setOnInsert = bson.M{}
+			w.document["$setOnInsert"] = setOnInsert
+		}
+		setOnInsert[lineageKey] = w.lineage
+	default:
+		set, _ := w.document["$set"].(bson.M)
+		if set == nil {
+			set = bson.M{}
+			w.document["$set"] = set
+		}
+		for name, lineage := range w.lineage {
+			set[lineageKey+"."+name] = lineage
+		}
+	}
+}
+
+// loadLineage keeps the lineage of a stored document.
+func (m *mongoRecord) loadLineage(value any) error {
+	data, err := bson.Marshal(bson.M{lineageKey: value})
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Lineage map[string]Lineage `bson:"_lineage"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	m.lineage = doc.Lineage
+	return nil
+}
+
+// keepLineage merges the lineage of a written write into the record.
+func (m *mongoRecord) keepLineage(lineage map[string]Lineage) {
+	if len(lineage) == 0 {
+		return
+	}
+	if m.lineage == nil {
+		m.lineage = make(map[string]Lineage, len(lineage))
+	}
+	maps.Copy(m.lineage, lineage)
+}
@@ -0,0 +1,89 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestLineage(t *testing.T) {
+	firstName, lastName := mustField(t, userSchema, "first_name"), mustField(t, userSchema, "last_name")
+	ctx := WithActor(WithRequestID(offlineContext(t), "req-7"), "ada@example.com")
+
+	t.Run("writes aren't stamped by default", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(firstName, "Ada"))
+		w, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.NotContains(t, w.document, lineageKey)
+	})
+
+	EnableLineage(DefaultStore, LineageOptions{Service: "billing-api"})
+	t.Cleanup(func() { DisableLineage(DefaultStore) })
+
+	t.Run("inserts stamp every written field", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(firstName, "Ada"))
+		assert.NoError(t, record.SetValue(lastName, "Lovelace"))
+		w, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+
+		lineage := w.document[lineageKey].(map[string]Lineage)
+		assert.Len(t, lineage, 2)
+		assert.Equal(t, "billing-api", lineage["first_name"].Service)
+		assert.Equal(t, "req-7", lineage["first_name"].RequestID)
+		assert.Equal(t, "ada@example.com", lineage["first_name"].Actor)
+		assert.Equal(t, ChangeInsert, lineage["last_name"].Operation)
+		assert.WithinDuration(t, time.Now(), lineage["last_name"].At, time.Minute)
+
+		assert.NoError(t, w.finish(ctx))
+		stamped, ok := LineageOf(record, firstName)
+		assert.True(t, ok)
+		assert.Equal(t, lineage["first_name"], stamped)
+	})
+
+	t.Run("updates stamp the changed fields", func(t *testing.T) {
+		record, err := RecordFromBSON(userSchema, bson.M{
+			"_id":        bson.NewObjectID(),
+			"first_name": "Ada",
+			"last_name":  "Lovelace",
+			lineageKey: bson.M{
+				"first_name": bson.M{"service": "import", "operation": "insert", "at": time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, record.UnknownKeys(), "lineage is hidden from records")
+
+		loaded, ok := LineageOf(record, firstName)
+		assert.True(t, ok)
+		assert.Equal(t, Lineage{Service: "import", Operation: ChangeInsert, At: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, loaded)
+		_, ok = LineageOf(record, lastName)
+		assert.False(t, ok)
+
+		assert.NoError(t, record.Unset(lastName))
+		w, err := record.(*mongoRecord).prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		set := w.document["$set"].(bson.M)
+		assert.Equal(t, ChangeUpdate, set[lineageKey+".last_name"].(Lineage).Operation)
+		assert.NotContains(t, set, lineageKey+".first_name")
+	})
+
+	t.Run("lineage is configured per store", func(t *testing.T) {
+		reports := NewSchema("test_lineage_reports").
+			Field("id", &String{}).
+			Field("title", &String{}).
+			Store("test_lineage_reporting").
+			Build()
+		db, _ := DatabaseFrom(offlineContext(t))
+		Open("test_lineage_reporting", db)
+		t.Cleanup(func() { Open("test_lineage_reporting", nil) })
+
+		record := NewMongoRecord(reports)
+		assert.NoError(t, record.SetValue(mustField(t, reports, "title"), "Q1"))
+		w, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.NotContains(t, w.document, lineageKey)
+	})
+}
@@ -0,0 +1,252 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// LoaderKey is the context key holding the request's Loader.
+//
+// Deprecated: use WithLoader and LoaderFrom.
+var LoaderKey key = "jpack.loader"
+
+const (
+	defaultLoaderWait     = time.Millisecond
+	defaultLoaderMaxBatch = 100
+)
+
+// Loader batches and caches record lookups by primary key for a single
+// request, DataLoader style: concurrent Load calls for the same schema made
+// within Wait are coalesced into one $in query, and every id is fetched at
+// most once. It is meant for resolvers that fetch the same refs repeatedly.
+type Loader struct {
+	// Wait is how long a batch collects ids before it is queried.
+	Wait time.Duration
+	// MaxBatch dispatches a batch early once it holds this many ids.
+	MaxBatch int
+
+	ctx   context.Context
+	fetch func(schema JSchema, ids []string) (map[string]JRecord, error)
+
+	mu      sync.Mutex
+	cache   map[string]*loaderBatch
+	pending map[string]*loaderBatch
+}
+
+type loaderBatch struct {
+	schema JSchema
+	ids    []string
+	once   sync.Once
+	done   chan struct{}
+
+	records map[string]JRecord
+	err     error
+}
+
+// NewLoader creates a loader that queries with ctx.
+func NewLoader(ctx context.Context) *Loader {
+	l := &Loader{
+		Wait:     defaultLoaderWait,
+		MaxBatch: defaultLoaderMaxBatch,
+		ctx:      ctx,
+		cache:    make(map[string]*loaderBatch),
+		pending:  make(map[string]*loaderBatch),
+	}
+	l.fetch = l.query
+	return l
+}
+
+// WithLoader returns a context carrying a new Loader bound to it.
+func WithLoader(ctx context.Context) context.Context {
+	loader := NewLoader(ctx)
+	ctx = context.WithValue(ctx, LoaderKey, loader)
+	loader.ctx = ctx
+	return ctx
+}
+
+// LoaderFrom returns the loader stored in the context, if any.
+func LoaderFrom(ctx context.Context) (*Loader, bool) {
+	loader, ok := ctx.Value(LoaderKey).(*Loader)
+	return loader, ok && loader != nil
+}
+
+// Load returns the record of the schema with the given primary key, or nil if
+// it doesn't exist.
+func (l *Loader) Load(schema JSchema, id string) (JRecord, error) {
+	batch := l.enqueue(schema, id)
+
+	select {
+	case <-batch.done:
+	case <-l.ctx.Done():
+		return nil, l.ctx.Err()
+	}
+
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.records[id], nil
+}
+
+// LoadMany loads several records in one batch. Missing records are nil.
+func (l *Loader) LoadMany(schema JSchema, ids ...string) ([]JRecord, error) {
+	batches := make([]*loaderBatch, len(ids))
+	for i, id := range ids {
+		batches[i] = l.enqueue(schema, id)
+	}
+
+	records := make([]JRecord, len(ids))
+	for i, batch := range batches {
+		select {
+		case <-batch.done:
+		case <-l.ctx.Done():
+			return nil, l.ctx.Err()
+		}
+
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		records[i] = batch.records[ids[i]]
+	}
+
+	return records, nil
+}
+
+// LoadRef loads the record a ref field of record points to.
+func (l *Loader) LoadRef(record JRecord, ref JRef) (JRecord, error) {
+	value, ok := record.Value(ref)
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	switch v := value.(type) {
+	case JRecord:
+		return v, nil
+	case string:
+		return l.Load(ref.RelSchema(), v)
+	default:
+		return nil, errors.New("ref value is not a record id")
+	}
+}
+
+// Prime adds an already loaded record to the cache.
+func (l *Loader) Prime(record JRecord) {
+	id, ok := recordID(record)
+	if !ok {
+		return
+	}
+
+	batch := &loaderBatch{
+		schema:  record.Schema(),
+		ids:     []string{id},
+		done:    make(chan struct{}),
+		records: map[string]JRecord{id: record},
+	}
+	batch.once.Do(func() { close(batch.done) })
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cache[identityKey(record.Schema(), id)] = batch
+}
+
+// Clear drops every cached record, e.g. after writes that change them.
+func (l *Loader) Clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.cache)
+}
+
+// enqueue returns the batch that will resolve id, starting a new one if needed.
+func (l *Loader) enqueue(schema JSchema, id string) *loaderBatch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := identityKey(schema, id)
+	if batch, ok := l.cache[key]; ok {
+		return batch
+	}
+
+	batch, ok := l.pending[schema.Name()]
+	if !ok {
+		batch = &loaderBatch{
+			schema: schema,
+			done:   make(chan struct{}),
+		}
+		l.pending[schema.Name()] = batch
+		time.AfterFunc(l.Wait, func() { l.dispatch(batch) })
+	}
+
+	batch.ids = append(batch.ids, id)
+	l.cache[key] = batch
+
+	if l.MaxBatch > 0 && len(batch.ids) >= l.MaxBatch {
+		delete(l.pending, schema.Name())
+		go l.dispatch(batch)
+	}
+
+	return batch
+}
+
+// dispatch runs the query for a batch once.
+func (l *Loader) dispatch(batch *loaderBatch) {
+	batch.once.Do(func() {
+		l.mu.Lock()
+		if l.pending[batch.schema.Name()] == batch {
+			delete(l.pending, batch.schema.Name())
+		}
+		ids := append([]string{}, batch.ids...)
+		l.mu.Unlock()
+
+		batch.records, batch.err = l.fetch(batch.schema, ids)
+
+		// Failed lookups are not cached so they can be retried
+		if batch.err != nil {
+			l.mu.Lock()
+			for _, id := range ids {
+				if l.cache[identityKey(batch.schema, id)] == batch {
+					delete(l.cache, identityKey(batch.schema, id))
+				}
+			}
+			l.mu.Unlock()
+		}
+
+		close(batch.done)
+	})
+}
+
+// query loads the records with the given ids in a single $in query.
+func (l *Loader) query(schema JSchema, ids []string) (map[string]JRecord, error) {
+	q := NewMongoQuery(l.ctx, schema).(*mongoQuery)
+	q.where = append(q.where, idsFilter(ids))
+
+	records, err := q.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]JRecord, len(records))
+	for _, record := range records {
+		if id, ok := recordID(record); ok {
+			result[id] = record
+		}
+	}
+	return result, nil
+}
+
+// idsFilter matches the documents with any of the primary keys.
+func idsFilter(ids []string) bson.M {
+	values := make([]any, 0, len(ids))
+	for _, id := range ids {
+		if objID, err := bson.ObjectIDFromHex(id); err == nil {
+			values = append(values, objID)
+		} else {
+			values = append(values, id)
+		}
+	}
+	return bson.M{defaultMongoPK: bson.M{"$in": values}}
+}
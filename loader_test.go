@@ -0,0 +1,149 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// fakeLoader returns a loader whose lookups are served from records and
+// recorded in calls.
+func fakeLoader(ctx context.Context, records map[string]JRecord) (*Loader, *[][]string) {
+	var mu sync.Mutex
+	var calls [][]string
+
+	loader := NewLoader(ctx)
+	loader.fetch = func(schema JSchema, ids []string) (map[string]JRecord, error) {
+		mu.Lock()
+		calls = append(calls, ids)
+		mu.Unlock()
+
+		result := map[string]JRecord{}
+		for _, id := range ids {
+			if record, ok := records[id]; ok {
+				result[id] = record
+			}
+		}
+		return result, nil
+	}
+	return loader, &calls
+}
+
+func loadedUser(t *testing.T) (string, JRecord) {
+	t.Helper()
+	id := bson.NewObjectID()
+	record := NewMongoRecord(userSchema)
+	assert.NoError(t, record.loadDocument(context.Background(), bson.M{"_id": id}))
+	return id.Hex(), record
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("concurrent loads are batched and cached", func(t *testing.T) {
+		id1, user1 := loadedUser(t)
+		id2, user2 := loadedUser(t)
+		loader, calls := fakeLoader(ctx, map[string]JRecord{id1: user1, id2: user2})
+
+		var wg sync.WaitGroup
+		results := make([]JRecord, 4)
+		for i, id := range []string{id1, id2, id1, "missing"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				record, err := loader.Load(userSchema, id)
+				assert.NoError(t, err)
+				results[i] = record
+			}()
+		}
+		wg.Wait()
+
+		assert.Same(t, user1, results[0])
+		assert.Same(t, user2, results[1])
+		assert.Same(t, user1, results[2])
+		assert.Nil(t, results[3])
+		assert.Len(t, *calls, 1)
+		assert.ElementsMatch(t, []string{id1, id2, "missing"}, (*calls)[0])
+
+		_, err := loader.Load(userSchema, id1)
+		assert.NoError(t, err)
+		assert.Len(t, *calls, 1, "cached ids are not fetched again")
+	})
+
+	t.Run("LoadMany keeps order", func(t *testing.T) {
+		id1, user1 := loadedUser(t)
+		id2, user2 := loadedUser(t)
+		loader, calls := fakeLoader(ctx, map[string]JRecord{id1: user1, id2: user2})
+
+		records, err := loader.LoadMany(userSchema, id2, "missing", id1)
+		assert.NoError(t, err)
+		assert.Equal(t, []JRecord{user2, nil, user1}, records)
+		assert.Len(t, *calls, 1)
+	})
+
+	t.Run("MaxBatch dispatches early", func(t *testing.T) {
+		loader, calls := fakeLoader(ctx, nil)
+		loader.MaxBatch = 2
+
+		_, err := loader.LoadMany(userSchema, "a", "b", "c")
+		assert.NoError(t, err)
+		assert.Len(t, *calls, 2)
+	})
+
+	t.Run("primed records skip the query", func(t *testing.T) {
+		id, user := loadedUser(t)
+		loader, calls := fakeLoader(ctx, nil)
+		loader.Prime(user)
+
+		record, err := loader.Load(userSchema, id)
+		assert.NoError(t, err)
+		assert.Same(t, user, record)
+		assert.Empty(t, *calls)
+	})
+
+	t.Run("errors are not cached", func(t *testing.T) {
+		loader := NewLoader(ctx)
+		fail := true
+		loader.fetch = func(schema JSchema, ids []string) (map[string]JRecord, error) {
+			if fail {
+				return nil, errors.New("boom")
+			}
+			return map[string]JRecord{}, nil
+		}
+
+		_, err := loader.Load(userSchema, "a")
+		assert.EqualError(t, err, "boom")
+
+		fail = false
+		_, err = loader.Load(userSchema, "a")
+		assert.NoError(t, err)
+	})
+
+	t.Run("LoadRef", func(t *testing.T) {
+		id, user := loadedUser(t)
+		loader, _ := fakeLoader(ctx, map[string]JRecord{id: user})
+
+		postSchema := NewSchema("test_loader_post").Field("id", &String{}).Ref("author", userSchema).Build()
+		author := mustField(t, postSchema, "author").(JRef)
+
+		post := NewMongoRecord(postSchema)
+		post.originalRecord = map[string]any{"id": "p1", "author": id}
+
+		record, err := loader.LoadRef(post, author)
+		assert.NoError(t, err)
+		assert.Same(t, user, record)
+	})
+
+	t.Run("context accessors", func(t *testing.T) {
+		loader, ok := LoaderFrom(WithLoader(ctx))
+		assert.True(t, ok)
+		assert.NotNil(t, loader)
+
+		_, ok = LoaderFrom(ctx)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,168 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const locksCollection = "jpack_locks"
+
+var (
+	// ErrLockHeld is returned by AcquireLock while another owner holds the lock.
+	ErrLockHeld = errors.New("jpack: lock is held by another owner")
+	// ErrLockLost is returned when renewing or releasing a lock that expired
+	// and may have been acquired by another owner.
+	ErrLockLost = errors.New("jpack: lock expired")
+)
+
+// Lock is a lease on a key, held until it is released or its TTL expires.
+type Lock struct {
+	Schema    string
+	Key       string
+	ExpiresAt time.Time
+
+	schema JSchema
+	token  string
+}
+
+func lockID(schema, key string) string {
+	return schema + ":" + key
+}
+
+// lockCollection returns the locks collection of the database holding the
+// schema's records.
+func lockCollection(ctx context.Context, schema JSchema) *mongo.Collection {
+	return mustDatabaseFor(ctx, schema).Collection(locksCollection)
+}
+
+// AcquireLock takes the lock on key within the schema's namespace, e.g. a
+// record id, for ttl. It fails with ErrLockHeld while another owner holds an
+// unexpired lock. Locks live in the jpack_locks collection of the database
+// holding the schema's records and are taken with an atomic upsert, so they
+// work across processes.
+func AcquireLock(ctx context.Context, schema JSchema, key string, ttl time.Duration) (*Lock, error) {
+	now := time.Now().UTC()
+	lock := &Lock{
+		Schema:    schema.Name(),
+		Key:       key,
+		ExpiresAt: now.Add(ttl),
+		schema:    schema,
+		token:     bson.NewObjectID().Hex(),
+	}
+
+	// Only an expired lock matches the filter; a held one makes the upsert
+	// insert a second document with the same _id, which fails.
+	_, err := lockCollection(ctx, schema).UpdateOne(ctx,
+		bson.M{defaultMongoPK: lockID(lock.Schema, key), "expires_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{
+			"schema":     lock.Schema,
+			"key":        key,
+			"token":      lock.token,
+			"expires_at": lock.ExpiresAt,
+		}},
+		options.UpdateOne().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrLockHeld
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// Renew extends a held lock to ttl from now.
+func (l *Lock) Renew(ctx context.Context, ttl time.Duration) error {
+	now := time.Now().UTC()
+	expiresAt := now.Add(ttl)
+
+	res, err := lockCollection(ctx, l.schema).UpdateOne(ctx,
+		bson.M{defaultMongoPK: lockID(l.Schema, l.Key), "token": l.token, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrLockLost
+	}
+
+	l.ExpiresAt = expiresAt
+	return nil
+}
+
+// Release gives up the lock. Releasing a lock that was taken over after it
+// expired returns ErrLockLost and leaves the new owner's lock alone.
+func (l *Lock) Release(ctx context.Context) error {
+	res, err := lockCollection(ctx, l.schema).DeleteOne(ctx,
+		bson.M{defaultMongoPK: lockID(l.Schema, l.Key), "token": l.token},
+	)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// WithLock runs fn while holding the lock on key, renewing it every third of
+// ttl. fn's context is cancelled if the lock is lost. The lock is released
+// when fn returns.
+func WithLock(ctx context.Context, schema JSchema, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := AcquireLock(ctx, schema, key, ttl)
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		renewLock(runCtx, lock, ttl, func(err error) { cancel(err) })
+	}()
+
+	fnErr := fn(runCtx)
+	cancel(nil)
+	<-renewed
+
+	lost := context.Cause(runCtx)
+	if errors.Is(lost, ErrLockLost) {
+		return errors.Join(fnErr, lost)
+	}
+
+	releaseErr := lock.Release(context.WithoutCancel(ctx))
+	return errors.Join(fnErr, releaseErr)
+}
+
+// renewLock renews the lock until ctx is done, reporting a failed renewal to
+// lost.
+func renewLock(ctx context.Context, lock *Lock, ttl time.Duration, lost func(error)) {
+	ticker := time.NewTicker(max(ttl/3, time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := lock.Renew(ctx, ttl); err != nil {
+			if ctx.Err() == nil {
+				if !errors.Is(err, ErrLockLost) {
+					err = errors.Join(ErrLockLost, err)
+				}
+				lost(err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestMongoLock(t *testing.T) {
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017"))
+	require.NoError(t, err, "Failed to connect to MongoDB")
+	defer client.Disconnect(context.TODO())
+
+	db := client.Database("jpack_test")
+	db.Collection(locksCollection).Drop(context.TODO())
+	ctx := WithDatabase(context.Background(), db)
+	schema := NewSchema("test_locks").Field("id", &String{}).Build()
+
+	t.Run("acquire, renew and release", func(t *testing.T) {
+		assert := assert.New(t)
+
+		lock, err := AcquireLock(ctx, schema, "order-1", time.Minute)
+		require.NoError(t, err)
+
+		_, err = AcquireLock(ctx, schema, "order-1", time.Minute)
+		assert.ErrorIs(err, ErrLockHeld)
+
+		other, err := AcquireLock(ctx, schema, "order-2", time.Minute)
+		require.NoError(t, err)
+		assert.NoError(other.Release(ctx))
+
+		assert.NoError(lock.Renew(ctx, time.Minute))
+		assert.NoError(lock.Release(ctx))
+		assert.ErrorIs(lock.Release(ctx), ErrLockLost)
+	})
+
+	t.Run("expired locks are taken over", func(t *testing.T) {
+		assert := assert.New(t)
+
+		stale, err := AcquireLock(ctx, schema, "cron", time.Millisecond)
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+
+		fresh, err := AcquireLock(ctx, schema, "cron", time.Minute)
+		require.NoError(t, err)
+		assert.ErrorIs(stale.Renew(ctx, time.Minute), ErrLockLost)
+		assert.ErrorIs(stale.Release(ctx), ErrLockLost)
+		assert.NoError(fresh.Release(ctx))
+	})
+
+	t.Run("WithLock", func(t *testing.T) {
+		assert := assert.New(t)
+
+		failure := errors.New("failed")
+		err := WithLock(ctx, schema, "job", 30*time.Millisecond, func(ctx context.Context) error {
+			_, err := AcquireLock(ctx, schema, "job", time.Minute)
+			assert.ErrorIs(err, ErrLockHeld)
+
+			// Outlives the TTL through renewals
+			time.Sleep(60 * time.Millisecond)
+			return failure
+		})
+		assert.ErrorIs(err, failure)
+
+		lock, err := AcquireLock(ctx, schema, "job", time.Minute)
+		require.NoError(t, err, "WithLock releases the lock")
+		assert.NoError(lock.Release(ctx))
+	})
+}
+
+func TestLockCollection(t *testing.T) {
+	ctx := offlineContext(t)
+	analytics := MustConn(ctx).Client().Database("jpack_test_analytics")
+	Open("locks", analytics)
+	t.Cleanup(func() { Open("locks", nil) })
+
+	bound := NewSchema("test_lock_bound").Field("id", &String{}).Store("locks").Build()
+	unbound := NewSchema("test_lock_unbound").Field("id", &String{}).Build()
+
+	assert.Equal(t, "jpack_test_analytics", lockCollection(ctx, bound).Database().Name())
+	assert.Equal(t, "jpack_test_analytics", lockCollection(context.Background(), bound).Database().Name())
+	assert.Equal(t, "jpack_test", lockCollection(ctx, unbound).Database().Name())
+	assert.Equal(t, locksCollection, lockCollection(ctx, bound).Name())
+}
@@ -0,0 +1,227 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const defaultRefreshInterval = time.Minute
+
+// RefreshStrategy is when a MaterializedView is refreshed.
+type RefreshStrategy int
+
+const (
+	// RefreshManual only refreshes on RefreshMaterializedView.
+	RefreshManual RefreshStrategy = iota
+	// RefreshOnWrite refreshes after every Save and Delete of a source
+	// record. Aggregations run on every write, so keep it for cheap views.
+	RefreshOnWrite
+	// RefreshScheduled refreshes every Interval while RunMaterializedViews
+	// runs.
+	RefreshScheduled
+)
+
+// MaterializedView keeps the results of an expensive aggregation over a
+// source schema in the collection of a target schema, e.g. for dashboards.
+// The target's records are queried like any other; jpack owns its documents.
+type MaterializedView struct {
+	// Target is the schema the results are stored as. Its primary key is
+	// matched with the _id of the results.
+	Target JSchema
+	// Source is the schema aggregated.
+	Source JSchema
+	// Where optionally narrows the source records aggregated.
+	Where Filter
+	// Pipeline aggregates the source records into target documents.
+	Pipeline []bson.M
+	Strategy RefreshStrategy
+	// Interval is the wait between scheduled refreshes, defaults to a minute.
+	Interval time.Duration
+	// Replace swaps the target's documents for the results with $out.
+	// Otherwise the results are merged into them with $merge, keeping target
+	// documents the pipeline didn't produce, e.g. for incremental pipelines.
+	Replace bool
+}
+
+// Stages returns the aggregation run on the source's collection to refresh
+// the view: the Where filter, the Pipeline and the stage writing the target.
+func (v MaterializedView) Stages(ctx context.Context) ([]bson.M, error) {
+	var stages []bson.M
+	if v.Where != nil {
+		if err := resolveSubqueries(v.Where); err != nil {
+			return nil, err
+		}
+		stages = append(stages, bson.M{"$match": ResolveFilter(v.Where)})
+	}
+	stages = append(stages, v.Pipeline...)
+
+	db := mustDatabaseFor(ctx, v.Target)
+	into := bson.M{"db": db.Name(), "coll": collectionName(ctx, v.Target)}
+	if v.Replace {
+		stages = append(stages, bson.M{"$out": into})
+	} else {
+		stages = append(stages, bson.M{"$merge": bson.M{"into": into, "on": defaultMongoPK, "whenMatched": "replace", "whenNotMatched": "insert"}})
+	}
+	return stages, nil
+}
+
+// Refresh runs the view's aggregation and invalidates the cached queries of
+// the target.
+func (v MaterializedView) Refresh(ctx context.Context) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+
+	stages, err := v.Stages(ctx)
+	if err != nil {
+		return err
+	}
+
+	coll := collection(ctx, v.Source)
+	start := time.Now()
+	cursor, err := coll.Aggregate(ctx, stages)
+	if err == nil {
+		// $merge and $out return no documents
+		err = cursor.Close(ctx)
+	}
+	tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "aggregate", Options: bson.M{"pipeline": stages}, Err: err}, start)
+	if err != nil {
+		return fmt.Errorf("jpack: refresh of %s: %w", v.Target.Name(), err)
+	}
+
+	InvalidateTags(v.Target.Name())
+	return nil
+}
+
+var (
+	materializedViewsMu sync.RWMutex
+	materializedViews   = make(map[string]MaterializedView)
+)
+
+// RegisterMaterializedView registers a view under its target schema,
+// replacing any previous one.
+func RegisterMaterializedView(view MaterializedView) error {
+	if view.Target == nil || view.Source == nil {
+		return errors.New("jpack: a materialized view needs a target and a source schema")
+	}
+	if _, ok := view.Target.(*ViewSchema); ok {
+		return fmt.Errorf("jpack: materialized view %s: %w", view.Target.Name(), ErrViewReadOnly)
+	}
+	if view.Target.Name() == view.Source.Name() {
+		return fmt.Errorf("jpack: materialized view %s can't aggregate itself", view.Target.Name())
+	}
+
+	materializedViewsMu.Lock()
+	defer materializedViewsMu.Unlock()
+
+	materializedViews[view.Target.Name()] = view
+	return nil
+}
+
+// UnregisterMaterializedView stops managing the view of the target schema.
+// Its documents are kept.
+func UnregisterMaterializedView(target JSchema) {
+	materializedViewsMu.Lock()
+	defer materializedViewsMu.Unlock()
+
+	delete(materializedViews, target.Name())
+}
+
+// GetMaterializedView retrieves the view registered for a target schema.
+func GetMaterializedView(target JSchema) (MaterializedView, bool) {
+	materializedViewsMu.RLock()
+	defer materializedViewsMu.RUnlock()
+
+	view, ok := materializedViews[target.Name()]
+	return view, ok
+}
+
+// RefreshMaterializedView refreshes the view registered for a target schema.
+func RefreshMaterializedView(ctx context.Context, target JSchema) error {
+	view, ok := GetMaterializedView(target)
+	if !ok {
+		return fmt.Errorf("jpack: %s is not a materialized view", target.Name())
+	}
+	return view.Refresh(ctx)
+}
+
+// materializedViewsWith returns the registered views with the strategy,
+// of any source when source is nil.
+func materializedViewsWith(strategy RefreshStrategy, source JSchema) []MaterializedView {
+	materializedViewsMu.RLock()
+	defer materializedViewsMu.RUnlock()
+
+	var views []MaterializedView
+	for _, view := range materializedViews {
+		if view.Strategy == strategy && (source == nil || view.Source.Name() == source.Name()) {
+			views = append(views, view)
+		}
+	}
+	return views
+}
+
+// refreshOnWrite refreshes the views refreshed on writes to the schema. The
+// write already succeeded, so failures are logged rather than returned.
+// MongoDB doesn't run $merge or $out in a transaction, and the views must
+// only see committed data, so writes of a transaction refresh once it
+// committed.
+func refreshOnWrite(ctx context.Context, schema JSchema) {
+	for _, view := range materializedViewsWith(RefreshOnWrite, storageSchema(schema)) {
+		refreshed := afterCommit(ctx, func(ctx context.Context) {
+			if err := view.Refresh(ctx); err != nil {
+				LoggerFrom(ctx).Error().Err(err).Str("schema", view.Target.Name()).Msg("jpack: failed to refresh materialized view")
+			}
+		})
+		if !refreshed {
+			LoggerFrom(ctx).Error().Str("schema", view.Target.Name()).Msg("jpack: materialized views aren't refreshed for writes in a transaction not run by a UnitOfWork")
+		}
+	}
+}
+
+// RunMaterializedViews refreshes the views registered with RefreshScheduled,
+// each every Interval, until ctx is done. Views registered later are picked
+// up on their next check. Failed refreshes are logged and retried on the
+// next tick.
+func RunMaterializedViews(ctx context.Context) error {
+	due := make(map[string]time.Time)
+	for {
+		now := time.Now()
+		next := now.Add(defaultRefreshInterval)
+		for _, view := range materializedViewsWith(RefreshScheduled, nil) {
+			name := view.Target.Name()
+			if at, ok := due[name]; ok && now.Before(at) {
+				next = minTime(next, at)
+				continue
+			}
+
+			if err := view.Refresh(ctx); err != nil && ctx.Err() == nil {
+				LoggerFrom(ctx).Error().Err(err).Str("schema", name).Msg("jpack: failed to refresh materialized view")
+			}
+			interval := view.Interval
+			if interval <= 0 {
+				interval = defaultRefreshInterval
+			}
+			due[name] = time.Now().Add(interval)
+			next = minTime(next, due[name])
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
+
+// minTime returns the earlier of two instants.
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,66 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMaterializedView(t *testing.T) {
+	orders := NewSchema("test_mv_orders").
+		Field("id", &String{}).
+		Field("status", &String{}).
+		Field("total", &Number{}).
+		Build()
+	revenue := NewSchema("test_mv_revenue").
+		Field("id", &String{}).
+		Field("total", &Number{}).
+		Build()
+	pipeline := []bson.M{{"$group": bson.M{"_id": "$status", "total": bson.M{"$sum": "$total"}}}}
+	view := MaterializedView{Target: revenue, Source: orders, Where: Eq(mustField(t, orders, "status"), "paid"), Pipeline: pipeline}
+
+	t.Run("results are merged into the target", func(t *testing.T) {
+		stages, err := view.Stages(offlineContext(t))
+		assert.NoError(t, err)
+		assert.Equal(t, []bson.M{
+			{"$match": bson.M{"status": "paid"}},
+			pipeline[0],
+			{"$merge": bson.M{"into": bson.M{"db": "jpack_test", "coll": "test_mv_revenue"}, "on": "_id", "whenMatched": "replace", "whenNotMatched": "insert"}},
+		}, stages)
+	})
+
+	t.Run("Replace writes the target with $out", func(t *testing.T) {
+		replace := view
+		replace.Replace, replace.Where = true, nil
+		stages, err := replace.Stages(offlineContext(t))
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$out": bson.M{"db": "jpack_test", "coll": "test_mv_revenue"}}, stages[len(stages)-1])
+		assert.Len(t, stages, 2)
+	})
+
+	t.Run("read-only contexts don't refresh", func(t *testing.T) {
+		assert.ErrorIs(t, view.Refresh(WithReadOnly(offlineContext(t))), ErrReadOnly)
+	})
+
+	t.Run("registration", func(t *testing.T) {
+		assert.Error(t, RegisterMaterializedView(MaterializedView{Source: orders}))
+		assert.ErrorIs(t, RegisterMaterializedView(MaterializedView{Target: NewView("test_mv_view", orders).Build(), Source: orders}), ErrViewReadOnly)
+		assert.ErrorContains(t, RegisterMaterializedView(MaterializedView{Target: orders, Source: orders}), "can't aggregate itself")
+
+		onWrite := view
+		onWrite.Strategy = RefreshOnWrite
+		assert.NoError(t, RegisterMaterializedView(onWrite))
+		defer UnregisterMaterializedView(revenue)
+
+		got, ok := GetMaterializedView(revenue)
+		assert.True(t, ok)
+		assert.Equal(t, RefreshOnWrite, got.Strategy)
+		assert.Len(t, materializedViewsWith(RefreshOnWrite, orders), 1)
+		assert.Empty(t, materializedViewsWith(RefreshOnWrite, revenue))
+		assert.Empty(t, materializedViewsWith(RefreshScheduled, nil))
+
+		assert.ErrorContains(t, RefreshMaterializedView(offlineContext(t), orders), "not a materialized view")
+		assert.ErrorIs(t, RefreshMaterializedView(WithReadOnly(offlineContext(t)), revenue), ErrReadOnly)
+	})
+}
@@ -0,0 +1,255 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// MergeValue is the value of a field in one of the records of a Merge.
+type MergeValue struct {
+	// Value is the scanned value, e.g. time.Time for a DateTime.
+	Value any
+	// Set reports whether the record has the field, see JRecord.IsSet.
+	Set    bool
+	Record JRecord
+}
+
+// MergeRule picks the merged value of a field from the winner's and the
+// loser's. The winner's field is unset when the result isn't Set.
+type MergeRule func(field JField, winner, loser MergeValue) MergeValue
+
+// PreferWinner keeps the winner's value.
+func PreferWinner(field JField, winner, loser MergeValue) MergeValue {
+	return winner
+}
+
+// PreferNonNull keeps the winner's value unless it is missing or nil.
+func PreferNonNull(field JField, winner, loser MergeValue) MergeValue {
+	if (!winner.Set || winner.Value == nil) && loser.Set && loser.Value != nil {
+		return loser
+	}
+	return winner
+}
+
+// NewestBy prefers the non-nil value of the record whose DateTime field
+// named updatedAt is the latest, e.g. "updated_at". Records without it count
+// as the oldest; ties go to the winner.
+func NewestBy(updatedAt string) MergeRule {
+	return func(field JField, winner, loser MergeValue) MergeValue {
+		if newerRecord(updatedAt, loser.Record, winner.Record) {
+			return PreferNonNull(field, loser, winner)
+		}
+		return PreferNonNull(field, winner, loser)
+	}
+}
+
+// newerRecord reports whether a was updated after b.
+func newerRecord(updatedAt string, a, b JRecord) bool {
+	field, ok := a.Schema().Field(updatedAt)
+	if !ok {
+		return false
+	}
+	at, ok := a.Time(field)
+	if !ok {
+		return false
+	}
+	bt, ok := b.Time(field)
+	return !ok || at.After(bt)
+}
+
+// MergeStrategy configures how Merge combines two records.
+type MergeStrategy struct {
+	// Default is the rule of fields without one in Fields, defaults to
+	// PreferNonNull.
+	Default MergeRule
+	// Fields maps field names to their rule.
+	Fields map[string]MergeRule
+	// Archive optionally names a collection of the schema's database the
+	// loser's document is copied to before it is deleted.
+	Archive string
+}
+
+// rule returns the rule of a field.
+func (s MergeStrategy) rule(field JField) MergeRule {
+	if rule, ok := s.Fields[field.Name()]; ok {
+		return rule
+	}
+	if s.Default != nil {
+		return s.Default
+	}
+	return PreferNonNull
+}
+
+// MergeResult is what a Merge changed.
+type MergeResult struct {
+	// Repointed counts the records whose refs were moved from the loser to
+	// the winner, by "schema.field".
+	Repointed map[string]int64
+	Archived  bool
+}
+
+// Merge folds a duplicate record, the loser, into the winner: it sets the
+// winner's fields by the strategy and saves it, repoints the refs of other
+// schemas from the loser to the winner, then archives the loser if asked and
+// deletes it. Referencing schemas are found through the edges of the schema
+// and the refs of registered schemas; their policy filters apply, and their
+// hooks don't run. The primary key and immutable fields keep the winner's
+// values.
+//
+// The steps aren't a transaction, but each can be repeated, so a Merge that
+// failed partway can be retried with the same records.
+func Merge(ctx context.Context, winner, loser JRecord, strategy MergeStrategy) (MergeResult, error) {
+	result := MergeResult{Repointed: make(map[string]int64)}
+	schema := winner.Schema()
+	if IsReadOnly(ctx) {
+		return result, ErrReadOnly
+	}
+	if _, ok := schema.(*ViewSchema); ok {
+		return result, ErrViewReadOnly
+	}
+	if loser.Schema().Name() != schema.Name() {
+		return result, fmt.Errorf("jpack: can't merge a record of %s into one of %s", loser.Schema().Name(), schema.Name())
+	}
+	if backendFor(ctx, schema) != mongoBackend {
+		return result, fmt.Errorf("jpack: merging %s needs a MongoDB database", schema.Name())
+	}
+	winnerID, ok := recordID(winner)
+	if !ok || winner.IsNew() {
+		return result, errors.New("jpack: the winner of a merge must be stored")
+	}
+	loserID, ok := recordID(loser)
+	if !ok || loser.IsNew() {
+		return result, errors.New("jpack: the loser of a merge must be stored")
+	}
+	if winnerID == loserID {
+		return result, errors.New("jpack: can't merge a record into itself")
+	}
+
+	if err := mergeInto(ctx, winner, loser, strategy); err != nil {
+		return result, err
+	}
+	if err := winner.Save(ctx); err != nil {
+		return result, fmt.Errorf("jpack: merge into %s: %w", winnerID, err)
+	}
+
+	for _, ref := range referencingRefs(schema) {
+		res, err := NewUpdate(ctx, ref.Schema()).
+			Where(Eq(ref, loserID)).
+			Set(ref.Name(), winnerID).
+			Execute()
+		if err != nil {
+			return result, fmt.Errorf("jpack: repoint %s.%s: %w", ref.Schema().Name(), ref.Name(), err)
+		}
+		if res.Modified > 0 {
+			result.Repointed[ref.Schema().Name()+"."+ref.Name()] = res.Modified
+		}
+	}
+
+	if strategy.Archive != "" {
+		if err := archiveRecord(ctx, schema, loserID, strategy.Archive); err != nil {
+			return result, fmt.Errorf("jpack: archive %s: %w", loserID, err)
+		}
+		result.Archived = true
+	}
+	if err := loser.Delete(ctx); err != nil {
+		return result, fmt.Errorf("jpack: delete %s: %w", loserID, err)
+	}
+	return result, nil
+}
+
+// mergeInto sets the fields of the winner to their merged values.
+func mergeInto(ctx context.Context, winner, loser JRecord, strategy MergeStrategy) error {
+	pk, _ := PK(winner.Schema())
+	for _, field := range winner.Schema().Fields() {
+		if (pk != nil && field.Name() == pk.Name()) || IsImmutable(field) {
+			continue
+		}
+
+		w, err := mergeValue(ctx, winner, field)
+		if err != nil {
+			return err
+		}
+		l, err := mergeValue(ctx, loser, field)
+		if err != nil {
+			return err
+		}
+
+		merged := strategy.rule(field)(field, w, l)
+		switch {
+		case !merged.Set && w.Set:
+			err = winner.Unset(field)
+		case merged.Set && (!w.Set || !valuesEqual(field, merged.Value, w.Value)):
+			err = winner.SetValue(field, merged.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+	return nil
+}
+
+// mergeValue returns the value of a field of a merged record.
+func mergeValue(ctx context.Context, record JRecord, field JField) (MergeValue, error) {
+	if !record.IsSet(field) {
+		return MergeValue{Record: record}, nil
+	}
+	value, err := record.ScannedValue(ctx, field)
+	if err != nil {
+		return MergeValue{}, fmt.Errorf("%s: %w", field.Name(), err)
+	}
+	return MergeValue{Value: value, Set: true, Record: record}, nil
+}
+
+// referencingRefs returns the refs to the schema, from its edges and the
+// fields of the registered schemas, once each.
+func referencingRefs(schema JSchema) []JRef {
+	var refs []JRef
+	seen := make(map[string]bool)
+	add := func(ref JRef) {
+		key := ref.Schema().Name() + "." + ref.Name()
+		if _, isView := ref.Schema().(*ViewSchema); isView || seen[key] || ref.RelSchema().Name() != schema.Name() {
+			return
+		}
+		seen[key] = true
+		refs = append(refs, ref)
+	}
+
+	for _, edge := range schema.Edge() {
+		add(edge.Ref())
+	}
+	for _, registered := range RegisteredSchemas() {
+		for _, field := range registered.Fields() {
+			if ref, ok := field.(JRef); ok {
+				add(ref)
+			}
+		}
+	}
+	return refs
+}
+
+// archiveRecord copies the stored document of a record to the archive
+// collection of the schema's database.
+func archiveRecord(ctx context.Context, schema JSchema, id, archive string) error {
+	coll := collection(ctx, schema)
+	filter := idsFilter([]string{id})
+
+	start := time.Now()
+	var doc bson.M
+	err := coll.FindOne(ctx, filter).Decode(&doc)
+	tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "findOne", Filter: filter, Count: 1, Err: err}, start)
+	if err != nil {
+		return err
+	}
+
+	// A document archived by an interrupted merge is already there
+	_, err = coll.Database().Collection(archive).InsertOne(ctx, doc)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
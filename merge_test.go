@@ -0,0 +1,111 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMerge(t *testing.T) {
+	contacts := NewSchema("test_merge_contacts").
+		Field("id", &String{}).
+		Field("source", &String{}, Immutable()).
+		Field("name", &String{}).
+		Field("email", &String{}).
+		Field("phone", &String{}).
+		Field("score", &Number{}).
+		Field("updated_at", &DateTime{}).
+		Build()
+	field := func(name string) JField { return mustField(t, contacts, name) }
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	load := func(doc bson.M) JRecord {
+		doc["_id"] = bson.NewObjectID()
+		record, err := RecordFromBSON(contacts, doc)
+		assert.NoError(t, err)
+		return record
+	}
+	records := func() (JRecord, JRecord) {
+		winner := load(bson.M{"source": "crm", "name": "Ada", "email": nil, "score": 3, "updated_at": bson.NewDateTimeFromTime(older)})
+		loser := load(bson.M{"source": "import", "name": "Ada L.", "email": "ada@example.com", "phone": "555", "score": 5, "updated_at": bson.NewDateTimeFromTime(newer)})
+		return winner, loser
+	}
+
+	t.Run("PreferNonNull fills the winner's gaps", func(t *testing.T) {
+		winner, loser := records()
+		assert.NoError(t, mergeInto(t.Context(), winner, loser, MergeStrategy{}))
+
+		name, _ := winner.String(field("name"))
+		email, _ := winner.String(field("email"))
+		phone, _ := winner.String(field("phone"))
+		source, _ := winner.String(field("source"))
+		assert.Equal(t, "Ada", name)
+		assert.Equal(t, "ada@example.com", email)
+		assert.Equal(t, "555", phone)
+		assert.Equal(t, "crm", source, "immutable fields keep the winner's value")
+		assert.ElementsMatch(t, []string{"email", "phone"}, winner.DirtyKeys())
+	})
+
+	t.Run("NewestBy prefers the latest record", func(t *testing.T) {
+		winner, loser := records()
+		assert.NoError(t, mergeInto(t.Context(), winner, loser, MergeStrategy{Default: NewestBy("updated_at")}))
+
+		name, _ := winner.String(field("name"))
+		at, _ := winner.Time(field("updated_at"))
+		assert.Equal(t, "Ada L.", name)
+		assert.True(t, newer.Equal(at))
+	})
+
+	t.Run("per-field rules", func(t *testing.T) {
+		winner, loser := records()
+		sum := func(field JField, w, l MergeValue) MergeValue {
+			a, _ := w.Value.(int)
+			b, _ := l.Value.(int)
+			return MergeValue{Value: a + b, Set: true}
+		}
+		drop := func(field JField, w, l MergeValue) MergeValue { return MergeValue{} }
+		assert.NoError(t, mergeInto(t.Context(), winner, loser, MergeStrategy{
+			Default: PreferWinner,
+			Fields:  map[string]MergeRule{"score": sum, "updated_at": drop},
+		}))
+
+		score, _ := winner.Int(field("score"))
+		email, _ := winner.Value(field("email"))
+		assert.Equal(t, 8, score)
+		assert.Nil(t, email)
+		assert.False(t, winner.IsSet(field("updated_at")))
+	})
+
+	t.Run("refs to the schema are found once", func(t *testing.T) {
+		notes := RegisterSchema(NewSchema("test_merge_notes").Field("id", &String{}).Ref("contact", contacts).Build())
+		deals := NewSchema("test_merge_deals").Field("id", &String{}).Ref("owner", contacts).Build()
+		contacts.AddEdge(NewEdge("notes", notes, mustField(t, notes, "contact").(JRef)))
+		contacts.AddEdge(NewEdge("deals", deals, mustField(t, deals, "owner").(JRef)))
+
+		var names []string
+		for _, ref := range referencingRefs(contacts) {
+			names = append(names, ref.Schema().Name()+"."+ref.Name())
+		}
+		assert.ElementsMatch(t, []string{"test_merge_notes.contact", "test_merge_deals.owner"}, names)
+	})
+
+	t.Run("invalid merges", func(t *testing.T) {
+		ctx := offlineContext(t)
+		winner, loser := records()
+
+		_, err := Merge(WithReadOnly(ctx), winner, loser, MergeStrategy{})
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = Merge(ctx, winner, NewMongoRecord(userSchema), MergeStrategy{})
+		assert.ErrorContains(t, err, "can't merge a record of test_user")
+		_, err = Merge(ctx, winner, winner, MergeStrategy{})
+		assert.ErrorContains(t, err, "into itself")
+		_, err = Merge(ctx, NewMongoRecord(contacts), loser, MergeStrategy{})
+		assert.ErrorContains(t, err, "winner of a merge must be stored")
+		_, err = Merge(context.Background(), winner, loser, MergeStrategy{})
+		assert.ErrorContains(t, err, "needs a MongoDB database")
+	})
+}
@@ -0,0 +1,371 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const migrationsCollection = "jpack_migrations"
+
+// MigrationStep is a single data change. Steps must be idempotent: a
+// migration interrupted halfway is run again from its first step.
+type MigrationStep interface {
+	Description() string
+	Apply(ctx context.Context) error
+}
+
+// Migration is a named list of steps applied once by a Migrator.
+type Migration struct {
+	ID    string
+	Steps []MigrationStep
+
+	// After holds the migration back until the migration with this ID has
+	// been applied for at least Delay, e.g. to drop fields only once every
+	// instance runs the schema that no longer reads them.
+	After string
+	Delay time.Duration
+}
+
+// Migrator applies migrations in order, recording applied ones in the
+// jpack_migrations collection.
+type Migrator struct {
+	Migrations []Migration
+
+	now func() time.Time
+}
+
+// NewMigrator creates a migrator for the migrations.
+func NewMigrator(migrations ...Migration) *Migrator {
+	return &Migrator{Migrations: migrations}
+}
+
+// Add appends migrations.
+func (m *Migrator) Add(migrations ...Migration) *Migrator {
+	m.Migrations = append(m.Migrations, migrations...)
+	return m
+}
+
+// Run applies every due migration that was not applied yet and returns the
+// IDs of the applied ones. Migrations held back by After are skipped and
+// picked up by a later run.
+func (m *Migrator) Run(ctx context.Context) ([]string, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := MustConn(ctx).Collection(migrationsCollection)
+	var ran []string
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+		if !m.due(migration, applied) {
+			continue
+		}
+
+		for i, step := range migration.Steps {
+			if err := step.Apply(ctx); err != nil {
+				return ran, fmt.Errorf("jpack: migration %s step %d (%s): %w", migration.ID, i+1, step.Description(), err)
+			}
+		}
+
+		appliedAt := m.clock().UTC()
+		if _, err := coll.InsertOne(ctx, bson.M{defaultMongoPK: migration.ID, "applied_at": appliedAt}); err != nil {
+			return ran, err
+		}
+		applied[migration.ID] = appliedAt
+		ran = append(ran, migration.ID)
+	}
+	return ran, nil
+}
+
+// Pending returns the migrations that were not applied yet, due or not.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.ID]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) due(migration Migration, applied map[string]time.Time) bool {
+	if migration.After == "" {
+		return true
+	}
+	appliedAt, ok := applied[migration.After]
+	return ok && !m.clock().Before(appliedAt.Add(migration.Delay))
+}
+
+// applied returns the application time of every applied migration.
+func (m *Migrator) applied(ctx context.Context) (map[string]time.Time, error) {
+	cursor, err := MustConn(ctx).Collection(migrationsCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []struct {
+		ID        string    `bson:"_id"`
+		AppliedAt time.Time `bson:"applied_at"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]time.Time, len(docs))
+	for _, doc := range docs {
+		applied[doc.ID] = doc.AppliedAt
+	}
+	return applied, nil
+}
+
+func (m *Migrator) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// CreateIndexStep creates an index on the schema's collection. Sparse
+// indexes skip documents without the keys.
+type CreateIndexStep struct {
+	Schema JSchema
+	Keys   []string
+	Unique bool
+	Sparse bool
+}
+
+// Description implements MigrationStep.
+func (s CreateIndexStep) Description() string {
+	return fmt.Sprintf("create index %v on %s", s.Keys, s.Schema.Name())
+}
+
+// Apply implements MigrationStep. Creating an existing index is a no-op.
+func (s CreateIndexStep) Apply(ctx context.Context) error {
+	keys := bson.D{}
+	for _, key := range s.Keys {
+		keys = append(keys, bson.E{Key: key, Value: 1})
+	}
+
+	opts := options.Index().SetUnique(s.Unique)
+	if s.Sparse {
+		opts.SetSparse(true)
+	}
+	model := mongo.IndexModel{Keys: keys, Options: opts}
+	_, err := collection(ctx, s.Schema).Indexes().CreateOne(ctx, model)
+	return err
+}
+
+// BackfillDefaultStep sets a field to its static or server-time default in
+// documents that don't have it.
+type BackfillDefaultStep struct {
+	Schema JSchema
+	Field  string
+}
+
+// Description implements MigrationStep.
+func (s BackfillDefaultStep) Description() string {
+	return fmt.Sprintf("backfill default of %s.%s", s.Schema.Name(), s.Field)
+}
+
+// Apply implements MigrationStep.
+func (s BackfillDefaultStep) Apply(ctx context.Context) error {
+	field, ok := s.Schema.Field(s.Field)
+	if !ok {
+		return fmt.Errorf("unknown field %s", s.Field)
+	}
+
+	keys := storageKeys(field)
+	filter := bson.M{keys[0]: bson.M{"$exists": false}}
+
+	var update bson.M
+	if IsServerDefault(field.Default()) {
+		currentDate := bson.M{}
+		for _, key := range keys {
+			currentDate[key] = true
+		}
+		update = bson.M{"$currentDate": currentDate}
+	} else {
+		record := NewMongoRecord(s.Schema)
+		if err := record.SetValue(field, field.Default()); err != nil {
+			return err
+		}
+		doc, err := record.ToBSON(ctx)
+		if err != nil {
+			return err
+		}
+
+		set := bson.M{}
+		for _, key := range keys {
+			set[key] = doc[key]
+		}
+		update = bson.M{"$set": set}
+	}
+
+	_, err := collection(ctx, s.Schema).UpdateMany(ctx, filter, update)
+	return err
+}
+
+// RenameFieldStep moves stored values from one key to another in documents
+// that don't have the new key yet.
+type RenameFieldStep struct {
+	Schema JSchema
+	From   string
+	To     string
+}
+
+// Description implements MigrationStep.
+func (s RenameFieldStep) Description() string {
+	return fmt.Sprintf("rename %s.%s to %s", s.Schema.Name(), s.From, s.To)
+}
+
+// Apply implements MigrationStep.
+func (s RenameFieldStep) Apply(ctx context.Context) error {
+	_, err := collection(ctx, s.Schema).UpdateMany(ctx,
+		bson.M{s.From: bson.M{"$exists": true}, s.To: bson.M{"$exists": false}},
+		bson.M{"$rename": bson.M{s.From: s.To}},
+	)
+	return err
+}
+
+// DropFieldStep removes keys from every document.
+type DropFieldStep struct {
+	Schema JSchema
+	Keys   []string
+}
+
+// Description implements MigrationStep.
+func (s DropFieldStep) Description() string {
+	return fmt.Sprintf("drop %v from %s", s.Keys, s.Schema.Name())
+}
+
+// Apply implements MigrationStep.
+func (s DropFieldStep) Apply(ctx context.Context) error {
+	unset := bson.M{}
+	for _, key := range s.Keys {
+		unset[key] = ""
+	}
+	_, err := collection(ctx, s.Schema).UpdateMany(ctx, bson.M{}, bson.M{"$unset": unset})
+	return err
+}
+
+// GenerateMigrations turns the diff between two versions of a schema into
+// migrations: renamed fields and removed aliases are moved to their new key,
+// added fields are backfilled with their default, added refs are indexed, unique fields get
+// a unique index,
+// new capped or time-series options create the collection and schemas that
+// became trees get their tree ids. Removed fields are dropped by a second
+// migration, ID "<id>-drop", that runs dropAfter the first one was applied. Retyped fields need a
+// hand-written step. It returns nil when there is nothing to migrate.
+func GenerateMigrations(id string, old, new JSchema, dropAfter time.Duration) []Migration {
+	diff := DiffSchemas(old, new)
+
+	var steps, drops []MigrationStep
+	_, isCapped := CappedOf(new)
+	_, isTimeSeries := TimeSeriesOf(new)
+	if (isCapped || isTimeSeries) && collectionOptionsChanged(old, new) {
+		steps = append(steps, CreateCollectionStep{Schema: new})
+	}
+
+	_, wasTree := ParentRefOf(old)
+	if _, isTree := ParentRefOf(new); isTree && !wasTree {
+		steps = append(steps, BackfillTreeIDStep{Schema: new}, CreateIndexStep{Schema: new, Keys: []string{treeIDKey}})
+	}
+
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case FieldRenamed:
+			oldField, _ := old.Field(change.Old)
+			newField, _ := new.Field(change.New)
+			for i, from := range storageKeys(oldField) {
+				if to := storageKeys(newField); i < len(to) && from != to[i] {
+					steps = append(steps, RenameFieldStep{Schema: new, From: from, To: to[i]})
+				}
+			}
+
+		case FieldConstraintChanged:
+			if change.Detail == aliasRemovedDetail {
+				steps = append(steps, RenameFieldStep{Schema: new, From: change.Old, To: change.Field})
+			}
+			if field, ok := new.Field(change.Field); ok && change.Detail == "unique" && IsUnique(field) {
+				steps = append(steps, CreateIndexStep{Schema: new, Keys: []string{field.Name()}, Unique: true})
+			}
+
+		case FieldAdded:
+			field, ok := new.Field(change.Field)
+			if !ok {
+				continue // An added composite part
+			}
+			if _, isRef := field.(JRef); isRef || IsUnique(field) {
+				steps = append(steps, CreateIndexStep{Schema: new, Keys: []string{field.Name()}, Unique: IsUnique(field)})
+			}
+			if backfillable(field.Default()) {
+				steps = append(steps, BackfillDefaultStep{Schema: new, Field: field.Name()})
+			}
+
+		case FieldRemoved:
+			if keys := removedKeys(old, change.Field); len(keys) > 0 {
+				drops = append(drops, DropFieldStep{Schema: new, Keys: keys})
+			}
+		}
+	}
+
+	if len(steps) == 0 && len(drops) == 0 {
+		return nil
+	}
+
+	// The first migration marks when the change rolled out, even without steps
+	migrations := []Migration{{ID: id, Steps: steps}}
+	if len(drops) > 0 {
+		migrations = append(migrations, Migration{ID: id + "-drop", Steps: drops, After: id, Delay: dropAfter})
+	}
+	return migrations
+}
+
+// backfillable reports whether every document can share the default value.
+func backfillable(defaultValue any) bool {
+	switch defaultValue.(type) {
+	case nil, DefaultFunc, func(context.Context) any:
+		return false
+	}
+	return true
+}
+
+// removedKeys returns the stored keys of a removed field or composite part.
+func removedKeys(old JSchema, name string) []string {
+	if field, ok := old.Field(name); ok {
+		return storageKeys(field)
+	}
+
+	for _, field := range old.Fields() {
+		composite, ok := field.Type().(*Composite)
+		if !ok {
+			continue
+		}
+		i := slices.IndexFunc(composite.Parts, func(p CompositePart) bool { return field.Name()+"."+p.Name == name })
+		if i >= 0 {
+			return []string{composite.partKey(field, composite.Parts[i])}
+		}
+	}
+	return nil
+}
+
+var (
+	_ MigrationStep = CreateIndexStep{}
+	_ MigrationStep = BackfillDefaultStep{}
+	_ MigrationStep = RenameFieldStep{}
+	_ MigrationStep = DropFieldStep{}
+)
@@ -0,0 +1,90 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateMigrations(t *testing.T) {
+	users := NewSchema("users").Field("id", &String{}).Build()
+	money := NewComposite(CompositePart{Name: "amount", Type: &Number{}}, CompositePart{Name: "currency", Type: &String{}})
+
+	old := NewSchema("orders").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Field("notes", &String{}, FieldAlias("comment")).
+		Field("total", money).
+		Field("legacy", &String{}).
+		Build()
+
+	new := NewSchema("orders").
+		Field("id", &String{}).
+		Field("name", &String{}, FieldAlias("title")).
+		Field("notes", &String{}).
+		Field("total", NewComposite(CompositePart{Name: "amount", Type: &Number{}})).
+		FieldWithDefault("priority", &Number{}, 1).
+		FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+		FieldWithDefault("token", &String{}, DefaultFunc(func(ctx context.Context) any { return "x" })).
+		Ref("owner", users).
+		Build()
+
+	migrations := GenerateMigrations("2024-06-orders", old, new, 24*time.Hour)
+
+	assert.Equal(t, []Migration{
+		{
+			ID: "2024-06-orders",
+			Steps: []MigrationStep{
+				RenameFieldStep{Schema: new, From: "title", To: "name"},
+				RenameFieldStep{Schema: new, From: "comment", To: "notes"},
+				BackfillDefaultStep{Schema: new, Field: "priority"},
+				BackfillDefaultStep{Schema: new, Field: "created_at"},
+				CreateIndexStep{Schema: new, Keys: []string{"owner"}},
+			},
+		},
+		{
+			ID: "2024-06-orders-drop",
+			Steps: []MigrationStep{
+				DropFieldStep{Schema: new, Keys: []string{"total_currency"}},
+				DropFieldStep{Schema: new, Keys: []string{"legacy"}},
+			},
+			After: "2024-06-orders",
+			Delay: 24 * time.Hour,
+		},
+	}, migrations)
+
+	assert.Nil(t, GenerateMigrations("noop", old, old, time.Hour))
+
+	t.Run("unique fields are indexed", func(t *testing.T) {
+		unique := NewSchema("orders").
+			Field("id", &String{}).
+			Field("title", &String{}, Unique()).
+			Field("notes", &String{}, FieldAlias("comment")).
+			Field("total", money).
+			Field("legacy", &String{}).
+			Field("code", &String{}, Unique()).
+			Build()
+
+		assert.Equal(t, []Migration{{
+			ID: "2024-07-orders",
+			Steps: []MigrationStep{
+				CreateIndexStep{Schema: unique, Keys: []string{"title"}, Unique: true},
+				CreateIndexStep{Schema: unique, Keys: []string{"code"}, Unique: true},
+			},
+		}}, GenerateMigrations("2024-07-orders", old, unique, time.Hour))
+	})
+
+	t.Run("drops wait for the grace period", func(t *testing.T) {
+		now := time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)
+		migrator := NewMigrator(migrations...)
+		migrator.now = func() time.Time { return now }
+
+		drop := migrations[1]
+		assert.True(t, migrator.due(migrations[0], nil))
+		assert.False(t, migrator.due(drop, map[string]time.Time{}))
+		assert.False(t, migrator.due(drop, map[string]time.Time{"2024-06-orders": now.Add(-time.Hour)}))
+		assert.True(t, migrator.due(drop, map[string]time.Time{"2024-06-orders": now.Add(-24 * time.Hour)}))
+	})
+}
@@ -0,0 +1,337 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrCurrencyMismatch is returned by Amount arithmetic on amounts of
+// different currencies.
+var ErrCurrencyMismatch = errors.New("jpack: currencies don't match")
+
+// currencyExponents holds the minor unit digits of the ISO 4217 currencies.
+var currencyExponents = func() map[string]int {
+	exponents := make(map[string]int)
+	for exponent, codes := range []string{
+		0: "BIF CLP DJF GNF ISK JPY KMF KRW PYG RWF UGX UYI VND VUV XAF XOF XPF",
+		2: "AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BOV BRL BSD BTN BWP BYN BZD " +
+			"CAD CDF CHE CHF CHW CNY COP COU CRC CUC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL " +
+			"GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR KPW KYD KZT LAK LBP LKR LRD " +
+			"LSL MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD PAB PEN " +
+			"PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG SEK SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL " +
+			"THB TJS TMT TOP TRY TTD TWD TZS UAH USD USN UYU UZS VED VES WST XCD YER ZAR ZMW ZWL",
+		3: "BHD IQD JOD KWD LYD OMR TND",
+		4: "CLF UYW",
+	} {
+		for _, code := range strings.Fields(codes) {
+			exponents[code] = exponent
+		}
+	}
+	return exponents
+}()
+
+// CurrencyExponent returns the number of minor unit digits of an ISO 4217
+// currency code, e.g. 2 for EUR and 0 for JPY, and whether the code is known.
+func CurrencyExponent(currency string) (int, bool) {
+	exponent, ok := currencyExponents[currency]
+	return exponent, ok
+}
+
+// Amount is a value of a Money field: a whole number of minor units, e.g.
+// cents, of an ISO 4217 currency. Amounts never go through floating point.
+type Amount struct {
+	Minor    int64
+	Currency string
+}
+
+// ParseAmount parses a decimal amount in major units, e.g. "12.50", of a
+// currency. It rejects more decimals than the currency has minor digits.
+func ParseAmount(s, currency string) (Amount, error) {
+	exponent, ok := CurrencyExponent(currency)
+	if !ok {
+		return Amount{}, fmt.Errorf("unknown currency %q", currency)
+	}
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if whole == "" || len(fraction) > exponent || strings.HasPrefix(whole, "+") {
+		return Amount{}, fmt.Errorf("invalid %s amount %q", currency, s)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", exponent-len(fraction))
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Amount{}, fmt.Errorf("invalid %s amount %q", currency, s)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Amount{Minor: minor, Currency: currency}, nil
+}
+
+// String formats the amount in major units, e.g. "12.50 EUR".
+func (a Amount) String() string {
+	exponent, ok := CurrencyExponent(a.Currency)
+	if !ok || exponent == 0 {
+		return fmt.Sprintf("%d %s", a.Minor, a.Currency)
+	}
+
+	sign, minor := "", uint64(a.Minor)
+	if a.Minor < 0 {
+		sign, minor = "-", uint64(-a.Minor)
+	}
+	scale := uint64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d %s", sign, minor/scale, exponent, minor%scale, a.Currency)
+}
+
+// IsZero reports whether the amount is zero.
+func (a Amount) IsZero() bool {
+	return a.Minor == 0
+}
+
+// Add returns the sum of two amounts of the same currency.
+func (a Amount) Add(b Amount) (Amount, error) {
+	if a.Currency != b.Currency {
+		return Amount{}, ErrCurrencyMismatch
+	}
+	sum := a.Minor + b.Minor
+	if (b.Minor > 0 && sum < a.Minor) || (b.Minor < 0 && sum > a.Minor) {
+		return Amount{}, errors.New("jpack: amount overflows")
+	}
+	return Amount{Minor: sum, Currency: a.Currency}, nil
+}
+
+// Sub returns the difference of two amounts of the same currency.
+func (a Amount) Sub(b Amount) (Amount, error) {
+	if b.Minor == math.MinInt64 {
+		return Amount{}, errors.New("jpack: amount overflows")
+	}
+	return a.Add(Amount{Minor: -b.Minor, Currency: b.Currency})
+}
+
+// Cmp compares two amounts of the same currency, returning -1, 0 or +1.
+func (a Amount) Cmp(b Amount) (int, error) {
+	if a.Currency != b.Currency {
+		return 0, ErrCurrencyMismatch
+	}
+	switch {
+	case a.Minor < b.Minor:
+		return -1, nil
+	case a.Minor > b.Minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Money is a field type for monetary amounts. Its value is an Amount, stored
+// as "<field>_amount" in minor units and "<field>_currency" as the ISO 4217
+// code. Filter it with the Money comparators, which match the currency too.
+type Money struct {
+	// Currencies restricts the accepted currency codes; empty accepts every
+	// ISO 4217 currency.
+	Currencies []string
+}
+
+var (
+	_ CompositeFieldType = &Money{}
+	_ OpenAPIFieldType   = &Money{}
+)
+
+// StorageKeys implements CompositeFieldType.
+func (m *Money) StorageKeys(field JField) []string {
+	return []string{moneyAmountKey(field), moneyCurrencyKey(field)}
+}
+
+func moneyAmountKey(field JField) string {
+	return field.Name() + "_amount"
+}
+
+func moneyCurrencyKey(field JField) string {
+	return field.Name() + "_currency"
+}
+
+// Scan implements JFieldType.
+func (m *Money) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	minor, hasMinor := row[moneyAmountKey(field)]
+	currency, hasCurrency := row[moneyCurrencyKey(field)]
+	if (!hasMinor || minor == nil) && (!hasCurrency || currency == nil) {
+		return nil, nil // No value found, return nil
+	}
+
+	return toAmount(map[string]any{"amount": minor, "currency": currency})
+}
+
+// SetValue implements JFieldType.
+func (m *Money) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, clear both keys
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[moneyAmountKey(field)] = nil
+		row[moneyCurrencyKey(field)] = nil
+		return nil
+	}
+
+	if err := m.Validate(value); err != nil {
+		return err
+	}
+
+	amount, _ := toAmount(value)
+	row[moneyAmountKey(field)] = amount.Minor
+	row[moneyCurrencyKey(field)] = amount.Currency
+	return nil
+}
+
+// Validate implements JFieldType.
+func (m *Money) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
+
+	amount, err := toAmount(value)
+	if err != nil {
+		return err
+	}
+	if len(m.Currencies) > 0 && !slices.Contains(m.Currencies, amount.Currency) {
+		return fmt.Errorf("currency %s is not one of %s", amount.Currency, strings.Join(m.Currencies, ", "))
+	}
+	return nil
+}
+
+// TypeName reports the registry name of the type.
+func (m *Money) TypeName() string {
+	return "money"
+}
+
+// Example returns an example value for generated documentation.
+func (m *Money) Example() any {
+	currency := "EUR"
+	if len(m.Currencies) > 0 {
+		currency = m.Currencies[0]
+	}
+	return map[string]any{"amount": 1250, "currency": currency}
+}
+
+// OpenAPISchema implements OpenAPIFieldType.
+func (m *Money) OpenAPISchema() map[string]any {
+	currency := map[string]any{"type": "string", "pattern": "^[A-Z]{3}$"}
+	if len(m.Currencies) > 0 {
+		currency = map[string]any{"type": "string", "enum": m.Currencies}
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"amount":   map[string]any{"type": "integer", "format": "int64", "description": "Minor units"},
+			"currency": currency,
+		},
+		"required": []string{"amount", "currency"},
+	}
+}
+
+// toAmount converts an Amount or a map with an "amount" in minor units and a
+// "currency".
+func toAmount(value any) (Amount, error) {
+	var amount Amount
+	switch v := value.(type) {
+	case Amount:
+		amount = v
+	case *Amount:
+		amount = *v
+	case map[string]any:
+		if v["amount"] == nil || v["currency"] == nil {
+			return Amount{}, errors.New("money needs an amount and a currency")
+		}
+		minor, err := convertToInt(reflect.ValueOf(v["amount"]))
+		if err != nil {
+			return Amount{}, fmt.Errorf("amount: %w", err)
+		}
+		currency, ok := v["currency"].(string)
+		if !ok {
+			return Amount{}, errors.New("currency is not a string")
+		}
+		amount = Amount{Minor: int64(minor), Currency: currency}
+	default:
+		return Amount{}, errors.New("value is not an Amount")
+	}
+
+	if _, ok := CurrencyExponent(amount.Currency); !ok {
+		return Amount{}, fmt.Errorf("unknown currency %q", amount.Currency)
+	}
+	return amount, nil
+}
+
+var (
+	// MoneyEq matches amounts equal to the value, in its currency.
+	MoneyEq Comparator = NewComparator("MONEY =")
+	// MoneyLt matches amounts below the value, in its currency.
+	MoneyLt Comparator = NewComparator("MONEY <")
+	// MoneyLte matches amounts up to the value, in its currency.
+	MoneyLte Comparator = NewComparator("MONEY <=")
+	// MoneyGt matches amounts above the value, in its currency.
+	MoneyGt Comparator = NewComparator("MONEY >")
+	// MoneyGte matches amounts from the value on, in its currency.
+	MoneyGte Comparator = NewComparator("MONEY >=")
+	// MoneyBetween matches amounts within the bounds, in their currency.
+	MoneyBetween RangeComparator = NewRangeComparator("MONEY BETWEEN")
+)
+
+// matchNothing is a filter no document matches, for money filters whose
+// value can't be compared.
+var matchNothing = bson.M{defaultMongoPK: bson.M{"$exists": false}}
+
+func init() {
+	for operator, mongoOperator := range map[string]string{
+		"MONEY =":  "$eq",
+		"MONEY <":  "$lt",
+		"MONEY <=": "$lte",
+		"MONEY >":  "$gt",
+		"MONEY >=": "$gte",
+	} {
+		RegisterFilterResolver(operator, func(filter Filter) bson.M {
+			field := filter.Field()
+			if field == nil {
+				return nil
+			}
+			amount, err := toAmount(filter.Value())
+			if err != nil {
+				return matchNothing
+			}
+			return bson.M{
+				moneyCurrencyKey(field): amount.Currency,
+				moneyAmountKey(field):   bson.M{mongoOperator: amount.Minor},
+			}
+		})
+	}
+
+	RegisterFilterResolver("MONEY BETWEEN", func(filter Filter) bson.M {
+		field := filter.Field()
+		if field == nil {
+			return nil
+		}
+		values, ok := filter.Value().([]any)
+		if !ok || len(values) != 2 {
+			return matchNothing
+		}
+		min, err := toAmount(values[0])
+		if err != nil {
+			return matchNothing
+		}
+		max, err := toAmount(values[1])
+		if err != nil || min.Currency != max.Currency {
+			return matchNothing
+		}
+		return bson.M{
+			moneyCurrencyKey(field): min.Currency,
+			moneyAmountKey(field):   bson.M{"$gte": min.Minor, "$lte": max.Minor},
+		}
+	})
+}
@@ -0,0 +1,108 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestAmount(t *testing.T) {
+	for input, want := range map[string]Amount{
+		"12.50":  {Minor: 1250, Currency: "EUR"},
+		"12.5":   {Minor: 1250, Currency: "EUR"},
+		"-0.07":  {Minor: -7, Currency: "EUR"},
+		"3":      {Minor: 300, Currency: "EUR"},
+		" 1.234": {Minor: 1234, Currency: "BHD"},
+		"500":    {Minor: 500, Currency: "JPY"},
+	} {
+		currency := want.Currency
+		got, err := ParseAmount(input, currency)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, input := range []string{"1.234", "", ".5", "1,00", "+1", "1e3"} {
+		_, err := ParseAmount(input, "EUR")
+		assert.Error(t, err, input)
+	}
+	_, err := ParseAmount("1", "EURO")
+	assert.ErrorContains(t, err, "unknown currency")
+
+	assert.Equal(t, "12.50 EUR", Amount{Minor: 1250, Currency: "EUR"}.String())
+	assert.Equal(t, "-0.07 EUR", Amount{Minor: -7, Currency: "EUR"}.String())
+	assert.Equal(t, "500 JPY", Amount{Minor: 500, Currency: "JPY"}.String())
+	assert.Equal(t, "1.234 BHD", Amount{Minor: 1234, Currency: "BHD"}.String())
+
+	eur := func(minor int64) Amount { return Amount{Minor: minor, Currency: "EUR"} }
+	sum, err := eur(1250).Add(eur(-250))
+	assert.NoError(t, err)
+	assert.Equal(t, eur(1000), sum)
+	diff, err := eur(100).Sub(eur(250))
+	assert.NoError(t, err)
+	assert.Equal(t, eur(-150), diff)
+	_, err = eur(1).Add(Amount{Minor: 1, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = eur(1 << 62).Add(eur(1 << 62))
+	assert.ErrorContains(t, err, "overflows")
+
+	c, err := eur(1).Cmp(eur(2))
+	assert.NoError(t, err)
+	assert.Equal(t, -1, c)
+	_, err = eur(1).Cmp(Amount{Minor: 1, Currency: "USD"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney(t *testing.T) {
+	schema := NewSchema("test_money").
+		Field("id", &String{}).
+		Field("price", &Money{Currencies: []string{"EUR", "USD"}}).
+		Build()
+	price := mustField(t, schema, "price")
+
+	t.Run("stored as minor units and currency", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(price, Amount{Minor: 1250, Currency: "EUR"}))
+		doc, err := record.convertToBSON(t.Context(), record.record)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"price_amount": int64(1250), "price_currency": "EUR"}, doc)
+
+		loaded := NewMongoRecord(schema)
+		assert.NoError(t, loaded.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "price_amount": int64(999), "price_currency": "USD"}))
+		got, ok := loaded.Value(price)
+		assert.True(t, ok)
+		assert.Equal(t, Amount{Minor: 999, Currency: "USD"}, got)
+		assert.Empty(t, loaded.UnknownKeys())
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		money := price.Type()
+		assert.NoError(t, money.Validate(map[string]any{"amount": float64(1250), "currency": "EUR"}), "whole JSON numbers")
+		assert.Error(t, money.Validate(map[string]any{"amount": 12.5, "currency": "EUR"}), "fractional minor units")
+		assert.Error(t, money.Validate(map[string]any{"amount": 1250}))
+		assert.EqualError(t, money.Validate(Amount{Minor: 1, Currency: "GBP"}), "currency GBP is not one of EUR, USD")
+		assert.ErrorContains(t, (&Money{}).Validate(Amount{Minor: 1, Currency: "XYZ"}), "unknown currency")
+		assert.Error(t, money.Validate(12.5))
+
+		_, err := money.Scan(t.Context(), price, map[string]any{"price_amount": int64(5)})
+		assert.Error(t, err, "an amount without currency")
+	})
+
+	t.Run("comparators match the currency", func(t *testing.T) {
+		ten := Amount{Minor: 1000, Currency: "EUR"}
+		assert.Equal(t, bson.M{"price_currency": "EUR", "price_amount": bson.M{"$lt": int64(1000)}}, ResolveFilter(MoneyLt(price, ten)))
+		assert.Equal(t, bson.M{"price_currency": "EUR", "price_amount": bson.M{"$eq": int64(1000)}}, ResolveFilter(MoneyEq(price, ten)))
+		assert.Equal(t, bson.M{"price_currency": "EUR", "price_amount": bson.M{"$gte": int64(1000), "$lte": int64(2000)}},
+			ResolveFilter(MoneyBetween(price, ten, Amount{Minor: 2000, Currency: "EUR"})))
+
+		assert.Equal(t, matchNothing, ResolveFilter(MoneyBetween(price, ten, Amount{Minor: 2000, Currency: "USD"})))
+		assert.Equal(t, matchNothing, ResolveFilter(MoneyGt(price, 10)))
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		fType, err := NewFieldType("money", map[string]any{"currencies": []any{"EUR"}})
+		assert.NoError(t, err)
+		assert.Equal(t, &Money{Currencies: []string{"EUR"}}, fType)
+		assert.Equal(t, "money", FieldTypeName(fType))
+	})
+}
@@ -3,36 +3,118 @@ package jpack
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
 
-	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/readpref"
 )
 
+// key is the type of jpack's context keys. It is unexported, so keys of
+// other packages can't collide with them.
 type key string
 
 var (
+	// Conn is the context key holding the MongoDB database.
+	//
+	// Deprecated: use WithDatabase and DatabaseFrom.
 	Conn key = "jpack.conn.mongo"
+
+	// ReadOnly marks a context whose store rejects writes.
+	//
+	// Deprecated: use WithReadOnly and IsReadOnly.
+	ReadOnly key = "jpack.conn.readonly"
 )
 
+// ErrReadOnly is returned when writing through a read-only store.
+var ErrReadOnly = errors.New("jpack: store is read-only")
+
+// ErrNotFound is returned by Query.First and FindByID when no record matches.
+var ErrNotFound = errors.New("jpack: record not found")
+
 const (
 	// ConnKey is the key used to store the MongoDB connection in the context.
 	defaultMongoPK = "_id"
 )
 
+// WithDatabase returns a context whose records and queries use db.
+func WithDatabase(ctx context.Context, db *mongo.Database) context.Context {
+	return context.WithValue(ctx, Conn, db)
+}
+
+// DatabaseFrom returns the database stored in the context, if any.
+func DatabaseFrom(ctx context.Context) (*mongo.Database, bool) {
+	db, ok := ctx.Value(Conn).(*mongo.Database)
+	return db, ok && db != nil
+}
+
+// MustConn returns the database stored in the context, or the DefaultStore,
+// and panics when there is neither.
 func MustConn(ctx context.Context) *mongo.Database {
-	conn, ok := ctx.Value(Conn).(*mongo.Database)
-	if !ok || conn == nil {
+	conn, ok := DatabaseFrom(ctx)
+	if !ok {
+		conn, ok = StoreDatabase(DefaultStore)
+	}
+	if !ok {
 		panic("jpack: mongo connection not found in context")
 	}
 	return conn
 }
 
+// WithReadOnly returns a context whose store rejects Save with ErrReadOnly and
+// routes queries to secondaries, e.g. for analytics replicas or maintenance windows.
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ReadOnly, true)
+}
+
+// IsReadOnly reports whether the context's store is read-only.
+func IsReadOnly(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(ReadOnly).(bool)
+	return readOnly
+}
+
+// collection returns the collection for the schema, preferring secondaries for read-only stores.
+func collection(ctx context.Context, schema JSchema) *mongo.Collection {
+	db := mustDatabaseFor(ctx, schema)
+	name := collectionName(ctx, storageSchema(schema))
+	if IsReadOnly(ctx) {
+		return db.Collection(name, options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+	}
+	return db.Collection(name)
+}
+
 type mongoRecord struct {
 	originalRecord map[string]any
 	record         map[string]any
 
+	// renamedKeys maps aliased keys found in the stored document to the
+	// current field name, so Save can rewrite them.
+	renamedKeys map[string]string
+
+	// observers are the OnChange callbacks registered since the last Save.
+	observers []func(field JField, old, new any)
+
+	// scanned memoizes ScannedValue by field name.
+	scanned map[string]any
+
+	// warnings are the warnings of the last Save or Validate.
+	warnings []Warning
+
+	// unknownKeys are the keys of the stored document not declared in the
+	// schema.
+	unknownKeys []string
+
+	// saves counts the writes of the record, so snapshots taken before one
+	// can't be rolled back to.
+	saves uint64
+
+	// lineage holds the Lineage of the stored document's fields by name.
+	lineage map[string]Lineage
+
 	schema JSchema
 }
 
@@ -40,7 +122,7 @@ type mongoRecord struct {
 func (m *mongoRecord) DirtyKeys() []string {
 	var dirtyKeys []string
 	for key := range m.record {
-		if _, exists := m.originalRecord[key]; !exists || m.record[key] != m.originalRecord[key] {
+		if m.isDirty(key) {
 			dirtyKeys = append(dirtyKeys, key)
 		}
 	}
@@ -51,8 +133,11 @@ func (m *mongoRecord) DirtyKeys() []string {
 func (m *mongoRecord) Fields() []JField {
 	var fields []JField
 	for _, field := range m.Schema().Fields() {
-		if _, ok := m.originalRecord[field.Name()]; ok {
-			fields = append(fields, field)
+		for _, key := range storageKeys(field) {
+			if _, ok := m.originalRecord[key]; ok {
+				fields = append(fields, field)
+				break
+			}
 		}
 	}
 
@@ -69,57 +154,517 @@ func (m *mongoRecord) IsNew() bool {
 	return len(m.originalRecord) == 0
 }
 
-// Save implements JRecord.
-func (m *mongoRecord) Save(ctx context.Context) error {
+// Save implements JRecord. Within the context of a RequestBatcher the record
+// is validated right away but written on the batcher's Flush, and within the
+// context of a PendingJournal it is queued until Sync.
+func (m *mongoRecord) Save(ctx context.Context, opts ...SaveOption) error {
+	saveOpts := newSaveOptions(opts)
+	if journal, ok := PendingJournalFrom(ctx); ok {
+		return m.savePending(ctx, journal, saveOpts)
+	}
+	if r, ok := m.sqliteRoute(ctx); ok {
+		return r.Save(ctx, opts...)
+	}
+
+	if err := checkOutbox(ctx, m.schema); err != nil {
+		return err
+	}
+
+	// Conditional saves need the match count of their own write, returned
+	// documents the read after it and idempotent inserts their own error
+	batcher, batched := RequestBatcherFrom(ctx)
+	batched = batched && saveOpts.ifMatch == nil && !saveOpts.returnDocument && saveOpts.idempotencyKey == ""
+
+	var restore func()
+	if batched {
+		restore = m.snapshot()
+	}
+
+	w, err := m.prepareSave(ctx, saveOpts)
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		// Nothing changed, so there is nothing to write
+		m.observers = nil
+		if saveOpts.returnDocument {
+			return m.refresh(ctx)
+		}
+		return nil
+	}
+
+	if batched {
+		batcher.add(w, restore)
+		return nil
+	}
+
+	matched, err := w.execute(ctx)
+	if err != nil && w.op == ChangeInsert && saveOpts.idempotencyKey != "" && mongo.IsDuplicateKeyError(err) {
+		// A retry of an insert that already succeeded
+		if found, loadErr := m.loadIdempotent(ctx, w.coll, saveOpts.idempotencyKey); loadErr != nil || found {
+			return loadErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if saveOpts.ifMatch != nil && matched == 0 {
+		return ErrPreconditionFailed
+	}
+	if err := w.finish(ctx); err != nil {
+		return err
+	}
+	if saveOpts.returnDocument {
+		return m.refresh(ctx)
+	}
+	return nil
+}
 
-	coll := MustConn(ctx).Collection(m.Schema().Name())
+// pendingWrite is the validated write of a Save, executed on its own or in
+// bulk by a RequestBatcher.
+type pendingWrite struct {
+	record *mongoRecord
+	coll   *mongo.Collection
+	op     ChangeOperation
+
+	// operation is "insert", "upsert" or "update", as tapped
+	operation string
+	filter    bson.M
+	document  bson.M
+	// insertedID is the _id of an inserted document
+	insertedID any
+	// lineage is the Lineage stamped on the written fields
+	lineage map[string]Lineage
+}
+
+// prepareSave validates the record and builds its write, or returns a nil
+// write when an unconditional update would change nothing.
+func (m *mongoRecord) prepareSave(ctx context.Context, saveOpts *saveOptions) (*pendingWrite, error) {
+	if IsReadOnly(ctx) {
+		return nil, ErrReadOnly
+	}
+	if _, ok := m.schema.(*ViewSchema); ok {
+		return nil, ErrViewReadOnly
+	}
+
+	for _, policy := range PoliciesOf(m.schema) {
+		if err := policy.IsValid(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := validateRecordFields(ctx, m, saveOpts.scenarios...); err != nil {
+		return nil, err
+	}
+	m.warnings = collectWarnings(ctx, m)
+
+	if err := checkTreeCycle(ctx, m); err != nil {
+		return nil, err
+	}
+
+	coll := collection(ctx, m.Schema())
 	pkField, _ := PK(m.schema)
 	if m.IsNew() {
-		convertToBSON, err := m.convertToBSON(ctx, m.record)
+		// There is no stored document an ETag could match
+		if saveOpts.ifMatch != nil {
+			return nil, ErrPreconditionFailed
+		}
+
+		serverDefaults, err := m.applyDefaults(ctx)
 		if err != nil {
-			log.Error().Err(err).Msg("jpack: failed to convert record to BSON")
-			return err
+			return nil, err
+		}
+
+		for _, field := range m.Schema().Fields() {
+			if slices.Contains(serverDefaults, field) {
+				continue
+			}
+			if err := m.checkRequired(field, m.record[field.Name()], saveOpts.scenarios...); err != nil {
+				return nil, err
+			}
 		}
-		res, err := coll.InsertOne(ctx, convertToBSON)
+
+		convertToBSON, err := m.convertToBSON(ctx, m.record)
 		if err != nil {
-			return nil
+			LoggerFrom(ctx).Error().Err(err).Msg("jpack: failed to convert record to BSON")
+			return nil, err
 		}
 
-		// m.record[defaultMongoPK] = res.InsertedID
-		objID, ok := res.InsertedID.(bson.ObjectID)
-		if ok {
+		// The id is chosen up front, so batched records can be referenced
+		// before they are written
+		if _, ok := convertToBSON[defaultMongoPK]; !ok {
+			id := bson.NewObjectID()
+			if hex, ok := m.record[pkField.Name()].(string); ok {
+				if objID, err := bson.ObjectIDFromHex(hex); err == nil {
+					id = objID
+					delete(convertToBSON, pkField.Name())
+				}
+			}
+			convertToBSON[defaultMongoPK] = id
+		}
+		setTreeID(m.schema, convertToBSON)
+		if saveOpts.idempotencyKey != "" {
+			convertToBSON[idempotencyKeyField] = saveOpts.idempotencyKey
+		}
+
+		w := &pendingWrite{record: m, coll: coll, op: ChangeInsert, insertedID: convertToBSON[defaultMongoPK]}
+		if len(serverDefaults) > 0 {
+			// $currentDate is only available on updates, so server-side
+			// defaults are written through an upsert of a fresh document.
+			w.operation = "upsert"
+			w.filter, w.document = upsertWithServerDefaults(convertToBSON, serverDefaults)
+		} else {
+			w.operation = "insert"
+			w.document = convertToBSON
+		}
+
+		written := make([]string, 0, len(m.record)+len(serverDefaults))
+		for name := range m.record {
+			if name != pkField.Name() {
+				written = append(written, name)
+			}
+		}
+		for _, field := range serverDefaults {
+			written = append(written, field.Name())
+		}
+		m.stampLineage(ctx, w, written)
+		return w, nil
+	}
+
+	for _, key := range m.DirtyKeys() {
+		if field, ok := m.Schema().Field(key); ok {
+			value := m.record[key]
+			if isUnset(value) {
+				value = nil
+			}
+			if err := m.checkImmutable(field, value); err != nil {
+				return nil, err
+			}
+			if err := m.checkRequired(field, value, saveOpts.scenarios...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := m.checkRequiredIn(saveOpts.scenarios); err != nil {
+		return nil, err
+	}
+
+	// Values read under a previous field name are rewritten under the current one
+	pending, cleared := m.pendingChanges()
+	for key := range pending {
+		if !m.isDirty(key) {
+			delete(pending, key)
+		}
+	}
+	for _, name := range m.renamedKeys {
+		if _, ok := pending[name]; !ok && !slices.Contains(cleared, name) {
+			pending[name] = m.originalRecord[name]
+		}
+	}
+
+	convertToBSON, err := m.convertToBSON(ctx, pending)
+	if err != nil {
+		LoggerFrom(ctx).Error().Err(err).Msg("jpack: failed to convert record to BSON")
+		return nil, err
+	}
+	delete(convertToBSON, pkField.Name()) // Remove the id field from the update
+	delete(convertToBSON, defaultMongoPK) // Remove the mongo id field from the update
+
+	objID, err := m.objectID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys dropped when loaded are removed from the document
+	var dropped []string
+	if UnknownFieldPolicyOf(m.schema) == DropUnknownFields {
+		dropped = m.unknownKeys
+	}
+
+	if len(convertToBSON)+len(m.renamedKeys)+len(cleared)+len(dropped) == 0 && saveOpts.ifMatch == nil {
+		return nil, nil
+	}
+
+	update := bson.M{}
+	if len(convertToBSON) > 0 || len(m.renamedKeys)+len(cleared)+len(dropped) == 0 {
+		update["$set"] = convertToBSON
+	}
+	if len(m.renamedKeys)+len(cleared)+len(dropped) > 0 {
+		unset := bson.M{}
+		for alias := range m.renamedKeys {
+			unset[alias] = ""
+		}
+		for _, key := range dropped {
+			unset[key] = ""
+		}
+		for _, name := range cleared {
+			if field, ok := m.Schema().Field(name); ok {
+				for _, key := range storageKeys(field) {
+					unset[key] = ""
+				}
+			}
+		}
+		update["$unset"] = unset
+	}
+
+	filter := bson.M{defaultMongoPK: objID}
+	if saveOpts.ifMatch != nil {
+		if filter, err = m.matchFilter(ctx, coll, objID, *saveOpts.ifMatch); err != nil {
+			return nil, err
+		}
+	}
+
+	w := &pendingWrite{record: m, coll: coll, op: ChangeUpdate, operation: "update", filter: filter, document: update}
+	m.stampLineage(ctx, w, append(slices.Collect(maps.Keys(pending)), cleared...))
+	return w, nil
+}
+
+// model returns the write as part of a bulk write.
+func (w *pendingWrite) model() mongo.WriteModel {
+	switch w.operation {
+	case "insert":
+		return mongo.NewInsertOneModel().SetDocument(w.document)
+	case "upsert":
+		return mongo.NewUpdateOneModel().SetFilter(w.filter).SetUpdate(w.document).SetUpsert(true)
+	}
+	return mongo.NewUpdateOneModel().SetFilter(w.filter).SetUpdate(w.document)
+}
+
+// execute runs the write on its own and returns the number of documents
+// inserted or matched.
+func (w *pendingWrite) execute(ctx context.Context) (int64, error) {
+	var count int64
+	var err error
+
+	opCtx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	switch w.operation {
+	case "insert":
+		_, err = w.coll.InsertOne(opCtx, w.document)
+		count = 1
+	case "upsert":
+		_, err = w.coll.UpdateOne(opCtx, w.filter, w.document, options.UpdateOne().SetUpsert(true))
+		count = 1
+	default:
+		var res *mongo.UpdateResult
+		if res, err = w.coll.UpdateOne(opCtx, w.filter, w.document); err == nil {
+			count = res.MatchedCount
+		}
+	}
+	tapQuery(ctx, writeOperation(w.coll, w.operation, w.filter, w.document, count, err), start)
+	return count, err
+}
+
+// finish updates the record after its write succeeded and runs the side
+// effects of the write.
+func (w *pendingWrite) finish(ctx context.Context) error {
+	m := w.record
+	m.saves++
+	m.keepLineage(w.lineage)
+	if w.op == ChangeInsert {
+		if objID, ok := w.insertedID.(bson.ObjectID); ok {
+			pkField, _ := PK(m.schema)
 			m.record[pkField.Name()] = objID.Hex() // Store the ID as a string in the record
 		}
 		// After inserting, we can set the original record to the current record
 		m.originalRecord = m.record
 		// and clear the record to indicate that it has been saved.
 		m.record = bson.M{}
+		m.invalidateScanned()
 
-		return nil
+		if identityMap, ok := IdentityMapFrom(ctx); ok {
+			identityMap.Track(m)
+		}
 	} else {
-		convertToBSON, err := m.convertToBSON(ctx, m.record)
-		delete(convertToBSON, pkField.Name()) // Remove the id field from the update
-		delete(convertToBSON, defaultMongoPK) // Remove the mongo id field from the update
-		if err != nil {
-			log.Error().Err(err).Msg("jpack: failed to convert record to BSON")
-			return err
+		m.keepUpdated()
+
+		clear(m.renamedKeys)
+		if UnknownFieldPolicyOf(m.schema) == DropUnknownFields {
+			m.unknownKeys = nil
 		}
+	}
+	m.observers = nil
 
-		objID, err := m.objectID()
-		if err != nil {
-			return err
+	if err := writeOutbox(ctx, m, w.op); err != nil {
+		return err
+	}
+
+	m.afterWrite(ctx, w.op)
+	return nil
+}
+
+// keepUpdated moves the values written by an update into the loaded record,
+// so the record shows them as stored and a later IfMatch save hashes the
+// document the update left behind.
+func (m *mongoRecord) keepUpdated() {
+	pending, cleared := m.pendingChanges()
+	maps.Copy(m.originalRecord, pending)
+	for _, key := range cleared {
+		delete(m.originalRecord, key)
+	}
+	m.record = bson.M{}
+	m.invalidateScanned()
+}
+
+// applyDefaults fills unset fields with their default values before an insert.
+// It returns the fields whose default must be computed by the server.
+func (m *mongoRecord) applyDefaults(ctx context.Context) ([]JField, error) {
+	var serverDefaults []JField
+	for _, field := range m.Schema().Fields() {
+		if _, ok := m.record[field.Name()]; ok {
+			continue
+		}
+
+		defaultValue := field.Default()
+		if defaultValue == nil {
+			continue
 		}
 
-		update := bson.M{"$set": convertToBSON}
-		_, err = coll.UpdateByID(ctx, objID, update)
+		if IsServerDefault(defaultValue) {
+			serverDefaults = append(serverDefaults, field)
+			continue
+		}
 
+		value, err := coerceInput(field, resolveDefault(ctx, defaultValue))
 		if err != nil {
-			return err
+			return nil, errors.Join(errors.New("invalid default value for field "+field.Name()), err)
 		}
 
-		return nil
+		if value != nil {
+			m.record[field.Name()] = value
+			m.invalidateScanned(field.Name())
+		}
+	}
+
+	return serverDefaults, nil
+}
+
+// upsertWithServerDefaults returns the filter and update inserting doc, with
+// its _id, through an upsert that sets the server defaults.
+func upsertWithServerDefaults(doc bson.M, serverDefaults []JField) (bson.M, bson.M) {
+	// The id comes from the upsert filter
+	fields := bson.M{}
+	for key, value := range doc {
+		if key != defaultMongoPK {
+			fields[key] = value
+		}
+	}
+
+	currentDate := bson.M{}
+	for _, field := range serverDefaults {
+		currentDate[field.Name()] = true
 	}
 
+	update := bson.M{"$currentDate": currentDate}
+	if len(fields) > 0 {
+		update["$setOnInsert"] = fields
+	}
+
+	return bson.M{defaultMongoPK: doc[defaultMongoPK]}, update
+}
+
+// Delete implements JRecord.
+func (m *mongoRecord) Delete(ctx context.Context) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	if _, ok := m.schema.(*ViewSchema); ok {
+		return ErrViewReadOnly
+	}
+	if journal, ok := PendingJournalFrom(ctx); ok {
+		return m.deletePending(ctx, journal)
+	}
+	if r, ok := m.sqliteRoute(ctx); ok {
+		return r.Delete(ctx)
+	}
+	if err := checkOutbox(ctx, m.schema); err != nil {
+		return err
+	}
+
+	objID, err := m.objectID()
+	if err != nil {
+		return err
+	}
+
+	coll := collection(ctx, m.Schema())
+	filter := bson.M{defaultMongoPK: objID}
+	opCtx, cancel := operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	res, err := coll.DeleteOne(opCtx, filter)
+	if err != nil {
+		tapQuery(ctx, writeOperation(coll, "delete", filter, nil, 0, err), start)
+		return err
+	}
+	tapQuery(ctx, writeOperation(coll, "delete", filter, nil, res.DeletedCount, nil), start)
+
+	if identityMap, ok := IdentityMapFrom(ctx); ok {
+		identityMap.Forget(m)
+	}
+
+	if err := writeOutbox(ctx, m, ChangeDelete); err != nil {
+		return err
+	}
+
+	m.afterWrite(ctx, ChangeDelete)
+	return nil
+}
+
+// writeOperation describes a record write for QueryTap.
+func writeOperation(coll *mongo.Collection, name string, filter, document bson.M, count int64, err error) QueryOperation {
+	if err != nil {
+		count = 0
+	}
+	return QueryOperation{
+		Collection: coll.Name(),
+		Operation:  name,
+		Filter:     filter,
+		Document:   document,
+		Count:      count,
+		Err:        err,
+	}
+}
+
+// afterWrite runs the side effects of a successful write: cache invalidation,
+// search index sync, materialized view refreshes and webhooks.
+func (m *mongoRecord) afterWrite(ctx context.Context, op ChangeOperation) {
+	invalidateOnWrite(ctx, m.Schema())
+
+	if op == ChangeDelete {
+		syncSearchOnDelete(ctx, m)
+	} else {
+		syncSearchOnSave(ctx, m)
+	}
+	refreshOnWrite(ctx, m.Schema())
+
+	dispatchWebhooks(ctx, m, op)
+}
+
+// snapshot captures the in-memory state of the record and returns a function
+// that restores it. The function can be called repeatedly, e.g. before each
+// attempt of a transaction.
+func (m *mongoRecord) snapshot() func() {
+	originalRecord := maps.Clone(m.originalRecord)
+	record := maps.Clone(m.record)
+	renamedKeys := maps.Clone(m.renamedKeys)
+	unknownKeys := slices.Clone(m.unknownKeys)
+	lineage := maps.Clone(m.lineage)
+	observers := slices.Clone(m.observers)
+	saves := m.saves
+
+	return func() {
+		m.originalRecord = maps.Clone(originalRecord)
+		m.record = maps.Clone(record)
+		m.renamedKeys = maps.Clone(renamedKeys)
+		m.unknownKeys = slices.Clone(unknownKeys)
+		m.lineage = maps.Clone(lineage)
+		m.observers = slices.Clone(observers)
+		m.saves = saves
+		m.invalidateScanned()
+	}
 }
 
 func (m *mongoRecord) objectID() (bson.ObjectID, error) {
@@ -165,18 +710,56 @@ func (m *mongoRecord) SetValue(field JField, value any) error {
 		return errors.New("field schema does not match record schema")
 	}
 
-	err := field.Type().Validate(value)
+	value, err := coerceInput(field, value)
 	if err != nil {
 		return err
 	}
 
+	if err := m.checkImmutable(field, value); err != nil {
+		return err
+	}
+
+	m.invalidateScanned(field.Name())
+	if len(m.observers) == 0 {
+		m.record[field.Name()] = value
+		return nil
+	}
+
+	old, _ := m.Value(field)
 	m.record[field.Name()] = value
+	if !valuesEqual(field, old, value) {
+		for _, fn := range m.observers {
+			fn(field, old, value)
+		}
+	}
 	return nil
 }
 
-// Validate implements JRecord.
-func (m *mongoRecord) Validate() error {
-	return m.schema.Validate(m)
+// OnChange implements JRecord.
+func (m *mongoRecord) OnChange(fn func(field JField, old, new any)) {
+	m.observers = append(m.observers, fn)
+}
+
+// checkImmutable rejects changes to immutable fields of records that already exist.
+func (m *mongoRecord) checkImmutable(field JField, value any) error {
+	if m.IsNew() || !IsImmutable(field) {
+		return nil
+	}
+
+	if valuesEqual(field, m.originalRecord[field.Name()], value) {
+		return nil
+	}
+
+	return &ImmutableFieldError{Schema: m.Schema().Name(), Field: field.Name()}
+}
+
+// checkRequired rejects a missing value for a field required in the
+// scenarios.
+func (m *mongoRecord) checkRequired(field JField, value any, scenarios ...Scenario) error {
+	if value != nil || !IsRequiredIn(field, scenarios...) {
+		return nil
+	}
+	return &RequiredFieldError{Schema: m.Schema().Name(), Field: field.Name()}
 }
 
 // Value implements JRecord.
@@ -184,6 +767,9 @@ func (m *mongoRecord) Value(field JField) (any, bool) {
 
 	val, ok := m.record[field.Name()]
 	if ok {
+		if isUnset(val) {
+			return nil, false
+		}
 		return val, true
 	}
 
@@ -193,6 +779,14 @@ func (m *mongoRecord) Value(field JField) (any, bool) {
 		return val, true
 	}
 
+	// Composite values are spread over several keys in the stored document
+	if composite, ok := field.Type().(CompositeFieldType); ok {
+		val, err := composite.Scan(context.Background(), field, m.originalRecord)
+		if err == nil && val != nil {
+			return val, true
+		}
+	}
+
 	// If the value is not found in either record, return nil and false
 	return nil, false
 }
@@ -204,13 +798,54 @@ func (m *mongoRecord) convertToBSON(ctx context.Context, record map[string]any)
 		if ok {
 			err := field.Type().SetValue(ctx, field, val, bsonRecord)
 			if err != nil {
-				log.Error().Err(err).Str("field", field.Name()).Msg("failed to set value in BSON record")
+				LoggerFrom(ctx).Error().Err(err).Str("field", field.Name()).Msg("failed to set value in BSON record")
 				return nil, err
 			}
 
 		}
 	}
-	return bsonRecord, nil
+
+	encoded, err := encodeDocument(ctx, m.Schema(), bsonRecord)
+	if err != nil {
+		LoggerFrom(ctx).Error().Err(err).Msg("jpack: failed to encode record")
+		return nil, err
+	}
+	return encoded, nil
+}
+
+// ToBSON implements JRecord.
+func (m *mongoRecord) ToBSON(ctx context.Context) (bson.M, error) {
+	values := make(map[string]any)
+	for _, field := range m.Schema().Fields() {
+		if value, ok := m.Value(field); ok {
+			values[field.Name()] = value
+		}
+	}
+
+	doc, err := m.convertToBSON(ctx, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stored documents keep the primary key in _id as an ObjectID
+	if pkField, ok := PK(m.Schema()); ok && pkField.Name() != defaultMongoPK {
+		if objID, err := m.objectID(); err == nil {
+			delete(doc, pkField.Name())
+			doc[defaultMongoPK] = objID
+		}
+	}
+
+	return doc, nil
+}
+
+// RecordFromBSON builds a record of the schema from a stored document, the
+// reverse of JRecord.ToBSON.
+func RecordFromBSON(schema JSchema, doc bson.M) (JRecord, error) {
+	record := NewMongoRecord(schema)
+	if err := record.loadDocument(context.Background(), maps.Clone(doc)); err != nil {
+		return nil, err
+	}
+	return record, nil
 }
 
 var _ JRecord = &mongoRecord{}
@@ -220,9 +855,91 @@ func NewMongoRecord(schema JSchema) *mongoRecord {
 		schema:         schema,
 		originalRecord: make(map[string]any),
 		record:         make(map[string]any),
+		renamedKeys:    make(map[string]string),
 	}
 }
 
+// loadDocument fills the original record from a stored document.
+func (m *mongoRecord) loadDocument(ctx context.Context, doc bson.M) error {
+	if profile, ok := HydrationProfileFrom(ctx); ok {
+		start := time.Now()
+		defer func() { profile.observeRecord(time.Since(start)) }()
+	}
+
+	doc, err := decodeDocument(ctx, m.Schema(), doc)
+	if err != nil {
+		LoggerFrom(ctx).Error().Err(err).Msg("jpack: failed to decode stored document")
+		return err
+	}
+
+	// Convert ObjectID to string for the id field
+	if id, ok := doc["_id"].(bson.ObjectID); ok {
+		if pkField, ok := PK(m.Schema()); ok {
+			m.originalRecord[pkField.Name()] = id.Hex()
+		}
+	}
+
+	// Convert other fields
+	for key, value := range doc {
+		if key == lineageKey {
+			if err := m.loadLineage(value); err != nil {
+				return err
+			}
+			continue
+		}
+		if key != "_id" && key != treeIDKey && key != idempotencyKeyField {
+			m.originalRecord[key] = value
+		}
+	}
+
+	// Expose values stored under a previous field name under the current one
+	for _, field := range m.Schema().Fields() {
+		if _, ok := m.originalRecord[field.Name()]; ok {
+			continue
+		}
+
+		for _, alias := range FieldAliases(field) {
+			if value, ok := m.originalRecord[alias]; ok {
+				m.originalRecord[field.Name()] = value
+				delete(m.originalRecord, alias)
+				m.renamedKeys[alias] = field.Name()
+				break
+			}
+		}
+	}
+
+	if err := m.applyUnknownFieldPolicy(); err != nil {
+		return err
+	}
+
+	if err := m.decryptFields(ctx); err != nil {
+		return err
+	}
+
+	// Compressed text is expanded when loaded
+	for _, field := range m.Schema().Fields() {
+		if _, ok := baseType(field.Type()).(*Text); !ok {
+			continue
+		}
+		if _, ok := encryptedEnvelope(m.originalRecord[field.Name()]); ok {
+			continue
+		}
+		value, err := scanField(ctx, field, m.originalRecord)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name(), err)
+		}
+		if value != nil {
+			m.originalRecord[field.Name()] = value
+		}
+	}
+
+	if view, ok := m.Schema().(*ViewSchema); ok {
+		view.compute(m)
+	}
+
+	return nil
+}
+
 // mongoQuery implements the Query interface for MongoDB
 type mongoQuery struct {
 	schema     JSchema
@@ -236,22 +953,53 @@ type mongoQuery struct {
 	limit      *int64
 	offset     *int64
 	withRefs   map[string]func(JSchema, Query) Query
+
+	// Result caching, enabled by Cached
+	cacheTTL  time.Duration
+	cacheTags []string
+
+	// err is a failed InQuery subquery, returned when the query runs
+	err error
 }
 
 // NewMongoQuery creates a new MongoDB query for the given schema
 func NewMongoQuery(ctx context.Context, schema JSchema) Query {
-	db := MustConn(ctx)
-	collection := db.Collection(schema.Name())
-
-	return &mongoQuery{
+	q := &mongoQuery{
 		schema:     schema,
 		ctx:        ctx,
-		collection: collection,
+		collection: collection(ctx, schema),
 		projection: bson.M{},
 		where:      []bson.M{},
 		orderBy:    bson.D{},
 		withRefs:   make(map[string]func(JSchema, Query) Query),
 	}
+	// Views only fetch their own fields
+	if view, ok := schema.(*ViewSchema); ok {
+		q.projection["_id"] = 1
+		for _, key := range view.projection() {
+			q.projection[key] = 1
+		}
+	}
+	return q
+}
+
+// FindByID returns the record of the schema with the primary key, or
+// ErrNotFound. Like NewQuery, it reads MongoDB when there is a database for
+// the schema, else the context's SQLite store.
+func FindByID(ctx context.Context, schema JSchema, id string) (JRecord, error) {
+	if _, ok := databaseFor(ctx, schema); !ok {
+		if _, ok := SQLiteFrom(ctx); ok {
+			pk, ok := PK(schema)
+			if !ok {
+				return nil, errors.New("no primary key found in schema")
+			}
+			return NewSQLiteQuery(ctx, schema).Where(Eq(pk, id)).First()
+		}
+	}
+
+	q := NewMongoQuery(ctx, schema).(*mongoQuery)
+	q.where = append(q.where, idsFilter([]string{id}))
+	return q.First()
 }
 
 // Schema implements Query
@@ -266,9 +1014,23 @@ func (q *mongoQuery) Select(fields ...JField) Query {
 	// Always include _id for MongoDB
 	projection["_id"] = 1
 
+	view, isView := q.schema.(*ViewSchema)
 	for _, field := range fields {
-		if field.Schema().Name() == q.schema.Name() {
-			projection[field.Name()] = 1
+		if isView && view.IsComputed(field.Name()) {
+			continue
+		}
+		if sameStorage(field.Schema(), q.schema) {
+			for _, key := range storageKeys(field) {
+				projection[key] = 1
+			}
+		}
+	}
+	// Computed fields need their dependencies
+	if isView {
+		for _, field := range view.dependsOn {
+			for _, key := range storageKeys(field) {
+				projection[key] = 1
+			}
 		}
 	}
 
@@ -284,6 +1046,11 @@ func (q *mongoQuery) With(ref JRef, fn func(JSchema, Query) Query) Query {
 
 // Where implements Query
 func (q *mongoQuery) Where(filter Filter) Query {
+	if err := resolveSubqueries(filter); err != nil {
+		q.err = err
+		return q
+	}
+
 	// Convert the filter to MongoDB BSON format using the resolver
 	mongoFilter := ResolveFilter(filter)
 	if mongoFilter != nil {
@@ -297,9 +1064,11 @@ func (q *mongoQuery) OrderBy(fields ...JField) Query {
 	orderBy := bson.D{}
 
 	for _, field := range fields {
-		if field.Schema().Name() == q.schema.Name() {
+		if sameStorage(field.Schema(), q.schema) {
 			// Default to ascending order
-			orderBy = append(orderBy, bson.E{Key: field.Name(), Value: 1})
+			for _, key := range storageKeys(field) {
+				orderBy = append(orderBy, bson.E{Key: key, Value: 1})
+			}
 		}
 	}
 
@@ -307,6 +1076,13 @@ func (q *mongoQuery) OrderBy(fields ...JField) Query {
 	return q
 }
 
+// Cached implements Query
+func (q *mongoQuery) Cached(ttl time.Duration, tags ...string) Query {
+	q.cacheTTL = ttl
+	q.cacheTags = tags
+	return q
+}
+
 // Limit implements Query
 func (q *mongoQuery) Limit(limit int) Query {
 	limit64 := int64(limit)
@@ -321,15 +1097,28 @@ func (q *mongoQuery) Offset(offset int) Query {
 	return q
 }
 
-// Execute implements Query
-func (q *mongoQuery) Execute() ([]JRecord, error) {
-	// Build the filter
-	filter := bson.M{}
-	if len(q.where) > 0 {
-		filter = bson.M{"$and": q.where}
+// filter combines the where clauses with the mandatory filters of the schema's policies.
+func (q *mongoQuery) filter() bson.M {
+	where := append([]bson.M{}, q.where...)
+	for _, policy := range PoliciesOf(q.schema) {
+		if policyFilter := ResolveFilter(policy.QueryFilter(q.ctx, q.schema)); policyFilter != nil {
+			where = append(where, policyFilter)
+		}
 	}
 
-	// Build options
+	if len(where) == 0 {
+		return bson.M{}
+	}
+	return bson.M{"$and": where}
+}
+
+// BucketBy implements Query
+func (q *mongoQuery) BucketBy(field JField, interval Interval) *BucketQuery {
+	return TimeBuckets(q, field, interval)
+}
+
+// findOptions returns the options of the query's find.
+func (q *mongoQuery) findOptions() *options.FindOptionsBuilder {
 	opts := options.Find()
 
 	if len(q.projection) > 0 {
@@ -348,38 +1137,62 @@ func (q *mongoQuery) Execute() ([]JRecord, error) {
 		opts.SetSkip(*q.offset)
 	}
 
-	// Execute the query
-	cursor, err := q.collection.Find(q.ctx, filter, opts)
-	if err != nil {
-		return nil, err
+	return opts
+}
+
+// Execute implements Query
+func (q *mongoQuery) Execute() ([]JRecord, error) {
+	if q.err != nil {
+		return nil, q.err
 	}
-	defer cursor.Close(q.ctx)
 
-	var records []JRecord
+	// Build the filter
+	filter := q.filter()
+	opts := q.findOptions()
 
-	for cursor.Next(q.ctx) {
-		var doc bson.M
-		if err := cursor.Decode(&doc); err != nil {
+	// The main find leaves a share of the deadline to the eager loads
+	share := 1.0
+	if len(q.withRefs) > 0 {
+		share = mainQueryShare(q.ctx)
+	}
+	findCtx, cancelFind, budget := stageBudget(q.ctx, share)
+	defer cancelFind()
+
+	// Execute the query
+	docs, err := cachedResult(q, "find", filter, func() (docs []bson.M, err error) {
+		start := time.Now()
+		defer func() {
+			tapQuery(q.ctx, q.operation("find", filter, true, int64(len(docs)), err), start)
+		}()
+
+		ctx, cancel := operationContext(findCtx)
+		defer cancel()
+
+		cursor, err := q.collection.Find(ctx, filter, opts)
+		if err != nil {
 			return nil, err
 		}
+		defer cursor.Close(context.WithoutCancel(ctx))
 
-		// Convert BSON document to mongoRecord
-		record := NewMongoRecord(q.schema)
-
-		// Convert ObjectID to string for the id field
-		if id, ok := doc["_id"].(bson.ObjectID); ok {
-			pkField, _ := PK(q.Schema())
-			record.originalRecord[pkField.Name()] = id.Hex()
+		if err := cursor.All(ctx, &docs); err != nil {
+			return nil, err
 		}
+		return docs, nil
+	})
+	if err != nil {
+		return nil, stageError(q.schema, "find", budget, err)
+	}
 
-		// Convert other fields
-		for key, value := range doc {
-			if key != "_id" {
-				record.originalRecord[key] = value
-			}
+	var records []JRecord
+
+	for _, doc := range docs {
+		// Convert BSON document to mongoRecord
+		record := NewMongoRecord(q.schema)
+		if err := record.loadDocument(q.ctx, maps.Clone(doc)); err != nil {
+			return nil, err
 		}
 
-		records = append(records, record)
+		records = append(records, q.track(record))
 	}
 
 	// Handle eager loading
@@ -394,11 +1207,24 @@ func (q *mongoQuery) Execute() ([]JRecord, error) {
 
 // First implements Query
 func (q *mongoQuery) First() (JRecord, error) {
-	// Build the filter
-	filter := bson.M{}
-	if len(q.where) > 0 {
-		filter = bson.M{"$and": q.where}
+	record, err := q.FirstOrNil()
+	if err != nil {
+		return nil, err
 	}
+	if record == nil {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// FirstOrNil implements Query
+func (q *mongoQuery) FirstOrNil() (JRecord, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	// Build the filter
+	filter := q.filter()
 
 	// Build options
 	opts := options.FindOne()
@@ -416,30 +1242,39 @@ func (q *mongoQuery) First() (JRecord, error) {
 	}
 
 	// Execute the query
-	var doc bson.M
-	err := q.collection.FindOne(q.ctx, filter, opts).Decode(&doc)
-	if err != nil {
+	doc, err := cachedResult(q, "first", filter, func() (bson.M, error) {
+		start := time.Now()
+		ctx, cancel := operationContext(q.ctx)
+		defer cancel()
+
+		var doc bson.M
+		err := q.collection.FindOne(ctx, filter, opts).Decode(&doc)
 		if err == mongo.ErrNoDocuments {
+			tapQuery(q.ctx, q.operation("findOne", filter, false, 0, nil), start)
 			return nil, nil
 		}
+
+		var found int64
+		if err == nil {
+			found = 1
+		}
+		tapQuery(q.ctx, q.operation("findOne", filter, false, found, err), start)
+		return doc, err
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Convert BSON document to mongoRecord
-	record := NewMongoRecord(q.schema)
-
-	// Convert ObjectID to string for the id field
-	if id, ok := doc["_id"].(bson.ObjectID); ok {
-		pkField, _ := PK(q.Schema())
-		record.originalRecord[pkField.Name()] = id.Hex()
+	if doc == nil {
+		return nil, nil
 	}
 
-	// Convert other fields
-	for key, value := range doc {
-		if key != "_id" {
-			record.originalRecord[key] = value
-		}
+	// Convert BSON document to mongoRecord
+	loaded := NewMongoRecord(q.schema)
+	if err := loaded.loadDocument(q.ctx, maps.Clone(doc)); err != nil {
+		return nil, err
 	}
+	record := q.track(loaded)
 
 	// Handle eager loading
 	if len(q.withRefs) > 0 {
@@ -451,16 +1286,60 @@ func (q *mongoQuery) First() (JRecord, error) {
 	return record, nil
 }
 
+// operation describes a find for QueryTap; withLimit is unset for findOne.
+func (q *mongoQuery) operation(name string, filter bson.M, withLimit bool, count int64, err error) QueryOperation {
+	opts := bson.M{}
+	if len(q.projection) > 0 {
+		opts["projection"] = q.projection
+	}
+	if len(q.orderBy) > 0 {
+		opts["sort"] = q.orderBy
+	}
+	if q.limit != nil && withLimit {
+		opts["limit"] = *q.limit
+	}
+	if q.offset != nil {
+		opts["skip"] = *q.offset
+	}
+
+	return QueryOperation{
+		Collection: q.collection.Name(),
+		Operation:  name,
+		Filter:     filter,
+		Options:    opts,
+		Count:      count,
+		Err:        err,
+	}
+}
+
+// track returns the instance already loaded in this request for the same
+// record when the context carries an identity map.
+func (q *mongoQuery) track(record *mongoRecord) JRecord {
+	if identityMap, ok := IdentityMapFrom(q.ctx); ok {
+		return identityMap.Track(record)
+	}
+	return record
+}
+
 // Count implements Query
 func (q *mongoQuery) Count() (int, error) {
-	// Build the filter
-	filter := bson.M{}
-	if len(q.where) > 0 {
-		filter = bson.M{"$and": q.where}
+	if q.err != nil {
+		return 0, q.err
 	}
 
+	// Build the filter
+	filter := q.filter()
+
 	// Execute the count query
-	count, err := q.collection.CountDocuments(q.ctx, filter)
+	count, err := cachedResult(q, "count", filter, func() (int64, error) {
+		ctx, cancel := operationContext(q.ctx)
+		defer cancel()
+
+		start := time.Now()
+		count, err := q.collection.CountDocuments(ctx, filter)
+		tapQuery(q.ctx, QueryOperation{Collection: q.collection.Name(), Operation: "count", Filter: filter, Count: count, Err: err}, start)
+		return count, err
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -470,53 +1349,64 @@ func (q *mongoQuery) Count() (int, error) {
 
 // loadReferences handles eager loading of referenced records
 func (q *mongoQuery) loadReferences(records []JRecord) error {
+	loaded := 0
 	for refName, refFn := range q.withRefs {
-		// Find the reference field
-		refField, ok := q.schema.Field(refName)
-		if !ok {
-			continue
+		// Each ref gets an equal share of what is left of the deadline
+		ctx, cancel, budget := stageBudget(q.ctx, 1/float64(len(q.withRefs)-loaded))
+		loaded++
+		err := q.loadReference(ctx, records, refName, refFn)
+		cancel()
+		if err != nil {
+			return stageError(q.schema, "ref "+refName, budget, err)
 		}
+	}
+	return nil
+}
 
-		ref, ok := refField.(JRef)
-		if !ok {
-			continue
-		}
+// loadReference attaches the records of one ref to the records.
+func (q *mongoQuery) loadReference(ctx context.Context, records []JRecord, refName string, refFn func(JSchema, Query) Query) error {
+	// Find the reference field
+	refField, ok := q.schema.Field(refName)
+	if !ok {
+		return nil
+	}
 
-		// Create a query for the referenced schema
-		refQuery := NewMongoQuery(q.ctx, ref.RelSchema())
+	ref, ok := refField.(JRef)
+	if !ok {
+		return nil
+	}
 
-		// Apply the custom function to the reference query
-		refQuery = refFn(ref.RelSchema(), refQuery)
+	// Create a query for the referenced schema
+	refQuery := NewMongoQuery(ctx, ref.RelSchema())
 
-		// Execute the reference query
-		refRecords, err := refQuery.Execute()
-		if err != nil {
-			return err
-		}
+	// Apply the custom function to the reference query
+	refQuery = refFn(ref.RelSchema(), refQuery)
 
-		// Create a map of reference records by ID for quick lookup
-		refMap := make(map[string]JRecord)
-		for _, refRecord := range refRecords {
-			if id, ok := refRecord.Value(refField); ok {
-				if idStr, ok := id.(string); ok {
-					refMap[idStr] = refRecord
-				}
-			}
+	// Execute the reference query
+	refRecords, err := refQuery.Execute()
+	if err != nil {
+		return err
+	}
+
+	// Create a map of reference records by ID for quick lookup
+	refMap := make(map[string]JRecord, len(refRecords))
+	for _, refRecord := range refRecords {
+		if id, ok := recordID(refRecord); ok {
+			refMap[id] = refRecord
 		}
+	}
 
-		// Attach reference records to the main records
-		for _, record := range records {
-			if refID, ok := record.Value(refField); ok {
-				if refIDStr, ok := refID.(string); ok {
-					if refRecord, exists := refMap[refIDStr]; exists {
-						// Set the reference record in the main record
-						record.SetValue(refField, refRecord)
-					}
+	// Attach reference records to the main records
+	for _, record := range records {
+		if refID, ok := record.Value(refField); ok {
+			if refIDStr, ok := refID.(string); ok {
+				if refRecord, exists := refMap[refIDStr]; exists {
+					// Set the reference record in the main record
+					record.SetValue(refField, refRecord)
 				}
 			}
 		}
 	}
-
 	return nil
 }
 
@@ -40,7 +40,7 @@ func Test_mongoRecord_DirtyKeys(t *testing.T) {
 
 }
 
-func mustField(t *testing.T, schema JSchema, name string) JField {
+func mustField(t testing.TB, schema JSchema, name string) JField {
 	t.Helper()
 	field, ok := schema.Field(name)
 	assert.True(t, ok, "Field %s should exist in schema", name)
@@ -59,7 +59,7 @@ func Test_mongoRecord_Save(t *testing.T) {
 	}()
 
 	client.Database("jpack_test").Drop(context.TODO())
-	ctx := context.WithValue(context.Background(), Conn, client.Database("jpack_test"))
+	ctx := WithDatabase(context.Background(), client.Database("jpack_test"))
 	m := NewMongoRecord(userSchema)
 	t.Run("Create Record", func(t *testing.T) {
 		m.SetValue(mustField(t, userSchema, "first_name"), "Jhon")
@@ -76,9 +76,50 @@ func Test_mongoRecord_Save(t *testing.T) {
 	})
 
 	t.Run("Update Record", func(t *testing.T) {
+		changes := 0
+		m.OnChange(func(field JField, old, new any) { changes++ })
+
 		m.SetValue(mustField(t, userSchema, "email"), "Jhon@gmail.com")
 		err = m.Save(ctx)
 		assert.NoError(t, err, "Failed to update record in MongoDB")
+
+		m.SetValue(mustField(t, userSchema, "email"), "Jhon@example.org")
+		assert.Equal(t, 1, changes, "Observers should be dropped after Save")
+	})
+
+	t.Run("Save with ReturnDocument", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		record.SetValue(mustField(t, userSchema, "first_name"), "Ada")
+		record.SetValue(mustField(t, userSchema, "age"), "36")
+		assert.NoError(t, record.Save(ctx, ReturnDocument()))
+		assert.False(t, record.IsModified())
+
+		age, ok := record.Value(mustField(t, userSchema, "age"))
+		assert.True(t, ok)
+		assert.EqualValues(t, 36, age, "the stored number, not the string input")
+
+		record.SetValue(mustField(t, userSchema, "last_name"), "Lovelace")
+		assert.NoError(t, record.Save(ctx, ReturnDocument()))
+		assert.Empty(t, record.DirtyKeys())
+	})
+
+	t.Run("Save with an idempotency key", func(t *testing.T) {
+		assert.NoError(t, IdempotencyIndexStep(userSchema).Apply(ctx))
+
+		first := NewMongoRecord(userSchema)
+		first.SetValue(mustField(t, userSchema, "first_name"), "Grace")
+		assert.NoError(t, first.Save(ctx, WithIdempotencyKey("signup-42")))
+
+		retry := NewMongoRecord(userSchema)
+		retry.SetValue(mustField(t, userSchema, "first_name"), "Grace (retried)")
+		assert.NoError(t, retry.Save(ctx, WithIdempotencyKey("signup-42")))
+		assert.False(t, retry.IsNew())
+
+		firstID, _ := recordID(first)
+		retryID, _ := recordID(retry)
+		assert.Equal(t, firstID, retryID, "the retry returns the inserted record")
+		name, _ := retry.String(mustField(t, userSchema, "first_name"))
+		assert.Equal(t, "Grace", name)
 	})
 
 	t.Run("Save record with ref", func(t *testing.T) {
@@ -96,6 +137,61 @@ func Test_mongoRecord_Save(t *testing.T) {
 		assert.NoError(t, err, "Failed to save post record with ref to user")
 	})
 
+	t.Run("Save with retry", func(t *testing.T) {
+		id, _ := m.Value(mustField(t, userSchema, "id"))
+		firstName := mustField(t, userSchema, "first_name")
+		lastName := mustField(t, userSchema, "last_name")
+
+		stale, err := FindByID(ctx, userSchema, id.(string))
+		assert.NoError(t, err)
+		other, err := FindByID(ctx, userSchema, id.(string))
+		assert.NoError(t, err)
+
+		other.SetValue(lastName, "Smith")
+		assert.NoError(t, other.Save(ctx))
+
+		stale.SetValue(firstName, "John")
+		assert.ErrorIs(t, stale.SaveWithRetry(ctx, 1, nil), ErrPreconditionFailed)
+
+		conflicts := 0
+		err = stale.SaveWithRetry(ctx, 3, func(local, remote JRecord) (JRecord, error) {
+			conflicts++
+			return local, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, conflicts)
+
+		saved, err := FindByID(ctx, userSchema, id.(string))
+		assert.NoError(t, err)
+		first, _ := saved.Value(firstName)
+		last, _ := saved.Value(lastName)
+		assert.Equal(t, "John", first)
+		assert.Equal(t, "Smith", last, "the concurrent change is kept")
+	})
+
+	t.Run("Save with retry twice on one record", func(t *testing.T) {
+		id, _ := m.Value(mustField(t, userSchema, "id"))
+		firstName := mustField(t, userSchema, "first_name")
+
+		record, err := FindByID(ctx, userSchema, id.(string))
+		assert.NoError(t, err)
+
+		conflicts := 0
+		onConflict := func(local, remote JRecord) (JRecord, error) {
+			conflicts++
+			return local, nil
+		}
+		record.SetValue(firstName, "Johnny")
+		assert.NoError(t, record.SaveWithRetry(ctx, 3, onConflict))
+		record.SetValue(firstName, "Jon")
+		assert.NoError(t, record.SaveWithRetry(ctx, 1, nil), "the record's own write is no conflict")
+		assert.Zero(t, conflicts)
+
+		saved, err := FindByID(ctx, userSchema, id.(string))
+		assert.NoError(t, err)
+		first, _ := saved.Value(firstName)
+		assert.Equal(t, "Jon", first)
+	})
 }
 
 func TestMongoQuery(t *testing.T) {
@@ -110,7 +206,7 @@ func TestMongoQuery(t *testing.T) {
 	}()
 
 	client.Database("jpack_test").Drop(context.TODO())
-	ctx := context.WithValue(context.Background(), Conn, client.Database("jpack_test"))
+	ctx := WithDatabase(context.Background(), client.Database("jpack_test"))
 
 	// Create some test data
 	t.Run("Setup Test Data", func(t *testing.T) {
@@ -152,6 +248,55 @@ func TestMongoQuery(t *testing.T) {
 		assert.NotEmpty(t, firstName, "First name should not be empty")
 	})
 
+	t.Run("Test Query First without match", func(t *testing.T) {
+		query := NewMongoQuery(ctx, userSchema).Where(Eq(mustField(t, userSchema, "first_name"), "Nobody"))
+		record, err := query.First()
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.Nil(t, record)
+
+		record, err = query.FirstOrNil()
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+
+		_, err = FindByID(ctx, userSchema, bson.NewObjectID().Hex())
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Test FindByID", func(t *testing.T) {
+		first, err := NewMongoQuery(ctx, userSchema).First()
+		assert.NoError(t, err)
+		id, _ := recordID(first)
+
+		record, err := FindByID(ctx, userSchema, id)
+		assert.NoError(t, err)
+		foundID, _ := recordID(record)
+		assert.Equal(t, id, foundID)
+	})
+
+	t.Run("Test Query FirstOrCreate", func(t *testing.T) {
+		firstName := mustField(t, userSchema, "first_name")
+		lastName := mustField(t, userSchema, "last_name")
+		query := NewMongoQuery(ctx, userSchema).Where(Eq(firstName, "Ada"))
+
+		created, inserted, err := query.FirstOrCreate(ctx, map[JField]any{firstName: "Ignored", lastName: "Lovelace"})
+		assert.NoError(t, err)
+		assert.True(t, inserted)
+		assert.False(t, created.IsNew())
+		name, _ := created.Value(firstName)
+		assert.Equal(t, "Ada", name, "equality conditions win over defaults")
+		surname, _ := created.Value(lastName)
+		assert.Equal(t, "Lovelace", surname)
+
+		found, inserted, err := GetOrInsert(ctx, userSchema, map[JField]any{firstName: "Ada"}, nil)
+		assert.NoError(t, err)
+		assert.False(t, inserted)
+		createdID, _ := recordID(created)
+		foundID, _ := recordID(found)
+		assert.Equal(t, createdID, foundID)
+
+		assert.NoError(t, found.Delete(ctx))
+	})
+
 	t.Run("Test Query Count", func(t *testing.T) {
 		query := NewMongoQuery(ctx, userSchema)
 		count, err := query.Count()
@@ -0,0 +1,65 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type valueChange struct {
+	field    string
+	old, new any
+}
+
+func TestRecordOnChange(t *testing.T) {
+	firstName := mustField(t, userSchema, "first_name")
+	email := mustField(t, userSchema, "email")
+
+	observe := func(record JRecord) *[]valueChange {
+		var changes []valueChange
+		record.OnChange(func(field JField, old, new any) {
+			changes = append(changes, valueChange{field.Name(), old, new})
+		})
+		return &changes
+	}
+
+	t.Run("SetValue reports changes", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		record.originalRecord = map[string]any{"id": "1", "first_name": "John"}
+		changes := observe(record)
+
+		assert.NoError(t, record.SetValue(firstName, "Jane"))
+		assert.NoError(t, record.SetValue(firstName, "Jane"), "setting the same value is not a change")
+		assert.NoError(t, record.SetValue(email, "jane@example.com"))
+
+		assert.Equal(t, []valueChange{
+			{"first_name", "John", "Jane"},
+			{"email", nil, "jane@example.com"},
+		}, *changes)
+	})
+
+	t.Run("rejected values are not reported", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		changes := observe(record)
+
+		assert.Error(t, record.SetValue(mustField(t, userSchema, "age"), "old"))
+		assert.Empty(t, *changes)
+	})
+
+	t.Run("SetValuesFromJSON reports changes once every key applied", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		changes := observe(record)
+
+		err := record.SetValuesFromJSON(context.Background(), []byte(`{"first_name": "Jane", "age": "old"}`), RejectUnknownKeys)
+		assert.Error(t, err)
+		assert.Empty(t, *changes)
+
+		err = record.SetValuesFromJSON(context.Background(), []byte(`{"first_name": "Jane", "age": 30}`), RejectUnknownKeys)
+		assert.NoError(t, err)
+		assert.Equal(t, []valueChange{
+			{"age", nil, int64(30)},
+			{"first_name", nil, "Jane"},
+		}, *changes)
+	})
+}
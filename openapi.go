@@ -0,0 +1,107 @@
+package jpack
+
+import (
+	"context"
+)
+
+// OpenAPIFieldType is implemented by field types that describe their own
+// OpenAPI schema, e.g. a UUID type returning {"type": "string", "format": "uuid"}
+// or a decimal type returning {"type": "string", "format": "decimal"}.
+type OpenAPIFieldType interface {
+	OpenAPISchema() map[string]any
+}
+
+// ToOpenAPI returns OpenAPI 3.1 component schemas for the schemas, or for every
+// registered schema when none are given, keyed by schema name. The result is
+// meant to be embedded under components.schemas of a service's API spec.
+func ToOpenAPI(schemas ...JSchema) map[string]any {
+	if len(schemas) == 0 {
+		schemas = RegisteredSchemas()
+	}
+
+	ctx := context.Background()
+	components := make(map[string]any, len(schemas))
+	for _, schema := range schemas {
+		properties := make(map[string]any, len(schema.Fields()))
+		var required []string
+		for _, field := range schema.Fields() {
+			properties[field.Name()] = openAPIField(ctx, field)
+			if IsRequired(field) {
+				required = append(required, field.Name())
+			}
+		}
+
+		component := map[string]any{
+			"type":       "object",
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			component["required"] = required
+		}
+		components[schema.Name()] = component
+	}
+
+	return components
+}
+
+func openAPIField(ctx context.Context, field JField) map[string]any {
+	property := openAPIType(ctx, field.Type())
+
+	if min, max, ok := FieldBounds(field.Type()); ok {
+		property["minimum"] = min
+		property["maximum"] = max
+	}
+
+	if ref, ok := field.(JRef); ok && ref.RelSchema() != nil {
+		property["description"] = "References " + ref.RelSchema().Name()
+	}
+
+	switch defaultValue := field.Default().(type) {
+	case nil, serverNow, DefaultFunc, func(context.Context) any:
+	default:
+		property["default"] = defaultValue
+	}
+
+	return property
+}
+
+// openAPIType maps a field type to an OpenAPI schema object.
+func openAPIType(ctx context.Context, fType JFieldType) map[string]any {
+	switch t := baseType(fType).(type) {
+	case OpenAPIFieldType:
+		return t.OpenAPISchema()
+	case *String:
+		return map[string]any{"type": "string"}
+	case *Text:
+		if t.MaxSize > 0 {
+			return map[string]any{"type": "string", "maxLength": t.MaxSize}
+		}
+		return map[string]any{"type": "string"}
+	case *Number:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case *Boolean:
+		return map[string]any{"type": "boolean"}
+	case *DateTime:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case *Ref:
+		return map[string]any{"type": "string"}
+	case *Options:
+		property := map[string]any{"type": "string"}
+		if options, err := t.GetAllOptions(ctx); err == nil {
+			enum := make([]any, 0, len(options))
+			for _, option := range options {
+				enum = append(enum, option.UniqueName)
+			}
+			property["enum"] = enum
+		}
+		return property
+	case *Composite:
+		properties := make(map[string]any, len(t.Parts))
+		for _, part := range t.Parts {
+			properties[part.Name] = openAPIType(ctx, part.Type)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		return map[string]any{}
+	}
+}
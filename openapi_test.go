@@ -0,0 +1,48 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type uuidType struct {
+	String
+}
+
+func (u *uuidType) OpenAPISchema() map[string]any {
+	return map[string]any{"type": "string", "format": "uuid"}
+}
+
+func TestToOpenAPI(t *testing.T) {
+	assert := assert.New(t)
+
+	status := NewOptions(NewInMemoryOptionService([]Option{
+		{UniqueName: "draft", DisplayName: "Draft"},
+		{UniqueName: "published", DisplayName: "Published"},
+	}))
+
+	userSchema := NewSchema("openapi_user").Field("id", &String{}).Build()
+	postSchema := NewSchema("openapi_post").
+		Field("id", &uuidType{}).
+		Field("views", &Number{}).
+		FieldWithDefault("status", status, "draft").
+		FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+		Ref("author", userSchema).
+		Build()
+
+	components := ToOpenAPI(postSchema)
+	post := components["openapi_post"].(map[string]any)
+	assert.Equal("object", post["type"])
+
+	properties := post["properties"].(map[string]any)
+	assert.Equal(map[string]any{"type": "string", "format": "uuid"}, properties["id"])
+	assert.Equal(map[string]any{"type": "integer", "format": "int64"}, properties["views"])
+	assert.Equal(map[string]any{"type": "string", "format": "date-time"}, properties["created_at"])
+	assert.Equal(map[string]any{
+		"type":    "string",
+		"enum":    []any{"draft", "published"},
+		"default": "draft",
+	}, properties["status"])
+	assert.Equal("References openapi_user", properties["author"].(map[string]any)["description"])
+}
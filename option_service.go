@@ -0,0 +1,211 @@
+package jpack
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// OptionServiceOption decorates an OptionService.
+type OptionServiceOption func(OptionService) OptionService
+
+// WrapOptionService decorates svc with opts. Each option wraps the result of
+// the previous ones, so the last option is the outermost, e.g.
+//
+//	WrapOptionService(remote, WithOptionRateLimit(10, 5), WithOptionSingleflight(), WithOptionCache(time.Minute))
+//
+// serves from the cache first, collapses concurrent misses into one call and
+// rate limits the calls that reach remote.
+func WrapOptionService(svc OptionService, opts ...OptionServiceOption) OptionService {
+	for _, opt := range opts {
+		svc = opt(svc)
+	}
+	return svc
+}
+
+// WithOptionCache caches the options for ttl. Errors are not cached.
+func WithOptionCache(ttl time.Duration) OptionServiceOption {
+	return func(svc OptionService) OptionService {
+		return &cachedOptionService{next: svc, ttl: ttl, now: time.Now}
+	}
+}
+
+type cachedOptionService struct {
+	next OptionService
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	options []Option
+	expires time.Time
+}
+
+// GetOptions implements OptionService.
+func (s *cachedOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	s.mu.RLock()
+	options, expires := s.options, s.expires
+	s.mu.RUnlock()
+
+	if options != nil && s.now().Before(expires) {
+		return copyOptions(options), nil
+	}
+
+	options, err := s.next.GetOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.options = copyOptions(options)
+	s.expires = s.now().Add(s.ttl)
+	s.mu.Unlock()
+
+	return options, nil
+}
+
+func copyOptions(options []Option) []Option {
+	result := make([]Option, len(options))
+	copy(result, options)
+	return result
+}
+
+// WithOptionSingleflight shares one in-flight GetOptions call between all
+// concurrent callers.
+func WithOptionSingleflight() OptionServiceOption {
+	return func(svc OptionService) OptionService {
+		return &singleflightOptionService{next: svc}
+	}
+}
+
+type singleflightOptionService struct {
+	next  OptionService
+	group singleflight.Group
+}
+
+// GetOptions implements OptionService. The shared call runs with the context
+// of the caller that started it.
+func (s *singleflightOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	result, err, _ := s.group.Do("options", func() (any, error) {
+		return s.next.GetOptions(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyOptions(result.([]Option)), nil
+}
+
+// WithOptionRateLimit allows perSecond GetOptions calls on average with bursts
+// of up to burst calls. Callers over the limit wait for their turn or until
+// their context is done.
+func WithOptionRateLimit(perSecond float64, burst int) OptionServiceOption {
+	return func(svc OptionService) OptionService {
+		return &rateLimitedOptionService{
+			next:   svc,
+			bucket: &tokenBucket{rate: perSecond, burst: float64(max(burst, 1)), tokens: float64(max(burst, 1))},
+		}
+	}
+}
+
+type rateLimitedOptionService struct {
+	next   OptionService
+	bucket *tokenBucket
+}
+
+// GetOptions implements OptionService.
+func (s *rateLimitedOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	if err := s.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.next.GetOptions(ctx)
+}
+
+// tokenBucket refills rate tokens per second up to burst.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes a token and returns how long to wait until it is available.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+	if b.rate <= 0 {
+		return time.Duration(1<<63 - 1)
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel gives back a reserved token that was not used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.burst, b.tokens+1)
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve(time.Now())
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// OptionServiceMetrics observes the calls made through an instrumented
+// OptionService, e.g. to feed Prometheus histograms.
+type OptionServiceMetrics interface {
+	ObserveGetOptions(ctx context.Context, duration time.Duration, count int, err error)
+}
+
+// WithOptionMetrics reports every GetOptions call to metrics.
+func WithOptionMetrics(metrics OptionServiceMetrics) OptionServiceOption {
+	return func(svc OptionService) OptionService {
+		return &instrumentedOptionService{next: svc, metrics: metrics}
+	}
+}
+
+type instrumentedOptionService struct {
+	next    OptionService
+	metrics OptionServiceMetrics
+}
+
+// GetOptions implements OptionService.
+func (s *instrumentedOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	start := time.Now()
+	options, err := s.next.GetOptions(ctx)
+	s.metrics.ObserveGetOptions(ctx, time.Since(start), len(options), err)
+	return options, err
+}
+
+var (
+	_ OptionService = &cachedOptionService{}
+	_ OptionService = &singleflightOptionService{}
+	_ OptionService = &rateLimitedOptionService{}
+	_ OptionService = &instrumentedOptionService{}
+)
@@ -0,0 +1,143 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingOptionService counts GetOptions calls and can block them.
+type countingOptionService struct {
+	options []Option
+	err     error
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (c *countingOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	c.calls.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.options, c.err
+}
+
+type recordingOptionMetrics struct {
+	counts []int
+	errs   []error
+}
+
+func (r *recordingOptionMetrics) ObserveGetOptions(ctx context.Context, duration time.Duration, count int, err error) {
+	r.counts = append(r.counts, count)
+	r.errs = append(r.errs, err)
+}
+
+func TestWrapOptionService(t *testing.T) {
+	ctx := context.Background()
+	options := []Option{{UniqueName: "active", DisplayName: "Active"}}
+
+	t.Run("cache", func(t *testing.T) {
+		assert := assert.New(t)
+
+		inner := &countingOptionService{options: options}
+		svc := WrapOptionService(inner, WithOptionCache(time.Minute)).(*cachedOptionService)
+		now := time.Now()
+		svc.now = func() time.Time { return now }
+
+		for range 3 {
+			got, err := svc.GetOptions(ctx)
+			assert.NoError(err)
+			assert.Equal(options, got)
+		}
+		assert.EqualValues(1, inner.calls.Load())
+
+		now = now.Add(2 * time.Minute)
+		_, err := svc.GetOptions(ctx)
+		assert.NoError(err)
+		assert.EqualValues(2, inner.calls.Load())
+	})
+
+	t.Run("cache skips errors", func(t *testing.T) {
+		inner := &countingOptionService{err: errors.New("unavailable")}
+		svc := WrapOptionService(inner, WithOptionCache(time.Minute))
+
+		_, err := svc.GetOptions(ctx)
+		assert.Error(t, err)
+		_, err = svc.GetOptions(ctx)
+		assert.Error(t, err)
+		assert.EqualValues(t, 2, inner.calls.Load())
+	})
+
+	t.Run("singleflight", func(t *testing.T) {
+		inner := &countingOptionService{options: options, release: make(chan struct{})}
+		svc := WrapOptionService(inner, WithOptionSingleflight())
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := svc.GetOptions(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, options, got)
+			}()
+		}
+
+		assert.Eventually(t, func() bool { return inner.calls.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(10 * time.Millisecond)
+		close(inner.release)
+		wg.Wait()
+		assert.EqualValues(t, 1, inner.calls.Load())
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		assert := assert.New(t)
+
+		bucket := &tokenBucket{rate: 10, burst: 2, tokens: 2}
+		now := time.Now()
+		assert.Zero(bucket.reserve(now))
+		assert.Zero(bucket.reserve(now))
+		assert.Equal(100*time.Millisecond, bucket.reserve(now))
+
+		// Refilled after the wait
+		assert.Equal(100*time.Millisecond, bucket.reserve(now.Add(100*time.Millisecond)))
+		assert.Zero(bucket.reserve(now.Add(time.Second)))
+
+		inner := &countingOptionService{options: options}
+		svc := WrapOptionService(inner, WithOptionRateLimit(0.001, 1))
+		_, err := svc.GetOptions(ctx)
+		assert.NoError(err)
+
+		timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err = svc.GetOptions(timeout)
+		assert.ErrorIs(err, context.DeadlineExceeded)
+		assert.EqualValues(1, inner.calls.Load())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		metrics := &recordingOptionMetrics{}
+		failure := errors.New("unavailable")
+
+		_, _ = WrapOptionService(&countingOptionService{options: options}, WithOptionMetrics(metrics)).GetOptions(ctx)
+		_, _ = WrapOptionService(&countingOptionService{err: failure}, WithOptionMetrics(metrics)).GetOptions(ctx)
+
+		assert.Equal(t, []int{1, 0}, metrics.counts)
+		assert.Equal(t, []error{nil, failure}, metrics.errs)
+	})
+
+	t.Run("composed", func(t *testing.T) {
+		inner := &countingOptionService{options: options}
+		svc := WrapOptionService(inner, WithOptionRateLimit(100, 1), WithOptionSingleflight(), WithOptionCache(time.Minute))
+
+		field := NewOptions(svc)
+		assert.NoError(t, field.Validate("active"))
+		assert.Error(t, field.Validate("deleted"))
+		assert.EqualValues(t, 1, inner.calls.Load())
+	})
+}
@@ -415,3 +415,38 @@ func TestOptions_GetAllOptions(t *testing.T) {
 		assert.Contains(t, err.Error(), "service error")
 	})
 }
+
+// lookupOptionService fails full scans to prove Options uses point lookups.
+type lookupOptionService struct {
+	*InMemoryOptionService
+}
+
+func (l *lookupOptionService) GetOptions(ctx context.Context) ([]Option, error) {
+	return nil, errors.New("full scan")
+}
+
+func TestOptions_SearchService(t *testing.T) {
+	service := &lookupOptionService{NewInMemoryOptionService([]Option{
+		{UniqueName: "active", DisplayName: "Active"},
+		{UniqueName: "inactive", DisplayName: "Inactive"},
+	})}
+	options := NewOptions(service)
+
+	assert.NoError(t, options.Validate("active"))
+	assert.ErrorContains(t, options.Validate("deleted"), "not in the list of available options")
+
+	name, err := options.GetDisplayName(context.Background(), "inactive")
+	assert.NoError(t, err)
+	assert.Equal(t, "Inactive", name)
+
+	found, err := options.SearchOptions(context.Background(), "act", 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Option{{UniqueName: "inactive", DisplayName: "Inactive"}}, found)
+
+	t.Run("falls back to listing", func(t *testing.T) {
+		options := NewOptions(&mockOptionService{options: []Option{{UniqueName: "a", DisplayName: "Alpha"}, {UniqueName: "b", DisplayName: "Beta"}}})
+		found, err := options.SearchOptions(context.Background(), "BET", 0, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []Option{{UniqueName: "b", DisplayName: "Beta"}}, found)
+	})
+}
@@ -0,0 +1,197 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// OutboxQueue is the job queue holding outbox messages in the jobs collection.
+const OutboxQueue = "jpack_outbox"
+
+var outboxJobs = NewJobQueue(OutboxQueue)
+
+// OutboxIntent is a side effect to run once a write is committed.
+type OutboxIntent struct {
+	Topic   string
+	Payload any
+}
+
+// OutboxHook returns the intents caused by a write to a record of its schema.
+type OutboxHook func(ctx context.Context, record JRecord, op ChangeOperation) ([]OutboxIntent, error)
+
+// OutboxMessage is a dispatched intent.
+type OutboxMessage struct {
+	// ID is stable across retries, so handlers can use it to drop duplicates.
+	ID        string
+	Topic     string
+	Schema    string
+	Operation ChangeOperation
+	RecordID  string
+	Payload   json.RawMessage
+	// Attempt counts deliveries, starting at 1.
+	Attempt int
+}
+
+// Decode unmarshals the message's JSON payload into v.
+func (m OutboxMessage) Decode(v any) error {
+	return json.Unmarshal(m.Payload, v)
+}
+
+type outboxEnvelope struct {
+	Topic     string          `json:"topic"`
+	Schema    string          `json:"schema,omitempty"`
+	Operation ChangeOperation `json:"operation,omitempty"`
+	RecordID  string          `json:"record_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+var (
+	outboxHooksMu sync.RWMutex
+	outboxHooks   = make(map[string][]OutboxHook)
+)
+
+// ErrOutboxNeedsTransaction is returned by Save, Delete and FirstOrCreate of
+// records whose schema has outbox hooks when they run outside a transaction,
+// where the record and its outbox messages couldn't commit together.
+var ErrOutboxNeedsTransaction = errors.New("jpack: writes of a schema with outbox hooks must run in a transaction")
+
+// ErrOutboxNotSupported is returned by writes of records whose schema has
+// outbox hooks when they are queued in a PendingJournal or stored by a
+// SQLiteStore, which can't write the outbox messages with the record.
+var ErrOutboxNotSupported = errors.New("jpack: writes of a schema with outbox hooks can't be queued in a pending journal or stored in SQLite")
+
+// RegisterOutboxHook adds a hook run by every Save and Delete of the schema's
+// records. Its intents are written to the outbox with the same context as the
+// write, so they commit or abort with it; a hook error fails the write. The
+// schema's records must then be written inside a UnitOfWork or transaction,
+// and writes outside one fail with ErrOutboxNeedsTransaction before anything
+// is stored. Writes queued in a PendingJournal or stored by a SQLiteStore fail
+// with ErrOutboxNotSupported.
+func RegisterOutboxHook(schema JSchema, hook OutboxHook) {
+	outboxHooksMu.Lock()
+	defer outboxHooksMu.Unlock()
+
+	outboxHooks[schema.Name()] = append(outboxHooks[schema.Name()], hook)
+}
+
+// hasOutboxHooks reports whether the schema has outbox hooks.
+func hasOutboxHooks(schema JSchema) bool {
+	outboxHooksMu.RLock()
+	defer outboxHooksMu.RUnlock()
+
+	return len(outboxHooks[schema.Name()]) > 0
+}
+
+// checkOutbox refuses a write of the schema's records outside a transaction
+// when the schema has outbox hooks.
+func checkOutbox(ctx context.Context, schema JSchema) error {
+	if hasOutboxHooks(schema) && !inTransaction(ctx) {
+		return ErrOutboxNeedsTransaction
+	}
+	return nil
+}
+
+// refuseOutbox refuses a write of the schema's records that can't write
+// outbox messages, such as queued and SQLite writes, when the schema has
+// outbox hooks.
+func refuseOutbox(schema JSchema) error {
+	if hasOutboxHooks(schema) {
+		return ErrOutboxNotSupported
+	}
+	return nil
+}
+
+// writeOutbox runs the outbox hooks of a written record. Writes check the
+// transaction with checkOutbox first, so an error aborts it with the write.
+func writeOutbox(ctx context.Context, record JRecord, op ChangeOperation) error {
+	outboxHooksMu.RLock()
+	hooks := outboxHooks[record.Schema().Name()]
+	outboxHooksMu.RUnlock()
+
+	for _, hook := range hooks {
+		intents, err := hook(ctx, record, op)
+		if err != nil {
+			return err
+		}
+
+		id, _ := recordID(record)
+		for _, intent := range intents {
+			envelope := outboxEnvelope{Topic: intent.Topic, Schema: record.Schema().Name(), Operation: op, RecordID: id}
+			if err := saveOutbox(ctx, envelope, intent.Payload); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteOutbox adds a message to the outbox. Call it with the context passed
+// to session.WithTransaction to commit it together with the business write.
+func WriteOutbox(ctx context.Context, topic string, payload any) error {
+	return saveOutbox(ctx, outboxEnvelope{Topic: topic}, payload)
+}
+
+func saveOutbox(ctx context.Context, envelope outboxEnvelope, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	envelope.Payload = data
+
+	_, err = outboxJobs.Enqueue(ctx, envelope)
+	return err
+}
+
+// OutboxDispatcher polls the outbox and hands committed messages to Handler.
+// Delivery is at least once: a message is retried with backoff when Handler
+// fails or its dispatcher crashes, and only marked done after Handler
+// returns, so Handler should drop duplicates by message ID.
+type OutboxDispatcher struct {
+	Handler func(ctx context.Context, msg OutboxMessage) error
+
+	// Concurrency, Visibility and PollInterval configure the underlying
+	// Worker. Retries and concurrency can reorder messages.
+	Concurrency  int
+	Visibility   time.Duration
+	PollInterval time.Duration
+}
+
+// Run dispatches messages until ctx is done.
+func (d *OutboxDispatcher) Run(ctx context.Context) error {
+	worker := &Worker{
+		Queue:        outboxJobs,
+		Handler:      d.handle,
+		Concurrency:  d.Concurrency,
+		Visibility:   d.Visibility,
+		PollInterval: d.PollInterval,
+	}
+	return worker.Run(ctx)
+}
+
+func (d *OutboxDispatcher) handle(ctx context.Context, job *Job) error {
+	msg, err := outboxMessage(job)
+	if err != nil {
+		return err
+	}
+	return d.Handler(ctx, msg)
+}
+
+func outboxMessage(job *Job) (OutboxMessage, error) {
+	var envelope outboxEnvelope
+	if err := job.Decode(&envelope); err != nil {
+		return OutboxMessage{}, err
+	}
+
+	return OutboxMessage{
+		ID:        job.ID,
+		Topic:     envelope.Topic,
+		Schema:    envelope.Schema,
+		Operation: envelope.Operation,
+		RecordID:  envelope.RecordID,
+		Payload:   envelope.Payload,
+		Attempt:   job.Attempts,
+	}, nil
+}
@@ -0,0 +1,150 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("hooks", func(t *testing.T) {
+		schema := NewSchema("test_outbox_hooks").Field("id", &String{}).Build()
+		failure := errors.New("no intent")
+
+		var ops []ChangeOperation
+		RegisterOutboxHook(schema, func(ctx context.Context, record JRecord, op ChangeOperation) ([]OutboxIntent, error) {
+			ops = append(ops, op)
+			if op == ChangeDelete {
+				return nil, failure
+			}
+			return nil, nil
+		})
+
+		record := NewMongoRecord(schema)
+		assert.NoError(t, writeOutbox(ctx, record, ChangeInsert))
+		assert.ErrorIs(t, writeOutbox(ctx, record, ChangeDelete), failure)
+		assert.Equal(t, []ChangeOperation{ChangeInsert, ChangeDelete}, ops)
+
+		// Schemas without hooks write nothing
+		assert.NoError(t, writeOutbox(ctx, NewMongoRecord(userSchema), ChangeInsert))
+	})
+
+	t.Run("writes outside transactions are refused", func(t *testing.T) {
+		schema := NewSchema("test_outbox_transactions").Field("id", &String{}).Field("name", &String{}).Build()
+		called := false
+		RegisterOutboxHook(schema, func(ctx context.Context, record JRecord, op ChangeOperation) ([]OutboxIntent, error) {
+			called = true
+			return nil, nil
+		})
+		offline := offlineContext(t)
+
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(mustField(t, schema, "name"), "Ada"))
+		assert.ErrorIs(t, record.Save(offline), ErrOutboxNeedsTransaction)
+		assert.True(t, record.IsNew(), "nothing is stored")
+		assert.Equal(t, []string{"name"}, record.DirtyKeys())
+
+		stored, err := RecordFromBSON(schema, bson.M{"_id": bson.NewObjectID(), "name": "Ada"})
+		assert.NoError(t, err)
+		assert.ErrorIs(t, stored.Delete(offline), ErrOutboxNeedsTransaction)
+
+		_, _, err = NewMongoQuery(offline, schema).FirstOrCreate(offline, nil)
+		assert.ErrorIs(t, err, ErrOutboxNeedsTransaction)
+		assert.False(t, called)
+
+		assert.NoError(t, checkOutbox(context.WithValue(offline, commitQueueKey, &commitQueue{}), schema))
+		assert.NoError(t, checkOutbox(offline, userSchema), "schemas without hooks write anywhere")
+	})
+
+	t.Run("queued and SQLite writes are refused", func(t *testing.T) {
+		schema := NewSchema("test_outbox_routes").Field("id", &String{}).Field("name", &String{}).Build()
+		called := false
+		RegisterOutboxHook(schema, func(ctx context.Context, record JRecord, op ChangeOperation) ([]OutboxIntent, error) {
+			called = true
+			return nil, nil
+		})
+		name := mustField(t, schema, "name")
+
+		journal := NewMemoryJournal()
+		queued := WithPendingJournal(context.Background(), journal)
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(name, "Ada"))
+		assert.ErrorIs(t, record.Save(queued), ErrOutboxNotSupported)
+		assert.True(t, record.IsNew(), "nothing is queued")
+
+		stored, err := RecordFromBSON(schema, bson.M{"_id": bson.NewObjectID(), "name": "Ada"})
+		assert.NoError(t, err)
+		assert.ErrorIs(t, stored.Delete(queued), ErrOutboxNotSupported)
+		ops, err := journal.Pending(queued)
+		assert.NoError(t, err)
+		assert.Empty(t, ops)
+
+		store := openSQLite(t)
+		local := WithSQLite(context.Background(), store)
+		assert.NoError(t, store.Migrate(local, schema))
+
+		row := NewSQLiteRecord(schema)
+		assert.NoError(t, row.SetValue(name, "Ada"))
+		assert.ErrorIs(t, row.Save(local), ErrOutboxNotSupported)
+		assert.True(t, row.IsNew())
+		assert.ErrorIs(t, row.SaveWithRetry(local, 1, nil), ErrOutboxNotSupported)
+
+		_, _, err = NewQuery(local, schema).FirstOrCreate(local, map[JField]any{name: "Ada"})
+		assert.ErrorIs(t, err, ErrOutboxNotSupported)
+		count, err := NewQuery(local, schema).Count()
+		assert.NoError(t, err)
+		assert.Zero(t, count, "nothing is stored")
+
+		assert.ErrorIs(t, (&sqliteRecord{mongoRecord: stored.(*mongoRecord)}).Delete(local), ErrOutboxNotSupported)
+		assert.False(t, called)
+	})
+
+	t.Run("messages", func(t *testing.T) {
+		assert := assert.New(t)
+
+		envelope, _ := json.Marshal(outboxEnvelope{
+			Topic:     "order.created",
+			Schema:    "orders",
+			Operation: ChangeInsert,
+			RecordID:  "o1",
+			Payload:   json.RawMessage(`{"total":42}`),
+		})
+		id := bson.NewObjectID()
+		record, err := RecordFromBSON(JobsSchema, bson.M{
+			"_id":      id,
+			"queue":    OutboxQueue,
+			"payload":  string(envelope),
+			"attempts": 2,
+		})
+		assert.NoError(err)
+		job, err := jobFromRecord(record)
+		assert.NoError(err)
+
+		var got OutboxMessage
+		dispatcher := &OutboxDispatcher{Handler: func(ctx context.Context, msg OutboxMessage) error {
+			got = msg
+			return nil
+		}}
+		assert.NoError(dispatcher.handle(ctx, job))
+
+		assert.Equal(OutboxMessage{
+			ID:        id.Hex(),
+			Topic:     "order.created",
+			Schema:    "orders",
+			Operation: ChangeInsert,
+			RecordID:  "o1",
+			Payload:   json.RawMessage(`{"total":42}`),
+			Attempt:   2,
+		}, got)
+
+		var payload struct{ Total int }
+		assert.NoError(got.Decode(&payload))
+		assert.Equal(42, payload.Total)
+	})
+}
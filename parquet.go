@@ -0,0 +1,311 @@
+package jpack
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// Parquet enum values used by the writer, see parquet.thrift.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetDataPage     = 0
+	parquetUncompressed = 0
+)
+
+var parquetMagic = []byte("PAR1")
+
+// ExportParquet runs the query and writes its results as a Parquet file.
+func ExportParquet(w io.Writer, q Query) error {
+	records, err := q.Execute()
+	if err != nil {
+		return err
+	}
+	return WriteParquet(w, q.Schema(), records)
+}
+
+// WriteParquet writes records of the schema as an uncompressed Parquet file
+// with a single row group. Columns match AvroSchema: every column is optional,
+// composite fields are flattened and datetimes are TIMESTAMP_MICROS.
+func WriteParquet(w io.Writer, schema JSchema, records []JRecord) error {
+	columns, rows, err := exportRows(schema, records)
+	if err != nil {
+		return err
+	}
+
+	file := append([]byte{}, parquetMagic...)
+
+	chunks := make([]parquetChunk, len(columns))
+	for i, column := range columns {
+		page := parquetPage(column, rows, i)
+
+		header := newThriftCompact()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.beginStruct(5)
+		header.i32(1, int32(len(rows)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunks[i] = parquetChunk{
+			offset: int64(len(file)),
+			size:   int64(len(header.buf) + len(page)),
+		}
+		file = append(file, header.buf...)
+		file = append(file, page...)
+	}
+
+	footer := parquetFooter(schema, columns, chunks, int64(len(rows)))
+	file = append(file, footer...)
+	file = binary.LittleEndian.AppendUint32(file, uint32(len(footer)))
+	file = append(file, parquetMagic...)
+
+	_, err = w.Write(file)
+	return err
+}
+
+type parquetChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetPage encodes a v1 data page for column i: RLE definition levels
+// followed by the PLAIN encoded non-null values.
+func parquetPage(column exportColumn, rows [][]any, i int) []byte {
+	levels := make([]bool, len(rows))
+	for r, row := range rows {
+		levels[r] = row[i] != nil
+	}
+	encodedLevels := parquetLevels(levels)
+
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(encodedLevels)))
+	page = append(page, encodedLevels...)
+
+	var bits int
+	var current byte
+	for _, row := range rows {
+		value := row[i]
+		if value == nil {
+			continue
+		}
+
+		switch column.kind {
+		case exportString:
+			s := value.(string)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(s)))
+			page = append(page, s...)
+		case exportLong:
+			page = binary.LittleEndian.AppendUint64(page, uint64(value.(int)))
+		case exportTimestamp:
+			page = binary.LittleEndian.AppendUint64(page, uint64(value.(time.Time).UnixMicro()))
+		case exportBoolean:
+			// Booleans are bit-packed, least significant bit first
+			if value.(bool) {
+				current |= 1 << bits
+			}
+			bits++
+			if bits == 8 {
+				page = append(page, current)
+				current, bits = 0, 0
+			}
+		}
+	}
+	if column.kind == exportBoolean && bits > 0 {
+		page = append(page, current)
+	}
+
+	return page
+}
+
+// parquetLevels encodes definition levels with bit width 1 as RLE runs of the
+// RLE/bit-packing hybrid encoding.
+func parquetLevels(levels []bool) []byte {
+	var buf []byte
+	for start := 0; start < len(levels); {
+		end := start
+		for end < len(levels) && levels[end] == levels[start] {
+			end++
+		}
+
+		buf = binary.AppendUvarint(buf, uint64(end-start)<<1)
+		if levels[start] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		start = end
+	}
+	return buf
+}
+
+// parquetFooter encodes the FileMetaData struct.
+func parquetFooter(schema JSchema, columns []exportColumn, chunks []parquetChunk, numRows int64) []byte {
+	meta := newThriftCompact()
+	meta.i32(1, 1) // version
+
+	meta.listHeader(2, thriftStruct, len(columns)+1)
+	meta.beginElement()
+	meta.binary(4, schema.Name())
+	meta.i32(5, int32(len(columns)))
+	meta.endStruct()
+	for _, column := range columns {
+		meta.beginElement()
+		meta.i32(1, parquetPhysicalType(column.kind))
+		meta.i32(3, parquetOptional)
+		meta.binary(4, column.name)
+		switch column.kind {
+		case exportString:
+			meta.i32(6, parquetUTF8)
+		case exportTimestamp:
+			meta.i32(6, parquetTimestampMicros)
+		}
+		meta.endStruct()
+	}
+
+	meta.i64(3, numRows)
+
+	var totalSize int64
+	for _, chunk := range chunks {
+		totalSize += chunk.size
+	}
+
+	meta.listHeader(4, thriftStruct, 1)
+	meta.beginElement()
+	meta.listHeader(1, thriftStruct, len(columns))
+	for i, column := range columns {
+		meta.beginElement()
+		meta.i64(2, chunks[i].offset)
+		meta.beginStruct(3)
+		meta.i32(1, parquetPhysicalType(column.kind))
+		meta.listHeader(2, thriftI32, 2)
+		meta.appendZigzag(parquetPlain)
+		meta.appendZigzag(parquetRLE)
+		meta.listHeader(3, thriftBinary, 1)
+		meta.appendString(column.name)
+		meta.i32(4, parquetUncompressed)
+		meta.i64(5, numRows)
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, totalSize)
+	meta.i64(3, numRows)
+	meta.endStruct()
+
+	meta.binary(6, "jpack")
+	meta.endStruct()
+
+	return meta.buf
+}
+
+func parquetPhysicalType(kind exportKind) int32 {
+	switch kind {
+	case exportLong, exportTimestamp:
+		return parquetInt64
+	case exportBoolean:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftCompact writes structs in the Thrift compact protocol, the encoding of
+// Parquet's metadata. Fields must be written in increasing id order.
+type thriftCompact struct {
+	buf []byte
+	// last holds the previous field id of each open struct
+	last []int16
+}
+
+// newThriftCompact starts writing a top-level struct.
+func newThriftCompact() *thriftCompact {
+	return &thriftCompact{last: []int16{0}}
+}
+
+func (t *thriftCompact) fieldHeader(id int16, fieldType byte) {
+	last := t.last[len(t.last)-1]
+	t.last[len(t.last)-1] = id
+
+	if delta := id - last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|fieldType)
+		return
+	}
+	t.buf = append(t.buf, fieldType)
+	t.buf = binary.AppendVarint(t.buf, int64(id))
+}
+
+func (t *thriftCompact) appendZigzag(v int64) {
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftCompact) appendString(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftCompact) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.appendZigzag(int64(v))
+}
+
+func (t *thriftCompact) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.appendZigzag(v)
+}
+
+func (t *thriftCompact) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.appendString(s)
+}
+
+func (t *thriftCompact) listHeader(id int16, elemType byte, size int) {
+	t.fieldHeader(id, thriftList)
+	if size < 15 {
+		t.buf = append(t.buf, byte(size)<<4|elemType)
+		return
+	}
+	t.buf = append(t.buf, 0xf0|elemType)
+	t.buf = binary.AppendUvarint(t.buf, uint64(size))
+}
+
+// beginStruct starts a struct-typed field; beginElement starts a struct list
+// element. Both are closed by endStruct.
+func (t *thriftCompact) beginStruct(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginElement()
+}
+
+func (t *thriftCompact) beginElement() {
+	t.last = append(t.last, 0)
+}
+
+func (t *thriftCompact) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.last = t.last[:len(t.last)-1]
+}
@@ -0,0 +1,447 @@
+package jpack
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// PendingJournalKey is the context key holding the PendingJournal of offline
+// Saves.
+var PendingJournalKey key = "jpack.pendingjournal"
+
+// PendingOperation is a Save or Delete queued in a PendingJournal while the
+// store is unreachable.
+type PendingOperation struct {
+	ID        string          `bson:"id"`
+	Schema    string          `bson:"schema"`
+	Operation ChangeOperation `bson:"operation"`
+	RecordID  string          `bson:"record_id"`
+	// Base is the record as it was loaded when the operation was queued;
+	// Sync reports a conflict when the stored document changed since. It is
+	// empty for inserts.
+	Base bson.M `bson:"base,omitempty"`
+	// Values holds the fields the operation sets and Unset the fields it
+	// removes.
+	Values bson.M   `bson:"values,omitempty"`
+	Unset  []string `bson:"unset,omitempty"`
+	// IdempotencyKey is the WithIdempotencyKey of a queued insert, which
+	// Sync inserts with.
+	IdempotencyKey string    `bson:"idempotency_key,omitempty"`
+	QueuedAt       time.Time `bson:"queued_at"`
+}
+
+// PendingJournal stores the operations queued by offline Saves and Deletes
+// until Sync writes them.
+type PendingJournal interface {
+	// Append queues an operation after the others.
+	Append(ctx context.Context, op PendingOperation) error
+	// Pending returns the queued operations in the order they were appended.
+	Pending(ctx context.Context) ([]PendingOperation, error)
+	// Remove drops a queued operation once it was written.
+	Remove(ctx context.Context, id string) error
+}
+
+// WithPendingJournal returns a context whose Saves and Deletes are queued in
+// journal instead of written, for tools that keep working while the store is
+// unreachable. Saved records look saved right away: new records get their id
+// and are no longer new, and changes are no longer dirty. Records are
+// validated when queued, but unique fields, tree cycles and server-side
+// defaults are only checked or applied by Sync.
+func WithPendingJournal(ctx context.Context, journal PendingJournal) context.Context {
+	return context.WithValue(ctx, PendingJournalKey, journal)
+}
+
+// PendingJournalFrom returns the journal stored in the context, if any.
+func PendingJournalFrom(ctx context.Context) (PendingJournal, bool) {
+	journal, ok := ctx.Value(PendingJournalKey).(PendingJournal)
+	return journal, ok && journal != nil
+}
+
+// MemoryJournal is a PendingJournal kept in memory, lost when the process
+// exits.
+type MemoryJournal struct {
+	mu  sync.Mutex
+	ops []PendingOperation
+}
+
+// NewMemoryJournal creates an empty in-memory journal.
+func NewMemoryJournal() *MemoryJournal {
+	return &MemoryJournal{}
+}
+
+// Append implements PendingJournal.
+func (j *MemoryJournal) Append(ctx context.Context, op PendingOperation) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.ops = append(j.ops, op)
+	return nil
+}
+
+// Pending implements PendingJournal.
+func (j *MemoryJournal) Pending(ctx context.Context) ([]PendingOperation, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return slices.Clone(j.ops), nil
+}
+
+// Remove implements PendingJournal.
+func (j *MemoryJournal) Remove(ctx context.Context, id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.ops = slices.DeleteFunc(j.ops, func(op PendingOperation) bool { return op.ID == id })
+	return nil
+}
+
+// FileJournal is a PendingJournal stored in a file, one operation per line
+// as canonical extended JSON, so queued writes survive restarts and keep
+// their BSON types.
+type FileJournal struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileJournal returns the journal stored at path. The file is created by
+// the first Append.
+func NewFileJournal(path string) *FileJournal {
+	return &FileJournal{path: path}
+}
+
+// Append implements PendingJournal.
+func (j *FileJournal) Append(ctx context.Context, op PendingOperation) error {
+	line, err := bson.MarshalExtJSON(op, true, false)
+	if err != nil {
+		return fmt.Errorf("jpack: encoding pending %s of %s: %w", op.Operation, op.Schema, err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Pending implements PendingJournal.
+func (j *FileJournal) Pending(ctx context.Context) ([]PendingOperation, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.read()
+}
+
+// Remove implements PendingJournal. The file is rewritten and replaced, so a
+// crash leaves either the old or the new journal.
+func (j *FileJournal) Remove(ctx context.Context, id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	ops, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, op := range ops {
+		if op.ID == id {
+			continue
+		}
+		line, err := bson.MarshalExtJSON(op, true, false)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+func (j *FileJournal) read() ([]PendingOperation, error) {
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ops []PendingOperation
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var op PendingOperation
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, &op); err != nil {
+			return nil, fmt.Errorf("jpack: reading pending journal %s: %w", j.path, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// savePending validates the record and queues its write in journal.
+func (m *mongoRecord) savePending(ctx context.Context, journal PendingJournal, saveOpts *saveOptions) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	if _, ok := m.schema.(*ViewSchema); ok {
+		return ErrViewReadOnly
+	}
+	if err := refuseOutbox(m.schema); err != nil {
+		return err
+	}
+	if saveOpts.returnDocument {
+		return errors.New("jpack: ReturnDocument needs the store, and Saves are queued")
+	}
+
+	for _, policy := range PoliciesOf(m.schema) {
+		if err := policy.IsValid(ctx, m); err != nil {
+			return err
+		}
+	}
+	if err := validateRecordFields(ctx, m, saveOpts.scenarios...); err != nil {
+		return err
+	}
+	m.warnings = collectWarnings(ctx, m)
+
+	pkField, _ := PK(m.schema)
+	op := PendingOperation{
+		ID:       bson.NewObjectID().Hex(),
+		Schema:   m.schema.Name(),
+		QueuedAt: time.Now(),
+	}
+
+	var pending map[string]any
+	var cleared []string
+	if m.IsNew() {
+		// There is no stored document an ETag could match
+		if saveOpts.ifMatch != nil {
+			return ErrPreconditionFailed
+		}
+
+		serverDefaults, err := m.applyDefaults(ctx)
+		if err != nil {
+			return err
+		}
+		for _, field := range m.Schema().Fields() {
+			if slices.Contains(serverDefaults, field) {
+				continue
+			}
+			if err := m.checkRequired(field, m.record[field.Name()], saveOpts.scenarios...); err != nil {
+				return err
+			}
+		}
+
+		// The id is chosen up front, so the record can be referenced
+		// before it is written
+		if id, _ := m.record[pkField.Name()].(string); id == "" {
+			m.record[pkField.Name()] = bson.NewObjectID().Hex()
+		}
+		op.Operation = ChangeInsert
+		op.IdempotencyKey = saveOpts.idempotencyKey
+		pending = maps.Clone(m.record)
+	} else {
+		if saveOpts.ifMatch != nil {
+			hash, err := m.loadedHash()
+			if err != nil {
+				return err
+			}
+			if hash != *saveOpts.ifMatch {
+				return ErrPreconditionFailed
+			}
+		}
+
+		for _, key := range m.DirtyKeys() {
+			if field, ok := m.Schema().Field(key); ok {
+				value := m.record[key]
+				if isUnset(value) {
+					value = nil
+				}
+				if err := m.checkImmutable(field, value); err != nil {
+					return err
+				}
+				if err := m.checkRequired(field, value, saveOpts.scenarios...); err != nil {
+					return err
+				}
+			}
+		}
+		if err := m.checkRequiredIn(saveOpts.scenarios); err != nil {
+			return err
+		}
+
+		pending, cleared = m.pendingChanges()
+		for key := range pending {
+			if !m.isDirty(key) {
+				delete(pending, key)
+			}
+		}
+		if len(pending)+len(cleared) == 0 {
+			m.observers = nil
+			return nil
+		}
+		op.Operation = ChangeUpdate
+		op.Base = maps.Clone(m.originalRecord)
+		op.Unset = cleared
+	}
+	op.Values = pending
+	op.RecordID, _ = recordID(m)
+
+	if err := journal.Append(ctx, op); err != nil {
+		return err
+	}
+
+	// The record shows the queued write as if it was stored
+	maps.Copy(m.originalRecord, pending)
+	for _, key := range cleared {
+		delete(m.originalRecord, key)
+	}
+	m.record = make(map[string]any)
+	m.observers = nil
+	m.saves++
+	m.invalidateScanned()
+	return nil
+}
+
+// deletePending queues the deletion of the record in journal.
+func (m *mongoRecord) deletePending(ctx context.Context, journal PendingJournal) error {
+	if err := refuseOutbox(m.schema); err != nil {
+		return err
+	}
+
+	id, ok := recordID(m)
+	if !ok || m.IsNew() {
+		return errors.New("jpack: only stored records can be deleted")
+	}
+
+	return journal.Append(ctx, PendingOperation{
+		ID:        bson.NewObjectID().Hex(),
+		Schema:    m.schema.Name(),
+		Operation: ChangeDelete,
+		RecordID:  id,
+		Base:      maps.Clone(m.originalRecord),
+		QueuedAt:  time.Now(),
+	})
+}
+
+// Sync writes the operations queued in journal to the store of the context,
+// in the order they were queued, and removes each once written. Schemas are
+// looked up with GetSchema, so they must be registered.
+//
+// Updates and deletes of records whose stored document changed since they
+// were loaded are conflicts: onConflict receives the queued record and the
+// stored one and returns the record to save, as with SaveWithRetry. For a
+// delete, returning the local record deletes the stored document anyway and
+// any other record is saved instead. A nil onConflict fails on conflicts with
+// ErrPreconditionFailed. Deleting an already deleted record is not an error.
+//
+// Sync stops at the first operation that fails, leaving it and the later
+// ones queued, and returns the number of operations written.
+func Sync(ctx context.Context, journal PendingJournal, onConflict func(local, remote JRecord) (JRecord, error)) (int, error) {
+	// Replayed writes go to the store, not back into the journal
+	ctx = WithPendingJournal(ctx, nil)
+
+	ops, err := journal.Pending(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, op := range ops {
+		if err := replayPending(ctx, op, onConflict); err != nil {
+			return i, fmt.Errorf("jpack: syncing %s of %s %s: %w", op.Operation, op.Schema, op.RecordID, err)
+		}
+		if err := journal.Remove(ctx, op.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(ops), nil
+}
+
+// replayPending writes one queued operation.
+func replayPending(ctx context.Context, op PendingOperation, onConflict func(local, remote JRecord) (JRecord, error)) error {
+	schema, ok := GetSchema(op.Schema)
+	if !ok {
+		return fmt.Errorf("jpack: schema %s is not registered", op.Schema)
+	}
+
+	record := NewMongoRecord(schema)
+	maps.Copy(record.originalRecord, op.Base)
+
+	switch op.Operation {
+	case ChangeInsert:
+		maps.Copy(record.record, op.Values)
+		if op.IdempotencyKey != "" {
+			return record.Save(ctx, WithIdempotencyKey(op.IdempotencyKey))
+		}
+		return record.Save(ctx)
+	case ChangeUpdate:
+		maps.Copy(record.record, op.Values)
+		for _, key := range op.Unset {
+			record.record[key] = unsetValue{}
+		}
+		// One attempt for the write and one for the resolved record
+		return record.SaveWithRetry(ctx, 2, onConflict)
+	case ChangeDelete:
+		return replayDelete(ctx, record, onConflict)
+	}
+	return fmt.Errorf("jpack: unknown pending operation %q", op.Operation)
+}
+
+// replayDelete deletes the record unless its stored document changed and
+// onConflict keeps another record.
+func replayDelete(ctx context.Context, local *mongoRecord, onConflict func(local, remote JRecord) (JRecord, error)) error {
+	remote, err := local.reload(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	loaded, err := local.loadedHash()
+	if err != nil {
+		return err
+	}
+	stored, err := remote.loadedHash()
+	if err != nil {
+		return err
+	}
+	if loaded == stored {
+		return remote.Delete(ctx)
+	}
+
+	if onConflict == nil {
+		return ErrPreconditionFailed
+	}
+	resolved, err := onConflict(local, remote)
+	if err != nil {
+		return err
+	}
+	if resolved == JRecord(local) {
+		return remote.Delete(ctx)
+	}
+	return resolved.Save(ctx)
+}
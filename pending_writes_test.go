@@ -0,0 +1,119 @@
+package jpack
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestPendingWrites(t *testing.T) {
+	notes := NewSchema("test_pending_notes").
+		Field("id", &String{}).
+		Field("title", &String{}, Required()).
+		Field("body", &String{}).
+		Field("status", &String{}, Default("draft")).
+		Build()
+	title, body, status := mustField(t, notes, "title"), mustField(t, notes, "body"), mustField(t, notes, "status")
+
+	journal := NewMemoryJournal()
+	ctx := WithPendingJournal(context.Background(), journal)
+
+	t.Run("saves are queued and look stored", func(t *testing.T) {
+		record := NewMongoRecord(notes)
+		assert.NoError(t, record.SetValue(title, "Groceries"))
+		assert.NoError(t, record.Save(ctx))
+
+		assert.False(t, record.IsNew())
+		assert.False(t, record.IsModified())
+		id, ok := recordID(record)
+		assert.True(t, ok)
+		_, err := bson.ObjectIDFromHex(id)
+		assert.NoError(t, err)
+		value, _ := record.Value(status)
+		assert.Equal(t, "draft", value)
+
+		assert.NoError(t, record.SetValue(body, "milk"))
+		assert.NoError(t, record.Unset(status))
+		assert.NoError(t, record.Save(ctx))
+		assert.NoError(t, record.Delete(ctx))
+
+		ops, err := journal.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, ops, 3)
+
+		assert.Equal(t, ChangeInsert, ops[0].Operation)
+		assert.Equal(t, id, ops[0].RecordID)
+		assert.Equal(t, bson.M{"id": id, "title": "Groceries", "status": "draft"}, ops[0].Values)
+		assert.Empty(t, ops[0].Base)
+
+		assert.Equal(t, ChangeUpdate, ops[1].Operation)
+		assert.Equal(t, bson.M{"id": id, "title": "Groceries", "status": "draft"}, ops[1].Base)
+		assert.Equal(t, bson.M{"body": "milk"}, ops[1].Values)
+		assert.Equal(t, []string{"status"}, ops[1].Unset)
+
+		assert.Equal(t, ChangeDelete, ops[2].Operation)
+		assert.Equal(t, bson.M{"id": id, "title": "Groceries", "body": "milk"}, ops[2].Base)
+	})
+
+	t.Run("records are validated when queued", func(t *testing.T) {
+		journal := NewMemoryJournal()
+		ctx := WithPendingJournal(context.Background(), journal)
+
+		assert.ErrorIs(t, NewMongoRecord(notes).Save(ctx), ErrRequiredField)
+
+		record := NewMongoRecord(notes)
+		assert.NoError(t, record.SetValue(title, "Groceries"))
+		assert.ErrorIs(t, record.Save(ctx, IfMatch(`"stale"`)), ErrPreconditionFailed)
+		assert.ErrorContains(t, record.Save(ctx, ReturnDocument()), "ReturnDocument")
+		assert.ErrorIs(t, record.Save(WithReadOnly(ctx)), ErrReadOnly)
+
+		assert.NoError(t, record.Save(ctx))
+		assert.ErrorIs(t, record.Save(ctx, IfMatch(`"stale"`)), ErrPreconditionFailed)
+
+		// Saving without changes queues nothing
+		assert.NoError(t, record.Save(ctx))
+		ops, _ := journal.Pending(ctx)
+		assert.Len(t, ops, 1)
+	})
+
+	t.Run("file journals keep BSON types", func(t *testing.T) {
+		journal := NewFileJournal(filepath.Join(t.TempDir(), "pending.jsonl"))
+		ops, err := journal.Pending(t.Context())
+		assert.NoError(t, err)
+		assert.Empty(t, ops)
+
+		queuedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		first := PendingOperation{ID: "1", Schema: "notes", Operation: ChangeInsert, Values: bson.M{"pages": int64(3), "at": queuedAt}, QueuedAt: queuedAt}
+		second := PendingOperation{ID: "2", Schema: "notes", Operation: ChangeDelete, RecordID: "abc", QueuedAt: queuedAt}
+		assert.NoError(t, journal.Append(t.Context(), first))
+		assert.NoError(t, journal.Append(t.Context(), second))
+
+		ops, err = journal.Pending(t.Context())
+		assert.NoError(t, err)
+		assert.Len(t, ops, 2)
+		assert.Equal(t, int64(3), ops[0].Values["pages"])
+		assert.Equal(t, bson.NewDateTimeFromTime(queuedAt), ops[0].Values["at"])
+		assert.True(t, queuedAt.Equal(ops[1].QueuedAt))
+
+		assert.NoError(t, journal.Remove(t.Context(), "1"))
+		ops, err = journal.Pending(t.Context())
+		assert.NoError(t, err)
+		assert.Len(t, ops, 1)
+		assert.Equal(t, "abc", ops[0].RecordID)
+	})
+
+	t.Run("sync stops at the first failing operation", func(t *testing.T) {
+		journal := NewMemoryJournal()
+		assert.NoError(t, journal.Append(t.Context(), PendingOperation{ID: "1", Schema: "test_pending_unregistered", Operation: ChangeInsert}))
+
+		n, err := Sync(context.Background(), journal, nil)
+		assert.Equal(t, 0, n)
+		assert.ErrorContains(t, err, "schema test_pending_unregistered is not registered")
+		ops, _ := journal.Pending(t.Context())
+		assert.Len(t, ops, 1)
+	})
+}
@@ -0,0 +1,74 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type ctxTenantKey struct{}
+
+type tenantPolicy struct {
+	field JField
+}
+
+func (p *tenantPolicy) IsValid(ctx context.Context, record JRecord) error {
+	return nil
+}
+
+func (p *tenantPolicy) QueryFilter(ctx context.Context, schema JSchema) Filter {
+	tenant, ok := ctx.Value(ctxTenantKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return Eq(p.field, tenant)
+}
+
+// offlineContext returns a context holding a database handle that never
+// connects, for tests that only build queries.
+func offlineContext(t *testing.T) context.Context {
+	t.Helper()
+	client, err := mongo.Connect(options.Client().ApplyURI("mongodb://localhost:27017"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	return WithDatabase(context.Background(), client.Database("jpack_test"))
+}
+
+func TestPolicyQueryFilter(t *testing.T) {
+	policy := &tenantPolicy{}
+	schema := NewSchema("test_policy").
+		Field("id", &String{}).
+		Field("tenant_id", &String{}).
+		Field("name", &String{}).
+		Policy(policy).
+		Build()
+	policy.field = mustField(t, schema, "tenant_id")
+	name := mustField(t, schema, "name")
+
+	t.Run("policy filter is added to where clauses", func(t *testing.T) {
+		ctx := context.WithValue(offlineContext(t), ctxTenantKey{}, "acme")
+		q := NewMongoQuery(ctx, schema).Where(Eq(name, "John")).(*mongoQuery)
+
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"name": "John"},
+			{"tenant_id": "acme"},
+		}}, q.filter())
+	})
+
+	t.Run("policy filter applies without where clauses", func(t *testing.T) {
+		ctx := context.WithValue(offlineContext(t), ctxTenantKey{}, "acme")
+		q := NewMongoQuery(ctx, schema).(*mongoQuery)
+
+		assert.Equal(t, bson.M{"$and": []bson.M{{"tenant_id": "acme"}}}, q.filter())
+	})
+
+	t.Run("nil policy filter is skipped", func(t *testing.T) {
+		q := NewMongoQuery(offlineContext(t), schema).(*mongoQuery)
+		assert.Equal(t, bson.M{}, q.filter())
+	})
+}
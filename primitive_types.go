@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -16,7 +17,7 @@ type Number struct{}
 
 // Scan implements JFieldType.
 func (n *Number) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	v, ok := row[field.Name()]
+	v, ok := RowValue(field, row)
 	if !ok {
 		return nil, nil // No value found, return nil
 	}
@@ -61,31 +62,49 @@ func (n *Number) SetValue(ctx context.Context, field JField, value any, row map[
 	return nil
 }
 
-// Validate implements JFieldType.
+// Validate implements JFieldType. It accepts exactly the values SetValue can
+// convert, so validation and conversion never disagree on the same input.
 func (n *Number) Validate(value any) error {
+	if value == nil {
+		return nil // If the value is nil, return nil
+	}
 
-	var validate func(reflect.Value) error
+	_, err := convertToInt(reflect.ValueOf(value))
+	return err
+}
 
-	validate = func(reflectValue reflect.Value) error {
-		switch reflectValue.Kind() {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			return nil // No error for valid integer types
-		case reflect.String:
-			_, err := strconv.ParseInt(reflectValue.String(), 10, 32)
-			if err != nil {
-				return errors.New("value is not a valid integer")
-			}
-			return nil // No error for valid integer types
-		case reflect.Pointer:
-			return validate(reflectValue.Elem())
-		default:
-			return errors.New("value is not a valid integer")
+// ValidateStrict implements StrictFieldType. Only Go integer values are accepted.
+func (n *Number) ValidateStrict(value any) error {
+	reflectValue, ok := strictValue(value)
+	if !ok {
+		return nil
+	}
+
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nil
+	default:
+		return errors.New("value is not an integer (strict mode)")
+	}
+}
+
+// strictValue dereferences pointers and reports whether there is a non-nil
+// value left to check.
+func strictValue(value any) (reflect.Value, bool) {
+	reflectValue := reflect.ValueOf(value)
+	for reflectValue.Kind() == reflect.Pointer {
+		if reflectValue.IsNil() {
+			return reflect.Value{}, false
 		}
+		reflectValue = reflectValue.Elem()
 	}
 
-	return validate(reflect.ValueOf(value))
+	return reflectValue, reflectValue.IsValid()
 }
 
+// convertToInt converts the supported numeric representations to int.
+// Conversions that would lose information, such as fractional floats or
+// values outside the int64 range, are rejected instead of truncated.
 func convertToInt(reflectValue reflect.Value) (int, error) {
 	switch reflectValue.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -95,17 +114,36 @@ func convertToInt(reflectValue reflect.Value) (int, error) {
 		} else {
 			return 0, errors.New("value cannot be converted to integer")
 		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if reflectValue.Uint() > math.MaxInt64 {
+			return 0, errors.New("value overflows int64")
+		}
+		return int(reflectValue.Uint()), nil
+
 	case reflect.Float32, reflect.Float64:
-		return int(math.Round(reflectValue.Float())), nil
+		f := reflectValue.Float()
+		if f != math.Trunc(f) || math.IsInf(f, 0) {
+			return 0, errors.New("value is not a whole number")
+		}
+		// float64(math.MaxInt64) rounds up to 2^63, which is already out of range
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, errors.New("value overflows int64")
+		}
+		return int(f), nil
 
 	case reflect.String:
 		// Attempt to parse the string as an integer
-		num, err := strconv.ParseInt(reflectValue.String(), 10, 32)
+		num, err := strconv.ParseInt(reflectValue.String(), 10, 64)
 		if err != nil {
 			return 0, errors.New("value is not a valid integer string")
 		}
 		return int(num), nil
 
+	case reflect.Struct:
+		if d, ok := reflectValue.Interface().(bson.Decimal128); ok {
+			return decimal128ToInt(d)
+		}
+
 	case reflect.Pointer:
 		if reflectValue.IsNil() {
 			return 0, nil // If the pointer is nil, return 0
@@ -117,13 +155,32 @@ func convertToInt(reflectValue reflect.Value) (int, error) {
 	return 0, errors.New("value is not an integer type")
 }
 
+// decimal128ToInt converts a Decimal128 holding a whole number to int.
+func decimal128ToInt(d bson.Decimal128) (int, error) {
+	r, ok := new(big.Rat).SetString(d.String())
+	if !ok {
+		return 0, errors.New("value is not a valid decimal number")
+	}
+
+	if !r.IsInt() {
+		return 0, errors.New("value is not a whole number")
+	}
+
+	if !r.Num().IsInt64() {
+		return 0, errors.New("value overflows int64")
+	}
+
+	return int(r.Num().Int64()), nil
+}
+
 var _ JFieldType = &Number{}
+var _ StrictFieldType = &Number{}
 
 type String struct{}
 
 // Scan implements JFieldType.
 func (s *String) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	v, ok := row[field.Name()]
+	v, ok := RowValue(field, row)
 	if !ok {
 		return nil, nil // No value found, return nil
 	}
@@ -182,13 +239,27 @@ func (s *String) Validate(value any) error {
 
 }
 
+// ValidateStrict implements StrictFieldType. Only Go strings are accepted.
+func (s *String) ValidateStrict(value any) error {
+	reflectValue, ok := strictValue(value)
+	if !ok {
+		return nil
+	}
+
+	if reflectValue.Kind() != reflect.String {
+		return errors.New("value is not a string (strict mode)")
+	}
+	return nil
+}
+
 var _ JFieldType = &String{}
+var _ StrictFieldType = &String{}
 
 type Ref struct{}
 
 // Scan implements JFieldType.
 func (r *Ref) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	v, ok := row[field.Name()]
+	v, ok := RowValue(field, row)
 	if !ok {
 		return nil, nil // No value found, return nil
 	}
@@ -283,13 +354,61 @@ func (r *Ref) Validate(value any) error {
 
 }
 
+// Normalize implements the normalization of inputs: ObjectID hex strings
+// are lowercased, as they are read back.
+func (r *Ref) Normalize(value any) (any, error) {
+	if hex, ok := value.(string); ok {
+		if _, err := bson.ObjectIDFromHex(hex); err == nil {
+			return strings.ToLower(hex), nil
+		}
+	}
+	return value, nil
+}
+
+// Equal implements EqualFieldType. Refs are equal when they reference the
+// same id, whatever the case of the hex string or whether the referenced
+// record is loaded.
+func (r *Ref) Equal(a, b any) bool {
+	idA, okA := refID(a)
+	idB, okB := refID(b)
+	if !okA || !okB {
+		return reflect.DeepEqual(a, b)
+	}
+	return strings.EqualFold(idA, idB)
+}
+
+// refID returns the id a ref value points to.
+func refID(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case bson.ObjectID:
+		return v.Hex(), true
+	case JRecord:
+		return recordID(v)
+	}
+	return "", false
+}
+
 var _ JFieldType = &Ref{}
+var _ EqualFieldType = &Ref{}
+
+// DateTime represents a date-time field type. Values are always stored in GMT.
+type DateTime struct {
+	// Layouts lists extra time layouts accepted when parsing strings,
+	// tried in order after RFC3339.
+	Layouts []string
+}
 
-type DateTime struct{}
+// epochMillisThreshold separates epoch seconds from epoch milliseconds.
+// 1e11 seconds is far beyond year 5000, so anything larger is treated as millis.
+const epochMillisThreshold = 100_000_000_000
 
 // Scan implements JFieldType.
 func (dt *DateTime) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	v, ok := row[field.Name()]
+	v, ok := RowValue(field, row)
 	if !ok {
 		return nil, nil // No value found, return nil
 	}
@@ -298,27 +417,13 @@ func (dt *DateTime) Scan(ctx context.Context, field JField, row map[string]any)
 		return nil, nil // If the value is nil, return nil
 	}
 
-	reflectValue := reflect.ValueOf(v)
-
-	switch reflectValue.Kind() {
-	case reflect.String:
-		// Parse RFC3339 format string and convert to GMT
-		t, err := time.Parse(time.RFC3339, reflectValue.String())
-		if err != nil {
-			return nil, errors.New("value is not a valid RFC3339 datetime string")
-		}
-		// Convert to GMT timezone
-		return t.UTC(), nil
-	case reflect.Struct:
-		// Check if it's a time.Time
-		if t, ok := v.(time.Time); ok {
-			// Convert to GMT timezone
-			return t.UTC(), nil
-		}
-		return nil, errors.New("value is not a time.Time struct")
-	default:
-		return nil, errors.New("value is not a valid datetime type")
+	t, err := dt.convertToTime(v)
+	if err != nil {
+		return nil, err
 	}
+
+	// Convert to GMT timezone
+	return t.UTC(), nil
 }
 
 // SetValue implements JFieldType.
@@ -335,23 +440,17 @@ func (dt *DateTime) SetValue(ctx context.Context, field JField, value any, row m
 		return err
 	}
 
-	columnName := field.Name()
+	if reflectValue.Kind() == reflect.Pointer {
+		value = reflectValue.Elem().Interface()
+	}
 
-	switch v := value.(type) {
-	case time.Time:
-		// Store in GMT timezone
-		row[columnName] = v.UTC()
-	case string:
-		// Parse RFC3339 format string and convert to GMT
-		t, err := time.Parse(time.RFC3339, v)
-		if err != nil {
-			return errors.New("value is not a valid RFC3339 datetime string")
-		}
-		row[columnName] = t.UTC()
-	default:
-		return errors.New("value is not a valid datetime type")
+	t, err := dt.convertToTime(value)
+	if err != nil {
+		return err
 	}
 
+	// Store in GMT timezone
+	row[field.Name()] = t.UTC()
 	return nil
 }
 
@@ -372,31 +471,110 @@ func (dt *DateTime) Validate(value any) error {
 		reflectValue = reflectValue.Elem()
 	}
 
-	switch reflectValue.Kind() {
-	case reflect.Struct:
-		// Check if it's a time.Time
-		if _, ok := reflectValue.Interface().(time.Time); ok {
-			return nil // No error for valid time.Time types
+	_, err := dt.convertToTime(reflectValue.Interface())
+	return err
+}
+
+// ValidateStrict implements StrictFieldType. Only time.Time values are accepted.
+func (dt *DateTime) ValidateStrict(value any) error {
+	reflectValue, ok := strictValue(value)
+	if !ok {
+		return nil
+	}
+
+	if _, ok := reflectValue.Interface().(time.Time); !ok {
+		return errors.New("value is not a time.Time (strict mode)")
+	}
+	return nil
+}
+
+// convertToTime converts the supported datetime representations to time.Time:
+// time.Time, bson.DateTime, epoch seconds or millis of any integer type or as
+// a whole float, and strings in RFC3339 or one of the configured layouts.
+func (dt *DateTime) convertToTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case bson.DateTime:
+		return v.Time(), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err == nil {
+			return t, nil
 		}
-		return errors.New("value is a struct but not a time.Time")
-	case reflect.String:
-		// Validate RFC3339 format
-		_, err := time.Parse(time.RFC3339, reflectValue.String())
+
+		for _, layout := range dt.Layouts {
+			if t, layoutErr := time.Parse(layout, v); layoutErr == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, errors.Join(errors.New("value is not a valid RFC3339 datetime string"), err)
+	}
+
+	reflectValue := reflect.ValueOf(value)
+	switch reflectValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		epoch, err := convertToInt(reflectValue)
 		if err != nil {
-			return errors.Join(errors.New("value is not a valid RFC3339 datetime string"), err)
+			return time.Time{}, errors.Join(errors.New("value is not a valid epoch"), err)
 		}
-		return nil // No error for valid RFC3339 string types
-	default:
-		return errors.New("value is not a valid datetime type (expected time.Time or RFC3339 string)")
+		return epochTime(int64(epoch)), nil
+	case reflect.Struct:
+		return time.Time{}, errors.New("value is a struct but not a time.Time")
+	}
+
+	return time.Time{}, errors.New("value is not a valid datetime type (expected time.Time, bson.DateTime, integer epoch or RFC3339 string)")
+}
+
+// epochTime returns the instant of epoch seconds, or of epoch millis when
+// the value is beyond epochMillisThreshold.
+func epochTime(epoch int64) time.Time {
+	if epoch >= epochMillisThreshold || epoch <= -epochMillisThreshold {
+		return time.UnixMilli(epoch)
+	}
+	return time.Unix(epoch, 0)
+}
+
+// Equal implements EqualFieldType. Date-times are equal when they are the
+// same instant to the millisecond, the precision they are stored with.
+func (dt *DateTime) Equal(a, b any) bool {
+	a, b = derefValue(a), derefValue(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	tA, errA := dt.convertToTime(a)
+	tB, errB := dt.convertToTime(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return tA.UnixMilli() == tB.UnixMilli()
+}
+
+// derefValue dereferences a pointer value, returning nil for nil pointers.
+func derefValue(value any) any {
+	reflectValue := reflect.ValueOf(value)
+	if reflectValue.Kind() != reflect.Pointer {
+		return value
 	}
+	if reflectValue.IsNil() {
+		return nil
+	}
+	return reflectValue.Elem().Interface()
 }
 
 var _ JFieldType = &DateTime{}
+var _ StrictFieldType = &DateTime{}
+var _ EqualFieldType = &DateTime{}
 
 // Option represents a single option with unique name and display name
 type Option struct {
 	UniqueName  string `json:"uniqueName"`
 	DisplayName string `json:"displayName"`
+	// Deprecated options are still accepted, with a warning.
+	Deprecated bool `json:"deprecated,omitempty"`
 }
 
 // OptionService defines the interface for getting available options
@@ -404,6 +582,19 @@ type OptionService interface {
 	GetOptions(ctx context.Context) ([]Option, error)
 }
 
+// OptionSearchService is an OptionService for large option lists. Options
+// uses it to validate values with point lookups instead of listing every
+// option.
+type OptionSearchService interface {
+	OptionService
+
+	// SearchOptions returns a page of the options whose unique or display
+	// name matches query. A limit of zero or less returns all matches.
+	SearchOptions(ctx context.Context, query string, limit, offset int) ([]Option, error)
+	// LookupOption returns the option with the unique name, if any.
+	LookupOption(ctx context.Context, uniqueName string) (Option, bool, error)
+}
+
 // Options represents an enum field type that gets its allowed values from a service
 type Options struct {
 	service OptionService
@@ -418,7 +609,7 @@ func NewOptions(service OptionService) *Options {
 
 // Scan implements JFieldType.
 func (o *Options) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	v, ok := row[field.Name()]
+	v, ok := RowValue(field, row)
 	if !ok {
 		return nil, nil // No value found, return nil
 	}
@@ -487,6 +678,17 @@ func (o *Options) Validate(value any) error {
 	// Get the string value (this should be the uniqueName)
 	strValue := reflectValue.String()
 
+	if search, ok := o.service.(OptionSearchService); ok {
+		_, found, err := search.LookupOption(context.Background(), strValue)
+		if err != nil {
+			return errors.Join(errors.New("failed to look up option"), err)
+		}
+		if !found {
+			return errors.New("value is not in the list of available options")
+		}
+		return nil
+	}
+
 	// Get available options from the service
 	availableOptions, err := o.service.GetOptions(context.Background())
 	if err != nil {
@@ -505,6 +707,17 @@ func (o *Options) Validate(value any) error {
 
 // GetDisplayName returns the display name for a given unique name
 func (o *Options) GetDisplayName(ctx context.Context, uniqueName string) (string, error) {
+	if search, ok := o.service.(OptionSearchService); ok {
+		option, found, err := search.LookupOption(ctx, uniqueName)
+		if err != nil {
+			return "", errors.Join(errors.New("failed to look up option"), err)
+		}
+		if !found {
+			return "", errors.New("option not found")
+		}
+		return option.DisplayName, nil
+	}
+
 	availableOptions, err := o.service.GetOptions(ctx)
 	if err != nil {
 		return "", errors.Join(errors.New("failed to get available options"), err)
@@ -540,6 +753,46 @@ func (o *Options) GetAllOptions(ctx context.Context) ([]Option, error) {
 	return o.service.GetOptions(ctx)
 }
 
+// SearchOptions returns a page of the options matching query, searching in
+// the service when it implements OptionSearchService.
+func (o *Options) SearchOptions(ctx context.Context, query string, limit, offset int) ([]Option, error) {
+	if search, ok := o.service.(OptionSearchService); ok {
+		return search.SearchOptions(ctx, query, limit, offset)
+	}
+
+	availableOptions, err := o.service.GetOptions(ctx)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed to get available options"), err)
+	}
+	return pageOptions(matchOptions(availableOptions, query), limit, offset), nil
+}
+
+// matchOptions returns the options whose unique or display name contains
+// query, ignoring case.
+func matchOptions(options []Option, query string) []Option {
+	query = strings.ToLower(query)
+
+	var matches []Option
+	for _, option := range options {
+		if strings.Contains(strings.ToLower(option.UniqueName), query) ||
+			strings.Contains(strings.ToLower(option.DisplayName), query) {
+			matches = append(matches, option)
+		}
+	}
+	return matches
+}
+
+func pageOptions(options []Option, limit, offset int) []Option {
+	if offset >= len(options) {
+		return []Option{}
+	}
+	options = options[max(offset, 0):]
+	if limit > 0 && limit < len(options) {
+		options = options[:limit]
+	}
+	return options
+}
+
 var _ JFieldType = &Options{}
 
 // Boolean represents a boolean field type
@@ -547,10 +800,8 @@ type Boolean struct{}
 
 // Scan implements JFieldType interface for boolean values
 func (b *Boolean) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
-	fieldName := field.Name()
-
 	// Check if field exists in row
-	if rawValue, exists := row[fieldName]; !exists {
+	if rawValue, exists := RowValue(field, row); !exists {
 		return nil, nil // Field doesn't exist, return nil
 	} else if rawValue == nil {
 		return nil, nil // Field is nil, return nil
@@ -595,6 +846,19 @@ func (b *Boolean) Validate(value any) error {
 	return err
 }
 
+// ValidateStrict implements StrictFieldType. Only Go booleans are accepted.
+func (b *Boolean) ValidateStrict(value any) error {
+	reflectValue, ok := strictValue(value)
+	if !ok {
+		return nil
+	}
+
+	if reflectValue.Kind() != reflect.Bool {
+		return errors.New("value is not a boolean (strict mode)")
+	}
+	return nil
+}
+
 // convertToBool converts various types to boolean
 func convertToBool(value any) (bool, error) {
 	reflectValue := reflect.ValueOf(value)
@@ -654,3 +918,4 @@ func convertToBool(value any) (bool, error) {
 }
 
 var _ JFieldType = &Boolean{}
+var _ StrictFieldType = &Boolean{}
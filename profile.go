@@ -0,0 +1,285 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const defaultProfileSampleSize = 1000
+
+// reservedKeys are document keys jpack writes besides the schema's fields.
+var reservedKeys = []string{defaultMongoPK, treeIDKey, idempotencyKeyField, lineageKey}
+
+// FieldProfile is what a sample of stored documents holds for one key.
+type FieldProfile struct {
+	// Name is the field name, or the stored key of an undeclared one.
+	Name string
+	// Declared reports whether the schema declares the field.
+	Declared bool
+	// Present counts the sampled documents with a value for the field,
+	// null included.
+	Present int
+	// Aliased counts the values still stored under a previous name.
+	Aliased int
+	// Types counts the observed BSON types by their $type alias, e.g.
+	// "string", "int" or "null".
+	Types map[string]int
+	// Violations counts the values outside the declared constraints, like
+	// Check reports them. Refs aren't looked up.
+	Violations map[CheckIssueKind]int
+}
+
+// Presence returns the percentage of the sampled documents that have a
+// value for the field.
+func (f FieldProfile) Presence(sampled int) float64 {
+	if sampled == 0 {
+		return 0
+	}
+	return 100 * float64(f.Present) / float64(sampled)
+}
+
+// Drifted reports whether the stored values differ from the declaration:
+// an undeclared key, values of several types, values outside the
+// constraints, or values stored under a previous name.
+func (f FieldProfile) Drifted() bool {
+	types := 0
+	for t := range f.Types {
+		if t != "null" {
+			types++
+		}
+	}
+	return !f.Declared || types > 1 || len(f.Violations) > 0 || f.Aliased > 0
+}
+
+// SchemaProfile is the outcome of Profile.
+type SchemaProfile struct {
+	Schema  string
+	Sampled int
+	// Fields holds the declared fields in schema order, then the
+	// undeclared keys by name.
+	Fields []FieldProfile
+}
+
+// Field returns the profile of a field or undeclared key.
+func (p *SchemaProfile) Field(name string) (FieldProfile, bool) {
+	i := slices.IndexFunc(p.Fields, func(f FieldProfile) bool { return f.Name == name })
+	if i < 0 {
+		return FieldProfile{}, false
+	}
+	return p.Fields[i], true
+}
+
+// Drift returns the profiles of the fields that drifted from the schema.
+func (p *SchemaProfile) Drift() []FieldProfile {
+	var drifted []FieldProfile
+	for _, f := range p.Fields {
+		if f.Drifted() {
+			drifted = append(drifted, f)
+		}
+	}
+	return drifted
+}
+
+// String formats the profile as a report of one line per field.
+func (p *SchemaProfile) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d documents sampled\n", p.Schema, p.Sampled)
+	for _, f := range p.Fields {
+		fmt.Fprintf(&b, "  %-24s %6.1f%%  %s", f.Name, f.Presence(p.Sampled), countsString(f.Types))
+		if !f.Declared {
+			b.WriteString("  undeclared")
+		}
+		if f.Aliased > 0 {
+			fmt.Fprintf(&b, "  aliased=%d", f.Aliased)
+		}
+		if len(f.Violations) > 0 {
+			violations := make(map[string]int, len(f.Violations))
+			for kind, n := range f.Violations {
+				violations[string(kind)] = n
+			}
+			fmt.Fprintf(&b, "  violations: %s", countsString(violations))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func countsString(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%d", key, counts[key])
+	}
+	return strings.Join(parts, " ")
+}
+
+// Profile samples up to sampleSize stored documents of the schema, 1000 when
+// zero, and reports per field how often it is present, the BSON types it is
+// stored as and the values outside its declared constraints, along with the
+// keys the schema doesn't declare. It guides schema evolution on long-lived
+// databases; use Check to find every offending document.
+func Profile(ctx context.Context, schema JSchema, sampleSize int) (*SchemaProfile, error) {
+	if sampleSize <= 0 {
+		sampleSize = defaultProfileSampleSize
+	}
+
+	cursor, err := collection(ctx, schema).Aggregate(ctx, mongo.Pipeline{{{Key: "$sample", Value: bson.M{"size": sampleSize}}}})
+	if err != nil {
+		return nil, fmt.Errorf("jpack: profiling %s: %w", schema.Name(), err)
+	}
+	defer cursor.Close(ctx)
+
+	p := newProfiler(schema)
+	for cursor.Next(ctx) {
+		if err := p.add(ctx, cursor.Current); err != nil {
+			return nil, fmt.Errorf("jpack: profiling %s: %w", schema.Name(), err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("jpack: profiling %s: %w", schema.Name(), err)
+	}
+	return p.profile(), nil
+}
+
+// profiler accumulates the profile of sampled documents.
+type profiler struct {
+	schema     JSchema
+	sampled    int
+	declared   []*FieldProfile
+	undeclared map[string]*FieldProfile
+	// owners maps the stored keys of declared fields to their index
+	owners map[string]int
+}
+
+func newProfiler(schema JSchema) *profiler {
+	p := &profiler{schema: schema, undeclared: make(map[string]*FieldProfile), owners: make(map[string]int)}
+	for i, field := range schema.Fields() {
+		p.declared = append(p.declared, &FieldProfile{Name: field.Name(), Declared: true, Types: map[string]int{}, Violations: map[CheckIssueKind]int{}})
+		for _, key := range append(storageKeys(field), FieldAliases(field)...) {
+			p.owners[key] = i
+		}
+		if pk, ok := PK(schema); ok && pk.Name() == field.Name() {
+			p.owners[defaultMongoPK] = i
+		}
+	}
+	return p
+}
+
+// add profiles a stored document.
+func (p *profiler) add(ctx context.Context, raw bson.Raw) error {
+	elements, err := raw.Elements()
+	if err != nil {
+		return err
+	}
+	p.sampled++
+
+	fields := p.schema.Fields()
+	present := make([]bool, len(fields))
+	for _, element := range elements {
+		key := element.Key()
+		typ := bsonTypeAlias(element.Value().Type)
+
+		i, ok := p.owners[key]
+		if !ok && slices.Contains(reservedKeys, key) {
+			continue
+		}
+		if !ok {
+			f := p.undeclared[key]
+			if f == nil {
+				f = &FieldProfile{Name: key, Types: map[string]int{}}
+				p.undeclared[key] = f
+			}
+			f.Present++
+			f.Types[typ]++
+			continue
+		}
+
+		f := p.declared[i]
+		f.Types[typ]++
+		if slices.Contains(FieldAliases(fields[i]), key) {
+			f.Aliased++
+		}
+		present[i] = true
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+	decoded, err := decodeDocument(ctx, p.schema, doc)
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		if present[i] {
+			p.declared[i].Present++
+		}
+		if issue, _ := checkField(ctx, field, decoded); issue.Kind != "" {
+			p.declared[i].Violations[issue.Kind]++
+		}
+	}
+	return nil
+}
+
+func (p *profiler) profile() *SchemaProfile {
+	profile := &SchemaProfile{Schema: p.schema.Name(), Sampled: p.sampled}
+	for _, f := range p.declared {
+		profile.Fields = append(profile.Fields, *f)
+	}
+
+	keys := make([]string, 0, len(p.undeclared))
+	for key := range p.undeclared {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		profile.Fields = append(profile.Fields, *p.undeclared[key])
+	}
+	return profile
+}
+
+// bsonTypeAlias returns the $type alias of a BSON type.
+func bsonTypeAlias(t bson.Type) string {
+	switch t {
+	case bson.TypeDouble:
+		return "double"
+	case bson.TypeString:
+		return "string"
+	case bson.TypeEmbeddedDocument:
+		return "object"
+	case bson.TypeArray:
+		return "array"
+	case bson.TypeBinary:
+		return "binData"
+	case bson.TypeObjectID:
+		return "objectId"
+	case bson.TypeBoolean:
+		return "bool"
+	case bson.TypeDateTime:
+		return "date"
+	case bson.TypeNull:
+		return "null"
+	case bson.TypeRegex:
+		return "regex"
+	case bson.TypeInt32:
+		return "int"
+	case bson.TypeTimestamp:
+		return "timestamp"
+	case bson.TypeInt64:
+		return "long"
+	case bson.TypeDecimal128:
+		return "decimal"
+	}
+	return t.String()
+}
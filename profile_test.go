@@ -0,0 +1,70 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestProfile(t *testing.T) {
+	status := NewOptions(NewInMemoryOptionService([]Option{{UniqueName: "draft"}, {UniqueName: "published"}}))
+	schema := NewSchema("test_profile").
+		Field("id", &String{}).
+		Field("title", &String{}, Required()).
+		Field("pages", &Number{}, FieldAlias("page_count")).
+		Field("status", status).
+		Build()
+
+	docs := []bson.M{
+		{"_id": bson.NewObjectID(), "title": "Ok", "pages": int32(10), "status": "draft"},
+		{"_id": bson.NewObjectID(), "title": "Long", "page_count": int64(900), "status": "archived", "legacy_flag": true},
+		{"_id": bson.NewObjectID(), "title": nil, "pages": "many", "_lineage": bson.M{}},
+		{"_id": bson.NewObjectID(), "title": "Short", "legacy_flag": false},
+	}
+	p := newProfiler(schema)
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		assert.NoError(t, err)
+		assert.NoError(t, p.add(context.Background(), raw))
+	}
+	profile := p.profile()
+
+	assert.Equal(t, 4, profile.Sampled)
+	assert.Equal(t, []string{"id", "title", "pages", "status", "legacy_flag"}, func() []string {
+		var names []string
+		for _, f := range profile.Fields {
+			names = append(names, f.Name)
+		}
+		return names
+	}())
+
+	id, _ := profile.Field("id")
+	assert.Equal(t, map[string]int{"objectId": 4}, id.Types)
+
+	title, _ := profile.Field("title")
+	assert.Equal(t, 4, title.Present)
+	assert.Equal(t, map[string]int{"string": 3, "null": 1}, title.Types)
+	assert.Equal(t, map[CheckIssueKind]int{IssueMissingRequired: 1}, title.Violations)
+
+	pages, _ := profile.Field("pages")
+	assert.Equal(t, 75.0, pages.Presence(profile.Sampled))
+	assert.Equal(t, 1, pages.Aliased)
+	assert.Equal(t, map[string]int{"int": 1, "long": 1, "string": 1}, pages.Types)
+	assert.Equal(t, map[CheckIssueKind]int{IssueTypeMismatch: 1}, pages.Violations)
+
+	statusProfile, _ := profile.Field("status")
+	assert.Equal(t, map[CheckIssueKind]int{IssueInvalidOption: 1}, statusProfile.Violations)
+
+	legacy, _ := profile.Field("legacy_flag")
+	assert.False(t, legacy.Declared)
+	assert.Equal(t, 50.0, legacy.Presence(profile.Sampled))
+
+	var drifted []string
+	for _, f := range profile.Drift() {
+		drifted = append(drifted, f.Name)
+	}
+	assert.Equal(t, []string{"title", "pages", "status", "legacy_flag"}, drifted)
+	assert.Contains(t, profile.String(), "legacy_flag                50.0%  bool=2  undeclared")
+}
@@ -0,0 +1,216 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// PrometheusMetricsPath is the path EnablePrometheus serves the metrics on.
+const PrometheusMetricsPath = "/metrics"
+
+var (
+	// defaultLatencyBuckets are the upper bounds, in seconds, of the
+	// operation latency histogram.
+	defaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// defaultBulkSizeBuckets are the upper bounds of the bulk size histogram.
+	defaultBulkSizeBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000}
+)
+
+// bulkOperations are the operations whose document counts are observed as
+// bulk sizes.
+var bulkOperations = []string{"insert", "upsert", "update", "delete", "cleanup"}
+
+// PrometheusMetrics is a QueryTap exporting the operations it observes in the
+// Prometheus text format:
+//
+//   - jpack_operations_total, counted by collection, operation and status
+//   - jpack_operation_duration_seconds, a latency histogram by collection and
+//     operation
+//   - jpack_bulk_size, a histogram of the documents written or deleted by a
+//     write, by collection and operation
+//   - jpack_operations_in_flight, the commands sent to MongoDB awaiting a
+//     reply, when CommandMonitor is installed on the client
+//
+// It serves the metrics as an http.Handler, so it needs no Prometheus client
+// library.
+type PrometheusMetrics struct {
+	mu         sync.Mutex
+	operations map[metricLabels]int64
+	latency    map[metricLabels]*histogram
+	bulkSizes  map[metricLabels]*histogram
+	inFlight   atomic.Int64
+}
+
+// metricLabels are the label values of a series.
+type metricLabels struct {
+	collection string
+	operation  string
+	status     string
+}
+
+// histogram is a Prometheus histogram with cumulative buckets.
+type histogram struct {
+	bounds []float64
+	counts []int64
+	count  int64
+	sum    float64
+}
+
+var _ QueryTap = &PrometheusMetrics{}
+var _ http.Handler = &PrometheusMetrics{}
+
+// NewPrometheusMetrics creates an exporter without observations.
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		operations: make(map[metricLabels]int64),
+		latency:    make(map[metricLabels]*histogram),
+		bulkSizes:  make(map[metricLabels]*histogram),
+	}
+}
+
+// MetricsRegisterer mounts a metrics handler, e.g. an *http.ServeMux.
+type MetricsRegisterer interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// EnablePrometheus exports every operation, through the package-wide query
+// tap, on the PrometheusMetricsPath of registerer. A tap set earlier keeps
+// being called. Install CommandMonitor of the returned metrics on the MongoDB
+// client to also report the operations in flight.
+func EnablePrometheus(registerer MetricsRegisterer) *PrometheusMetrics {
+	metrics := NewPrometheusMetrics()
+	addQueryTap(metrics)
+	registerer.Handle(PrometheusMetricsPath, metrics)
+	return metrics
+}
+
+// Tap implements QueryTap.
+func (p *PrometheusMetrics) Tap(ctx context.Context, op QueryOperation) {
+	status := "ok"
+	if op.Err != nil {
+		status = "error"
+	}
+	series := metricLabels{collection: op.Collection, operation: op.Operation}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.operations[metricLabels{collection: op.Collection, operation: op.Operation, status: status}]++
+	observe(p.latency, series, defaultLatencyBuckets, op.Duration.Seconds())
+	if op.Err == nil && slices.Contains(bulkOperations, op.Operation) {
+		observe(p.bulkSizes, series, defaultBulkSizeBuckets, float64(op.Count))
+	}
+}
+
+// observe adds a value to the histogram of the series.
+func observe(histograms map[metricLabels]*histogram, series metricLabels, bounds []float64, value float64) {
+	h, ok := histograms[series]
+	if !ok {
+		h = &histogram{bounds: bounds, counts: make([]int64, len(bounds))}
+		histograms[series] = h
+	}
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// CommandMonitor returns a monitor for options.Client().SetMonitor that counts
+// the commands in flight. Chain it by hand with a monitor of your own.
+func (p *PrometheusMetrics) CommandMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started:   func(context.Context, *event.CommandStartedEvent) { p.inFlight.Add(1) },
+		Succeeded: func(context.Context, *event.CommandSucceededEvent) { p.inFlight.Add(-1) },
+		Failed:    func(context.Context, *event.CommandFailedEvent) { p.inFlight.Add(-1) },
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (p *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := p.WriteTo(w); err != nil {
+		LoggerFrom(r.Context()).Error().Err(err).Msg("jpack: failed to write metrics")
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (p *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	p.mu.Lock()
+	b.WriteString("# HELP jpack_operations_total Database operations by collection, operation and status.\n")
+	b.WriteString("# TYPE jpack_operations_total counter\n")
+	for _, series := range sortedSeries(p.operations) {
+		fmt.Fprintf(&b, "jpack_operations_total{%s} %d\n", series.format(), p.operations[series])
+	}
+	writeHistograms(&b, "jpack_operation_duration_seconds", "Latency of database operations in seconds.", p.latency)
+	writeHistograms(&b, "jpack_bulk_size", "Documents written or deleted by a write operation.", p.bulkSizes)
+	p.mu.Unlock()
+
+	b.WriteString("# HELP jpack_operations_in_flight MongoDB commands awaiting a reply.\n")
+	b.WriteString("# TYPE jpack_operations_in_flight gauge\n")
+	fmt.Fprintf(&b, "jpack_operations_in_flight %d\n", p.inFlight.Load())
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeHistograms writes the histograms of a metric.
+func writeHistograms(b *strings.Builder, name, help string, histograms map[metricLabels]*histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, series := range sortedSeries(histograms) {
+		h, labels := histograms[series], series.format()
+		for i, bound := range h.bounds {
+			fmt.Fprintf(b, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(b, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+// sortedSeries returns the series of a metric in a stable order.
+func sortedSeries[V any](metric map[metricLabels]V) []metricLabels {
+	series := make([]metricLabels, 0, len(metric))
+	for labels := range metric {
+		series = append(series, labels)
+	}
+	slices.SortFunc(series, func(a, b metricLabels) int {
+		return strings.Compare(a.collection+"\x00"+a.operation+"\x00"+a.status, b.collection+"\x00"+b.operation+"\x00"+b.status)
+	})
+	return series
+}
+
+// format formats the labels of a series.
+func (l metricLabels) format() string {
+	labels := fmt.Sprintf("collection=%s,operation=%s", quoteLabel(l.collection), quoteLabel(l.operation))
+	if l.status != "" {
+		labels += ",status=" + quoteLabel(l.status)
+	}
+	return labels
+}
+
+// labelEscaper escapes label values as the text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quoteLabel quotes a label value.
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// formatFloat formats a sample value.
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,74 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	t.Run("operations are counted and timed", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		ctx := context.Background()
+		metrics.Tap(ctx, QueryOperation{Collection: "users", Operation: "find", Duration: 3 * time.Millisecond, Count: 10})
+		metrics.Tap(ctx, QueryOperation{Collection: "users", Operation: "find", Duration: 30 * time.Millisecond, Err: errors.New("boom")})
+		metrics.Tap(ctx, QueryOperation{Collection: "users", Operation: "insert", Duration: time.Millisecond, Count: 20})
+
+		var b strings.Builder
+		_, err := metrics.WriteTo(&b)
+		assert.NoError(t, err)
+		out := b.String()
+
+		assert.Contains(t, out, `jpack_operations_total{collection="users",operation="find",status="error"} 1`)
+		assert.Contains(t, out, `jpack_operations_total{collection="users",operation="find",status="ok"} 1`)
+		assert.Contains(t, out, `jpack_operation_duration_seconds_bucket{collection="users",operation="find",le="0.005"} 1`)
+		assert.Contains(t, out, `jpack_operation_duration_seconds_bucket{collection="users",operation="find",le="0.05"} 2`)
+		assert.Contains(t, out, `jpack_operation_duration_seconds_count{collection="users",operation="find"} 2`)
+		assert.Contains(t, out, `jpack_bulk_size_bucket{collection="users",operation="insert",le="10"} 0`)
+		assert.Contains(t, out, `jpack_bulk_size_bucket{collection="users",operation="insert",le="25"} 1`)
+		assert.Contains(t, out, `jpack_bulk_size_sum{collection="users",operation="insert"} 20`)
+		assert.NotContains(t, out, `jpack_bulk_size_count{collection="users",operation="find"}`)
+		assert.Contains(t, out, "jpack_operations_in_flight 0")
+	})
+
+	t.Run("label values are escaped", func(t *testing.T) {
+		assert.Equal(t, `"a\"b\\c\nd"`, quoteLabel("a\"b\\c\nd"))
+	})
+
+	t.Run("commands in flight", func(t *testing.T) {
+		metrics := NewPrometheusMetrics()
+		monitor := metrics.CommandMonitor()
+		monitor.Started(context.Background(), &event.CommandStartedEvent{})
+		monitor.Started(context.Background(), &event.CommandStartedEvent{})
+		monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{})
+
+		var b strings.Builder
+		_, err := metrics.WriteTo(&b)
+		assert.NoError(t, err)
+		assert.Contains(t, b.String(), "jpack_operations_in_flight 1")
+	})
+
+	t.Run("EnablePrometheus serves tapped operations", func(t *testing.T) {
+		previous := &RecordingQueryTap{}
+		SetQueryTap(previous)
+		defer SetQueryTap(nil)
+
+		mux := http.NewServeMux()
+		EnablePrometheus(mux)
+		tapQuery(context.Background(), QueryOperation{Collection: "orders", Operation: "count"}, time.Now())
+		assert.Len(t, previous.Operations(), 1, "the earlier tap keeps being called")
+
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, PrometheusMetricsPath, nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, rec.Body.String(), `jpack_operations_total{collection="orders",operation="count",status="ok"} 1`)
+	})
+}
@@ -0,0 +1,430 @@
+package jpack
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Protobuf wire types used by the encoder.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// ProtoNumber pins the protobuf field number of a field. Fields without one are
+// numbered by their position in the schema, so pin numbers before reordering
+// or removing fields of a published message.
+func ProtoNumber(n int) FieldOption {
+	return func(f *fieldImpl) {
+		f.protoNumber = n
+	}
+}
+
+type protoField struct {
+	number int
+	field  JField
+}
+
+// protoFields returns the fields of a schema with their protobuf numbers.
+func protoFields(schema JSchema) ([]protoField, error) {
+	fields := make([]protoField, 0, len(schema.Fields()))
+	seen := make(map[int]string)
+
+	for i, field := range schema.Fields() {
+		number := i + 1
+		if f, ok := field.(interface{ ProtoNumber() int }); ok && f.ProtoNumber() > 0 {
+			number = f.ProtoNumber()
+		}
+
+		if other, ok := seen[number]; ok {
+			return nil, fmt.Errorf("jpack: %s: fields %s and %s share protobuf number %d", schema.Name(), other, field.Name(), number)
+		}
+		seen[number] = field.Name()
+
+		fields = append(fields, protoField{number: number, field: field})
+	}
+
+	return fields, nil
+}
+
+// protoName converts a snake_case name to the CamelCase used for messages.
+func protoName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == ' ' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// GenerateProto writes a proto3 file with a message per schema. Message names
+// are the CamelCase schema names; composite fields become nested messages.
+func GenerateProto(w io.Writer, pkg string, schemas ...JSchema) error {
+	if len(schemas) == 0 {
+		schemas = RegisteredSchemas()
+	}
+
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n")
+	if pkg != "" {
+		fmt.Fprintf(&b, "\npackage %s;\n", pkg)
+	}
+	b.WriteString("\nimport \"google/protobuf/timestamp.proto\";\n")
+
+	for _, schema := range schemas {
+		fields, err := protoFields(schema)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&b, "\nmessage %s {\n", protoName(schema.Name()))
+		for _, pf := range fields {
+			if composite, ok := pf.field.Type().(*Composite); ok {
+				if err := writeProtoComposite(&b, pf.field, composite); err != nil {
+					return err
+				}
+			}
+		}
+		for _, pf := range fields {
+			typeName, err := protoType(pf.field.Name(), pf.field.Type())
+			if err != nil {
+				return fmt.Errorf("jpack: %s.%s: %w", schema.Name(), pf.field.Name(), err)
+			}
+			fmt.Fprintf(&b, "  %s %s = %d;\n", typeName, pf.field.Name(), pf.number)
+		}
+		b.WriteString("}\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeProtoComposite(b *strings.Builder, field JField, composite *Composite) error {
+	fmt.Fprintf(b, "  message %s {\n", protoName(field.Name()))
+	for i, part := range composite.Parts {
+		typeName, err := protoType(part.Name, part.Type)
+		if err != nil {
+			return fmt.Errorf("jpack: %s.%s: %w", field.Name(), part.Name, err)
+		}
+		fmt.Fprintf(b, "    %s %s = %d;\n", typeName, part.Name, i+1)
+	}
+	b.WriteString("  }\n")
+	return nil
+}
+
+// protoType returns the proto3 type of a field. Scalars are optional so unset
+// and zero values stay distinguishable.
+func protoType(name string, fType JFieldType) (string, error) {
+	switch baseType(fType).(type) {
+	case *String, *Text, *Options, *DependentOptions, *Ref:
+		return "optional string", nil
+	case *Number:
+		return "optional int64", nil
+	case *Boolean:
+		return "optional bool", nil
+	case *DateTime:
+		return "google.protobuf.Timestamp", nil
+	case *Composite:
+		return protoName(name), nil
+	default:
+		return "", fmt.Errorf("no protobuf mapping for field type %s", FieldTypeName(fType))
+	}
+}
+
+// MarshalProto encodes a record in the protobuf wire format of the message
+// GenerateProto emits for its schema.
+func MarshalProto(record JRecord) ([]byte, error) {
+	fields, err := protoFields(record.Schema())
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	for _, pf := range fields {
+		value, ok := record.Value(pf.field)
+		if !ok {
+			continue
+		}
+
+		value, err := normalizeValue(pf.field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pf.field.Name(), err)
+		}
+
+		buf, err = appendProtoValue(buf, pf.number, pf.field.Type(), value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pf.field.Name(), err)
+		}
+	}
+
+	return buf, nil
+}
+
+// normalizeValue converts a value to the representation its field type reads
+// back from a row, e.g. int for numbers and UTC time.Time for datetimes.
+func normalizeValue(field JField, value any) (any, error) {
+	ctx := context.Background()
+
+	row := make(map[string]any)
+	if err := field.Type().SetValue(ctx, field, value, row); err != nil {
+		return nil, err
+	}
+	return scanField(ctx, field, row)
+}
+
+func appendProtoValue(buf []byte, number int, fType JFieldType, value any) ([]byte, error) {
+	if value == nil {
+		return buf, nil
+	}
+
+	switch t := baseType(fType).(type) {
+	case *String, *Text, *Options, *DependentOptions, *Ref:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", value)
+		}
+		return appendProtoBytes(buf, number, []byte(s)), nil
+	case *Number:
+		n, ok := value.(int)
+		if !ok {
+			return nil, fmt.Errorf("expected int, got %T", value)
+		}
+		buf = appendProtoTag(buf, number, protoVarint)
+		return binary.AppendUvarint(buf, uint64(int64(n))), nil
+	case *Boolean:
+		v, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected bool, got %T", value)
+		}
+		buf = appendProtoTag(buf, number, protoVarint)
+		if v {
+			return append(buf, 1), nil
+		}
+		return append(buf, 0), nil
+	case *DateTime:
+		ts, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("expected time.Time, got %T", value)
+		}
+		// google.protobuf.Timestamp: seconds = 1, nanos = 2
+		var msg []byte
+		msg = appendProtoTag(msg, 1, protoVarint)
+		msg = binary.AppendUvarint(msg, uint64(ts.Unix()))
+		msg = appendProtoTag(msg, 2, protoVarint)
+		msg = binary.AppendUvarint(msg, uint64(ts.Nanosecond()))
+		return appendProtoBytes(buf, number, msg), nil
+	case *Composite:
+		parts, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected map[string]any, got %T", value)
+		}
+		var msg []byte
+		for i, part := range t.Parts {
+			var err error
+			msg, err = appendProtoValue(msg, i+1, part.Type, parts[part.Name])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", part.Name, err)
+			}
+		}
+		return appendProtoBytes(buf, number, msg), nil
+	default:
+		return nil, fmt.Errorf("no protobuf mapping for field type %s", FieldTypeName(fType))
+	}
+}
+
+func appendProtoTag(buf []byte, number int, wireType int) []byte {
+	return binary.AppendUvarint(buf, uint64(number)<<3|uint64(wireType))
+}
+
+func appendProtoBytes(buf []byte, number int, data []byte) []byte {
+	buf = appendProtoTag(buf, number, protoBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
+
+// UnmarshalProto decodes a message produced by MarshalProto, or by any
+// protobuf implementation of the generated message, into a record. Unknown
+// field numbers are skipped so older readers accept newer messages.
+func UnmarshalProto(schema JSchema, data []byte) (JRecord, error) {
+	fields, err := protoFields(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	byNumber := make(map[int]JField, len(fields))
+	for _, pf := range fields {
+		byNumber[pf.number] = pf.field
+	}
+
+	values := make(map[string]any)
+	err = readProtoMessage(data, func(number, wireType int, raw []byte, n uint64) error {
+		field, ok := byNumber[number]
+		if !ok {
+			return nil
+		}
+
+		value, err := decodeProtoValue(field.Type(), wireType, raw, n)
+		if err != nil {
+			return fmt.Errorf("%s: %w", field.Name(), err)
+		}
+		values[field.Name()] = value
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record := NewMongoRecord(schema)
+
+	// Messages without a primary key describe records that don't exist yet
+	pkField, ok := PK(schema)
+	if !ok || values[pkField.Name()] == nil {
+		for _, field := range schema.Fields() {
+			if value, ok := values[field.Name()]; ok {
+				if err := record.SetValue(field, value); err != nil {
+					return nil, fmt.Errorf("%s: %w", field.Name(), err)
+				}
+			}
+		}
+		return record, nil
+	}
+
+	ctx := context.Background()
+	for _, field := range schema.Fields() {
+		value, ok := values[field.Name()]
+		if !ok {
+			continue
+		}
+		if err := field.Type().SetValue(ctx, field, value, record.originalRecord); err != nil {
+			return nil, fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+
+	return record, nil
+}
+
+// readProtoMessage calls fn for every field of a message. Varint fields are
+// passed in n, length-delimited ones in raw.
+func readProtoMessage(data []byte, fn func(number, wireType int, raw []byte, n uint64) error) error {
+	for len(data) > 0 {
+		tag, size := binary.Uvarint(data)
+		if size <= 0 {
+			return errors.New("jpack: malformed protobuf tag")
+		}
+		data = data[size:]
+
+		number, wireType := int(tag>>3), int(tag&7)
+		var raw []byte
+		var n uint64
+
+		switch wireType {
+		case protoVarint:
+			n, size = binary.Uvarint(data)
+			if size <= 0 {
+				return errors.New("jpack: malformed protobuf varint")
+			}
+			data = data[size:]
+		case protoFixed64, protoFixed32:
+			width := 8
+			if wireType == protoFixed32 {
+				width = 4
+			}
+			if len(data) < width {
+				return errors.New("jpack: truncated protobuf message")
+			}
+			raw, data = data[:width], data[width:]
+		case protoBytes:
+			length, size := binary.Uvarint(data)
+			if size <= 0 || uint64(len(data)-size) < length {
+				return errors.New("jpack: truncated protobuf message")
+			}
+			data = data[size:]
+			raw, data = data[:length], data[length:]
+		default:
+			return fmt.Errorf("jpack: unsupported protobuf wire type %d", wireType)
+		}
+
+		if err := fn(number, wireType, raw, n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeProtoValue(fType JFieldType, wireType int, raw []byte, n uint64) (any, error) {
+	fType = baseType(fType)
+	expected := protoVarint
+	switch fType.(type) {
+	case *String, *Text, *Options, *DependentOptions, *Ref, *DateTime, *Composite:
+		expected = protoBytes
+	}
+	if wireType != expected {
+		return nil, fmt.Errorf("unexpected protobuf wire type %d", wireType)
+	}
+
+	switch t := fType.(type) {
+	case *String, *Text, *Options, *DependentOptions, *Ref:
+		return string(raw), nil
+	case *Number:
+		v := int64(n)
+		if v > math.MaxInt || v < math.MinInt {
+			return nil, errors.New("number out of range")
+		}
+		return int(v), nil
+	case *Boolean:
+		return n != 0, nil
+	case *DateTime:
+		var seconds, nanos int64
+		err := readProtoMessage(raw, func(number, _ int, _ []byte, n uint64) error {
+			switch number {
+			case 1:
+				seconds = int64(n)
+			case 2:
+				nanos = int64(n)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return time.Unix(seconds, nanos).UTC(), nil
+	case *Composite:
+		parts := make(map[string]any, len(t.Parts))
+		err := readProtoMessage(raw, func(number, wireType int, raw []byte, n uint64) error {
+			if number < 1 || number > len(t.Parts) {
+				return nil
+			}
+			part := t.Parts[number-1]
+			value, err := decodeProtoValue(part.Type, wireType, raw, n)
+			if err != nil {
+				return fmt.Errorf("%s: %w", part.Name, err)
+			}
+			parts[part.Name] = value
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return parts, nil
+	default:
+		return nil, fmt.Errorf("no protobuf mapping for field type %s", FieldTypeName(fType))
+	}
+}
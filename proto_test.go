@@ -0,0 +1,124 @@
+package jpack
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestProto(t *testing.T) {
+	money := NewComposite(
+		CompositePart{Name: "amount", Type: &Number{}},
+		CompositePart{Name: "currency", Type: &String{}},
+	)
+
+	orderSchema := NewSchema("proto_order").
+		Field("id", &String{}).
+		Field("total", money).
+		Field("paid", &Boolean{}).
+		Field("placed_at", &DateTime{}, ProtoNumber(10)).
+		Ref("customer", userSchema).
+		Build()
+
+	t.Run("generate", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.NoError(t, GenerateProto(&buf, "shop.v1", orderSchema))
+
+		assert.Equal(t, `syntax = "proto3";
+
+package shop.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message ProtoOrder {
+  message Total {
+    optional int64 amount = 1;
+    optional string currency = 2;
+  }
+  optional string id = 1;
+  Total total = 2;
+  optional bool paid = 3;
+  google.protobuf.Timestamp placed_at = 10;
+  optional string customer = 5;
+}
+`, buf.String())
+	})
+
+	t.Run("duplicate numbers", func(t *testing.T) {
+		schema := NewSchema("proto_dup").
+			Field("a", &String{}).
+			Field("b", &String{}, ProtoNumber(1)).
+			Build()
+
+		var buf bytes.Buffer
+		assert.Error(t, GenerateProto(&buf, "", schema))
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		assert := assert.New(t)
+
+		id := bson.NewObjectID()
+		customer := bson.NewObjectID().Hex()
+		placedAt := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+		record, err := RecordFromBSON(orderSchema, bson.M{
+			"_id":            id,
+			"total_amount":   int32(1250),
+			"total_currency": "EUR",
+			"paid":           false,
+			"placed_at":      placedAt,
+			"customer":       customer,
+		})
+		assert.NoError(err)
+
+		data, err := MarshalProto(record)
+		assert.NoError(err)
+
+		decoded, err := UnmarshalProto(orderSchema, data)
+		assert.NoError(err)
+		assert.False(decoded.IsNew())
+
+		for name, want := range map[string]any{
+			"id":        id.Hex(),
+			"total":     map[string]any{"amount": 1250, "currency": "EUR"},
+			"paid":      false,
+			"placed_at": placedAt,
+			"customer":  customer,
+		} {
+			got, ok := decoded.Value(mustField(t, orderSchema, name))
+			assert.True(ok, name)
+			assert.Equal(want, got, name)
+		}
+	})
+
+	t.Run("messages without id decode as new records", func(t *testing.T) {
+		assert := assert.New(t)
+
+		record := NewMongoRecord(orderSchema)
+		assert.NoError(record.SetValue(mustField(t, orderSchema, "paid"), true))
+
+		data, err := MarshalProto(record)
+		assert.NoError(err)
+
+		decoded, err := UnmarshalProto(orderSchema, data)
+		assert.NoError(err)
+		assert.True(decoded.IsNew())
+		assert.Equal([]string{"paid"}, decoded.DirtyKeys())
+	})
+
+	t.Run("unknown fields are skipped", func(t *testing.T) {
+		data := appendProtoBytes(nil, 99, []byte("future"))
+		data = appendProtoTag(data, 3, protoVarint)
+		data = append(data, 1)
+
+		decoded, err := UnmarshalProto(orderSchema, data)
+		assert.NoError(t, err)
+
+		paid, ok := decoded.Value(mustField(t, orderSchema, "paid"))
+		assert.True(t, ok)
+		assert.Equal(t, true, paid)
+	})
+}
@@ -1,6 +1,10 @@
 package jpack
 
 import (
+	"context"
+	"iter"
+	"time"
+
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -26,14 +30,35 @@ type Query interface {
 	// offset clause
 	Offset(int) Query
 
+	// caches the results for ttl; the cache entry is dropped when any of the
+	// tags, or the schema name, is invalidated
+	Cached(ttl time.Duration, tags ...string) Query
+
 	// execute the query
 	Execute() ([]JRecord, error)
 
-	// execute the query and return the first record
+	// iterate over the results as they are read; stopping the loop releases
+	// the cursor
+	All(ctx context.Context) iter.Seq2[JRecord, error]
+
+	// execute the query and return the first record, or ErrNotFound
 	First() (JRecord, error)
 
+	// execute the query and return the first record, or nil if none matches
+	FirstOrNil() (JRecord, error)
+
+	// return the first record, or atomically insert one made of the filter's
+	// equality conditions and the defaults; reports whether it was inserted
+	FirstOrCreate(ctx context.Context, defaults map[JField]any) (JRecord, bool, error)
+
 	// execute the query and return the count of records
 	Count() (int, error)
+
+	// group the matching records into time buckets of a date field
+	BucketBy(field JField, interval Interval) *BucketQuery
+
+	// what the query's backend supports
+	Capabilities() Capabilities
 }
 
 // FilterResolver converts a Filter to MongoDB BSON format
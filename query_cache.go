@@ -0,0 +1,230 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// QueryCache stores query results for Query.Cached.
+type QueryCache interface {
+	Get(key string) (any, bool)
+	Set(key string, value any, ttl time.Duration, tags []string)
+
+	// InvalidateTags drops every entry stored with one of the tags.
+	InvalidateTags(tags ...string)
+}
+
+var (
+	queryCacheMu sync.RWMutex
+	queryCache   QueryCache = NewMemoryQueryCache()
+)
+
+// SetQueryCache replaces the package-wide query cache.
+func SetQueryCache(cache QueryCache) {
+	queryCacheMu.Lock()
+	defer queryCacheMu.Unlock()
+
+	queryCache = cache
+}
+
+// GetQueryCache returns the package-wide query cache.
+func GetQueryCache() QueryCache {
+	queryCacheMu.RLock()
+	defer queryCacheMu.RUnlock()
+
+	return queryCache
+}
+
+// InvalidateTags drops cached query results stored with any of the tags.
+// Save and Delete invalidate the schema name automatically.
+func InvalidateTags(tags ...string) {
+	GetQueryCache().InvalidateTags(tags...)
+}
+
+// invalidateOnWrite drops the cached results of the schema after a write,
+// and again once the transaction of the write commits: queries run in
+// between read and may cache the documents the write replaces.
+func invalidateOnWrite(ctx context.Context, schema JSchema) {
+	InvalidateTags(schema.Name())
+	if !inTransaction(ctx) {
+		return
+	}
+
+	invalidated := afterCommit(ctx, func(context.Context) {
+		InvalidateTags(schema.Name())
+	})
+	if !invalidated {
+		LoggerFrom(ctx).Error().Str("schema", schema.Name()).Msg("jpack: query cache isn't invalidated on commit for writes in a transaction not run by a UnitOfWork")
+	}
+}
+
+// cachedResult returns the cached result of the query operation, or runs
+// fetch and caches its result when the query has caching enabled. Queries in
+// a transaction bypass the cache, which would share their uncommitted reads.
+func cachedResult[T any](q *mongoQuery, op string, filter bson.M, fetch func() (T, error)) (T, error) {
+	if q.cacheTTL <= 0 || inTransaction(q.ctx) {
+		return fetch()
+	}
+
+	cache := GetQueryCache()
+	key := q.cacheKey(op, filter)
+
+	if value, ok := cache.Get(key); ok {
+		if result, ok := value.(T); ok {
+			return result, nil
+		}
+	}
+
+	result, err := fetch()
+	if err != nil {
+		return result, err
+	}
+
+	tags := append([]string{storageSchema(q.schema).Name()}, q.cacheTags...)
+	cache.Set(key, result, q.cacheTTL, tags)
+	return result, nil
+}
+
+// cacheKey identifies a query by everything that affects its result,
+// including the schema version and the collection it reads, so that the same
+// schema queried in another database or store doesn't share results. fmt
+// prints maps with sorted keys, so equal filters produce equal keys.
+func (q *mongoQuery) cacheKey(op string, filter bson.M) string {
+	var limit, offset int64 = -1, -1
+	if q.limit != nil {
+		limit = *q.limit
+	}
+	if q.offset != nil {
+		offset = *q.offset
+	}
+
+	return fmt.Sprintf("%s.%s|%s@%d|%s|%v|%v|%v|%d|%d", q.collection.Database().Name(), q.collection.Name(), q.schema.Name(), SchemaVersion(q.schema), op, filter, q.projection, q.orderBy, limit, offset)
+}
+
+// defaultQueryCacheMaxEntries bounds a MemoryQueryCache by default.
+const defaultQueryCacheMaxEntries = 10000
+
+// MemoryQueryCache is an in-process QueryCache.
+type MemoryQueryCache struct {
+	// MaxEntries bounds the number of cached results. When the cache is full,
+	// Set drops the expired entries, then the ones expiring soonest. Zero
+	// disables the bound.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]memoryCacheEntry
+	tags    map[string]map[string]struct{}
+}
+
+type memoryCacheEntry struct {
+	value   any
+	expires time.Time
+	tags    []string
+}
+
+// NewMemoryQueryCache creates an empty in-process query cache.
+func NewMemoryQueryCache() *MemoryQueryCache {
+	return &MemoryQueryCache{
+		MaxEntries: defaultQueryCacheMaxEntries,
+		entries:    make(map[string]memoryCacheEntry),
+		tags:       make(map[string]map[string]struct{}),
+	}
+}
+
+// Get implements QueryCache.
+func (c *MemoryQueryCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) {
+		c.remove(key)
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+// Set implements QueryCache.
+func (c *MemoryQueryCache) Set(key string, value any, ttl time.Duration, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.remove(key)
+	c.evict(now)
+	c.entries[key] = memoryCacheEntry{
+		value:   value,
+		expires: now.Add(ttl),
+		tags:    tags,
+	}
+
+	for _, tag := range tags {
+		if c.tags[tag] == nil {
+			c.tags[tag] = make(map[string]struct{})
+		}
+		c.tags[tag][key] = struct{}{}
+	}
+}
+
+// InvalidateTags implements QueryCache.
+func (c *MemoryQueryCache) InvalidateTags(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range c.tags[tag] {
+			c.remove(key)
+		}
+	}
+}
+
+// evict makes room for an entry when the cache is full: the expired entries
+// are dropped, then the ones expiring soonest. The caller must hold c.mu.
+func (c *MemoryQueryCache) evict(now time.Time) {
+	if c.MaxEntries <= 0 || len(c.entries) < c.MaxEntries {
+		return
+	}
+
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			c.remove(key)
+		}
+	}
+	for len(c.entries) >= c.MaxEntries {
+		var soonest string
+		var expires time.Time
+		for key, entry := range c.entries {
+			if expires.IsZero() || entry.expires.Before(expires) {
+				soonest, expires = key, entry.expires
+			}
+		}
+		c.remove(soonest)
+	}
+}
+
+// remove deletes an entry and its tag index. The caller must hold c.mu.
+func (c *MemoryQueryCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+
+	delete(c.entries, key)
+	for _, tag := range entry.tags {
+		delete(c.tags[tag], key)
+		if len(c.tags[tag]) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+var _ QueryCache = &MemoryQueryCache{}
@@ -0,0 +1,160 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestMemoryQueryCache(t *testing.T) {
+	t.Run("get and set", func(t *testing.T) {
+		cache := NewMemoryQueryCache()
+		cache.Set("a", 1, time.Minute, nil)
+
+		value, ok := cache.Get("a")
+		assert.True(t, ok)
+		assert.Equal(t, 1, value)
+
+		_, ok = cache.Get("b")
+		assert.False(t, ok)
+	})
+
+	t.Run("entries expire", func(t *testing.T) {
+		cache := NewMemoryQueryCache()
+		cache.Set("a", 1, time.Nanosecond, []string{"users"})
+		time.Sleep(time.Millisecond)
+
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		assert.Empty(t, cache.tags)
+	})
+
+	t.Run("full caches evict", func(t *testing.T) {
+		cache := NewMemoryQueryCache()
+		cache.MaxEntries = 2
+		cache.Set("expired", 1, time.Nanosecond, []string{"users"})
+		cache.Set("late", 2, time.Hour, nil)
+		time.Sleep(time.Millisecond)
+
+		// Expired entries go first, then the ones expiring soonest
+		cache.Set("soon", 3, time.Minute, nil)
+		assert.Len(t, cache.entries, 2)
+		assert.NotContains(t, cache.entries, "expired")
+		assert.Empty(t, cache.tags)
+
+		cache.Set("later", 4, time.Hour, nil)
+		assert.Len(t, cache.entries, 2)
+		assert.NotContains(t, cache.entries, "soon")
+		_, ok := cache.Get("late")
+		assert.True(t, ok)
+	})
+
+	t.Run("tag invalidation", func(t *testing.T) {
+		cache := NewMemoryQueryCache()
+		cache.Set("a", 1, time.Minute, []string{"users", "lists"})
+		cache.Set("b", 2, time.Minute, []string{"posts"})
+
+		cache.InvalidateTags("lists")
+
+		_, ok := cache.Get("a")
+		assert.False(t, ok)
+		_, ok = cache.Get("b")
+		assert.True(t, ok)
+	})
+}
+
+func TestQueryCached(t *testing.T) {
+	cache := NewMemoryQueryCache()
+	SetQueryCache(cache)
+	defer SetQueryCache(NewMemoryQueryCache())
+
+	q := NewMongoQuery(offlineContext(t), userSchema).Cached(time.Minute, "directory").(*mongoQuery)
+
+	calls := 0
+	fetch := func() (int64, error) {
+		calls++
+		return 42, nil
+	}
+
+	t.Run("results are cached", func(t *testing.T) {
+		for range 2 {
+			count, err := cachedResult(q, "count", bson.M{}, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(42), count)
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("the schema name invalidates", func(t *testing.T) {
+		InvalidateTags(userSchema.Name())
+		_, _ = cachedResult(q, "count", bson.M{}, fetch)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("custom tags invalidate", func(t *testing.T) {
+		InvalidateTags("directory")
+		_, _ = cachedResult(q, "count", bson.M{}, fetch)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("different filters use different entries", func(t *testing.T) {
+		_, _ = cachedResult(q, "count", bson.M{"age": 1}, fetch)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("other databases use different entries", func(t *testing.T) {
+		ctx := offlineContext(t)
+		other := NewMongoQuery(WithDatabase(ctx, MustConn(ctx).Client().Database("jpack_test_other")), userSchema).Cached(time.Minute).(*mongoQuery)
+		_, _ = cachedResult(other, "count", bson.M{"age": 1}, fetch)
+		assert.Equal(t, 5, calls)
+	})
+
+	t.Run("uncached queries always fetch", func(t *testing.T) {
+		uncached := NewMongoQuery(offlineContext(t), userSchema).(*mongoQuery)
+		_, _ = cachedResult(uncached, "count", bson.M{}, fetch)
+		_, _ = cachedResult(uncached, "count", bson.M{}, fetch)
+		assert.Equal(t, 7, calls)
+	})
+
+	t.Run("schema changes invalidate", func(t *testing.T) {
+		schema := NewSchema("test_cache_version").Field("id", &String{}).Build()
+		q := NewMongoQuery(offlineContext(t), schema).Cached(time.Minute).(*mongoQuery)
+		_, _ = cachedResult(q, "count", bson.M{}, fetch)
+		_, _ = cachedResult(q, "count", bson.M{}, fetch)
+		assert.Equal(t, 8, calls)
+
+		schema.AddField(&fieldImpl{name: "name", fType: &String{}, schema: schema})
+		_, _ = cachedResult(q, "count", bson.M{}, fetch)
+		assert.Equal(t, 9, calls)
+	})
+	t.Run("queries in a transaction bypass the cache", func(t *testing.T) {
+		filter := bson.M{"first_name": "Ada"}
+		stubFlushTransaction(t, func(ctx context.Context, fn func(ctx context.Context) error) error {
+			ctx = context.WithValue(ctx, commitQueueKey, &commitQueue{})
+			if err := fn(ctx); err != nil {
+				return err
+			}
+
+			// The transaction reads its own uncommitted write
+			q := NewMongoQuery(ctx, userSchema).Cached(time.Minute).(*mongoQuery)
+			for range 2 {
+				_, _ = cachedResult(q, "count", filter, fetch)
+			}
+			return nil
+		})
+
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(mustField(t, userSchema, "first_name"), "Ada"))
+
+		before := calls
+		ctx := WithPendingJournal(offlineContext(t), NewMemoryJournal())
+		assert.NoError(t, NewUnitOfWork().Add(record).Flush(ctx))
+		assert.Equal(t, before+2, calls)
+
+		_, ok := cache.Get(q.cacheKey("count", filter))
+		assert.False(t, ok, "uncommitted reads aren't shared")
+	})
+}
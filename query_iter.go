@@ -0,0 +1,96 @@
+package jpack
+
+import (
+	"context"
+	"iter"
+	"maps"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// executed runs execute when the loop starts, and yields its records or its
+// error.
+func executed(execute func() ([]JRecord, error)) iter.Seq2[JRecord, error] {
+	return func(yield func(JRecord, error) bool) {
+		records, err := execute()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// All implements Query. Records are decoded one at a time as the cursor
+// reads them, so large results don't have to fit in memory; the cursor is
+// closed when the loop ends, breaks or fails. Queries with eager loaded refs
+// or cached results are executed first and then iterated.
+//
+//	for record, err := range query.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (q *mongoQuery) All(ctx context.Context) iter.Seq2[JRecord, error] {
+	if q.err != nil || len(q.withRefs) > 0 || q.cacheTTL > 0 {
+		return executed(q.Execute)
+	}
+
+	return func(yield func(JRecord, error) bool) {
+		filter := q.filter()
+		start := time.Now()
+		var read int64
+		var err error
+		defer func() {
+			tapQuery(q.ctx, q.operation("find", filter, true, read, err), start)
+		}()
+
+		findCtx, cancel := operationContext(ctx)
+		cursor, err := q.collection.Find(findCtx, filter, q.findOptions())
+		cancel()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close(context.WithoutCancel(ctx))
+
+		for cursor.Next(ctx) {
+			var doc bson.M
+			if err = cursor.Decode(&doc); err != nil {
+				yield(nil, err)
+				return
+			}
+			read++
+
+			record := NewMongoRecord(q.schema)
+			if err = record.loadDocument(ctx, maps.Clone(doc)); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(q.track(record), nil) {
+				return
+			}
+		}
+		if err = cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// All implements Query. SQLite rows are read when the loop starts, then
+// yielded one at a time.
+func (q *sqliteQuery) All(ctx context.Context) iter.Seq2[JRecord, error] {
+	return executed(q.Execute)
+}
+
+// All implements Query. The queries are executed and merged when the loop
+// starts.
+func (u *unionQuery) All(ctx context.Context) iter.Seq2[JRecord, error] {
+	return executed(u.Execute)
+}
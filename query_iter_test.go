@@ -0,0 +1,54 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryAll(t *testing.T) {
+	firstName := mustField(t, userSchema, "first_name")
+	var records []JRecord
+	for _, name := range []string{"Ada", "Grace", "Linus"} {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(firstName, name))
+		records = append(records, record)
+	}
+
+	t.Run("records are yielded in order until the loop breaks", func(t *testing.T) {
+		var names []string
+		for record, err := range (&staticQuery{schema: userSchema, records: records}).All(context.Background()) {
+			assert.NoError(t, err)
+			name, _ := record.String(firstName)
+			names = append(names, name)
+			if len(names) == 2 {
+				break
+			}
+		}
+		assert.Equal(t, []string{"Ada", "Grace"}, names)
+	})
+
+	t.Run("a failed query yields its error once", func(t *testing.T) {
+		calls := 0
+		for record, err := range (&staticQuery{schema: userSchema, err: errors.New("boom")}).All(context.Background()) {
+			calls++
+			assert.Nil(t, record)
+			assert.EqualError(t, err, "boom")
+		}
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("queries run when the loop starts", func(t *testing.T) {
+		executions := 0
+		seq := executed(func() ([]JRecord, error) {
+			executions++
+			return records, nil
+		})
+		assert.Equal(t, 0, executions)
+		for range seq {
+		}
+		assert.Equal(t, 1, executions)
+	})
+}
@@ -0,0 +1,173 @@
+package jpack
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// QueryTapKey is the context key holding a QueryTap set with WithQueryTap.
+//
+// Deprecated: use WithQueryTap.
+var QueryTapKey key = "jpack.querytap"
+
+// QueryOperation is a database operation run by a query or a record write.
+type QueryOperation struct {
+	Collection string
+	// Operation is one of "find", "findOne", "count", "aggregate", "insert",
+	// "upsert", "update" or "delete".
+	Operation string
+	Filter    bson.M
+	// Options holds the non-default find options: "projection", "sort",
+	// "limit" and "skip", or the "pipeline" of an aggregate.
+	Options bson.M
+	// Document is the inserted document or the update of a write.
+	Document bson.M
+	Duration time.Duration
+	// Count is the number of documents returned, counted, inserted, matched
+	// or deleted.
+	Count int64
+	Err   error
+}
+
+// QueryTap observes every database operation, e.g. to assert on generated
+// queries in tests or to ship them to logs. Results served by Query.Cached
+// don't reach the database and aren't tapped.
+type QueryTap interface {
+	Tap(ctx context.Context, op QueryOperation)
+}
+
+// QueryTapFunc adapts a function to QueryTap.
+type QueryTapFunc func(ctx context.Context, op QueryOperation)
+
+// Tap implements QueryTap.
+func (f QueryTapFunc) Tap(ctx context.Context, op QueryOperation) {
+	f(ctx, op)
+}
+
+// WithQueryTap returns a context whose operations are reported to tap, in
+// addition to the package-wide tap.
+func WithQueryTap(ctx context.Context, tap QueryTap) context.Context {
+	return context.WithValue(ctx, QueryTapKey, tap)
+}
+
+// QueryTaps reports operations to several taps in order.
+type QueryTaps []QueryTap
+
+// Tap implements QueryTap.
+func (t QueryTaps) Tap(ctx context.Context, op QueryOperation) {
+	for _, tap := range t {
+		tap.Tap(ctx, op)
+	}
+}
+
+var (
+	queryTapMu sync.RWMutex
+	queryTap   QueryTap
+)
+
+// SetQueryTap sets the package-wide tap, or removes it when nil.
+func SetQueryTap(tap QueryTap) {
+	queryTapMu.Lock()
+	defer queryTapMu.Unlock()
+
+	queryTap = tap
+}
+
+// addQueryTap adds a tap after the package-wide one, if any.
+func addQueryTap(tap QueryTap) {
+	queryTapMu.Lock()
+	defer queryTapMu.Unlock()
+
+	switch current := queryTap.(type) {
+	case nil:
+		queryTap = tap
+	case QueryTaps:
+		queryTap = append(slices.Clip(current), tap)
+	default:
+		queryTap = QueryTaps{current, tap}
+	}
+}
+
+// tapQuery reports an operation started at start to the context's and the
+// package-wide taps.
+func tapQuery(ctx context.Context, op QueryOperation, start time.Time) {
+	queryTapMu.RLock()
+	global := queryTap
+	queryTapMu.RUnlock()
+
+	scoped, _ := ctx.Value(QueryTapKey).(QueryTap)
+	logged := ConfigFrom(ctx).LogQueries
+	if global == nil && scoped == nil && !logged {
+		return
+	}
+
+	op.Duration = time.Since(start)
+	if logged {
+		LogQueryTap{}.Tap(ctx, op)
+	}
+	if scoped != nil {
+		scoped.Tap(ctx, op)
+	}
+	if global != nil {
+		global.Tap(ctx, op)
+	}
+}
+
+// RecordingQueryTap keeps every tapped operation, for tests.
+type RecordingQueryTap struct {
+	mu         sync.Mutex
+	operations []QueryOperation
+}
+
+// Tap implements QueryTap.
+func (r *RecordingQueryTap) Tap(ctx context.Context, op QueryOperation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.operations = append(r.operations, op)
+}
+
+// Operations returns the tapped operations in order.
+func (r *RecordingQueryTap) Operations() []QueryOperation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]QueryOperation(nil), r.operations...)
+}
+
+// Reset forgets the tapped operations.
+func (r *RecordingQueryTap) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.operations = nil
+}
+
+// LogQueryTap logs operations with the configured logger at debug level, and
+// failed ones at error level.
+type LogQueryTap struct{}
+
+// Tap implements QueryTap.
+func (LogQueryTap) Tap(ctx context.Context, op QueryOperation) {
+	entry := LoggerFrom(ctx).Debug()
+	if op.Err != nil {
+		entry = LoggerFrom(ctx).Error().Err(op.Err)
+	}
+	entry.Str("collection", op.Collection).
+		Str("operation", op.Operation).
+		Interface("filter", op.Filter).
+		Interface("options", op.Options).
+		Dur("duration", op.Duration).
+		Int64("count", op.Count).
+		Msg("jpack: query")
+}
+
+var (
+	_ QueryTap = QueryTapFunc(nil)
+	_ QueryTap = &RecordingQueryTap{}
+	_ QueryTap = LogQueryTap{}
+)
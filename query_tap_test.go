@@ -0,0 +1,65 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestQueryTap(t *testing.T) {
+	t.Run("context and package-wide taps", func(t *testing.T) {
+		assert := assert.New(t)
+
+		scoped := &RecordingQueryTap{}
+		global := &RecordingQueryTap{}
+		SetQueryTap(global)
+		defer SetQueryTap(nil)
+
+		op := QueryOperation{Collection: "users", Operation: "count", Count: 3}
+		tapQuery(WithQueryTap(context.Background(), scoped), op, time.Now())
+		tapQuery(context.Background(), op, time.Now())
+
+		assert.Len(scoped.Operations(), 1)
+		assert.Len(global.Operations(), 2)
+		assert.Equal("count", scoped.Operations()[0].Operation)
+		assert.EqualValues(3, scoped.Operations()[0].Count)
+
+		scoped.Reset()
+		assert.Empty(scoped.Operations())
+	})
+
+	t.Run("find options", func(t *testing.T) {
+		assert := assert.New(t)
+
+		query := NewMongoQuery(offlineContext(t), userSchema).
+			Select(mustField(t, userSchema, "email")).
+			OrderBy(mustField(t, userSchema, "age")).
+			Limit(10).
+			Offset(20).(*mongoQuery)
+		filter := bson.M{"age": bson.M{"$gt": 18}}
+
+		op := query.operation("find", filter, true, 7, nil)
+		assert.Equal("test_user", op.Collection)
+		assert.Equal(filter, op.Filter)
+		assert.EqualValues(7, op.Count)
+		assert.EqualValues(10, op.Options["limit"])
+		assert.EqualValues(20, op.Options["skip"])
+		assert.Contains(op.Options, "sort")
+		assert.Contains(op.Options, "projection")
+
+		assert.NotContains(query.operation("findOne", filter, false, 1, nil).Options, "limit")
+	})
+
+	t.Run("failed writes count nothing", func(t *testing.T) {
+		coll := MustConn(offlineContext(t)).Collection("users")
+		failure := errors.New("boom")
+
+		op := writeOperation(coll, "update", bson.M{"_id": 1}, bson.M{"$set": bson.M{"a": 1}}, 1, failure)
+		assert.Zero(t, op.Count)
+		assert.Equal(t, failure, op.Err)
+	})
+}
@@ -0,0 +1,23 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Run("context flag", func(t *testing.T) {
+		assert.False(t, IsReadOnly(context.Background()))
+		assert.True(t, IsReadOnly(WithReadOnly(context.Background())))
+	})
+
+	t.Run("Save returns ErrReadOnly", func(t *testing.T) {
+		ctx := WithReadOnly(offlineContext(t))
+
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(mustField(t, userSchema, "first_name"), "John"))
+		assert.ErrorIs(t, record.Save(ctx), ErrReadOnly)
+	})
+}
@@ -0,0 +1,101 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+)
+
+// NewRecord creates a record of the schema stored by the backend the context
+// selects, so code creating records doesn't depend on a backend. It is
+// schema.NewRecord(ctx).
+func NewRecord(ctx context.Context, schema JSchema) (JRecord, error) {
+	return schema.NewRecord(ctx)
+}
+
+// RecordHook runs on every record created with JSchema.NewRecord, e.g. to
+// register OnChange observers or fill in values derived from the context.
+type RecordHook func(ctx context.Context, record JRecord) error
+
+// OnNewRecord registers a hook run on every record created with NewRecord,
+// in registration order.
+func (s *SchemaBuilder) OnNewRecord(hook RecordHook) *SchemaBuilder {
+	s.schema.recordHooks = append(s.schema.recordHooks, hook)
+	return s
+}
+
+// NewRecord implements JSchema. The record is stored by the backend the
+// context selects for the schema, like NewQuery: MongoDB when there is a
+// database for the schema, else the context's SQLite store. Client-side
+// defaults are applied right away, so forms can show them, then the hooks
+// registered with OnNewRecord run. Server-side defaults are still set when
+// the record is saved.
+func (s *schemaImpl) NewRecord(ctx context.Context) (JRecord, error) {
+	var record JRecord
+	var m *mongoRecord
+	switch backendFor(ctx, s) {
+	case mongoBackend:
+		m = NewMongoRecord(s)
+		record = m
+	case sqliteBackend:
+		r := NewSQLiteRecord(s)
+		m, record = r.mongoRecord, r
+	default:
+		return nil, errors.New("jpack: no supported database connection found in context")
+	}
+
+	if _, err := m.applyDefaults(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, hook := range s.recordHooks {
+		if err := hook(ctx, record); err != nil {
+			return nil, err
+		}
+	}
+	return record, nil
+}
+
+// NewRecord implements JSchema. Records of a view are read from its base
+// schema, so it can't create them.
+func (v *ViewSchema) NewRecord(ctx context.Context) (JRecord, error) {
+	return nil, ErrViewReadOnly
+}
+
+// backend is a storage implementation records can be written through.
+type backend int
+
+const (
+	noBackend backend = iota
+	mongoBackend
+	sqliteBackend
+)
+
+// backendFor returns the backend the context selects for the schema, like
+// NewQuery: MongoDB when there is a database for the schema, else the
+// context's SQLite store.
+func backendFor(ctx context.Context, schema JSchema) backend {
+	if _, ok := databaseFor(ctx, schema); ok {
+		return mongoBackend
+	}
+	if _, ok := SQLiteFrom(ctx); ok {
+		return sqliteBackend
+	}
+	return noBackend
+}
+
+// sqliteRoute returns the record to write a MongoDB record through when the
+// context selects SQLite for its schema, e.g. a record created for one
+// backend and saved by code running against another.
+func (m *mongoRecord) sqliteRoute(ctx context.Context) (*sqliteRecord, bool) {
+	if backendFor(ctx, m.schema) != sqliteBackend {
+		return nil, false
+	}
+	return &sqliteRecord{mongoRecord: m}, true
+}
+
+// mongoRoute reports whether a SQLite record is written through MongoDB,
+// because the context has no SQLite store but a database for its schema.
+func (r *sqliteRecord) mongoRoute(ctx context.Context) bool {
+	_, ok := SQLiteFrom(ctx)
+	return !ok && backendFor(ctx, r.schema) == mongoBackend
+}
@@ -0,0 +1,91 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRecord(t *testing.T) {
+	var changed []string
+	schema := NewSchema("test_record_factory").
+		Field("id", &String{}).
+		FieldWithDefault("status", &String{}, "draft").
+		FieldWithDefault("created_at", &DateTime{}, ServerNow()).
+		OnNewRecord(func(ctx context.Context, record JRecord) error {
+			record.OnChange(func(field JField, old, new any) {
+				changed = append(changed, field.Name())
+			})
+			return nil
+		}).
+		Build()
+	status := mustField(t, schema, "status")
+
+	t.Run("the backend follows the context", func(t *testing.T) {
+		record, err := schema.NewRecord(offlineContext(t))
+		assert.NoError(t, err)
+		assert.IsType(t, &mongoRecord{}, record)
+
+		record, err = schema.NewRecord(WithSQLite(context.Background(), NewSQLiteStore(nil)))
+		assert.NoError(t, err)
+		assert.IsType(t, &sqliteRecord{}, record)
+
+		_, err = schema.NewRecord(context.Background())
+		assert.ErrorContains(t, err, "no supported database")
+	})
+
+	t.Run("defaults are applied and hooks registered", func(t *testing.T) {
+		changed = nil
+		record, err := schema.NewRecord(offlineContext(t))
+		assert.NoError(t, err)
+		value, _ := record.String(status)
+		assert.Equal(t, "draft", value)
+		_, ok := record.Value(mustField(t, schema, "created_at"))
+		assert.False(t, ok, "server defaults are set when saved")
+
+		assert.NoError(t, record.SetValue(status, "published"))
+		assert.Equal(t, []string{"status"}, changed)
+	})
+
+	t.Run("clones keep the hooks", func(t *testing.T) {
+		Freeze(schema)
+		clone := CloneBuilder(schema).Field("title", &String{}).Build()
+
+		changed = nil
+		record, err := clone.NewRecord(offlineContext(t))
+		assert.NoError(t, err)
+		assert.NoError(t, record.SetValue(mustField(t, clone, "status"), "published"))
+		assert.Equal(t, []string{"status"}, changed)
+	})
+
+	t.Run("hooks can fail the creation", func(t *testing.T) {
+		failing := NewSchema("test_record_factory_failing").
+			Field("id", &String{}).
+			OnNewRecord(func(ctx context.Context, record JRecord) error { return errors.New("no tenant") }).
+			Build()
+		_, err := failing.NewRecord(offlineContext(t))
+		assert.EqualError(t, err, "no tenant")
+	})
+
+	t.Run("views can't create records", func(t *testing.T) {
+		_, err := NewView("test_record_factory_view", schema).Build().NewRecord(offlineContext(t))
+		assert.ErrorIs(t, err, ErrViewReadOnly)
+	})
+
+	t.Run("saves follow the backend of the context", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(status, "draft"))
+		ctx := WithSQLite(context.Background(), NewSQLiteStore(nil))
+		assert.ErrorContains(t, record.Save(ctx, WithIdempotencyKey("k")), "SQLite stores don't support idempotency keys")
+
+		journal := &MemoryJournal{}
+		sqlite := NewSQLiteRecord(schema)
+		assert.NoError(t, sqlite.SetValue(status, "draft"))
+		assert.NoError(t, sqlite.Save(WithPendingJournal(offlineContext(t), journal)))
+		pending, err := journal.Pending(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, pending, 1)
+	})
+}
@@ -0,0 +1,108 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// RecordSet is a list of records, e.g. the results of Query.Execute, with
+// the helpers consumers otherwise write as loops.
+//
+//	records, err := query.Execute()
+//	emails := jpack.RecordSet(records).Pluck(emailField)
+type RecordSet []JRecord
+
+// Pluck returns the value of the field of every record, nil where a record
+// has none.
+func (s RecordSet) Pluck(field JField) []any {
+	values := make([]any, len(s))
+	for i, record := range s {
+		values[i], _ = record.Value(field)
+	}
+	return values
+}
+
+// IndexByPK maps the records by their primary key. Records without one are
+// left out; of records sharing one, the last is kept.
+func (s RecordSet) IndexByPK() map[string]JRecord {
+	index := make(map[string]JRecord, len(s))
+	for _, record := range s {
+		if id, ok := recordID(record); ok {
+			index[id] = record
+		}
+	}
+	return index
+}
+
+// GroupBy groups the records by the value of the field, keeping their order
+// within each group. Records without a value are grouped under nil, and
+// values that can't be map keys, e.g. lists, under their fmt.Sprint form.
+func (s RecordSet) GroupBy(field JField) map[any]RecordSet {
+	groups := make(map[any]RecordSet)
+	for _, record := range s {
+		value, _ := record.Value(field)
+		if value != nil && !reflect.TypeOf(value).Comparable() {
+			value = fmt.Sprint(value)
+		}
+		groups[value] = append(groups[value], record)
+	}
+	return groups
+}
+
+// Filter returns the records for which keep returns true.
+func (s RecordSet) Filter(keep func(JRecord) bool) RecordSet {
+	var kept RecordSet
+	for _, record := range s {
+		if keep(record) {
+			kept = append(kept, record)
+		}
+	}
+	return kept
+}
+
+// ToJSON encodes the records as a JSON array of objects keyed by field name,
+// with the values normalized like change events carry them.
+func (s RecordSet) ToJSON() ([]byte, error) {
+	objects := make([]map[string]any, len(s))
+	for i, record := range s {
+		values, err := recordValues(record)
+		if err != nil {
+			return nil, err
+		}
+		objects[i] = values
+	}
+	return json.Marshal(objects)
+}
+
+// SaveAll saves every record, even after one fails, and joins the errors.
+// With a RequestBatcher in the context, the writes are batched.
+func (s RecordSet) SaveAll(ctx context.Context, opts ...SaveOption) error {
+	var errs []error
+	for i, record := range s {
+		if err := record.Save(ctx, opts...); err != nil {
+			errs = append(errs, fmt.Errorf("record %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// recordValues returns the normalized values of the record's fields.
+func recordValues(record JRecord) (map[string]any, error) {
+	values := make(map[string]any)
+	for _, field := range record.Schema().Fields() {
+		value, ok := record.Value(field)
+		if !ok {
+			continue
+		}
+
+		value, err := normalizeValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		values[field.Name()] = value
+	}
+	return values, nil
+}
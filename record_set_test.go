@@ -0,0 +1,78 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRecordSet(t *testing.T) {
+	firstName, age := mustField(t, userSchema, "first_name"), mustField(t, userSchema, "age")
+	ids := []bson.ObjectID{bson.NewObjectID(), bson.NewObjectID(), bson.NewObjectID()}
+
+	var set RecordSet
+	for i, doc := range []bson.M{
+		{"_id": ids[0], "first_name": "Ada", "age": int32(36)},
+		{"_id": ids[1], "first_name": "Grace", "age": int32(36)},
+		{"_id": ids[2], "first_name": "Linus"},
+	} {
+		record, err := RecordFromBSON(userSchema, doc)
+		assert.NoError(t, err, i)
+		set = append(set, record)
+	}
+
+	t.Run("Pluck", func(t *testing.T) {
+		assert.Equal(t, []any{"Ada", "Grace", "Linus"}, set.Pluck(firstName))
+		assert.Equal(t, []any{int32(36), int32(36), nil}, set.Pluck(age))
+	})
+
+	t.Run("IndexByPK", func(t *testing.T) {
+		index := set.IndexByPK()
+		assert.Len(t, index, 3)
+		assert.Same(t, set[1], index[ids[1].Hex()])
+		assert.Empty(t, RecordSet{NewMongoRecord(userSchema)}.IndexByPK())
+	})
+
+	t.Run("GroupBy", func(t *testing.T) {
+		groups := set.GroupBy(age)
+		assert.Len(t, groups, 2)
+		assert.Equal(t, RecordSet{set[0], set[1]}, groups[int32(36)])
+		assert.Equal(t, RecordSet{set[2]}, groups[nil])
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		adults := set.Filter(func(record JRecord) bool {
+			_, ok := record.Value(age)
+			return ok
+		})
+		assert.Equal(t, RecordSet{set[0], set[1]}, adults)
+		assert.Nil(t, set.Filter(func(JRecord) bool { return false }))
+	})
+
+	t.Run("ToJSON", func(t *testing.T) {
+		data, err := set[:1].ToJSON()
+		assert.NoError(t, err)
+		assert.JSONEq(t, `[{"id": "`+ids[0].Hex()+`", "first_name": "Ada", "age": 36}]`, string(data))
+	})
+
+	t.Run("SaveAll saves every record", func(t *testing.T) {
+		journal := &MemoryJournal{}
+		for _, record := range set {
+			assert.NoError(t, record.SetValue(age, 40))
+		}
+		alan := NewMongoRecord(userSchema)
+		assert.NoError(t, alan.SetValue(firstName, "Alan"))
+
+		ctx := WithPendingJournal(offlineContext(t), journal)
+		err := append(RecordSet{alan}, set...).SaveAll(WithReadOnly(ctx))
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assert.ErrorContains(t, err, "record 3: ")
+
+		assert.NoError(t, set.SaveAll(ctx))
+		pending, err := journal.Pending(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, pending, 3)
+	})
+}
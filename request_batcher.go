@@ -0,0 +1,200 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// RequestBatcherKey is the context key holding the request's RequestBatcher.
+//
+// Deprecated: use WithRequestBatcher and RequestBatcherFrom.
+var RequestBatcherKey key = "jpack.requestbatcher"
+
+// RequestBatcher collects the Saves of a request and writes them with one
+// bulk write per collection on Flush, saving round trips in handlers that
+// touch many records. Saves within its context are validated right away and
+// new records get their id, so they can be referenced before the flush; the
+// rest of the write, including outbox entries and webhooks, happens on Flush.
+// Queries don't see batched writes until then, and Saves with IfMatch are
+// written immediately.
+type RequestBatcher struct {
+	mu      sync.Mutex
+	entries []batchEntry
+}
+
+type batchEntry struct {
+	write *pendingWrite
+	// restore resets the record to its state before its first batched Save
+	restore func()
+}
+
+// NewRequestBatcher creates an empty batcher.
+func NewRequestBatcher() *RequestBatcher {
+	return &RequestBatcher{}
+}
+
+// WithRequestBatcher returns a context whose Saves are collected by batcher.
+func WithRequestBatcher(ctx context.Context, batcher *RequestBatcher) context.Context {
+	return context.WithValue(ctx, RequestBatcherKey, batcher)
+}
+
+// RequestBatcherFrom returns the batcher stored in the context, if any.
+func RequestBatcherFrom(ctx context.Context) (*RequestBatcher, bool) {
+	batcher, ok := ctx.Value(RequestBatcherKey).(*RequestBatcher)
+	return batcher, ok && batcher != nil
+}
+
+// Batch runs fn with a new RequestBatcher in its context and flushes it when
+// fn succeeds. When fn fails or panics, the batched Saves are discarded.
+func Batch(ctx context.Context, fn func(ctx context.Context) error) error {
+	batcher := NewRequestBatcher()
+	defer func() {
+		if r := recover(); r != nil {
+			batcher.Discard()
+			panic(r)
+		}
+	}()
+
+	if err := fn(WithRequestBatcher(ctx, batcher)); err != nil {
+		batcher.Discard()
+		return err
+	}
+	return batcher.Flush(ctx)
+}
+
+// add queues a write, replacing an earlier write of the same record.
+func (b *RequestBatcher) add(w *pendingWrite, restore func()) {
+	if w.op == ChangeInsert {
+		if pkField, ok := PK(w.record.schema); ok {
+			if objID, ok := w.insertedID.(bson.ObjectID); ok {
+				w.record.record[pkField.Name()] = objID.Hex()
+				w.record.invalidateScanned(pkField.Name())
+			}
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, entry := range b.entries {
+		if entry.write.record == w.record {
+			b.entries[i].write = w
+			return
+		}
+	}
+	b.entries = append(b.entries, batchEntry{write: w, restore: restore})
+}
+
+// Len returns the number of records waiting to be written.
+func (b *RequestBatcher) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// Discard drops the batched writes and restores the records' in-memory state.
+func (b *RequestBatcher) Discard() {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+
+	for _, entry := range entries {
+		entry.restore()
+	}
+}
+
+// Flush writes the batched Saves, one ordered bulk write per collection in
+// the order of their first Save. A failed bulk write stops the flush: the
+// records written before the failure are saved, the others are restored as if
+// they had been discarded. The batcher is empty afterwards either way.
+func (b *RequestBatcher) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	entries := b.entries
+	b.entries = nil
+	b.mu.Unlock()
+
+	if len(entries) == 0 {
+		return nil
+	}
+	if IsReadOnly(ctx) {
+		for _, entry := range entries {
+			entry.restore()
+		}
+		return ErrReadOnly
+	}
+
+	groups := groupByCollection(entries)
+	var errs []error
+	for gi, group := range groups {
+		models := make([]mongo.WriteModel, len(group))
+		for i, entry := range group {
+			models[i] = entry.write.model()
+		}
+
+		start := time.Now()
+		coll := group[0].write.coll
+		opCtx, cancel := operationContext(ctx)
+		_, err := coll.BulkWrite(opCtx, models, options.BulkWrite().SetOrdered(true))
+		cancel()
+
+		// Ordered bulk writes stop at the first failed write
+		written := len(group)
+		if err != nil {
+			written = 0
+			var bulkErr mongo.BulkWriteException
+			if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+				written = bulkErr.WriteErrors[0].Index
+			}
+		}
+
+		for i, entry := range group {
+			w := entry.write
+			switch {
+			case i < written:
+				tapQuery(ctx, writeOperation(coll, w.operation, w.filter, w.document, 1, nil), start)
+				if err := w.finish(ctx); err != nil {
+					errs = append(errs, err)
+				}
+			case i == written:
+				tapQuery(ctx, writeOperation(coll, w.operation, w.filter, w.document, 0, err), start)
+				entry.restore()
+			default:
+				entry.restore()
+			}
+		}
+
+		if err != nil {
+			for _, rest := range groups[gi+1:] {
+				for _, entry := range rest {
+					entry.restore()
+				}
+			}
+			return errors.Join(append([]error{err}, errs...)...)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func groupByCollection(entries []batchEntry) [][]batchEntry {
+	var groups [][]batchEntry
+	index := make(map[string]int)
+	for _, entry := range entries {
+		name := entry.write.coll.Name()
+		i, ok := index[name]
+		if !ok {
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], entry)
+	}
+	return groups
+}
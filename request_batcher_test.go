@@ -0,0 +1,129 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestRequestBatcher(t *testing.T) {
+	authorSchema := NewSchema("test_batch_author").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Build()
+	postSchema := NewSchema("test_batch_post").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		FieldWithDefault("status", &String{}, "draft").
+		Ref("author", authorSchema).
+		Build()
+
+	newAuthor := func(name string) *mongoRecord {
+		author := NewMongoRecord(authorSchema)
+		assert.NoError(t, author.SetValue(mustField(t, authorSchema, "name"), name))
+		return author
+	}
+
+	t.Run("saves are queued with their ids", func(t *testing.T) {
+		batcher := NewRequestBatcher()
+		ctx := WithRequestBatcher(offlineContext(t), batcher)
+
+		author := newAuthor("Ada")
+		assert.NoError(t, author.Save(ctx))
+		assert.Equal(t, 1, batcher.Len())
+		assert.True(t, author.IsNew(), "nothing is written before Flush")
+
+		id, ok := recordID(author)
+		assert.True(t, ok, "new records get their id on Save")
+
+		// Records saved earlier in the batch can be referenced
+		post := NewMongoRecord(postSchema)
+		assert.NoError(t, post.SetValue(mustField(t, postSchema, "title"), "Hello"))
+		assert.NoError(t, post.SetValue(mustField(t, postSchema, "author"), author))
+		assert.NoError(t, post.Save(ctx))
+
+		// Saving again replaces the queued write and keeps the id
+		assert.NoError(t, author.SetValue(mustField(t, authorSchema, "name"), "Ada L."))
+		assert.NoError(t, author.Save(ctx))
+		assert.Equal(t, 2, batcher.Len())
+
+		batcher.mu.Lock()
+		write := batcher.entries[0].write
+		batcher.mu.Unlock()
+		assert.Equal(t, "insert", write.operation)
+		objID, _ := bson.ObjectIDFromHex(id)
+		assert.Equal(t, objID, write.document[defaultMongoPK])
+		assert.Equal(t, "Ada L.", write.document["name"])
+		assert.IsType(t, &mongo.InsertOneModel{}, write.model())
+
+		groups := groupByCollection(batcher.entries)
+		assert.Len(t, groups, 2)
+		assert.Equal(t, "test_batch_author", groups[0][0].write.coll.Name())
+	})
+
+	t.Run("validation errors are returned by Save", func(t *testing.T) {
+		batcher := NewRequestBatcher()
+		ctx := WithRequestBatcher(WithReadOnly(offlineContext(t)), batcher)
+
+		assert.ErrorIs(t, newAuthor("Ada").Save(ctx), ErrReadOnly)
+		assert.Zero(t, batcher.Len())
+	})
+
+	t.Run("discard restores the records", func(t *testing.T) {
+		batcher := NewRequestBatcher()
+		ctx := WithRequestBatcher(offlineContext(t), batcher)
+
+		post := NewMongoRecord(postSchema)
+		assert.NoError(t, post.SetValue(mustField(t, postSchema, "title"), "Hello"))
+		assert.NoError(t, post.Save(ctx))
+		_, hasID := recordID(post)
+		assert.True(t, hasID)
+		assert.Equal(t, "draft", post.record["status"], "defaults are applied on Save")
+
+		batcher.Discard()
+		assert.Zero(t, batcher.Len())
+		_, hasID = recordID(post)
+		assert.False(t, hasID)
+		assert.Equal(t, map[string]any{"title": "Hello"}, post.record)
+	})
+
+	t.Run("flush on a read-only store", func(t *testing.T) {
+		batcher := NewRequestBatcher()
+		author := newAuthor("Ada")
+		assert.NoError(t, author.Save(WithRequestBatcher(offlineContext(t), batcher)))
+
+		assert.ErrorIs(t, batcher.Flush(WithReadOnly(context.Background())), ErrReadOnly)
+		assert.Zero(t, batcher.Len())
+		_, hasID := recordID(author)
+		assert.False(t, hasID)
+
+		assert.NoError(t, batcher.Flush(context.Background()), "empty batches flush nothing")
+	})
+
+	t.Run("Batch discards on error and panic", func(t *testing.T) {
+		ctx := offlineContext(t)
+		author := newAuthor("Ada")
+		failure := errors.New("boom")
+
+		err := Batch(ctx, func(ctx context.Context) error {
+			assert.NoError(t, author.Save(ctx))
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		_, hasID := recordID(author)
+		assert.False(t, hasID)
+
+		assert.PanicsWithValue(t, "boom", func() {
+			Batch(ctx, func(ctx context.Context) error {
+				assert.NoError(t, author.Save(ctx))
+				panic("boom")
+			})
+		})
+		_, hasID = recordID(author)
+		assert.False(t, hasID)
+	})
+}
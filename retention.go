@@ -0,0 +1,256 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultCleanupInterval  = time.Hour
+	defaultCleanupBatchSize = 500
+)
+
+// RetentionPolicy expires the records of a schema once a date field is older
+// than MaxAge, e.g. closed tickets 90 days after they were closed. Unlike a
+// TTL index, it can be narrowed with a filter and archive what it removes.
+type RetentionPolicy struct {
+	Schema JSchema
+	// Field names the DateTime field the age is measured from; records
+	// without it never expire.
+	Field  string
+	MaxAge time.Duration
+	// Where optionally narrows the records that expire.
+	Where Filter
+	// Archive optionally names a collection of the schema's database that
+	// expired documents are copied to before they are deleted.
+	Archive string
+}
+
+// cutoff returns the instant before which records expire at now.
+func (p RetentionPolicy) cutoff(now time.Time) time.Time {
+	return now.Add(-p.MaxAge).UTC()
+}
+
+// Filter returns the filter matching the records expired at now.
+func (p RetentionPolicy) Filter(now time.Time) (Filter, error) {
+	field, ok := p.Schema.Field(p.Field)
+	if !ok {
+		return nil, fmt.Errorf("jpack: retention of %s: unknown field %s", p.Schema.Name(), p.Field)
+	}
+	if _, ok := field.Type().(*DateTime); !ok {
+		return nil, fmt.Errorf("jpack: retention of %s: %s is not a DateTime field", p.Schema.Name(), p.Field)
+	}
+
+	filter := Lt(field, p.cutoff(now))
+	if p.Where != nil {
+		filter = filter.And(p.Where)
+	}
+	return filter, nil
+}
+
+// ExpiresAt returns when the record expires by its age. Where isn't
+// evaluated, so records it excludes report an expiry too.
+func (p RetentionPolicy) ExpiresAt(record JRecord) (time.Time, bool) {
+	field, ok := p.Schema.Field(p.Field)
+	if !ok {
+		return time.Time{}, false
+	}
+	at, ok := record.Time(field)
+	if !ok {
+		return time.Time{}, false
+	}
+	return at.Add(p.MaxAge), true
+}
+
+// Expired returns a query of the records expired at now.
+func (p RetentionPolicy) Expired(ctx context.Context, now time.Time) (Query, error) {
+	filter, err := p.Filter(now)
+	if err != nil {
+		return nil, err
+	}
+	return NewQuery(ctx, p.Schema).Where(filter), nil
+}
+
+// CleanupResult is what a Cleanup pass did for one policy.
+type CleanupResult struct {
+	Schema string
+	// Matched counts the expired records. A dry run only counts them.
+	Matched  int
+	Archived int
+	Deleted  int
+	DryRun   bool
+}
+
+// CleanupMetrics observes the passes of a Cleanup runner, e.g. to feed
+// Prometheus counters.
+type CleanupMetrics interface {
+	ObserveCleanup(ctx context.Context, result CleanupResult, duration time.Duration, err error)
+}
+
+// Cleanup deletes, or archives and deletes, the records expired by its
+// policies on a schedule, for retention rules that don't map to a TTL
+// index. Documents are removed directly, without hooks, outbox entries or
+// webhooks.
+type Cleanup struct {
+	Policies []RetentionPolicy
+
+	// Interval is the wait between passes, defaults to an hour.
+	Interval time.Duration
+	// BatchSize is the number of documents removed per write, defaults to 500.
+	BatchSize int
+	// DryRun only counts the expired records.
+	DryRun  bool
+	Metrics CleanupMetrics
+
+	// now returns the current time, for tests
+	now func() time.Time
+}
+
+// Run makes a pass over the policies every Interval until ctx is done. Failed
+// passes are logged and retried on the next tick.
+func (c *Cleanup) Run(ctx context.Context) error {
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultCleanupInterval
+	}
+
+	for {
+		if _, err := c.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			LoggerFrom(ctx).Error().Err(err).Msg("jpack: cleanup failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// RunOnce makes one pass over the policies. Every policy is run even when an
+// earlier one fails; the errors are joined.
+func (c *Cleanup) RunOnce(ctx context.Context) ([]CleanupResult, error) {
+	if IsReadOnly(ctx) && !c.DryRun {
+		return nil, ErrReadOnly
+	}
+
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+
+	results := make([]CleanupResult, 0, len(c.Policies))
+	var errs []error
+	for _, policy := range c.Policies {
+		start := time.Now()
+		result, err := c.apply(ctx, policy, now)
+		if c.Metrics != nil {
+			c.Metrics.ObserveCleanup(ctx, result, time.Since(start), err)
+		}
+		results = append(results, result)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("jpack: cleanup of %s: %w", policy.Schema.Name(), err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// apply removes the records expired by one policy.
+func (c *Cleanup) apply(ctx context.Context, policy RetentionPolicy, now time.Time) (CleanupResult, error) {
+	result := CleanupResult{Schema: policy.Schema.Name(), DryRun: c.DryRun}
+	if _, ok := policy.Schema.(*ViewSchema); ok {
+		return result, ErrViewReadOnly
+	}
+	if policy.MaxAge <= 0 {
+		return result, errors.New("jpack: retention needs a positive MaxAge")
+	}
+
+	filter, err := policy.Filter(now)
+	if err != nil {
+		return result, err
+	}
+	if err := resolveSubqueries(filter); err != nil {
+		return result, err
+	}
+	mongoFilter := ResolveFilter(filter)
+	coll := collection(ctx, policy.Schema)
+
+	if c.DryRun {
+		start := time.Now()
+		count, err := coll.CountDocuments(ctx, mongoFilter)
+		tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "count", Filter: mongoFilter, Count: count, Err: err}, start)
+		result.Matched = int(count)
+		return result, err
+	}
+
+	batchSize := c.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultCleanupBatchSize
+	}
+
+	var archive *mongo.Collection
+	findOpts := options.Find().SetBatchSize(int32(batchSize))
+	if policy.Archive != "" {
+		archive = coll.Database().Collection(policy.Archive)
+	} else {
+		findOpts.SetProjection(bson.M{defaultMongoPK: 1})
+	}
+
+	cursor, err := coll.Find(ctx, mongoFilter, findOpts)
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []any
+	var ids []any
+	remove := func() error {
+		if len(ids) == 0 {
+			return nil
+		}
+		if archive != nil {
+			// Documents archived by an interrupted pass are already there
+			_, err := archive.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+			if err != nil && !mongo.IsDuplicateKeyError(err) {
+				return err
+			}
+			result.Archived += len(docs)
+		}
+
+		start := time.Now()
+		res, err := coll.DeleteMany(ctx, bson.M{defaultMongoPK: bson.M{"$in": ids}})
+		var deleted int64
+		if err == nil {
+			deleted = res.DeletedCount
+		}
+		tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "cleanup", Count: deleted, Err: err}, start)
+		result.Deleted += int(deleted)
+		docs, ids = docs[:0], ids[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return result, err
+		}
+		result.Matched++
+		docs = append(docs, doc)
+		ids = append(ids, doc[defaultMongoPK])
+		if len(ids) >= batchSize {
+			if err := remove(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return result, err
+	}
+	return result, remove()
+}
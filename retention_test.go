@@ -0,0 +1,85 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type recordingCleanupMetrics struct {
+	results []CleanupResult
+	errs    []error
+}
+
+func (m *recordingCleanupMetrics) ObserveCleanup(ctx context.Context, result CleanupResult, duration time.Duration, err error) {
+	m.results = append(m.results, result)
+	m.errs = append(m.errs, err)
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	tickets := NewSchema("test_retention_tickets").
+		Field("id", &String{}).
+		Field("status", &String{}).
+		Field("closed_at", &DateTime{}).
+		Build()
+	status := mustField(t, tickets, "status")
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	policy := RetentionPolicy{Schema: tickets, Field: "closed_at", MaxAge: 90 * 24 * time.Hour, Where: Eq(status, "closed")}
+
+	t.Run("filters match records older than MaxAge", func(t *testing.T) {
+		filter, err := policy.Filter(now)
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"closed_at": bson.M{"$lt": time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)}},
+			{"status": "closed"},
+		}}, ResolveFilter(filter))
+	})
+
+	t.Run("the field must be a DateTime", func(t *testing.T) {
+		_, err := RetentionPolicy{Schema: tickets, Field: "status", MaxAge: time.Hour}.Filter(now)
+		assert.ErrorContains(t, err, "status is not a DateTime field")
+		_, err = RetentionPolicy{Schema: tickets, Field: "opened_at", MaxAge: time.Hour}.Expired(offlineContext(t), now)
+		assert.ErrorContains(t, err, "unknown field opened_at")
+	})
+
+	t.Run("ExpiresAt adds MaxAge to the field", func(t *testing.T) {
+		record := NewMongoRecord(tickets)
+		_, ok := policy.ExpiresAt(record)
+		assert.False(t, ok)
+
+		assert.NoError(t, record.SetValue(mustField(t, tickets, "closed_at"), now))
+		at, ok := policy.ExpiresAt(record)
+		assert.True(t, ok)
+		assert.True(t, now.Add(90*24*time.Hour).Equal(at))
+	})
+
+	t.Run("read-only stores only allow dry runs", func(t *testing.T) {
+		cleanup := &Cleanup{Policies: []RetentionPolicy{policy}}
+		_, err := cleanup.RunOnce(WithReadOnly(offlineContext(t)))
+		assert.ErrorIs(t, err, ErrReadOnly)
+	})
+
+	t.Run("every policy runs and is observed", func(t *testing.T) {
+		metrics := &recordingCleanupMetrics{}
+		cleanup := &Cleanup{
+			Policies: []RetentionPolicy{
+				{Schema: tickets, Field: "closed_at"},
+				{Schema: NewView("test_retention_view", tickets).Build(), Field: "closed_at", MaxAge: time.Hour},
+			},
+			Metrics: metrics,
+			now:     func() time.Time { return now },
+		}
+
+		results, err := cleanup.RunOnce(offlineContext(t))
+		assert.ErrorContains(t, err, "positive MaxAge")
+		assert.ErrorIs(t, err, ErrViewReadOnly)
+		assert.Len(t, results, 2)
+		assert.Equal(t, "test_retention_tickets", results[0].Schema)
+		assert.Len(t, metrics.results, 2)
+		assert.Error(t, metrics.errs[0])
+		assert.ErrorIs(t, metrics.errs[1], ErrViewReadOnly)
+	})
+}
@@ -0,0 +1,265 @@
+package jpack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3BlobStore stores files in an S3 or S3-compatible bucket (MinIO, R2, ...),
+// signing requests with AWS Signature Version 4.
+type S3BlobStore struct {
+	// Endpoint of the service, e.g. https://s3.eu-west-1.amazonaws.com
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as Endpoint/Bucket instead of as a
+	// subdomain, as most S3-compatible servers expect.
+	PathStyle bool
+	Client    *http.Client
+
+	now func() time.Time
+}
+
+// Put implements BlobStore. S3 needs the content length up front, so content
+// of unknown length is spooled to a temporary file first.
+func (s *S3BlobStore) Put(ctx context.Context, key string, content io.Reader, info FileInfo) error {
+	body, size, cleanup, err := sizedReader(content)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	req, err := s.request(ctx, http.MethodPut, key, body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if info.ContentType != "" {
+		req.Header.Set("Content-Type", info.ContentType)
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// Get implements BlobStore.
+func (s *S3BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.request(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// Delete implements BlobStore.
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	req, err := s.request(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req)
+	if err == ErrBlobNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *S3BlobStore) request(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	endpoint := strings.TrimSuffix(s.Endpoint, "/")
+	path := "/" + awsURIEncode(key, false)
+
+	var target string
+	if s.PathStyle {
+		target = endpoint + "/" + awsURIEncode(s.Bucket, true) + path
+	} else {
+		scheme, host, ok := strings.Cut(endpoint, "://")
+		if !ok {
+			return nil, fmt.Errorf("jpack: invalid S3 endpoint %q", s.Endpoint)
+		}
+		target = scheme + "://" + s.Bucket + "." + host + path
+	}
+
+	return http.NewRequestWithContext(ctx, method, target, body)
+}
+
+func (s *S3BlobStore) do(req *http.Request) (*http.Response, error) {
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	signS3Request(req, s.AccessKey, s.SecretKey, s.Region, s3UnsignedPayload, now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, ErrBlobNotFound
+	}
+	if res.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		res.Body.Close()
+		return nil, fmt.Errorf("jpack: S3 returned %d: %s", res.StatusCode, data)
+	}
+	return res, nil
+}
+
+// sizedReader returns content with its length, spooling it to a temporary
+// file when the length can't be determined.
+func sizedReader(content io.Reader) (io.Reader, int64, func(), error) {
+	noop := func() {}
+
+	if sized, ok := content.(interface{ Len() int }); ok {
+		return content, int64(sized.Len()), noop, nil
+	}
+
+	tmp, err := os.CreateTemp("", "jpack-upload-*")
+	if err != nil {
+		return nil, 0, noop, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	size, err := io.Copy(tmp, content)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		cleanup()
+		return nil, 0, noop, err
+	}
+
+	return tmp, size, cleanup, nil
+}
+
+// signS3Request adds an AWS Signature Version 4 Authorization header covering
+// the host and every header already set on the request.
+func signS3Request(req *http.Request, accessKey, secretKey, region, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(req *http.Request) string {
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, awsURIEncode(key, true)+"="+awsURIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode percent-encodes everything but unreserved characters, and
+// slashes unless encodeSlash is set, as Signature Version 4 requires.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+var _ BlobStore = &S3BlobStore{}
@@ -0,0 +1,125 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// ErrPreconditionFailed is returned by Save when a precondition such as
+// IfMatch doesn't hold for the stored document.
+var ErrPreconditionFailed = errors.New("jpack: precondition failed")
+
+// SaveOption configures a single Save call.
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	ifMatch        *string
+	returnDocument bool
+	idempotencyKey string
+	scenarios      []Scenario
+}
+
+func newSaveOptions(opts []SaveOption) *saveOptions {
+	o := &saveOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// IfMatch only saves when the stored document still has the given ETag, the
+// value of JRecord.Hash when the client read it. Quoted and weak ("W/") HTTP
+// ETags are accepted.
+func IfMatch(etag string) SaveOption {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	etag = strings.Trim(etag, `"`)
+
+	return func(o *saveOptions) {
+		o.ifMatch = &etag
+	}
+}
+
+// ReturnDocument reads the stored document back after the write, so the
+// record reflects what the server stored, e.g. server-side defaults or values
+// changed by a concurrent write, instead of its own pending values. The record
+// is clean afterwards. It costs a findOne per Save, and the write isn't
+// batched by a RequestBatcher.
+func ReturnDocument() SaveOption {
+	return func(o *saveOptions) {
+		o.returnDocument = true
+	}
+}
+
+// refresh replaces the state of the record with its stored document.
+func (m *mongoRecord) refresh(ctx context.Context) error {
+	stored, err := m.reload(ctx)
+	if err != nil {
+		return err
+	}
+
+	m.originalRecord = stored.originalRecord
+	m.record = stored.record
+	m.renamedKeys = stored.renamedKeys
+	m.unknownKeys = stored.unknownKeys
+	m.lineage = stored.lineage
+	m.invalidateScanned()
+	return nil
+}
+
+// ETag returns the record's hash formatted as a quoted HTTP entity tag.
+func ETag(record JRecord) (string, error) {
+	hash, err := record.Hash()
+	if err != nil {
+		return "", err
+	}
+	return `"` + hash + `"`, nil
+}
+
+// matchFilter checks the stored document against etag and returns a filter
+// that only matches the document while it is unchanged, so a concurrent write
+// between the check and the update is detected too.
+func (m *mongoRecord) matchFilter(ctx context.Context, coll *mongo.Collection, objID bson.ObjectID, etag string) (bson.M, error) {
+	var doc bson.M
+	err := coll.FindOne(ctx, bson.M{defaultMongoPK: objID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPreconditionFailed
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := RecordFromBSON(m.Schema(), doc)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := stored.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if hash != etag {
+		return nil, ErrPreconditionFailed
+	}
+
+	filter := bson.M{defaultMongoPK: objID}
+	for _, field := range m.Schema().Fields() {
+		for _, key := range storageKeys(field) {
+			if key == defaultMongoPK {
+				continue
+			}
+
+			if value, ok := doc[key]; ok {
+				filter[key] = value
+			} else {
+				filter[key] = bson.M{"$exists": false}
+			}
+		}
+	}
+
+	return filter, nil
+}
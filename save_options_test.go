@@ -0,0 +1,85 @@
+package jpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestIfMatch(t *testing.T) {
+	t.Run("HTTP ETag forms are normalized", func(t *testing.T) {
+		for _, etag := range []string{`abc`, `"abc"`, `W/"abc"`, ` "abc" `} {
+			o := newSaveOptions([]SaveOption{IfMatch(etag)})
+			assert.Equal(t, "abc", *o.ifMatch, etag)
+		}
+	})
+
+	t.Run("no options", func(t *testing.T) {
+		assert.Nil(t, newSaveOptions(nil).ifMatch)
+		assert.False(t, newSaveOptions(nil).returnDocument)
+	})
+
+	t.Run("ReturnDocument", func(t *testing.T) {
+		assert.True(t, newSaveOptions([]SaveOption{ReturnDocument()}).returnDocument)
+	})
+
+	t.Run("WithIdempotencyKey", func(t *testing.T) {
+		assert.Equal(t, "signup-42", newSaveOptions([]SaveOption{WithIdempotencyKey("signup-42")}).idempotencyKey)
+	})
+
+	t.Run("ETag quotes the record hash", func(t *testing.T) {
+		record, err := RecordFromBSON(userSchema, bson.M{"_id": bson.NewObjectID(), "first_name": "John"})
+		assert.NoError(t, err)
+
+		hash, err := record.Hash()
+		assert.NoError(t, err)
+
+		etag, err := ETag(record)
+		assert.NoError(t, err)
+		assert.Equal(t, `"`+hash+`"`, etag)
+		assert.True(t, strings.HasPrefix(etag, `"`))
+	})
+
+	t.Run("new records fail the precondition", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(mustField(t, userSchema, "first_name"), "John"))
+		assert.ErrorIs(t, record.Save(offlineContext(t), IfMatch(`"abc"`)), ErrPreconditionFailed)
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	firstName := mustField(t, userSchema, "first_name")
+
+	t.Run("inserts store the key", func(t *testing.T) {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.SetValue(firstName, "Grace"))
+
+		w, err := record.prepareSave(offlineContext(t), &saveOptions{idempotencyKey: "signup-42"})
+		assert.NoError(t, err)
+		assert.Equal(t, "signup-42", w.document[idempotencyKeyField])
+	})
+
+	t.Run("updates ignore the key", func(t *testing.T) {
+		record, err := RecordFromBSON(userSchema, bson.M{"_id": bson.NewObjectID(), "first_name": "Grace", idempotencyKeyField: "signup-42"})
+		assert.NoError(t, err)
+		assert.Empty(t, record.UnknownKeys(), "the key is hidden from records")
+
+		assert.NoError(t, record.SetValue(firstName, "Ada"))
+		w, err := record.(*mongoRecord).prepareSave(offlineContext(t), &saveOptions{idempotencyKey: "signup-43"})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$set": bson.M{"first_name": "Ada"}}, w.document)
+	})
+
+	t.Run("the index is unique and sparse", func(t *testing.T) {
+		assert.Equal(t, CreateIndexStep{Schema: userSchema, Keys: []string{idempotencyKeyField}, Unique: true, Sparse: true}, IdempotencyIndexStep(userSchema))
+	})
+
+	t.Run("SQLite stores reject keys", func(t *testing.T) {
+		record := NewSQLiteRecord(userSchema)
+		assert.NoError(t, record.SetValue(firstName, "Grace"))
+		ctx := WithSQLite(t.Context(), NewSQLiteStore(nil))
+		assert.ErrorContains(t, record.Save(ctx, WithIdempotencyKey("signup-42")), "idempotency keys")
+	})
+}
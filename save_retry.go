@@ -0,0 +1,127 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// SaveWithRetry implements JRecord.
+func (m *mongoRecord) SaveWithRetry(ctx context.Context, attempts int, onConflict func(local, remote JRecord) (JRecord, error)) error {
+	if r, ok := m.sqliteRoute(ctx); ok {
+		return r.SaveWithRetry(ctx, attempts, onConflict)
+	}
+	if m.IsNew() {
+		return m.Save(ctx)
+	}
+
+	current := m
+	for attempt := 1; ; attempt++ {
+		etag, err := current.loadedHash()
+		if err != nil {
+			return err
+		}
+
+		err = current.Save(ctx, IfMatch(etag))
+		if err == nil {
+			if current != m {
+				m.adopt(current)
+			}
+			return nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) || attempt >= attempts {
+			return err
+		}
+
+		remote, err := current.reload(ctx)
+		if err != nil {
+			return err
+		}
+		if current, err = resolveConflict(m, remote, onConflict); err != nil {
+			return err
+		}
+	}
+}
+
+// loadedHash returns the hash of the record as it was loaded, the ETag of
+// the stored document it was read from.
+func (m *mongoRecord) loadedHash() (string, error) {
+	loaded := &mongoRecord{schema: m.schema, originalRecord: m.originalRecord, record: map[string]any{}}
+	return loaded.Hash()
+}
+
+// reload reads the stored document of the record into a new record, leaving
+// the identity map alone. A deleted document is ErrNotFound.
+func (m *mongoRecord) reload(ctx context.Context) (*mongoRecord, error) {
+	objID, err := m.objectID()
+	if err != nil {
+		return nil, err
+	}
+
+	coll := collection(ctx, m.Schema())
+	filter := bson.M{defaultMongoPK: objID}
+	start := time.Now()
+	var doc bson.M
+	err = coll.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "findOne", Filter: filter}, start)
+		return nil, ErrNotFound
+	}
+	tapQuery(ctx, QueryOperation{Collection: coll.Name(), Operation: "findOne", Filter: filter, Count: 1, Err: err}, start)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := NewMongoRecord(m.Schema())
+	if err := remote.loadDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+// resolveConflict asks onConflict for the record to save next. Returning the
+// local record keeps its changes on top of the stored document.
+func resolveConflict(local, remote *mongoRecord, onConflict func(local, remote JRecord) (JRecord, error)) (*mongoRecord, error) {
+	if onConflict == nil {
+		return nil, ErrPreconditionFailed
+	}
+
+	resolved, err := onConflict(local, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	next, ok := resolved.(*mongoRecord)
+	if !ok || next.Schema().Name() != local.Schema().Name() {
+		return nil, fmt.Errorf("jpack: conflict resolution must return a record of %s", local.Schema().Name())
+	}
+
+	if next == local {
+		rebased := NewMongoRecord(local.schema)
+		rebased.originalRecord = remote.originalRecord
+		rebased.renamedKeys = remote.renamedKeys
+		for key, value := range local.record {
+			rebased.record[key] = value
+		}
+		return rebased, nil
+	}
+	if next != remote {
+		// Any other record is saved over the document read by remote
+		next.originalRecord = remote.originalRecord
+	}
+	return next, nil
+}
+
+// adopt takes over the state of a record saved in place of m.
+func (m *mongoRecord) adopt(saved *mongoRecord) {
+	m.originalRecord = saved.originalRecord
+	m.record = saved.record
+	m.renamedKeys = saved.renamedKeys
+	m.lineage = saved.lineage
+	m.saves++
+	m.invalidateScanned()
+}
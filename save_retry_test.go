@@ -0,0 +1,112 @@
+package jpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestResolveConflict(t *testing.T) {
+	firstName := mustField(t, userSchema, "first_name")
+	lastName := mustField(t, userSchema, "last_name")
+	id := bson.NewObjectID()
+
+	load := func(first, last string) *mongoRecord {
+		record := NewMongoRecord(userSchema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": id, "first_name": first, "last_name": last}))
+		return record
+	}
+
+	t.Run("local changes on top of the stored document", func(t *testing.T) {
+		local := load("Jhon", "Doe")
+		local.SetValue(firstName, "John")
+		remote := load("Jhon", "Smith")
+
+		next, err := resolveConflict(local, remote, func(l, r JRecord) (JRecord, error) { return l, nil })
+		assert.NoError(t, err)
+		assert.NotSame(t, local, next)
+		assert.Equal(t, []string{"first_name"}, next.DirtyKeys())
+
+		first, _ := next.Value(firstName)
+		last, _ := next.Value(lastName)
+		assert.Equal(t, "John", first)
+		assert.Equal(t, "Smith", last)
+
+		// The next attempt is conditional on the remote document
+		remoteHash, _ := remote.Hash()
+		nextHash, _ := next.loadedHash()
+		assert.Equal(t, remoteHash, nextHash)
+
+		// Local is untouched until the save succeeds
+		last, _ = local.Value(lastName)
+		assert.Equal(t, "Doe", last)
+	})
+
+	t.Run("merged remote record", func(t *testing.T) {
+		local := load("Jhon", "Doe")
+		local.SetValue(firstName, "John")
+		remote := load("Jhon", "Smith")
+
+		next, err := resolveConflict(local, remote, func(l, r JRecord) (JRecord, error) {
+			value, _ := l.Value(firstName)
+			return r, r.SetValue(firstName, value)
+		})
+		assert.NoError(t, err)
+		assert.Same(t, remote, next)
+		assert.Equal(t, []string{"first_name"}, next.DirtyKeys())
+
+		local.adopt(next)
+		last, _ := local.Value(lastName)
+		assert.Equal(t, "Smith", last)
+	})
+
+	t.Run("giving up", func(t *testing.T) {
+		local, remote := load("a", "b"), load("c", "d")
+		failure := errors.New("manual merge needed")
+
+		_, err := resolveConflict(local, remote, func(l, r JRecord) (JRecord, error) { return nil, failure })
+		assert.ErrorIs(t, err, failure)
+
+		_, err = resolveConflict(local, remote, nil)
+		assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+		other := NewMongoRecord(NewSchema("other").Field("id", &String{}).Build())
+		_, err = resolveConflict(local, remote, func(l, r JRecord) (JRecord, error) { return other, nil })
+		assert.Error(t, err)
+	})
+
+	t.Run("loaded hash ignores pending changes", func(t *testing.T) {
+		record := load("Jhon", "Doe")
+		before, _ := record.loadedHash()
+		record.SetValue(firstName, "John")
+		after, _ := record.loadedHash()
+		assert.Equal(t, before, after)
+
+		current, _ := record.Hash()
+		assert.NotEqual(t, before, current)
+	})
+}
+
+func TestLoadedHashAfterUpdate(t *testing.T) {
+	ctx := offlineContext(t)
+	firstName := mustField(t, userSchema, "first_name")
+	lastName := mustField(t, userSchema, "last_name")
+
+	record := NewMongoRecord(userSchema)
+	assert.NoError(t, record.loadDocument(ctx, bson.M{"_id": bson.NewObjectID(), "first_name": "Jhon", "last_name": "Doe"}))
+	record.SetValue(firstName, "John")
+	record.SetValue(lastName, nil)
+
+	w, err := record.prepareSave(ctx, &saveOptions{})
+	assert.NoError(t, err)
+	assert.NoError(t, w.finish(ctx))
+
+	// The next conditional save matches the document this write left behind
+	stored, _ := record.Hash()
+	loaded, err := record.loadedHash()
+	assert.NoError(t, err)
+	assert.Equal(t, stored, loaded)
+	assert.Empty(t, record.DirtyKeys())
+}
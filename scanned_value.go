@@ -0,0 +1,77 @@
+package jpack
+
+import (
+	"context"
+	"time"
+)
+
+// ScannedValue implements JRecord. Pending values are converted to their
+// stored form first, so they scan the same as values read from the database.
+func (m *mongoRecord) ScannedValue(ctx context.Context, field JField) (any, error) {
+	if value, ok := m.scanned[field.Name()]; ok {
+		return value, nil
+	}
+
+	row := m.originalRecord
+	if value, ok := m.record[field.Name()]; ok {
+		row = map[string]any{}
+		if !isUnset(value) {
+			if err := field.Type().SetValue(ctx, field, value, row); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	value, err := scanField(ctx, field, row)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.scanned == nil {
+		m.scanned = make(map[string]any)
+	}
+	m.scanned[field.Name()] = value
+	return value, nil
+}
+
+// invalidateScanned drops the memoized scans of the given keys, or of all
+// fields without keys.
+func (m *mongoRecord) invalidateScanned(keys ...string) {
+	if len(keys) == 0 {
+		m.scanned = nil
+		return
+	}
+	for _, key := range keys {
+		delete(m.scanned, key)
+	}
+}
+
+// String implements JRecord.
+func (m *mongoRecord) String(field JField) (string, bool) {
+	return scannedAs[string](m, field)
+}
+
+// Int implements JRecord.
+func (m *mongoRecord) Int(field JField) (int, bool) {
+	return scannedAs[int](m, field)
+}
+
+// Time implements JRecord.
+func (m *mongoRecord) Time(field JField) (time.Time, bool) {
+	return scannedAs[time.Time](m, field)
+}
+
+// Bool implements JRecord.
+func (m *mongoRecord) Bool(field JField) (bool, bool) {
+	return scannedAs[bool](m, field)
+}
+
+func scannedAs[T any](m *mongoRecord, field JField) (T, bool) {
+	var zero T
+	value, err := m.ScannedValue(context.Background(), field)
+	if err != nil {
+		return zero, false
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
@@ -0,0 +1,91 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestScannedValue(t *testing.T) {
+	ctx := context.Background()
+	schema := NewSchema("test_events").
+		Field("id", &String{}).
+		Field("at", &DateTime{}).
+		Build()
+	at := mustField(t, schema, "at")
+
+	stored := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	record := NewMongoRecord(schema)
+	assert.NoError(t, record.loadDocument(ctx, bson.M{"_id": bson.NewObjectID(), "at": bson.NewDateTimeFromTime(stored)}))
+
+	value, err := record.ScannedValue(ctx, at)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, value)
+
+	// Scans are memoized
+	record.originalRecord["at"] = "not a time"
+	value, err = record.ScannedValue(ctx, at)
+	assert.NoError(t, err)
+	assert.Equal(t, stored, value)
+
+	// SetValue invalidates the field, and pending values scan like stored ones
+	assert.NoError(t, record.SetValue(at, "2024-07-02T08:30:00+02:00"))
+	value, err = record.ScannedValue(ctx, at)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 7, 2, 6, 30, 0, 0, time.UTC), value)
+
+	value, err = NewMongoRecord(schema).ScannedValue(ctx, at)
+	assert.NoError(t, err)
+	assert.Nil(t, value, "unset fields scan to nil")
+}
+
+func TestTypedGetters(t *testing.T) {
+	schema := NewSchema("test_typed").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("age", &Number{}).
+		Field("at", &DateTime{}).
+		Field("active", &Boolean{}).
+		Build()
+	name, age := mustField(t, schema, "name"), mustField(t, schema, "age")
+	at, active := mustField(t, schema, "at"), mustField(t, schema, "active")
+
+	record := NewMongoRecord(schema)
+	stored := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, record.loadDocument(context.Background(), bson.M{
+		"_id":    bson.NewObjectID(),
+		"name":   "Ada",
+		"age":    int32(36),
+		"at":     bson.NewDateTimeFromTime(stored),
+		"active": true,
+	}))
+
+	s, ok := record.String(name)
+	assert.True(t, ok)
+	assert.Equal(t, "Ada", s)
+
+	n, ok := record.Int(age)
+	assert.True(t, ok)
+	assert.Equal(t, 36, n)
+
+	ts, ok := record.Time(at)
+	assert.True(t, ok)
+	assert.Equal(t, stored, ts)
+
+	b, ok := record.Bool(active)
+	assert.True(t, ok)
+	assert.True(t, b)
+
+	// Other types and unset fields are the zero value
+	n, ok = record.Int(name)
+	assert.False(t, ok)
+	assert.Zero(t, n)
+
+	assert.NoError(t, record.SetValue(name, nil))
+	s, ok = record.String(name)
+	assert.False(t, ok)
+	assert.Empty(t, s)
+}
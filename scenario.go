@@ -0,0 +1,109 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// Scenario names a validation flow over a schema, e.g. "draft" or "publish",
+// whose rules differ in strictness.
+type Scenario string
+
+// RequiredIn marks a field that must have a value when a record is validated
+// or saved in one of the scenarios, e.g. a body required to publish but not
+// to save a draft. Required fields are required in every scenario.
+func RequiredIn(scenarios ...Scenario) FieldOption {
+	return func(f *fieldImpl) {
+		f.requiredIn = append(f.requiredIn, scenarios...)
+	}
+}
+
+// ValidateIn limits the record checks of a field, e.g. the option checks of
+// DependentOptions, to the scenarios. Values are still checked against the
+// field type when they are set.
+func ValidateIn(scenarios ...Scenario) FieldOption {
+	return func(f *fieldImpl) {
+		f.validateIn = append(f.validateIn, scenarios...)
+	}
+}
+
+// WithScenario saves the record in the scenario, enforcing the fields
+// required in it.
+func WithScenario(scenario Scenario) SaveOption {
+	return func(o *saveOptions) {
+		o.scenarios = append(o.scenarios, scenario)
+	}
+}
+
+// IsRequiredIn reports whether the field must have a value in any of the
+// scenarios.
+func IsRequiredIn(field JField, scenarios ...Scenario) bool {
+	if IsRequired(field) {
+		return true
+	}
+	f, ok := field.(interface{ RequiredIn() []Scenario })
+	return ok && inScenario(f.RequiredIn(), scenarios)
+}
+
+// validatesIn reports whether the record checks of the field run in any of
+// the scenarios.
+func validatesIn(field JField, scenarios []Scenario) bool {
+	f, ok := field.(interface{ ValidateIn() []Scenario })
+	if !ok || len(f.ValidateIn()) == 0 {
+		return true
+	}
+	return inScenario(f.ValidateIn(), scenarios)
+}
+
+func inScenario(rule, scenarios []Scenario) bool {
+	for _, scenario := range scenarios {
+		if slices.Contains(rule, scenario) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRequiredIn rejects saving a stored record in the scenarios without a
+// value a field requires in them, even when the field isn't changed, e.g.
+// publishing a draft that never got a body.
+func (m *mongoRecord) checkRequiredIn(scenarios []Scenario) error {
+	if len(scenarios) == 0 {
+		return nil
+	}
+	for _, field := range m.Schema().Fields() {
+		if IsRequired(field) {
+			continue
+		}
+		value, _ := m.Value(field)
+		if err := m.checkRequired(field, value, scenarios...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate implements JRecord. It reports every missing required value and
+// failed record check at once, joined, so a form can show them together.
+// Warnings are kept apart, for WarningsOf.
+// Fields with a default aren't required of new records, which get the default
+// when saved.
+func (m *mongoRecord) Validate(ctx context.Context, scenarios ...Scenario) error {
+	var errs []error
+	for _, field := range m.Schema().Fields() {
+		if value, ok := m.Value(field); ok && value != nil {
+			continue
+		}
+		if m.IsNew() && field.Default() != nil {
+			continue
+		}
+		if err := m.checkRequired(field, nil, scenarios...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, runRecordChecks(ctx, m, scenarios)...)
+	m.warnings = collectWarnings(ctx, m)
+	return errors.Join(errs...)
+}
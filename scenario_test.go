@@ -0,0 +1,81 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestScenarios(t *testing.T) {
+	const publish Scenario = "publish"
+	categories := DependentOptionsMap{
+		"news": {{UniqueName: "politics", DisplayName: "Politics"}},
+	}
+	articles := NewSchema("test_scenario_articles").
+		Field("id", &String{}).
+		Field("title", &String{}, Required()).
+		Field("body", &String{}, RequiredIn(publish)).
+		Field("section", &String{}).
+		Field("category", NewDependentOptions(categories, "section"), ValidateIn(publish)).
+		Build()
+	title, body := mustField(t, articles, "title"), mustField(t, articles, "body")
+	section, category := mustField(t, articles, "section"), mustField(t, articles, "category")
+	ctx := offlineContext(t)
+
+	draft := func(t *testing.T) JRecord {
+		record := NewMongoRecord(articles)
+		assert.NoError(t, record.SetValue(title, "Elections"))
+		assert.NoError(t, record.SetValue(section, "news"))
+		assert.NoError(t, record.SetValue(category, "sports"))
+		return record
+	}
+
+	t.Run("rules outside their scenario don't apply", func(t *testing.T) {
+		record := draft(t)
+		assert.NoError(t, record.Validate(ctx))
+		assert.NoError(t, record.Validate(ctx, "draft"))
+		_, err := record.(*mongoRecord).prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Validate reports every failed rule of the scenario", func(t *testing.T) {
+		record := draft(t)
+		assert.NoError(t, record.Unset(title))
+		err := record.Validate(ctx, publish)
+		assert.ErrorIs(t, err, ErrRequiredField)
+		assert.ErrorContains(t, err, "test_scenario_articles.title is required")
+		assert.ErrorContains(t, err, "test_scenario_articles.body is required")
+		assert.ErrorContains(t, err, "category: value is not in the list")
+	})
+
+	t.Run("saves enforce the scenario", func(t *testing.T) {
+		record := draft(t)
+		_, err := record.(*mongoRecord).prepareSave(ctx, newSaveOptions([]SaveOption{WithScenario(publish)}))
+		assert.ErrorContains(t, err, "category: value is not in the list")
+
+		assert.NoError(t, record.SetValue(category, "politics"))
+		_, err = record.(*mongoRecord).prepareSave(ctx, newSaveOptions([]SaveOption{WithScenario(publish)}))
+		assert.ErrorIs(t, err, ErrRequiredField)
+	})
+
+	t.Run("publishing a stored draft needs its unchanged fields", func(t *testing.T) {
+		record, err := RecordFromBSON(articles, bson.M{"_id": bson.NewObjectID(), "title": "Elections"})
+		assert.NoError(t, err)
+		assert.NoError(t, record.SetValue(title, "Elections 2024"))
+
+		_, err = record.(*mongoRecord).prepareSave(ctx, newSaveOptions([]SaveOption{WithScenario(publish)}))
+		assert.ErrorContains(t, err, "test_scenario_articles.body is required")
+
+		assert.NoError(t, record.SetValue(body, "..."))
+		_, err = record.(*mongoRecord).prepareSave(ctx, newSaveOptions([]SaveOption{WithScenario(publish)}))
+		assert.NoError(t, err)
+	})
+
+	t.Run("IsRequiredIn", func(t *testing.T) {
+		assert.True(t, IsRequiredIn(title))
+		assert.False(t, IsRequiredIn(body))
+		assert.False(t, IsRequiredIn(body, "draft"))
+		assert.True(t, IsRequiredIn(body, "draft", publish))
+	})
+}
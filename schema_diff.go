@@ -0,0 +1,366 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SchemaChangeKind classifies a SchemaChange.
+type SchemaChangeKind string
+
+const (
+	FieldAdded   SchemaChangeKind = "field_added"
+	FieldRemoved SchemaChangeKind = "field_removed"
+	// FieldRenamed is a field replaced by one listing its name in FieldAlias.
+	FieldRenamed           SchemaChangeKind = "field_renamed"
+	FieldRetyped           SchemaChangeKind = "field_retyped"
+	FieldConstraintChanged SchemaChangeKind = "constraint_changed"
+	RefTargetChanged       SchemaChangeKind = "ref_target_changed"
+)
+
+const aliasRemovedDetail = "alias removed, values stored under it are no longer read"
+
+// SchemaChange is a single difference between two versions of a schema.
+type SchemaChange struct {
+	Kind SchemaChangeKind
+	// Field is the field name in the new schema, or in the old one for
+	// removed fields. Composite parts are named "<field>.<part>".
+	Field string
+	// Old and New describe the changed property, e.g. the field type names.
+	Old string
+	New string
+	// Breaking changes can fail to read stored documents, reject writes that
+	// used to succeed or break clients of generated formats.
+	Breaking bool
+	Detail   string
+}
+
+func (c SchemaChange) String() string {
+	var b strings.Builder
+	if c.Breaking {
+		b.WriteString("BREAKING ")
+	}
+	fmt.Fprintf(&b, "%s %s", c.Kind, c.Field)
+	switch {
+	case c.Old != "" && c.New != "":
+		fmt.Fprintf(&b, ": %s -> %s", c.Old, c.New)
+	case c.Old != "":
+		fmt.Fprintf(&b, ": %s", c.Old)
+	case c.New != "":
+		fmt.Fprintf(&b, ": %s", c.New)
+	}
+	if c.Detail != "" {
+		fmt.Fprintf(&b, " (%s)", c.Detail)
+	}
+	return b.String()
+}
+
+// SchemaDiff lists the changes from one version of a schema to the next.
+type SchemaDiff struct {
+	Schema  string
+	Changes []SchemaChange
+}
+
+// IsBreaking reports whether any change is breaking.
+func (d SchemaDiff) IsBreaking() bool {
+	return slices.ContainsFunc(d.Changes, func(c SchemaChange) bool { return c.Breaking })
+}
+
+// Breaking returns the breaking changes.
+func (d SchemaDiff) Breaking() []SchemaChange {
+	var changes []SchemaChange
+	for _, change := range d.Changes {
+		if change.Breaking {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// String lists the changes one per line, e.g. for CI output.
+func (d SchemaDiff) String() string {
+	var b strings.Builder
+	for _, change := range d.Changes {
+		b.WriteString(d.Schema + ": " + change.String() + "\n")
+	}
+	return b.String()
+}
+
+// DiffSchemas compares two versions of a schema. Fields are matched by name,
+// or through FieldAlias when a field was renamed.
+func DiffSchemas(old, new JSchema) SchemaDiff {
+	ctx := context.Background()
+	diff := SchemaDiff{Schema: new.Name()}
+	matched := make(map[string]bool)
+
+	for _, oldField := range old.Fields() {
+		newField, renamed := matchField(new, oldField.Name())
+		if newField == nil {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:     FieldRemoved,
+				Field:    oldField.Name(),
+				Old:      FieldTypeName(oldField.Type()),
+				Breaking: true,
+				Detail:   "stored values are no longer read",
+			})
+			continue
+		}
+
+		matched[newField.Name()] = true
+		if renamed {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:   FieldRenamed,
+				Field:  newField.Name(),
+				Old:    oldField.Name(),
+				New:    newField.Name(),
+				Detail: "stored values are read through the alias",
+			})
+		}
+		diff.Changes = append(diff.Changes, diffFields(ctx, oldField, newField)...)
+	}
+
+	for _, newField := range new.Fields() {
+		if matched[newField.Name()] {
+			continue
+		}
+		diff.Changes = append(diff.Changes, SchemaChange{
+			Kind:  FieldAdded,
+			Field: newField.Name(),
+			New:   FieldTypeName(newField.Type()),
+		})
+	}
+
+	return diff
+}
+
+// matchField finds the field of schema with the name, or one renamed from it.
+func matchField(schema JSchema, name string) (JField, bool) {
+	if field, ok := schema.Field(name); ok {
+		return field, false
+	}
+	for _, field := range schema.Fields() {
+		if slices.Contains(FieldAliases(field), name) {
+			return field, true
+		}
+	}
+	return nil, false
+}
+
+func diffFields(ctx context.Context, old, new JField) []SchemaChange {
+	var changes []SchemaChange
+	name := new.Name()
+
+	oldType, newType := FieldTypeName(old.Type()), FieldTypeName(new.Type())
+	if oldType != newType {
+		changes = append(changes, SchemaChange{
+			Kind:     FieldRetyped,
+			Field:    name,
+			Old:      oldType,
+			New:      newType,
+			Breaking: !widensToString(old.Type(), new.Type()),
+		})
+	} else {
+		changes = append(changes, diffTypes(ctx, name, old.Type(), new.Type())...)
+	}
+
+	oldRef, oldIsRef := old.(JRef)
+	newRef, newIsRef := new.(JRef)
+	if oldIsRef && newIsRef && schemaName(oldRef.RelSchema()) != schemaName(newRef.RelSchema()) {
+		changes = append(changes, SchemaChange{
+			Kind:     RefTargetChanged,
+			Field:    name,
+			Old:      schemaName(oldRef.RelSchema()),
+			New:      schemaName(newRef.RelSchema()),
+			Breaking: true,
+		})
+	}
+
+	if oldImmutable, newImmutable := IsImmutable(old), IsImmutable(new); oldImmutable != newImmutable {
+		changes = append(changes, SchemaChange{
+			Kind:     FieldConstraintChanged,
+			Field:    name,
+			Old:      fmt.Sprintf("immutable=%t", oldImmutable),
+			New:      fmt.Sprintf("immutable=%t", newImmutable),
+			Breaking: newImmutable,
+			Detail:   "immutable",
+		})
+	}
+
+	if oldRequired, newRequired := IsRequired(old), IsRequired(new); oldRequired != newRequired {
+		changes = append(changes, SchemaChange{
+			Kind:     FieldConstraintChanged,
+			Field:    name,
+			Old:      fmt.Sprintf("required=%t", oldRequired),
+			New:      fmt.Sprintf("required=%t", newRequired),
+			Breaking: newRequired,
+			Detail:   "required",
+		})
+	}
+
+	if oldUnique, newUnique := IsUnique(old), IsUnique(new); oldUnique != newUnique {
+		changes = append(changes, SchemaChange{
+			Kind:     FieldConstraintChanged,
+			Field:    name,
+			Old:      fmt.Sprintf("unique=%t", oldUnique),
+			New:      fmt.Sprintf("unique=%t", newUnique),
+			Breaking: newUnique,
+			Detail:   "unique",
+		})
+	}
+
+	if oldDefault, newDefault := describeDefault(old.Default()), describeDefault(new.Default()); oldDefault != newDefault {
+		changes = append(changes, SchemaChange{
+			Kind:   FieldConstraintChanged,
+			Field:  name,
+			Old:    oldDefault,
+			New:    newDefault,
+			Detail: "default",
+		})
+	}
+
+	newAliases := append(slices.Clone(FieldAliases(new)), name)
+	for _, alias := range FieldAliases(old) {
+		if !slices.Contains(newAliases, alias) {
+			changes = append(changes, SchemaChange{
+				Kind:     FieldConstraintChanged,
+				Field:    name,
+				Old:      alias,
+				Breaking: true,
+				Detail:   aliasRemovedDetail,
+			})
+		}
+	}
+
+	oldNumber, newNumber := fieldProtoNumber(old), fieldProtoNumber(new)
+	if oldNumber != 0 && newNumber != 0 && oldNumber != newNumber {
+		changes = append(changes, SchemaChange{
+			Kind:     FieldConstraintChanged,
+			Field:    name,
+			Old:      fmt.Sprint(oldNumber),
+			New:      fmt.Sprint(newNumber),
+			Breaking: true,
+			Detail:   "protobuf field number",
+		})
+	}
+
+	return changes
+}
+
+// diffTypes compares the configuration of two field types of the same kind.
+func diffTypes(ctx context.Context, name string, old, new JFieldType) []SchemaChange {
+	var changes []SchemaChange
+
+	switch oldType := old.(type) {
+	case *Composite:
+		newType := new.(*Composite)
+		for _, oldPart := range oldType.Parts {
+			partName := name + "." + oldPart.Name
+			i := slices.IndexFunc(newType.Parts, func(p CompositePart) bool { return p.Name == oldPart.Name })
+			if i < 0 {
+				changes = append(changes, SchemaChange{
+					Kind:     FieldRemoved,
+					Field:    partName,
+					Old:      FieldTypeName(oldPart.Type),
+					Breaking: true,
+					Detail:   "stored values are no longer read",
+				})
+				continue
+			}
+
+			newPart := newType.Parts[i]
+			oldPartType, newPartType := FieldTypeName(oldPart.Type), FieldTypeName(newPart.Type)
+			if oldPartType != newPartType {
+				changes = append(changes, SchemaChange{
+					Kind:     FieldRetyped,
+					Field:    partName,
+					Old:      oldPartType,
+					New:      newPartType,
+					Breaking: !widensToString(oldPart.Type, newPart.Type),
+				})
+			}
+		}
+		for _, newPart := range newType.Parts {
+			if !slices.ContainsFunc(oldType.Parts, func(p CompositePart) bool { return p.Name == newPart.Name }) {
+				changes = append(changes, SchemaChange{
+					Kind:  FieldAdded,
+					Field: name + "." + newPart.Name,
+					New:   FieldTypeName(newPart.Type),
+				})
+			}
+		}
+
+	case *Options:
+		oldOptions, oldErr := oldType.GetAllOptions(ctx)
+		newOptions, newErr := new.(*Options).GetAllOptions(ctx)
+		if oldErr != nil || newErr != nil {
+			break
+		}
+		for _, option := range oldOptions {
+			if !slices.ContainsFunc(newOptions, func(o Option) bool { return o.UniqueName == option.UniqueName }) {
+				changes = append(changes, SchemaChange{
+					Kind:     FieldConstraintChanged,
+					Field:    name,
+					Old:      option.UniqueName,
+					Breaking: true,
+					Detail:   "option removed",
+				})
+			}
+		}
+		for _, option := range newOptions {
+			if !slices.ContainsFunc(oldOptions, func(o Option) bool { return o.UniqueName == option.UniqueName }) {
+				changes = append(changes, SchemaChange{
+					Kind:   FieldConstraintChanged,
+					Field:  name,
+					New:    option.UniqueName,
+					Detail: "option added",
+				})
+			}
+		}
+
+	case *DependentOptions:
+		if oldType.DependsOn() != new.(*DependentOptions).DependsOn() {
+			changes = append(changes, SchemaChange{
+				Kind:     FieldConstraintChanged,
+				Field:    name,
+				Old:      oldType.DependsOn(),
+				New:      new.(*DependentOptions).DependsOn(),
+				Breaking: true,
+				Detail:   "depends on",
+			})
+		}
+	}
+
+	return changes
+}
+
+// widensToString reports whether values of the old type are still valid
+// strings, e.g. an enum relaxed to free text.
+func widensToString(old, new JFieldType) bool {
+	// Text reads plain strings, but String can't read compressed text
+	switch baseType(new).(type) {
+	case *String, *Text:
+	default:
+		return false
+	}
+	switch baseType(old).(type) {
+	case *String, *Options, *DependentOptions:
+		return true
+	}
+	return false
+}
+
+func fieldProtoNumber(field JField) int {
+	if f, ok := field.(interface{ ProtoNumber() int }); ok {
+		return f.ProtoNumber()
+	}
+	return 0
+}
+
+func schemaName(schema JSchema) string {
+	if schema == nil {
+		return ""
+	}
+	return schema.Name()
+}
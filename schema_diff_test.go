@@ -0,0 +1,71 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	users := NewSchema("users").Field("id", &String{}).Build()
+	accounts := NewSchema("accounts").Field("id", &String{}).Build()
+	statuses := NewInMemoryOptionService([]Option{{UniqueName: "open"}, {UniqueName: "closed"}})
+	money := NewComposite(CompositePart{Name: "amount", Type: &Number{}}, CompositePart{Name: "currency", Type: &String{}})
+
+	old := NewSchema("orders").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Field("status", NewOptions(statuses)).
+		Field("kind", NewOptions(statuses)).
+		Field("qty", &Number{}).
+		Field("notes", &String{}, FieldAlias("comment")).
+		Field("total", money).
+		FieldWithDefault("priority", &Number{}, 1).
+		Field("legacy", &String{}).
+		Ref("owner", users).
+		Build()
+
+	newStatuses := NewInMemoryOptionService([]Option{{UniqueName: "open"}, {UniqueName: "archived"}})
+	newMoney := NewComposite(CompositePart{Name: "amount", Type: &String{}}, CompositePart{Name: "scale", Type: &Number{}})
+	new := NewSchema("orders").
+		Field("id", &String{}).
+		Field("name", &String{}, FieldAlias("title")).
+		Field("status", NewOptions(newStatuses)).
+		Field("kind", &String{}).
+		Field("qty", &String{}, Immutable()).
+		Field("notes", &String{}).
+		Field("total", newMoney).
+		FieldWithDefault("priority", &Number{}, 2).
+		Ref("owner", accounts).
+		Field("created_at", &DateTime{}).
+		Build()
+
+	diff := DiffSchemas(old, new)
+
+	assert.Equal(t, []SchemaChange{
+		{Kind: FieldRenamed, Field: "name", Old: "title", New: "name", Detail: "stored values are read through the alias"},
+		{Kind: FieldConstraintChanged, Field: "status", Old: "closed", Breaking: true, Detail: "option removed"},
+		{Kind: FieldConstraintChanged, Field: "status", New: "archived", Detail: "option added"},
+		{Kind: FieldRetyped, Field: "kind", Old: "options", New: "string"},
+		{Kind: FieldRetyped, Field: "qty", Old: "number", New: "string", Breaking: true},
+		{Kind: FieldConstraintChanged, Field: "qty", Old: "immutable=false", New: "immutable=true", Breaking: true, Detail: "immutable"},
+		{Kind: FieldConstraintChanged, Field: "notes", Old: "comment", Breaking: true, Detail: "alias removed, values stored under it are no longer read"},
+		{Kind: FieldRetyped, Field: "total.amount", Old: "number", New: "string", Breaking: true},
+		{Kind: FieldRemoved, Field: "total.currency", Old: "string", Breaking: true, Detail: "stored values are no longer read"},
+		{Kind: FieldAdded, Field: "total.scale", New: "number"},
+		{Kind: FieldConstraintChanged, Field: "priority", Old: "1", New: "2", Detail: "default"},
+		{Kind: FieldRemoved, Field: "legacy", Old: "string", Breaking: true, Detail: "stored values are no longer read"},
+		{Kind: RefTargetChanged, Field: "owner", Old: "users", New: "accounts", Breaking: true},
+		{Kind: FieldAdded, Field: "created_at", New: "datetime"},
+	}, diff.Changes)
+
+	assert.True(t, diff.IsBreaking())
+	assert.Len(t, diff.Breaking(), 8)
+	assert.Contains(t, diff.String(), "orders: BREAKING field_removed legacy: string (stored values are no longer read)\n")
+
+	t.Run("identical schemas", func(t *testing.T) {
+		diff := DiffSchemas(old, old)
+		assert.Empty(t, diff.Changes)
+		assert.False(t, diff.IsBreaking())
+	})
+}
@@ -0,0 +1,124 @@
+package jpack
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrSchemaFrozen is matched by every FrozenSchemaError.
+var ErrSchemaFrozen = errors.New("schema is frozen")
+
+// FrozenSchemaError is the error of changes to a frozen schema, returned by
+// TryAddField, TryAddEdge and SchemaBuilder.TryBuild. AddField, AddEdge and
+// Build panic with it.
+type FrozenSchemaError struct {
+	Schema string
+	// Change describes the rejected change, e.g. "add field email".
+	Change string
+}
+
+func (e *FrozenSchemaError) Error() string {
+	return fmt.Sprintf("jpack: schema %s is frozen, cannot %s", e.Schema, e.Change)
+}
+
+// Is implements errors.Is support for ErrSchemaFrozen.
+func (e *FrozenSchemaError) Is(target error) bool {
+	return target == ErrSchemaFrozen
+}
+
+// Freeze makes schemas immutable, once they are fully wired up, e.g. after
+// edges between them were added with AddEdge. Records and queries can then
+// rely on the fields not changing: TryAddField, TryAddEdge and another
+// TryBuild of the schema's builder return a *FrozenSchemaError, and AddField,
+// AddEdge and Build panic with it. Derive changed schemas with CloneBuilder
+// instead.
+func Freeze(schemas ...JSchema) {
+	for _, schema := range schemas {
+		if s, ok := schema.(interface{ Freeze() }); ok {
+			s.Freeze()
+		}
+	}
+}
+
+// IsFrozen reports whether a schema was frozen.
+func IsFrozen(schema JSchema) bool {
+	s, ok := schema.(interface{ Frozen() bool })
+	return ok && s.Frozen()
+}
+
+// Freeze makes the schema immutable.
+func (s *schemaImpl) Freeze() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frozen = true
+}
+
+// Frozen reports whether the schema was frozen.
+func (s *schemaImpl) Frozen() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.frozen
+}
+
+// TryAddField adds a field to a built schema like JSchema.AddField, and
+// returns a *FrozenSchemaError instead of panicking when the schema is
+// frozen.
+func TryAddField(schema JSchema, field JField) error {
+	if s, ok := schema.(interface{ tryAddField(JField) error }); ok {
+		return s.tryAddField(field)
+	}
+	schema.AddField(field)
+	return nil
+}
+
+// TryAddEdge adds an edge to a built schema like JSchema.AddEdge, and returns
+// a *FrozenSchemaError instead of panicking when the schema is frozen.
+func TryAddEdge(schema JSchema, edge JEdge) error {
+	if s, ok := schema.(interface{ tryAddEdge(JEdge) error }); ok {
+		return s.tryAddEdge(edge)
+	}
+	schema.AddEdge(edge)
+	return nil
+}
+
+// checkMutable returns a *FrozenSchemaError for the change when the schema is
+// frozen. The caller holds s.mu.
+func (s *schemaImpl) checkMutable(change string) error {
+	if s.frozen {
+		return &FrozenSchemaError{Schema: s.name, Change: change}
+	}
+	return nil
+}
+
+// CloneBuilder returns a builder starting from the fields, edges, options and
+// OnNewRecord hooks of a schema, to derive a new schema from a frozen one. The copied fields
+// belong to the new schema; refs of the schema to itself, e.g. a ParentRef,
+// point to the new schema.
+func CloneBuilder(schema JSchema) *SchemaBuilder {
+	builder := NewSchema(schema.Name())
+	if s, ok := schema.(*schemaImpl); ok {
+		builder.schema = &schemaImpl{
+			name:             s.name,
+			conversionPolicy: s.conversionPolicy,
+			serializers:      slices.Clone(s.serializers),
+			policies:         slices.Clone(s.policies),
+			capped:           s.capped,
+			timeSeries:       s.timeSeries,
+			parentRef:        s.parentRef,
+			unknownFields:    s.unknownFields,
+			store:            s.store,
+			recordHooks:      slices.Clone(s.recordHooks),
+		}
+	}
+
+	for _, field := range schema.Fields() {
+		field = rebindField(field, builder.schema)
+		if ref, ok := field.(*refImpl); ok && ref.relSchema == schema {
+			ref.relSchema = builder.schema
+		}
+		builder.appendFieldIfNotPresent(field)
+	}
+	builder.edges = slices.Clone(schema.Edge())
+	return builder
+}
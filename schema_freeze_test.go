@@ -0,0 +1,124 @@
+package jpack
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	users := NewSchema("test_freeze_users").Field("id", &String{}).Build()
+	builder := NewSchema("test_freeze_posts").
+		Field("id", &String{}).
+		Ref("author", users).
+		ParentRef("parent").
+		Policy(&tenantPolicy{})
+	posts := builder.Build()
+	author := mustField(t, posts, "author").(JRef)
+
+	// Edges can still be wired up before freezing
+	users.AddEdge(NewEdge("posts", posts, author))
+	Freeze(users, posts)
+	assert.True(t, IsFrozen(posts))
+
+	assertFrozen := func(change string, mutate func()) {
+		t.Helper()
+		defer func() {
+			err, _ := recover().(error)
+			assert.True(t, errors.Is(err, ErrSchemaFrozen), change)
+			assert.EqualError(t, err, "jpack: schema test_freeze_posts is frozen, cannot "+change)
+		}()
+		mutate()
+	}
+	assertFrozen("add field title", func() { posts.AddField(&fieldImpl{name: "title", fType: &String{}}) })
+	assertFrozen("add edge comments", func() { posts.AddEdge(NewEdge("comments", users, author)) })
+	assertFrozen("rebuild", func() { builder.Field("title", &String{}).Build() })
+
+	t.Run("changes return errors", func(t *testing.T) {
+		var frozen *FrozenSchemaError
+		err := TryAddField(posts, &fieldImpl{name: "title", fType: &String{}})
+		assert.True(t, errors.As(err, &frozen))
+		assert.Equal(t, &FrozenSchemaError{Schema: "test_freeze_posts", Change: "add field title"}, frozen)
+
+		err = TryAddEdge(posts, NewEdge("comments", users, author))
+		assert.True(t, errors.As(err, &frozen))
+		assert.Equal(t, "add edge comments", frozen.Change)
+
+		schema, err := builder.Field("title", &String{}).TryBuild()
+		assert.Nil(t, schema)
+		assert.True(t, errors.As(err, &frozen))
+		assert.Equal(t, "rebuild", frozen.Change)
+		assert.Len(t, posts.Fields(), 3)
+
+		// Existing names are no change
+		assert.NoError(t, TryAddField(posts, mustField(t, posts, "id")))
+
+		mutable := NewSchema("test_freeze_mutable").Field("id", &String{}).Build()
+		assert.NoError(t, TryAddField(mutable, &fieldImpl{name: "title", fType: &String{}, schema: mutable}))
+		assert.NoError(t, TryAddEdge(mutable, NewEdge("posts", posts, author)))
+		assert.Len(t, mutable.Fields(), 2)
+		assert.Len(t, mutable.Edge(), 1)
+	})
+
+	t.Run("clones are mutable", func(t *testing.T) {
+		derived := CloneBuilder(posts).Field("title", &String{}).Build()
+
+		assert.False(t, IsFrozen(derived))
+		assert.Equal(t, []string{"id", "author", "parent", "title"}, fieldNames(derived))
+		assert.Len(t, posts.Fields(), 3, "the frozen schema is unchanged")
+		assert.Len(t, PoliciesOf(derived), 1)
+
+		for _, field := range derived.Fields() {
+			assert.Same(t, derived, field.Schema())
+		}
+		parent, ok := ParentRefOf(derived)
+		assert.True(t, ok)
+		assert.Same(t, derived, parent.RelSchema())
+		assert.Same(t, users, mustField(t, derived, "author").(JRef).RelSchema())
+
+		derived.AddEdge(NewEdge("comments", users, author))
+		assert.Len(t, derived.Edge(), 1)
+		assert.Empty(t, posts.Edge())
+	})
+}
+
+func fieldNames(schema JSchema) []string {
+	var names []string
+	for _, field := range schema.Fields() {
+		names = append(names, field.Name())
+	}
+	return names
+}
+
+func TestSchemaConcurrentMutation(t *testing.T) {
+	schema := NewSchema("test_concurrent").Field("id", &String{}).Build()
+	assert.Equal(t, uint64(1), SchemaVersion(schema))
+
+	fields := schema.Fields()
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			schema.AddField(&fieldImpl{name: fmt.Sprintf("f%d", i), fType: &String{}, schema: schema})
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = schema.Field("id")
+			_ = SchemaVersion(schema)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, schema.Fields(), 51)
+	assert.Len(t, fields, 1, "handed out field lists don't change")
+	assert.Equal(t, uint64(51), SchemaVersion(schema))
+
+	schema.AddField(&fieldImpl{name: "id", fType: &String{}})
+	assert.Equal(t, uint64(51), SchemaVersion(schema), "ignored additions keep the version")
+	schema.AddEdge(NewEdge("self", schema, nil))
+	assert.Equal(t, uint64(52), SchemaVersion(schema))
+}
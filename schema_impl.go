@@ -1,42 +1,140 @@
 package jpack
 
+import (
+	"slices"
+	"sync"
+)
+
 type schemaImpl struct {
-	name   string
-	fields []JField
-	edges  []JEdge
+	name string
+
+	// mu guards fields, edges, version and frozen. The field and edge lists
+	// are copied on write, so the slices handed out stay unchanged.
+	mu      sync.RWMutex
+	fields  []JField
+	edges   []JEdge
+	version uint64
+
+	conversionPolicy *ConversionPolicy
+	serializers      []RecordSerializer
+	policies         []JPolicy
+
+	capped     *CappedOptions
+	timeSeries *TimeSeriesOptions
+
+	// parentRef names the ParentRef field of a tree schema
+	parentRef string
+
+	unknownFields UnknownFieldPolicy
+
+	// store names the store the schema is bound to
+	store string
+
+	// recordHooks run on the records created with NewRecord
+	recordHooks []RecordHook
+
+	frozen bool
+}
+
+// Policies returns the access policies attached to the schema.
+func (s *schemaImpl) Policies() []JPolicy {
+	return s.policies
+}
+
+// Serializers returns the record serializers registered on the schema.
+func (s *schemaImpl) Serializers() []RecordSerializer {
+	return s.serializers
+}
+
+// ConversionPolicy returns the schema's own conversion policy, if one was set.
+func (s *schemaImpl) ConversionPolicy() (ConversionPolicy, bool) {
+	if s.conversionPolicy == nil {
+		return ConversionPolicy{}, false
+	}
+	return *s.conversionPolicy, true
 }
 
-// AddEdge implements JSchema.
+// Capped returns the schema's capped collection options, if set.
+func (s *schemaImpl) Capped() (CappedOptions, bool) {
+	if s.capped == nil {
+		return CappedOptions{}, false
+	}
+	return *s.capped, true
+}
+
+// TimeSeries returns the schema's time-series collection options, if set.
+func (s *schemaImpl) TimeSeries() (TimeSeriesOptions, bool) {
+	if s.timeSeries == nil {
+		return TimeSeriesOptions{}, false
+	}
+	return *s.timeSeries, true
+}
+
+// AddEdge implements JSchema. It is safe to call while the schema is in use,
+// and panics with a *FrozenSchemaError when the schema is frozen; see
+// TryAddEdge.
 func (s *schemaImpl) AddEdge(edge JEdge) JSchema {
+	if err := s.tryAddEdge(edge); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// tryAddEdge adds the edge unless one with its name exists, or returns a
+// *FrozenSchemaError when the schema is frozen.
+func (s *schemaImpl) tryAddEdge(edge JEdge) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for _, e := range s.edges {
 		if edge.Name() == e.Name() {
-			// If a field with the same name already exists, return the schema builder
-			// without adding a new field.
-			return s
+			// An edge with the same name is kept as it is
+			return nil
 		}
 	}
 
-	s.edges = append(s.edges, edge)
-	return s
+	if err := s.checkMutable("add edge " + edge.Name()); err != nil {
+		return err
+	}
+	s.edges = append(slices.Clip(s.edges), edge)
+	s.version++
+	return nil
 }
 
-// AddField implements JSchema.
+// AddField implements JSchema. It is safe to call while the schema is in use,
+// and panics with a *FrozenSchemaError when the schema is frozen; see
+// TryAddField.
 func (s *schemaImpl) AddField(field JField) JSchema {
+	if err := s.tryAddField(field); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// tryAddField adds the field unless one with its name exists, or returns a
+// *FrozenSchemaError when the schema is frozen.
+func (s *schemaImpl) tryAddField(field JField) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	for _, f := range s.fields {
 		if field.Name() == f.Name() {
-			// If a field with the same name already exists, return the schema builder
-			// without adding a new field.
-			return s
+			// A field with the same name is kept as it is
+			return nil
 		}
 	}
 
-	s.fields = append(s.fields, field)
-	return s
+	if err := s.checkMutable("add field " + field.Name()); err != nil {
+		return err
+	}
+	s.fields = append(slices.Clip(s.fields), field)
+	s.version++
+	return nil
 }
 
 // Field implements JSchema.
 func (s *schemaImpl) Field(name string) (JField, bool) {
-	for _, f := range s.fields {
+	for _, f := range s.Fields() {
 		if f.Name() == name {
 			return f, true
 		}
@@ -47,14 +145,25 @@ func (s *schemaImpl) Field(name string) (JField, bool) {
 
 // Edge implements JSchema.
 func (s *schemaImpl) Edge() []JEdge {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.edges
 }
 
 // Fields implements JSchema.
 func (s *schemaImpl) Fields() []JField {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.fields
 }
 
+// Version implements VersionedSchema.
+func (s *schemaImpl) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
 // Name implements JSchema.
 func (s *schemaImpl) Name() string {
 	return s.name
@@ -65,7 +174,7 @@ func (s *schemaImpl) Validate(JRecord) error {
 	panic("unimplemented")
 }
 
-var _ JSchema = &schemaImpl{}
+var _ VersionedSchema = &schemaImpl{}
 
 type edgeImpl struct {
 	name   string
@@ -73,6 +182,12 @@ type edgeImpl struct {
 	field  JRef
 }
 
+// NewEdge creates an edge to schema joined by ref, for JSchema.AddEdge on
+// schemas that are built before the ref exists, e.g. a user's posts.
+func NewEdge(name string, schema JSchema, ref JRef) JEdge {
+	return &edgeImpl{name: name, schema: schema, field: ref}
+}
+
 // Ref implements JEdge.
 func (e *edgeImpl) Ref() JRef {
 	return e.field
@@ -95,6 +210,70 @@ type fieldImpl struct {
 	fType        JFieldType
 	schema       JSchema
 	defaultValue any
+
+	immutable   bool
+	required    bool
+	requiredIn  []Scenario
+	validateIn  []Scenario
+	unique      bool
+	aliases     []string
+	protoNumber int
+	meta        map[string]any
+
+	sensitive         bool
+	sensitiveCategory string
+
+	scanFallback func(raw any) (any, error)
+}
+
+// Aliases returns the previous names of the field.
+func (f *fieldImpl) Aliases() []string {
+	return f.aliases
+}
+
+// Immutable reports whether the field rejects updates after creation.
+func (f *fieldImpl) Immutable() bool {
+	return f.immutable
+}
+
+// Required reports whether the field must have a value.
+func (f *fieldImpl) Required() bool {
+	return f.required
+}
+
+// RequiredIn returns the scenarios set with the RequiredIn option.
+func (f *fieldImpl) RequiredIn() []Scenario {
+	return f.requiredIn
+}
+
+// ValidateIn returns the scenarios set with the ValidateIn option.
+func (f *fieldImpl) ValidateIn() []Scenario {
+	return f.validateIn
+}
+
+// Unique reports whether the field's values must not repeat.
+func (f *fieldImpl) Unique() bool {
+	return f.unique
+}
+
+// Meta returns the metadata attached to the field.
+func (f *fieldImpl) Meta() map[string]any {
+	return f.meta
+}
+
+// ProtoNumber returns the protobuf field number set with the ProtoNumber option.
+func (f *fieldImpl) ProtoNumber() int {
+	return f.protoNumber
+}
+
+// ScanFallback returns the function set with the WithScanFallback option.
+func (f *fieldImpl) ScanFallback() func(raw any) (any, error) {
+	return f.scanFallback
+}
+
+// Sensitive returns the category set with the Sensitive option.
+func (f *fieldImpl) Sensitive() (string, bool) {
+	return f.sensitiveCategory, f.sensitive
 }
 
 // Default implements JField.
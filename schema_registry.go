@@ -0,0 +1,45 @@
+package jpack
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	schemasMu sync.RWMutex
+	schemas   = make(map[string]JSchema)
+)
+
+// RegisterSchema makes a schema known to tooling that works on every schema of
+// an application, such as documentation and API spec generators. Registering
+// a name again replaces the previous schema.
+func RegisterSchema(schema JSchema) JSchema {
+	schemasMu.Lock()
+	defer schemasMu.Unlock()
+
+	schemas[schema.Name()] = schema
+	return schema
+}
+
+// GetSchema retrieves a registered schema by name.
+func GetSchema(name string) (JSchema, bool) {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	schema, exists := schemas[name]
+	return schema, exists
+}
+
+// RegisteredSchemas returns all registered schemas sorted by name.
+func RegisteredSchemas() []JSchema {
+	schemasMu.RLock()
+	defer schemasMu.RUnlock()
+
+	result := make([]JSchema, 0, len(schemas))
+	for _, schema := range schemas {
+		result = append(result, schema)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
+
+	return result
+}
@@ -0,0 +1,417 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SearchBackend stores documents in a full-text search engine.
+type SearchBackend interface {
+	Index(ctx context.Context, index, id string, doc map[string]any) error
+	Delete(ctx context.Context, index, id string) error
+	Search(ctx context.Context, index string, req SearchRequest) (SearchHits, error)
+}
+
+// SearchRequest is a full-text query against the indexed fields.
+type SearchRequest struct {
+	Text   string
+	Fields []string
+	From   int
+	Size   int
+}
+
+// SearchHits are the ids of matching documents in relevance order.
+type SearchHits struct {
+	IDs   []string
+	Total int64
+}
+
+// SearchIndex maps a schema to a search index.
+type SearchIndex struct {
+	Schema  JSchema
+	Backend SearchBackend
+
+	// Name of the index, defaults to the schema name.
+	Name string
+	// Fields are indexed and searched; defaults to every String, Options and
+	// DependentOptions field except the primary key.
+	Fields []string
+	// SyncOnWrite indexes records on Save and removes them on Delete. Leave it
+	// off when indexes are kept in sync with RunSearchSync.
+	SyncOnWrite bool
+}
+
+var (
+	searchIndexesMu sync.RWMutex
+	searchIndexes   = make(map[string]SearchIndex)
+)
+
+// RegisterSearchIndex registers the search index of a schema, replacing any
+// previous one.
+func RegisterSearchIndex(index SearchIndex) {
+	if index.Name == "" {
+		index.Name = index.Schema.Name()
+	}
+	if len(index.Fields) == 0 {
+		pkField, hasPK := PK(index.Schema)
+		for _, field := range index.Schema.Fields() {
+			if hasPK && field.Name() == pkField.Name() {
+				continue
+			}
+			switch baseType(field.Type()).(type) {
+			case *String, *Text, *Options, *DependentOptions:
+				index.Fields = append(index.Fields, field.Name())
+			}
+		}
+	}
+
+	searchIndexesMu.Lock()
+	defer searchIndexesMu.Unlock()
+
+	searchIndexes[index.Schema.Name()] = index
+}
+
+// GetSearchIndex retrieves the search index registered for a schema.
+func GetSearchIndex(schema JSchema) (SearchIndex, bool) {
+	searchIndexesMu.RLock()
+	defer searchIndexesMu.RUnlock()
+
+	index, exists := searchIndexes[schema.Name()]
+	return index, exists
+}
+
+// document returns the indexed fields of a record.
+func (i SearchIndex) document(record JRecord) (map[string]any, error) {
+	doc := make(map[string]any, len(i.Fields))
+	for _, name := range i.Fields {
+		field, ok := i.Schema.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("jpack: search index %s: unknown field %s", i.Name, name)
+		}
+
+		value, ok := record.Value(field)
+		if !ok {
+			continue
+		}
+
+		value, err := normalizeValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		doc[name] = value
+	}
+	return doc, nil
+}
+
+// IndexRecord pushes a record to its schema's search index, if any.
+func IndexRecord(ctx context.Context, record JRecord) error {
+	index, ok := GetSearchIndex(record.Schema())
+	if !ok {
+		return nil
+	}
+
+	id, ok := recordID(record)
+	if !ok {
+		return errors.New("jpack: cannot index a record without an id")
+	}
+
+	doc, err := index.document(record)
+	if err != nil {
+		return err
+	}
+	return index.Backend.Index(ctx, index.Name, id, doc)
+}
+
+// UnindexRecord removes a record from its schema's search index, if any.
+func UnindexRecord(ctx context.Context, schema JSchema, id string) error {
+	index, ok := GetSearchIndex(schema)
+	if !ok {
+		return nil
+	}
+	return index.Backend.Delete(ctx, index.Name, id)
+}
+
+// syncSearchOnSave indexes a saved record when its index syncs on write. The
+// write already succeeded, so failures are logged rather than returned.
+// Writes of a transaction are indexed once it committed.
+func syncSearchOnSave(ctx context.Context, record JRecord) {
+	if index, ok := GetSearchIndex(record.Schema()); !ok || !index.SyncOnWrite {
+		return
+	}
+
+	synced := afterCommit(ctx, func(ctx context.Context) {
+		if err := IndexRecord(ctx, record); err != nil {
+			LoggerFrom(ctx).Error().Err(err).Str("schema", record.Schema().Name()).Msg("jpack: failed to index record")
+		}
+	})
+	if !synced {
+		LoggerFrom(ctx).Error().Str("schema", record.Schema().Name()).Msg("jpack: search index isn't synced for writes in a transaction not run by a UnitOfWork")
+	}
+}
+
+// syncSearchOnDelete removes a deleted record when its index syncs on write,
+// once the transaction of the delete committed.
+func syncSearchOnDelete(ctx context.Context, record JRecord) {
+	if index, ok := GetSearchIndex(record.Schema()); !ok || !index.SyncOnWrite {
+		return
+	}
+	id, ok := recordID(record)
+	if !ok {
+		return
+	}
+
+	synced := afterCommit(ctx, func(ctx context.Context) {
+		if err := UnindexRecord(ctx, record.Schema(), id); err != nil {
+			LoggerFrom(ctx).Error().Err(err).Str("schema", record.Schema().Name()).Msg("jpack: failed to remove record from search index")
+		}
+	})
+	if !synced {
+		LoggerFrom(ctx).Error().Str("schema", record.Schema().Name()).Msg("jpack: search index isn't synced for writes in a transaction not run by a UnitOfWork")
+	}
+}
+
+// RunSearchSync keeps the search indexes of the schemas in sync from their
+// change streams, checkpointing progress under name like CDCRunner.
+func RunSearchSync(ctx context.Context, name string, checkpoints CheckpointStore, schemas ...JSchema) error {
+	if checkpoints == nil {
+		checkpoints = &MongoCheckpointStore{}
+	}
+
+	token, err := checkpoints.Load(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return WatchChanges(ctx, schemas, token, func(event ChangeEvent) error {
+		if err := applySearchChange(ctx, event); err != nil {
+			return err
+		}
+		return checkpoints.Save(ctx, name, event.ResumeToken)
+	})
+}
+
+func applySearchChange(ctx context.Context, event ChangeEvent) error {
+	if event.Record == nil {
+		return UnindexRecord(ctx, event.Schema, event.ID)
+	}
+	return IndexRecord(ctx, event.Record)
+}
+
+// SearchQuery runs a full-text search and returns the matching records,
+// loaded from MongoDB in relevance order.
+type SearchQuery struct {
+	ctx    context.Context
+	schema JSchema
+	req    SearchRequest
+}
+
+// SearchResult is a page of records matching a SearchQuery.
+type SearchResult struct {
+	Records []JRecord
+	// Total is the number of matching documents reported by the index.
+	Total int64
+}
+
+// NewSearchQuery creates a search over the schema's registered index.
+func NewSearchQuery(ctx context.Context, schema JSchema, text string) *SearchQuery {
+	return &SearchQuery{ctx: ctx, schema: schema, req: SearchRequest{Text: text, Size: 10}}
+}
+
+// Fields restricts the search to some of the indexed fields.
+func (q *SearchQuery) Fields(fields ...JField) *SearchQuery {
+	q.req.Fields = nil
+	for _, field := range fields {
+		q.req.Fields = append(q.req.Fields, field.Name())
+	}
+	return q
+}
+
+// Limit sets the page size.
+func (q *SearchQuery) Limit(limit int) *SearchQuery {
+	q.req.Size = limit
+	return q
+}
+
+// Offset skips the first hits.
+func (q *SearchQuery) Offset(offset int) *SearchQuery {
+	q.req.From = offset
+	return q
+}
+
+// Execute searches the index and hydrates the hits. Hits whose record no
+// longer exists are skipped.
+func (q *SearchQuery) Execute() (SearchResult, error) {
+	index, ok := GetSearchIndex(q.schema)
+	if !ok {
+		return SearchResult{}, fmt.Errorf("jpack: no search index registered for %s", q.schema.Name())
+	}
+
+	req := q.req
+	if len(req.Fields) == 0 {
+		req.Fields = index.Fields
+	}
+
+	hits, err := index.Backend.Search(q.ctx, index.Name, req)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	records, err := q.hydrate(hits.IDs)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	return SearchResult{Records: records, Total: hits.Total}, nil
+}
+
+// hydrate loads the records with the ids in a single query, in ids order.
+func (q *SearchQuery) hydrate(ids []string) ([]JRecord, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	query := NewMongoQuery(q.ctx, q.schema).(*mongoQuery)
+	query.where = append(query.where, idsFilter(ids))
+
+	found, err := query.Execute()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]JRecord, len(found))
+	for _, record := range found {
+		if id, ok := recordID(record); ok {
+			byID[id] = record
+		}
+	}
+
+	records := make([]JRecord, 0, len(ids))
+	for _, id := range ids {
+		if record, ok := byID[id]; ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// ElasticsearchBackend is a SearchBackend for Elasticsearch and OpenSearch
+// using their REST API.
+type ElasticsearchBackend struct {
+	// URL of the cluster, e.g. http://localhost:9200
+	URL    string
+	Client *http.Client
+	// Header is added to every request, e.g. for authorization.
+	Header http.Header
+}
+
+// Index implements SearchBackend.
+func (b *ElasticsearchBackend) Index(ctx context.Context, index, id string, doc map[string]any) error {
+	return b.do(ctx, http.MethodPut, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), doc, nil)
+}
+
+// Delete implements SearchBackend. Deleting a missing document succeeds.
+func (b *ElasticsearchBackend) Delete(ctx context.Context, index, id string) error {
+	err := b.do(ctx, http.MethodDelete, "/"+url.PathEscape(index)+"/_doc/"+url.PathEscape(id), nil, nil)
+	var statusErr *searchStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Search implements SearchBackend.
+func (b *ElasticsearchBackend) Search(ctx context.Context, index string, req SearchRequest) (SearchHits, error) {
+	body := map[string]any{
+		"query": map[string]any{
+			"multi_match": map[string]any{
+				"query":  req.Text,
+				"fields": req.Fields,
+			},
+		},
+		"from":    req.From,
+		"size":    req.Size,
+		"_source": false,
+	}
+
+	var res struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := b.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_search", body, &res); err != nil {
+		return SearchHits{}, err
+	}
+
+	hits := SearchHits{Total: res.Hits.Total.Value}
+	for _, hit := range res.Hits.Hits {
+		hits.IDs = append(hits.IDs, hit.ID)
+	}
+	return hits, nil
+}
+
+type searchStatusError struct {
+	status int
+	body   string
+}
+
+func (e *searchStatusError) Error() string {
+	return fmt.Sprintf("jpack: search backend returned %d: %s", e.status, e.body)
+}
+
+func (b *ElasticsearchBackend) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(b.URL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range b.Header {
+		req.Header[key] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client := b.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return &searchStatusError{status: res.StatusCode, body: string(data)}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+var _ SearchBackend = &ElasticsearchBackend{}
@@ -0,0 +1,99 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type memorySearchBackend struct {
+	docs map[string]map[string]any
+}
+
+func (b *memorySearchBackend) Index(ctx context.Context, index, id string, doc map[string]any) error {
+	b.docs[index+"/"+id] = doc
+	return nil
+}
+
+func (b *memorySearchBackend) Delete(ctx context.Context, index, id string) error {
+	delete(b.docs, index+"/"+id)
+	return nil
+}
+
+func (b *memorySearchBackend) Search(ctx context.Context, index string, req SearchRequest) (SearchHits, error) {
+	return SearchHits{}, nil
+}
+
+func TestSearchIndexSync(t *testing.T) {
+	assert := assert.New(t)
+	ctx := context.Background()
+
+	schema := NewSchema("search_article").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Field("views", &Number{}).
+		Build()
+
+	backend := &memorySearchBackend{docs: map[string]map[string]any{}}
+	RegisterSearchIndex(SearchIndex{Schema: schema, Backend: backend})
+
+	index, ok := GetSearchIndex(schema)
+	assert.True(ok)
+	assert.Equal("search_article", index.Name)
+	assert.Equal([]string{"title"}, index.Fields)
+
+	id := bson.NewObjectID()
+	record, err := RecordFromBSON(schema, bson.M{"_id": id, "title": "Hello", "views": 3})
+	assert.NoError(err)
+
+	assert.NoError(applySearchChange(ctx, ChangeEvent{Schema: schema, Operation: ChangeInsert, ID: id.Hex(), Record: record}))
+	assert.Equal(map[string]any{"title": "Hello"}, backend.docs["search_article/"+id.Hex()])
+
+	assert.NoError(applySearchChange(ctx, ChangeEvent{Schema: schema, Operation: ChangeDelete, ID: id.Hex()}))
+	assert.Empty(backend.docs)
+}
+
+func TestElasticsearchBackend(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		assert.Equal(t, "ApiKey secret", r.Header.Get("Authorization"))
+
+		switch {
+		case r.Method == http.MethodPost:
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			match := body["query"].(map[string]any)["multi_match"].(map[string]any)
+			assert.Equal(t, "hello", match["query"])
+			assert.Equal(t, []any{"title"}, match["fields"])
+
+			w.Write([]byte(`{"hits":{"total":{"value":7},"hits":[{"_id":"b"},{"_id":"a"}]}}`))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	backend := &ElasticsearchBackend{URL: server.URL + "/", Header: http.Header{"Authorization": {"ApiKey secret"}}}
+
+	assert.NoError(t, backend.Index(ctx, "articles", "a", map[string]any{"title": "hello"}))
+	assert.NoError(t, backend.Delete(ctx, "articles", "missing"), "deleting a missing document succeeds")
+
+	hits, err := backend.Search(ctx, "articles", SearchRequest{Text: "hello", Fields: []string{"title"}, Size: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, SearchHits{IDs: []string{"b", "a"}, Total: 7}, hits)
+
+	assert.Equal(t, []string{
+		"PUT /articles/_doc/a",
+		"DELETE /articles/_doc/missing",
+		"POST /articles/_search",
+	}, requests)
+}
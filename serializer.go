@@ -0,0 +1,58 @@
+package jpack
+
+import (
+	"context"
+)
+
+// RecordSerializer transforms whole documents on their way to and from
+// storage, e.g. compressing a large text field or flattening a legacy
+// structure, without writing a dedicated field type.
+type RecordSerializer struct {
+	// Encode runs on the document built by the field types before it is
+	// written. Updates only carry the changed keys, so Encode must cope with
+	// partial documents.
+	Encode func(ctx context.Context, doc map[string]any) (map[string]any, error)
+
+	// Decode runs on a stored document before it is loaded into a record.
+	Decode func(ctx context.Context, doc map[string]any) (map[string]any, error)
+}
+
+// SerializersOf returns the serializers registered on the schema.
+func SerializersOf(schema JSchema) []RecordSerializer {
+	if s, ok := schema.(interface{ Serializers() []RecordSerializer }); ok {
+		return s.Serializers()
+	}
+	return nil
+}
+
+// encodeDocument applies the schema's Encode hooks in registration order.
+func encodeDocument(ctx context.Context, schema JSchema, doc map[string]any) (map[string]any, error) {
+	for _, serializer := range SerializersOf(schema) {
+		if serializer.Encode == nil {
+			continue
+		}
+
+		var err error
+		if doc, err = serializer.Encode(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// decodeDocument applies the schema's Decode hooks in reverse registration
+// order, so each hook sees the document as its own Encode produced it.
+func decodeDocument(ctx context.Context, schema JSchema, doc map[string]any) (map[string]any, error) {
+	serializers := SerializersOf(schema)
+	for i := len(serializers) - 1; i >= 0; i-- {
+		if serializers[i].Decode == nil {
+			continue
+		}
+
+		var err error
+		if doc, err = serializers[i].Decode(ctx, doc); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
@@ -0,0 +1,83 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestRecordSerializer(t *testing.T) {
+	ctx := context.Background()
+
+	upper := RecordSerializer{
+		Encode: func(ctx context.Context, doc map[string]any) (map[string]any, error) {
+			if body, ok := doc["body"].(string); ok {
+				doc["body"] = strings.ToUpper(body)
+			}
+			return doc, nil
+		},
+		Decode: func(ctx context.Context, doc map[string]any) (map[string]any, error) {
+			if body, ok := doc["body"].(string); ok {
+				doc["body"] = strings.ToLower(body)
+			}
+			return doc, nil
+		},
+	}
+
+	// Flattens {"meta": {"author": ...}} written by an older version
+	legacy := RecordSerializer{
+		Decode: func(ctx context.Context, doc map[string]any) (map[string]any, error) {
+			if meta, ok := doc["meta"].(bson.M); ok {
+				doc["author"] = meta["author"]
+				delete(doc, "meta")
+			}
+			return doc, nil
+		},
+	}
+
+	schema := NewSchema("test_serializer").
+		Field("id", &String{}).
+		Field("body", &String{}).
+		Field("author", &String{}).
+		Serializer(upper).
+		Serializer(legacy).
+		Build()
+
+	t.Run("encode on write", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(mustField(t, schema, "body"), "hello"))
+
+		doc, err := record.convertToBSON(ctx, record.record)
+		assert.NoError(t, err)
+		assert.Equal(t, "HELLO", doc["body"])
+	})
+
+	t.Run("decode on read", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		err := record.loadDocument(ctx, bson.M{"body": "HELLO", "meta": bson.M{"author": "john"}})
+		assert.NoError(t, err)
+
+		body, _ := record.Value(mustField(t, schema, "body"))
+		author, _ := record.Value(mustField(t, schema, "author"))
+		assert.Equal(t, "hello", body)
+		assert.Equal(t, "john", author)
+	})
+
+	t.Run("errors are returned", func(t *testing.T) {
+		failing := NewSchema("test_serializer_err").
+			Field("body", &String{}).
+			Serializer(RecordSerializer{
+				Decode: func(ctx context.Context, doc map[string]any) (map[string]any, error) {
+					return nil, errors.New("corrupt document")
+				},
+			}).
+			Build()
+
+		record := NewMongoRecord(failing)
+		assert.EqualError(t, record.loadDocument(ctx, bson.M{"body": "x"}), "corrupt document")
+	})
+}
@@ -0,0 +1,41 @@
+package jpack
+
+import (
+	"errors"
+	"maps"
+)
+
+// ErrStaleSnapshot is returned by RollbackTo for snapshots of another record,
+// or taken before the record was last saved.
+var ErrStaleSnapshot = errors.New("jpack: snapshot is of another record or predates its last save")
+
+// Snapshot is the in-memory state of a record, taken with JRecord.Snapshot.
+type Snapshot struct {
+	record      JRecord
+	saves       uint64
+	values      map[string]any
+	renamedKeys map[string]string
+}
+
+// Snapshot implements JRecord.
+func (m *mongoRecord) Snapshot() Snapshot {
+	return Snapshot{
+		record:      m,
+		saves:       m.saves,
+		values:      maps.Clone(m.record),
+		renamedKeys: maps.Clone(m.renamedKeys),
+	}
+}
+
+// RollbackTo implements JRecord. A snapshot can be rolled back to any number
+// of times.
+func (m *mongoRecord) RollbackTo(snapshot Snapshot) error {
+	if snapshot.record != JRecord(m) || snapshot.saves != m.saves {
+		return ErrStaleSnapshot
+	}
+
+	m.record = maps.Clone(snapshot.values)
+	m.renamedKeys = maps.Clone(snapshot.renamedKeys)
+	m.invalidateScanned()
+	return nil
+}
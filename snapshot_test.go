@@ -0,0 +1,54 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSnapshot(t *testing.T) {
+	schema := NewSchema("test_snapshot").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Field("age", &Number{}).
+		Build()
+	name, age := mustField(t, schema, "name"), mustField(t, schema, "age")
+
+	record := NewMongoRecord(schema)
+	assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Jane", "age": 30}))
+	assert.NoError(t, record.SetValue(name, "Janet"))
+
+	snapshot := record.Snapshot()
+	assert.NoError(t, record.SetValue(name, "J"))
+	assert.NoError(t, record.SetValue(age, 31))
+	assert.NoError(t, record.Unset(age))
+	scanned, _ := record.String(name)
+	assert.Equal(t, "J", scanned)
+
+	t.Run("rollback restores the changes", func(t *testing.T) {
+		assert.NoError(t, record.RollbackTo(snapshot))
+		assert.Equal(t, []string{"name"}, record.DirtyKeys())
+		value, _ := record.Value(name)
+		assert.Equal(t, "Janet", value)
+		scanned, _ := record.String(name)
+		assert.Equal(t, "Janet", scanned, "scanned values are not stale")
+		assert.True(t, record.IsSet(age))
+	})
+
+	t.Run("snapshots can be reused", func(t *testing.T) {
+		assert.NoError(t, record.SetValue(name, "Jo"))
+		assert.NoError(t, record.RollbackTo(snapshot))
+		value, _ := record.Value(name)
+		assert.Equal(t, "Janet", value)
+	})
+
+	t.Run("stale snapshots are rejected", func(t *testing.T) {
+		assert.ErrorIs(t, NewMongoRecord(schema).RollbackTo(snapshot), ErrStaleSnapshot)
+		assert.ErrorIs(t, record.RollbackTo(Snapshot{}), ErrStaleSnapshot)
+
+		w := &pendingWrite{record: record, op: ChangeUpdate}
+		assert.NoError(t, w.finish(t.Context()))
+		assert.ErrorIs(t, record.RollbackTo(snapshot), ErrStaleSnapshot)
+	})
+}
@@ -0,0 +1,668 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// sqliteKey is the context key holding a SQLiteStore set with WithSQLite.
+var sqliteKey key = "jpack.conn.sqlite"
+
+// SQLiteStore keeps the records of jpack schemas in an SQLite database, for
+// desktop and CLI tools that run without a database server. Each schema is a
+// table of JSON documents keyed by the record id:
+//
+//	CREATE TABLE "users" (id TEXT PRIMARY KEY, doc TEXT NOT NULL)
+//
+// Filters are rendered to json_extract predicates. The database is opened
+// with the SQLite driver of the application's choice, e.g.
+// modernc.org/sqlite or github.com/mattn/go-sqlite3. LIKE filters fail unless
+// the driver defines the regexp function and RegisterSQLiteRegexpFilters was
+// called.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore returns a store keeping records in db.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// DB returns the database of the store.
+func (s *SQLiteStore) DB() *sql.DB {
+	return s.db
+}
+
+// WithSQLite returns a context whose SQLite records and queries use store.
+func WithSQLite(ctx context.Context, store *SQLiteStore) context.Context {
+	return context.WithValue(ctx, sqliteKey, store)
+}
+
+// SQLiteFrom returns the SQLite store of the context, if any.
+func SQLiteFrom(ctx context.Context) (*SQLiteStore, bool) {
+	store, ok := ctx.Value(sqliteKey).(*SQLiteStore)
+	return store, ok && store != nil
+}
+
+// mustSQLite returns the SQLite store of the context, and panics when there
+// is none.
+func mustSQLite(ctx context.Context) *SQLiteStore {
+	store, ok := SQLiteFrom(ctx)
+	if !ok {
+		panic("jpack: SQLite store not found in context")
+	}
+	return store
+}
+
+// Migrate creates the tables of the schemas, with an index on the JSON path
+// of every unique and ref field. Existing tables and indexes are kept, so it
+// is safe to run on every start.
+func (s *SQLiteStore) Migrate(ctx context.Context, schemas ...JSchema) error {
+	for _, schema := range schemas {
+		if _, ok := schema.(*ViewSchema); ok {
+			continue
+		}
+		for _, stmt := range sqliteSchemaDDL(ctx, schema) {
+			if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("jpack: migrate %s: %w", schema.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// sqliteSchemaDDL returns the statements creating the table and indexes of
+// a schema.
+func sqliteSchemaDDL(ctx context.Context, schema JSchema) []string {
+	table := sqliteTable(ctx, schema)
+	stmts := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id TEXT PRIMARY KEY, doc TEXT NOT NULL)", sqliteIdent(table)),
+	}
+
+	pk, _ := PK(schema)
+	for _, field := range schema.Fields() {
+		if pk != nil && field.Name() == pk.Name() {
+			continue
+		}
+
+		kind := ""
+		if IsUnique(field) {
+			kind = "UNIQUE "
+		} else if _, ok := field.(JRef); !ok {
+			continue
+		}
+
+		for _, key := range storageKeys(field) {
+			index := sqliteIdent(table + "_" + key)
+			stmts = append(stmts, fmt.Sprintf("CREATE %sINDEX IF NOT EXISTS %s ON %s (%s)", kind, index, sqliteIdent(table), sqliteJSONPath(key)))
+		}
+	}
+	return stmts
+}
+
+// sqliteTable returns the table of the schema's records.
+func sqliteTable(ctx context.Context, schema JSchema) string {
+	return collectionName(ctx, storageSchema(schema))
+}
+
+// sqliteIdent quotes an SQL identifier.
+func sqliteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqliteJSONPath returns the expression extracting a key of the document.
+func sqliteJSONPath(key string) string {
+	return sqliteJSONCall("json_extract", key)
+}
+
+// sqliteJSONCall returns the call of a JSON function on a key of the
+// document. The path is inlined rather than bound, so that indexes on it are
+// used.
+func sqliteJSONCall(fn, key string) string {
+	path := `$."` + strings.ReplaceAll(key, `"`, `\"`) + `"`
+	return fn + "(doc, '" + strings.ReplaceAll(path, "'", "''") + "')"
+}
+
+// sqliteTimeLayout formats stored date-times: UTC with fixed millisecond
+// precision, so that they sort and compare as strings.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sqliteValue converts a stored value to its JSON form: date-times become
+// sortable strings, ObjectIDs hex strings and decimals strings.
+func sqliteValue(value any) any {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(sqliteTimeLayout)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return sqliteValue(*v)
+	case bson.DateTime:
+		return sqliteValue(v.Time())
+	case bson.ObjectID:
+		return v.Hex()
+	case bson.Decimal128:
+		return v.String()
+	case bson.D:
+		doc := make(map[string]any, len(v))
+		for _, e := range v {
+			doc[e.Key] = sqliteValue(e.Value)
+		}
+		return doc
+	case map[string]any:
+		doc := make(map[string]any, len(v))
+		for key, value := range v {
+			doc[key] = sqliteValue(value)
+		}
+		return doc
+	case bson.M:
+		return sqliteValue(map[string]any(v))
+	case bson.A:
+		return sqliteValue([]any(v))
+	case []any:
+		values := make([]any, len(v))
+		for i, value := range v {
+			values[i] = sqliteValue(value)
+		}
+		return values
+	}
+	return value
+}
+
+// encodeSQLiteDocument returns the JSON text stored for a document.
+func encodeSQLiteDocument(doc bson.M) (string, error) {
+	data, err := json.Marshal(sqliteValue(doc))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// decodeSQLiteDocument parses a stored row into a document like the ones
+// read from MongoDB: whole numbers are int64 and the id is the _id.
+func decodeSQLiteDocument(id, data string) (bson.M, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(data)))
+	decoder.UseNumber()
+
+	var doc map[string]any
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("jpack: invalid document %s: %w", id, err)
+	}
+
+	decoded, _ := sqliteNumbers(doc).(map[string]any)
+	if decoded == nil {
+		decoded = map[string]any{}
+	}
+	if objID, err := bson.ObjectIDFromHex(id); err == nil {
+		decoded[defaultMongoPK] = objID
+	} else {
+		decoded[defaultMongoPK] = id
+	}
+	return decoded, nil
+}
+
+// sqliteNumbers converts the JSON numbers of a decoded value.
+func sqliteNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = sqliteNumbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = sqliteNumbers(value)
+		}
+	}
+	return value
+}
+
+// sqliteExecutor runs statements on a database or in a transaction.
+type sqliteExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqliteRecord is a record stored by a SQLiteStore. It keeps its values like
+// a MongoDB record and only writes differently.
+type sqliteRecord struct {
+	*mongoRecord
+}
+
+var _ JRecord = &sqliteRecord{}
+
+// NewSQLiteRecord creates a record of the schema stored by the SQLiteStore of
+// the context it is saved with.
+func NewSQLiteRecord(schema JSchema) *sqliteRecord {
+	return &sqliteRecord{mongoRecord: NewMongoRecord(schema)}
+}
+
+// loadSQLiteRecord builds a record from a stored row.
+func loadSQLiteRecord(ctx context.Context, schema JSchema, id, data string) (*sqliteRecord, error) {
+	doc, err := decodeSQLiteDocument(id, data)
+	if err != nil {
+		return nil, err
+	}
+
+	record := NewSQLiteRecord(schema)
+	if _, ok := doc[defaultMongoPK].(string); ok {
+		if pk, ok := PK(schema); ok {
+			record.originalRecord[pk.Name()] = id
+		}
+		delete(doc, defaultMongoPK)
+	}
+	if err := record.loadDocument(ctx, doc); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Save implements JRecord. Server-time defaults are taken from the local
+// clock. Records of schemas with outbox hooks can't be saved.
+func (r *sqliteRecord) Save(ctx context.Context, opts ...SaveOption) error {
+	if r.mongoRoute(ctx) {
+		return r.mongoRecord.Save(ctx, opts...)
+	}
+	return r.save(ctx, mustSQLite(ctx).db, newSaveOptions(opts))
+}
+
+func (r *sqliteRecord) save(ctx context.Context, exec sqliteExecutor, saveOpts *saveOptions) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	if _, ok := r.schema.(*ViewSchema); ok {
+		return ErrViewReadOnly
+	}
+	if err := refuseOutbox(r.schema); err != nil {
+		return err
+	}
+
+	for _, policy := range PoliciesOf(r.schema) {
+		if err := policy.IsValid(ctx, r); err != nil {
+			return err
+		}
+	}
+	if err := validateRecordFields(ctx, r, saveOpts.scenarios...); err != nil {
+		return err
+	}
+	r.warnings = collectWarnings(ctx, r)
+
+	opCtx, cancel := operationContext(ctx)
+	defer cancel()
+
+	op := ChangeUpdate
+	if r.IsNew() {
+		op = ChangeInsert
+		if err := r.insert(ctx, opCtx, exec, saveOpts); err != nil {
+			return err
+		}
+	} else if written, err := r.update(ctx, opCtx, exec, saveOpts); err != nil || !written {
+		return err
+	}
+
+	r.saves++
+	r.observers = nil
+	r.afterWrite(ctx, op)
+
+	if saveOpts.returnDocument {
+		return r.refresh(ctx, exec)
+	}
+	return nil
+}
+
+// insert writes a new record.
+func (r *sqliteRecord) insert(ctx, opCtx context.Context, exec sqliteExecutor, saveOpts *saveOptions) error {
+	if saveOpts.ifMatch != nil {
+		return ErrPreconditionFailed
+	}
+	if saveOpts.idempotencyKey != "" {
+		return errors.New("jpack: SQLite stores don't support idempotency keys")
+	}
+
+	serverDefaults, err := r.applyDefaults(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, field := range serverDefaults {
+		r.record[field.Name()] = now
+	}
+
+	for _, field := range r.Schema().Fields() {
+		if err := r.checkRequired(field, r.record[field.Name()], saveOpts.scenarios...); err != nil {
+			return err
+		}
+	}
+
+	doc, err := r.convertToBSON(ctx, r.record)
+	if err != nil {
+		return err
+	}
+
+	pkField, _ := PK(r.schema)
+	id := bson.NewObjectID().Hex()
+	if hex, ok := r.record[pkField.Name()].(string); ok {
+		if _, err := bson.ObjectIDFromHex(hex); err == nil {
+			id = hex
+		}
+	}
+	delete(doc, pkField.Name())
+	delete(doc, defaultMongoPK)
+
+	data, err := encodeSQLiteDocument(doc)
+	if err != nil {
+		return err
+	}
+
+	table := sqliteTable(ctx, r.schema)
+	stmt := fmt.Sprintf("INSERT INTO %s (id, doc) VALUES (?, ?)", sqliteIdent(table))
+	start := time.Now()
+	_, err = exec.ExecContext(opCtx, stmt, id, data)
+	tapQuery(ctx, sqliteOperation(table, "insert", stmt, []any{id}, 1, err), start)
+	if err != nil {
+		return err
+	}
+
+	r.record[pkField.Name()] = id
+	r.originalRecord = r.record
+	r.record = bson.M{}
+	r.invalidateScanned()
+
+	if identityMap, ok := IdentityMapFrom(ctx); ok {
+		identityMap.Track(r)
+	}
+	return nil
+}
+
+// update rewrites the stored document of a loaded record, and reports
+// whether there was anything to write.
+func (r *sqliteRecord) update(ctx, opCtx context.Context, exec sqliteExecutor, saveOpts *saveOptions) (bool, error) {
+	for _, key := range r.DirtyKeys() {
+		if field, ok := r.Schema().Field(key); ok {
+			value := r.record[key]
+			if isUnset(value) {
+				value = nil
+			}
+			if err := r.checkImmutable(field, value); err != nil {
+				return false, err
+			}
+			if err := r.checkRequired(field, value, saveOpts.scenarios...); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := r.checkRequiredIn(saveOpts.scenarios); err != nil {
+		return false, err
+	}
+
+	dropped := UnknownFieldPolicyOf(r.schema) == DropUnknownFields && len(r.unknownKeys) > 0
+	if !r.IsModified() && len(r.renamedKeys) == 0 && !dropped && saveOpts.ifMatch == nil {
+		r.observers = nil
+		if saveOpts.returnDocument {
+			return false, r.refresh(ctx, exec)
+		}
+		return false, nil
+	}
+
+	doc, err := r.ToBSON(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !dropped {
+		for _, key := range r.unknownKeys {
+			doc[key] = r.originalRecord[key]
+		}
+	}
+	pkField, _ := PK(r.schema)
+	delete(doc, pkField.Name())
+	delete(doc, defaultMongoPK)
+
+	data, err := encodeSQLiteDocument(doc)
+	if err != nil {
+		return false, err
+	}
+	id, err := r.sqliteID()
+	if err != nil {
+		return false, err
+	}
+
+	table := sqliteTable(ctx, r.schema)
+	stmt := fmt.Sprintf("UPDATE %s SET doc = ? WHERE id = ?", sqliteIdent(table))
+	args := []any{data, id}
+	if saveOpts.ifMatch != nil {
+		stored, err := r.matchDocument(ctx, opCtx, exec, *saveOpts.ifMatch)
+		if err != nil {
+			return false, err
+		}
+		// The stored document is part of the condition, so a write between
+		// the check and the update is detected too
+		stmt += " AND doc = ?"
+		args = append(args, stored)
+	}
+
+	start := time.Now()
+	res, err := exec.ExecContext(opCtx, stmt, args...)
+	var matched int64
+	if err == nil {
+		matched, err = res.RowsAffected()
+	}
+	tapQuery(ctx, sqliteOperation(table, "update", stmt, []any{id}, matched, err), start)
+	if err != nil {
+		return false, err
+	}
+	if saveOpts.ifMatch != nil && matched == 0 {
+		return false, ErrPreconditionFailed
+	}
+
+	r.keepUpdated()
+	clear(r.renamedKeys)
+	if dropped {
+		r.unknownKeys = nil
+	}
+	return true, nil
+}
+
+// matchDocument checks the stored document against etag and returns its
+// text.
+func (r *sqliteRecord) matchDocument(ctx, opCtx context.Context, exec sqliteExecutor, etag string) (string, error) {
+	stored, data, err := r.read(ctx, opCtx, exec)
+	if errors.Is(err, ErrNotFound) {
+		return "", ErrPreconditionFailed
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash, err := stored.Hash()
+	if err != nil {
+		return "", err
+	}
+	if hash != etag {
+		return "", ErrPreconditionFailed
+	}
+	return data, nil
+}
+
+// read loads the stored row of the record into a new record, and returns
+// the row's document text. A deleted row is ErrNotFound.
+func (r *sqliteRecord) read(ctx, opCtx context.Context, exec sqliteExecutor) (*sqliteRecord, string, error) {
+	id, err := r.sqliteID()
+	if err != nil {
+		return nil, "", err
+	}
+
+	table := sqliteTable(ctx, r.schema)
+	stmt := fmt.Sprintf("SELECT doc FROM %s WHERE id = ?", sqliteIdent(table))
+	start := time.Now()
+	var data string
+	err = exec.QueryRowContext(opCtx, stmt, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		tapQuery(ctx, sqliteOperation(table, "findOne", stmt, []any{id}, 0, nil), start)
+		return nil, "", ErrNotFound
+	}
+	tapQuery(ctx, sqliteOperation(table, "findOne", stmt, []any{id}, 1, err), start)
+	if err != nil {
+		return nil, "", err
+	}
+
+	stored, err := loadSQLiteRecord(ctx, r.schema, id, data)
+	if err != nil {
+		return nil, "", err
+	}
+	return stored, data, nil
+}
+
+// refresh replaces the state of the record with its stored row.
+func (r *sqliteRecord) refresh(ctx context.Context, exec sqliteExecutor) error {
+	opCtx, cancel := operationContext(ctx)
+	defer cancel()
+
+	stored, _, err := r.read(ctx, opCtx, exec)
+	if err != nil {
+		return err
+	}
+
+	r.originalRecord = stored.originalRecord
+	r.record = stored.record
+	r.renamedKeys = stored.renamedKeys
+	r.unknownKeys = stored.unknownKeys
+	r.invalidateScanned()
+	return nil
+}
+
+// sqliteID returns the id of a stored record.
+func (r *sqliteRecord) sqliteID() (string, error) {
+	pkField, _ := PK(r.schema)
+	id, ok := r.originalRecord[pkField.Name()].(string)
+	if !ok || id == "" {
+		return "", errors.New("record id can't be empty")
+	}
+	return id, nil
+}
+
+// Delete implements JRecord.
+func (r *sqliteRecord) Delete(ctx context.Context) error {
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+	if _, ok := r.schema.(*ViewSchema); ok {
+		return ErrViewReadOnly
+	}
+	if r.mongoRoute(ctx) {
+		return r.mongoRecord.Delete(ctx)
+	}
+	if err := refuseOutbox(r.schema); err != nil {
+		return err
+	}
+
+	id, err := r.sqliteID()
+	if err != nil {
+		return err
+	}
+
+	opCtx, cancel := operationContext(ctx)
+	defer cancel()
+
+	table := sqliteTable(ctx, r.schema)
+	stmt := fmt.Sprintf("DELETE FROM %s WHERE id = ?", sqliteIdent(table))
+	start := time.Now()
+	res, err := mustSQLite(ctx).db.ExecContext(opCtx, stmt, id)
+	var deleted int64
+	if err == nil {
+		deleted, err = res.RowsAffected()
+	}
+	tapQuery(ctx, sqliteOperation(table, "delete", stmt, []any{id}, deleted, err), start)
+	if err != nil {
+		return err
+	}
+
+	if identityMap, ok := IdentityMapFrom(ctx); ok {
+		identityMap.Forget(r)
+	}
+	r.afterWrite(ctx, ChangeDelete)
+	return nil
+}
+
+// SaveWithRetry implements JRecord.
+func (r *sqliteRecord) SaveWithRetry(ctx context.Context, attempts int, onConflict func(local, remote JRecord) (JRecord, error)) error {
+	if r.mongoRoute(ctx) {
+		return r.mongoRecord.SaveWithRetry(ctx, attempts, onConflict)
+	}
+	if r.IsNew() {
+		return r.Save(ctx)
+	}
+
+	current := r
+	for attempt := 1; ; attempt++ {
+		etag, err := current.loadedHash()
+		if err != nil {
+			return err
+		}
+
+		err = current.Save(ctx, IfMatch(etag))
+		if err == nil {
+			if current != r {
+				r.adopt(current.mongoRecord)
+			}
+			return nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) || attempt >= attempts {
+			return err
+		}
+
+		opCtx, cancel := operationContext(ctx)
+		remote, _, err := current.read(ctx, opCtx, mustSQLite(ctx).db)
+		cancel()
+		if err != nil {
+			return err
+		}
+
+		// onConflict sees SQLite records, resolveConflict their state
+		var resolve func(local, remote JRecord) (JRecord, error)
+		if onConflict != nil {
+			resolve = func(JRecord, JRecord) (JRecord, error) {
+				resolved, err := onConflict(r, remote)
+				if s, ok := resolved.(*sqliteRecord); ok {
+					return s.mongoRecord, err
+				}
+				return resolved, err
+			}
+		}
+		next, err := resolveConflict(r.mongoRecord, remote.mongoRecord, resolve)
+		if err != nil {
+			return err
+		}
+		current = &sqliteRecord{mongoRecord: next}
+	}
+}
+
+// sqliteOperation describes a statement for QueryTap. The filter holds the
+// SQL and its arguments.
+func sqliteOperation(table, name, stmt string, args []any, count int64, err error) QueryOperation {
+	if err != nil {
+		count = 0
+	}
+	return QueryOperation{
+		Collection: table,
+		Operation:  name,
+		Filter:     bson.M{"$sql": stmt, "$args": slices.Clone(args)},
+		Count:      count,
+		Err:        err,
+	}
+}
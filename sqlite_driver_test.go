@@ -0,0 +1,253 @@
+package jpack
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"modernc.org/sqlite"
+)
+
+// openSQLite returns a store on a fresh database file of modernc.org/sqlite,
+// a cgo-free build of SQLite.
+func openSQLite(t *testing.T) *SQLiteStore {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "jpack.db")
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewSQLiteStore(db)
+}
+
+// sqliteRegexpDefined reports whether defineSQLiteRegexp ran. Functions of
+// modernc.org/sqlite can't be removed once defined.
+var sqliteRegexpDefined bool
+
+// defineSQLiteRegexp defines the regexp function REGEXP calls for the
+// connections opened from now on.
+func defineSQLiteRegexp(t *testing.T) {
+	if sqliteRegexpDefined {
+		return
+	}
+	sqliteRegexpDefined = true
+
+	err := sqlite.RegisterDeterministicScalarFunction("regexp", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		pattern, _ := args[0].(string)
+		value, ok := args[1].(string)
+		if !ok {
+			return false, nil
+		}
+		return regexp.MatchString(pattern, value)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteDriver runs records and queries through database/sql on SQLite.
+func TestSQLiteDriver(t *testing.T) {
+	books := NewSchema("test_sqlite_driver_books").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Field("isbn", &String{}, Unique()).
+		Field("pages", &Number{}).
+		Field("published", &DateTime{}).
+		Build()
+	id, title, isbn, pages, published := mustField(t, books, "id"), mustField(t, books, "title"), mustField(t, books, "isbn"), mustField(t, books, "pages"), mustField(t, books, "published")
+
+	setup := func(t *testing.T) context.Context {
+		store := openSQLite(t)
+		ctx := WithSQLite(context.Background(), store)
+		assert.NoError(t, store.Migrate(ctx, books))
+		return ctx
+	}
+	create := func(t *testing.T, ctx context.Context, name string, n int) JRecord {
+		record := NewSQLiteRecord(books)
+		assert.NoError(t, record.SetValue(title, name))
+		assert.NoError(t, record.SetValue(pages, n))
+		assert.NoError(t, record.Save(ctx))
+		return record
+	}
+
+	t.Run("saves round trip", func(t *testing.T) {
+		ctx := setup(t)
+		instant := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		record := NewSQLiteRecord(books)
+		assert.NoError(t, record.SetValue(title, "Dune"))
+		assert.NoError(t, record.SetValue(pages, 412))
+		assert.NoError(t, record.SetValue(published, instant))
+		assert.NoError(t, record.Save(ctx))
+		assert.False(t, record.IsNew())
+
+		bookID, _ := record.Value(id)
+		loaded, err := NewQuery(ctx, books).Where(Eq(id, bookID)).First()
+		assert.NoError(t, err)
+		name, _ := loaded.Value(title)
+		assert.Equal(t, "Dune", name)
+		n, ok := loaded.Int(pages)
+		assert.True(t, ok)
+		assert.Equal(t, 412, n)
+		at, ok := loaded.Time(published)
+		assert.True(t, ok)
+		assert.True(t, instant.Equal(at))
+
+		assert.NoError(t, loaded.SetValue(pages, 896))
+		assert.NoError(t, loaded.Save(ctx))
+
+		reloaded, err := NewQuery(ctx, books).Where(Eq(id, bookID)).First()
+		assert.NoError(t, err)
+		n, _ = reloaded.Int(pages)
+		assert.Equal(t, 896, n)
+		name, _ = reloaded.Value(title)
+		assert.Equal(t, "Dune", name, "updates keep the fields they don't set")
+	})
+
+	t.Run("updates mark the record stored", func(t *testing.T) {
+		ctx := setup(t)
+		record := create(t, ctx, "Dune", 412)
+
+		assert.NoError(t, record.SetValue(pages, 896))
+		assert.NoError(t, record.SaveWithRetry(ctx, 1, nil))
+		assert.False(t, record.IsModified())
+		n, _ := record.Int(pages)
+		assert.Equal(t, 896, n)
+
+		// The etag hashes the document the update left behind
+		assert.NoError(t, record.SaveWithRetry(ctx, 1, nil))
+		assert.NoError(t, record.SetValue(pages, 900))
+		assert.NoError(t, record.SaveWithRetry(ctx, 1, nil))
+
+		bookID, _ := record.Value(id)
+		stored, err := NewQuery(ctx, books).Where(Eq(id, bookID)).First()
+		assert.NoError(t, err)
+		n, _ = stored.Int(pages)
+		assert.Equal(t, 900, n)
+	})
+
+	t.Run("FindByID reads the store", func(t *testing.T) {
+		ctx := setup(t)
+		create(t, ctx, "Emma", 474)
+		dune := create(t, ctx, "Dune", 412)
+
+		bookID, _ := dune.String(id)
+		found, err := FindByID(ctx, books, bookID)
+		assert.NoError(t, err)
+		name, _ := found.Value(title)
+		assert.Equal(t, "Dune", name)
+
+		_, err = FindByID(ctx, books, "missing")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("unique fields are enforced", func(t *testing.T) {
+		ctx := setup(t)
+		for _, name := range []string{"Dune", "Emma"} {
+			record := NewSQLiteRecord(books)
+			assert.NoError(t, record.SetValue(title, name))
+			assert.NoError(t, record.SetValue(isbn, "978-0441013593"))
+			err := record.Save(ctx)
+			if name == "Dune" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		}
+	})
+
+	t.Run("queries filter order and page", func(t *testing.T) {
+		ctx := setup(t)
+		create(t, ctx, "Dune", 412)
+		create(t, ctx, "Emma", 474)
+		create(t, ctx, "Ulysses", 730)
+
+		records, err := NewQuery(ctx, books).Where(Gt(pages, 420)).OrderBy(pages).Execute()
+		assert.NoError(t, err)
+		var names []any
+		for _, record := range records {
+			name, _ := record.Value(title)
+			names = append(names, name)
+		}
+		assert.Equal(t, []any{"Emma", "Ulysses"}, names)
+
+		records, err = NewQuery(ctx, books).OrderBy(pages).Offset(1).Limit(1).Execute()
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		name, _ := records[0].Value(title)
+		assert.Equal(t, "Emma", name)
+
+		count, err := NewQuery(ctx, books).Where(In(title, []any{"Dune", "Ulysses"})).Count()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count)
+
+		missing, err := NewQuery(ctx, books).Where(Eq(title, "Persuasion")).FirstOrNil()
+		assert.NoError(t, err)
+		assert.Nil(t, missing)
+	})
+
+	t.Run("LIKE filters need the regexp function", func(t *testing.T) {
+		ctx := setup(t)
+		create(t, ctx, "Dune", 412)
+		create(t, ctx, "Emma", 474)
+
+		_, err := NewQuery(ctx, books).Where(Like(title, "^D")).Execute()
+		assert.ErrorContains(t, err, "RegisterSQLiteRegexpFilters")
+		assert.False(t, NewQuery(ctx, books).Capabilities().Regex)
+
+		RegisterSQLiteRegexpFilters()
+		t.Cleanup(func() {
+			delete(sqlFilterResolvers, "LIKE")
+			delete(sqlFilterResolvers, "NOT LIKE")
+		})
+		assert.True(t, NewQuery(ctx, books).Capabilities().Regex)
+
+		if !sqliteRegexpDefined {
+			// SQLite itself has no regexp function
+			_, err = NewQuery(ctx, books).Where(Like(title, "^D")).Execute()
+			assert.ErrorContains(t, err, "no such function: REGEXP")
+		}
+
+		defineSQLiteRegexp(t)
+		ctx = setup(t)
+		create(t, ctx, "Dune", 412)
+		create(t, ctx, "Emma", 474)
+		untitled := NewSQLiteRecord(books)
+		assert.NoError(t, untitled.SetValue(pages, 1))
+		assert.NoError(t, untitled.Save(ctx))
+
+		records, err := NewQuery(ctx, books).Where(Like(title, "^D")).Execute()
+		assert.NoError(t, err)
+		if assert.Len(t, records, 1) {
+			name, _ := records[0].Value(title)
+			assert.Equal(t, "Dune", name)
+		}
+
+		count, err := NewQuery(ctx, books).Where(NotLike(title, "^D")).Count()
+		assert.NoError(t, err)
+		assert.Equal(t, 2, count, "missing values don't match")
+	})
+
+	t.Run("deletes remove the row", func(t *testing.T) {
+		ctx := setup(t)
+		dune := create(t, ctx, "Dune", 412)
+		create(t, ctx, "Emma", 474)
+
+		assert.NoError(t, dune.Delete(ctx))
+		count, err := NewQuery(ctx, books).Count()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		duneID, _ := dune.Value(id)
+		gone, err := NewQuery(ctx, books).Where(Eq(id, duneID)).FirstOrNil()
+		assert.NoError(t, err)
+		assert.Nil(t, gone)
+	})
+}
@@ -0,0 +1,705 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLFilterResolver renders a Filter as an SQLite predicate over the stored
+// JSON documents, with its arguments. An empty predicate adds no condition.
+type SQLFilterResolver func(Filter) (string, []any)
+
+var sqlFilterResolvers = make(map[string]SQLFilterResolver)
+
+// RegisterSQLFilterResolver registers the SQLite resolver of an operator, the
+// counterpart of RegisterFilterResolver for SQLiteStore queries.
+func RegisterSQLFilterResolver(operator string, resolver SQLFilterResolver) {
+	sqlFilterResolvers[operator] = resolver
+}
+
+// ResolveSQLFilter renders a Filter as an SQLite predicate. Operators without
+// an SQLite resolver are an error rather than dropped, so a query never
+// matches more than it asks for.
+func ResolveSQLFilter(filter Filter) (string, []any, error) {
+	if filter == nil {
+		return "", nil, nil
+	}
+
+	switch operator := filter.Operator(); operator {
+	case "AND", "OR":
+		left, leftArgs, err := ResolveSQLFilter(filter.Left())
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := ResolveSQLFilter(filter.Right())
+		if err != nil {
+			return "", nil, err
+		}
+		if left == "" {
+			return right, rightArgs, nil
+		}
+		if right == "" {
+			return left, leftArgs, nil
+		}
+		return "(" + left + ") " + operator + " (" + right + ")", append(leftArgs, rightArgs...), nil
+	case "NOT":
+		right, args, err := ResolveSQLFilter(filter.Right())
+		if err != nil || right == "" {
+			return "", nil, err
+		}
+		return "NOT (" + right + ")", args, nil
+	default:
+		resolver, ok := sqlFilterResolvers[operator]
+		if !ok && (operator == "LIKE" || operator == "NOT LIKE") {
+			return "", nil, fmt.Errorf("jpack: SQLite can't filter with %s: it has no regexp function, see RegisterSQLiteRegexpFilters", operator)
+		}
+		if !ok {
+			return "", nil, fmt.Errorf("jpack: SQLite can't filter with %s", operator)
+		}
+		predicate, args := resolver(filter)
+		return predicate, args, nil
+	}
+}
+
+// RegisterSQLiteRegexpFilters registers the SQLite resolvers of LIKE and NOT
+// LIKE, which render to the REGEXP operator. SQLite has no regexp function of
+// its own, and neither modernc.org/sqlite nor github.com/mattn/go-sqlite3
+// provides one by default, so call it only once the driver of every SQLite
+// database defines regexp(pattern, value), e.g. with
+// sqlite.RegisterDeterministicScalarFunction of modernc.org/sqlite. Without
+// it, LIKE filters fail on SQLite stores.
+func RegisterSQLiteRegexpFilters() {
+	// LIKE is a regular expression, as with MongoDB
+	RegisterSQLFilterResolver("LIKE", func(filter Filter) (string, []any) {
+		pattern, ok := filter.Value().(string)
+		if filter.Field() == nil || !ok {
+			return "", nil
+		}
+		return sqliteColumn(filter.Field()) + " REGEXP ?", []any{pattern}
+	})
+
+	RegisterSQLFilterResolver("NOT LIKE", func(filter Filter) (string, []any) {
+		pattern, ok := filter.Value().(string)
+		if filter.Field() == nil || !ok {
+			return "", nil
+		}
+		column := sqliteColumn(filter.Field())
+		return "(" + column + " IS NULL OR NOT " + column + " REGEXP ?)", []any{pattern}
+	})
+}
+
+// sqliteColumn returns the expression of a field in predicates: the id
+// column for the primary key, the JSON path of the field otherwise.
+func sqliteColumn(field JField) string {
+	if pk, ok := PK(field.Schema()); ok && pk.Name() == field.Name() {
+		return "id"
+	}
+	return sqliteJSONPath(field.Name())
+}
+
+// sqliteArg converts a filter value to the form it is stored in.
+func sqliteArg(value any) any {
+	if record, ok := value.(JRecord); ok {
+		id, _ := recordID(record)
+		return id
+	}
+	return sqliteValue(value)
+}
+
+// sqliteList renders the placeholders and arguments of a list of values.
+func sqliteList(values []any) (string, []any) {
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = sqliteArg(value)
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", "), args
+}
+
+// sqliteComparison returns the resolver of a binary comparison.
+func sqliteComparison(op string) SQLFilterResolver {
+	return func(filter Filter) (string, []any) {
+		if filter.Field() == nil {
+			return "", nil
+		}
+		return sqliteColumn(filter.Field()) + " " + op + " ?", []any{sqliteArg(filter.Value())}
+	}
+}
+
+func init() {
+	RegisterSQLFilterResolver("=", func(filter Filter) (string, []any) {
+		if filter.Field() == nil {
+			return "", nil
+		}
+		if filter.Value() == nil {
+			return sqliteColumn(filter.Field()) + " IS NULL", nil
+		}
+		return sqliteColumn(filter.Field()) + " = ?", []any{sqliteArg(filter.Value())}
+	})
+
+	// Like $ne, missing values don't equal anything
+	RegisterSQLFilterResolver("!=", sqliteComparison("IS NOT"))
+	RegisterSQLFilterResolver("<", sqliteComparison("<"))
+	RegisterSQLFilterResolver("<=", sqliteComparison("<="))
+	RegisterSQLFilterResolver(">", sqliteComparison(">"))
+	RegisterSQLFilterResolver(">=", sqliteComparison(">="))
+
+	RegisterSQLFilterResolver("IN", func(filter Filter) (string, []any) {
+		values, ok := filter.Value().([]any)
+		if filter.Field() == nil || !ok {
+			return "", nil
+		}
+		if len(values) == 0 {
+			return "0", nil
+		}
+		list, args := sqliteList(values)
+		return sqliteColumn(filter.Field()) + " IN (" + list + ")", args
+	})
+
+	RegisterSQLFilterResolver("NOT IN", func(filter Filter) (string, []any) {
+		values, ok := filter.Value().([]any)
+		if filter.Field() == nil || !ok || len(values) == 0 {
+			return "", nil
+		}
+		column := sqliteColumn(filter.Field())
+		list, args := sqliteList(values)
+		return "(" + column + " IS NULL OR " + column + " NOT IN (" + list + "))", args
+	})
+
+	RegisterSQLFilterResolver("BETWEEN", func(filter Filter) (string, []any) {
+		values, ok := filter.Value().([]any)
+		if filter.Field() == nil || !ok || len(values) != 2 {
+			return "", nil
+		}
+		return sqliteColumn(filter.Field()) + " BETWEEN ? AND ?", []any{sqliteArg(values[0]), sqliteArg(values[1])}
+	})
+
+	RegisterSQLFilterResolver("NOT BETWEEN", func(filter Filter) (string, []any) {
+		values, ok := filter.Value().([]any)
+		if filter.Field() == nil || !ok || len(values) != 2 {
+			return "", nil
+		}
+		column := sqliteColumn(filter.Field())
+		return "(" + column + " IS NULL OR " + column + " NOT BETWEEN ? AND ?)", []any{sqliteArg(values[0]), sqliteArg(values[1])}
+	})
+
+	RegisterSQLFilterResolver("EXISTS", func(filter Filter) (string, []any) {
+		if filter.Field() == nil {
+			return "", nil
+		}
+		if sqliteColumn(filter.Field()) == "id" {
+			return "1", nil
+		}
+		return sqliteJSONCall("json_type", filter.Field().Name()) + " IS NOT NULL", nil
+	})
+
+	RegisterSQLFilterResolver("NOT EXISTS", func(filter Filter) (string, []any) {
+		if filter.Field() == nil {
+			return "", nil
+		}
+		if sqliteColumn(filter.Field()) == "id" {
+			return "0", nil
+		}
+		return sqliteJSONCall("json_type", filter.Field().Name()) + " IS NULL", nil
+	})
+
+	RegisterSQLFilterResolver("IN QUERY", func(filter Filter) (string, []any) {
+		sub, ok := filter.Value().(*subqueryValues)
+		if filter.Field() == nil || !ok {
+			return "", nil
+		}
+		// Query.Where reports a failed subquery
+		values, err := sub.resolve()
+		if err != nil || len(values) == 0 {
+			return "0", nil
+		}
+		list, args := sqliteList(values)
+		return sqliteColumn(filter.Field()) + " IN (" + list + ")", args
+	})
+
+	RegisterSQLFilterResolver(matchNothingOperator, func(Filter) (string, []any) {
+		return "0", nil
+	})
+}
+
+// sqliteQuery implements Query for a SQLiteStore.
+type sqliteQuery struct {
+	schema JSchema
+	ctx    context.Context
+	store  *SQLiteStore
+
+	fields   []JField
+	where    []Filter
+	orderBy  []JField
+	limit    *int
+	offset   *int
+	withRefs map[string]func(JSchema, Query) Query
+
+	// Result caching, enabled by Cached
+	cacheTTL  time.Duration
+	cacheTags []string
+
+	// err is a filter that can't be rendered or a failed subquery,
+	// returned when the query runs
+	err error
+}
+
+var _ Query = &sqliteQuery{}
+
+// NewSQLiteQuery creates a query of the schema on the SQLiteStore of the
+// context.
+func NewSQLiteQuery(ctx context.Context, schema JSchema) Query {
+	return &sqliteQuery{
+		schema:   schema,
+		ctx:      ctx,
+		store:    mustSQLite(ctx),
+		withRefs: make(map[string]func(JSchema, Query) Query),
+	}
+}
+
+// Schema implements Query
+func (q *sqliteQuery) Schema() JSchema {
+	return q.schema
+}
+
+// Select implements Query. Documents are read whole, and only the selected
+// fields are loaded into the records.
+func (q *sqliteQuery) Select(fields ...JField) Query {
+	q.fields = fields
+	return q
+}
+
+// With implements Query for eager loading
+func (q *sqliteQuery) With(ref JRef, fn func(JSchema, Query) Query) Query {
+	q.withRefs[ref.Name()] = fn
+	return q
+}
+
+// Where implements Query
+func (q *sqliteQuery) Where(filter Filter) Query {
+	if err := resolveSubqueries(filter); err != nil {
+		q.err = err
+		return q
+	}
+	if _, _, err := ResolveSQLFilter(filter); err != nil {
+		q.err = err
+		return q
+	}
+	if filter != nil {
+		q.where = append(q.where, filter)
+	}
+	return q
+}
+
+// OrderBy implements Query
+func (q *sqliteQuery) OrderBy(fields ...JField) Query {
+	q.orderBy = fields
+	return q
+}
+
+// Cached implements Query
+func (q *sqliteQuery) Cached(ttl time.Duration, tags ...string) Query {
+	q.cacheTTL = ttl
+	q.cacheTags = tags
+	return q
+}
+
+// Limit implements Query
+func (q *sqliteQuery) Limit(limit int) Query {
+	q.limit = &limit
+	return q
+}
+
+// Offset implements Query
+func (q *sqliteQuery) Offset(offset int) Query {
+	q.offset = &offset
+	return q
+}
+
+// BucketBy implements Query. Buckets are computed by MongoDB aggregations,
+// so executing them on a SQLite query fails.
+func (q *sqliteQuery) BucketBy(field JField, interval Interval) *BucketQuery {
+	return TimeBuckets(q, field, interval)
+}
+
+// filters returns the where clauses with the mandatory filters of the
+// schema's policies.
+func (q *sqliteQuery) filters() []Filter {
+	filters := append([]Filter{}, q.where...)
+	for _, policy := range PoliciesOf(q.schema) {
+		if filter := policy.QueryFilter(q.ctx, q.schema); filter != nil {
+			filters = append(filters, filter)
+		}
+	}
+	return filters
+}
+
+// statement builds a SELECT of columns; paged statements are ordered and
+// limited.
+func (q *sqliteQuery) statement(columns string, paged bool, limit *int) (string, []any, error) {
+	var b strings.Builder
+	var args []any
+	fmt.Fprintf(&b, "SELECT %s FROM %s", columns, sqliteIdent(sqliteTable(q.ctx, q.schema)))
+
+	var predicates []string
+	for _, filter := range q.filters() {
+		predicate, filterArgs, err := ResolveSQLFilter(filter)
+		if err != nil {
+			return "", nil, err
+		}
+		if predicate != "" {
+			predicates = append(predicates, "("+predicate+")")
+			args = append(args, filterArgs...)
+		}
+	}
+	if len(predicates) > 0 {
+		b.WriteString(" WHERE " + strings.Join(predicates, " AND "))
+	}
+
+	if !paged {
+		return b.String(), args, nil
+	}
+
+	var order []string
+	for _, field := range q.orderBy {
+		if sameStorage(field.Schema(), q.schema) {
+			order = append(order, sqliteColumn(field))
+		}
+	}
+	if len(order) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	}
+
+	if limit != nil || q.offset != nil {
+		// SQLite only takes an offset after a limit, -1 being none
+		n, skip := -1, 0
+		if limit != nil {
+			n = *limit
+		}
+		if q.offset != nil {
+			skip = *q.offset
+		}
+		b.WriteString(" LIMIT ? OFFSET ?")
+		args = append(args, n, skip)
+	}
+	return b.String(), args, nil
+}
+
+// sqliteRow is a stored row: the record id and its JSON document.
+type sqliteRow struct {
+	id  string
+	doc string
+}
+
+// rows runs a SELECT of id and doc.
+func (q *sqliteQuery) rows(exec sqliteExecutor, op string, limit *int) ([]sqliteRow, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	stmt, args, err := q.statement("id, doc", true, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return sqliteCached(q, op, stmt, args, func() (rows []sqliteRow, err error) {
+		ctx, cancel := operationContext(q.ctx)
+		defer cancel()
+
+		start := time.Now()
+		defer func() {
+			tapQuery(q.ctx, sqliteOperation(sqliteTable(q.ctx, q.schema), op, stmt, args, int64(len(rows)), err), start)
+		}()
+
+		result, err := exec.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer result.Close()
+
+		for result.Next() {
+			var row sqliteRow
+			if err := result.Scan(&row.id, &row.doc); err != nil {
+				return nil, err
+			}
+			rows = append(rows, row)
+		}
+		return rows, result.Err()
+	})
+}
+
+// records loads the rows into records.
+func (q *sqliteQuery) records(rows []sqliteRow) ([]JRecord, error) {
+	var keep map[string]bool
+	if len(q.fields) > 0 {
+		keep = map[string]bool{}
+		if pk, ok := PK(q.schema); ok {
+			keep[pk.Name()] = true
+		}
+		for _, field := range q.fields {
+			for _, key := range storageKeys(field) {
+				keep[key] = true
+			}
+		}
+		if view, ok := q.schema.(*ViewSchema); ok {
+			for _, field := range view.dependsOn {
+				for _, key := range storageKeys(field) {
+					keep[key] = true
+				}
+			}
+		}
+	}
+
+	records := make([]JRecord, 0, len(rows))
+	for _, row := range rows {
+		record, err := loadSQLiteRecord(q.ctx, q.schema, row.id, row.doc)
+		if err != nil {
+			return nil, err
+		}
+		if keep != nil {
+			for key := range record.originalRecord {
+				if !keep[key] {
+					delete(record.originalRecord, key)
+				}
+			}
+		}
+		records = append(records, q.track(record))
+	}
+
+	if len(q.withRefs) > 0 {
+		if err := q.loadReferences(records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// track returns the instance already loaded in this request for the same
+// record when the context carries an identity map.
+func (q *sqliteQuery) track(record *sqliteRecord) JRecord {
+	if identityMap, ok := IdentityMapFrom(q.ctx); ok {
+		return identityMap.Track(record)
+	}
+	return record
+}
+
+// Execute implements Query
+func (q *sqliteQuery) Execute() ([]JRecord, error) {
+	rows, err := q.rows(q.store.db, "find", q.limit)
+	if err != nil {
+		return nil, err
+	}
+	return q.records(rows)
+}
+
+// First implements Query
+func (q *sqliteQuery) First() (JRecord, error) {
+	record, err := q.FirstOrNil()
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// FirstOrNil implements Query
+func (q *sqliteQuery) FirstOrNil() (JRecord, error) {
+	one := 1
+	rows, err := q.rows(q.store.db, "findOne", &one)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	records, err := q.records(rows)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// Count implements Query
+func (q *sqliteQuery) Count() (int, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+
+	stmt, args, err := q.statement("COUNT(*)", false, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return sqliteCached(q, "count", stmt, args, func() (count int, err error) {
+		ctx, cancel := operationContext(q.ctx)
+		defer cancel()
+
+		start := time.Now()
+		err = q.store.db.QueryRowContext(ctx, stmt, args...).Scan(&count)
+		tapQuery(q.ctx, sqliteOperation(sqliteTable(q.ctx, q.schema), "count", stmt, args, int64(count), err), start)
+		return count, err
+	})
+}
+
+// FirstOrCreate implements Query. The lookup and the insert run in one
+// transaction, which SQLite serializes with other writers. The inserted
+// record holds the equality conditions of the filter, including policy
+// filters, and the defaults.
+func (q *sqliteQuery) FirstOrCreate(ctx context.Context, defaults map[JField]any) (JRecord, bool, error) {
+	if IsReadOnly(ctx) {
+		return nil, false, ErrReadOnly
+	}
+	if q.err != nil {
+		return nil, false, q.err
+	}
+	if err := refuseOutbox(q.schema); err != nil {
+		return nil, false, err
+	}
+
+	tx, err := q.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	one := 1
+	rows, err := q.rows(tx, "findOne", &one)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(rows) > 0 {
+		if err := tx.Commit(); err != nil {
+			return nil, false, err
+		}
+		records, err := q.records(rows)
+		if err != nil {
+			return nil, false, err
+		}
+		return records[0], false, nil
+	}
+
+	record := NewSQLiteRecord(q.schema)
+	for field, value := range defaults {
+		if err := record.SetValue(field, value); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+	// Values fixed by the filter win
+	values := make(map[JField]any)
+	for _, filter := range q.filters() {
+		sqliteEqualities(filter, values)
+	}
+	for field, value := range values {
+		if err := record.SetValue(field, value); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", field.Name(), err)
+		}
+	}
+
+	if err := record.save(ctx, tx, &saveOptions{}); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+	return q.track(record), true, nil
+}
+
+// sqliteEqualities collects the values a filter fixes by plain equality.
+func sqliteEqualities(filter Filter, values map[JField]any) {
+	if filter == nil {
+		return
+	}
+	switch filter.Operator() {
+	case "AND":
+		sqliteEqualities(filter.Left(), values)
+		sqliteEqualities(filter.Right(), values)
+	case "=":
+		if filter.Field() != nil {
+			values[filter.Field()] = filter.Value()
+		}
+	}
+}
+
+// loadReferences eager loads the records referenced by ref fields.
+func (q *sqliteQuery) loadReferences(records []JRecord) error {
+	for refName, refFn := range q.withRefs {
+		refField, ok := q.schema.Field(refName)
+		if !ok {
+			continue
+		}
+		ref, ok := refField.(JRef)
+		if !ok {
+			continue
+		}
+		pk, ok := PK(ref.RelSchema())
+		if !ok {
+			return errors.New("no primary key found in referenced schema")
+		}
+
+		var ids []any
+		for _, record := range records {
+			if id, ok := record.Value(refField); ok {
+				if id, ok := id.(string); ok {
+					ids = append(ids, id)
+				}
+			}
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		refQuery := refFn(ref.RelSchema(), NewSQLiteQuery(q.ctx, ref.RelSchema()).Where(In(pk, ids)))
+		refRecords, err := refQuery.Execute()
+		if err != nil {
+			return err
+		}
+
+		byID := make(map[string]JRecord, len(refRecords))
+		for _, refRecord := range refRecords {
+			if id, ok := recordID(refRecord); ok {
+				byID[id] = refRecord
+			}
+		}
+		for _, record := range records {
+			if id, ok := record.Value(refField); ok {
+				if id, ok := id.(string); ok && byID[id] != nil {
+					record.SetValue(refField, byID[id])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sqliteCached returns the cached result of the statement, or runs fetch and
+// caches its result when the query has caching enabled.
+func sqliteCached[T any](q *sqliteQuery, op, stmt string, args []any, fetch func() (T, error)) (T, error) {
+	if q.cacheTTL <= 0 {
+		return fetch()
+	}
+
+	var selected []string
+	for _, field := range q.fields {
+		selected = append(selected, field.Name())
+	}
+	// The statement names the table; the store tells databases apart
+	key := fmt.Sprintf("sqlite(%p)|%s@%d|%s|%s|%v|%v", q.store, q.schema.Name(), SchemaVersion(q.schema), op, stmt, args, selected)
+
+	cache := GetQueryCache()
+	if value, ok := cache.Get(key); ok {
+		if result, ok := value.(T); ok {
+			return result, nil
+		}
+	}
+
+	result, err := fetch()
+	if err != nil {
+		return result, err
+	}
+
+	tags := append([]string{storageSchema(q.schema).Name()}, q.cacheTags...)
+	cache.Set(key, result, q.cacheTTL, tags)
+	return result, nil
+}
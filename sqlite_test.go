@@ -0,0 +1,152 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSQLite(t *testing.T) {
+	authors := NewSchema("test_sqlite_authors").
+		Field("id", &String{}).
+		Build()
+	books := NewSchema("test_sqlite_books").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Field("isbn", &String{}, Unique()).
+		Field("pages", &Number{}).
+		Field("published", &DateTime{}).
+		Ref("author", authors).
+		Build()
+	id, title, pages, published := mustField(t, books, "id"), mustField(t, books, "title"), mustField(t, books, "pages"), mustField(t, books, "published")
+	store := openSQLite(t)
+	ctx := WithSQLite(context.Background(), store)
+
+	t.Run("tables index unique and ref fields", func(t *testing.T) {
+		assert.Equal(t, []string{
+			`CREATE TABLE IF NOT EXISTS "test_sqlite_books" (id TEXT PRIMARY KEY, doc TEXT NOT NULL)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "test_sqlite_books_isbn" ON "test_sqlite_books" (json_extract(doc, '$."isbn"'))`,
+			`CREATE INDEX IF NOT EXISTS "test_sqlite_books_author" ON "test_sqlite_books" (json_extract(doc, '$."author"'))`,
+		}, sqliteSchemaDDL(ctx, books))
+		assert.NoError(t, store.Migrate(ctx, authors, books))
+		assert.NoError(t, store.Migrate(ctx, authors, books), "migrations can be repeated")
+	})
+
+	t.Run("filters render to json_extract predicates", func(t *testing.T) {
+		instant := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+		tests := []struct {
+			filter    Filter
+			predicate string
+			args      []any
+		}{
+			{Eq(title, "Dune"), `json_extract(doc, '$."title"') = ?`, []any{"Dune"}},
+			{Eq(title, nil), `json_extract(doc, '$."title"') IS NULL`, nil},
+			{Ne(title, "Dune"), `json_extract(doc, '$."title"') IS NOT ?`, []any{"Dune"}},
+			{Eq(id, "65f1a2b3c4d5e6f7a8b9c0d1"), `id = ?`, []any{"65f1a2b3c4d5e6f7a8b9c0d1"}},
+			{Gte(published, instant), `json_extract(doc, '$."published"') >= ?`, []any{"2024-03-01T11:00:00.000Z"}},
+			{In(pages, []any{1, 2}), `json_extract(doc, '$."pages"') IN (?, ?)`, []any{1, 2}},
+			{In(pages, []any{}), `0`, nil},
+			{Exists(title), `json_type(doc, '$."title"') IS NOT NULL`, nil},
+			{Between(pages, 1, 9), `json_extract(doc, '$."pages"') BETWEEN ? AND ?`, []any{1, 9}},
+			{
+				Eq(title, "Dune").Or(Gt(pages, 100)).Not(),
+				`NOT ((json_extract(doc, '$."title"') = ?) OR (json_extract(doc, '$."pages"') > ?))`,
+				[]any{"Dune", 100},
+			},
+		}
+		for _, tt := range tests {
+			predicate, args, err := ResolveSQLFilter(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.predicate, predicate)
+			assert.Equal(t, tt.args, args)
+
+			// SQLite accepts the predicate
+			_, err = NewQuery(ctx, books).Where(tt.filter).Execute()
+			assert.NoError(t, err, predicate)
+		}
+	})
+
+	t.Run("operators without a resolver are an error", func(t *testing.T) {
+		_, _, err := ResolveSQLFilter(&filterImpl{field: title, operator: "NEAR"})
+		assert.ErrorContains(t, err, "can't filter with NEAR")
+
+		q := NewSQLiteQuery(ctx, books).Where(&filterImpl{field: title, operator: "NEAR"})
+		_, err = q.Execute()
+		assert.ErrorContains(t, err, "can't filter with NEAR")
+
+		_, _, err = ResolveSQLFilter(Like(title, "^D"))
+		assert.ErrorContains(t, err, "can't filter with LIKE: it has no regexp function, see RegisterSQLiteRegexpFilters")
+	})
+
+	t.Run("queries build paged statements", func(t *testing.T) {
+		q := NewQuery(ctx, books).Where(Eq(title, "Dune")).OrderBy(published).Offset(20).(*sqliteQuery)
+		stmt, args, err := q.statement("id, doc", true, q.limit)
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT id, doc FROM "test_sqlite_books" WHERE (json_extract(doc, '$."title"') = ?) ORDER BY json_extract(doc, '$."published"') LIMIT ? OFFSET ?`, stmt)
+		assert.Equal(t, []any{"Dune", -1, 20}, args)
+		_, err = q.Execute()
+		assert.NoError(t, err)
+
+		stmt, args, err = q.statement("COUNT(*)", false, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, `SELECT COUNT(*) FROM "test_sqlite_books" WHERE (json_extract(doc, '$."title"') = ?)`, stmt)
+		assert.Equal(t, []any{"Dune"}, args)
+	})
+
+	t.Run("policies filter queries", func(t *testing.T) {
+		tenanted := NewSchema("test_sqlite_tenanted").
+			Field("id", &String{}).
+			Field("tenant", &String{}).
+			Policy(&TenantPolicy{Field: "tenant"}).
+			Build()
+
+		stmt, _, err := NewSQLiteQuery(ctx, tenanted).(*sqliteQuery).statement("id, doc", true, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, stmt, "WHERE (0)")
+
+		stmt, args, err := NewSQLiteQuery(WithTenant(ctx, "acme"), tenanted).(*sqliteQuery).statement("id, doc", true, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, stmt, `WHERE (json_extract(doc, '$."tenant"') = ?)`)
+		assert.Equal(t, []any{"acme"}, args)
+	})
+
+	t.Run("documents round trip through JSON", func(t *testing.T) {
+		instant := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		author := bson.NewObjectID().Hex()
+		data, err := encodeSQLiteDocument(bson.M{"title": "Dune", "pages": 412, "published": instant, "author": author})
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"title": "Dune", "pages": 412, "published": "2024-03-01T12:00:00.000Z", "author": "`+author+`"}`, data)
+
+		bookID := bson.NewObjectID().Hex()
+		record, err := loadSQLiteRecord(t.Context(), books, bookID, data)
+		assert.NoError(t, err)
+		assert.False(t, record.IsNew())
+
+		value, _ := record.Value(id)
+		assert.Equal(t, bookID, value)
+		n, ok := record.Int(pages)
+		assert.True(t, ok)
+		assert.Equal(t, 412, n)
+		at, ok := record.Time(published)
+		assert.True(t, ok)
+		assert.True(t, instant.Equal(at))
+
+		// Setting the stored instant again isn't a change
+		assert.NoError(t, record.SetValue(published, instant))
+		assert.False(t, record.IsModified())
+	})
+
+	t.Run("records need a store in the context", func(t *testing.T) {
+		record := NewSQLiteRecord(books)
+		assert.NoError(t, record.SetValue(title, "Dune"))
+		assert.PanicsWithValue(t, "jpack: SQLite store not found in context", func() { record.Save(context.Background()) })
+	})
+
+	t.Run("MongoDB is preferred when the context has a database", func(t *testing.T) {
+		_, ok := NewQuery(WithSQLite(offlineContext(t), NewSQLiteStore(nil)), books).(*mongoQuery)
+		assert.True(t, ok)
+	})
+}
@@ -0,0 +1,87 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// DefaultStore names the store used by schemas without a store of their own
+// when the context holds no database.
+const DefaultStore = "default"
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]*mongo.Database{}
+)
+
+// Open registers db as the named store, e.g. a reporting database next to
+// the operational one, replacing any store of that name. Schemas bound to it
+// with SchemaBuilder.Store read and write db whatever database the context
+// holds. A nil db closes the store.
+func Open(name string, db *mongo.Database) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if db == nil {
+		delete(stores, name)
+		return
+	}
+	stores[name] = db
+}
+
+// StoreDatabase returns the database of a store opened with Open.
+func StoreDatabase(name string) (*mongo.Database, bool) {
+	storesMu.RLock()
+	defer storesMu.RUnlock()
+
+	db, ok := stores[name]
+	return db, ok
+}
+
+// Store binds the schema to a store opened with Open. Records and queries of
+// the schema use the store's database instead of the context's.
+func (s *SchemaBuilder) Store(name string) *SchemaBuilder {
+	s.schema.store = name
+	return s
+}
+
+// Store returns the name of the store the schema is bound to.
+func (s *schemaImpl) Store() string {
+	return s.store
+}
+
+// StoreOf returns the name of the store a schema, or the base schema of a
+// view, is bound to. It is empty for schemas using the context's database.
+func StoreOf(schema JSchema) string {
+	if s, ok := storageSchema(schema).(interface{ Store() string }); ok {
+		return s.Store()
+	}
+	return ""
+}
+
+// databaseFor returns the database holding the schema's collection: the
+// schema's store, the context's database or the default store.
+func databaseFor(ctx context.Context, schema JSchema) (*mongo.Database, bool) {
+	if name := StoreOf(schema); name != "" {
+		return StoreDatabase(name)
+	}
+	if db, ok := DatabaseFrom(ctx); ok {
+		return db, true
+	}
+	return StoreDatabase(DefaultStore)
+}
+
+// mustDatabaseFor is databaseFor, and panics when there is no database.
+func mustDatabaseFor(ctx context.Context, schema JSchema) *mongo.Database {
+	db, ok := databaseFor(ctx, schema)
+	if ok {
+		return db
+	}
+	if name := StoreOf(schema); name != "" {
+		panic(fmt.Sprintf("jpack: store %q of %s is not open", name, schema.Name()))
+	}
+	panic("jpack: mongo connection not found in context")
+}
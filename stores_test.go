@@ -0,0 +1,70 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStores(t *testing.T) {
+	ctx := offlineContext(t)
+	operational := MustConn(ctx)
+	analytics := operational.Client().Database("jpack_test_analytics")
+
+	Open("analytics", analytics)
+	t.Cleanup(func() { Open("analytics", nil) })
+
+	events := NewSchema("test_store_events").
+		Field("id", &String{}).
+		Store("analytics").
+		Build()
+	users := NewSchema("test_store_users").
+		Field("id", &String{}).
+		Build()
+
+	t.Run("bound schemas use their store", func(t *testing.T) {
+		assert.Equal(t, "analytics", StoreOf(events))
+		assert.Equal(t, "jpack_test_analytics", collection(ctx, events).Database().Name())
+		assert.Equal(t, "jpack_test", collection(ctx, users).Database().Name())
+	})
+
+	t.Run("views use the store of their base", func(t *testing.T) {
+		view := NewView("test_store_event_view", events).Build()
+		assert.Equal(t, "analytics", StoreOf(view))
+	})
+
+	t.Run("bound schemas don't need a database in the context", func(t *testing.T) {
+		assert.NotPanics(t, func() { NewQuery(context.Background(), events) })
+		assert.Panics(t, func() { NewQuery(context.Background(), users) })
+	})
+
+	t.Run("the default store backs contexts without a database", func(t *testing.T) {
+		Open(DefaultStore, operational)
+		t.Cleanup(func() { Open(DefaultStore, nil) })
+
+		assert.Equal(t, "jpack_test", collection(context.Background(), users).Database().Name())
+		assert.Equal(t, operational, MustConn(context.Background()))
+	})
+
+	t.Run("stores that aren't open panic", func(t *testing.T) {
+		orphan := NewSchema("test_store_orphan").Field("id", &String{}).Store("reporting").Build()
+		assert.PanicsWithValue(t, `jpack: store "reporting" of test_store_orphan is not open`, func() { collection(ctx, orphan) })
+	})
+
+	t.Run("change streams are per store", func(t *testing.T) {
+		err := WatchChanges(ctx, []JSchema{users, events}, nil, func(ChangeEvent) error { return nil })
+		assert.ErrorContains(t, err, "different stores")
+	})
+
+	t.Run("CloneBuilder keeps the store", func(t *testing.T) {
+		assert.Equal(t, "analytics", StoreOf(CloneBuilder(events).Build()))
+	})
+
+	t.Run("closed stores are forgotten", func(t *testing.T) {
+		Open("scratch", analytics)
+		Open("scratch", nil)
+		_, ok := StoreDatabase("scratch")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,223 @@
+package jpack
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// StructTag is the struct tag read by SchemaFromStruct.
+const StructTag = "jpack"
+
+// SchemaFromStruct derives a schema from the exported fields of a struct, so
+// struct-first code doesn't have to repeat its definitions. Fields are
+// configured with the jpack tag:
+//
+//	type Post struct {
+//		ID     string    `jpack:"id"`
+//		Title  string    `jpack:"title,required"`
+//		Status string    `jpack:",type=options"` // fails: options need a service
+//		Author *User     `jpack:"author,immutable"`
+//		Posted time.Time `jpack:"posted_at,alias=published_at"`
+//		Cache  string    `jpack:"-"`
+//	}
+//
+// The first tag entry is the field name, defaulting to the snake_case Go
+// name. The others are "type=<name>" to pick a registered field type,
+// "required", "immutable" and "alias=<old name>", which can repeat. Without a
+// type, strings map to string, integers to number, bools to boolean and
+// time.Time to datetime; types needing configuration, like options, are
+// better added with SchemaBuilder. A pointer to another struct becomes a ref
+// to the schema derived from that struct.
+//
+// The schema is named after the snake_case type name, or by the struct's
+// SchemaName() string method. An "id" string field is added first when the
+// struct has none.
+func SchemaFromStruct(v any) (JSchema, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("jpack: SchemaFromStruct needs a struct, got %T", v)
+	}
+
+	return schemaFromStructType(t, make(map[reflect.Type]JSchema))
+}
+
+// schemaFromStructType derives the schema of t. seen holds the schemas being
+// derived, so self and mutual references resolve to the same schema.
+func schemaFromStructType(t reflect.Type, seen map[reflect.Type]JSchema) (JSchema, error) {
+	if schema, ok := seen[t]; ok {
+		return schema, nil
+	}
+
+	builder := NewSchema(structSchemaName(t))
+	seen[t] = builder.schema
+
+	if !structHasID(t) {
+		builder.Field("id", &String{})
+	}
+
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+
+		tag, ok := parseStructTag(sf)
+		if !ok {
+			continue
+		}
+
+		var opts []FieldOption
+		if tag.required {
+			opts = append(opts, Required())
+		}
+		if tag.unique {
+			opts = append(opts, Unique())
+		}
+		if tag.immutable {
+			opts = append(opts, Immutable())
+		}
+		if len(tag.aliases) > 0 {
+			opts = append(opts, FieldAlias(tag.aliases...))
+		}
+
+		if rel, ok := refStructType(sf.Type); ok && (tag.typeName == "" || tag.typeName == "ref") {
+			relSchema, err := schemaFromStructType(rel, seen)
+			if err != nil {
+				return nil, err
+			}
+			builder.Ref(tag.name, relSchema, opts...)
+			continue
+		}
+
+		fType, err := structFieldType(sf, tag.typeName)
+		if err != nil {
+			return nil, fmt.Errorf("jpack: %s.%s: %w", t.Name(), sf.Name, err)
+		}
+		builder.Field(tag.name, fType, opts...)
+	}
+
+	return builder.Build(), nil
+}
+
+type structTag struct {
+	name      string
+	typeName  string
+	required  bool
+	unique    bool
+	immutable bool
+	aliases   []string
+}
+
+// parseStructTag reads the jpack tag of a struct field. It reports false for
+// fields tagged "-".
+func parseStructTag(sf reflect.StructField) (structTag, bool) {
+	value := sf.Tag.Get(StructTag)
+	if value == "-" {
+		return structTag{}, false
+	}
+
+	parts := strings.Split(value, ",")
+	tag := structTag{name: parts[0]}
+	if tag.name == "" {
+		tag.name = snakeCase(sf.Name)
+	}
+
+	for _, part := range parts[1:] {
+		key, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "type":
+			tag.typeName = val
+		case "required":
+			tag.required = true
+		case "unique":
+			tag.unique = true
+		case "immutable":
+			tag.immutable = true
+		case "alias":
+			tag.aliases = append(tag.aliases, val)
+		}
+	}
+	return tag, true
+}
+
+// structFieldType picks the field type of a struct field, by its tag or by
+// its Go type.
+func structFieldType(sf reflect.StructField, typeName string) (JFieldType, error) {
+	if typeName != "" {
+		return NewFieldType(typeName, nil)
+	}
+
+	t := sf.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeFor[time.Time]():
+		return &DateTime{}, nil
+	case t.Kind() == reflect.String:
+		return &String{}, nil
+	case t.Kind() == reflect.Bool:
+		return &Boolean{}, nil
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Number{}, nil
+	}
+	return nil, fmt.Errorf("no field type for %s, set one with type=", sf.Type)
+}
+
+// refStructType returns the struct a pointer field refers to.
+func refStructType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Pointer {
+		return nil, false
+	}
+	elem := t.Elem()
+	if elem.Kind() != reflect.Struct || elem == reflect.TypeFor[time.Time]() {
+		return nil, false
+	}
+	return elem, true
+}
+
+func structHasID(t reflect.Type) bool {
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		if tag, ok := parseStructTag(sf); ok && (tag.name == "id" || tag.name == "_id") {
+			return true
+		}
+	}
+	return false
+}
+
+func structSchemaName(t reflect.Type) string {
+	if named, ok := reflect.New(t).Elem().Interface().(interface{ SchemaName() string }); ok {
+		return named.SchemaName()
+	}
+	if named, ok := reflect.New(t).Interface().(interface{ SchemaName() string }); ok {
+		return named.SchemaName()
+	}
+	return snakeCase(t.Name())
+}
+
+// snakeCase converts a Go name to snake_case, keeping initialisms together,
+// e.g. "UserID" to "user_id" and "HTTPServer" to "http_server".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,98 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type structTeam struct {
+	Name  string        `jpack:"name,required"`
+	Owner *structMember `jpack:"owner"`
+}
+
+type structMember struct {
+	ID        string `jpack:"id"`
+	FirstName string `jpack:",required"`
+	Email     string `jpack:"email_address,immutable,unique,alias=email,alias=mail"`
+	Age       int    `jpack:"age"`
+	Active    bool
+	JoinedAt  *time.Time  `jpack:"joined_at"`
+	Team      *structTeam `jpack:"team"`
+	Notes     string      `jpack:"notes,type=string"`
+	Secret    string      `jpack:"-"`
+	internal  string
+}
+
+func (structMember) SchemaName() string { return "test_struct_members" }
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct(&structMember{})
+	assert.NoError(t, err)
+	assert.Equal(t, "test_struct_members", schema.Name())
+
+	t.Run("fields follow the struct", func(t *testing.T) {
+		var names []string
+		for _, field := range schema.Fields() {
+			names = append(names, field.Name())
+		}
+		assert.Equal(t, []string{"id", "first_name", "email_address", "age", "active", "joined_at", "team", "notes"}, names)
+
+		assert.IsType(t, &String{}, mustField(t, schema, "first_name").Type())
+		assert.IsType(t, &Number{}, mustField(t, schema, "age").Type())
+		assert.IsType(t, &Boolean{}, mustField(t, schema, "active").Type())
+		assert.IsType(t, &DateTime{}, mustField(t, schema, "joined_at").Type())
+	})
+
+	t.Run("tag options are applied", func(t *testing.T) {
+		assert.True(t, IsRequired(mustField(t, schema, "first_name")))
+		assert.False(t, IsRequired(mustField(t, schema, "age")))
+		assert.True(t, IsUnique(mustField(t, schema, "email_address")))
+
+		email := mustField(t, schema, "email_address")
+		assert.True(t, IsImmutable(email))
+		assert.Equal(t, []string{"email", "mail"}, FieldAliases(email))
+	})
+
+	t.Run("struct pointers become refs", func(t *testing.T) {
+		team, ok := mustField(t, schema, "team").(JRef)
+		assert.True(t, ok)
+		assert.Equal(t, "struct_team", team.RelSchema().Name())
+
+		// Structs without an id get one
+		_, ok = team.RelSchema().Field("id")
+		assert.True(t, ok)
+
+		owner, ok := mustField(t, team.RelSchema(), "owner").(JRef)
+		assert.True(t, ok)
+		assert.Same(t, schema, owner.RelSchema(), "cycles resolve to the same schema")
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := SchemaFromStruct("not a struct")
+		assert.Error(t, err)
+
+		_, err = SchemaFromStruct(struct {
+			Tags []string
+		}{})
+		assert.ErrorContains(t, err, "[]string")
+
+		_, err = SchemaFromStruct(struct {
+			Name string `jpack:"name,type=does_not_exist"`
+		}{})
+		assert.Error(t, err)
+	})
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":       "name",
+		"FirstName":  "first_name",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"ID":         "id",
+	} {
+		assert.Equal(t, want, snakeCase(name), name)
+	}
+}
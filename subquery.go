@@ -0,0 +1,111 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// InQuery matches records whose field is among the subField values of the
+// subQuery's results, e.g. the users that wrote a published post:
+//
+//	InQuery(userID, NewMongoQuery(ctx, posts).Where(Eq(status, "published")), author)
+//
+// The subquery runs once, when the filter is passed to Query.Where, and its
+// values are inlined into an $in condition; an error fails the outer query.
+// A primary key field matches the stored _id.
+func InQuery(field JField, subQuery Query, subField JField) Filter {
+	return &filterImpl{
+		field:    field,
+		value:    &subqueryValues{query: subQuery, field: subField},
+		operator: "IN QUERY",
+	}
+}
+
+// subqueryValues runs a subquery once and keeps the values of one field.
+type subqueryValues struct {
+	query Query
+	field JField
+
+	once   sync.Once
+	values []any
+	err    error
+}
+
+func (s *subqueryValues) resolve() ([]any, error) {
+	s.once.Do(func() {
+		records, err := s.query.Select(s.field).Execute()
+		if err != nil {
+			s.err = fmt.Errorf("jpack: subquery on %s: %w", s.query.Schema().Name(), err)
+			return
+		}
+
+		seen := make(map[any]bool)
+		s.values = []any{}
+		for _, record := range records {
+			value, ok := record.Value(s.field)
+			if !ok || value == nil {
+				continue
+			}
+			// Eagerly loaded refs hold the referenced record
+			if ref, ok := value.(JRecord); ok {
+				if value, ok = recordID(ref); !ok {
+					continue
+				}
+			}
+			if key := fmt.Sprint(value); !seen[key] {
+				seen[key] = true
+				s.values = append(s.values, value)
+			}
+		}
+	})
+	return s.values, s.err
+}
+
+// resolveSubqueries runs the subqueries of a filter tree.
+func resolveSubqueries(filter Filter) error {
+	if filter == nil {
+		return nil
+	}
+	if sub, ok := filter.Value().(*subqueryValues); ok && filter.Operator() == "IN QUERY" {
+		if _, err := sub.resolve(); err != nil {
+			return err
+		}
+	}
+	if err := resolveSubqueries(filter.Left()); err != nil {
+		return err
+	}
+	return resolveSubqueries(filter.Right())
+}
+
+func init() {
+	RegisterFilterResolver("IN QUERY", func(filter Filter) bson.M {
+		field := filter.Field()
+		sub, ok := filter.Value().(*subqueryValues)
+		if field == nil || !ok {
+			return nil
+		}
+
+		values, err := sub.resolve()
+		if err != nil {
+			// Query.Where reports the error; elsewhere the filter matches nothing
+			ctx := context.Background()
+			if q, ok := sub.query.(*mongoQuery); ok {
+				ctx = q.ctx
+			}
+			LoggerFrom(ctx).Error().Err(err).Str("field", field.Name()).Msg("jpack: subquery failed")
+			values = []any{}
+		}
+
+		if pk, ok := PK(field.Schema()); ok && pk.Name() == field.Name() {
+			ids := make([]string, 0, len(values))
+			for _, value := range values {
+				ids = append(ids, fmt.Sprint(value))
+			}
+			return idsFilter(ids)
+		}
+		return bson.M{field.Name(): bson.M{"$in": values}}
+	})
+}
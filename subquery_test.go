@@ -0,0 +1,53 @@
+package jpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestInQuery(t *testing.T) {
+	posts := NewSchema("test_subquery_posts").
+		Field("id", &String{}).
+		Ref("author", userSchema).
+		Build()
+	author := mustField(t, posts, "author")
+
+	authorA, authorB := bson.NewObjectID(), bson.NewObjectID()
+	post := func(authorID bson.ObjectID) JRecord {
+		record := NewMongoRecord(posts)
+		record.originalRecord = map[string]any{"id": bson.NewObjectID().Hex(), "author": authorID.Hex()}
+		return record
+	}
+	published := &staticQuery{schema: posts, records: []JRecord{post(authorA), post(authorB), post(authorA)}}
+
+	t.Run("primary keys match the stored _id", func(t *testing.T) {
+		q := NewMongoQuery(offlineContext(t), userSchema).
+			Where(InQuery(mustField(t, userSchema, "id"), published, author)).(*mongoQuery)
+
+		assert.NoError(t, q.err)
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"_id": bson.M{"$in": []any{authorA, authorB}}},
+		}}, q.filter())
+	})
+
+	t.Run("other fields match the values", func(t *testing.T) {
+		email := mustField(t, userSchema, "email")
+		emails := &staticQuery{schema: userSchema, records: []JRecord{}}
+		filter := ResolveFilter(InQuery(email, emails, email))
+		assert.Equal(t, bson.M{"email": bson.M{"$in": []any{}}}, filter)
+	})
+
+	t.Run("subquery errors fail the query", func(t *testing.T) {
+		failing := &staticQuery{schema: posts, err: errors.New("boom")}
+		q := NewMongoQuery(offlineContext(t), userSchema).
+			Where(Eq(mustField(t, userSchema, "first_name"), "Ada").And(InQuery(mustField(t, userSchema, "id"), failing, author)))
+
+		_, err := q.Execute()
+		assert.ErrorContains(t, err, "boom")
+		_, err = q.Count()
+		assert.ErrorContains(t, err, "boom")
+	})
+}
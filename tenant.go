@@ -0,0 +1,73 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// tenantKey is the context key holding the tenant set with WithTenant.
+var tenantKey key = "jpack.tenant"
+
+// ErrNoTenant is returned by TenantPolicy when the context has no tenant.
+var ErrNoTenant = errors.New("jpack: no tenant in context")
+
+// WithTenant returns a context acting on behalf of a tenant, for policies
+// such as TenantPolicy.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenant)
+}
+
+// TenantFrom returns the tenant of the context, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok && tenant != ""
+}
+
+// TenantPolicy isolates tenants sharing a collection: queries only match
+// records whose Field holds the context's tenant, and Save rejects records of
+// other tenants. Without a tenant in the context, queries match nothing and
+// Save fails with ErrNoTenant.
+type TenantPolicy struct {
+	// Field names the field holding the tenant.
+	Field string
+}
+
+var _ JPolicy = &TenantPolicy{}
+
+// IsValid implements JPolicy.
+func (p *TenantPolicy) IsValid(ctx context.Context, record JRecord) error {
+	tenant, ok := TenantFrom(ctx)
+	if !ok {
+		return ErrNoTenant
+	}
+	field, ok := record.Schema().Field(p.Field)
+	if !ok {
+		return fmt.Errorf("jpack: unknown tenant field %s", p.Field)
+	}
+	if value, _ := record.Value(field); value != tenant {
+		return fmt.Errorf("jpack: record of tenant %v saved by tenant %s", value, tenant)
+	}
+	return nil
+}
+
+// QueryFilter implements JPolicy.
+func (p *TenantPolicy) QueryFilter(ctx context.Context, schema JSchema) Filter {
+	field, ok := schema.Field(p.Field)
+	tenant, hasTenant := TenantFrom(ctx)
+	if !ok || !hasTenant {
+		return &filterImpl{operator: matchNothingOperator}
+	}
+	return Eq(field, tenant)
+}
+
+// matchNothingOperator is the operator of filters no document matches.
+const matchNothingOperator = "MATCH NOTHING"
+
+func init() {
+	RegisterFilterResolver(matchNothingOperator, func(Filter) bson.M {
+		return matchNothing
+	})
+}
@@ -0,0 +1,42 @@
+package jpack
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestTenantPolicy(t *testing.T) {
+	schema := NewSchema("test_tenant").
+		Field("id", &String{}).
+		Field("tenant_id", &String{}).
+		Policy(&TenantPolicy{Field: "tenant_id"}).
+		Build()
+	tenantField := mustField(t, schema, "tenant_id")
+
+	_, ok := TenantFrom(t.Context())
+	assert.False(t, ok)
+	ctx := WithTenant(offlineContext(t), "acme")
+	tenant, ok := TenantFrom(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenant)
+
+	t.Run("queries match the tenant", func(t *testing.T) {
+		q := NewMongoQuery(ctx, schema).(*mongoQuery)
+		assert.Equal(t, bson.M{"$and": []bson.M{{"tenant_id": "acme"}}}, q.filter())
+
+		q = NewMongoQuery(offlineContext(t), schema).(*mongoQuery)
+		assert.Equal(t, bson.M{"$and": []bson.M{matchNothing}}, q.filter(), "no tenant matches nothing")
+	})
+
+	t.Run("saves are checked", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(tenantField, "other"))
+		assert.ErrorContains(t, record.Save(ctx), "record of tenant other saved by tenant acme")
+
+		err := record.Save(offlineContext(t))
+		assert.True(t, errors.Is(err, ErrNoTenant))
+	})
+}
@@ -0,0 +1,204 @@
+package jpack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Compression is the codec of compressed Text values.
+type Compression string
+
+const (
+	CompressNone Compression = ""
+	CompressGzip Compression = "gzip"
+	CompressZstd Compression = "zstd"
+)
+
+// TextTooLargeError is returned for Text values longer than the field's
+// MaxSize.
+type TextTooLargeError struct {
+	Size    int
+	MaxSize int
+}
+
+func (e *TextTooLargeError) Error() string {
+	return fmt.Sprintf("text of %d bytes exceeds the maximum of %d bytes", e.Size, e.MaxSize)
+}
+
+// Text is a field type for large strings, like descriptions and HTML bodies.
+// Values above CompressAbove bytes are stored compressed, which is invisible
+// to readers of the record, and values above MaxSize are rejected so a single
+// field can't push a document towards MongoDB's 16MB limit. Compressed values
+// can't be filtered or sorted on in queries.
+type Text struct {
+	// Compression is the codec for large values; CompressNone stores every
+	// value as a plain string.
+	Compression Compression
+	// CompressAbove is the size in bytes from which values are compressed;
+	// zero compresses every value. A value is kept plain when compression
+	// doesn't make it smaller.
+	CompressAbove int
+	// MaxSize is the maximum size of a value in bytes before compression;
+	// zero means no limit.
+	MaxSize int
+}
+
+// Scan implements JFieldType.
+func (t *Text) Scan(ctx context.Context, field JField, row map[string]any) (value any, err error) {
+	v, ok := RowValue(field, row)
+	if !ok || v == nil {
+		return nil, nil // No value found, return nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case map[string]any:
+		return decompressText(v)
+	case bson.M:
+		return decompressText(v)
+	case bson.D:
+		doc := make(map[string]any, len(v))
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+		return decompressText(doc)
+	}
+
+	return nil, errors.New("value is not a text")
+}
+
+// SetValue implements JFieldType.
+func (t *Text) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	reflectValue := reflect.ValueOf(value)
+
+	// If the value is nil, set the row field to nil
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		row[field.Name()] = nil
+		return nil
+	}
+
+	if err := t.Validate(value); err != nil {
+		return err
+	}
+	if reflectValue.Kind() == reflect.Pointer {
+		reflectValue = reflectValue.Elem()
+	}
+
+	text := reflectValue.String()
+	if t.Compression == CompressNone || len(text) < t.CompressAbove {
+		row[field.Name()] = text
+		return nil
+	}
+
+	data, err := compressText(t.Compression, text)
+	if err != nil {
+		return err
+	}
+	if len(data) >= len(text) {
+		row[field.Name()] = text
+		return nil
+	}
+
+	row[field.Name()] = bson.M{
+		"codec": string(t.Compression),
+		"data":  bson.Binary{Data: data},
+		"size":  int64(len(text)),
+	}
+	return nil
+}
+
+// Validate implements JFieldType.
+func (t *Text) Validate(value any) error {
+	reflectValue := reflect.ValueOf(value)
+	if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+		return nil // If the value is nil, return nil
+	}
+	if reflectValue.Kind() == reflect.Pointer {
+		reflectValue = reflectValue.Elem()
+	}
+
+	if reflectValue.Kind() != reflect.String {
+		return errors.New("value is not a string")
+	}
+	if t.MaxSize > 0 && reflectValue.Len() > t.MaxSize {
+		return &TextTooLargeError{Size: reflectValue.Len(), MaxSize: t.MaxSize}
+	}
+	return nil
+}
+
+var (
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) { return zstd.NewWriter(nil) })
+	zstdDecoder = sync.OnceValues(func() (*zstd.Decoder, error) { return zstd.NewReader(nil) })
+)
+
+func compressText(codec Compression, text string) ([]byte, error) {
+	switch codec {
+	case CompressZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll([]byte(text), nil), nil
+
+	case CompressGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := io.WriteString(w, text); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return nil, fmt.Errorf("jpack: unknown text compression %q", codec)
+}
+
+// decompressText reads the stored form of a compressed value.
+func decompressText(doc map[string]any) (string, error) {
+	codec, _ := doc["codec"].(string)
+
+	var data []byte
+	switch d := doc["data"].(type) {
+	case bson.Binary:
+		data = d.Data
+	case []byte:
+		data = d
+	default:
+		return "", errors.New("value is not a compressed text")
+	}
+
+	switch Compression(codec) {
+	case CompressZstd:
+		dec, err := zstdDecoder()
+		if err != nil {
+			return "", err
+		}
+		text, err := dec.DecodeAll(data, nil)
+		return string(text), err
+
+	case CompressGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		text, err := io.ReadAll(r)
+		return string(text), err
+	}
+
+	return "", fmt.Errorf("jpack: unknown text compression %q", codec)
+}
+
+var _ JFieldType = &Text{}
@@ -0,0 +1,94 @@
+package jpack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestText(t *testing.T) {
+	ctx := context.Background()
+	body := strings.Repeat("<p>lorem ipsum dolor sit amet</p>", 200)
+
+	for _, compression := range []Compression{CompressGzip, CompressZstd} {
+		t.Run(string(compression), func(t *testing.T) {
+			schema := NewSchema("articles").
+				Field("id", &String{}).
+				Field("body", &Text{Compression: compression, CompressAbove: 1024}).
+				Build()
+			field := mustField(t, schema, "body")
+
+			row := map[string]any{}
+			assert.NoError(t, field.Type().SetValue(ctx, field, body, row))
+			stored, ok := row["body"].(bson.M)
+			assert.True(t, ok, "large values are compressed")
+			assert.Equal(t, string(compression), stored["codec"])
+			assert.Less(t, len(stored["data"].(bson.Binary).Data), len(body))
+
+			value, err := field.Type().Scan(ctx, field, row)
+			assert.NoError(t, err)
+			assert.Equal(t, body, value)
+
+			// Values read back from MongoDB are bson.D
+			value, err = field.Type().Scan(ctx, field, map[string]any{"body": bson.D{
+				{Key: "codec", Value: stored["codec"]},
+				{Key: "data", Value: stored["data"]},
+				{Key: "size", Value: stored["size"]},
+			}})
+			assert.NoError(t, err)
+			assert.Equal(t, body, value)
+
+			assert.NoError(t, field.Type().SetValue(ctx, field, "short", row))
+			assert.Equal(t, "short", row["body"])
+		})
+	}
+
+	t.Run("loaded records hold the text", func(t *testing.T) {
+		schema := NewSchema("articles").
+			Field("id", &String{}).
+			Field("body", &Text{Compression: CompressZstd}).
+			Build()
+		field := mustField(t, schema, "body")
+
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(field, body))
+		doc, err := record.ToBSON(ctx)
+		assert.NoError(t, err)
+		assert.IsType(t, bson.M{}, doc["body"])
+
+		loaded := NewMongoRecord(schema)
+		assert.NoError(t, loaded.loadDocument(ctx, doc))
+		value, ok := loaded.Value(field)
+		assert.True(t, ok)
+		assert.Equal(t, body, value)
+	})
+
+	t.Run("incompressible values stay plain", func(t *testing.T) {
+		text := &Text{Compression: CompressGzip}
+		field := &fieldImpl{name: "body", fType: text}
+		row := map[string]any{}
+		assert.NoError(t, text.SetValue(ctx, field, "abc", row))
+		assert.Equal(t, "abc", row["body"])
+	})
+
+	t.Run("max size", func(t *testing.T) {
+		text := &Text{MaxSize: 10}
+		assert.NoError(t, text.Validate("0123456789"))
+
+		var tooLarge *TextTooLargeError
+		assert.ErrorAs(t, text.Validate("0123456789a"), &tooLarge)
+		assert.Equal(t, 11, tooLarge.Size)
+		assert.Error(t, text.Validate(42))
+		assert.NoError(t, text.Validate(nil))
+	})
+
+	t.Run("registry", func(t *testing.T) {
+		fType, err := NewFieldType("text", map[string]any{"compression": "zstd", "compress_above": 512, "max_size": 1 << 20})
+		assert.NoError(t, err)
+		assert.Equal(t, &Text{Compression: CompressZstd, CompressAbove: 512, MaxSize: 1 << 20}, fType)
+		assert.Equal(t, "text", FieldTypeName(fType))
+	})
+}
@@ -0,0 +1,194 @@
+package jpack
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// BucketUnit is the unit of an Interval.
+type BucketUnit string
+
+const (
+	BucketSecond BucketUnit = "second"
+	BucketMinute BucketUnit = "minute"
+	BucketHour   BucketUnit = "hour"
+	BucketDay    BucketUnit = "day"
+	BucketWeek   BucketUnit = "week"
+	BucketMonth  BucketUnit = "month"
+)
+
+// Interval is the width of time buckets: BinSize units, truncated the way
+// MongoDB's $dateTrunc does, in UTC.
+type Interval struct {
+	Unit    BucketUnit
+	BinSize int
+}
+
+var (
+	Hourly = Interval{Unit: BucketHour, BinSize: 1}
+	Daily  = Interval{Unit: BucketDay, BinSize: 1}
+	Weekly = Interval{Unit: BucketWeek, BinSize: 1}
+)
+
+// Every returns an interval of n units, e.g. Every(5, BucketMinute).
+func Every(n int, unit BucketUnit) Interval {
+	return Interval{Unit: unit, BinSize: n}
+}
+
+// TimeBucket is one bucket of a BucketQuery.
+type TimeBucket struct {
+	Start time.Time
+	// Meta is the value of the ByMeta field, nil without one.
+	Meta  any
+	Count int64
+	// Values holds the aggregates by name, e.g. "avg_latency".
+	Values map[string]any
+}
+
+// BucketQuery groups the records of a query into time buckets.
+type BucketQuery struct {
+	query    Query
+	field    JField
+	interval Interval
+
+	meta       JField
+	aggregates bson.M
+}
+
+// TimeBuckets groups the records matched by q by the time in field, truncated
+// to the interval, e.g. 5-minute buckets of a metrics schema:
+//
+//	buckets, err := jpack.TimeBuckets(
+//		jpack.NewMongoQuery(ctx, metrics).Where(jpack.Gte(at, since)),
+//		at, jpack.Every(5, jpack.BucketMinute),
+//	).ByMeta(host).Avg(latency).Execute()
+//
+// Buckets are computed by the database and ordered by start time and meta
+// value. Buckets without records are left out. It needs MongoDB 5.0. See
+// Query.BucketBy.
+func TimeBuckets(q Query, field JField, interval Interval) *BucketQuery {
+	interval.BinSize = max(interval.BinSize, 1)
+	return &BucketQuery{query: q, field: field, interval: interval, aggregates: bson.M{}}
+}
+
+// ByMeta buckets each value of the field, e.g. the meta field of a
+// time-series schema, separately.
+func (b *BucketQuery) ByMeta(field JField) *BucketQuery {
+	b.meta = field
+	return b
+}
+
+// Sum adds the sum of a field to the buckets, as "sum_<field>".
+func (b *BucketQuery) Sum(field JField) *BucketQuery {
+	return b.aggregate("sum", field)
+}
+
+// Avg adds the average of a field to the buckets, as "avg_<field>".
+func (b *BucketQuery) Avg(field JField) *BucketQuery {
+	return b.aggregate("avg", field)
+}
+
+// Min adds the minimum of a field to the buckets, as "min_<field>".
+func (b *BucketQuery) Min(field JField) *BucketQuery {
+	return b.aggregate("min", field)
+}
+
+// Max adds the maximum of a field to the buckets, as "max_<field>".
+func (b *BucketQuery) Max(field JField) *BucketQuery {
+	return b.aggregate("max", field)
+}
+
+func (b *BucketQuery) aggregate(op string, field JField) *BucketQuery {
+	b.aggregates[op+"_"+field.Name()] = bson.M{"$" + op: "$" + storageKeys(field)[0]}
+	return b
+}
+
+// pipeline returns the aggregation pipeline of the buckets.
+func (b *BucketQuery) pipeline(filter bson.M) []bson.M {
+	id := bson.M{
+		"start": bson.M{"$dateTrunc": bson.M{"date": "$" + storageKeys(b.field)[0], "unit": string(b.interval.Unit), "binSize": b.interval.BinSize}},
+	}
+	if b.meta != nil {
+		id["meta"] = "$" + storageKeys(b.meta)[0]
+	}
+
+	group := bson.M{"_id": id, "count": bson.M{"$sum": 1}}
+	for name, expr := range b.aggregates {
+		group[name] = expr
+	}
+
+	return []bson.M{
+		{"$match": filter},
+		{"$group": group},
+		{"$sort": bson.D{{Key: "_id.start", Value: 1}, {Key: "_id.meta", Value: 1}}},
+	}
+}
+
+// Execute runs the aggregation.
+func (b *BucketQuery) Execute() ([]TimeBucket, error) {
+	var q *mongoQuery
+	var filter bson.M
+	switch t := b.query.(type) {
+	case *mongoQuery:
+		if t.err != nil {
+			return nil, t.err
+		}
+		q, filter = t, t.filter()
+	case *unionQuery:
+		var err error
+		if q, filter, err = t.filter(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("jpack: cannot bucket a %T", b.query)
+	}
+
+	pipeline := b.pipeline(filter)
+	start := time.Now()
+	cursor, err := q.collection.Aggregate(q.ctx, pipeline)
+	op := QueryOperation{Collection: q.collection.Name(), Operation: "aggregate", Filter: filter, Options: bson.M{"pipeline": pipeline}}
+	if err != nil {
+		op.Err = err
+		tapQuery(q.ctx, op, start)
+		return nil, err
+	}
+	defer cursor.Close(q.ctx)
+
+	var docs []bson.M
+	err = cursor.All(q.ctx, &docs)
+	op.Count, op.Err = int64(len(docs)), err
+	tapQuery(q.ctx, op, start)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]TimeBucket, 0, len(docs))
+	for _, doc := range docs {
+		buckets = append(buckets, toTimeBucket(doc))
+	}
+	return buckets, nil
+}
+
+func toTimeBucket(doc bson.M) TimeBucket {
+	bucket := TimeBucket{Values: make(map[string]any)}
+	if id, ok := doc["_id"].(bson.M); ok {
+		if start, ok := id["start"].(bson.DateTime); ok {
+			bucket.Start = start.Time().UTC()
+		}
+		bucket.Meta = id["meta"]
+	}
+	for key, value := range doc {
+		switch key {
+		case "_id":
+		case "count":
+			if count, ok := toFloat(value); ok {
+				bucket.Count = int64(count)
+			}
+		default:
+			bucket.Values[key] = value
+		}
+	}
+	return bucket
+}
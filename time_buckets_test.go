@@ -0,0 +1,62 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestTimeBuckets(t *testing.T) {
+	ctx := offlineContext(t)
+	metrics := metricsSchema()
+	at := mustField(t, metrics, "at")
+	host := mustField(t, metrics, "host")
+	latency := mustField(t, metrics, "latency")
+
+	b := NewMongoQuery(ctx, metrics).BucketBy(at, Every(5, BucketMinute)).ByMeta(host).Avg(latency).Max(latency)
+	assert.Equal(t, []bson.M{
+		{"$match": bson.M{}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"start": bson.M{"$dateTrunc": bson.M{"date": "$at", "unit": "minute", "binSize": 5}},
+				"meta":  "$host",
+			},
+			"count":       bson.M{"$sum": 1},
+			"avg_latency": bson.M{"$avg": "$latency"},
+			"max_latency": bson.M{"$max": "$latency"},
+		}},
+		{"$sort": bson.D{{Key: "_id.start", Value: 1}, {Key: "_id.meta", Value: 1}}},
+	}, b.pipeline(bson.M{}))
+
+	start := time.Date(2024, 7, 1, 12, 5, 0, 0, time.UTC)
+	bucket := toTimeBucket(bson.M{
+		"_id":         bson.M{"start": bson.NewDateTimeFromTime(start), "meta": "web-1"},
+		"count":       int32(3),
+		"avg_latency": 12.5,
+	})
+	assert.Equal(t, TimeBucket{Start: start, Meta: "web-1", Count: 3, Values: map[string]any{"avg_latency": 12.5}}, bucket)
+
+	t.Run("union", func(t *testing.T) {
+		u := Union(
+			NewMongoQuery(ctx, metrics).Where(Eq(host, "web-1")),
+			NewMongoQuery(ctx, metrics).Where(Eq(host, "web-2")),
+		).(*unionQuery)
+		_, filter, err := u.filter()
+		assert.NoError(t, err)
+		assert.Len(t, filter["$or"], 2)
+
+		_, err = Union().BucketBy(at, Daily).Execute()
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported query", func(t *testing.T) {
+		_, err := TimeBuckets(&staticQuery{schema: metrics}, at, Hourly).Execute()
+		assert.ErrorContains(t, err, "cannot bucket")
+	})
+
+	t.Run("bin size", func(t *testing.T) {
+		assert.Equal(t, Interval{Unit: BucketDay, BinSize: 1}, TimeBuckets(nil, at, Every(0, BucketDay)).interval)
+	})
+}
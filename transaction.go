@@ -0,0 +1,95 @@
+package jpack
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// commitQueueKey is the context key holding the commitQueue of a
+// transaction run by transaction.
+var commitQueueKey key = "jpack.commitqueue"
+
+// commitQueue holds the side effects of a transaction's writes, such as
+// webhook deliveries, until it commits.
+type commitQueue struct {
+	mu  sync.Mutex
+	fns []func(ctx context.Context)
+}
+
+// add queues fn.
+func (q *commitQueue) add(fn func(ctx context.Context)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.fns = append(q.fns, fn)
+}
+
+// run runs the queued functions in order with ctx.
+func (q *commitQueue) run(ctx context.Context) {
+	q.mu.Lock()
+	fns := q.fns
+	q.fns = nil
+	q.mu.Unlock()
+
+	for _, fn := range fns {
+		fn(ctx)
+	}
+}
+
+// transaction runs fn in a MongoDB transaction of the context's client,
+// running it again when the driver retries the transaction on transient
+// errors. Side effects announcing writes, such as webhooks, are held until
+// the transaction commits; those of aborted attempts are dropped.
+//
+// Records saved by an aborted attempt are already marked clean when fn runs
+// again, so fn must restore their state first, as UnitOfWork.Flush does.
+// Transactions require MongoDB to run as a replica set.
+func transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := MustConn(ctx).Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	var queue *commitQueue
+	_, err = session.WithTransaction(ctx, func(ctx context.Context) (any, error) {
+		queue = &commitQueue{}
+		return nil, fn(context.WithValue(ctx, commitQueueKey, queue))
+	})
+	if err != nil {
+		return err
+	}
+
+	// The session ended, so side effects run outside of it
+	queue.run(ctx)
+	return nil
+}
+
+// inTransaction reports whether the writes of ctx run in a transaction,
+// whether or not it was started by transaction.
+func inTransaction(ctx context.Context) bool {
+	if _, ok := ctx.Value(commitQueueKey).(*commitQueue); ok {
+		return true
+	}
+	session := mongo.SessionFromContext(ctx)
+	return session != nil && session.ClientSession().TransactionRunning()
+}
+
+// afterCommit runs fn once the transaction of ctx commits, with the context
+// transaction was called with, or right away with ctx outside transactions.
+// It reports false, without running fn, within a transaction not run by
+// transaction, whose commit it can't observe.
+func afterCommit(ctx context.Context, fn func(ctx context.Context)) bool {
+	if queue, ok := ctx.Value(commitQueueKey).(*commitQueue); ok {
+		queue.add(fn)
+		return true
+	}
+	if inTransaction(ctx) {
+		return false
+	}
+
+	fn(ctx)
+	return true
+}
@@ -0,0 +1,173 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestAfterCommit(t *testing.T) {
+	t.Run("runs right away outside transactions", func(t *testing.T) {
+		ran := false
+		assert.True(t, afterCommit(context.Background(), func(context.Context) { ran = true }))
+		assert.True(t, ran)
+	})
+
+	t.Run("waits for the commit of a Transaction", func(t *testing.T) {
+		queue := &commitQueue{}
+		ctx := context.WithValue(context.Background(), commitQueueKey, queue)
+
+		var order []int
+		assert.True(t, afterCommit(ctx, func(context.Context) { order = append(order, 1) }))
+		assert.True(t, afterCommit(ctx, func(context.Context) { order = append(order, 2) }))
+		assert.Empty(t, order)
+
+		queue.run(context.Background())
+		assert.Equal(t, []int{1, 2}, order)
+		queue.run(context.Background())
+		assert.Len(t, order, 2, "queued functions run once")
+	})
+
+	t.Run("refuses transactions it can't observe", func(t *testing.T) {
+		ctx := offlineContext(t)
+		session, err := MustConn(ctx).Client().StartSession()
+		assert.NoError(t, err)
+		defer session.EndSession(ctx)
+		assert.NoError(t, session.StartTransaction())
+
+		ran := false
+		assert.False(t, afterCommit(mongo.NewSessionContext(ctx, session), func(context.Context) { ran = true }))
+		assert.False(t, ran)
+	})
+}
+
+func TestWebhooksWaitForCommit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	dispatcher := NewWebhookDispatcher()
+	dispatcher.Log = &memoryDeliveryLog{}
+	dispatcher.Register(userSchema, Webhook{URL: server.URL})
+	SetWebhookDispatcher(dispatcher)
+	defer SetWebhookDispatcher(nil)
+
+	record, err := RecordFromBSON(userSchema, bson.M{"_id": bson.NewObjectID(), "first_name": "Ada"})
+	assert.NoError(t, err)
+
+	queue := &commitQueue{}
+	ctx := context.WithValue(context.Background(), commitQueueKey, queue)
+	dispatchWebhooks(ctx, record, ChangeUpdate)
+	dispatcher.Wait()
+	assert.Zero(t, calls.Load(), "nothing is sent before the commit")
+
+	queue.run(context.Background())
+	dispatcher.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestSearchSyncWaitsForCommit(t *testing.T) {
+	schema := NewSchema("search_tx_article").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Build()
+
+	backend := &memorySearchBackend{docs: map[string]map[string]any{}}
+	RegisterSearchIndex(SearchIndex{Schema: schema, Backend: backend, SyncOnWrite: true})
+
+	id := bson.NewObjectID()
+	record, err := RecordFromBSON(schema, bson.M{"_id": id, "title": "Hello"})
+	assert.NoError(t, err)
+
+	queue := &commitQueue{}
+	ctx := context.WithValue(context.Background(), commitQueueKey, queue)
+	syncSearchOnSave(ctx, record)
+	assert.Empty(t, backend.docs, "nothing is indexed before the commit")
+
+	queue.run(context.Background())
+	assert.Equal(t, map[string]any{"title": "Hello"}, backend.docs["search_tx_article/"+id.Hex()])
+
+	syncSearchOnDelete(ctx, record)
+	assert.Len(t, backend.docs, 1, "nothing is removed before the commit")
+
+	queue.run(context.Background())
+	assert.Empty(t, backend.docs)
+}
+
+func TestMaterializedViewsRefreshAfterCommit(t *testing.T) {
+	orders := NewSchema("test_mv_tx_orders").
+		Field("id", &String{}).
+		Field("total", &Number{}).
+		Build()
+	revenue := NewSchema("test_mv_tx_revenue").
+		Field("id", &String{}).
+		Field("total", &Number{}).
+		Build()
+	assert.NoError(t, RegisterMaterializedView(MaterializedView{
+		Target:   revenue,
+		Source:   orders,
+		Pipeline: []bson.M{{"$group": bson.M{"_id": nil, "total": bson.M{"$sum": "$total"}}}},
+		Strategy: RefreshOnWrite,
+	}))
+	defer UnregisterMaterializedView(revenue)
+
+	// Read-only contexts refuse refreshes before reaching the server, so the
+	// logged refusal shows when a refresh ran
+	var out bytes.Buffer
+	logger := zerolog.New(&out)
+	committed := WithLogger(WithReadOnly(offlineContext(t)), &logger)
+
+	record, err := RecordFromBSON(orders, bson.M{"_id": bson.NewObjectID(), "total": 5})
+	assert.NoError(t, err)
+
+	queue := &commitQueue{}
+	record.(*mongoRecord).afterWrite(context.WithValue(committed, commitQueueKey, queue), ChangeInsert)
+	assert.Empty(t, out.String(), "nothing is refreshed within the transaction")
+
+	queue.run(committed)
+	assert.Contains(t, out.String(), "jpack: failed to refresh materialized view")
+	assert.Contains(t, out.String(), ErrReadOnly.Error())
+}
+
+func TestQueryCacheInvalidatesAfterCommit(t *testing.T) {
+	cache := NewMemoryQueryCache()
+	SetQueryCache(cache)
+	defer SetQueryCache(NewMemoryQueryCache())
+
+	queue := &commitQueue{}
+	ctx := context.WithValue(context.Background(), commitQueueKey, queue)
+	cache.Set("before", 1, time.Minute, []string{userSchema.Name()})
+	invalidateOnWrite(ctx, userSchema)
+	_, ok := cache.Get("before")
+	assert.False(t, ok, "the write invalidates right away")
+
+	// A query run before the commit caches the replaced documents
+	cache.Set("between", 2, time.Minute, []string{userSchema.Name()})
+	queue.run(context.Background())
+	_, ok = cache.Get("between")
+	assert.False(t, ok, "the commit invalidates again")
+
+	t.Run("warns within transactions it can't observe", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := zerolog.New(&out)
+		ctx := WithLogger(offlineContext(t), &logger)
+		session, err := MustConn(ctx).Client().StartSession()
+		assert.NoError(t, err)
+		defer session.EndSession(ctx)
+		assert.NoError(t, session.StartTransaction())
+
+		invalidateOnWrite(mongo.NewSessionContext(ctx, session), userSchema)
+		assert.Contains(t, out.String(), "query cache isn't invalidated on commit")
+	})
+}
@@ -0,0 +1,176 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Traversal follows edges from a set of records, one query per hop: each hop
+// loads the records connected to all records of the previous level at once,
+// so multi-hop relationship queries don't turn into N+1 loops.
+//
+//	authors, err := jpack.Traverse(ctx, user).
+//		Out(followsEdge).
+//		Out(postsEdge).
+//		Where(jpack.Eq(status, "published")).
+//		Execute()
+type Traversal struct {
+	ctx    context.Context
+	start  []JRecord
+	schema JSchema
+	hops   []traversalHop
+
+	// err is an edge that doesn't start at the previous level
+	err error
+}
+
+type traversalHop struct {
+	edge    JEdge
+	forward bool
+	filters []Filter
+}
+
+// Traverse starts a traversal at the given records, which must share a
+// schema.
+func Traverse(ctx context.Context, start ...JRecord) *Traversal {
+	t := &Traversal{ctx: ctx, start: start}
+	for _, record := range start {
+		if t.schema == nil {
+			t.schema = record.Schema()
+		} else if !sameStorage(t.schema, record.Schema()) {
+			t.err = fmt.Errorf("jpack: traversal starts at records of %s and %s", t.schema.Name(), record.Schema().Name())
+		}
+	}
+	return t
+}
+
+// Out follows an edge from the records of the previous level. An edge whose
+// ref is a field of its source, e.g. a post's author, loads the records the
+// refs point to; an edge whose ref is a field of its target, e.g. a user's
+// posts, loads the records referring to the previous level.
+func (t *Traversal) Out(edge JEdge) *Traversal {
+	if t.err != nil {
+		return t
+	}
+
+	ref := edge.Ref()
+	if ref == nil {
+		t.err = fmt.Errorf("jpack: edge %s has no ref", edge.Name())
+		return t
+	}
+
+	// Self-referencing edges follow the ref, from a record to its parent
+	hop := traversalHop{edge: edge, forward: sameStorage(ref.RelSchema(), edge.Schema())}
+	source := ref.Schema()
+	if !hop.forward {
+		source = ref.RelSchema()
+		if !sameStorage(ref.Schema(), edge.Schema()) {
+			t.err = fmt.Errorf("jpack: ref %s of edge %s joins neither end of it", ref.Name(), edge.Name())
+			return t
+		}
+	}
+	if t.schema != nil && !sameStorage(source, t.schema) {
+		t.err = fmt.Errorf("jpack: edge %s does not start at %s", edge.Name(), t.schema.Name())
+		return t
+	}
+
+	t.hops = append(t.hops, hop)
+	t.schema = edge.Schema()
+	return t
+}
+
+// Where filters the records reached by the last hop. Records filtered out
+// are not followed any further.
+func (t *Traversal) Where(filter Filter) *Traversal {
+	if t.err != nil {
+		return t
+	}
+	if len(t.hops) == 0 {
+		t.err = errors.New("jpack: Where must follow an Out")
+		return t
+	}
+
+	t.hops[len(t.hops)-1].filters = append(t.hops[len(t.hops)-1].filters, filter)
+	return t
+}
+
+// Execute runs the traversal and returns the distinct records of the last
+// level, or the start records when there are no hops.
+func (t *Traversal) Execute() ([]JRecord, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	level := t.start
+	for _, hop := range t.hops {
+		if len(level) == 0 {
+			return []JRecord{}, nil
+		}
+
+		q, ok := hop.query(t.ctx, level)
+		if !ok {
+			return []JRecord{}, nil
+		}
+
+		var err error
+		if level, err = q.Execute(); err != nil {
+			return nil, fmt.Errorf("jpack: traversing %s: %w", hop.edge.Name(), err)
+		}
+	}
+	return level, nil
+}
+
+// query returns the query loading the records a hop reaches from level, or
+// false when no record of level has any.
+func (h traversalHop) query(ctx context.Context, level []JRecord) (Query, bool) {
+	ref := h.edge.Ref()
+	q := NewMongoQuery(ctx, h.edge.Schema())
+
+	if h.forward {
+		ids := make([]string, 0, len(level))
+		seen := make(map[string]bool)
+		for _, record := range level {
+			value, ok := record.Value(ref)
+			if !ok || value == nil {
+				continue
+			}
+			// Eagerly loaded refs hold the referenced record
+			var id string
+			switch v := value.(type) {
+			case JRecord:
+				if id, ok = recordID(v); !ok {
+					continue
+				}
+			default:
+				id = fmt.Sprint(v)
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, false
+		}
+
+		mq := q.(*mongoQuery)
+		mq.where = append(mq.where, idsFilter(ids))
+	} else {
+		ids := make([]any, 0, len(level))
+		for _, record := range level {
+			if id, ok := recordID(record); ok {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return nil, false
+		}
+		q = q.Where(In(ref, ids))
+	}
+
+	for _, filter := range h.filters {
+		q = q.Where(filter)
+	}
+	return q, true
+}
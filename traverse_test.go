@@ -0,0 +1,99 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestTraverse(t *testing.T) {
+	ctx := offlineContext(t)
+
+	authors := NewSchema("test_traverse_author").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Build()
+	posts := NewSchema("test_traverse_post").
+		Field("id", &String{}).
+		Field("status", &String{}).
+		Ref("author", authors).
+		Build()
+	authorRef := mustField(t, posts, "author").(JRef)
+	status := mustField(t, posts, "status")
+
+	// Declared the way schemas would: a post's author and an author's posts
+	authorEdge := NewEdge("author", authors, authorRef)
+	postsEdge := NewEdge("posts", posts, authorRef)
+
+	newRecord := func(schema JSchema, values map[string]any) *mongoRecord {
+		record := NewMongoRecord(schema)
+		for name, value := range values {
+			assert.NoError(t, record.SetValue(mustField(t, schema, name), value))
+		}
+		return record
+	}
+	ada := newRecord(authors, map[string]any{"id": bson.NewObjectID().Hex(), "name": "Ada"})
+	grace := newRecord(authors, map[string]any{"id": bson.NewObjectID().Hex(), "name": "Grace"})
+
+	t.Run("reverse hops match the refs of the target", func(t *testing.T) {
+		traversal := Traverse(ctx, ada, grace).Out(postsEdge).Where(Eq(status, "published"))
+		assert.NoError(t, traversal.err)
+
+		q, ok := traversal.hops[0].query(ctx, traversal.start)
+		assert.True(t, ok)
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"author": bson.M{"$in": []any{mustID(t, ada), mustID(t, grace)}}},
+			{"status": "published"},
+		}}, q.(*mongoQuery).filter())
+	})
+
+	t.Run("forward hops load the referenced records once", func(t *testing.T) {
+		first := newRecord(posts, map[string]any{"author": ada})
+		second := newRecord(posts, map[string]any{"author": mustID(t, ada)})
+		orphan := newRecord(posts, map[string]any{"status": "draft"})
+
+		hop := Traverse(ctx, first).Out(authorEdge).hops[0]
+		q, ok := hop.query(ctx, []JRecord{first, second, orphan})
+		assert.True(t, ok)
+		objID, _ := bson.ObjectIDFromHex(mustID(t, ada))
+		assert.Equal(t, bson.M{"$and": []bson.M{
+			{"_id": bson.M{"$in": []any{objID}}},
+		}}, q.(*mongoQuery).filter())
+
+		_, ok = hop.query(ctx, []JRecord{orphan})
+		assert.False(t, ok, "records without refs lead nowhere")
+	})
+
+	t.Run("levels without records end the traversal", func(t *testing.T) {
+		records, err := Traverse(ctx).Out(postsEdge).Out(authorEdge).Execute()
+		assert.NoError(t, err)
+		assert.Empty(t, records)
+
+		records, err = Traverse(ctx, ada).Execute()
+		assert.NoError(t, err)
+		assert.Equal(t, []JRecord{ada}, records)
+	})
+
+	t.Run("edges must start at the previous level", func(t *testing.T) {
+		_, err := Traverse(ctx, ada).Out(authorEdge).Execute()
+		assert.ErrorContains(t, err, "does not start at test_traverse_author")
+
+		_, err = Traverse(ctx, ada).Where(Eq(status, "published")).Execute()
+		assert.ErrorContains(t, err, "Where must follow an Out")
+
+		_, err = Traverse(ctx, ada, newRecord(posts, nil)).Execute()
+		assert.Error(t, err)
+
+		unrelated := NewEdge("users", userSchema, authorRef)
+		_, err = Traverse(ctx, ada).Out(unrelated).Execute()
+		assert.ErrorContains(t, err, "joins neither end")
+	})
+}
+
+func mustID(t *testing.T, record JRecord) string {
+	t.Helper()
+	id, ok := recordID(record)
+	assert.True(t, ok)
+	return id
+}
@@ -0,0 +1,255 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// ErrTreeCycle is returned by Save when the parent of a record is the record
+// itself or one of its descendants.
+var ErrTreeCycle = errors.New("jpack: parent would make the tree a cycle")
+
+// treeIDKey holds the id of a tree node as a hex string, the way refs store
+// it, so $graphLookup can connect parent refs to the nodes they point to.
+const treeIDKey = "_tree_id"
+
+const treeDepthKey = "_tree_depth"
+
+// ParentRef adds a ref to the parent record of the same schema, making the
+// schema a tree, e.g. categories or an org chart. Roots have no parent. Saves
+// that would make a record its own ancestor fail with ErrTreeCycle.
+func (s *SchemaBuilder) ParentRef(name string, opts ...FieldOption) *SchemaBuilder {
+	s.Ref(name, s.schema, opts...)
+	s.schema.parentRef = name
+	return s
+}
+
+// ParentRef returns the schema's parent ref, if it is a tree.
+func (s *schemaImpl) ParentRef() (JRef, bool) {
+	if s.parentRef == "" {
+		return nil, false
+	}
+	field, ok := s.Field(s.parentRef)
+	if !ok {
+		return nil, false
+	}
+	ref, ok := field.(JRef)
+	return ref, ok
+}
+
+// ParentRefOf returns the parent ref of a tree schema, or of the base schema
+// of a view.
+func ParentRefOf(schema JSchema) (JRef, bool) {
+	if s, ok := storageSchema(schema).(interface{ ParentRef() (JRef, bool) }); ok {
+		return s.ParentRef()
+	}
+	return nil, false
+}
+
+// Ancestors returns the ancestors of a saved record, its parent first and the
+// root last.
+func Ancestors(ctx context.Context, record JRecord) ([]JRecord, error) {
+	parent, id, err := treeNode(record)
+	if err != nil {
+		return nil, err
+	}
+	return treeLookup(ctx, record.Schema(), ancestorsPipeline(collection(ctx, record.Schema()).Name(), parent, id, treeFilter(ctx, record.Schema())))
+}
+
+// Descendants returns the descendants of a saved record level by level, its
+// children first, down to depth levels below it. A depth of 0 or less
+// returns the whole subtree.
+func Descendants(ctx context.Context, record JRecord, depth int) ([]JRecord, error) {
+	parent, id, err := treeNode(record)
+	if err != nil {
+		return nil, err
+	}
+	return treeLookup(ctx, record.Schema(), descendantsPipeline(collection(ctx, record.Schema()).Name(), parent, id, depth, treeFilter(ctx, record.Schema())))
+}
+
+func treeNode(record JRecord) (JRef, bson.ObjectID, error) {
+	parent, ok := ParentRefOf(record.Schema())
+	if !ok {
+		return nil, bson.ObjectID{}, fmt.Errorf("jpack: %s has no parent ref", record.Schema().Name())
+	}
+
+	id, ok := recordID(record)
+	if !ok {
+		return nil, bson.ObjectID{}, errors.New("record id can't be empty")
+	}
+	objID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, bson.ObjectID{}, errors.Join(errors.New("failed to convert record id to ObjectID"), err)
+	}
+	return parent, objID, nil
+}
+
+// treeFilter returns the policy filters of the schema, which restrict the
+// nodes a lookup walks through.
+func treeFilter(ctx context.Context, schema JSchema) bson.M {
+	return NewMongoQuery(ctx, schema).(*mongoQuery).filter()
+}
+
+func ancestorsPipeline(coll string, parent JRef, id bson.ObjectID, filter bson.M) []bson.M {
+	return treePipeline(id, bson.M{
+		"from":             coll,
+		"startWith":        "$" + parent.Name(),
+		"connectFromField": parent.Name(),
+		"connectToField":   treeIDKey,
+		"as":               "_tree",
+		"depthField":       treeDepthKey,
+	}, filter)
+}
+
+func descendantsPipeline(coll string, parent JRef, id bson.ObjectID, depth int, filter bson.M) []bson.M {
+	lookup := bson.M{
+		"from": coll,
+		// Roots saved before the schema was a tree may lack treeIDKey
+		"startWith":        bson.M{"$toString": "$_id"},
+		"connectFromField": treeIDKey,
+		"connectToField":   parent.Name(),
+		"as":               "_tree",
+		"depthField":       treeDepthKey,
+	}
+	if depth > 0 {
+		lookup["maxDepth"] = depth - 1
+	}
+	return treePipeline(id, lookup, filter)
+}
+
+func treePipeline(id bson.ObjectID, lookup, filter bson.M) []bson.M {
+	if len(filter) > 0 {
+		lookup["restrictSearchWithMatch"] = filter
+	}
+	return []bson.M{
+		{"$match": bson.M{defaultMongoPK: id}},
+		{"$graphLookup": lookup},
+		{"$unwind": "$_tree"},
+		{"$replaceRoot": bson.M{"newRoot": "$_tree"}},
+		{"$sort": bson.D{{Key: treeDepthKey, Value: 1}, {Key: defaultMongoPK, Value: 1}}},
+	}
+}
+
+// treeLookup runs a tree pipeline and loads the nodes it returns.
+func treeLookup(ctx context.Context, schema JSchema, pipeline []bson.M) ([]JRecord, error) {
+	docs, err := treeAggregate(ctx, schema, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	q := NewMongoQuery(ctx, schema).(*mongoQuery)
+	records := make([]JRecord, 0, len(docs))
+	for _, doc := range docs {
+		delete(doc, treeDepthKey)
+		record := NewMongoRecord(schema)
+		if err := record.loadDocument(ctx, maps.Clone(doc)); err != nil {
+			return nil, err
+		}
+		records = append(records, q.track(record))
+	}
+	return records, nil
+}
+
+func treeAggregate(ctx context.Context, schema JSchema, pipeline []bson.M) ([]bson.M, error) {
+	coll := collection(ctx, schema)
+	op := QueryOperation{Collection: coll.Name(), Operation: "aggregate", Filter: pipeline[0]["$match"].(bson.M), Options: bson.M{"pipeline": pipeline}}
+	start := time.Now()
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		op.Err = err
+		tapQuery(ctx, op, start)
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []bson.M
+	err = cursor.All(ctx, &docs)
+	op.Count, op.Err = int64(len(docs)), err
+	tapQuery(ctx, op, start)
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// setTreeID stores the id of a new tree node under treeIDKey.
+func setTreeID(schema JSchema, doc bson.M) {
+	if _, ok := ParentRefOf(schema); !ok {
+		return
+	}
+	if objID, ok := doc[defaultMongoPK].(bson.ObjectID); ok {
+		doc[treeIDKey] = objID.Hex()
+	}
+}
+
+// checkTreeCycle rejects a changed parent that is the record itself or one of
+// its descendants. Stored nodes are checked regardless of policies.
+func checkTreeCycle(ctx context.Context, m *mongoRecord) error {
+	parent, ok := ParentRefOf(m.schema)
+	if !ok || !slices.Contains(m.DirtyKeys(), parent.Name()) {
+		return nil
+	}
+
+	value := m.record[parent.Name()]
+	if isUnset(value) {
+		return nil
+	}
+	row := map[string]any{}
+	if err := parent.Type().SetValue(ctx, parent, value, row); err != nil {
+		return err
+	}
+	parentID, _ := row[parent.Name()].(string)
+	id, hasID := recordID(m)
+	if parentID == "" || !hasID {
+		return nil
+	}
+	if parentID == id {
+		return ErrTreeCycle
+	}
+	if m.IsNew() {
+		return nil
+	}
+
+	parentObjID, err := bson.ObjectIDFromHex(parentID)
+	if err != nil {
+		return nil
+	}
+
+	// The record must not be among the ancestors of its new parent
+	ancestors, err := treeAggregate(ctx, m.schema, ancestorsPipeline(collection(ctx, m.schema).Name(), parent, parentObjID, nil))
+	if err != nil {
+		return err
+	}
+	for _, doc := range ancestors {
+		if objID, ok := doc[defaultMongoPK].(bson.ObjectID); ok && objID.Hex() == id {
+			return ErrTreeCycle
+		}
+	}
+	return nil
+}
+
+// BackfillTreeIDStep stores the node id of tree documents saved before their
+// schema had a ParentRef, which Ancestors needs to find them.
+type BackfillTreeIDStep struct {
+	Schema JSchema
+}
+
+// Description implements MigrationStep.
+func (s BackfillTreeIDStep) Description() string {
+	return fmt.Sprintf("backfill tree ids of %s", s.Schema.Name())
+}
+
+// Apply implements MigrationStep.
+func (s BackfillTreeIDStep) Apply(ctx context.Context) error {
+	_, err := collection(ctx, s.Schema).UpdateMany(ctx,
+		bson.M{treeIDKey: bson.M{"$exists": false}},
+		[]bson.M{{"$set": bson.M{treeIDKey: bson.M{"$toString": "$_id"}}}},
+	)
+	return err
+}
@@ -0,0 +1,106 @@
+package jpack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func categorySchema() JSchema {
+	return NewSchema("test_category").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		ParentRef("parent").
+		Build()
+}
+
+func TestTree(t *testing.T) {
+	categories := categorySchema()
+	parent, ok := ParentRefOf(categories)
+	assert.True(t, ok)
+	assert.Equal(t, "parent", parent.Name())
+	assert.Same(t, categories, parent.RelSchema())
+
+	_, ok = ParentRefOf(userSchema)
+	assert.False(t, ok)
+
+	t.Run("pipelines", func(t *testing.T) {
+		id := bson.NewObjectID()
+		tenant := bson.M{"tenant_id": "acme"}
+
+		assert.Equal(t, []bson.M{
+			{"$match": bson.M{"_id": id}},
+			{"$graphLookup": bson.M{
+				"from":                    "test_category",
+				"startWith":               "$parent",
+				"connectFromField":        "parent",
+				"connectToField":          "_tree_id",
+				"as":                      "_tree",
+				"depthField":              "_tree_depth",
+				"restrictSearchWithMatch": tenant,
+			}},
+			{"$unwind": "$_tree"},
+			{"$replaceRoot": bson.M{"newRoot": "$_tree"}},
+			{"$sort": bson.D{{Key: "_tree_depth", Value: 1}, {Key: "_id", Value: 1}}},
+		}, ancestorsPipeline("test_category", parent, id, tenant))
+
+		lookup := descendantsPipeline("test_category", parent, id, 2, bson.M{})[1]["$graphLookup"].(bson.M)
+		assert.Equal(t, bson.M{
+			"from":             "test_category",
+			"startWith":        bson.M{"$toString": "$_id"},
+			"connectFromField": "_tree_id",
+			"connectToField":   "parent",
+			"as":               "_tree",
+			"depthField":       "_tree_depth",
+			"maxDepth":         1,
+		}, lookup)
+
+		lookup = descendantsPipeline("test_category", parent, id, 0, nil)[1]["$graphLookup"].(bson.M)
+		assert.NotContains(t, lookup, "maxDepth")
+	})
+
+	t.Run("new nodes store their tree id", func(t *testing.T) {
+		ctx := offlineContext(t)
+		root := NewMongoRecord(categories)
+		assert.NoError(t, root.SetValue(mustField(t, categories, "name"), "Books"))
+
+		w, err := root.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, w.insertedID.(bson.ObjectID).Hex(), w.document["_tree_id"])
+
+		loaded := NewMongoRecord(categories)
+		assert.NoError(t, loaded.loadDocument(ctx, w.document))
+		assert.NotContains(t, loaded.originalRecord, "_tree_id")
+	})
+
+	t.Run("a node can't be its own parent", func(t *testing.T) {
+		ctx := offlineContext(t)
+		id := bson.NewObjectID().Hex()
+		node := NewMongoRecord(categories)
+		assert.NoError(t, node.SetValue(mustField(t, categories, "id"), id))
+		assert.NoError(t, node.SetValue(parent, id))
+
+		_, err := node.prepareSave(ctx, &saveOptions{})
+		assert.ErrorIs(t, err, ErrTreeCycle)
+	})
+
+	t.Run("lookups need a tree node", func(t *testing.T) {
+		ctx := offlineContext(t)
+		_, err := Ancestors(ctx, NewMongoRecord(userSchema))
+		assert.ErrorContains(t, err, "has no parent ref")
+		_, err = Descendants(ctx, NewMongoRecord(categories), 1)
+		assert.Error(t, err)
+	})
+
+	t.Run("migrations", func(t *testing.T) {
+		flat := NewSchema("test_category").Field("id", &String{}).Field("name", &String{}).Build()
+		migrations := GenerateMigrations("2024-08-tree", flat, categories, time.Hour)
+		assert.Len(t, migrations, 1)
+		assert.Contains(t, migrations[0].Steps, BackfillTreeIDStep{Schema: categories})
+		assert.Contains(t, migrations[0].Steps, CreateIndexStep{Schema: categories, Keys: []string{"_tree_id"}})
+
+		assert.Nil(t, GenerateMigrations("noop", categories, categorySchema(), time.Hour))
+	})
+}
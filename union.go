@@ -0,0 +1,311 @@
+package jpack
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// unionQuery implements Query over the combined results of several queries
+type unionQuery struct {
+	queries []Query
+
+	orderBy []JField
+	limit   *int
+	offset  *int
+}
+
+// Union combines queries on the same schema, e.g. "items I own" and "items
+// shared with me", which are best served by different filters and indexes.
+// Each query runs on its own and the results are merged, keeping the first
+// record of every primary key. Records follow the union's OrderBy, or the
+// order of the queries without one. Select, With, Where and Cached apply to
+// every query; Limit and Offset apply to the merged results. Count and
+// BucketBy cover the distinct matches of all filters in one query.
+// FirstOrCreate is not supported.
+func Union(queries ...Query) Query {
+	return &unionQuery{queries: queries}
+}
+
+// Schema implements Query
+func (u *unionQuery) Schema() JSchema {
+	if len(u.queries) == 0 {
+		return nil
+	}
+	return u.queries[0].Schema()
+}
+
+// Select implements Query
+func (u *unionQuery) Select(fields ...JField) Query {
+	u.each(func(q Query) Query { return q.Select(fields...) })
+	return u
+}
+
+// With implements Query
+func (u *unionQuery) With(ref JRef, fn func(JSchema, Query) Query) Query {
+	u.each(func(q Query) Query { return q.With(ref, fn) })
+	return u
+}
+
+// Where implements Query
+func (u *unionQuery) Where(filter Filter) Query {
+	u.each(func(q Query) Query { return q.Where(filter) })
+	return u
+}
+
+// OrderBy implements Query
+func (u *unionQuery) OrderBy(fields ...JField) Query {
+	u.orderBy = fields
+	u.each(func(q Query) Query { return q.OrderBy(fields...) })
+	return u
+}
+
+// Limit implements Query
+func (u *unionQuery) Limit(limit int) Query {
+	u.limit = &limit
+	return u
+}
+
+// Offset implements Query
+func (u *unionQuery) Offset(offset int) Query {
+	u.offset = &offset
+	return u
+}
+
+// Cached implements Query
+func (u *unionQuery) Cached(ttl time.Duration, tags ...string) Query {
+	u.each(func(q Query) Query { return q.Cached(ttl, tags...) })
+	return u
+}
+
+func (u *unionQuery) each(fn func(Query) Query) {
+	for i, q := range u.queries {
+		u.queries[i] = fn(q)
+	}
+}
+
+func (u *unionQuery) validate() error {
+	if len(u.queries) == 0 {
+		return errors.New("jpack: union of no queries")
+	}
+	name := u.queries[0].Schema().Name()
+	for _, q := range u.queries[1:] {
+		if q.Schema().Name() != name {
+			return fmt.Errorf("jpack: union of queries on %s and %s", name, q.Schema().Name())
+		}
+	}
+	return nil
+}
+
+// Execute implements Query
+func (u *unionQuery) Execute() ([]JRecord, error) {
+	if err := u.validate(); err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if u.offset != nil {
+		offset = *u.offset
+	}
+
+	var records []JRecord
+	seen := make(map[string]bool)
+	for _, q := range u.queries {
+		// The first offset+limit merged records are among the first
+		// offset+limit records of every query
+		if u.limit != nil {
+			q = firstN(q, offset+*u.limit)
+		}
+
+		results, err := q.Execute()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range results {
+			if id, ok := recordID(record); ok {
+				if seen[id] {
+					continue
+				}
+				seen[id] = true
+			}
+			records = append(records, record)
+		}
+	}
+
+	if len(u.orderBy) > 0 {
+		slices.SortStableFunc(records, func(a, b JRecord) int {
+			for _, field := range u.orderBy {
+				va, _ := a.Value(field)
+				vb, _ := b.Value(field)
+				if c := compareValues(va, vb); c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+	}
+
+	records = records[min(offset, len(records)):]
+	if u.limit != nil && len(records) > *u.limit {
+		records = records[:*u.limit]
+	}
+	return records, nil
+}
+
+// firstN returns a query for the first n results of q, leaving q unchanged
+// where it can.
+func firstN(q Query, n int) Query {
+	switch t := q.(type) {
+	case *mongoQuery:
+		c := *t
+		limit := int64(n)
+		c.limit, c.offset = &limit, nil
+		return &c
+	case *unionQuery:
+		c := *t
+		c.limit, c.offset = &n, nil
+		return &c
+	}
+	return q.Limit(n).Offset(0)
+}
+
+// First implements Query
+func (u *unionQuery) First() (JRecord, error) {
+	record, err := u.FirstOrNil()
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, ErrNotFound
+	}
+	return record, nil
+}
+
+// FirstOrNil implements Query
+func (u *unionQuery) FirstOrNil() (JRecord, error) {
+	records, err := firstN(u, 1).Execute()
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// FirstOrCreate implements Query
+func (u *unionQuery) FirstOrCreate(ctx context.Context, defaults map[JField]any) (JRecord, bool, error) {
+	return nil, false, errors.New("jpack: FirstOrCreate is not supported on a union")
+}
+
+// Count implements Query
+func (u *unionQuery) Count() (int, error) {
+	first, filter, err := u.filter()
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	count, err := first.collection.CountDocuments(first.ctx, filter)
+	tapQuery(first.ctx, QueryOperation{Collection: first.collection.Name(), Operation: "count", Filter: filter, Count: count, Err: err}, start)
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// BucketBy implements Query. Buckets count the distinct matches of all
+// filters.
+func (u *unionQuery) BucketBy(field JField, interval Interval) *BucketQuery {
+	return TimeBuckets(u, field, interval)
+}
+
+// filter returns the first query, which all queries share a collection with,
+// and a filter matching the records of every query.
+func (u *unionQuery) filter() (*mongoQuery, bson.M, error) {
+	if err := u.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	filters := make([]bson.M, 0, len(u.queries))
+	for _, q := range u.queries {
+		mq, ok := q.(*mongoQuery)
+		if !ok {
+			return nil, nil, fmt.Errorf("jpack: cannot combine a union with a %T", q)
+		}
+		if mq.err != nil {
+			return nil, nil, mq.err
+		}
+		filters = append(filters, mq.filter())
+	}
+
+	return u.queries[0].(*mongoQuery), bson.M{"$or": filters}, nil
+}
+
+// compareValues orders field values for merging sorted results: nil first,
+// then numbers, strings, booleans and times by value.
+func compareValues(a, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return cmp.Compare(fa, fb)
+		}
+	}
+
+	switch va := a.(type) {
+	case string:
+		if vb, ok := b.(string); ok {
+			return cmp.Compare(va, vb)
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			switch {
+			case va == vb:
+				return 0
+			case !va:
+				return -1
+			default:
+				return 1
+			}
+		}
+	case time.Time:
+		if vb, ok := b.(time.Time); ok {
+			return va.Compare(vb)
+		}
+	case bson.DateTime:
+		if vb, ok := b.(bson.DateTime); ok {
+			return cmp.Compare(va, vb)
+		}
+	}
+
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+var _ Query = &unionQuery{}
@@ -0,0 +1,131 @@
+package jpack
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// staticQuery is a Query over fixed records, sorted by OrderBy like MongoDB would.
+type staticQuery struct {
+	schema  JSchema
+	records []JRecord
+	orderBy []JField
+	limit   int
+	err     error
+}
+
+func (q *staticQuery) Schema() JSchema                             { return q.schema }
+func (q *staticQuery) Select(...JField) Query                      { return q }
+func (q *staticQuery) With(JRef, func(JSchema, Query) Query) Query { return q }
+func (q *staticQuery) Where(Filter) Query                          { return q }
+func (q *staticQuery) OrderBy(fields ...JField) Query              { q.orderBy = fields; return q }
+func (q *staticQuery) Limit(limit int) Query                       { q.limit = limit; return q }
+func (q *staticQuery) Offset(int) Query                            { return q }
+func (q *staticQuery) Cached(time.Duration, ...string) Query       { return q }
+func (q *staticQuery) Count() (int, error)                         { return len(q.records), nil }
+func (q *staticQuery) BucketBy(field JField, interval Interval) *BucketQuery {
+	return TimeBuckets(q, field, interval)
+}
+func (q *staticQuery) Capabilities() Capabilities   { return Capabilities{} }
+func (q *staticQuery) First() (JRecord, error)      { return q.records[0], nil }
+func (q *staticQuery) FirstOrNil() (JRecord, error) { return q.records[0], nil }
+func (q *staticQuery) FirstOrCreate(context.Context, map[JField]any) (JRecord, bool, error) {
+	return nil, false, nil
+}
+
+func (q *staticQuery) All(ctx context.Context) iter.Seq2[JRecord, error] {
+	return executed(q.Execute)
+}
+
+func (q *staticQuery) Execute() ([]JRecord, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	result := slices.Clone(q.records)
+	slices.SortStableFunc(result, func(a, b JRecord) int {
+		for _, field := range q.orderBy {
+			va, _ := a.Value(field)
+			vb, _ := b.Value(field)
+			if c := compareValues(va, vb); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+	if q.limit > 0 && len(result) > q.limit {
+		result = result[:q.limit]
+	}
+	return result, nil
+}
+
+func TestUnion(t *testing.T) {
+	firstName := mustField(t, userSchema, "first_name")
+	age := mustField(t, userSchema, "age")
+
+	user := func(id, name string, years int) JRecord {
+		record := NewMongoRecord(userSchema)
+		record.originalRecord = map[string]any{"id": id, "first_name": name, "age": years}
+		return record
+	}
+	ada, bob, cyd, dee := user("1", "Ada", 36), user("2", "Bob", 25), user("3", "Cyd", 41), user("4", "Dee", 19)
+
+	names := func(records []JRecord) []any {
+		var result []any
+		for _, record := range records {
+			name, _ := record.Value(firstName)
+			result = append(result, name)
+		}
+		return result
+	}
+
+	owned := func() Query { return &staticQuery{schema: userSchema, records: []JRecord{ada, bob}} }
+	shared := func() Query { return &staticQuery{schema: userSchema, records: []JRecord{bob, cyd, dee}} }
+
+	t.Run("results are deduplicated by primary key", func(t *testing.T) {
+		records, err := Union(owned(), shared()).Execute()
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Ada", "Bob", "Cyd", "Dee"}, names(records))
+	})
+
+	t.Run("ordering is merged", func(t *testing.T) {
+		records, err := Union(owned(), shared()).OrderBy(age).Execute()
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Dee", "Bob", "Ada", "Cyd"}, names(records))
+	})
+
+	t.Run("limit and offset apply to the merged results", func(t *testing.T) {
+		records, err := Union(owned(), shared()).OrderBy(age).Offset(1).Limit(2).Execute()
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Bob", "Ada"}, names(records))
+
+		first, err := Union(owned(), shared()).OrderBy(age).First()
+		assert.NoError(t, err)
+		assert.Equal(t, []any{"Dee"}, names([]JRecord{first}))
+	})
+
+	t.Run("empty unions find nothing", func(t *testing.T) {
+		_, err := Union(&staticQuery{schema: userSchema}).First()
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("queries must share a schema", func(t *testing.T) {
+		other := NewSchema("test_union_other").Field("id", &String{}).Build()
+		_, err := Union(owned(), &staticQuery{schema: other}).Execute()
+		assert.Error(t, err)
+	})
+}
+
+func TestCompareValues(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, -1, compareValues(nil, 1))
+	assert.Equal(t, -1, compareValues(int64(2), 10.5))
+	assert.Equal(t, 1, compareValues("b", "a"))
+	assert.Equal(t, -1, compareValues(false, true))
+	assert.Equal(t, 1, compareValues(now.Add(time.Second), now))
+	assert.Equal(t, 0, compareValues(int32(3), uint8(3)))
+}
@@ -0,0 +1,203 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// UnitOfWork collects record changes and writes them together in a single
+// transaction instead of saving as you go. Transactions require MongoDB to run
+// as a replica set.
+//
+// Nested units created with Begin act like savepoints: Commit hands their
+// changes to the parent, Rollback discards them.
+type UnitOfWork struct {
+	parent *UnitOfWork
+
+	saves   []JRecord
+	deletes []JRecord
+}
+
+// flushTransaction runs the transaction of UnitOfWork.Flush. Tests replace it
+// to retry or fail transactions without a replica set.
+var flushTransaction = transaction
+
+// NewUnitOfWork creates an empty unit of work.
+func NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{}
+}
+
+// Add registers a new or modified record to be saved on Flush.
+func (u *UnitOfWork) Add(records ...JRecord) *UnitOfWork {
+	for _, record := range records {
+		if !slices.Contains(u.saves, record) {
+			u.saves = append(u.saves, record)
+		}
+	}
+	return u
+}
+
+// Delete registers a record to be deleted on Flush.
+func (u *UnitOfWork) Delete(records ...JRecord) *UnitOfWork {
+	for _, record := range records {
+		u.saves = slices.DeleteFunc(u.saves, func(r JRecord) bool { return r == record })
+		if !slices.Contains(u.deletes, record) {
+			u.deletes = append(u.deletes, record)
+		}
+	}
+	return u
+}
+
+// Begin starts a nested unit of work.
+func (u *UnitOfWork) Begin() *UnitOfWork {
+	return &UnitOfWork{parent: u}
+}
+
+// Commit hands the changes of a nested unit of work to its parent.
+func (u *UnitOfWork) Commit() error {
+	if u.parent == nil {
+		return errors.New("jpack: commit on a unit of work without parent, use Flush")
+	}
+
+	u.parent.Add(u.saves...)
+	u.parent.Delete(u.deletes...)
+	u.saves, u.deletes = nil, nil
+	return nil
+}
+
+// Rollback discards the changes registered on the unit of work.
+func (u *UnitOfWork) Rollback() {
+	u.saves, u.deletes = nil, nil
+}
+
+// Flush writes all registered changes in one transaction: saves first, with
+// referenced records before the records referring to them, then deletes in
+// the opposite order. If anything fails, the transaction is aborted and the
+// in-memory state of the records is restored. The records are also restored
+// before each retry of the transaction, so a retry writes what the aborted
+// attempt did. Saves are written within the transaction even when ctx holds
+// a RequestBatcher.
+func (u *UnitOfWork) Flush(ctx context.Context) error {
+	if len(u.saves) == 0 && len(u.deletes) == 0 {
+		return nil
+	}
+
+	if IsReadOnly(ctx) {
+		return ErrReadOnly
+	}
+
+	saves, err := dependencyOrder(u.saves)
+	if err != nil {
+		return err
+	}
+
+	deletes, err := dependencyOrder(u.deletes)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(deletes)
+
+	var restore []func()
+	for _, record := range append(slices.Clone(saves), deletes...) {
+		if s, ok := record.(interface{ snapshot() func() }); ok {
+			restore = append(restore, s.snapshot())
+		}
+	}
+	rollback := func() {
+		for _, fn := range restore {
+			fn()
+		}
+	}
+
+	err = flushTransaction(ctx, func(ctx context.Context) error {
+		// An aborted attempt may have marked records saved or deleted
+		rollback()
+		ctx = transactionContext(ctx)
+
+		for _, record := range saves {
+			if err := record.Save(ctx); err != nil {
+				return err
+			}
+		}
+
+		for _, record := range deletes {
+			if err := record.Delete(ctx); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		rollback()
+		return err
+	}
+
+	u.saves, u.deletes = nil, nil
+	return nil
+}
+
+// transactionContext returns the context the writes of a transaction run
+// in: without a RequestBatcher, which would write them after the
+// transaction.
+func transactionContext(ctx context.Context) context.Context {
+	if _, ok := RequestBatcherFrom(ctx); ok {
+		return WithRequestBatcher(ctx, nil)
+	}
+	return ctx
+}
+
+// dependencyOrder sorts records so that a record referenced through a ref
+// field comes before the records referring to it. Records without
+// dependencies keep their registration order.
+func dependencyOrder(records []JRecord) ([]JRecord, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	state := make(map[JRecord]int, len(records))
+	ordered := make([]JRecord, 0, len(records))
+
+	var visit func(JRecord) error
+	visit = func(record JRecord) error {
+		switch state[record] {
+		case done:
+			return nil
+		case visiting:
+			return errors.New("jpack: circular reference between records in unit of work")
+		}
+
+		state[record] = visiting
+		for _, field := range record.Schema().Fields() {
+			if _, ok := field.(JRef); !ok {
+				continue
+			}
+
+			value, _ := record.Value(field)
+			dependency, ok := value.(JRecord)
+			if !ok || !slices.Contains(records, dependency) {
+				continue
+			}
+
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[record] = done
+
+		ordered = append(ordered, record)
+		return nil
+	}
+
+	for _, record := range records {
+		if err := visit(record); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
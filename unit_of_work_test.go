@@ -0,0 +1,159 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork(t *testing.T) {
+	authorSchema := NewSchema("test_uow_author").
+		Field("id", &String{}).
+		Field("name", &String{}).
+		Build()
+	postSchema := NewSchema("test_uow_post").
+		Field("id", &String{}).
+		Field("title", &String{}).
+		Ref("author", authorSchema).
+		Build()
+
+	newPost := func(author JRecord) *mongoRecord {
+		post := NewMongoRecord(postSchema)
+		post.SetValue(mustField(t, postSchema, "title"), "Hello")
+		if author != nil {
+			assert.NoError(t, post.SetValue(mustField(t, postSchema, "author"), author))
+		}
+		return post
+	}
+
+	t.Run("refs are ordered before referrers", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+		post := newPost(author)
+		other := newPost(nil)
+
+		ordered, err := dependencyOrder([]JRecord{post, other, author})
+		assert.NoError(t, err)
+		assert.Equal(t, []JRecord{author, post, other}, ordered)
+	})
+
+	t.Run("nested units commit into their parent", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+		post := newPost(author)
+
+		uow := NewUnitOfWork().Add(author)
+		nested := uow.Begin().Add(post)
+		assert.NoError(t, nested.Commit())
+
+		assert.Equal(t, []JRecord{author, post}, uow.saves)
+		assert.Empty(t, nested.saves)
+	})
+
+	t.Run("nested units roll back", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+
+		uow := NewUnitOfWork()
+		nested := uow.Begin().Add(author)
+		nested.Rollback()
+		assert.NoError(t, nested.Commit())
+
+		assert.Empty(t, uow.saves)
+		assert.Error(t, uow.Commit(), "root units are flushed, not committed")
+	})
+
+	t.Run("deleting a record drops its pending save", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+
+		uow := NewUnitOfWork().Add(author).Delete(author)
+		assert.Empty(t, uow.saves)
+		assert.Equal(t, []JRecord{author}, uow.deletes)
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		assert.NoError(t, NewUnitOfWork().Flush(context.Background()), "nothing to flush")
+
+		uow := NewUnitOfWork().Add(NewMongoRecord(authorSchema))
+		assert.ErrorIs(t, uow.Flush(WithReadOnly(context.Background())), ErrReadOnly)
+	})
+
+	t.Run("retried transactions write what the aborted attempt did", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+		author.SetValue(mustField(t, authorSchema, "name"), "John")
+		post := newPost(author)
+
+		attempts := 0
+		uow := NewUnitOfWork().Add(post, author)
+		stubFlushTransaction(t, func(ctx context.Context, fn func(ctx context.Context) error) error {
+			// A transient error aborts the first attempt after its writes
+			for range 2 {
+				attempts++
+				if err := fn(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		journal := NewMemoryJournal()
+		batcher := NewRequestBatcher()
+		ctx := WithPendingJournal(WithRequestBatcher(context.Background(), batcher), journal)
+		assert.NoError(t, uow.Flush(ctx))
+
+		ops, err := journal.Pending(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+		if assert.Len(t, ops, 4, "both attempts insert both records") {
+			assert.Equal(t, ops[0].Schema, ops[2].Schema)
+			assert.Equal(t, ops[0].Values["name"], ops[2].Values["name"])
+			assert.Equal(t, ChangeInsert, ops[3].Operation)
+		}
+		assert.False(t, author.IsNew())
+		assert.Zero(t, batcher.Len())
+	})
+
+	t.Run("failed transactions restore the records", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+		author.SetValue(mustField(t, authorSchema, "name"), "John")
+
+		uow := NewUnitOfWork().Add(author)
+		stubFlushTransaction(t, func(ctx context.Context, fn func(ctx context.Context) error) error {
+			assert.NoError(t, fn(ctx))
+			return errors.New("commit failed")
+		})
+
+		ctx := WithPendingJournal(context.Background(), NewMemoryJournal())
+		assert.ErrorContains(t, uow.Flush(ctx), "commit failed")
+		assert.True(t, author.IsNew())
+		assert.Equal(t, []string{"name"}, author.DirtyKeys())
+	})
+
+	t.Run("transactions don't batch their writes", func(t *testing.T) {
+		ctx := WithRequestBatcher(context.Background(), NewRequestBatcher())
+		_, ok := RequestBatcherFrom(transactionContext(ctx))
+		assert.False(t, ok)
+	})
+
+	t.Run("snapshot restores in-memory state", func(t *testing.T) {
+		author := NewMongoRecord(authorSchema)
+		author.SetValue(mustField(t, authorSchema, "name"), "John")
+
+		restore := author.snapshot()
+		author.originalRecord = author.record
+		author.record = map[string]any{}
+
+		restore()
+		assert.True(t, author.IsNew())
+		assert.Equal(t, []string{"name"}, author.DirtyKeys())
+	})
+}
+
+// stubFlushTransaction runs the transactions of UnitOfWork.Flush with fn
+// until the test ends.
+func stubFlushTransaction(t *testing.T, fn func(ctx context.Context, fn func(ctx context.Context) error) error) {
+	t.Helper()
+
+	previous := flushTransaction
+	flushTransaction = fn
+	t.Cleanup(func() { flushTransaction = previous })
+}
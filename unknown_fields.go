@@ -0,0 +1,94 @@
+package jpack
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UnknownFieldPolicy decides what happens to keys of stored documents that
+// are not declared in the schema.
+type UnknownFieldPolicy int
+
+const (
+	// PreserveUnknownFields keeps unknown keys in the loaded record.
+	PreserveUnknownFields UnknownFieldPolicy = iota
+
+	// DropUnknownFields leaves unknown keys out of the loaded record, and the
+	// next Save of the record removes them from the stored document.
+	DropUnknownFields
+
+	// RejectUnknownFields fails loading documents with unknown keys with an
+	// UnknownFieldsError.
+	RejectUnknownFields
+)
+
+// UnknownFieldsError is returned when a document with keys not declared in
+// its schema is loaded under RejectUnknownFields.
+type UnknownFieldsError struct {
+	Schema string
+	Keys   []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("jpack: %s document has unknown fields %s", e.Schema, strings.Join(e.Keys, ", "))
+}
+
+// UnknownFields sets how the schema handles undeclared keys of stored
+// documents. The default is PreserveUnknownFields.
+func (s *SchemaBuilder) UnknownFields(policy UnknownFieldPolicy) *SchemaBuilder {
+	s.schema.unknownFields = policy
+	return s
+}
+
+// UnknownFields returns the schema's unknown field policy.
+func (s *schemaImpl) UnknownFields() UnknownFieldPolicy {
+	return s.unknownFields
+}
+
+// UnknownFieldPolicyOf returns the unknown field policy of a schema, or of the
+// base schema of a view.
+func UnknownFieldPolicyOf(schema JSchema) UnknownFieldPolicy {
+	if s, ok := storageSchema(schema).(interface{ UnknownFields() UnknownFieldPolicy }); ok {
+		return s.UnknownFields()
+	}
+	return PreserveUnknownFields
+}
+
+// UnknownKeys implements JRecord.
+func (m *mongoRecord) UnknownKeys() []string {
+	return m.unknownKeys
+}
+
+// applyUnknownFieldPolicy finds the keys of the loaded document that aren't
+// declared in the schema and handles them by the schema's policy.
+func (m *mongoRecord) applyUnknownFieldPolicy() error {
+	known := make(map[string]bool)
+	for _, field := range m.Schema().Fields() {
+		known[field.Name()] = true
+		for _, key := range storageKeys(field) {
+			known[key] = true
+		}
+	}
+
+	m.unknownKeys = nil
+	for key := range m.originalRecord {
+		if !known[key] {
+			m.unknownKeys = append(m.unknownKeys, key)
+		}
+	}
+	if len(m.unknownKeys) == 0 {
+		return nil
+	}
+	slices.Sort(m.unknownKeys)
+
+	switch UnknownFieldPolicyOf(m.Schema()) {
+	case DropUnknownFields:
+		for _, key := range m.unknownKeys {
+			delete(m.originalRecord, key)
+		}
+	case RejectUnknownFields:
+		return &UnknownFieldsError{Schema: m.Schema().Name(), Keys: m.unknownKeys}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUnknownFields(t *testing.T) {
+	newSchema := func(policy UnknownFieldPolicy) JSchema {
+		return NewSchema("test_unknown").
+			Field("id", &String{}).
+			Field("name", &String{}).
+			Field("price", &Composite{Parts: []CompositePart{{Name: "amount", Type: &Number{}}, {Name: "currency", Type: &String{}}}}).
+			UnknownFields(policy).
+			Build()
+	}
+	doc := func() bson.M {
+		return bson.M{
+			"_id":            bson.NewObjectID(),
+			"name":           "Ada",
+			"price_amount":   int32(10),
+			"price_currency": "EUR",
+			"legacy_flag":    true,
+			"notes":          "",
+		}
+	}
+
+	t.Run("preserve", func(t *testing.T) {
+		record := NewMongoRecord(newSchema(PreserveUnknownFields))
+		assert.NoError(t, record.loadDocument(t.Context(), doc()))
+		assert.Equal(t, []string{"legacy_flag", "notes"}, record.UnknownKeys())
+		assert.Equal(t, true, record.originalRecord["legacy_flag"])
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		schema := newSchema(DropUnknownFields)
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), doc()))
+		assert.Equal(t, []string{"legacy_flag", "notes"}, record.UnknownKeys())
+		assert.NotContains(t, record.originalRecord, "legacy_flag")
+
+		// The next Save removes them from the stored document
+		assert.NoError(t, record.SetValue(mustField(t, schema, "name"), "Grace"))
+		w, err := record.prepareSave(offlineContext(t), &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"legacy_flag": "", "notes": ""}, w.document["$unset"])
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		record := NewMongoRecord(newSchema(RejectUnknownFields))
+		err := record.loadDocument(t.Context(), doc())
+		var unknown *UnknownFieldsError
+		assert.ErrorAs(t, err, &unknown)
+		assert.Equal(t, []string{"legacy_flag", "notes"}, unknown.Keys)
+
+		clean := doc()
+		delete(clean, "legacy_flag")
+		delete(clean, "notes")
+		record = NewMongoRecord(newSchema(RejectUnknownFields))
+		assert.NoError(t, record.loadDocument(t.Context(), clean))
+		assert.Empty(t, record.UnknownKeys())
+	})
+
+	t.Run("aliased keys are known", func(t *testing.T) {
+		schema := NewSchema("test_unknown").
+			Field("id", &String{}).
+			Field("full_name", &String{}, FieldAlias("name")).
+			UnknownFields(RejectUnknownFields).
+			Build()
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Ada"}))
+		assert.Equal(t, PreserveUnknownFields, UnknownFieldPolicyOf(userSchema))
+	})
+}
@@ -0,0 +1,74 @@
+package jpack
+
+import (
+	"errors"
+	"maps"
+)
+
+// unsetValue marks a pending change that removes a field from the stored
+// document, as opposed to nil, which stores null.
+type unsetValue struct{}
+
+func isUnset(value any) bool {
+	_, ok := value.(unsetValue)
+	return ok
+}
+
+// IsSet implements JRecord.
+func (m *mongoRecord) IsSet(field JField) bool {
+	_, ok := m.Value(field)
+	return ok
+}
+
+// Unset implements JRecord.
+func (m *mongoRecord) Unset(field JField) error {
+	if field == nil {
+		return errors.New("field cannot be nil")
+	}
+	if field.Schema().Name() != m.Schema().Name() {
+		return errors.New("field schema does not match record schema")
+	}
+	if err := m.checkImmutable(field, nil); err != nil {
+		return err
+	}
+
+	old, wasSet := m.Value(field)
+	m.invalidateScanned(field.Name())
+	if m.stored(field) {
+		m.record[field.Name()] = unsetValue{}
+	} else {
+		// Nothing is stored that Save would have to remove
+		delete(m.record, field.Name())
+	}
+
+	if wasSet && old != nil {
+		for _, fn := range m.observers {
+			fn(field, old, nil)
+		}
+	}
+	return nil
+}
+
+// stored reports whether the stored document has a value for the field.
+func (m *mongoRecord) stored(field JField) bool {
+	for _, key := range storageKeys(field) {
+		if _, ok := m.originalRecord[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pendingChanges returns the changes Save writes with $set, and the names of
+// the fields it removes with $unset.
+func (m *mongoRecord) pendingChanges() (map[string]any, []string) {
+	pending := maps.Clone(m.record)
+	var cleared []string
+	for key, value := range pending {
+		if isUnset(value) {
+			delete(pending, key)
+			cleared = append(cleared, key)
+		}
+	}
+	return pending, cleared
+}
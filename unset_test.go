@@ -0,0 +1,87 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUnset(t *testing.T) {
+	schema := NewSchema("test_unset").
+		Field("id", &String{}).
+		Field("name", &String{}, Required()).
+		Field("nickname", &String{}).
+		Field("age", &Number{}).
+		Build()
+	name, nickname, age := mustField(t, schema, "name"), mustField(t, schema, "nickname"), mustField(t, schema, "age")
+
+	load := func(t *testing.T) *mongoRecord {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.loadDocument(t.Context(), bson.M{"_id": bson.NewObjectID(), "name": "Ada", "nickname": nil, "age": int32(36)}))
+		return record
+	}
+
+	t.Run("null and unset are distinct", func(t *testing.T) {
+		record := load(t)
+		assert.True(t, record.IsSet(nickname), "stored nulls are set")
+		assert.True(t, record.IsSet(age))
+
+		assert.NoError(t, record.Unset(age))
+		assert.False(t, record.IsSet(age))
+		_, ok := record.Value(age)
+		assert.False(t, ok)
+		assert.Contains(t, record.DirtyKeys(), "age")
+
+		value, err := record.ScannedValue(t.Context(), age)
+		assert.NoError(t, err)
+		assert.Nil(t, value)
+	})
+
+	t.Run("Save removes unset fields and skips unchanged nulls", func(t *testing.T) {
+		ctx := offlineContext(t)
+		record := load(t)
+		assert.NoError(t, record.Unset(age))
+
+		w, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$unset": bson.M{"age": ""}}, w.document)
+
+		assert.NoError(t, record.SetValue(nickname, "Countess"))
+		assert.NoError(t, record.SetValue(nickname, nil))
+		// The stored null isn't rewritten
+		w, err = record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$unset": bson.M{"age": ""}}, w.document)
+
+		// Setting the field again replaces the unset
+		assert.NoError(t, record.SetValue(age, 37))
+		assert.True(t, record.IsSet(age))
+	})
+
+	t.Run("required fields can't be unset", func(t *testing.T) {
+		record := load(t)
+		assert.NoError(t, record.Unset(name))
+		_, err := record.prepareSave(offlineContext(t), &saveOptions{})
+		var required *RequiredFieldError
+		assert.ErrorAs(t, err, &required)
+	})
+
+	t.Run("unsetting a field that isn't stored drops the change", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(age, 36))
+		assert.NoError(t, record.Unset(age))
+		assert.Empty(t, record.record)
+		assert.False(t, record.IsSet(age))
+	})
+
+	t.Run("observers hear about cleared values", func(t *testing.T) {
+		record := load(t)
+		var changes []any
+		record.OnChange(func(field JField, old, new any) { changes = append(changes, field.Name(), old, new) })
+
+		assert.NoError(t, record.Unset(age))
+		assert.NoError(t, record.Unset(nickname))
+		assert.Equal(t, []any{"age", int32(36), nil}, changes)
+	})
+}
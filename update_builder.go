@@ -0,0 +1,241 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// arrayFilterIdentifier matches the identifiers of filtered positional
+// operators, $[identifier], as MongoDB accepts them.
+var arrayFilterIdentifier = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+// filteredPositional finds the $[identifier] operators of an update path.
+var filteredPositional = regexp.MustCompile(`\$\[([^\]]*)\]`)
+
+// UpdateResult is what an UpdateBuilder changed.
+type UpdateResult struct {
+	Matched  int64
+	Modified int64
+}
+
+// UpdateBuilder patches the stored documents of a schema matching a filter
+// in place, including single elements of embedded arrays, without loading
+// and rewriting them. Paths start with a field of the schema and may use the
+// positional operators of MongoDB:
+//
+//   - items.$.qty updates the first element matched by the filter
+//   - items.$[].qty updates every element
+//   - items.$[low].qty updates the elements matched by the array filter
+//     named low
+//
+// Values are stored as given, without the field type's conversion, since
+// array elements have no field type. Policy filters apply, but hooks,
+// outbox entries and webhooks don't run, like for Cleanup.
+type UpdateBuilder struct {
+	ctx    context.Context
+	schema JSchema
+	where  []Filter
+	update bson.M
+	// arrayFilters are keyed by identifier.
+	arrayFilters map[string]bson.M
+	one          bool
+	errs         []error
+}
+
+// NewUpdate starts an update of the documents of the schema.
+func NewUpdate(ctx context.Context, schema JSchema) *UpdateBuilder {
+	return &UpdateBuilder{ctx: ctx, schema: schema, update: bson.M{}, arrayFilters: make(map[string]bson.M)}
+}
+
+// Where narrows the updated documents. Filters of several calls are and-ed.
+func (b *UpdateBuilder) Where(filter Filter) *UpdateBuilder {
+	b.where = append(b.where, filter)
+	return b
+}
+
+// One only updates the first matching document.
+func (b *UpdateBuilder) One() *UpdateBuilder {
+	b.one = true
+	return b
+}
+
+// Set sets the value at path.
+func (b *UpdateBuilder) Set(path string, value any) *UpdateBuilder {
+	return b.operator("$set", path, value)
+}
+
+// Unset removes the value at path.
+func (b *UpdateBuilder) Unset(path string) *UpdateBuilder {
+	return b.operator("$unset", path, "")
+}
+
+// Inc adds n to the number at path.
+func (b *UpdateBuilder) Inc(path string, n any) *UpdateBuilder {
+	return b.operator("$inc", path, n)
+}
+
+// Push appends values to the array at path.
+func (b *UpdateBuilder) Push(path string, values ...any) *UpdateBuilder {
+	if len(values) == 1 {
+		return b.operator("$push", path, values[0])
+	}
+	return b.operator("$push", path, bson.M{"$each": values})
+}
+
+// Pull removes the elements of the array at path matching condition, either
+// a value or a query document such as bson.M{"qty": bson.M{"$lte": 0}}.
+func (b *UpdateBuilder) Pull(path string, condition any) *UpdateBuilder {
+	return b.operator("$pull", path, condition)
+}
+
+// ArrayFilter names the condition selecting the array elements updated
+// through $[identifier]. Its keys start with the identifier, e.g.
+// ArrayFilter("low", bson.M{"low.qty": bson.M{"$lt": 5}}).
+func (b *UpdateBuilder) ArrayFilter(identifier string, condition bson.M) *UpdateBuilder {
+	if !arrayFilterIdentifier.MatchString(identifier) {
+		b.errs = append(b.errs, fmt.Errorf("jpack: array filter identifier %q must be alphanumeric and start with a lowercase letter", identifier))
+		return b
+	}
+	for key := range condition {
+		if key != identifier && !strings.HasPrefix(key, identifier+".") {
+			b.errs = append(b.errs, fmt.Errorf("jpack: array filter %s: key %s doesn't start with the identifier", identifier, key))
+			return b
+		}
+	}
+	b.arrayFilters[identifier] = condition
+	return b
+}
+
+// operator adds a path to an update operator.
+func (b *UpdateBuilder) operator(name, path string, value any) *UpdateBuilder {
+	if err := b.checkPath(path); err != nil {
+		b.errs = append(b.errs, err)
+		return b
+	}
+
+	for op, paths := range b.update {
+		if _, ok := paths.(bson.M)[path]; ok && op != name {
+			b.errs = append(b.errs, fmt.Errorf("jpack: update of %s: %s is set by both %s and %s", b.schema.Name(), path, op, name))
+			return b
+		}
+	}
+	paths, _ := b.update[name].(bson.M)
+	if paths == nil {
+		paths = bson.M{}
+		b.update[name] = paths
+	}
+	paths[path] = value
+	return b
+}
+
+// checkPath checks that a path starts with a mutable field of the schema.
+func (b *UpdateBuilder) checkPath(path string) error {
+	root, _, _ := strings.Cut(path, ".")
+	if root == defaultMongoPK {
+		return fmt.Errorf("jpack: update of %s: _id can't be changed", b.schema.Name())
+	}
+
+	for _, field := range b.schema.Fields() {
+		if field.Name() != root && !slices.ContainsFunc(storageKeys(field), func(key string) bool {
+			return key == root || strings.HasPrefix(key, root+".")
+		}) {
+			continue
+		}
+		if IsImmutable(field) {
+			return &ImmutableFieldError{Schema: b.schema.Name(), Field: field.Name()}
+		}
+		return nil
+	}
+	return fmt.Errorf("jpack: update of %s: unknown field %s", b.schema.Name(), root)
+}
+
+// build returns the update document and the array filters, checking that
+// every $[identifier] of the paths has a filter and every filter is used.
+func (b *UpdateBuilder) build() (bson.M, []any, error) {
+	if len(b.errs) > 0 {
+		return nil, nil, errors.Join(b.errs...)
+	}
+	if len(b.update) == 0 {
+		return nil, nil, fmt.Errorf("jpack: update of %s changes nothing", b.schema.Name())
+	}
+
+	used := make(map[string]bool)
+	for _, paths := range b.update {
+		for path := range paths.(bson.M) {
+			for _, match := range filteredPositional.FindAllStringSubmatch(path, -1) {
+				if identifier := match[1]; identifier != "" {
+					if _, ok := b.arrayFilters[identifier]; !ok {
+						return nil, nil, fmt.Errorf("jpack: update of %s: no array filter for %s", b.schema.Name(), match[0])
+					}
+					used[identifier] = true
+				}
+			}
+		}
+	}
+
+	var arrayFilters []any
+	for _, identifier := range slices.Sorted(maps.Keys(b.arrayFilters)) {
+		if !used[identifier] {
+			return nil, nil, fmt.Errorf("jpack: update of %s: array filter %s is not used", b.schema.Name(), identifier)
+		}
+		arrayFilters = append(arrayFilters, b.arrayFilters[identifier])
+	}
+	return b.update, arrayFilters, nil
+}
+
+// Execute applies the update.
+func (b *UpdateBuilder) Execute() (UpdateResult, error) {
+	ctx := b.ctx
+	if IsReadOnly(ctx) {
+		return UpdateResult{}, ErrReadOnly
+	}
+	if _, ok := b.schema.(*ViewSchema); ok {
+		return UpdateResult{}, ErrViewReadOnly
+	}
+	if backendFor(ctx, b.schema) != mongoBackend {
+		return UpdateResult{}, fmt.Errorf("jpack: update of %s needs a MongoDB database", b.schema.Name())
+	}
+
+	update, arrayFilters, err := b.build()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	q := NewMongoQuery(ctx, b.schema).(*mongoQuery)
+	for _, filter := range b.where {
+		q.Where(filter)
+	}
+	if q.err != nil {
+		return UpdateResult{}, q.err
+	}
+	filter := q.filter()
+
+	start := time.Now()
+	var res *mongo.UpdateResult
+	if b.one {
+		res, err = q.collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetArrayFilters(arrayFilters))
+	} else {
+		res, err = q.collection.UpdateMany(ctx, filter, update, options.UpdateMany().SetArrayFilters(arrayFilters))
+	}
+	var result UpdateResult
+	if err == nil {
+		result = UpdateResult{Matched: res.MatchedCount, Modified: res.ModifiedCount}
+	}
+	tapQuery(ctx, writeOperation(q.collection, "update", filter, update, result.Modified, err), start)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	InvalidateTags(b.schema.Name())
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUpdateBuilder(t *testing.T) {
+	orders := NewSchema("test_update_orders").
+		Field("id", &String{}).
+		Field("number", &String{}, Immutable()).
+		Field("status", &String{}).
+		Field("items", &String{}).
+		Build()
+	status := mustField(t, orders, "status")
+
+	t.Run("array filters select the updated elements", func(t *testing.T) {
+		update, arrayFilters, err := NewUpdate(offlineContext(t), orders).
+			Where(Eq(status, "open")).
+			Set("items.$[low].reorder", true).
+			Inc("items.$[].views", 1).
+			Pull("items", bson.M{"qty": 0}).
+			Set("status", "restocking").
+			ArrayFilter("low", bson.M{"low.qty": bson.M{"$lt": 5}}).
+			build()
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{
+			"$set":  bson.M{"items.$[low].reorder": true, "status": "restocking"},
+			"$inc":  bson.M{"items.$[].views": 1},
+			"$pull": bson.M{"items": bson.M{"qty": 0}},
+		}, update)
+		assert.Equal(t, []any{bson.M{"low.qty": bson.M{"$lt": 5}}}, arrayFilters)
+	})
+
+	t.Run("Push appends one or many values", func(t *testing.T) {
+		update, _, err := NewUpdate(offlineContext(t), orders).Push("items", "a").build()
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$push": bson.M{"items": "a"}}, update)
+
+		update, _, err = NewUpdate(offlineContext(t), orders).Push("items", "a", "b").build()
+		assert.NoError(t, err)
+		assert.Equal(t, bson.M{"$push": bson.M{"items": bson.M{"$each": []any{"a", "b"}}}}, update)
+	})
+
+	t.Run("invalid updates", func(t *testing.T) {
+		build := func(b *UpdateBuilder) error {
+			_, _, err := b.build()
+			return err
+		}
+		ctx := offlineContext(t)
+
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders)), "changes nothing")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("notes", 1)), "unknown field notes")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("_id", 1)), "_id can't be changed")
+		assert.ErrorIs(t, build(NewUpdate(ctx, orders).Set("number", "2")), ErrImmutableField)
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("status", "a").Unset("status")), "set by both")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("items.$[low].qty", 1)), "no array filter for $[low]")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("status", "a").ArrayFilter("low", bson.M{"low": 1})), "low is not used")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("status", "a").ArrayFilter("Low", bson.M{"Low": 1})), "must be alphanumeric")
+		assert.ErrorContains(t, build(NewUpdate(ctx, orders).Set("status", "a").ArrayFilter("low", bson.M{"qty": 1})), "doesn't start with the identifier")
+	})
+
+	t.Run("Execute needs a writable MongoDB schema", func(t *testing.T) {
+		_, err := NewUpdate(WithReadOnly(offlineContext(t)), orders).Set("status", "a").Execute()
+		assert.ErrorIs(t, err, ErrReadOnly)
+
+		_, err = NewUpdate(offlineContext(t), NewView("test_update_view", orders).Build()).Set("status", "a").Execute()
+		assert.ErrorIs(t, err, ErrViewReadOnly)
+
+		_, err = NewUpdate(context.Background(), orders).Set("status", "a").Execute()
+		assert.ErrorContains(t, err, "needs a MongoDB database")
+	})
+}
@@ -2,8 +2,20 @@ package jpack
 
 import "github.com/samber/lo"
 
+// PoliciesOf returns the access policies attached to the schema.
+func PoliciesOf(schema JSchema) []JPolicy {
+	if s, ok := schema.(interface{ Policies() []JPolicy }); ok {
+		return s.Policies()
+	}
+	return nil
+}
+
 func PK(schema JSchema) (JField, bool) {
+	name := pkFieldName()
 	return lo.Find(schema.Fields(), func(f JField) bool {
+		if name != "" {
+			return f.Name() == name
+		}
 		return f.Name() == "id" || f.Name() == "_id"
 	})
 }
@@ -0,0 +1,80 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// defaultValidationConcurrency bounds the record checks run at once when
+// Config.ValidationConcurrency isn't set.
+const defaultValidationConcurrency = 8
+
+// recordCheck is the record check of one field.
+type recordCheck struct {
+	field JField
+	check RecordFieldType
+}
+
+// runRecordChecks runs the record checks of the fields checked in the
+// scenarios and returns their failures in field order. Checks often hit the
+// network, e.g. remote option lookups, so they run concurrently on a pool of
+// Config.ValidationConcurrency workers. ValidateRecord must therefore only
+// read the record.
+func runRecordChecks(ctx context.Context, record JRecord, scenarios []Scenario) []error {
+	var checks []recordCheck
+	for _, field := range record.Schema().Fields() {
+		if t, ok := field.Type().(RecordFieldType); ok && validatesIn(field, scenarios) {
+			checks = append(checks, recordCheck{field: field, check: t})
+		}
+	}
+
+	errs := make([]error, len(checks))
+	runConcurrently(ctx, len(checks), func(i int) {
+		if err := checks[i].check.ValidateRecord(ctx, checks[i].field, record); err != nil {
+			errs[i] = fmt.Errorf("%s: %w", checks[i].field.Name(), err)
+		}
+	})
+
+	failed := errs[:0]
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	return failed
+}
+
+// runConcurrently calls fn for 0..n-1 on a pool of
+// Config.ValidationConcurrency workers, and waits for the calls to return.
+func runConcurrently(ctx context.Context, n int, fn func(i int)) {
+	workers := ConfigFrom(ctx).ValidationConcurrency
+	if workers <= 0 {
+		workers = defaultValidationConcurrency
+	}
+	workers = min(workers, n)
+
+	if workers <= 1 {
+		for i := range n {
+			fn(i)
+		}
+		return
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
@@ -0,0 +1,68 @@
+package jpack
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowCheck is a record check that takes a while, like a remote lookup.
+type slowCheck struct {
+	String
+	running, peak *atomic.Int32
+	err           error
+}
+
+func (s *slowCheck) ValidateRecord(ctx context.Context, field JField, record JRecord) error {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return s.err
+}
+
+func TestRecordChecksRunConcurrently(t *testing.T) {
+	var running, peak atomic.Int32
+	builder := NewSchema("test_validation_wide").Field("id", &String{})
+	names := []string{"a", "b", "c", "d", "e", "f"}
+	for _, name := range names {
+		check := &slowCheck{running: &running, peak: &peak}
+		if name == "b" || name == "e" {
+			check.err = errors.New("remote check failed")
+		}
+		builder.Field(name, check)
+	}
+	schema := builder.Build()
+	record := NewMongoRecord(schema)
+
+	t.Run("checks run on a bounded pool", func(t *testing.T) {
+		peak.Store(0)
+		ctx := WithConfig(context.Background(), Config{ValidationConcurrency: 3})
+		start := time.Now()
+		err := record.Validate(ctx)
+		assert.Less(t, time.Since(start), 6*20*time.Millisecond)
+		assert.EqualValues(t, 3, peak.Load())
+
+		assert.EqualError(t, err, "b: remote check failed\ne: remote check failed")
+	})
+
+	t.Run("Save reports the first failing field", func(t *testing.T) {
+		assert.EqualError(t, validateRecordFields(context.Background(), record), "b: remote check failed")
+	})
+
+	t.Run("a concurrency of 1 runs them in order", func(t *testing.T) {
+		peak.Store(0)
+		ctx := WithConfig(context.Background(), Config{ValidationConcurrency: 1})
+		assert.Error(t, record.Validate(ctx))
+		assert.EqualValues(t, 1, peak.Load())
+	})
+}
@@ -0,0 +1,208 @@
+package jpack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrViewReadOnly is returned when saving or deleting a record of a view.
+var ErrViewReadOnly = errors.New("jpack: records of a view are read-only")
+
+// ComputedFunc computes the value of a view field from a loaded record. The
+// record holds the view's fields and the fields the computed field depends on.
+type ComputedFunc func(record JRecord) any
+
+// ViewSchema is a named read model of a schema: a subset of its fields plus
+// computed fields. Queries on a view read the base schema's collection and
+// only fetch the fields the view needs, so list endpoints and exports can work
+// with trimmed records. The base schema's policies and serializers apply.
+// Records of a view are read-only.
+type ViewSchema struct {
+	name     string
+	base     JSchema
+	fields   []JField
+	computed map[string]ComputedFunc
+	// dependsOn lists the base fields read by computed fields
+	dependsOn []JField
+}
+
+// ViewBuilder builds a ViewSchema.
+type ViewBuilder struct {
+	view *ViewSchema
+}
+
+// NewView starts a view of the base schema. The base schema's primary key is
+// always part of the view.
+func NewView(name string, base JSchema) *ViewBuilder {
+	view := &ViewSchema{
+		name:     name,
+		base:     base,
+		computed: make(map[string]ComputedFunc),
+	}
+	builder := &ViewBuilder{view: view}
+	if pk, ok := PK(base); ok {
+		builder.Fields(pk.Name())
+	}
+	return builder
+}
+
+// Fields adds base schema fields to the view. It panics on unknown fields.
+func (b *ViewBuilder) Fields(names ...string) *ViewBuilder {
+	for _, name := range names {
+		field, ok := b.view.base.Field(name)
+		if !ok {
+			panic(fmt.Sprintf("jpack: view %s: unknown field %s of %s", b.view.name, name, b.view.base.Name()))
+		}
+		b.view.AddField(rebindField(field, b.view))
+	}
+	return b
+}
+
+// Computed adds a field whose value is computed by fn from the base fields
+// listed in dependsOn. It panics on unknown fields.
+func (b *ViewBuilder) Computed(name string, fType JFieldType, fn ComputedFunc, dependsOn ...string) *ViewBuilder {
+	for _, dep := range dependsOn {
+		field, ok := b.view.base.Field(dep)
+		if !ok {
+			panic(fmt.Sprintf("jpack: view %s: unknown field %s of %s", b.view.name, dep, b.view.base.Name()))
+		}
+		b.view.dependsOn = append(b.view.dependsOn, field)
+	}
+
+	b.view.AddField(&fieldImpl{name: name, fType: fType, schema: b.view})
+	b.view.computed[name] = fn
+	return b
+}
+
+// Build returns the view.
+func (b *ViewBuilder) Build() *ViewSchema {
+	return b.view
+}
+
+// Base returns the schema the view reads from.
+func (v *ViewSchema) Base() JSchema {
+	return v.base
+}
+
+// IsComputed reports whether the named field is computed.
+func (v *ViewSchema) IsComputed(name string) bool {
+	_, ok := v.computed[name]
+	return ok
+}
+
+// Name implements JSchema.
+func (v *ViewSchema) Name() string {
+	return v.name
+}
+
+// Fields implements JSchema.
+func (v *ViewSchema) Fields() []JField {
+	return v.fields
+}
+
+// Field implements JSchema.
+func (v *ViewSchema) Field(name string) (JField, bool) {
+	for _, f := range v.fields {
+		if f.Name() == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// AddField implements JSchema.
+func (v *ViewSchema) AddField(field JField) JSchema {
+	if _, ok := v.Field(field.Name()); !ok {
+		v.fields = append(v.fields, field)
+	}
+	return v
+}
+
+// Edge implements JSchema.
+func (v *ViewSchema) Edge() []JEdge {
+	return nil
+}
+
+// AddEdge implements JSchema. Views have no edges.
+func (v *ViewSchema) AddEdge(edge JEdge) JSchema {
+	return v
+}
+
+// Validate implements JSchema.
+func (v *ViewSchema) Validate(JRecord) error {
+	return ErrViewReadOnly
+}
+
+// Policies returns the base schema's access policies.
+func (v *ViewSchema) Policies() []JPolicy {
+	return PoliciesOf(v.base)
+}
+
+// Serializers returns the base schema's record serializers.
+func (v *ViewSchema) Serializers() []RecordSerializer {
+	return SerializersOf(v.base)
+}
+
+// ConversionPolicy returns the base schema's own conversion policy.
+func (v *ViewSchema) ConversionPolicy() (ConversionPolicy, bool) {
+	if s, ok := v.base.(interface {
+		ConversionPolicy() (ConversionPolicy, bool)
+	}); ok {
+		return s.ConversionPolicy()
+	}
+	return ConversionPolicy{}, false
+}
+
+// projection returns the stored keys the view reads.
+func (v *ViewSchema) projection() []string {
+	var keys []string
+	for _, field := range v.fields {
+		if !v.IsComputed(field.Name()) {
+			keys = append(keys, storageKeys(field)...)
+		}
+	}
+	for _, field := range v.dependsOn {
+		keys = append(keys, storageKeys(field)...)
+	}
+	return keys
+}
+
+// compute fills the computed fields of a loaded record.
+func (v *ViewSchema) compute(record *mongoRecord) {
+	for _, field := range v.fields {
+		if fn, ok := v.computed[field.Name()]; ok {
+			record.originalRecord[field.Name()] = fn(record)
+		}
+	}
+}
+
+// storageSchema returns the schema whose collection holds the records of
+// schema: the base schema of a view, or schema itself.
+func storageSchema(schema JSchema) JSchema {
+	if view, ok := schema.(*ViewSchema); ok {
+		return storageSchema(view.base)
+	}
+	return schema
+}
+
+// sameStorage reports whether two schemas read the same collection.
+func sameStorage(a, b JSchema) bool {
+	return storageSchema(a).Name() == storageSchema(b).Name()
+}
+
+// rebindField copies a field of the base schema into the view.
+func rebindField(field JField, schema JSchema) JField {
+	switch f := field.(type) {
+	case *refImpl:
+		c := *f
+		c.schema = schema
+		return &c
+	case *fieldImpl:
+		c := *f
+		c.schema = schema
+		return &c
+	}
+	return &fieldImpl{name: field.Name(), fType: field.Type(), schema: schema, defaultValue: field.Default()}
+}
+
+var _ JSchema = &ViewSchema{}
@@ -0,0 +1,80 @@
+package jpack
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func userListView() *ViewSchema {
+	return NewView("test_user_list", userSchema).
+		Fields("first_name").
+		Computed("full_name", &String{}, func(record JRecord) any {
+			firstName, _ := userSchema.Field("first_name")
+			lastName, _ := userSchema.Field("last_name")
+			first, _ := record.Value(firstName)
+			last, _ := record.Value(lastName)
+			return first.(string) + " " + last.(string)
+		}, "first_name", "last_name").
+		Build()
+}
+
+func TestNewView(t *testing.T) {
+	view := userListView()
+
+	assert.Equal(t, "test_user_list", view.Name())
+	assert.Equal(t, userSchema, view.Base())
+
+	var names []string
+	for _, field := range view.Fields() {
+		names = append(names, field.Name())
+		assert.Equal(t, JSchema(view), field.Schema())
+	}
+	assert.Equal(t, []string{"id", "first_name", "full_name"}, names)
+	assert.True(t, view.IsComputed("full_name"))
+	assert.False(t, view.IsComputed("first_name"))
+
+	pk, ok := PK(view)
+	assert.True(t, ok)
+	assert.Equal(t, "id", pk.Name())
+
+	assert.Panics(t, func() { NewView("bad", userSchema).Fields("missing") })
+	assert.Panics(t, func() {
+		NewView("bad", userSchema).Computed("x", &String{}, func(JRecord) any { return nil }, "missing")
+	})
+}
+
+func TestViewQuery(t *testing.T) {
+	ctx := offlineContext(t)
+	view := userListView()
+
+	q := NewMongoQuery(ctx, view).(*mongoQuery)
+	assert.Equal(t, "test_user", q.collection.Name())
+	assert.Equal(t, bson.M{"_id": 1, "id": 1, "first_name": 1, "last_name": 1}, q.projection)
+
+	// Computed fields are not stored, their dependencies are fetched instead
+	full, _ := view.Field("full_name")
+	q.Select(full)
+	assert.Equal(t, bson.M{"_id": 1, "first_name": 1, "last_name": 1}, q.projection)
+}
+
+func TestViewRecord(t *testing.T) {
+	ctx := offlineContext(t)
+	view := userListView()
+
+	record := NewMongoRecord(view)
+	id := bson.NewObjectID()
+	err := record.loadDocument(context.Background(), bson.M{"_id": id, "first_name": "Ada", "last_name": "Lovelace"})
+	assert.NoError(t, err)
+
+	value, ok := record.Value(mustField(t, view, "full_name"))
+	assert.True(t, ok)
+	assert.Equal(t, "Ada Lovelace", value)
+	value, _ = record.Value(mustField(t, view, "id"))
+	assert.Equal(t, id.Hex(), value)
+
+	assert.ErrorIs(t, record.Save(ctx), ErrViewReadOnly)
+	assert.ErrorIs(t, record.Delete(ctx), ErrViewReadOnly)
+}
@@ -0,0 +1,138 @@
+package jpack
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Warning codes of the built-in field types.
+const (
+	// WarningDeprecatedOption is an Options value whose option is deprecated.
+	WarningDeprecatedOption = "deprecated_option"
+	// WarningNearMaxSize is a Text value above 90% of the field's MaxSize.
+	WarningNearMaxSize = "near_max_size"
+)
+
+// nearMaxSizeRatio is the share of a Text field's MaxSize from which values
+// are warned about.
+const nearMaxSizeRatio = 0.9
+
+// Warning is a non-fatal validation result, e.g. a deprecated option being
+// used. Warnings never block a write; UIs can show them as soft guidance.
+type Warning struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Field, w.Message)
+}
+
+// WarningFieldType is implemented by field types that warn about some values
+// without rejecting them. Like ValidateRecord, Warnings runs concurrently with
+// the other fields of the record and must only read it.
+type WarningFieldType interface {
+	Warnings(ctx context.Context, field JField, record JRecord) []Warning
+}
+
+// WarningsOf returns the warnings of the record's last Save or Validate.
+func WarningsOf(record JRecord) []Warning {
+	switch r := record.(type) {
+	case *mongoRecord:
+		return r.warnings
+	case *sqliteRecord:
+		return r.warnings
+	}
+	return nil
+}
+
+// collectWarnings returns the warnings of the record's fields that have a
+// value, in field order.
+func collectWarnings(ctx context.Context, record JRecord) []Warning {
+	type warner struct {
+		field JField
+		warn  WarningFieldType
+	}
+	var warners []warner
+	for _, field := range record.Schema().Fields() {
+		t, ok := field.Type().(WarningFieldType)
+		if !ok {
+			continue
+		}
+		if value, ok := record.Value(field); ok && value != nil {
+			warners = append(warners, warner{field: field, warn: t})
+		}
+	}
+
+	found := make([][]Warning, len(warners))
+	runConcurrently(ctx, len(warners), func(i int) {
+		found[i] = warners[i].warn.Warnings(ctx, warners[i].field, record)
+	})
+	return slices.Concat(found...)
+}
+
+// Warnings implements WarningFieldType for values of a deprecated option.
+func (o *Options) Warnings(ctx context.Context, field JField, record JRecord) []Warning {
+	value, ok := record.Value(field)
+	if !ok {
+		return nil
+	}
+	name, ok := value.(string)
+	if !ok {
+		return nil
+	}
+
+	option, found, err := o.lookup(ctx, name)
+	if err != nil || !found || !option.Deprecated {
+		return nil
+	}
+	return []Warning{{
+		Field:   field.Name(),
+		Code:    WarningDeprecatedOption,
+		Message: fmt.Sprintf("option %q is deprecated", name),
+	}}
+}
+
+// lookup finds an option by its unique name.
+func (o *Options) lookup(ctx context.Context, name string) (Option, bool, error) {
+	if search, ok := o.service.(OptionSearchService); ok {
+		return search.LookupOption(ctx, name)
+	}
+	options, err := o.service.GetOptions(ctx)
+	if err != nil {
+		return Option{}, false, err
+	}
+	for _, option := range options {
+		if option.UniqueName == name {
+			return option, true, nil
+		}
+	}
+	return Option{}, false, nil
+}
+
+// Warnings implements WarningFieldType for values close to MaxSize.
+func (t *Text) Warnings(ctx context.Context, field JField, record JRecord) []Warning {
+	if t.MaxSize <= 0 {
+		return nil
+	}
+	value, ok := record.Value(field)
+	if !ok {
+		return nil
+	}
+	text, ok := value.(string)
+	if !ok || float64(len(text)) < nearMaxSizeRatio*float64(t.MaxSize) {
+		return nil
+	}
+	return []Warning{{
+		Field:   field.Name(),
+		Code:    WarningNearMaxSize,
+		Message: fmt.Sprintf("text of %d bytes is close to the maximum of %d bytes", len(text), t.MaxSize),
+	}}
+}
+
+var (
+	_ WarningFieldType = &Options{}
+	_ WarningFieldType = &Text{}
+)
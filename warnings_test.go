@@ -0,0 +1,53 @@
+package jpack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarnings(t *testing.T) {
+	plans := NewInMemoryOptionService([]Option{
+		{UniqueName: "pro", DisplayName: "Pro"},
+		{UniqueName: "legacy", DisplayName: "Legacy", Deprecated: true},
+	})
+	accounts := NewSchema("test_warning_accounts").
+		Field("id", &String{}).
+		Field("plan", NewOptions(plans)).
+		Field("bio", &Text{MaxSize: 100}).
+		Field("notes", &Text{}).
+		Build()
+	plan, bio, notes := mustField(t, accounts, "plan"), mustField(t, accounts, "bio"), mustField(t, accounts, "notes")
+	ctx := offlineContext(t)
+
+	t.Run("clean values have no warnings", func(t *testing.T) {
+		record := NewMongoRecord(accounts)
+		assert.NoError(t, record.SetValue(plan, "pro"))
+		assert.NoError(t, record.SetValue(bio, "Hello"))
+		assert.NoError(t, record.SetValue(notes, strings.Repeat("x", 1000)))
+		assert.NoError(t, record.Validate(ctx))
+		assert.Empty(t, WarningsOf(record))
+	})
+
+	t.Run("warnings don't block the write", func(t *testing.T) {
+		record := NewMongoRecord(accounts)
+		assert.NoError(t, record.SetValue(plan, "legacy"))
+		assert.NoError(t, record.SetValue(bio, strings.Repeat("x", 95)))
+
+		_, err := record.prepareSave(ctx, &saveOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, []Warning{
+			{Field: "plan", Code: WarningDeprecatedOption, Message: `option "legacy" is deprecated`},
+			{Field: "bio", Code: WarningNearMaxSize, Message: "text of 95 bytes is close to the maximum of 100 bytes"},
+		}, WarningsOf(record))
+	})
+
+	t.Run("Validate reports warnings apart from errors", func(t *testing.T) {
+		record := NewMongoRecord(accounts)
+		assert.NoError(t, record.SetValue(plan, "legacy"))
+		assert.NoError(t, record.Validate(ctx))
+		assert.Len(t, WarningsOf(record), 1)
+		assert.Equal(t, "plan: option \"legacy\" is deprecated", WarningsOf(record)[0].String())
+	})
+}
@@ -0,0 +1,269 @@
+package jpack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+)
+
+// Webhook is an endpoint notified of record changes.
+type Webhook struct {
+	URL string
+	// Secret signs payloads; receivers check them with VerifyWebhookSignature.
+	Secret string
+	// Events limits the operations sent to the endpoint; empty sends all.
+	Events []ChangeOperation
+}
+
+// WebhookDelivery is a single delivery attempt.
+type WebhookDelivery struct {
+	ID         string
+	URL        string
+	Schema     string
+	Operation  ChangeOperation
+	Attempt    int
+	StatusCode int
+	Err        error
+	Time       time.Time
+}
+
+// DeliveryLog records webhook delivery attempts.
+type DeliveryLog interface {
+	Record(ctx context.Context, delivery WebhookDelivery)
+}
+
+// WebhookDispatcher POSTs signed JSON payloads to the webhooks registered for
+// a schema when its records are created, updated or deleted. Deliveries run
+// in the background and are retried with exponential backoff on network
+// errors, 429 and 5xx responses.
+type WebhookDispatcher struct {
+	Client *http.Client
+	// MaxAttempts per delivery, defaults to 5.
+	MaxAttempts int
+	// Backoff before the first retry, doubled for every further one.
+	Backoff time.Duration
+	// Log defaults to logging deliveries with zerolog.
+	Log DeliveryLog
+
+	mu       sync.RWMutex
+	webhooks map[string][]Webhook
+	inflight sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates a dispatcher without webhooks.
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		MaxAttempts: defaultWebhookAttempts,
+		Backoff:     defaultWebhookBackoff,
+		webhooks:    make(map[string][]Webhook),
+	}
+}
+
+// Register adds a webhook for the records of a schema.
+func (d *WebhookDispatcher) Register(schema JSchema, webhook Webhook) *WebhookDispatcher {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.webhooks[schema.Name()] = append(d.webhooks[schema.Name()], webhook)
+	return d
+}
+
+// Dispatch starts delivering an event to the schema's webhooks.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, event ChangeEvent) error {
+	d.mu.RLock()
+	webhooks := d.webhooks[event.Schema.Name()]
+	d.mu.RUnlock()
+
+	var targets []Webhook
+	for _, webhook := range webhooks {
+		if len(webhook.Events) == 0 || slices.Contains(webhook.Events, event.Operation) {
+			targets = append(targets, webhook)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	payload, err := JSONChangeEncoder{}.Encode(event)
+	if err != nil {
+		return err
+	}
+
+	// Deliveries outlive the request that caused them
+	ctx = context.WithoutCancel(ctx)
+	for _, webhook := range targets {
+		d.inflight.Add(1)
+		go func() {
+			defer d.inflight.Done()
+			d.deliver(ctx, webhook, event, payload)
+		}()
+	}
+	return nil
+}
+
+// Wait blocks until every started delivery has finished, e.g. on shutdown.
+func (d *WebhookDispatcher) Wait() {
+	d.inflight.Wait()
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, webhook Webhook, event ChangeEvent, payload []byte) {
+	delivery := WebhookDelivery{
+		ID:        bson.NewObjectID().Hex(),
+		URL:       webhook.URL,
+		Schema:    event.Schema.Name(),
+		Operation: event.Operation,
+	}
+
+	attempts := d.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	backoff := d.Backoff
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		delivery.Attempt = attempt
+		delivery.Time = time.Now().UTC()
+		delivery.StatusCode, delivery.Err = d.post(ctx, webhook, delivery.ID, payload)
+		d.log().Record(ctx, delivery)
+
+		if !retryableDelivery(delivery.StatusCode, delivery.Err) || attempt == attempts {
+			return
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, webhook Webhook, deliveryID string, payload []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Jpack-Delivery", deliveryID)
+	req.Header.Set("X-Jpack-Timestamp", timestamp)
+	if webhook.Secret != "" {
+		req.Header.Set("X-Jpack-Signature", signWebhook(webhook.Secret, timestamp, payload))
+	}
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("jpack: webhook returned %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+func retryableDelivery(status int, err error) bool {
+	if err == nil {
+		return false
+	}
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+func (d *WebhookDispatcher) log() DeliveryLog {
+	if d.Log == nil {
+		return zerologDeliveryLog{}
+	}
+	return d.Log
+}
+
+// signWebhook returns "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<payload>".
+func signWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks the X-Jpack-Signature header of a delivery
+// against its X-Jpack-Timestamp header and body.
+func VerifyWebhookSignature(secret, timestamp string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(signWebhook(secret, timestamp, payload)), []byte(signature))
+}
+
+type zerologDeliveryLog struct{}
+
+func (zerologDeliveryLog) Record(ctx context.Context, delivery WebhookDelivery) {
+	entry := LoggerFrom(ctx).Info()
+	if delivery.Err != nil {
+		entry = LoggerFrom(ctx).Warn().Err(delivery.Err)
+	}
+	entry.Str("delivery", delivery.ID).
+		Str("url", delivery.URL).
+		Str("schema", delivery.Schema).
+		Str("operation", string(delivery.Operation)).
+		Int("attempt", delivery.Attempt).
+		Int("status", delivery.StatusCode).
+		Msg("jpack: webhook delivery")
+}
+
+var (
+	webhookDispatcherMu sync.RWMutex
+	webhookDispatcher   *WebhookDispatcher
+)
+
+// SetWebhookDispatcher sets the dispatcher notified by Save and Delete, or
+// disables webhooks when nil.
+func SetWebhookDispatcher(dispatcher *WebhookDispatcher) {
+	webhookDispatcherMu.Lock()
+	defer webhookDispatcherMu.Unlock()
+
+	webhookDispatcher = dispatcher
+}
+
+// dispatchWebhooks notifies the package-wide dispatcher of a write.
+func dispatchWebhooks(ctx context.Context, record JRecord, op ChangeOperation) {
+	webhookDispatcherMu.RLock()
+	dispatcher := webhookDispatcher
+	webhookDispatcherMu.RUnlock()
+
+	if dispatcher == nil {
+		return
+	}
+
+	event := ChangeEvent{Schema: record.Schema(), Operation: op, Time: time.Now().UTC()}
+	event.ID, _ = recordID(record)
+	if op != ChangeDelete {
+		event.Record = record
+	}
+
+	// Writes of a transaction are only announced once it committed
+	dispatched := afterCommit(ctx, func(ctx context.Context) {
+		if err := dispatcher.Dispatch(ctx, event); err != nil {
+			LoggerFrom(ctx).Error().Err(err).Str("schema", record.Schema().Name()).Msg("jpack: failed to dispatch webhooks")
+		}
+	})
+	if !dispatched {
+		LoggerFrom(ctx).Error().Str("schema", record.Schema().Name()).Msg("jpack: webhooks aren't sent for writes in a transaction not run by a UnitOfWork")
+	}
+}
+
+var _ DeliveryLog = zerologDeliveryLog{}
@@ -0,0 +1,99 @@
+package jpack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+type memoryDeliveryLog struct {
+	mu         sync.Mutex
+	deliveries []WebhookDelivery
+}
+
+func (l *memoryDeliveryLog) Record(ctx context.Context, delivery WebhookDelivery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deliveries = append(l.deliveries, delivery)
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	ctx := context.Background()
+	id := bson.NewObjectID()
+	record, err := RecordFromBSON(userSchema, bson.M{"_id": id, "first_name": "Ada"})
+	assert.NoError(t, err)
+
+	t.Run("signs and retries failed deliveries", func(t *testing.T) {
+		assert := assert.New(t)
+
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.True(VerifyWebhookSignature("s3cret", r.Header.Get("X-Jpack-Timestamp"), body, r.Header.Get("X-Jpack-Signature")))
+
+			var payload map[string]any
+			assert.NoError(json.Unmarshal(body, &payload))
+			assert.Equal("update", payload["operation"])
+			assert.Equal(id.Hex(), payload["id"])
+
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		deliveries := &memoryDeliveryLog{}
+		dispatcher := NewWebhookDispatcher()
+		dispatcher.Backoff = 0
+		dispatcher.Log = deliveries
+		dispatcher.Register(userSchema, Webhook{URL: server.URL, Secret: "s3cret"})
+
+		assert.NoError(dispatcher.Dispatch(ctx, ChangeEvent{Schema: userSchema, Operation: ChangeUpdate, ID: id.Hex(), Record: record}))
+		dispatcher.Wait()
+
+		assert.Equal(int32(2), calls.Load())
+		assert.Len(deliveries.deliveries, 2)
+		assert.Equal(http.StatusServiceUnavailable, deliveries.deliveries[0].StatusCode)
+		assert.Error(deliveries.deliveries[0].Err)
+		assert.Equal(2, deliveries.deliveries[1].Attempt)
+		assert.NoError(deliveries.deliveries[1].Err)
+		assert.Equal(deliveries.deliveries[0].ID, deliveries.deliveries[1].ID)
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		dispatcher := NewWebhookDispatcher()
+		dispatcher.Backoff = 0
+		dispatcher.Log = &memoryDeliveryLog{}
+		dispatcher.Register(userSchema, Webhook{URL: server.URL})
+
+		assert.NoError(t, dispatcher.Dispatch(ctx, ChangeEvent{Schema: userSchema, Operation: ChangeDelete, ID: id.Hex()}))
+		dispatcher.Wait()
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("filters events", func(t *testing.T) {
+		deliveries := &memoryDeliveryLog{}
+		dispatcher := NewWebhookDispatcher()
+		dispatcher.Log = deliveries
+		dispatcher.Register(userSchema, Webhook{URL: "http://127.0.0.1:0", Events: []ChangeOperation{ChangeDelete}})
+
+		assert.NoError(t, dispatcher.Dispatch(ctx, ChangeEvent{Schema: userSchema, Operation: ChangeInsert, Record: record}))
+		dispatcher.Wait()
+		assert.Empty(t, deliveries.deliveries)
+	})
+}
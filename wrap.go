@@ -0,0 +1,173 @@
+package jpack
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Transform normalizes a value before a Wrapped field type validates and
+// stores it. Values a transform doesn't apply to are returned unchanged, and
+// left to the inner type's validation.
+type Transform func(value any) (any, error)
+
+// Wrapped decorates a field type with transforms, so normalization such as
+// trimming or lowercasing isn't copy-pasted into hooks. Values are
+// transformed in SetValue before they are validated, and JRecord.SetValue
+// keeps the transformed value. Stored values are scanned by the inner type.
+type Wrapped struct {
+	Inner      JFieldType
+	Transforms []Transform
+}
+
+// Wrap decorates inner with transforms, applied in order.
+func Wrap(inner JFieldType, transforms ...Transform) *Wrapped {
+	return &Wrapped{Inner: inner, Transforms: transforms}
+}
+
+var (
+	_ JFieldType      = &Wrapped{}
+	_ StrictFieldType = &Wrapped{}
+)
+
+// Normalize applies the transforms to a value.
+func (w *Wrapped) Normalize(value any) (any, error) {
+	for _, transform := range w.Transforms {
+		var err error
+		if value, err = transform(value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// Unwrap returns the inner field type.
+func (w *Wrapped) Unwrap() JFieldType {
+	return w.Inner
+}
+
+// TypeName reports the registry name of the inner type.
+func (w *Wrapped) TypeName() string {
+	return FieldTypeName(w.Inner)
+}
+
+// Scan implements JFieldType.
+func (w *Wrapped) Scan(ctx context.Context, field JField, row map[string]any) (any, error) {
+	return w.Inner.Scan(ctx, field, row)
+}
+
+// SetValue implements JFieldType.
+func (w *Wrapped) SetValue(ctx context.Context, field JField, value any, row map[string]any) error {
+	value, err := w.Normalize(value)
+	if err != nil {
+		return err
+	}
+	return w.Inner.SetValue(ctx, field, value, row)
+}
+
+// Validate implements JFieldType.
+func (w *Wrapped) Validate(value any) error {
+	value, err := w.Normalize(value)
+	if err != nil {
+		return err
+	}
+	return w.Inner.Validate(value)
+}
+
+// ValidateStrict implements StrictFieldType for inner types that do. The
+// value is checked as given, before it is transformed.
+func (w *Wrapped) ValidateStrict(value any) error {
+	if strict, ok := w.Inner.(StrictFieldType); ok {
+		return strict.ValidateStrict(value)
+	}
+	return nil
+}
+
+// ValidateRecord implements RecordFieldType for inner types that do.
+func (w *Wrapped) ValidateRecord(ctx context.Context, field JField, record JRecord) error {
+	if t, ok := w.Inner.(RecordFieldType); ok {
+		return t.ValidateRecord(ctx, field, record)
+	}
+	return nil
+}
+
+// Warnings implements WarningFieldType for inner types that do.
+func (w *Wrapped) Warnings(ctx context.Context, field JField, record JRecord) []Warning {
+	if t, ok := w.Inner.(WarningFieldType); ok {
+		return t.Warnings(ctx, field, record)
+	}
+	return nil
+}
+
+// baseType returns the innermost type of a Wrapped field type, for code that
+// depends on the concrete type, e.g. exports.
+func baseType(fType JFieldType) JFieldType {
+	for {
+		w, ok := fType.(interface{ Unwrap() JFieldType })
+		if !ok {
+			return fType
+		}
+		fType = w.Unwrap()
+	}
+}
+
+// normalizeInput applies the transforms of a field type that has them.
+func normalizeInput(field JField, value any) (any, error) {
+	if n, ok := field.Type().(interface{ Normalize(any) (any, error) }); ok {
+		return n.Normalize(value)
+	}
+	return value, nil
+}
+
+// stringTransform returns a transform applying fn to string values.
+func stringTransform(fn func(string) string) Transform {
+	return func(value any) (any, error) {
+		if s, ok := value.(string); ok {
+			return fn(s), nil
+		}
+		return value, nil
+	}
+}
+
+// TrimSpace removes leading and trailing white space from strings.
+func TrimSpace() Transform {
+	return stringTransform(strings.TrimSpace)
+}
+
+// Lowercase lowercases strings, e.g. emails or slugs.
+func Lowercase() Transform {
+	return stringTransform(strings.ToLower)
+}
+
+// Truncate shortens strings to at most n characters.
+func Truncate(n int) Transform {
+	return stringTransform(func(s string) string {
+		if utf8.RuneCountInString(s) <= n {
+			return s
+		}
+		return string([]rune(s)[:n])
+	})
+}
+
+// ClampRange limits numbers to [min, max]. Values that aren't whole numbers
+// are left to the inner type's validation.
+func ClampRange(min, max int) Transform {
+	return func(value any) (any, error) {
+		reflectValue := reflect.ValueOf(value)
+		if value == nil || (reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()) {
+			return value, nil
+		}
+		n, err := convertToInt(reflectValue)
+		if err != nil {
+			return value, nil
+		}
+		if n < min {
+			return min, nil
+		}
+		if n > max {
+			return max, nil
+		}
+		return n, nil
+	}
+}
@@ -0,0 +1,69 @@
+package jpack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrap(t *testing.T) {
+	schema := NewSchema("test_wrap").
+		Field("id", &String{}).
+		Field("email", Wrap(&String{}, TrimSpace(), Lowercase())).
+		Field("title", Wrap(&String{}, TrimSpace(), Truncate(5))).
+		Field("rating", Wrap(&Number{}, ClampRange(1, 5))).
+		Build()
+	email, title, rating := mustField(t, schema, "email"), mustField(t, schema, "title"), mustField(t, schema, "rating")
+
+	t.Run("values are transformed before validation", func(t *testing.T) {
+		record := NewMongoRecord(schema)
+		assert.NoError(t, record.SetValue(email, "  Ada@Example.ORG "))
+		assert.NoError(t, record.SetValue(title, " Lovelace "))
+		assert.NoError(t, record.SetValue(rating, 9))
+
+		got, _ := record.Value(email)
+		assert.Equal(t, "ada@example.org", got)
+		got, _ = record.Value(title)
+		assert.Equal(t, "Lovel", got)
+		got, _ = record.Value(rating)
+		assert.Equal(t, 5, got)
+
+		assert.NoError(t, record.SetValue(rating, "-3"))
+		got, _ = record.Value(rating)
+		assert.Equal(t, 1, got)
+
+		assert.Error(t, record.SetValue(rating, "lots"), "the inner type still validates")
+		assert.NoError(t, record.SetValue(email, nil))
+	})
+
+	t.Run("field type SetValue", func(t *testing.T) {
+		row := map[string]any{}
+		assert.NoError(t, email.Type().SetValue(t.Context(), email, " X@Y.Z", row))
+		assert.Equal(t, "x@y.z", row["email"])
+	})
+
+	t.Run("Truncate counts characters", func(t *testing.T) {
+		got, err := Truncate(2)("héllo")
+		assert.NoError(t, err)
+		assert.Equal(t, "hé", got)
+	})
+
+	t.Run("strict mode checks the given value", func(t *testing.T) {
+		strict := NewSchema("test_wrap_strict").
+			Field("id", &String{}).
+			Field("rating", Wrap(&Number{}, ClampRange(1, 5))).
+			ConversionPolicy(ConversionPolicy{Mode: Strict}).
+			Build()
+		record := NewMongoRecord(strict)
+		assert.Error(t, record.SetValue(mustField(t, strict, "rating"), "3"))
+		assert.NoError(t, record.SetValue(mustField(t, strict, "rating"), 30))
+	})
+
+	t.Run("exports see the inner type", func(t *testing.T) {
+		assert.Equal(t, "string", FieldTypeName(email.Type()))
+		assert.Equal(t, map[string]any{"type": "integer", "format": "int64"}, openAPIType(t.Context(), rating.Type()))
+		kind, err := exportKindOf(rating.Type())
+		assert.NoError(t, err)
+		assert.Equal(t, exportLong, kind)
+	})
+}